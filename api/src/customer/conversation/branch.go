@@ -0,0 +1,254 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+)
+
+// max length of the preview of the last message of a branch
+const branchPreviewLength = 120
+
+// The messages that share a parent with the message at an index of the active branch
+type MessageBranches struct {
+	MessageIDs []uuid.UUID `json:"messageIds"` // ordered by creation, including the active message
+	Current    int         `json:"current"`    // index of the active message in MessageIDs
+}
+
+// A branch of the conversation, identified by the last message of the branch
+type ConversationBranch struct {
+	LeafID    uuid.UUID          `json:"leafId"`
+	Length    int32              `json:"length"`
+	Role      string             `json:"role"`
+	Preview   string             `json:"preview"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	Active    bool               `json:"active"`
+}
+
+// removes the messages from the index onwards from the internal lists
+func (c *Conversation) truncate(index int) {
+	if index < 0 {
+		index = 0
+	}
+	if index < len(c.messages) {
+		c.messages = c.messages[:index]
+	}
+	if index < len(c.messageIds) {
+		c.messageIds = c.messageIds[:index]
+	}
+	for key := range c.citations {
+		if key >= index {
+			delete(c.citations, key)
+		}
+	}
+}
+
+/*
+Starts a new branch at the message at the index. The active branch is cut before the message,
+so the next saved message is created as a sibling of it. Use this to edit a user message by
+saving the new message, or to regenerate an AI response by running a completion without a
+message. The previous branch is kept and can be switched back to.
+*/
+func (c *Conversation) Branch(
+	ctx context.Context,
+	db queries.DBTX,
+	index int,
+) error {
+	if index < 1 || index >= len(c.messages) {
+		return fmt.Errorf("invalid message index: %d", index)
+	}
+
+	dmodel := queries.New(db)
+	if err := dmodel.SetConversationActiveMessage(ctx, &queries.SetConversationActiveMessageParams{
+		ID:              c.ID,
+		ActiveMessageID: utils.GoogleUUIDToPGXUUID(c.messageIds[index-1]),
+	}); err != nil {
+		return fmt.Errorf("failed to set the active message: %w", err)
+	}
+	if err := c.resetSummary(ctx, db, index); err != nil {
+		return err
+	}
+
+	c.truncate(index)
+	return nil
+}
+
+// Same as `Branch`, but validates the message at the index is a user message
+func (c *Conversation) BranchUserMessage(
+	ctx context.Context,
+	db queries.DBTX,
+	index int,
+) error {
+	if index < 1 || index >= len(c.messages) || c.messages[index].Role != gollm.RoleUser {
+		return fmt.Errorf("the message at index %d is not a user message", index)
+	}
+	return c.Branch(ctx, db, index)
+}
+
+// Same as `Branch`, but validates the message at the index is a response from the AI
+func (c *Conversation) BranchAIMessage(
+	ctx context.Context,
+	db queries.DBTX,
+	index int,
+) error {
+	if index < 1 || index >= len(c.messages) || (c.messages[index].Role != gollm.RoleAI && c.messages[index].Role != gollm.RoleToolCall) {
+		return fmt.Errorf("the message at index %d is not a response from the AI", index)
+	}
+	return c.Branch(ctx, db, index)
+}
+
+// Switches the active branch to the latest branch that contains the message
+func (c *Conversation) SwitchBranch(
+	ctx context.Context,
+	db queries.DBTX,
+	messageId uuid.UUID,
+) error {
+	dmodel := queries.New(db)
+
+	// ensure the message is part of the conversation
+	if _, err := dmodel.GetConversationMessage(ctx, &queries.GetConversationMessageParams{
+		ID:             messageId,
+		ConversationID: c.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to get the message: %w", err)
+	}
+
+	leaf, err := dmodel.GetLatestConversationLeaf(ctx, messageId)
+	if err != nil {
+		return fmt.Errorf("failed to get the latest message of the branch: %w", err)
+	}
+	msgs, err := dmodel.GetConversationBranch(ctx, leaf.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get the branch: %w", err)
+	}
+
+	if err := dmodel.SetConversationActiveMessage(ctx, &queries.SetConversationActiveMessageParams{
+		ID:              c.ID,
+		ActiveMessageID: utils.GoogleUUIDToPGXUUID(leaf.ID),
+	}); err != nil {
+		return fmt.Errorf("failed to set the active message: %w", err)
+	}
+
+	// the summary only covers the messages both branches share
+	shared := 0
+	for shared < len(msgs) && shared < len(c.messageIds) && msgs[shared].ID == c.messageIds[shared] {
+		shared++
+	}
+	if err := c.resetSummary(ctx, db, shared); err != nil {
+		return err
+	}
+
+	c.setMessages(msgs)
+	return nil
+}
+
+// Clears the running summary when it covers messages from the index onwards, as they are no
+// longer part of the active branch
+func (c *Conversation) resetSummary(ctx context.Context, db queries.DBTX, index int) error {
+	if c.Summary == "" || int(c.SummaryIndex) <= index {
+		return nil
+	}
+
+	dmodel := queries.New(db)
+	if err := dmodel.UpdateConversationSummary(ctx, &queries.UpdateConversationSummaryParams{
+		ID:           c.ID,
+		Summary:      "",
+		SummaryIndex: 0,
+	}); err != nil {
+		return fmt.Errorf("failed to reset the summary: %w", err)
+	}
+	c.Summary = ""
+	c.SummaryIndex = 0
+	return nil
+}
+
+// Gets the siblings of the messages of the active branch by message index. Only messages that
+// have been edited or regenerated are included
+func (c *Conversation) GetMessageBranches(
+	ctx context.Context,
+	db queries.DBTX,
+) (map[int]*MessageBranches, error) {
+	dmodel := queries.New(db)
+	tree, err := dmodel.GetConversationMessageTree(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the messages: %w", err)
+	}
+
+	// group the messages by parent
+	children := make(map[uuid.UUID][]uuid.UUID)
+	for _, item := range tree {
+		var parent uuid.UUID
+		if id := utils.PGXUUIDToGoogleUUID(item.ParentID); id != nil {
+			parent = *id
+		}
+		children[parent] = append(children[parent], item.ID)
+	}
+
+	response := make(map[int]*MessageBranches)
+	for i, id := range c.messageIds {
+		var parent uuid.UUID
+		if i > 0 {
+			parent = c.messageIds[i-1]
+		}
+		siblings := children[parent]
+		if len(siblings) < 2 {
+			continue
+		}
+		branches := &MessageBranches{MessageIDs: siblings}
+		for j, item := range siblings {
+			if item == id {
+				branches.Current = j
+			}
+		}
+		response[i] = branches
+	}
+	return response, nil
+}
+
+// Lists every branch of the conversation, latest first
+func (c *Conversation) GetBranches(
+	ctx context.Context,
+	db queries.DBTX,
+) ([]*ConversationBranch, error) {
+	dmodel := queries.New(db)
+	leaves, err := dmodel.GetConversationLeaves(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the branches: %w", err)
+	}
+
+	var activeId uuid.UUID
+	if len(c.messageIds) != 0 {
+		activeId = c.messageIds[len(c.messageIds)-1]
+	}
+
+	response := make([]*ConversationBranch, len(leaves))
+	for i, item := range leaves {
+		response[i] = &ConversationBranch{
+			LeafID:    item.ID,
+			Length:    item.Index + 1,
+			Role:      item.Role,
+			Preview:   branchPreview(item.Message),
+			CreatedAt: item.CreatedAt,
+			Active:    item.ID == activeId,
+		}
+	}
+	return response, nil
+}
+
+func branchPreview(message string) string {
+	if utf8.RuneCountInString(message) <= branchPreviewLength {
+		return message
+	}
+	return string([]rune(message)[:branchPreviewLength]) + "..."
+}
+
+// ids of the messages of the active branch
+func (c Conversation) GetMessageIDs() []uuid.UUID {
+	return c.messageIds
+}
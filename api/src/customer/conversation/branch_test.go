@@ -0,0 +1,50 @@
+package conversation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/stretchr/testify/require"
+)
+
+func branchTestConversation() *Conversation {
+	messages := contextTestMessages(2)
+	ids := make([]uuid.UUID, len(messages))
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	return &Conversation{
+		Conversation: &queries.Conversation{ID: uuid.New()},
+		messages:     messages,
+		messageIds:   ids,
+		citations: map[int][]*Citation{
+			4: {{Number: 1}},
+			8: {{Number: 1}},
+		},
+	}
+}
+
+func TestConversationTruncate(t *testing.T) {
+	conv := branchTestConversation()
+	conv.truncate(5)
+
+	require.Len(t, conv.GetMessages(), 5)
+	require.Len(t, conv.GetMessageIDs(), 5)
+	require.Contains(t, conv.GetCitations(), 4)
+	require.NotContains(t, conv.GetCitations(), 8)
+}
+
+func TestBranchValidatesTheMessage(t *testing.T) {
+	conv := branchTestConversation()
+
+	// the message at the index has the wrong role, so nothing is written
+	require.Error(t, conv.BranchUserMessage(context.TODO(), nil, 4))
+	require.Error(t, conv.BranchAIMessage(context.TODO(), nil, 5))
+	require.Error(t, conv.Branch(context.TODO(), nil, 0))
+	require.Error(t, conv.Branch(context.TODO(), nil, len(conv.GetMessages())))
+	require.Len(t, conv.GetMessages(), 9)
+	require.Equal(t, gollm.RoleAI, conv.GetMessages()[8].Role)
+}
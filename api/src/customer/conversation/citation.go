@@ -0,0 +1,77 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+const (
+	CITATION_SOURCE_DOCUMENT     = "document"
+	CITATION_SOURCE_WEBSITE_PAGE = "websitePage"
+	CITATION_SOURCE_FEED_ITEM    = "feedItem"
+	CITATION_SOURCE_WEB          = "web"
+)
+
+// max length of the snippet stored with a citation
+const citationSnippetLength = 300
+
+// A source that was used to compose an AI message. The model references it in the
+// message with the `[Number]` marker
+type Citation struct {
+	Number     int        `json:"number"`
+	SourceType string     `json:"sourceType"`
+	SourceID   *uuid.UUID `json:"sourceId,omitempty"` // document, page, or feed item id
+	Title      string     `json:"title,omitempty"`
+	Url        string     `json:"url,omitempty"`
+	ChunkIndex *int32     `json:"chunkIndex,omitempty"`
+	Snippet    string     `json:"snippet"`
+}
+
+// Shortens the content to be stored as the snippet of a citation
+func CitationSnippet(content string) string {
+	if utf8.RuneCountInString(content) <= citationSnippetLength {
+		return content
+	}
+	return string([]rune(content)[:citationSnippetLength]) + "..."
+}
+
+// Saves the citations on the last message of the conversation, which has to be from the AI
+func (c *Conversation) SaveCitations(
+	ctx context.Context,
+	db queries.DBTX,
+	citations []*Citation,
+) error {
+	if len(citations) == 0 {
+		return nil
+	}
+	if len(c.messages) == 0 || c.messages[len(c.messages)-1].Role != gollm.RoleAI {
+		return fmt.Errorf("the last message is not from the AI")
+	}
+
+	enc, err := json.Marshal(citations)
+	if err != nil {
+		return fmt.Errorf("failed to encode the citations: %w", err)
+	}
+
+	dmodel := queries.New(db)
+	if err := dmodel.UpdateConversationMessageCitations(ctx, &queries.UpdateConversationMessageCitationsParams{
+		ID:        c.messageIds[len(c.messageIds)-1],
+		Citations: enc,
+	}); err != nil {
+		return fmt.Errorf("failed to save the citations: %w", err)
+	}
+
+	c.citations[len(c.messages)-1] = citations
+	return nil
+}
+
+// Citations of the messages by message index
+func (c Conversation) GetCitations() map[int][]*Citation {
+	return c.citations
+}
@@ -0,0 +1,32 @@
+package conversation
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCitationSnippet(t *testing.T) {
+	require.Equal(t, "short", CitationSnippet("short"))
+
+	snippet := CitationSnippet(strings.Repeat("é", citationSnippetLength+10))
+	require.Equal(t, strings.Repeat("é", citationSnippetLength)+"...", snippet)
+}
+
+func TestSaveCitationsRequiresAIMessage(t *testing.T) {
+	conv := &Conversation{
+		messages:  []*gollm.Message{{Role: gollm.RoleUser, Message: "hello"}},
+		citations: make(map[int][]*Citation),
+	}
+
+	// nothing to save
+	require.NoError(t, conv.SaveCitations(context.TODO(), nil, nil))
+
+	// the citations can only be saved on an AI message
+	err := conv.SaveCitations(context.TODO(), nil, []*Citation{{Number: 1, SourceType: CITATION_SOURCE_WEB}})
+	require.Error(t, err)
+	require.Empty(t, conv.GetCitations())
+}
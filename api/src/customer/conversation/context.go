@@ -0,0 +1,324 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+const (
+	// the earlier turns are dropped once the conversation does not fit
+	CONTEXT_STRATEGY_TRUNCATE = "truncate"
+	// the earlier turns are summarized into the running summary of the conversation once the
+	// conversation does not fit. Turns are still dropped when the summary is not enough
+	CONTEXT_STRATEGY_SUMMARIZE = "summarize"
+)
+
+/*
+How the messages of a conversation are fit into the context window of the model.
+
+The system prompt and the latest `KeepTurns` turns are always sent. A turn starts with a user
+message and holds the tool calls and responses that follow it. When the messages do not fit,
+the tool results of the earlier turns are compacted first, then the earlier turns are
+summarized or dropped depending on the strategy.
+*/
+type ContextStrategy struct {
+	Strategy string
+
+	// fraction of the input token limit of the model the messages can use. The rest is left
+	// for the model instructions, the tools, and estimation errors
+	Budget float64
+
+	// number of the latest turns that are sent verbatim
+	KeepTurns int
+
+	// max length in characters of the tool results of the earlier turns once compacted
+	ToolResultLength int
+}
+
+var DefaultContextStrategy = &ContextStrategy{
+	Strategy:         CONTEXT_STRATEGY_SUMMARIZE,
+	Budget:           0.75,
+	KeepTurns:        4,
+	ToolResultLength: 1000,
+}
+
+var (
+	contextStrategies = map[string]*ContextStrategy{
+		// tool results hold the retrieved documents, so they are compacted aggressively
+		"rag": {
+			Strategy:         CONTEXT_STRATEGY_SUMMARIZE,
+			Budget:           0.7,
+			KeepTurns:        3,
+			ToolResultLength: 500,
+		},
+	}
+	contextStrategiesMu sync.RWMutex
+)
+
+// Sets the context strategy of a conversation type
+func SetContextStrategy(conversationType string, strategy *ContextStrategy) {
+	contextStrategiesMu.Lock()
+	defer contextStrategiesMu.Unlock()
+	contextStrategies[conversationType] = strategy
+}
+
+// Gets the context strategy of a conversation type, or the default when it has none
+func GetContextStrategy(conversationType string) *ContextStrategy {
+	contextStrategiesMu.RLock()
+	defer contextStrategiesMu.RUnlock()
+	if strategy, ok := contextStrategies[conversationType]; ok {
+		return strategy
+	}
+	return DefaultContextStrategy
+}
+
+// the number of tokens the messages can use with the model. 0 when the model has no limit
+func (s *ContextStrategy) tokenLimit(model *llm.LLM) int32 {
+	if model == nil || model.AvailableModel == nil {
+		return 0
+	}
+	return int32(float64(model.AvailableModel.InputTokenLimit) * s.Budget)
+}
+
+// Creates the messages that are sent to the model from the messages of the conversation
+// and the message being sent. The passed messages are not modified
+func (c *Conversation) contextMessages(
+	ctx context.Context,
+	db queries.DBTX,
+	model *llm.LLM,
+	messages []*gollm.Message,
+) ([]*gollm.Message, error) {
+	strategy := GetContextStrategy(c.ConversationType)
+	logger := c.logger.With("strategy", strategy.Strategy)
+
+	window := c.window(messages)
+	limit := strategy.tokenLimit(model)
+	if limit <= 0 {
+		return window, nil
+	}
+	estimate := func(msgs []*gollm.Message) int32 {
+		return estimateTokens(model, msgs)
+	}
+	if estimate(window) <= limit {
+		return window, nil
+	}
+
+	// compact the tool results of the earlier turns
+	logger.InfoContext(ctx, "The conversation does not fit in the context window, compacting ...", "limit", limit)
+	window = compactToolResults(window, keptTurnStart(window, strategy.KeepTurns), strategy.ToolResultLength)
+	if estimate(window) <= limit {
+		return window, nil
+	}
+
+	// move the earlier turns into the running summary
+	if strategy.Strategy == CONTEXT_STRATEGY_SUMMARIZE {
+		summarized, err := c.summarize(ctx, db, model, messages, strategy)
+		if err != nil {
+			// the turns are dropped instead
+			logger.ErrorContext(ctx, "failed to summarize the conversation", "error", err)
+		}
+		if summarized {
+			window = c.window(messages)
+			window = compactToolResults(window, keptTurnStart(window, strategy.KeepTurns), strategy.ToolResultLength)
+			if estimate(window) <= limit {
+				return window, nil
+			}
+		}
+	}
+
+	// drop the earlier turns until the conversation fits
+	logger.InfoContext(ctx, "Dropping the earlier turns of the conversation ...")
+	window = dropTurns(window, func(msgs []*gollm.Message) bool { return estimate(msgs) <= limit })
+	if estimate(window) > limit {
+		logger.WarnContext(ctx, "The latest turn does not fit in the context window", "limit", limit)
+	}
+	return window, nil
+}
+
+// The system message with the running summary, followed by the messages that are not covered
+// by the summary
+func (c *Conversation) window(messages []*gollm.Message) []*gollm.Message {
+	if len(messages) == 0 || messages[0].Role != gollm.RoleSystem {
+		return messages
+	}
+
+	start := c.summaryStart(messages)
+	window := make([]*gollm.Message, 0, len(messages)-start+1)
+	if start == 1 {
+		window = append(window, messages[0])
+	} else {
+		system := *messages[0]
+		system.Message += fmt.Sprintf(prompts.CONVERSATION_SUMMARY_CONTEXT, c.Summary)
+		window = append(window, &system)
+	}
+	return append(window, messages[start:]...)
+}
+
+// index of the first message that is not covered by the running summary
+func (c *Conversation) summaryStart(messages []*gollm.Message) int {
+	if c.Summary == "" || c.SummaryIndex <= 1 || int(c.SummaryIndex) > len(messages) {
+		return 1
+	}
+	return int(c.SummaryIndex)
+}
+
+/*
+Summarizes the turns before the kept turns into the running summary of the conversation with
+the passed model. The summary is saved on the conversation so the turns are only summarized
+once. Returns false when there are no turns to summarize.
+*/
+func (c *Conversation) summarize(
+	ctx context.Context,
+	db queries.DBTX,
+	model *llm.LLM,
+	messages []*gollm.Message,
+	strategy *ContextStrategy,
+) (bool, error) {
+	start := c.summaryStart(messages)
+	end := start + keptTurnStart(messages[start:], strategy.KeepTurns)
+	if end > len(c.messages) {
+		// only saved messages can be summarized, as the summary index points to them
+		end = len(c.messages)
+	}
+	if end <= start {
+		return false, nil
+	}
+
+	c.logger.InfoContext(ctx, "Summarizing the earlier turns of the conversation ...", "start", start, "end", end)
+	summary := c.Summary
+	if summary == "" {
+		summary = "(empty)"
+	}
+	input := fmt.Sprintf(
+		"Current summary:\n%s\n\nNew messages:\n%s",
+		summary,
+		transcript(messages[start:end], strategy.ToolResultLength),
+	)
+	system, t, err := prompts.Render(ctx, c.logger, db, c.CustomerID, prompts.TEMPLATE_CONVERSATION_SUMMARY, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the summary prompt: %w", err)
+	}
+	response, err := model.SingleCompletion(ctx, c.logger, c.CustomerID, system, input)
+	if err != nil {
+		return false, fmt.Errorf("failed to create the summary: %w", err)
+	}
+	if err := c.RecordPrompt(ctx, db, t); err != nil {
+		return false, err
+	}
+	c.usageRecords = append(c.usageRecords, response.UsageRecord)
+
+	dmodel := queries.New(db)
+	if err := dmodel.UpdateConversationSummary(ctx, &queries.UpdateConversationSummaryParams{
+		ID:           c.ID,
+		Summary:      response.Message.Message,
+		SummaryIndex: int32(end),
+	}); err != nil {
+		return false, fmt.Errorf("failed to save the summary: %w", err)
+	}
+	c.Summary = response.Message.Message
+	c.SummaryIndex = int32(end)
+	return true, nil
+}
+
+// Gets the index of the first message of the latest turns. Everything before it can be
+// compacted. Returns 0 when there are not more turns than kept
+func keptTurnStart(messages []*gollm.Message, keepTurns int) int {
+	turns := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != gollm.RoleUser {
+			continue
+		}
+		turns++
+		if turns == keepTurns {
+			return i
+		}
+	}
+	return 0
+}
+
+// Shortens the tool results before the end index. The messages are copied before changing them
+func compactToolResults(messages []*gollm.Message, end int, length int) []*gollm.Message {
+	response := make([]*gollm.Message, len(messages))
+	copy(response, messages)
+	for i := 0; i < end && i < len(response); i++ {
+		if response[i].Role != gollm.RoleToolResult || utf8.RuneCountInString(response[i].Message) <= length {
+			continue
+		}
+		compacted := *response[i]
+		compacted.Message = string([]rune(compacted.Message)[:length]) + "\n[The rest of this tool result was removed to save space]"
+		response[i] = &compacted
+	}
+	return response
+}
+
+// Removes the earliest turns after the system message until the messages fit. The latest turn
+// is always kept
+func dropTurns(messages []*gollm.Message, fits func(msgs []*gollm.Message) bool) []*gollm.Message {
+	if len(messages) < 2 || fits(messages) {
+		return messages
+	}
+
+	response := messages
+	for i := 2; i < len(messages); i++ {
+		if messages[i].Role != gollm.RoleUser {
+			continue
+		}
+		response = append([]*gollm.Message{messages[0]}, messages[i:]...)
+		if fits(response) {
+			break
+		}
+	}
+	return response
+}
+
+// Estimates the tokens of the messages with the tokenizer of the model. Falls back on 4
+// characters per token when the model has no tokenizer
+func estimateTokens(model *llm.LLM, messages []*gollm.Message) int32 {
+	var total int32
+	for _, item := range messages {
+		content := item.Message
+		if item.Role == gollm.RoleToolCall {
+			if enc, err := json.Marshal(item.ToolArguments); err == nil {
+				content += string(enc)
+			}
+		}
+		tokens, err := model.GetEstimatedTokens(content)
+		if err != nil {
+			tokens = int32(utf8.RuneCountInString(content) / 4)
+		}
+		total += tokens
+	}
+	return total
+}
+
+// Formats the messages for the summary model
+func transcript(messages []*gollm.Message, toolResultLength int) string {
+	buf := new(strings.Builder)
+	for _, item := range messages {
+		switch item.Role {
+		case gollm.RoleUser:
+			buf.WriteString(fmt.Sprintf("User: %s\n\n", item.Message))
+		case gollm.RoleAI:
+			buf.WriteString(fmt.Sprintf("Assistant: %s\n\n", item.Message))
+		case gollm.RoleToolCall:
+			args, _ := json.Marshal(item.ToolArguments)
+			buf.WriteString(fmt.Sprintf("Assistant used the tool %s with: %s\n\n", item.ToolName, args))
+		case gollm.RoleToolResult:
+			content := item.Message
+			if utf8.RuneCountInString(content) > toolResultLength {
+				content = string([]rune(content)[:toolResultLength]) + "..."
+			}
+			buf.WriteString(fmt.Sprintf("Result of the tool %s: %s\n\n", item.ToolName, content))
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,70 @@
+package conversation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/stretchr/testify/require"
+)
+
+// a conversation with a system message and a tool call in every turn
+func contextTestMessages(turns int) []*gollm.Message {
+	messages := []*gollm.Message{{Role: gollm.RoleSystem, Message: "system"}}
+	for i := 0; i < turns; i++ {
+		messages = append(messages,
+			&gollm.Message{Role: gollm.RoleUser, Message: "question"},
+			&gollm.Message{Role: gollm.RoleToolCall, ToolName: "vector_query"},
+			&gollm.Message{Role: gollm.RoleToolResult, ToolName: "vector_query", Message: strings.Repeat("a", 100)},
+			&gollm.Message{Role: gollm.RoleAI, Message: "answer"},
+		)
+	}
+	return messages
+}
+
+func TestKeptTurnStart(t *testing.T) {
+	messages := contextTestMessages(3)
+	require.Equal(t, 9, keptTurnStart(messages, 1))
+	require.Equal(t, 5, keptTurnStart(messages, 2))
+	require.Equal(t, 1, keptTurnStart(messages, 3))
+	require.Equal(t, 0, keptTurnStart(messages, 4))
+}
+
+func TestCompactToolResults(t *testing.T) {
+	messages := contextTestMessages(2)
+	compacted := compactToolResults(messages, keptTurnStart(messages, 1), 10)
+
+	// only the results of the earlier turns are compacted, without changing the passed messages
+	require.True(t, strings.HasPrefix(compacted[3].Message, strings.Repeat("a", 10)+"\n"))
+	require.Equal(t, strings.Repeat("a", 100), messages[3].Message)
+	require.Equal(t, strings.Repeat("a", 100), compacted[7].Message)
+}
+
+func TestDropTurns(t *testing.T) {
+	messages := contextTestMessages(3)
+
+	// drops whole turns, keeping the system message
+	dropped := dropTurns(messages, func(msgs []*gollm.Message) bool { return len(msgs) <= 9 })
+	require.Len(t, dropped, 9)
+	require.Equal(t, gollm.RoleSystem, dropped[0].Role)
+	require.Equal(t, gollm.RoleUser, dropped[1].Role)
+
+	// the latest turn is kept when nothing fits
+	dropped = dropTurns(messages, func(msgs []*gollm.Message) bool { return false })
+	require.Len(t, dropped, 5)
+}
+
+func TestConversationWindow(t *testing.T) {
+	messages := contextTestMessages(3)
+	conv := &Conversation{Conversation: &queries.Conversation{}}
+	require.Equal(t, messages, conv.window(messages))
+
+	// the summarized messages are replaced by the summary in the system message
+	conv.Summary = "The user asked two questions"
+	conv.SummaryIndex = 9
+	window := conv.window(messages)
+	require.Len(t, window, 5)
+	require.Contains(t, window[0].Message, conv.Summary)
+	require.Equal(t, "system", messages[0].Message)
+}
@@ -3,6 +3,7 @@ package conversation
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/jake-landersweb/gollm/v2/src/gollm"
 	"github.com/jake-landersweb/gollm/v2/src/tokens"
 	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
 	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
 	"github.com/sapphirenw/ai-content-creation-api/src/utils"
@@ -18,7 +20,9 @@ import (
 type Conversation struct {
 	*queries.Conversation
 	messages     []*gollm.Message      // internal message conversation stored
+	messageIds   []uuid.UUID           // ids of the messages, the last id is the active message of the conversation
 	usageRecords []*tokens.UsageRecord // token records stored with this conversation. Ephemeral, not sourced from the database on re-load
+	citations    map[int][]*Citation   // citations of the AI messages by message index
 	logger       *slog.Logger
 	New          bool // whether the conversation was created in this request or not
 }
@@ -62,7 +66,8 @@ func GoLLMMessageFromDB(cm *queries.ConversationMessage) *gollm.Message {
 }
 
 // Attemps to parse the conversationId passed to it and fetch a conversation.
-// If no conversation exists, then a new one will be created and returned.
+// If no conversation exists, then a new one will be created and returned with the version
+// of the system prompt the customer uses, which is recorded on the conversation.
 // No conversation is created on any errors
 func AutoConversation(
 	ctx context.Context,
@@ -70,18 +75,26 @@ func AutoConversation(
 	db queries.DBTX,
 	customerId uuid.UUID,
 	conversationId string,
-	systemMessage string,
+	systemPrompt prompts.TemplateName,
 	title string,
 	conversationType string,
 ) (*Conversation, error) {
 	var conv *Conversation
 	var err error
 	if conversationId == "" {
+		systemMessage, system, err := prompts.Render(ctx, logger, db, customerId, systemPrompt, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the system prompt: %w", err)
+		}
+
 		// create a new conversation
 		conv, err = CreateConversation(ctx, logger, db, customerId, systemMessage, title, conversationType)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create the conversation: %w", err)
 		}
+		if err := conv.RecordPrompt(ctx, db, system); err != nil {
+			return nil, err
+		}
 	} else {
 		if _, err := uuid.Parse(conversationId); err != nil {
 			return nil, fmt.Errorf("failed to parse the conversationId: '%s'", conversationId)
@@ -125,6 +138,8 @@ func CreateConversation(
 	conv := &Conversation{
 		Conversation: conversation,
 		messages:     make([]*gollm.Message, 0),
+		messageIds:   make([]uuid.UUID, 0),
+		citations:    make(map[int][]*Citation),
 		logger:       logger.With("conversationId", conversation.ID.String()),
 		New:          true,
 	}
@@ -137,7 +152,7 @@ func CreateConversation(
 	return conv, nil
 }
 
-// Fetches a conversation and messages from a given conversationID
+// Fetches a conversation and the messages of its active branch from a given conversationID
 func GetConversation(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -152,26 +167,43 @@ func GetConversation(
 		return nil, fmt.Errorf("failed to get the conversation: %w", err)
 	}
 
-	// get the messages
-	msgs, err := dmodel.GetConversationMessages(ctx, conversationId)
+	// get the messages of the active branch
+	var msgs []*queries.ConversationMessage
+	if activeId := utils.PGXUUIDToGoogleUUID(conv.ActiveMessageID); activeId != nil {
+		msgs, err = dmodel.GetConversationBranch(ctx, *activeId)
+	} else {
+		msgs, err = dmodel.GetConversationMessages(ctx, conversationId)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversation messages: %w", err)
 	}
 
 	logger.DebugContext(ctx, "Fetched messages from database", "length", len(msgs))
 
-	// make the needed internal lists
-	messages := make([]*gollm.Message, 0)
-	for _, item := range msgs {
-		messages = append(messages, GoLLMMessageFromDB(item))
-	}
-
-	return &Conversation{
+	c := &Conversation{
 		Conversation: conv,
-		messages:     messages,
 		logger:       logger,
 		New:          false,
-	}, nil
+	}
+	c.setMessages(msgs)
+	return c, nil
+}
+
+// replaces the internal lists with the messages of a branch
+func (c *Conversation) setMessages(msgs []*queries.ConversationMessage) {
+	c.messages = make([]*gollm.Message, 0, len(msgs))
+	c.messageIds = make([]uuid.UUID, 0, len(msgs))
+	c.citations = make(map[int][]*Citation)
+	for i, item := range msgs {
+		c.messages = append(c.messages, GoLLMMessageFromDB(item))
+		c.messageIds = append(c.messageIds, item.ID)
+
+		// okay for this to fail
+		var cites []*Citation
+		if err := json.Unmarshal(item.Citations, &cites); err == nil && len(cites) != 0 {
+			c.citations[i] = cites
+		}
+	}
 }
 
 // Contains a JSON argument that should not be exposed if not necessary for normal conversations.
@@ -184,60 +216,164 @@ func (c *Conversation) internalCompletion(
 	tools []*gollm.Tool,
 	requiredTool *gollm.Tool,
 	schema string,
-) (*gollm.CompletionResponse, error) {
+	handler llm.StreamHandler,
+) (*llm.StreamResponse, error) {
+	pending := make([]*gollm.Message, 0, 1)
+	if message != nil {
+		pending = append(pending, message)
+	}
+	response, err := c.complete(ctx, db, model, pending, tools, requiredTool, schema, handler)
+	if err != nil {
+		if response != nil && ctx.Err() != nil {
+			return nil, c.saveCancelled(ctx, db, model, message, response)
+		}
+		return nil, err
+	}
+	if err := c.saveCompletion(ctx, db, model, message, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Runs the completion on the messages of the conversation followed by the pending messages,
+// and reports the usage. Nothing is saved to the conversation. On a failed stream the partial
+// response is returned with the error
+func (c *Conversation) complete(
+	ctx context.Context,
+	db queries.DBTX,
+	model *llm.LLM,
+	pending []*gollm.Message,
+	tools []*gollm.Tool,
+	requiredTool *gollm.Tool,
+	schema string,
+	handler llm.StreamHandler,
+) (*llm.StreamResponse, error) {
 	logger := c.logger.With("model", model.Llm.ID.String())
 
-	// create a copy of the messages array
-	messages := make([]*gollm.Message, len(c.messages))
+	// create a copy of the messages array with the pending messages
+	messages := make([]*gollm.Message, len(c.messages), len(c.messages)+len(pending))
 	copy(messages, c.messages)
-	if message != nil {
-		// add the passed message
-		messages = append(messages, message)
-	}
+	messages = append(messages, pending...)
 
 	// check the conversation state for mismatched state
 	if messages[len(messages)-1].Role != gollm.RoleToolResult && messages[len(messages)-1].Role != gollm.RoleUser {
 		return nil, fmt.Errorf("lastest message role: %s", messages[len(messages)-1].Role.ToString())
 	}
 
-	// run the completion
-	logger.InfoContext(ctx, "Beginning conversation completion ...")
-	response, err := model.Completion(ctx, c.logger, &llm.CompletionArgs{
+	// fit the messages into the context window of the model
+	messages, err := c.contextMessages(ctx, db, model, messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the context: %w", err)
+	}
+
+	// run the completion, streaming the response when a handler is passed
+	logger.InfoContext(ctx, "Beginning conversation completion ...", "stream", handler != nil)
+	args := &llm.CompletionArgs{
 		CustomerID:   c.CustomerID.String(),
 		Messages:     messages,
 		Tools:        tools,
 		RequiredTool: requiredTool,
 		Json:         schema != "",
 		JsonSchema:   schema,
-	})
+	}
+	var response *llm.StreamResponse
+	if handler == nil {
+		var completion *gollm.CompletionResponse
+		var used *llm.LLM
+		completion, used, err = model.CompletionWithModel(ctx, c.logger, args)
+		if err == nil {
+			response = &llm.StreamResponse{CompletionResponse: completion, Model: used}
+			if completion.Message.Role == gollm.RoleToolCall {
+				response.ToolCalls = []*gollm.Message{completion.Message}
+			}
+		}
+	} else {
+		response, err = model.CompletionStream(ctx, c.logger, args, handler)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed conversation completion: %w", err)
+		return response, fmt.Errorf("failed conversation completion: %w", err)
 	}
 
 	// report the usage
 	c.usageRecords = append(c.usageRecords, response.UsageRecord)
 	logger.InfoContext(ctx, "Reporting the usage ...")
 	if err := utils.ReportUsage(ctx, c.logger, db, c.CustomerID, c.usageRecords, c.Conversation); err != nil {
-		c.messages = c.messages[:len(c.messages)-1]
+		c.truncate(len(c.messages) - 1)
 		return nil, fmt.Errorf("failed to save the token usage")
 	}
+	return response, nil
+}
+
+// saves the input message and the output of the completion to the conversation
+func (c *Conversation) saveCompletion(
+	ctx context.Context,
+	db queries.DBTX,
+	model *llm.LLM,
+	message *gollm.Message,
+	response *llm.StreamResponse,
+) error {
+	logger := c.logger.With("model", model.Llm.ID.String())
 
 	// add messages to the conversation
 	if message != nil {
 		logger.InfoContext(ctx, "Saving the input message ...")
 		if err := c.SaveMessage(ctx, db, model, message); err != nil {
-			c.messages = c.messages[:len(c.messages)-1]
-			return nil, fmt.Errorf("failed to save the input message to the conversation: %w", err)
+			c.truncate(len(c.messages) - 1)
+			return fmt.Errorf("failed to save the input message to the conversation: %w", err)
 		}
 	}
 	logger.InfoContext(ctx, "Saving the output message ...")
-	if err := c.SaveMessage(ctx, db, model, response.Message); err != nil {
-		c.messages = c.messages[:len(c.messages)-1]
-		return nil, fmt.Errorf("failed to save the output message to the conversation: %w", err)
+	outputs := response.ToolCalls
+	if len(outputs) == 0 {
+		outputs = []*gollm.Message{response.Message}
+	}
+	for _, item := range outputs {
+		if err := c.SaveMessage(ctx, db, responseModel(model, response), item); err != nil {
+			c.truncate(len(c.messages) - 1)
+			return fmt.Errorf("failed to save the output message to the conversation: %w", err)
+		}
 	}
 
 	logger.InfoContext(ctx, "Successfully saved conversation")
-	return response, nil
+	return nil
+}
+
+// persists what was generated before the completion was cancelled. The request context is
+// already done, so the writes run on a context that is detached from the cancellation
+func (c *Conversation) saveCancelled(
+	ctx context.Context,
+	db queries.DBTX,
+	model *llm.LLM,
+	message *gollm.Message,
+	response *llm.StreamResponse,
+) error {
+	cause := ctx.Err()
+	ctx = context.WithoutCancel(ctx)
+	logger := c.logger.With("model", model.Llm.ID.String())
+	logger.InfoContext(ctx, "The completion was cancelled, saving the partial response ...")
+
+	c.usageRecords = append(c.usageRecords, response.UsageRecord)
+	if err := utils.ReportUsage(ctx, c.logger, db, c.CustomerID, c.usageRecords, c.Conversation); err != nil {
+		return fmt.Errorf("failed to save the token usage of the cancelled completion: %w", err)
+	}
+	if message != nil {
+		if err := c.SaveMessage(ctx, db, model, message); err != nil {
+			return fmt.Errorf("failed to save the input message to the conversation: %w", err)
+		}
+	}
+	if err := c.SaveCancelledMessage(ctx, db, responseModel(model, response), response.Message); err != nil {
+		return fmt.Errorf("failed to save the partial message to the conversation: %w", err)
+	}
+
+	return fmt.Errorf("the completion was cancelled: %w", cause)
+}
+
+// the model that generated the response, which differs from the called model when it fell back
+func responseModel(model *llm.LLM, response *llm.StreamResponse) *llm.LLM {
+	if response.Model != nil {
+		return response.Model
+	}
+	return model
 }
 
 // Runs a completion against the model, and automatically saves the response message into the
@@ -250,76 +386,68 @@ func (c *Conversation) Completion(
 	tools []*gollm.Tool,
 	requiredTool *gollm.Tool,
 ) (*gollm.CompletionResponse, error) {
-	response, err := c.internalCompletion(ctx, db, model, message, tools, requiredTool, "")
+	response, err := c.internalCompletion(ctx, db, model, message, tools, requiredTool, "", nil)
 	if err != nil {
 		if err := c.ReportError(ctx, db, err); err != nil {
 			return nil, slogger.Error(ctx, c.logger, "failed to report the internal error for the convertation", err)
 		}
 		return nil, slogger.Error(ctx, c.logger, "failed the internal completion on the converstaion", err)
 	}
-	return response, nil
+	return response.CompletionResponse, nil
 }
 
-// Send a JSON completion against the model where the response is automatically serialized
-// from the response message. This function calls `Conversation.Completion` under the hood.
-// Note: This will not response with the entire response object as seen in Completion. Ensure
-// there is no information in this object that you need.
-func JsonCompletion[T any](
-	conv *Conversation,
+// Same as `Completion`, but the response is streamed to the handler as it is generated.
+// The assembled message is saved into the database once the stream ends. When the model
+// calls several tools, every call is saved in order
+func (c *Conversation) CompletionStream(
 	ctx context.Context,
 	db queries.DBTX,
 	model *llm.LLM,
 	message *gollm.Message,
 	tools []*gollm.Tool,
-	schema string,
-) (*T, error) {
-	// create a completion
-	response, err := jsonCompletion[T](conv, ctx, db, model, message, tools, schema)
+	requiredTool *gollm.Tool,
+	handler llm.StreamHandler,
+) (*llm.StreamResponse, error) {
+	response, err := c.internalCompletion(ctx, db, model, message, tools, requiredTool, "", handler)
 	if err != nil {
-		// report the error on the conversation
-		if err := conv.ReportError(ctx, db, err); err != nil {
-			return nil, slogger.Error(ctx, conv.logger, "failed to report the internal error for the convertation", err)
+		// a cancelled stream is requested by the user, so it is not an error on the conversation
+		if errors.Is(err, context.Canceled) {
+			return nil, err
 		}
-		return nil, err
+		if err := c.ReportError(ctx, db, err); err != nil {
+			return nil, slogger.Error(ctx, c.logger, "failed to report the internal error for the convertation", err)
+		}
+		return nil, slogger.Error(ctx, c.logger, "failed the internal streamed completion on the converstaion", err)
 	}
 	return response, nil
 }
 
-func jsonCompletion[T any](
-	conv *Conversation,
+// Adds a message to the internal messages array and saves the messages to the database
+func (c *Conversation) SaveMessage(
 	ctx context.Context,
 	db queries.DBTX,
 	model *llm.LLM,
 	message *gollm.Message,
-	tools []*gollm.Tool,
-	schema string,
-) (*T, error) {
-	// check the emssage
-	if message.Role == gollm.RoleToolCall || message.Role == gollm.RoleToolResult {
-		return nil, fmt.Errorf("the role cannot be a tool result or response to use JSON mode")
-	}
-
-	// create a completion
-	response, err := conv.internalCompletion(ctx, db, model, message, tools, nil, schema)
-	if err != nil {
-		return nil, err
-	}
-
-	// serialize the response
-	var resp T
-	if err := json.Unmarshal([]byte(response.Message.Message), &resp); err != nil {
-		return nil, fmt.Errorf("failed to serialize the JSON: %w", err)
-	}
+) error {
+	return c.saveMessage(ctx, db, model, message, false)
+}
 
-	return &resp, nil
+// Same as `SaveMessage`, but marks the message as cut short by the user cancelling it
+func (c *Conversation) SaveCancelledMessage(
+	ctx context.Context,
+	db queries.DBTX,
+	model *llm.LLM,
+	message *gollm.Message,
+) error {
+	return c.saveMessage(ctx, db, model, message, true)
 }
 
-// Adds a message to the internal messages array and saves the messages to the database
-func (c *Conversation) SaveMessage(
+func (c *Conversation) saveMessage(
 	ctx context.Context,
 	db queries.DBTX,
 	model *llm.LLM,
 	message *gollm.Message,
+	cancelled bool,
 ) error {
 	input := &queries.CreateConversationMessageParams{
 		ConversationID: c.ID,
@@ -328,6 +456,12 @@ func (c *Conversation) SaveMessage(
 		Index:          int32(len(c.messages)),
 		ToolUseID:      message.ToolUseID,
 		ToolName:       message.ToolName,
+		IsCancelled:    cancelled,
+	}
+
+	// the message continues the active branch
+	if len(c.messageIds) != 0 {
+		input.ParentID = utils.GoogleUUIDToPGXUUID(c.messageIds[len(c.messageIds)-1])
 	}
 
 	if model != nil {
@@ -355,13 +489,20 @@ func (c *Conversation) SaveMessage(
 
 	// post to the database
 	dmodel := queries.New(db)
-	_, err := dmodel.CreateConversationMessage(ctx, input)
+	saved, err := dmodel.CreateConversationMessage(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to save the message: %w", err)
 	}
+	if err := dmodel.SetConversationActiveMessage(ctx, &queries.SetConversationActiveMessageParams{
+		ID:              c.ID,
+		ActiveMessageID: utils.GoogleUUIDToPGXUUID(saved.ID),
+	}); err != nil {
+		return fmt.Errorf("failed to set the active message: %w", err)
+	}
 
 	// add the message to the internal array
 	c.messages = append(c.messages, message)
+	c.messageIds = append(c.messageIds, saved.ID)
 	return nil
 }
 
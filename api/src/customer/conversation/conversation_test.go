@@ -2,10 +2,8 @@ package conversation
 
 import (
 	"context"
-	"fmt"
 	"testing"
 
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jake-landersweb/gollm/v2/src/gollm"
 	"github.com/sapphirenw/ai-content-creation-api/src/llm"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
@@ -20,9 +18,11 @@ func TestConversation(t *testing.T) {
 	pool := testingutils.GetDatabase(t, ctx)
 	c := testingutils.GetTestCustomer(t, ctx, pool)
 
-	// get a default llm
-	model, err := llm.GetLLM(ctx, pool, c.ID, pgtype.UUID{})
-	require.NoError(t, err)
+	// use a scripted model so the test runs offline
+	row := testingutils.GetMockLLM(t, ctx, pool, c, "mock-conversation")
+	model := llm.FromObjects(&row.Llm, &row.AvailableModel)
+	llm.Mock.Queue("mock-conversation", &llm.MockResponse{Message: "Ahoy! What be yer business?"})
+	defer llm.Mock.Reset()
 
 	conv, err := CreateConversation(ctx, logger, pool, c.ID, "You are a pirate", "Test Conversation", "Testing")
 	require.NoError(t, err)
@@ -32,7 +32,7 @@ func TestConversation(t *testing.T) {
 	response, err := conv.Completion(ctx, pool, model, userMsg, nil, nil)
 	require.NoError(t, err)
 
-	fmt.Printf("MODEL RESPONSE: %s\n", response.Message.Message)
+	require.Equal(t, "Ahoy! What be yer business?", response.Message.Message)
 
 	// check the records against the database
 	dmodel := queries.New(pool)
@@ -0,0 +1,500 @@
+package conversation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+)
+
+const (
+	// transcript of the active branch
+	EXPORT_FORMAT_MARKDOWN = "markdown"
+	// every message of every branch, with the model settings and the token usage
+	EXPORT_FORMAT_JSON = "json"
+	// one fine-tuning example in the OpenAI chat format for every branch
+	EXPORT_FORMAT_JSONL = "jsonl"
+)
+
+// version of the JSON export. Bump it when a change to the format cannot be imported by the
+// previous version
+const CONVERSATION_EXPORT_VERSION = 1
+
+/*
+A lossless export of a conversation. Every branch is included, so the messages form a tree
+through their parent ids, and the active message is the last message of the active branch.
+
+The ids are the ids of the exported conversation. They are only used to link the messages, as
+new ids are created when the export is imported.
+*/
+type ConversationExport struct {
+	Version          int                `json:"version"`
+	Title            string             `json:"title"`
+	ConversationType string             `json:"conversationType"`
+	SystemMessage    string             `json:"systemMessage"`
+	Metadata         json.RawMessage    `json:"metadata,omitempty"`
+	Summary          string             `json:"summary,omitempty"`
+	SummaryIndex     int32              `json:"summaryIndex,omitempty"`
+	ActiveMessageID  *uuid.UUID         `json:"activeMessageId,omitempty"`
+	CreatedAt        pgtype.Timestamptz `json:"createdAt"`
+	Messages         []*ExportedMessage `json:"messages"` // parents are always before their children
+	Usage            []*ExportedUsage   `json:"usage,omitempty"`
+}
+
+type ExportedMessage struct {
+	ID            uuid.UUID          `json:"id"`
+	ParentID      *uuid.UUID         `json:"parentId,omitempty"`
+	Index         int32              `json:"index"`
+	Role          string             `json:"role"`
+	Message       string             `json:"message"`
+	ToolUseID     string             `json:"toolUseId,omitempty"`
+	ToolName      string             `json:"toolName,omitempty"`
+	ToolArguments json.RawMessage    `json:"toolArguments,omitempty"`
+	ToolResults   json.RawMessage    `json:"toolResults,omitempty"`
+	Model         string             `json:"model,omitempty"`
+	Temperature   float64            `json:"temperature,omitempty"`
+	Instructions  string             `json:"instructions,omitempty"`
+	IsCancelled   bool               `json:"isCancelled,omitempty"`
+	Citations     []*Citation        `json:"citations,omitempty"`
+	CreatedAt     pgtype.Timestamptz `json:"createdAt"`
+}
+
+type ExportedUsage struct {
+	Model        string             `json:"model"`
+	InputTokens  int32              `json:"inputTokens"`
+	OutputTokens int32              `json:"outputTokens"`
+	TotalTokens  int32              `json:"totalTokens"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+}
+
+func (e ConversationExport) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string)
+	if e.Version < 1 || e.Version > CONVERSATION_EXPORT_VERSION {
+		p["version"] = fmt.Sprintf("unsupported version: %d", e.Version)
+	}
+	if len(e.Messages) == 0 {
+		p["messages"] = "cannot be empty"
+	}
+
+	// the expected index of every message by id. The index is the depth of the message in the
+	// tree, so a wrong index is only reported on the message itself and not on its children
+	indexes := make(map[uuid.UUID]int32, len(e.Messages))
+	for i, item := range e.Messages {
+		if _, ok := parseRole(item.Role); !ok {
+			p[fmt.Sprintf("messages[%d].role", i)] = fmt.Sprintf("invalid role: %s", item.Role)
+		}
+		if _, exists := indexes[item.ID]; exists {
+			p[fmt.Sprintf("messages[%d].id", i)] = "is not unique"
+		}
+		var index int32
+		if item.ParentID != nil {
+			parentIndex, ok := indexes[*item.ParentID]
+			if !ok {
+				p[fmt.Sprintf("messages[%d].parentId", i)] = "has to reference an earlier message"
+			}
+			index = parentIndex + 1
+		}
+		if item.Index != index && item.ParentID == nil {
+			p[fmt.Sprintf("messages[%d].index", i)] = "has to be 0 for a message without a parent"
+		} else if item.Index != index {
+			p[fmt.Sprintf("messages[%d].index", i)] = fmt.Sprintf("has to be %d, one more than the parent", index)
+		}
+		indexes[item.ID] = index
+	}
+	if e.ActiveMessageID != nil {
+		if _, ok := indexes[*e.ActiveMessageID]; !ok {
+			p["activeMessageId"] = "has to reference a message"
+		}
+	}
+	return p
+}
+
+// Exports the conversation with every branch and the token usage
+func (c *Conversation) Export(
+	ctx context.Context,
+	db queries.DBTX,
+) (*ConversationExport, error) {
+	dmodel := queries.New(db)
+	msgs, err := dmodel.GetConversationMessages(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the messages: %w", err)
+	}
+	usage, err := dmodel.GetConversationTokenUsage(ctx, utils.GoogleUUIDToPGXUUID(c.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the token usage: %w", err)
+	}
+
+	export := &ConversationExport{
+		Version:          CONVERSATION_EXPORT_VERSION,
+		Title:            c.Title,
+		ConversationType: c.ConversationType,
+		SystemMessage:    c.SystemMessage,
+		Summary:          c.Summary,
+		SummaryIndex:     c.SummaryIndex,
+		ActiveMessageID:  utils.PGXUUIDToGoogleUUID(c.ActiveMessageID),
+		CreatedAt:        c.CreatedAt,
+		Messages:         make([]*ExportedMessage, 0, len(msgs)),
+		Usage:            make([]*ExportedUsage, 0, len(usage)),
+	}
+	if json.Valid(c.Metadata) {
+		export.Metadata = c.Metadata
+	}
+
+	// ordered by index, so the parents are always before their children
+	for _, item := range msgs {
+		msg := &ExportedMessage{
+			ID:           item.ID,
+			ParentID:     utils.PGXUUIDToGoogleUUID(item.ParentID),
+			Index:        item.Index,
+			Role:         item.Role,
+			Message:      item.Message,
+			ToolUseID:    item.ToolUseID,
+			ToolName:     item.ToolName,
+			Model:        item.Model,
+			Temperature:  item.Temperature,
+			Instructions: item.Instructions,
+			IsCancelled:  item.IsCancelled,
+			CreatedAt:    item.CreatedAt,
+		}
+		if json.Valid(item.ToolArguments) {
+			msg.ToolArguments = item.ToolArguments
+		}
+		if json.Valid(item.ToolResults) {
+			msg.ToolResults = item.ToolResults
+		}
+
+		// okay for this to fail
+		json.Unmarshal(item.Citations, &msg.Citations)
+
+		export.Messages = append(export.Messages, msg)
+	}
+
+	for _, item := range usage {
+		export.Usage = append(export.Usage, &ExportedUsage{
+			Model:        item.Model,
+			InputTokens:  item.InputTokens,
+			OutputTokens: item.OutputTokens,
+			TotalTokens:  item.TotalTokens,
+			CreatedAt:    item.CreatedAt,
+		})
+	}
+
+	return export, nil
+}
+
+/*
+Creates a conversation for the customer from an export. The messages and branches are
+recreated with new ids. The token usage is not imported, as it was billed to the customer the
+conversation was exported from, and the messages are not linked to the models of the customer
+as the model ids are not portable.
+*/
+func ImportConversation(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	export *ConversationExport,
+) (*Conversation, error) {
+	if problems := export.Valid(ctx); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid export: %v", problems)
+	}
+
+	dmodel := queries.New(db)
+	title := export.Title
+	if title == "" {
+		title = "(Untitled Conversation)"
+	}
+	conv, err := dmodel.CreateConversation(ctx, &queries.CreateConversationParams{
+		CustomerID:       customerId,
+		Title:            title,
+		ConversationType: export.ConversationType,
+		SystemMessage:    export.SystemMessage,
+		Metadata:         export.Metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the conversation: %w", err)
+	}
+
+	// the exported ids mapped to the new ids
+	ids := make(map[uuid.UUID]uuid.UUID, len(export.Messages))
+	var lastId uuid.UUID
+	for _, item := range export.Messages {
+		input := &queries.CreateConversationMessageParams{
+			ConversationID: conv.ID,
+			Model:          item.Model,
+			Temperature:    item.Temperature,
+			Instructions:   item.Instructions,
+			Role:           item.Role,
+			Message:        item.Message,
+			Index:          item.Index,
+			ToolUseID:      item.ToolUseID,
+			ToolName:       item.ToolName,
+			ToolArguments:  item.ToolArguments,
+			ToolResults:    item.ToolResults,
+			IsCancelled:    item.IsCancelled,
+		}
+		if item.ParentID != nil {
+			input.ParentID = utils.GoogleUUIDToPGXUUID(ids[*item.ParentID])
+		}
+		saved, err := dmodel.CreateConversationMessage(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save the message: %w", err)
+		}
+		ids[item.ID] = saved.ID
+		lastId = saved.ID
+
+		if len(item.Citations) != 0 {
+			enc, err := json.Marshal(item.Citations)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode the citations: %w", err)
+			}
+			if err := dmodel.UpdateConversationMessageCitations(ctx, &queries.UpdateConversationMessageCitationsParams{
+				ID:        saved.ID,
+				Citations: enc,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to save the citations: %w", err)
+			}
+		}
+	}
+
+	// the last imported message is active when the export has no active message
+	activeId := lastId
+	if export.ActiveMessageID != nil {
+		activeId = ids[*export.ActiveMessageID]
+	}
+	if err := dmodel.SetConversationActiveMessage(ctx, &queries.SetConversationActiveMessageParams{
+		ID:              conv.ID,
+		ActiveMessageID: utils.GoogleUUIDToPGXUUID(activeId),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to set the active message: %w", err)
+	}
+
+	if export.Summary != "" {
+		if err := dmodel.UpdateConversationSummary(ctx, &queries.UpdateConversationSummaryParams{
+			ID:           conv.ID,
+			Summary:      export.Summary,
+			SummaryIndex: export.SummaryIndex,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to save the summary: %w", err)
+		}
+	}
+
+	return GetConversation(ctx, logger.With("conversationId", conv.ID.String()), db, conv.ID)
+}
+
+// Gets the messages of the branch that ends with the message, from the first message
+func (e *ConversationExport) branch(leafId uuid.UUID) []*ExportedMessage {
+	byId := make(map[uuid.UUID]*ExportedMessage, len(e.Messages))
+	for _, item := range e.Messages {
+		byId[item.ID] = item
+	}
+
+	response := make([]*ExportedMessage, 0)
+	for msg, ok := byId[leafId]; ok; {
+		response = append(response, msg)
+		if msg.ParentID == nil {
+			break
+		}
+		msg, ok = byId[*msg.ParentID]
+	}
+
+	// reverse to start from the first message
+	for i, j := 0, len(response)-1; i < j; i, j = i+1, j-1 {
+		response[i], response[j] = response[j], response[i]
+	}
+	return response
+}
+
+// Gets the messages of the active branch. The branch of the last message is used when the
+// export has no active message
+func (e *ConversationExport) activeBranch() []*ExportedMessage {
+	if len(e.Messages) == 0 {
+		return nil
+	}
+	if e.ActiveMessageID != nil {
+		return e.branch(*e.ActiveMessageID)
+	}
+	return e.branch(e.Messages[len(e.Messages)-1].ID)
+}
+
+// Gets the ids of the last messages of every branch, in the order they were exported
+func (e *ConversationExport) leaves() []uuid.UUID {
+	parents := make(map[uuid.UUID]bool, len(e.Messages))
+	for _, item := range e.Messages {
+		if item.ParentID != nil {
+			parents[*item.ParentID] = true
+		}
+	}
+
+	response := make([]uuid.UUID, 0)
+	for _, item := range e.Messages {
+		if !parents[item.ID] {
+			response = append(response, item.ID)
+		}
+	}
+	return response
+}
+
+// Formats the active branch as a Markdown transcript. The system message is left out
+func (e *ConversationExport) Markdown() string {
+	buf := new(strings.Builder)
+	buf.WriteString(fmt.Sprintf("# %s\n\n", e.Title))
+
+	for _, item := range e.activeBranch() {
+		role, _ := parseRole(item.Role)
+		switch role {
+		case gollm.RoleUser:
+			buf.WriteString(fmt.Sprintf("## User\n\n%s\n\n", item.Message))
+		case gollm.RoleAI:
+			buf.WriteString(fmt.Sprintf("## Assistant\n\n%s\n\n", item.Message))
+			if item.IsCancelled {
+				buf.WriteString("*The response was cancelled.*\n\n")
+			}
+			if len(item.Citations) != 0 {
+				buf.WriteString("**Sources**\n\n")
+				for _, cite := range item.Citations {
+					buf.WriteString(fmt.Sprintf("%d. %s\n", cite.Number, citationMarkdown(cite)))
+				}
+				buf.WriteString("\n")
+			}
+		case gollm.RoleToolCall:
+			buf.WriteString(fmt.Sprintf("### Tool call: %s\n\n```json\n%s\n```\n\n", item.ToolName, indentJSON(item.ToolArguments)))
+		case gollm.RoleToolResult:
+			content := item.Message
+			if content == "" {
+				content = indentJSON(item.ToolResults)
+			}
+			buf.WriteString(fmt.Sprintf("### Tool result: %s\n\n```\n%s\n```\n\n", item.ToolName, content))
+		}
+	}
+
+	return strings.TrimSpace(buf.String()) + "\n"
+}
+
+func citationMarkdown(cite *Citation) string {
+	title := cite.Title
+	if title == "" {
+		title = cite.SourceType
+	}
+	if cite.Url != "" {
+		return fmt.Sprintf("[%s](%s)", title, cite.Url)
+	}
+	return title
+}
+
+func indentJSON(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "{}"
+	}
+	buf := new(bytes.Buffer)
+	if err := json.Indent(buf, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIMessage struct {
+	Role       string            `json:"role"`
+	Content    *string           `json:"content"`
+	ToolCalls  []*openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+	Weight     *int              `json:"weight,omitempty"`
+}
+
+/*
+Formats every branch as a fine-tuning example in the OpenAI chat format, one example per line.
+Consecutive tool calls are merged into a single assistant message, and cancelled responses are
+kept with a weight of 0 so they are not trained on. Examples end with the last assistant message
+of the branch, and branches without an assistant message are left out.
+*/
+func (e *ConversationExport) JSONL() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	for _, leaf := range e.leaves() {
+		messages := openAIMessages(e.branch(leaf))
+		if len(messages) == 0 {
+			continue
+		}
+		if err := enc.Encode(map[string]any{"messages": messages}); err != nil {
+			return nil, fmt.Errorf("failed to encode the example: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func openAIMessages(branch []*ExportedMessage) []*openAIMessage {
+	response := make([]*openAIMessage, 0, len(branch))
+	lastAssistant := -1
+	for _, item := range branch {
+		content := item.Message
+		role, _ := parseRole(item.Role)
+		switch role {
+		case gollm.RoleSystem:
+			response = append(response, &openAIMessage{Role: "system", Content: &content})
+		case gollm.RoleUser:
+			response = append(response, &openAIMessage{Role: "user", Content: &content})
+		case gollm.RoleAI:
+			msg := &openAIMessage{Role: "assistant", Content: &content}
+			if item.IsCancelled {
+				weight := 0
+				msg.Weight = &weight
+			}
+			response = append(response, msg)
+			lastAssistant = len(response) - 1
+		case gollm.RoleToolCall:
+			call := &openAIToolCall{ID: item.ToolUseID, Type: "function"}
+			call.Function.Name = item.ToolName
+			call.Function.Arguments = "{}"
+			if len(item.ToolArguments) != 0 {
+				call.Function.Arguments = string(item.ToolArguments)
+			}
+
+			// parallel tool calls are sent in the same message
+			if last := len(response) - 1; last >= 0 && len(response[last].ToolCalls) != 0 {
+				response[last].ToolCalls = append(response[last].ToolCalls, call)
+			} else {
+				response = append(response, &openAIMessage{Role: "assistant", ToolCalls: []*openAIToolCall{call}})
+			}
+			lastAssistant = len(response) - 1
+		case gollm.RoleToolResult:
+			if content == "" {
+				content = string(item.ToolResults)
+			}
+			response = append(response, &openAIMessage{Role: "tool", Content: &content, ToolCallID: item.ToolUseID})
+		}
+	}
+	return response[:lastAssistant+1]
+}
+
+// parses the role of a stored message
+func parseRole(role string) (gollm.Role, bool) {
+	for _, item := range []gollm.Role{
+		gollm.RoleSystem,
+		gollm.RoleUser,
+		gollm.RoleAI,
+		gollm.RoleToolCall,
+		gollm.RoleToolResult,
+	} {
+		if item.ToString() == role {
+			return item, true
+		}
+	}
+	return gollm.RoleSystem, false
+}
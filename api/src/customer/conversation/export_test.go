@@ -0,0 +1,89 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/stretchr/testify/require"
+)
+
+// an export with a tool call, and a regenerated response that was cancelled
+func exportTestConversation() *ConversationExport {
+	system := &ExportedMessage{ID: uuid.New(), Role: gollm.RoleSystem.ToString(), Message: "system"}
+	user := &ExportedMessage{ID: uuid.New(), ParentID: &system.ID, Index: 1, Role: gollm.RoleUser.ToString(), Message: "question"}
+	call := &ExportedMessage{ID: uuid.New(), ParentID: &user.ID, Index: 2, Role: gollm.RoleToolCall.ToString(), ToolUseID: "call_1", ToolName: "vector_query", ToolArguments: json.RawMessage(`{"query":"a"}`)}
+	result := &ExportedMessage{ID: uuid.New(), ParentID: &call.ID, Index: 3, Role: gollm.RoleToolResult.ToString(), ToolUseID: "call_1", ToolName: "vector_query", Message: "[1] source"}
+	answer := &ExportedMessage{ID: uuid.New(), ParentID: &result.ID, Index: 4, Role: gollm.RoleAI.ToString(), Message: "answer [1]", Citations: []*Citation{{Number: 1, SourceType: CITATION_SOURCE_WEB, Title: "Source", Url: "https://example.com"}}}
+	cancelled := &ExportedMessage{ID: uuid.New(), ParentID: &result.ID, Index: 4, Role: gollm.RoleAI.ToString(), Message: "partial", IsCancelled: true}
+
+	return &ConversationExport{
+		Version:         CONVERSATION_EXPORT_VERSION,
+		Title:           "Test",
+		ActiveMessageID: &answer.ID,
+		Messages:        []*ExportedMessage{system, user, call, result, answer, cancelled},
+	}
+}
+
+func TestConversationExportValid(t *testing.T) {
+	export := exportTestConversation()
+	require.Empty(t, export.Valid(context.TODO()))
+
+	// the parents have to be before their children
+	export.Messages[1], export.Messages[2] = export.Messages[2], export.Messages[1]
+	require.Contains(t, export.Valid(context.TODO()), "messages[1].parentId")
+
+	// the index of a message is one more than its parent
+	export = exportTestConversation()
+	export.Messages[2].Index = 5
+	export.Messages[0].Index = 1
+	problems := export.Valid(context.TODO())
+	require.Len(t, problems, 2)
+	require.Contains(t, problems, "messages[0].index")
+	require.Contains(t, problems, "messages[2].index")
+
+	export = exportTestConversation()
+	export.Version = CONVERSATION_EXPORT_VERSION + 1
+	export.Messages[1].Role = "unknown"
+	problems = export.Valid(context.TODO())
+	require.Contains(t, problems, "version")
+	require.Contains(t, problems, "messages[1].role")
+}
+
+func TestConversationExportMarkdown(t *testing.T) {
+	export := exportTestConversation()
+	markdown := export.Markdown()
+
+	require.True(t, strings.HasPrefix(markdown, "# Test\n"))
+	require.NotContains(t, markdown, "system")
+	require.Contains(t, markdown, "### Tool call: vector_query")
+	require.Contains(t, markdown, "1. [Source](https://example.com)")
+	require.NotContains(t, markdown, "partial")
+}
+
+func TestConversationExportJSONL(t *testing.T) {
+	export := exportTestConversation()
+	body, err := export.JSONL()
+	require.NoError(t, err)
+
+	// one example for every branch
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.Len(t, lines, 2)
+
+	var example struct {
+		Messages []*openAIMessage `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &example))
+	require.Len(t, example.Messages, 5)
+	require.Equal(t, "assistant", example.Messages[2].Role)
+	require.Equal(t, `{"query":"a"}`, example.Messages[2].ToolCalls[0].Function.Arguments)
+	require.Equal(t, "call_1", example.Messages[3].ToolCallID)
+	require.Nil(t, example.Messages[4].Weight)
+
+	// the cancelled response is not trained on
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &example))
+	require.Equal(t, 0, *example.Messages[4].Weight)
+}
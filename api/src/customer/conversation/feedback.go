@@ -0,0 +1,283 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+const (
+	FEEDBACK_RATING_POSITIVE int16 = 1
+	FEEDBACK_RATING_NEGATIVE int16 = -1
+)
+
+// max length of the comment of a feedback
+const feedbackCommentLength = 2000
+
+// max number of sources in the feedback analytics
+const feedbackSourcesLimit = 50
+
+// The feedback of the user on an AI message, with the tools and the sources that produced it
+type MessageFeedback struct {
+	MessageID uuid.UUID          `json:"messageId"`
+	LlmID     pgtype.UUID        `json:"llmId"`
+	Rating    int16              `json:"rating"`
+	Comment   string             `json:"comment"`
+	Tools     []string           `json:"tools"`
+	Sources   []*Citation        `json:"sources"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+}
+
+func messageFeedbackFromDB(item *queries.ConversationMessageFeedback) *MessageFeedback {
+	feedback := &MessageFeedback{
+		MessageID: item.ConversationMessageID,
+		LlmID:     item.LlmID,
+		Rating:    item.Rating,
+		Comment:   item.Comment,
+		Tools:     item.Tools,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+	}
+
+	// okay for this to fail
+	json.Unmarshal(item.Sources, &feedback.Sources)
+
+	return feedback
+}
+
+/*
+Saves the feedback of the user on an AI message of any branch of the conversation. The model,
+the tools called in the turn, and the citations of the message are stored with the feedback.
+Feedback on a message that already has feedback replaces it.
+*/
+func (c *Conversation) SaveFeedback(
+	ctx context.Context,
+	db queries.DBTX,
+	messageId uuid.UUID,
+	rating int16,
+	comment string,
+) (*MessageFeedback, error) {
+	dmodel := queries.New(db)
+
+	// ensure the message is part of the conversation
+	if _, err := dmodel.GetConversationMessage(ctx, &queries.GetConversationMessageParams{
+		ID:             messageId,
+		ConversationID: c.ID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get the message: %w", err)
+	}
+	branch, err := dmodel.GetConversationBranch(ctx, messageId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the branch of the message: %w", err)
+	}
+	msg := branch[len(branch)-1]
+	if msg.Role != gollm.RoleAI.ToString() {
+		return nil, fmt.Errorf("feedback can only be given on a message from the AI")
+	}
+
+	sources := msg.Citations
+	if len(sources) == 0 {
+		sources = []byte("[]")
+	}
+	response, err := dmodel.UpsertConversationMessageFeedback(ctx, &queries.UpsertConversationMessageFeedbackParams{
+		ConversationMessageID: msg.ID,
+		ConversationID:        c.ID,
+		CustomerID:            c.CustomerID,
+		LlmID:                 msg.LlmID,
+		Rating:                rating,
+		Comment:               comment,
+		Tools:                 feedbackTools(branch),
+		Sources:               sources,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save the feedback: %w", err)
+	}
+	return messageFeedbackFromDB(response), nil
+}
+
+// Removes the feedback of the user on a message
+func (c *Conversation) DeleteFeedback(
+	ctx context.Context,
+	db queries.DBTX,
+	messageId uuid.UUID,
+) error {
+	dmodel := queries.New(db)
+	if err := dmodel.DeleteConversationMessageFeedback(ctx, &queries.DeleteConversationMessageFeedbackParams{
+		ConversationMessageID: messageId,
+		ConversationID:        c.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to delete the feedback: %w", err)
+	}
+	return nil
+}
+
+// Gets the feedback on the messages of the active branch by message index
+func (c *Conversation) GetFeedback(
+	ctx context.Context,
+	db queries.DBTX,
+) (map[int]*MessageFeedback, error) {
+	dmodel := queries.New(db)
+	items, err := dmodel.GetConversationFeedback(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the feedback: %w", err)
+	}
+
+	indexes := make(map[uuid.UUID]int, len(c.messageIds))
+	for i, id := range c.messageIds {
+		indexes[id] = i
+	}
+	response := make(map[int]*MessageFeedback)
+	for _, item := range items {
+		if i, ok := indexes[item.ConversationMessageID]; ok {
+			response[i] = messageFeedbackFromDB(item)
+		}
+	}
+	return response, nil
+}
+
+// Gets the names of the tools called in the turn that ends with the last message of the branch
+func feedbackTools(branch []*queries.ConversationMessage) []string {
+	start := len(branch)
+	for start > 0 && branch[start-1].Role != gollm.RoleUser.ToString() {
+		start--
+	}
+
+	tools := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, item := range branch[start:] {
+		if item.Role != gollm.RoleToolCall.ToString() || seen[item.ToolName] {
+			continue
+		}
+		seen[item.ToolName] = true
+		tools = append(tools, item.ToolName)
+	}
+	return tools
+}
+
+type FeedbackStats struct {
+	Total        int64   `json:"total"`
+	Positive     int64   `json:"positive"`
+	Negative     int64   `json:"negative"`
+	Comments     int64   `json:"comments"`
+	PositiveRate float64 `json:"positiveRate"` // fraction of the feedback that is positive
+}
+
+func newFeedbackStats(total int64, positive int64, negative int64, comments int64) FeedbackStats {
+	stats := FeedbackStats{
+		Total:    total,
+		Positive: positive,
+		Negative: negative,
+		Comments: comments,
+	}
+	if total != 0 {
+		stats.PositiveRate = float64(positive) / float64(total)
+	}
+	return stats
+}
+
+type LLMFeedback struct {
+	LlmID pgtype.UUID `json:"llmId"`
+	Title string      `json:"title"`
+	Model string      `json:"model"`
+	FeedbackStats
+}
+
+type ConversationTypeFeedback struct {
+	ConversationType string `json:"conversationType"`
+	FeedbackStats
+}
+
+// The feedback on the messages that cited a document, website page, or feed item
+type SourceFeedback struct {
+	SourceType string    `json:"sourceType"`
+	SourceID   uuid.UUID `json:"sourceId"`
+	Title      string    `json:"title"`
+	FeedbackStats
+}
+
+type FeedbackAnalytics struct {
+	ByLLM              []*LLMFeedback              `json:"byLlm"`
+	ByConversationType []*ConversationTypeFeedback `json:"byConversationType"`
+	BySource           []*SourceFeedback           `json:"bySource"` // most negative feedback first
+}
+
+// Aggregates the feedback of the customer per model configuration, per conversation type, and
+// per cited source. The feedback can be limited to the feedback created between from and to
+func GetFeedbackAnalytics(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	from *time.Time,
+	to *time.Time,
+) (*FeedbackAnalytics, error) {
+	var start, end pgtype.Timestamptz
+	if from != nil {
+		start = pgtype.Timestamptz{Time: *from, Valid: true}
+	}
+	if to != nil {
+		end = pgtype.Timestamptz{Time: *to, Valid: true}
+	}
+
+	dmodel := queries.New(db)
+	llms, err := dmodel.GetFeedbackByLLM(ctx, &queries.GetFeedbackByLLMParams{
+		CustomerID: customerId,
+		Column2:    start,
+		Column3:    end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the feedback by llm: %w", err)
+	}
+	types, err := dmodel.GetFeedbackByConversationType(ctx, &queries.GetFeedbackByConversationTypeParams{
+		CustomerID: customerId,
+		Column2:    start,
+		Column3:    end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the feedback by conversation type: %w", err)
+	}
+	sources, err := dmodel.GetFeedbackBySource(ctx, &queries.GetFeedbackBySourceParams{
+		CustomerID: customerId,
+		Column2:    start,
+		Column3:    end,
+		Limit:      feedbackSourcesLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the feedback by source: %w", err)
+	}
+
+	response := &FeedbackAnalytics{
+		ByLLM:              make([]*LLMFeedback, len(llms)),
+		ByConversationType: make([]*ConversationTypeFeedback, len(types)),
+		BySource:           make([]*SourceFeedback, len(sources)),
+	}
+	for i, item := range llms {
+		response.ByLLM[i] = &LLMFeedback{
+			LlmID:         item.LlmID,
+			Title:         item.Title,
+			Model:         item.Model,
+			FeedbackStats: newFeedbackStats(item.Total, item.Positive, item.Negative, item.Comments),
+		}
+	}
+	for i, item := range types {
+		response.ByConversationType[i] = &ConversationTypeFeedback{
+			ConversationType: item.ConversationType,
+			FeedbackStats:    newFeedbackStats(item.Total, item.Positive, item.Negative, item.Comments),
+		}
+	}
+	for i, item := range sources {
+		response.BySource[i] = &SourceFeedback{
+			SourceType:    item.SourceType,
+			SourceID:      item.SourceID,
+			Title:         item.Title,
+			FeedbackStats: newFeedbackStats(item.Total, item.Positive, item.Negative, item.Comments),
+		}
+	}
+	return response, nil
+}
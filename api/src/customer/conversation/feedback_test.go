@@ -0,0 +1,41 @@
+package conversation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedbackTools(t *testing.T) {
+	branch := []*queries.ConversationMessage{
+		{Role: gollm.RoleUser.ToString()},
+		{Role: gollm.RoleToolCall.ToString(), ToolName: "web_search"},
+		{Role: gollm.RoleToolResult.ToString(), ToolName: "web_search"},
+		{Role: gollm.RoleAI.ToString()},
+		{Role: gollm.RoleUser.ToString()},
+		{Role: gollm.RoleToolCall.ToString(), ToolName: "vector_query"},
+		{Role: gollm.RoleToolResult.ToString(), ToolName: "vector_query"},
+		{Role: gollm.RoleToolCall.ToString(), ToolName: "web_search"},
+		{Role: gollm.RoleToolResult.ToString(), ToolName: "web_search"},
+		{Role: gollm.RoleToolCall.ToString(), ToolName: "vector_query"},
+		{Role: gollm.RoleToolResult.ToString(), ToolName: "vector_query"},
+		{Role: gollm.RoleAI.ToString()},
+	}
+
+	// only the tools of the last turn, once each in the order they were first called
+	require.Equal(t, []string{"vector_query", "web_search"}, feedbackTools(branch))
+	require.Empty(t, feedbackTools(branch[:5]))
+}
+
+func TestFeedbackStats(t *testing.T) {
+	require.Equal(t, 0.75, newFeedbackStats(4, 3, 1, 0).PositiveRate)
+	require.Equal(t, 0.0, newFeedbackStats(0, 0, 0, 0).PositiveRate)
+}
+
+func TestMessageFeedbackRequestValid(t *testing.T) {
+	require.Empty(t, messageFeedbackRequest{Rating: FEEDBACK_RATING_NEGATIVE, Comment: "wrong"}.Valid(context.TODO()))
+	require.Contains(t, messageFeedbackRequest{}.Valid(context.TODO()), "rating")
+}
@@ -3,7 +3,9 @@ package conversation
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httplog/v2"
@@ -18,9 +20,18 @@ import (
 
 func Handler(mux chi.Router) {
 	mux.Get("/", rootHandler(getConversations))
+	mux.Get("/search", rootHandler(searchConversations))
+	mux.Get("/feedback", rootHandler(getFeedbackAnalytics))
+	mux.Post("/import", rootHandler(importConversation))
 
 	mux.Route("/{conversationId}", func(r chi.Router) {
 		r.Get("/", conversationHandler(getConversation))
+		r.Get("/branches", conversationHandler(getConversationBranches))
+		r.Put("/branches/{messageId}", conversationHandler(switchConversationBranch))
+		r.Get("/export", conversationHandler(exportConversation))
+		r.Get("/prompts", conversationHandler(getConversationPrompts))
+		r.Put("/messages/{messageId}/feedback", conversationHandler(putMessageFeedback))
+		r.Delete("/messages/{messageId}/feedback", conversationHandler(deleteMessageFeedback))
 	})
 }
 
@@ -109,6 +120,10 @@ func conversationHandler(
 				http.Error(w, fmt.Sprintf("There was an internal issue: %w", err), http.StatusInternalServerError)
 				return
 			}
+			if conv.CustomerID != customer.ID {
+				slogger.ServerError(w, &logger, 404, "the conversation was not found", nil)
+				return
+			}
 
 			// pass to the handler
 			handler(w, r, pool, customer, conv)
@@ -117,9 +132,13 @@ func conversationHandler(
 }
 
 type GetConverstaionResponse struct {
-	ConversationId uuid.UUID        `json:"conversationId"`
-	Title          string           `json:"title"`
-	Messages       []*gollm.Message `json:"messages"`
+	ConversationId uuid.UUID                `json:"conversationId"`
+	Title          string                   `json:"title"`
+	Messages       []*gollm.Message         `json:"messages"`
+	MessageIds     []uuid.UUID              `json:"messageIds"`
+	Citations      map[int][]*Citation      `json:"citations"` // by message index
+	Branches       map[int]*MessageBranches `json:"branches"`  // by message index
+	Feedback       map[int]*MessageFeedback `json:"feedback"`  // by message index
 }
 
 func getConversation(
@@ -129,14 +148,97 @@ func getConversation(
 	customer *queries.Customer,
 	c *Conversation,
 ) {
+	encodeConversation(w, r, pool, c)
+}
+
+// returns the active branch of the conversation
+func encodeConversation(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Conversation,
+) {
+	branches, err := c.GetMessageBranches(r.Context(), pool)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to get the branches", err)
+		return
+	}
+	feedback, err := c.GetFeedback(r.Context(), pool)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to get the feedback", err)
+		return
+	}
+
 	// return relevant conversation information
 	request.Encode(w, r, c.logger, http.StatusOK, &GetConverstaionResponse{
 		ConversationId: c.ID,
 		Title:          c.Title,
 		Messages:       c.GetMessages(),
+		MessageIds:     c.GetMessageIDs(),
+		Citations:      c.GetCitations(),
+		Branches:       branches,
+		Feedback:       feedback,
 	})
 }
 
+func getConversationBranches(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+	c *Conversation,
+) {
+	branches, err := c.GetBranches(r.Context(), pool)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to get the branches", err)
+		return
+	}
+	request.Encode(w, r, c.logger, http.StatusOK, branches)
+}
+
+// Lists the prompt versions that were used in the conversation
+func getConversationPrompts(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+	c *Conversation,
+) {
+	response, err := c.GetPrompts(r.Context(), pool)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to get the prompts", err)
+		return
+	}
+	request.Encode(w, r, c.logger, http.StatusOK, response)
+}
+
+// Switches the active branch to the latest branch that contains the message, and returns the
+// conversation with the new branch
+func switchConversationBranch(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+	c *Conversation,
+) {
+	messageId, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		slogger.ServerError(w, c.logger, 400, "invalid messageId", err)
+		return
+	}
+
+	if err := c.SwitchBranch(r.Context(), pool, messageId); err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			slogger.ServerError(w, c.logger, 404, "the message was not found", err)
+			return
+		}
+		slogger.ServerError(w, c.logger, 500, "failed to switch the branch", err)
+		return
+	}
+
+	encodeConversation(w, r, pool, c)
+}
+
 func getConversations(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -160,3 +262,245 @@ func getConversations(
 
 	request.Encode(w, r, &logger, http.StatusOK, response)
 }
+
+// Exports the conversation as a Markdown transcript, a lossless JSON document, or a JSONL
+// fine-tuning dataset depending on the `format` query parameter. Defaults to JSON
+func exportConversation(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+	c *Conversation,
+) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = EXPORT_FORMAT_JSON
+	}
+	if format != EXPORT_FORMAT_MARKDOWN && format != EXPORT_FORMAT_JSON && format != EXPORT_FORMAT_JSONL {
+		slogger.ServerError(w, c.logger, 400, fmt.Sprintf("invalid format: %s", format), nil)
+		return
+	}
+
+	export, err := c.Export(r.Context(), pool)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to export the conversation", err)
+		return
+	}
+
+	filename := fmt.Sprintf("conversation-%s", c.ID.String())
+	switch format {
+	case EXPORT_FORMAT_MARKDOWN:
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".md"))
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(export.Markdown()))
+	case EXPORT_FORMAT_JSONL:
+		body, err := export.JSONL()
+		if err != nil {
+			slogger.ServerError(w, c.logger, 500, "failed to create the dataset", err)
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".jsonl"))
+		w.Header().Set("Content-Type", "application/jsonl")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	default:
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		request.Encode(w, r, c.logger, http.StatusOK, export)
+	}
+}
+
+// max size of the body of an import request
+const maxImportSize = 20 << 20
+
+// Creates a conversation for the customer from a JSON export, and returns the conversation
+func importConversation(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+) {
+	logger := httplog.LogEntry(r.Context())
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	body, valid := request.Decode[ConversationExport](w, r, &logger)
+	if !valid {
+		return
+	}
+
+	tx, err := pool.Begin(r.Context())
+	if err != nil {
+		slogger.ServerError(w, &logger, 500, "failed to start a transaction", err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	conv, err := ImportConversation(r.Context(), &logger, tx, customer.ID, &body)
+	if err != nil {
+		slogger.ServerError(w, &logger, 500, "failed to import the conversation", err)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		slogger.ServerError(w, &logger, 500, "failed to commit the transaction", err)
+		return
+	}
+
+	encodeConversation(w, r, pool, conv)
+}
+
+/*
+Searches the messages of the conversations of the customer. Query parameters:
+
+  - q: the search query, in web search syntax
+  - semantic: also match the messages by embedding similarity
+  - type: only search conversations of the type
+  - from, to: RFC3339 timestamps the messages were created between
+  - llmId: only search messages created with the model. Can be repeated
+  - limit: max number of results
+*/
+func searchConversations(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+) {
+	logger := httplog.LogEntry(r.Context())
+	params := r.URL.Query()
+
+	input := &SearchInput{
+		Query:            params.Get("q"),
+		ConversationType: params.Get("type"),
+	}
+	if semantic := params.Get("semantic"); semantic != "" {
+		value, err := strconv.ParseBool(semantic)
+		if err != nil {
+			slogger.ServerError(w, &logger, 400, "failed to parse semantic", err)
+			return
+		}
+		input.Semantic = value
+	}
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		slogger.ServerError(w, &logger, 400, "failed to parse the time range", err)
+		return
+	}
+	input.From = from
+	input.To = to
+	for _, item := range params["llmId"] {
+		llmId, err := uuid.Parse(item)
+		if err != nil {
+			slogger.ServerError(w, &logger, 400, "invalid llmId", err)
+			return
+		}
+		input.LlmIDs = append(input.LlmIDs, llmId)
+	}
+	if limit := params.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			slogger.ServerError(w, &logger, 400, "failed to parse the limit", err)
+			return
+		}
+		input.Limit = value
+	}
+	if problems := input.Valid(r.Context()); len(problems) > 0 {
+		slogger.ServerError(w, &logger, 400, fmt.Sprintf("invalid search: %v", problems), nil)
+		return
+	}
+
+	results, err := SearchConversations(r.Context(), &logger, pool, customer, input)
+	if err != nil {
+		slogger.ServerError(w, &logger, 500, "failed to search the conversations", err)
+		return
+	}
+	request.Encode(w, r, &logger, http.StatusOK, results)
+}
+
+// parses the optional `from` and `to` RFC3339 query parameters
+func parseTimeRange(r *http.Request) (*time.Time, *time.Time, error) {
+	var response [2]*time.Time
+	for i, key := range []string{"from", "to"} {
+		value := r.URL.Query().Get(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		response[i] = &parsed
+	}
+	return response[0], response[1], nil
+}
+
+// Saves the thumbs up or down and the comment of the user on an AI message
+func putMessageFeedback(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+	c *Conversation,
+) {
+	messageId, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		slogger.ServerError(w, c.logger, 400, "invalid messageId", err)
+		return
+	}
+	body, valid := request.Decode[messageFeedbackRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
+
+	feedback, err := c.SaveFeedback(r.Context(), pool, messageId, body.Rating, body.Comment)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			slogger.ServerError(w, c.logger, 404, "the message was not found", err)
+			return
+		}
+		slogger.ServerError(w, c.logger, 500, "failed to save the feedback", err)
+		return
+	}
+	request.Encode(w, r, c.logger, http.StatusOK, feedback)
+}
+
+func deleteMessageFeedback(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+	c *Conversation,
+) {
+	messageId, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		slogger.ServerError(w, c.logger, 400, "invalid messageId", err)
+		return
+	}
+	if err := c.DeleteFeedback(r.Context(), pool, messageId); err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to delete the feedback", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Aggregates the feedback of the customer per model, per conversation type, and per cited
+// source, optionally between the `from` and `to` RFC3339 query parameters
+func getFeedbackAnalytics(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	customer *queries.Customer,
+) {
+	logger := httplog.LogEntry(r.Context())
+
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		slogger.ServerError(w, &logger, 400, "failed to parse the time range", err)
+		return
+	}
+
+	response, err := GetFeedbackAnalytics(r.Context(), pool, customer.ID, from, to)
+	if err != nil {
+		slogger.ServerError(w, &logger, 500, "failed to get the feedback analytics", err)
+		return
+	}
+	request.Encode(w, r, &logger, http.StatusOK, response)
+}
@@ -0,0 +1,156 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/jsonschema"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+// how many times the model is asked to fix a response that does not match the schema
+const JSON_COMPLETION_REPAIR_ATTEMPTS = 2
+
+// Returned by `JsonCompletion` when the model did not respond with JSON that matches the schema,
+// even after being asked to repair it. Holds the errors of the last response
+type JsonValidationError struct {
+	Attempts int                           `json:"attempts"`
+	Output   string                        `json:"output"`
+	Errors   []*jsonschema.ValidationError `json:"errors"`
+}
+
+func (e *JsonValidationError) Error() string {
+	items := make([]string, len(e.Errors))
+	for i, item := range e.Errors {
+		items[i] = item.Error()
+	}
+	return fmt.Sprintf("the response did not match the JSON schema after %d attempts: %s", e.Attempts, strings.Join(items, "; "))
+}
+
+/*
+Send a JSON completion against the model where the response is automatically serialized
+from the response message. The JSON schema sent to the model is generated from `T`, see
+`jsonschema.For` for the tags that describe the fields.
+
+The response is validated against the schema, after removing a markdown code fence around it.
+When it does not match, the errors are sent back to the model so it can fix them, up to
+`JSON_COMPLETION_REPAIR_ATTEMPTS` times, after which a `*JsonValidationError` is returned. The
+repair exchange is not saved, only the message and the final valid response are added to the
+conversation. A nil message completes the current state of the conversation, such as after
+branching it.
+
+Note: This will not response with the entire response object as seen in Completion. Ensure
+there is no information in this object that you need.
+*/
+func JsonCompletion[T any](
+	conv *Conversation,
+	ctx context.Context,
+	db queries.DBTX,
+	model *llm.LLM,
+	message *gollm.Message,
+	tools []*gollm.Tool,
+) (*T, error) {
+	schema, err := jsonschema.For[T]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the JSON schema: %w", err)
+	}
+
+	// create a completion
+	response, err := jsonCompletion[T](conv, ctx, db, model, message, tools, schema)
+	if err != nil {
+		// report the error on the conversation
+		if err := conv.ReportError(ctx, db, err); err != nil {
+			return nil, slogger.Error(ctx, conv.logger, "failed to report the internal error for the convertation", err)
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+func jsonCompletion[T any](
+	conv *Conversation,
+	ctx context.Context,
+	db queries.DBTX,
+	model *llm.LLM,
+	message *gollm.Message,
+	tools []*gollm.Tool,
+	schema *jsonschema.Schema,
+) (*T, error) {
+	// check the message
+	if message != nil && (message.Role == gollm.RoleToolCall || message.Role == gollm.RoleToolResult) {
+		return nil, fmt.Errorf("the role cannot be a tool result or response to use JSON mode")
+	}
+
+	// the invalid responses and the repair messages are only sent to the model
+	pending := make([]*gollm.Message, 0, 1)
+	if message != nil {
+		pending = append(pending, message)
+	}
+
+	for attempt := 1; ; attempt++ {
+		// create a completion
+		response, err := conv.complete(ctx, db, model, pending, tools, nil, schema.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// serialize the response
+		output := response.Message.Message
+		resp, errs := parseJson[T](schema, output)
+		if len(errs) == 0 {
+			if err := conv.saveCompletion(ctx, db, model, message, response); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+		if attempt > JSON_COMPLETION_REPAIR_ATTEMPTS {
+			return nil, &JsonValidationError{Attempts: attempt, Output: output, Errors: errs}
+		}
+
+		conv.logger.WarnContext(ctx, "The response did not match the JSON schema, asking the model to repair it ...", "attempt", attempt, "errors", len(errs))
+		pending = append(pending, response.Message, jsonRepairMessage(errs))
+	}
+}
+
+// validates the output against the schema and serializes it
+func parseJson[T any](schema *jsonschema.Schema, output string) (*T, []*jsonschema.ValidationError) {
+	output = trimCodeFence(output)
+	if errs := schema.Validate([]byte(output)); len(errs) != 0 {
+		return nil, errs
+	}
+	var resp T
+	if err := json.Unmarshal([]byte(output), &resp); err != nil {
+		// values the schema cannot describe, such as a number that overflows the field
+		return nil, []*jsonschema.ValidationError{{Path: "$", Message: err.Error()}}
+	}
+	return &resp, nil
+}
+
+// removes a markdown code fence, such as ```json, that some models wrap the JSON in
+func trimCodeFence(output string) string {
+	output = strings.TrimSpace(output)
+	if !strings.HasPrefix(output, "```") || !strings.HasSuffix(output, "```") {
+		return output
+	}
+	start := strings.Index(output, "\n")
+	if start == -1 {
+		return output
+	}
+	return strings.TrimSpace(output[start+1 : len(output)-3])
+}
+
+// the message that asks the model to fix the errors of its last response
+func jsonRepairMessage(errs []*jsonschema.ValidationError) *gollm.Message {
+	var sb strings.Builder
+	sb.WriteString("Your last response did not match the JSON schema:\n")
+	for _, item := range errs {
+		sb.WriteString(fmt.Sprintf("- %s\n", item.Error()))
+	}
+	sb.WriteString("Fix these errors and respond again with only the corrected JSON, without any other text.")
+	return &gollm.Message{Role: gollm.RoleUser, Message: sb.String()}
+}
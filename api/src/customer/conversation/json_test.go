@@ -0,0 +1,123 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/jsonschema"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/testingutils"
+	"github.com/stretchr/testify/require"
+)
+
+type testJsonIdeas struct {
+	Ideas []struct {
+		Title string `json:"title" jsonschema:"minLength=1"`
+	} `json:"ideas" jsonschema:"minItems=1"`
+}
+
+func TestParseJson(t *testing.T) {
+	schema, err := jsonschema.For[testJsonIdeas]()
+	require.NoError(t, err)
+
+	resp, errs := parseJson[testJsonIdeas](schema, `{"ideas": [{"title": "Rocks"}]}`)
+	require.Empty(t, errs)
+	require.Equal(t, "Rocks", resp.Ideas[0].Title)
+
+	// the code fence some models wrap the JSON in is removed
+	resp, errs = parseJson[testJsonIdeas](schema, "```json\n{\"ideas\": [{\"title\": \"Fenced\"}]}\n```")
+	require.Empty(t, errs)
+	require.Equal(t, "Fenced", resp.Ideas[0].Title)
+
+	resp, errs = parseJson[testJsonIdeas](schema, "```\n{\"ideas\": []}\n```")
+	require.Nil(t, resp)
+	require.Len(t, errs, 1)
+	require.Equal(t, "$.ideas", errs[0].Path)
+
+	_, errs = parseJson[testJsonIdeas](schema, "not json")
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Message, "invalid JSON")
+
+	_, errs = parseJson[testJsonIdeas](schema, `{"ideas": [{"title": ""}, {}]}`)
+	require.Len(t, errs, 2)
+
+	// values the schema does not describe fail to serialize
+	_, errs = parseJson[struct {
+		Count int8 `json:"count"`
+	}](&jsonschema.Schema{}, `{"count": 1000}`)
+	require.Len(t, errs, 1)
+	require.Equal(t, "$", errs[0].Path)
+}
+
+func TestJsonRepairMessage(t *testing.T) {
+	message := jsonRepairMessage([]*jsonschema.ValidationError{
+		{Path: "$.ideas", Message: "expected at least 1 items, got 0"},
+		{Path: "$.title", Message: "is required"},
+	})
+	require.Equal(t, gollm.RoleUser, message.Role)
+	require.Contains(t, message.Message, "- $.ideas: expected at least 1 items, got 0\n- $.title: is required\n")
+}
+
+func TestJsonCompletionRejectsToolMessages(t *testing.T) {
+	conv := &Conversation{logger: testingutils.GetDefaultLogger()}
+	_, err := jsonCompletion[testJsonIdeas](conv, context.TODO(), nil, nil, &gollm.Message{Role: gollm.RoleToolResult}, nil, &jsonschema.Schema{})
+	require.Error(t, err)
+}
+
+func TestJsonCompletionRepair(t *testing.T) {
+	ctx := context.Background()
+	logger := testingutils.GetDefaultLogger()
+	pool := testingutils.GetDatabase(t, ctx)
+	c := testingutils.GetTestCustomer(t, ctx, pool)
+
+	row := testingutils.GetMockLLM(t, ctx, pool, c, "mock-json")
+	model := llm.FromObjects(&row.Llm, &row.AvailableModel)
+	llm.Mock.Reset()
+	defer llm.Mock.Reset()
+
+	conv, err := CreateConversation(ctx, logger, pool, c.ID, "You generate ideas", "Test JSON Conversation", "Testing")
+	require.NoError(t, err)
+
+	// the model fixes the response when given the errors
+	llm.Mock.Queue("mock-json",
+		&llm.MockResponse{Message: `{"ideas": []}`},
+		&llm.MockResponse{Message: `{"ideas": [{"title": "Rocks"}]}`},
+	)
+	resp, err := JsonCompletion[testJsonIdeas](conv, ctx, pool, model, &gollm.Message{Role: gollm.RoleUser, Message: "Give me ideas"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Rocks", resp.Ideas[0].Title)
+
+	requests := llm.Mock.Requests()
+	require.Len(t, requests, 2)
+	require.True(t, requests[0].Json)
+	require.Contains(t, requests[0].JsonSchema, `"minItems":1`)
+	last := requests[1].Messages[len(requests[1].Messages)-1]
+	require.Contains(t, last.Message, "$.ideas: expected at least 1 items, got 0")
+
+	// only the message and the valid response are saved, not the repair exchange
+	saved, err := GetConversation(ctx, logger, pool, conv.ID)
+	require.NoError(t, err)
+	messages := saved.GetMessages()
+	require.Len(t, messages, 3)
+	require.Equal(t, "Give me ideas", messages[1].Message)
+	require.Equal(t, `{"ideas": [{"title": "Rocks"}]}`, messages[2].Message)
+	require.Len(t, conv.GetMessages(), 3)
+
+	// the repairs run out
+	llm.Mock.Reset()
+	llm.Mock.Queue("mock-json",
+		&llm.MockResponse{Message: `not json`},
+		&llm.MockResponse{Message: `{"ideas": [{}]}`},
+		&llm.MockResponse{Message: `{"ideas": [{"title": ""}]}`},
+	)
+	_, err = JsonCompletion[testJsonIdeas](conv, ctx, pool, model, &gollm.Message{Role: gollm.RoleUser, Message: "Try again"}, nil)
+	var validationErr *JsonValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Equal(t, JSON_COMPLETION_REPAIR_ATTEMPTS+1, validationErr.Attempts)
+	require.Equal(t, `{"ideas": [{"title": ""}]}`, validationErr.Output)
+	require.Len(t, validationErr.Errors, 1)
+	require.Equal(t, "$.ideas[0].title", validationErr.Errors[0].Path)
+	require.Len(t, llm.Mock.Requests(), JSON_COMPLETION_REPAIR_ATTEMPTS+1)
+}
@@ -0,0 +1,50 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+// A prompt version that was used in the conversation
+type ConversationPrompt struct {
+	*queries.PromptTemplate
+	UsedAt time.Time `json:"usedAt"` // the first time the version was used
+}
+
+// Records that the prompt version was used in the conversation. The built-in prompts that are
+// not in the database are not recorded
+func (c *Conversation) RecordPrompt(ctx context.Context, db queries.DBTX, t *prompts.Template) error {
+	if t == nil || t.ID == uuid.Nil {
+		return nil
+	}
+	dmodel := queries.New(db)
+	if err := dmodel.CreateConversationPromptTemplate(ctx, &queries.CreateConversationPromptTemplateParams{
+		ConversationID:   c.ID,
+		PromptTemplateID: t.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to record the prompt template: %w", err)
+	}
+	return nil
+}
+
+// Gets the prompt versions that were used in the conversation, in the order they were first used
+func (c *Conversation) GetPrompts(ctx context.Context, db queries.DBTX) ([]*ConversationPrompt, error) {
+	dmodel := queries.New(db)
+	items, err := dmodel.GetConversationPromptTemplates(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the prompt templates: %w", err)
+	}
+	response := make([]*ConversationPrompt, len(items))
+	for i, item := range items {
+		response[i] = &ConversationPrompt{
+			PromptTemplate: &item.PromptTemplate,
+			UsedAt:         item.UsedAt.Time,
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,23 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+type messageFeedbackRequest struct {
+	Rating  int16  `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+func (r messageFeedbackRequest) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string)
+	if r.Rating != FEEDBACK_RATING_POSITIVE && r.Rating != FEEDBACK_RATING_NEGATIVE {
+		p["rating"] = fmt.Sprintf("must be %d or %d", FEEDBACK_RATING_POSITIVE, FEEDBACK_RATING_NEGATIVE)
+	}
+	if utf8.RuneCountInString(r.Comment) > feedbackCommentLength {
+		p["comment"] = fmt.Sprintf("cannot be longer than %d characters", feedbackCommentLength)
+	}
+	return p
+}
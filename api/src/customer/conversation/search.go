@@ -0,0 +1,321 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+)
+
+const (
+	SEARCH_MATCH_FULL_TEXT = "fullText"
+	SEARCH_MATCH_SEMANTIC  = "semantic"
+)
+
+const (
+	searchDefaultLimit = 20
+	searchMaxLimit     = 50
+
+	// min cosine similarity of a semantic match. The embeddings are normalized, so the similarity
+	// is the negative of the `<#>` distance
+	searchMinSimilarity = 0.3
+
+	// max length of the snippet of a semantic match
+	searchSnippetLength = 200
+
+	// dampens the weight of the top ranks when the full text and semantic matches are fused
+	searchRankConstant = 60
+)
+
+// the roles of the messages that are searched and embedded. System messages and tool results are
+// left out, as they hold the prompts and the retrieved documents rather than the conversation
+var searchRoles = []string{gollm.RoleUser.ToString(), gollm.RoleAI.ToString()}
+
+type SearchInput struct {
+	Query string
+
+	// whether to also match the messages by embedding similarity
+	Semantic bool
+
+	// filters
+
+	ConversationType string
+	From             *time.Time
+	To               *time.Time
+	LlmIDs           []uuid.UUID
+
+	Limit int
+}
+
+func (input *SearchInput) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string)
+	if strings.TrimSpace(input.Query) == "" {
+		p["q"] = "cannot be empty"
+	}
+	if len(input.Query) > gollm.OPENAI_EMBEDDINGS_INPUT_MAX {
+		p["q"] = fmt.Sprintf("the query is too long: %d characters", len(input.Query))
+	}
+	if input.Limit < 0 || input.Limit > searchMaxLimit {
+		p["limit"] = fmt.Sprintf("must be between 1 and %d", searchMaxLimit)
+	}
+	if input.From != nil && input.To != nil && !input.From.Before(*input.To) {
+		p["from"] = "must be before to"
+	}
+	return p
+}
+
+// A message that matched a search, with a snippet of the matching content
+type SearchResult struct {
+	MessageID        uuid.UUID          `json:"messageId"`
+	ConversationID   uuid.UUID          `json:"conversationId"`
+	Title            string             `json:"title"`
+	ConversationType string             `json:"conversationType"`
+	Role             string             `json:"role"`
+	Index            int32              `json:"index"`
+	Snippet          string             `json:"snippet"`
+	CreatedAt        pgtype.Timestamptz `json:"createdAt"`
+	Score            float64            `json:"score"`
+	MatchedBy        []string           `json:"matchedBy"`
+}
+
+/*
+Searches the messages of the conversations of the customer. The messages are matched with
+Postgres full text search, and by embedding similarity when `Semantic` is set. Both lists are
+fused by reciprocal rank, so messages that match both ways rank first.
+
+The messages are embedded in the background by `EmbedConversationMessages`, so the newest
+messages may only be matched by the full text search.
+*/
+func SearchConversations(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	customer *queries.Customer,
+	input *SearchInput,
+) ([]*SearchResult, error) {
+	if problems := input.Valid(ctx); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid search: %v", problems)
+	}
+	limit := input.Limit
+	if limit == 0 {
+		limit = searchDefaultLimit
+	}
+
+	var from, to pgtype.Timestamptz
+	if input.From != nil {
+		from = pgtype.Timestamptz{Time: *input.From, Valid: true}
+	}
+	if input.To != nil {
+		to = pgtype.Timestamptz{Time: *input.To, Valid: true}
+	}
+
+	dmodel := queries.New(db)
+	logger.InfoContext(ctx, "Searching the conversations ...", "semantic", input.Semantic)
+	textRows, err := dmodel.SearchConversationMessages(ctx, &queries.SearchConversationMessagesParams{
+		CustomerID:       customer.ID,
+		Column2:          input.Query,
+		Column3:          searchRoles,
+		ConversationType: input.ConversationType,
+		Column5:          from,
+		Column6:          to,
+		Column7:          input.LlmIDs,
+		Limit:            int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run the full text search: %w", err)
+	}
+	textResults := make([]*SearchResult, len(textRows))
+	for i, item := range textRows {
+		textResults[i] = &SearchResult{
+			MessageID:        item.ID,
+			ConversationID:   item.ConversationID,
+			Title:            item.Title,
+			ConversationType: item.ConversationType,
+			Role:             item.Role,
+			Index:            item.Index,
+			Snippet:          item.Snippet,
+			CreatedAt:        item.CreatedAt,
+			MatchedBy:        []string{SEARCH_MATCH_FULL_TEXT},
+		}
+	}
+	if !input.Semantic {
+		return fuseSearchResults(textResults, nil, limit), nil
+	}
+
+	// embed the query
+	emb := llm.GetEmbeddings(logger, customer)
+	res, err := emb.Embed(ctx, logger, &gollm.EmbedArgs{
+		InputChunks: []string{input.Query},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed the query: %w", err)
+	}
+	if len(res.Embeddings) == 0 {
+		return nil, fmt.Errorf("there were no embeddings returned")
+	}
+	if err := utils.ReportUsage(ctx, logger, db, customer.ID, []*tokens.UsageRecord{res.Usage}, nil); err != nil {
+		return nil, fmt.Errorf("failed to report the usage: %w", err)
+	}
+
+	semanticRows, err := dmodel.SearchConversationMessagesSemantic(ctx, &queries.SearchConversationMessagesSemanticParams{
+		CustomerID:       customer.ID,
+		Embeddings:       &res.Embeddings[0].Embedding,
+		Column3:          searchRoles,
+		ConversationType: input.ConversationType,
+		Column5:          from,
+		Column6:          to,
+		Column7:          input.LlmIDs,
+		Limit:            int32(limit),
+		Column9:          -searchMinSimilarity,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run the semantic search: %w", err)
+	}
+	semanticResults := make([]*SearchResult, len(semanticRows))
+	for i, item := range semanticRows {
+		semanticResults[i] = &SearchResult{
+			MessageID:        item.ID,
+			ConversationID:   item.ConversationID,
+			Title:            item.Title,
+			ConversationType: item.ConversationType,
+			Role:             item.Role,
+			Index:            item.Index,
+			Snippet:          searchSnippet(item.Message),
+			CreatedAt:        item.CreatedAt,
+			MatchedBy:        []string{SEARCH_MATCH_SEMANTIC},
+		}
+	}
+
+	return fuseSearchResults(textResults, semanticResults, limit), nil
+}
+
+// Gets up to `limit` messages of any customer that have not been embedded yet. Messages whose
+// embedding failed after `failedBefore` are skipped until then
+func GetMessagesToEmbed(
+	ctx context.Context,
+	db queries.DBTX,
+	limit int32,
+	failedBefore time.Time,
+) ([]*queries.GetConversationMessagesWithoutEmbeddingsRow, error) {
+	dmodel := queries.New(db)
+	msgs, err := dmodel.GetConversationMessagesWithoutEmbeddings(ctx, &queries.GetConversationMessagesWithoutEmbeddingsParams{
+		Column1:           searchRoles,
+		Limit:             limit,
+		EmbeddingFailedAt: pgtype.Timestamptz{Time: failedBefore, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the messages to embed: %w", err)
+	}
+	return msgs, nil
+}
+
+// Records that the messages failed to embed, so they are not retried on every run
+func SetMessagesEmbeddingFailed(
+	ctx context.Context,
+	db queries.DBTX,
+	msgs []*queries.GetConversationMessagesWithoutEmbeddingsRow,
+) error {
+	ids := make([]uuid.UUID, len(msgs))
+	for i, item := range msgs {
+		ids[i] = item.ID
+	}
+	if err := queries.New(db).SetConversationMessagesEmbeddingFailed(ctx, ids); err != nil {
+		return fmt.Errorf("failed to mark the messages as failed: %w", err)
+	}
+	return nil
+}
+
+// Embeds the messages of the customer so they can be matched by a semantic search
+func EmbedConversationMessages(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	customer *queries.Customer,
+	msgs []*queries.GetConversationMessagesWithoutEmbeddingsRow,
+) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	dmodel := queries.New(db)
+	emb := llm.GetEmbeddings(logger, customer)
+	logger.InfoContext(ctx, "Embedding the conversation messages ...", "length", len(msgs))
+	chunks := make([]string, len(msgs))
+	for i, item := range msgs {
+		chunks[i] = item.Message
+		if utf8.RuneCountInString(chunks[i]) > gollm.OPENAI_EMBEDDINGS_INPUT_MAX {
+			chunks[i] = string([]rune(chunks[i])[:gollm.OPENAI_EMBEDDINGS_INPUT_MAX])
+		}
+	}
+	res, err := emb.Embed(ctx, logger, &gollm.EmbedArgs{
+		InputChunks: chunks,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to embed the messages: %w", err)
+	}
+	if len(res.Embeddings) != len(msgs) {
+		return fmt.Errorf("expected %d embeddings, got %d", len(msgs), len(res.Embeddings))
+	}
+
+	for i, item := range res.Embeddings {
+		if err := dmodel.CreateConversationMessageEmbedding(ctx, &queries.CreateConversationMessageEmbeddingParams{
+			ConversationMessageID: msgs[i].ID,
+			CustomerID:            customer.ID,
+			Embeddings:            &item.Embedding,
+		}); err != nil {
+			return fmt.Errorf("failed to save the embeddings: %w", err)
+		}
+	}
+
+	if err := utils.ReportUsage(ctx, logger, db, customer.ID, []*tokens.UsageRecord{res.Usage}, nil); err != nil {
+		return fmt.Errorf("failed to report the usage: %w", err)
+	}
+	return nil
+}
+
+// Fuses the ranked lists with reciprocal rank fusion. A message in both lists keeps the snippet
+// of the full text match, as it highlights the matched terms
+func fuseSearchResults(textResults []*SearchResult, semanticResults []*SearchResult, limit int) []*SearchResult {
+	byId := make(map[uuid.UUID]*SearchResult)
+	response := make([]*SearchResult, 0, len(textResults)+len(semanticResults))
+	for _, list := range [][]*SearchResult{textResults, semanticResults} {
+		for rank, item := range list {
+			score := 1 / float64(searchRankConstant+rank+1)
+			if existing, ok := byId[item.MessageID]; ok {
+				existing.Score += score
+				existing.MatchedBy = append(existing.MatchedBy, item.MatchedBy...)
+				continue
+			}
+			item.Score = score
+			byId[item.MessageID] = item
+			response = append(response, item)
+		}
+	}
+
+	// stable so ties keep the full text order
+	sort.SliceStable(response, func(i, j int) bool {
+		return response[i].Score > response[j].Score
+	})
+	if len(response) > limit {
+		response = response[:limit]
+	}
+	return response
+}
+
+func searchSnippet(message string) string {
+	if utf8.RuneCountInString(message) <= searchSnippetLength {
+		return message
+	}
+	return string([]rune(message)[:searchSnippetLength]) + "..."
+}
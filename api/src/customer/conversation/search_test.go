@@ -0,0 +1,35 @@
+package conversation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchInputValid(t *testing.T) {
+	require.Empty(t, (&SearchInput{Query: "decision"}).Valid(context.TODO()))
+	require.Contains(t, (&SearchInput{Query: " "}).Valid(context.TODO()), "q")
+	require.Contains(t, (&SearchInput{Query: "decision", Limit: searchMaxLimit + 1}).Valid(context.TODO()), "limit")
+}
+
+func TestFuseSearchResults(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	textResults := []*SearchResult{
+		{MessageID: ids[0], Snippet: "**decision**", MatchedBy: []string{SEARCH_MATCH_FULL_TEXT}},
+		{MessageID: ids[1], Snippet: "**plan**", MatchedBy: []string{SEARCH_MATCH_FULL_TEXT}},
+	}
+	semanticResults := []*SearchResult{
+		{MessageID: ids[2], MatchedBy: []string{SEARCH_MATCH_SEMANTIC}},
+		{MessageID: ids[1], Snippet: "semantic", MatchedBy: []string{SEARCH_MATCH_SEMANTIC}},
+	}
+
+	// the message matched both ways ranks first, with the full text snippet
+	fused := fuseSearchResults(textResults, semanticResults, 2)
+	require.Len(t, fused, 2)
+	require.Equal(t, ids[1], fused[0].MessageID)
+	require.Equal(t, []string{SEARCH_MATCH_FULL_TEXT, SEARCH_MATCH_SEMANTIC}, fused[0].MatchedBy)
+	require.Equal(t, "**plan**", fused[0].Snippet)
+	require.Equal(t, ids[0], fused[1].MessageID)
+}
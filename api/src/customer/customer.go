@@ -120,57 +120,14 @@ func (c *Customer) CreateFolder(ctx context.Context, txn queries.DBTX, args *cre
 
 // Does an 'ls' on a folder
 func (c *Customer) ListFolderContents(ctx context.Context, db queries.DBTX, folderId pgtype.UUID) (*listFolderContentsResponse, error) {
-	logger := c.logger.With()
-	if folderId.Valid {
-		logger = logger.With("folderId", folderId.Bytes)
-	}
-	logger.InfoContext(ctx, "Getting all children of the folder ...")
-
-	model := queries.New(db)
-
-	var err error
-	var folder *queries.Folder
-	var folders []*queries.Folder
-	var documents []*queries.Document
-
-	if !folderId.Valid {
-		// get root file information
-		folders, err = model.GetRootFoldersByCustomer(ctx, c.ID)
-		if err != nil {
-			return nil, fmt.Errorf("there was an issue getting the folders: %v", err)
-		}
-		documents, err = model.GetRootDocumentsByCustomer(ctx, c.ID)
-		if err != nil {
-			return nil, fmt.Errorf("there was an issue getting the documents: %v", err)
-		}
-	} else {
-		// get self
-		uid := utils.PGXUUIDToGoogleUUID(folderId)
-		if uid == nil {
-			return nil, fmt.Errorf("failed to convert folderId to a google uuid")
-		}
-		folder, err = model.GetFolder(ctx, *uid)
-		if err != nil {
-			return nil, fmt.Errorf("this folder does not exist: %w", err)
-		}
-
-		// get the information using the folder
-		folders, err = model.GetFoldersFromParent(ctx, folderId)
-		if err != nil {
-			return nil, fmt.Errorf("there was an issue getting the folders: %v", err)
-		}
-		documents, err = model.GetDocumentsFromParent(ctx, folderId)
-		if err != nil {
-			return nil, fmt.Errorf("there was an issue getting the documents: %v", err)
-		}
+	contents, err := datastore.ListFolderContents(ctx, c.logger, db, c.ID, folderId)
+	if err != nil {
+		return nil, err
 	}
-
-	logger.InfoContext(ctx, "Successfully listed folder contents", "folders", len(folders), "documents", len(documents))
-
 	return &listFolderContentsResponse{
-		Self:      folder,
-		Folders:   folders,
-		Documents: documents,
+		Self:      contents.Self,
+		Folders:   contents.Folders,
+		Documents: contents.Documents,
 	}, nil
 }
 
@@ -375,10 +332,11 @@ func (c *Customer) InsertWebsite(
 	// insert the pages
 	for i, item := range request.Pages {
 		page, err := model.CreateWebsitePage(ctx, &queries.CreateWebsitePageParams{
-			CustomerID: c.ID,
-			WebsiteID:  site.ID,
-			Url:        item,
-			Sha256:     utils.GenerateFingerprint([]byte(item)), // use a tmp hash until the content is actually ingested
+			CustomerID:  c.ID,
+			WebsiteID:   site.ID,
+			Url:         item,
+			Sha256:      utils.GenerateFingerprint([]byte(item)), // use a tmp hash until the content is actually ingested
+			ContentType: pageContentType(item),
 		})
 		if err != nil {
 			return nil, fmt.Errorf("there was an issue inserting the page: %v", err)
@@ -421,27 +379,39 @@ func (c *Customer) InsertSinglePage(
 		return slogger.Error(ctx, logger, "failed to parse the url", err)
 	}
 
-	// insert the website
+	// reuse the website when it exists so the lists the customer configured are kept
 	model := queries.New(db)
-	site, err := model.CreateWebsite(ctx, &queries.CreateWebsiteParams{
+	site, err := model.GetWebsiteByPath(ctx, &queries.GetWebsiteByPathParams{
 		CustomerID: c.ID,
-		Protocol:   parsed.Scheme,
 		Domain:     parsed.Host,
 		Path:       parsed.Path,
-		Whitelist:  []string{},
-		Blacklist:  []string{},
 	})
 	if err != nil {
-		return slogger.Error(ctx, logger, "error creating the website", err)
+		if !strings.Contains(err.Error(), "no rows in result set") {
+			return slogger.Error(ctx, logger, "error getting the website", err)
+		}
+		site, err = model.CreateWebsite(ctx, &queries.CreateWebsiteParams{
+			CustomerID: c.ID,
+			Protocol:   parsed.Scheme,
+			Domain:     parsed.Host,
+			Path:       parsed.Path,
+			Whitelist:  []string{},
+			Blacklist:  []string{},
+		})
+		if err != nil {
+			return slogger.Error(ctx, logger, "error creating the website", err)
+		}
 	}
 
-	// insert the single page
-	u := fmt.Sprintf("%s://%s%s", site.Protocol, site.Domain, site.Path)
+	// insert the single page, the query string is part of the page
+	parsed.Fragment = ""
+	u := parsed.String()
 	if _, err = model.CreateWebsitePage(ctx, &queries.CreateWebsitePageParams{
-		CustomerID: c.ID,
-		WebsiteID:  site.ID,
-		Url:        u,
-		Sha256:     utils.GenerateFingerprint([]byte(u)), // use a tmp hash until the content is actually ingested
+		CustomerID:  c.ID,
+		WebsiteID:   site.ID,
+		Url:         u,
+		Sha256:      utils.GenerateFingerprint([]byte(u)), // use a tmp hash until the content is actually ingested
+		ContentType: pageContentType(u),
 	}); err != nil {
 		return slogger.Error(ctx, logger, "there was an issue inserting the page", err)
 	}
@@ -451,6 +421,14 @@ func (c *Customer) InsertSinglePage(
 	return nil
 }
 
+// linked documents are stored with their content type so they get parsed instead of scraped
+func pageContentType(u string) string {
+	if ct := webparse.DocumentContentTypeFromURL(u); ct != "" {
+		return ct
+	}
+	return "text/html"
+}
+
 // func (c *Customer) VectorizeWebsite(ctx context.Context, txn queries.DBTX, site *queries.Website) error {
 // 	logger := c.logger.With("site.ID", site.ID.String(), "site.Domain", site.Domain)
 // 	logger.InfoContext(ctx, "Parsing site ...")
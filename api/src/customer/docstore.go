@@ -276,3 +276,22 @@ func getDocumentChunked(
 		"chunks":   chunks,
 	})
 }
+
+func getDocumentSummary(
+	w http.ResponseWriter,
+	r *http.Request,
+	_ *pgxpool.Pool,
+	c *Customer,
+	doc *datastore.Document,
+) {
+	if doc.CustomerID != c.ID {
+		http.Error(w, "There was no document found", http.StatusNotFound)
+		return
+	}
+
+	request.Encode(w, r, c.logger, http.StatusOK, &summaryResponse{
+		Summary:         doc.Summary,
+		IsCurrent:       doc.CurrentSummary() != "",
+		SummaryFailedAt: doc.SummaryFailedAt,
+	})
+}
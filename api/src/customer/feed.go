@@ -0,0 +1,385 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/datastore"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/request"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/sapphirenw/ai-content-creation-api/src/webparse"
+)
+
+func getFeeds(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	dmodel := queries.New(pool)
+	feeds, err := dmodel.GetFeedsByCustomer(r.Context(), c.ID)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to get the feeds", err)
+		return
+	}
+	request.Encode(w, r, c.logger, http.StatusOK, feeds)
+}
+
+func getFeed(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+	feed *queries.Feed,
+) {
+	request.Encode(w, r, c.logger, http.StatusOK, feed)
+}
+
+func getFeedItems(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+	feed *queries.Feed,
+) {
+	dmodel := queries.New(pool)
+	items, err := dmodel.GetFeedItemsByFeed(r.Context(), feed.ID)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to get the feed items", err)
+		return
+	}
+	request.Encode(w, r, c.logger, http.StatusOK, items)
+}
+
+func createFeed(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	// parse the body
+	body, valid := request.Decode[createFeedRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
+
+	feed, err := c.CreateFeed(r.Context(), pool, &body)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 400, "failed to create the feed", err)
+		return
+	}
+
+	request.Encode(w, r, c.logger, http.StatusOK, feed)
+}
+
+func deleteFeed(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+	feed *queries.Feed,
+) {
+	dmodel := queries.New(pool)
+	if err := dmodel.DeleteFeed(r.Context(), feed.ID); err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to delete the feed", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// polls the feed outside of the regular schedule
+func pollFeed(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+	feed *queries.Feed,
+) {
+	response, err := c.PollFeed(r.Context(), pool, feed)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to poll the feed", err)
+		return
+	}
+
+	request.Encode(w, r, c.logger, http.StatusOK, response)
+}
+
+// Registers a new feed. The feed is fetched once to ensure it can be parsed,
+// the entries are ingested on the next poll
+func (c *Customer) CreateFeed(
+	ctx context.Context,
+	db queries.DBTX,
+	body *createFeedRequest,
+) (*queries.Feed, error) {
+	logger := c.logger.With("url", body.Url)
+	logger.InfoContext(ctx, "Creating feed ...")
+
+	parsed, err := webparse.FetchFeed(ctx, logger, body.Url)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to parse the feed", err)
+	}
+
+	interval := body.PollIntervalMinutes
+	if interval == 0 {
+		interval = 60
+	}
+
+	dmodel := queries.New(db)
+	feed, err := dmodel.CreateFeed(ctx, &queries.CreateFeedParams{
+		CustomerID:          c.ID,
+		Url:                 body.Url,
+		Title:               parsed.Title,
+		FeedType:            parsed.Type,
+		FetchArticle:        body.FetchArticle,
+		PollIntervalMinutes: int32(interval),
+	})
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to create the feed", err)
+	}
+
+	return feed, nil
+}
+
+// Fetches the feed, inserts all new or changed entries and vectorizes them
+func (c *Customer) PollFeed(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	feed *queries.Feed,
+) (*queries.Feed, error) {
+	logger := c.logger.With("feed.ID", feed.ID.String(), "feed.Url", feed.Url)
+	logger.InfoContext(ctx, "Polling feed ...")
+
+	dmodel := queries.New(pool)
+
+	parsed, err := webparse.FetchFeed(ctx, logger, feed.Url)
+	if err != nil {
+		// record the error so it can be surfaced to the user
+		msg := err.Error()
+		if _, err := dmodel.UpdateFeedPolled(ctx, &queries.UpdateFeedPolledParams{
+			ID:        feed.ID,
+			Title:     feed.Title,
+			FeedType:  feed.FeedType,
+			LastError: &msg,
+		}); err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to update the feed", err)
+		}
+		return nil, slogger.Error(ctx, logger, "failed to fetch the feed", err)
+	}
+
+	// existing items are used to avoid re-fetching articles
+	existing, err := dmodel.GetFeedItemsByFeed(ctx, feed.ID)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to get the existing feed items", err)
+	}
+	existingMap := make(map[string]*queries.FeedItem, len(existing))
+	for _, item := range existing {
+		existingMap[item.Guid] = item
+	}
+
+	logger.InfoContext(ctx, "Inserting feed items ...", "length", len(parsed.Items))
+	for _, item := range parsed.Items {
+		if item.GUID == "" {
+			logger.WarnContext(ctx, "Skipping feed item without an id or link", "title", item.Title)
+			continue
+		}
+
+		content := item.Content
+		metadata := map[string]any{}
+		if prev, ok := existingMap[item.GUID]; ok && prev.Content != "" && (content == "" || feed.FetchArticle) {
+			// the article was already fetched
+			content = prev.Content
+			if len(prev.Metadata) != 0 {
+				json.Unmarshal(prev.Metadata, &metadata)
+			}
+		} else if feed.FetchArticle && item.Link != "" {
+			logger.InfoContext(ctx, "Fetching the article body ...", "link", item.Link)
+			response, err := webparse.ScrapeSingle(ctx, logger, &queries.WebsitePage{Url: item.Link})
+			if err != nil {
+				logger.WarnContext(ctx, "failed to fetch the article, using the feed content", "link", item.Link, "error", err)
+			} else if response.Content != "" {
+				content = response.Content
+				metadata["fetchedArticle"] = true
+			}
+		}
+
+		var published pgtype.Timestamptz
+		if item.Published != nil {
+			published = pgtype.Timestamptz{Time: *item.Published, Valid: true}
+		}
+
+		enc, _ := json.Marshal(metadata)
+		if _, err := dmodel.CreateFeedItem(ctx, &queries.CreateFeedItemParams{
+			CustomerID:  c.ID,
+			FeedID:      feed.ID,
+			Guid:        item.GUID,
+			Title:       item.Title,
+			Link:        item.Link,
+			Author:      item.Author,
+			PublishedAt: published,
+			Content:     content,
+			Sha256:      utils.GenerateFingerprint([]byte(item.Title + content)),
+			Metadata:    enc,
+		}); err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to insert the feed item", err)
+		}
+	}
+
+	// vectorize all new or changed items
+	if err := c.vectorizeFeed(ctx, pool, logger, feed); err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to vectorize the feed", err)
+	}
+
+	updated, err := dmodel.UpdateFeedPolled(ctx, &queries.UpdateFeedPolledParams{
+		ID:       feed.ID,
+		Title:    parsed.Title,
+		FeedType: parsed.Type,
+	})
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to update the feed", err)
+	}
+
+	logger.InfoContext(ctx, "Successfully polled feed")
+	return updated, nil
+}
+
+func (c *Customer) vectorizeFeed(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	logger *slog.Logger,
+	feed *queries.Feed,
+) error {
+	dmodel := queries.New(pool)
+	items, err := dmodel.GetFeedItemsNotVectorized(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get the feed items: %w", err)
+	}
+	if len(items) == 0 {
+		logger.InfoContext(ctx, "There are no new feed items to vectorize")
+		return nil
+	}
+
+	logger.InfoContext(ctx, "Creating embeddings for each feed item ...", "length", len(items))
+
+	// track token usage
+	usageRecords := make([]*tokens.UsageRecord, 0)
+	emb := llm.GetEmbeddings(logger, c.Customer)
+
+	for _, item := range items {
+		// create a transaction
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			return slogger.Error(ctx, logger, "failed to start a transaction", err)
+		}
+
+		usageRecord, err := c.handleFeedItemVectorization(ctx, tx, logger, emb, item)
+		if err == nil {
+			if err := tx.Commit(ctx); err != nil {
+				return slogger.Error(ctx, logger, "failed to commit the transaction", err)
+			}
+			if usageRecord != nil {
+				usageRecords = append(usageRecords, usageRecord)
+			}
+		} else {
+			if err := tx.Rollback(ctx); err != nil {
+				return slogger.Error(ctx, logger, "failed to rollback the transaction", err)
+			}
+		}
+	}
+
+	// report usage
+	if err := utils.ReportUsage(ctx, logger, pool, c.ID, usageRecords, nil); err != nil {
+		return slogger.Error(ctx, logger, "failed to report usage", err)
+	}
+
+	return nil
+}
+
+func (c *Customer) handleFeedItemVectorization(
+	ctx context.Context,
+	db queries.DBTX,
+	l *slog.Logger,
+	emb gollm.Embeddings,
+	i *queries.FeedItem,
+) (*tokens.UsageRecord, error) {
+	logger := l.With("feedItem.ID", i.ID.String(), "feedItem.Link", i.Link)
+	dmodel := queries.New(db)
+
+	// create a new item type (never returns an error)
+	item, _ := datastore.NewFeedItemFromFeedItem(ctx, logger, i)
+
+	chunks, err := item.GetChunks(ctx)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to chunk the feed item", err)
+	}
+	if len(chunks) == 0 {
+		logger.InfoContext(ctx, "The feed item has no content")
+		return nil, nil
+	}
+
+	// delete the old vectors
+	if err := dmodel.DeleteFeedItemVectors(ctx, item.ID); err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to delete old vectors", err)
+	}
+
+	// embed the content
+	res, err := emb.Embed(ctx, logger, &gollm.EmbedArgs{
+		InputChunks: chunks,
+	})
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to embed the content", err)
+	}
+
+	metadata, err := item.GetMetadata(ctx)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to get the metadata", err)
+	}
+
+	for index, vec := range res.Embeddings {
+		// create raw vector object
+		vecId, err := dmodel.CreateVector(ctx, &queries.CreateVectorParams{
+			Raw:            vec.Raw,
+			Embeddings:     &vec.Embedding,
+			ObjectID:       item.ID,
+			ObjectParentID: utils.GoogleUUIDToPGXUUID(item.FeedID),
+			ContentType:    "feed_item",
+			CustomerID:     c.ID,
+			Metadata:       metadata.Bytes(),
+		})
+		if err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to insert the embeddings", err)
+		}
+
+		// create a reference to the vector onto the item
+		if _, err := dmodel.CreateFeedItemVector(ctx, &queries.CreateFeedItemVectorParams{
+			FeedItemID:    item.ID,
+			VectorStoreID: vecId,
+			CustomerID:    c.ID,
+			Index:         int32(index),
+			Metadata:      metadata.Bytes(),
+		}); err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to create the vector relationship", err)
+		}
+	}
+
+	// set the vector signature
+	if err := dmodel.UpdateFeedItemVectorSig(ctx, &queries.UpdateFeedItemVectorSigParams{
+		ID:           item.ID,
+		VectorSha256: item.Sha256,
+	}); err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to update the feed item signature", err)
+	}
+
+	logger.InfoContext(ctx, "Successfully processed feed item")
+	return res.Usage, nil
+}
@@ -29,6 +29,27 @@ func Handler(mux chi.Router) {
 		r.Post("/", customerHandler(createModel))
 		r.Route("/{llmId}", func(r chi.Router) {
 			r.Put("/", customerHandler(updateModel))
+			r.Get("/retryPolicy", customerHandler(getRetryPolicy))
+			r.Put("/retryPolicy", customerHandler(updateRetryPolicy))
+			r.Delete("/retryPolicy", customerHandler(deleteRetryPolicy))
+		})
+	})
+
+	// tools
+	mux.Route("/tools", func(r chi.Router) {
+		r.Get("/", customerHandler(getTools))
+		r.Put("/", customerHandler(updateToolConfig))
+		r.Delete("/", customerHandler(deleteToolConfig))
+	})
+
+	// prompts
+	mux.Route("/prompts", func(r chi.Router) {
+		r.Get("/", customerHandler(getPromptTemplates))
+		r.Route("/{name}", func(r chi.Router) {
+			r.Get("/", customerHandler(getPromptTemplateVersions))
+			r.Post("/", customerHandler(savePromptTemplate))
+			r.Delete("/", customerHandler(resetPromptTemplate))
+			r.Put("/activate", customerHandler(activatePromptTemplate))
 		})
 	})
 
@@ -47,6 +68,7 @@ func Handler(mux chi.Router) {
 		r.Get("/raw", documentHandler(getDocumentRaw))
 		r.Get("/cleaned", documentHandler(getDocumentCleaned))
 		r.Get("/chunked", documentHandler(getDocumentChunked))
+		r.Get("/summary", documentHandler(getDocumentSummary))
 	})
 
 	// folders
@@ -67,6 +89,19 @@ func Handler(mux chi.Router) {
 			r.Delete("/", websiteHandler(deleteWebsite))
 			r.Get("/pages", websiteHandler(getWebsitePages))
 			r.Get("/pages/{pageId}/content", websiteHandler(getWebsitePageContent))
+			r.Get("/pages/{pageId}/summary", websiteHandler(getWebsitePageSummary))
+		})
+	})
+
+	// feeds
+	mux.Route("/feeds", func(r chi.Router) {
+		r.Get("/", customerHandler(getFeeds))
+		r.Post("/", customerHandler(createFeed))
+		r.Route("/{feedId}", func(r chi.Router) {
+			r.Get("/", feedHandler(getFeed))
+			r.Delete("/", feedHandler(deleteFeed))
+			r.Post("/poll", feedHandler(pollFeed))
+			r.Get("/items", feedHandler(getFeedItems))
 		})
 	})
 
@@ -75,6 +110,7 @@ func Handler(mux chi.Router) {
 		r.Put("/query", customerHandler(queryVectorStore))
 		r.Put("/queryDocs", customerHandler(queryVectorStoreDocuments))
 		r.Put("/queryWebsitePages", customerHandler(queryVectorStoreWebsitePages))
+		r.Put("/queryFeedItems", customerHandler(queryVectorStoreFeedItems))
 		r.Put("/queryRaw", customerHandler(queryVectorStoreRaw))
 		r.Get("/vectorize", customerHandler(getAllVectorizeRequests))
 		r.Post("/vectorize", customerHandler(createVectorizeRequest))
@@ -99,6 +135,8 @@ func Handler(mux chi.Router) {
 	// rag
 	mux.Post("/rag", customerHandler(handleRAG))
 	mux.Get("/rag2", customerHandler(handleRag2))
+	mux.Post("/rag2/ticket", customerHandler(createRag2Ticket))
+	mux.Post("/rag2/confirmSave", customerHandler(confirmRag2Save))
 
 	// resume
 	mux.Route("/resumes", resume.Handler)
@@ -190,6 +228,40 @@ func websiteHandler(
 	)
 }
 
+func feedHandler(
+	handler func(
+		w http.ResponseWriter,
+		r *http.Request,
+		pool *pgxpool.Pool,
+		c *Customer,
+		feed *queries.Feed,
+	),
+) http.HandlerFunc {
+	return http.HandlerFunc(
+		customerHandler(func(w http.ResponseWriter, r *http.Request, pool *pgxpool.Pool, c *Customer) {
+			id := chi.URLParam(r, "feedId")
+			feedId, err := uuid.Parse(id)
+			if err != nil {
+				c.logger.Error("Invalid feedId", "feedId", id)
+				http.Error(w, fmt.Sprintf("Invalid feedId: %s", id), http.StatusBadRequest)
+				return
+			}
+
+			// get the feed from the db
+			model := queries.New(pool)
+			feed, err := model.GetFeed(r.Context(), feedId)
+			if err != nil || feed.CustomerID != c.ID {
+				c.logger.Error("Error getting the feed", "error", err)
+				http.Error(w, fmt.Sprintf("There was no feed found with feedId: %s", id), http.StatusNotFound)
+				return
+			}
+
+			// pass to the handler
+			handler(w, r, pool, c, feed)
+		}),
+	)
+}
+
 func getCustomer(
 	w http.ResponseWriter,
 	r *http.Request,
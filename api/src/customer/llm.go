@@ -2,9 +2,12 @@ package customer
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sapphirenw/ai-content-creation-api/src/llm"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
@@ -188,7 +191,11 @@ func (c *Customer) GetSummaryLLM(
 		return nil, slogger.Error(ctx, logger, "failed to get the summary llm", err)
 	}
 
-	return llm.FromObjects(&response.Llm, &response.AvailableModel), nil
+	model := llm.FromObjects(&response.Llm, &response.AvailableModel)
+	if err := model.LoadRetryPolicy(ctx, db); err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to load the retry policy of the summary llm", err)
+	}
+	return model, nil
 }
 
 func (c *Customer) GetChatLLM(
@@ -203,5 +210,106 @@ func (c *Customer) GetChatLLM(
 		return nil, slogger.Error(ctx, logger, "failed to get the summary llm", err)
 	}
 
-	return llm.FromObjects(&response.Llm, &response.AvailableModel), nil
+	model := llm.FromObjects(&response.Llm, &response.AvailableModel)
+	if err := model.LoadRetryPolicy(ctx, db); err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to load the retry policy of the chat llm", err)
+	}
+	return model, nil
+}
+
+// parses the llm id of the url, and ensures the llm belongs to the customer
+func (c *Customer) getOwnedLLMId(r *http.Request, db queries.DBTX) (uuid.UUID, error) {
+	llmId, err := uuid.Parse(chi.URLParam(r, "llmId"))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid llm id: %w", err)
+	}
+	dmodel := queries.New(db)
+	item, err := dmodel.GetLLM(r.Context(), llmId)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get the llm: %w", err)
+	}
+	if item.Llm.CustomerID != utils.GoogleUUIDToPGXUUID(c.ID) {
+		return uuid.Nil, fmt.Errorf("the llm does not belong to the customer")
+	}
+	return llmId, nil
+}
+
+// Gets the retry policy of the llm, or the default policy when it has none
+func getRetryPolicy(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "getRetryPolicy")
+
+	llmId, err := c.getOwnedLLMId(r, pool)
+	if err != nil {
+		slogger.ServerError(w, logger, 404, "failed to get the llm", err)
+		return
+	}
+
+	policy, err := llm.GetRetryPolicy(r.Context(), pool, llmId)
+	if err != nil {
+		slogger.ServerError(w, logger, 500, "failed to get the retry policy", err)
+		return
+	}
+
+	response := &retryPolicyResponse{Policy: policy}
+	if policy == nil {
+		response.Policy = llm.DefaultRetryPolicy()
+		response.IsDefault = true
+	}
+	request.Encode(w, r, logger, http.StatusOK, response)
+}
+
+func updateRetryPolicy(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "updateRetryPolicy")
+
+	llmId, err := c.getOwnedLLMId(r, pool)
+	if err != nil {
+		slogger.ServerError(w, logger, 404, "failed to get the llm", err)
+		return
+	}
+
+	body, valid := request.Decode[updateRetryPolicyRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
+
+	policy, err := llm.SaveRetryPolicy(r.Context(), pool, c.ID, llmId, body.policy())
+	if err != nil {
+		slogger.ServerError(w, logger, 400, "failed to save the retry policy", err)
+		return
+	}
+
+	request.Encode(w, r, logger, http.StatusOK, &retryPolicyResponse{Policy: policy})
+}
+
+// Removes the retry policy of the llm, so it goes back to the default
+func deleteRetryPolicy(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "deleteRetryPolicy")
+
+	llmId, err := c.getOwnedLLMId(r, pool)
+	if err != nil {
+		slogger.ServerError(w, logger, 404, "failed to get the llm", err)
+		return
+	}
+
+	if err := llm.DeleteRetryPolicy(r.Context(), pool, llmId); err != nil {
+		slogger.ServerError(w, logger, 500, "failed to delete the retry policy", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
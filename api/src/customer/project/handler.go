@@ -1,6 +1,7 @@
 package project
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/go-chi/httplog/v2"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
 	db "github.com/sapphirenw/ai-content-creation-api/src/database"
 	"github.com/sapphirenw/ai-content-creation-api/src/request"
 )
@@ -117,6 +119,11 @@ func generateIdeas(
 	response, err := p.GenerateIdeas(r.Context(), tx, &body)
 	if err != nil {
 		p.logger.Error("failed to generate ideas", "error", err)
+		var validationErr *conversation.JsonValidationError
+		if errors.As(err, &validationErr) {
+			http.Error(w, "The model did not respond with valid ideas", http.StatusBadGateway)
+			return
+		}
 		http.Error(w, "There was an internal issue", http.StatusInternalServerError)
 		return
 	}
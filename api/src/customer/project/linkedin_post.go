@@ -2,6 +2,7 @@ package project
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -22,6 +23,12 @@ type LinkedInPost struct {
 	*queries.LinkedinPost
 }
 
+// The structured response of the model when generating a post
+type LinkedInPostDraft struct {
+	Post     string   `json:"post" jsonschema:"description=The full text of the post;minLength=1"`
+	Hashtags []string `json:"hashtags,omitempty" jsonschema:"description=The hashtags to add to the post, without the leading #;maxItems=5"`
+}
+
 func (post *LinkedInPost) GetConfig(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -199,6 +206,11 @@ func generateLinkedInPost(
 	response, err := p.GenerateLinkedInPost(r.Context(), tx, post, &body)
 	if err != nil {
 		p.logger.Error("failed to generate the linkedin post", "error", err)
+		var validationErr *conversation.JsonValidationError
+		if errors.As(err, &validationErr) {
+			http.Error(w, "The model did not respond with a valid post", http.StatusBadGateway)
+			return
+		}
 		http.Error(w, "There was an internal issue", http.StatusInternalServerError)
 		return
 	}
@@ -232,7 +244,7 @@ func (p *Project) GenerateLinkedInPost(
 
 	// get the conversation
 	conv, err := conversation.AutoConversation(
-		ctx, logger, db, p.CustomerID, args.ConversationId, prompts.LINKEDIN_POST_SYSTEM,
+		ctx, logger, db, p.CustomerID, args.ConversationId, prompts.TEMPLATE_LINKEDIN_POST,
 		fmt.Sprintf("LinkedIn Post: %s-conv", post.Title),
 		"linkedin-post",
 	)
@@ -240,9 +252,33 @@ func (p *Project) GenerateLinkedInPost(
 		return nil, fmt.Errorf("failed to get the conversation: %w", err)
 	}
 
+	// retry from the middle of the conversation on a new branch
+	if args.RegenerateIndex != 0 {
+		logger.InfoContext(ctx, "Regenerating the post ...", "index", args.RegenerateIndex)
+		if err := conv.BranchAIMessage(ctx, db, args.RegenerateIndex); err != nil {
+			return nil, fmt.Errorf("failed to branch the conversation: %w", err)
+		}
+		draft, err := conversation.JsonCompletion[LinkedInPostDraft](conv, ctx, db, genModel, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send the completion: %w", err)
+		}
+		return &generateLinkedInPostResponse{
+			ConversationId: conv.ID,
+			Messages:       conv.GetMessages(),
+			LatestMessage:  draft.Post,
+			Draft:          draft,
+		}, nil
+	}
+	if args.EditIndex != 0 {
+		logger.InfoContext(ctx, "Editing the user message ...", "index", args.EditIndex)
+		if err := conv.BranchUserMessage(ctx, db, args.EditIndex); err != nil {
+			return nil, fmt.Errorf("failed to branch the conversation: %w", err)
+		}
+	}
+
 	// create a prompt
 	var prompt string
-	if conv.New {
+	if conv.New || len(conv.GetMessages()) == 1 {
 		// first user message
 		prompt = fmt.Sprintf("Title: %s\nWhat the post should be about: %s", post.Title, args.Input)
 	} else {
@@ -253,7 +289,9 @@ func (p *Project) GenerateLinkedInPost(
 	logger.InfoContext(ctx, "Sending completion request ...")
 
 	// create the post
-	response, err := conv.Completion(ctx, db, genModel, gollm.NewUserMessage(prompt), nil, nil)
+	draft, err := conversation.JsonCompletion[LinkedInPostDraft](
+		conv, ctx, db, genModel, &gollm.Message{Role: gollm.RoleUser, Message: prompt}, nil,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send the completion: %w", err)
 	}
@@ -261,6 +299,7 @@ func (p *Project) GenerateLinkedInPost(
 	return &generateLinkedInPostResponse{
 		ConversationId: conv.ID,
 		Messages:       conv.GetMessages(),
-		LatestMessage:  response.Message.Message,
+		LatestMessage:  draft.Post,
+		Draft:          draft,
 	}, nil
 }
@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
 	"github.com/sapphirenw/ai-content-creation-api/src/testingutils"
 	"github.com/sapphirenw/ai-content-creation-api/src/utils"
-	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
 	"github.com/stretchr/testify/require"
 )
 
@@ -86,12 +87,37 @@ func TestLinkedInPostGenerate(t *testing.T) {
 	post, err := project.NewLinkedInPost(ctx, pool, "", "Congratulations My Coworker!")
 	require.NoError(t, err)
 
+	// generate the post with a scripted model
+	row := testingutils.GetMockLLM(t, ctx, pool, c, "mock-linkedin-post")
+	llm.Mock.Reset()
+	defer llm.Mock.Reset()
+	defaultConfig, err := post.GetConfig(ctx, logger, pool)
+	require.NoError(t, err)
+	_, err = project.CreateLinkedInPostConfig(ctx, pool, &createLinkedinPostConfigRequest{
+		LinkedInPostId:            post.ID.String(),
+		MinSections:               1,
+		MaxSections:               3,
+		NumDocuments:              1,
+		NumWebsitePages:           1,
+		LlmContentCenerationId:    row.Llm.ID.String(),
+		LlmVectorSummarizationId:  utils.StringFromPGXUUID(defaultConfig.LlmVectorSummarizationID),
+		LlmWebsiteSummarizationId: utils.StringFromPGXUUID(defaultConfig.LlmWebsiteSummarizationID),
+		LlmProofReadingId:         utils.StringFromPGXUUID(defaultConfig.LlmProofReadingID),
+	})
+	require.NoError(t, err)
+	llm.Mock.Queue("mock-linkedin-post",
+		&llm.MockResponse{Message: `{"post": "Congratulations to my coworker on the promotion!", "hashtags": ["promotion"]}`},
+		&llm.MockResponse{Message: `{"post": "My coworker got promoted. Watch out, the bar just went up!", "hashtags": ["promotion", "zest"]}`},
+	)
+
 	// send the first generation
 	response1, err := project.GenerateLinkedInPost(ctx, pool, post, &generateLinkedInPostRequest{
 		ConversationId: "",
 		Input:          "A post congratulating my co-worker for their promotion.",
 	})
 	require.NoError(t, err)
+	require.Equal(t, "Congratulations to my coworker on the promotion!", response1.Draft.Post)
+	require.Equal(t, response1.Draft.Post, response1.LatestMessage)
 
 	// send corrections
 	response2, err := project.GenerateLinkedInPost(ctx, pool, post, &generateLinkedInPostRequest{
@@ -99,9 +125,17 @@ func TestLinkedInPostGenerate(t *testing.T) {
 		Input:          "Make it more aggressive! We need some ZEST in the workplace.",
 	})
 	require.NoError(t, err)
+	require.Equal(t, response1.ConversationId, response2.ConversationId)
+	require.Equal(t, []string{"promotion", "zest"}, response2.Draft.Hashtags)
+
+	// the feedback was sent with the first draft in the conversation
+	requests := llm.Mock.Requests()
+	require.Len(t, requests, 2)
+	require.True(t, requests[0].Json)
+	last := requests[1].Messages[len(requests[1].Messages)-1]
+	require.Contains(t, last.Message, "ZEST in the workplace")
 
-	// print the conversation
 	conv, err := conversation.GetConversation(ctx, logger, pool, response2.ConversationId)
 	require.NoError(t, err)
-	conv.PrintConversation()
+	require.Len(t, conv.GetMessages(), 5)
 }
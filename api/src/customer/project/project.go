@@ -143,7 +143,7 @@ func (p *Project) GenerateIdeas(
 		p.CustomerID,
 
 		args.ConversationId,
-		prompts.PROJECT_IDEA_SYSTEM,
+		prompts.TEMPLATE_PROJECT_IDEA,
 		fmt.Sprintf("Idea Generation for project: %s", p.Title),
 		"idea-generation",
 	)
@@ -164,8 +164,7 @@ func (p *Project) GenerateIdeas(
 
 	// run the completion against the conversation
 	response, err := conversation.JsonCompletion[projectIdeas](
-		conv, ctx, db, model, gollm.NewUserMessage(prompt), nil,
-		`{"ideas": [{"title", string}]}`,
+		conv, ctx, db, model, &gollm.Message{Role: gollm.RoleUser, Message: prompt}, nil,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("the completion failed: %w", err)
@@ -2,9 +2,9 @@ package project
 
 // for holding the list that is generated from the model
 type projectIdeas struct {
-	Ideas []*ProjectIdea `json:"ideas"`
+	Ideas []*ProjectIdea `json:"ideas" jsonschema:"description=The generated ideas;minItems=1"`
 }
 
 type ProjectIdea struct {
-	Title string `json:"title"`
+	Title string `json:"title" jsonschema:"description=The title of the idea;minLength=1"`
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sapphirenw/ai-content-creation-api/src/llm"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/testingutils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -75,13 +76,9 @@ import (
 // }
 
 func getTestProject(t *testing.T, ctx context.Context, logger *slog.Logger, pool *pgxpool.Pool, c *queries.Customer) *Project {
-	// create a custom model to use for generating ideas on the project
-	model, err := llm.CreateLLM(
-		ctx, logger, pool, c, "Idea Generator", "gpt-3.5-turbo", 1.2,
-		"You are inquisitive, yet bold with your outputs. You have a keen eye for what people will want to listen too, without overloading with buzzwords or unnecessarily complex language. You are also pay extreme attention to the instructions you are given.",
-		false,
-	)
-	require.NoError(t, err)
+	// generate the ideas of the project with a scripted model so the tests run offline
+	row := testingutils.GetMockLLM(t, ctx, pool, c, "mock-project")
+	model := llm.FromObjects(&row.Llm, &row.AvailableModel)
 
 	// create a test project
 	project, err := CreateProject(
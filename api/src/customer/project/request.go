@@ -75,12 +75,30 @@ func (r createLinkedinPostConfigRequest) Valid(ctx context.Context) map[string]s
 type generateLinkedInPostRequest struct {
 	ConversationId string `json:"conversationId"`
 	Input          string `json:"input"`
+
+	// optional, retry from the middle of the conversation on a new branch. EditIndex replaces
+	// the user message at the index with the input, RegenerateIndex regenerates the post at
+	// the index
+	EditIndex       int `json:"editIndex"`
+	RegenerateIndex int `json:"regenerateIndex"`
 }
 
 func (r generateLinkedInPostRequest) Valid(ctx context.Context) map[string]string {
 	p := make(map[string]string)
-	if r.Input == "" {
+	if r.Input == "" && r.RegenerateIndex == 0 {
 		p["input"] = "cannot be empty"
 	}
+	if (r.EditIndex != 0 || r.RegenerateIndex != 0) && r.ConversationId == "" {
+		p["conversationId"] = "cannot be empty when editing or regenerating"
+	}
+	if r.EditIndex != 0 && r.RegenerateIndex != 0 {
+		p["editIndex"] = "cannot be set with regenerateIndex"
+	}
+	if r.EditIndex < 0 {
+		p["editIndex"] = "cannot be negative"
+	}
+	if r.RegenerateIndex < 0 {
+		p["regenerateIndex"] = "cannot be negative"
+	}
 	return p
 }
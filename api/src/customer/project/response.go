@@ -11,7 +11,8 @@ type generateIdeasResponse struct {
 }
 
 type generateLinkedInPostResponse struct {
-	ConversationId uuid.UUID        `json:"conversationId"`
-	Messages       []*gollm.Message `json:"messages"`
-	LatestMessage  string           `json:"latestMessage"`
+	ConversationId uuid.UUID          `json:"conversationId"`
+	Messages       []*gollm.Message   `json:"messages"`
+	LatestMessage  string             `json:"latestMessage"` // the text of the post
+	Draft          *LinkedInPostDraft `json:"draft"`
 }
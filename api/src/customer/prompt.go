@@ -0,0 +1,153 @@
+package customer
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
+	"github.com/sapphirenw/ai-content-creation-api/src/request"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+type getPromptTemplatesResponse struct {
+	Templates []*prompts.Template        `json:"templates"` // the version of every prompt the customer uses
+	Defaults  []*prompts.DefaultTemplate `json:"defaults"`  // the built-in prompts with their variables
+}
+
+// parses the name of the prompt from the url
+func promptTemplateName(w http.ResponseWriter, r *http.Request, logger *slog.Logger) (prompts.TemplateName, bool) {
+	name := prompts.TemplateName(chi.URLParam(r, "name"))
+	if _, err := prompts.GetDefaultTemplate(name); err != nil {
+		slogger.ServerError(w, logger, 404, "failed to get the prompt", err)
+		return "", false
+	}
+	return name, true
+}
+
+func getPromptTemplates(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "getPromptTemplates")
+
+	templates, err := prompts.GetActiveTemplates(r.Context(), pool, c.ID)
+	if err != nil {
+		slogger.ServerError(w, logger, 500, "failed to get the prompt templates", err)
+		return
+	}
+
+	request.Encode(w, r, logger, http.StatusOK, &getPromptTemplatesResponse{
+		Templates: templates,
+		Defaults:  prompts.DefaultTemplates(),
+	})
+}
+
+func getPromptTemplateVersions(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "getPromptTemplateVersions")
+	name, ok := promptTemplateName(w, r, logger)
+	if !ok {
+		return
+	}
+
+	response, err := prompts.GetTemplateVersions(r.Context(), pool, c.ID, name)
+	if err != nil {
+		slogger.ServerError(w, logger, 500, "failed to get the prompt template versions", err)
+		return
+	}
+
+	request.Encode(w, r, logger, http.StatusOK, response)
+}
+
+// Saves a new version of the prompt for the customer, which becomes the version they use
+func savePromptTemplate(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "savePromptTemplate")
+	name, ok := promptTemplateName(w, r, logger)
+	if !ok {
+		return
+	}
+
+	body, valid := request.Decode[savePromptTemplateRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
+
+	response, err := prompts.SaveCustomerTemplate(r.Context(), pool, c.ID, name, body.Content, body.Description)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid template") {
+			slogger.ServerError(w, logger, 400, "the template is not valid", err)
+			return
+		}
+		slogger.ServerError(w, logger, 500, "failed to save the prompt template", err)
+		return
+	}
+
+	request.Encode(w, r, logger, http.StatusCreated, response)
+}
+
+// Switches the customer to one of their saved versions of the prompt
+func activatePromptTemplate(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "activatePromptTemplate")
+	name, ok := promptTemplateName(w, r, logger)
+	if !ok {
+		return
+	}
+
+	body, valid := request.Decode[activatePromptTemplateRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
+
+	response, err := prompts.ActivateCustomerTemplate(r.Context(), pool, c.ID, name, body.Version)
+	if err != nil {
+		if strings.Contains(err.Error(), "there is no version") {
+			slogger.ServerError(w, logger, 404, "failed to get the version", err)
+			return
+		}
+		slogger.ServerError(w, logger, 500, "failed to activate the prompt template", err)
+		return
+	}
+
+	request.Encode(w, r, logger, http.StatusOK, response)
+}
+
+// Removes the override of the prompt so the customer goes back to the default. The saved
+// versions are kept and can be activated again
+func resetPromptTemplate(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "resetPromptTemplate")
+	name, ok := promptTemplateName(w, r, logger)
+	if !ok {
+		return
+	}
+
+	if err := prompts.ResetCustomerTemplate(r.Context(), pool, c.ID, name); err != nil {
+		slogger.ServerError(w, logger, 500, "failed to reset the prompt template", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
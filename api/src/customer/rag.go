@@ -68,7 +68,7 @@ func handleRAG(
 
 	// send the response
 	conversationId := r.URL.Query().Get("conversationId")
-	response, err := c.RAG(r.Context(), tx, conversationId, &body)
+	response, err := c.RAG(r.Context(), pool, tx, conversationId, &body)
 	if err != nil {
 		tx.Rollback(r.Context())
 		c.logger.Error("failed to query the vectorstore", "error", err)
@@ -81,6 +81,7 @@ func handleRAG(
 
 func (c *Customer) RAG(
 	ctx context.Context,
+	pool *pgxpool.Pool,
 	db queries.DBTX,
 	conversationId string,
 	args *ragRequest,
@@ -90,10 +91,6 @@ func (c *Customer) RAG(
 
 	// get the conversation
 
-	// initial setup
-	logger.DebugContext(ctx, "Getting required objects ...")
-	ragTools := getRAGTools()
-
 	// track all token usage across this request through a buffered channel
 	// var tokenMutex sync.Mutex
 	usageRecords := make([]*tokens.UsageRecord, 0)
@@ -121,7 +118,7 @@ func (c *Customer) RAG(
 		db,
 		c.ID,
 		conversationId,
-		prompts.RAG_COMPLETE_SYSTEM_PROMPT,
+		prompts.TEMPLATE_RAG_COMPLETE,
 		"Information Chat",
 		"rag",
 	)
@@ -129,11 +126,23 @@ func (c *Customer) RAG(
 		return nil, fmt.Errorf("failed to parse the conversation: %w", err)
 	}
 
+	// get the tools the customer enabled for the conversation
+	logger.DebugContext(ctx, "Getting the tools ...")
+	toolConfig, err := tool.GetToolConfig(ctx, db, c.ID, conv.ConversationType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the tool configuration: %w", err)
+	}
+	ragTools := toolConfig.Tools()
+
 	// check the state of the conversation
 	if conv.New {
 		// send a request for a tool usage
+		var requiredTool *gollm.Tool
+		if len(ragTools) != 0 {
+			requiredTool = ragTools[0].GetSchema()
+		}
 		message := gollm.NewUserMessage(args.Input)
-		response, err := conv.Completion(ctx, db, chatLLM, message, tool.ToolsToGollm(ragTools), ragTools[0].GetSchema())
+		response, err := conv.Completion(ctx, db, chatLLM, message, tool.ToolsToGollm(ragTools), requiredTool)
 		if err != nil {
 			return nil, fmt.Errorf("failed the completion: %w", err)
 		}
@@ -162,7 +171,10 @@ func (c *Customer) RAG(
 		if err != nil {
 			return nil, slogger.Error(ctx, logger, "failed to parse the tool name", err)
 		}
-		parsedTool := tool.NewTool(toolType)
+		parsedTool, err := tool.NewTool(toolType)
+		if err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to create the tool", err)
+		}
 
 		// get the summary llm
 		summaryLLM, err := llm.GetLLMString(ctx, db, c.ID, args.SummaryModelId)
@@ -172,6 +184,7 @@ func (c *Customer) RAG(
 
 		vecResponse, err := parsedTool.Run(ctx, logger, &tool.RunToolArgs{
 			Database:    db,
+			Pool:        pool,
 			Customer:    c.Customer,
 			LastMessage: lastMessage,
 			ToolLLM:     summaryLLM,
@@ -232,9 +245,3 @@ func (c *Customer) RAG(
 		return nil, fmt.Errorf("invalid conversation state. Last message role: %s", lastMessage.Role.ToString())
 	}
 }
-
-func getRAGTools() []tool.Tool {
-	tools := make([]tool.Tool, 0)
-	tools = append(tools, tool.NewTool(tool.VectorQuery))
-	return tools
-}
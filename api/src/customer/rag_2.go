@@ -3,11 +3,15 @@ package customer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,8 +19,10 @@ import (
 	"github.com/jake-landersweb/gollm/v2/src/tokens"
 	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
 	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/middleware"
 	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/request"
 	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
 	"github.com/sapphirenw/ai-content-creation-api/src/tool"
 	"github.com/sapphirenw/ai-content-creation-api/src/utils"
@@ -26,36 +32,62 @@ type ragMessageType string
 
 const (
 	ragError             ragMessageType = "error"
-	ragNewMessage        ragMessageType = "newMessage"
 	ragNewConversationId ragMessageType = "newConversationId"
 	ragTitleUpdate       ragMessageType = "titleUpdate"
 	ragChangeChatLLM     ragMessageType = "changeChatLLM"
+	ragMessageDelta      ragMessageType = "messageDelta"
+	ragMessageSnapshot   ragMessageType = "messageSnapshot" // the deltas of a message merged on resume
+	ragToolCallStart     ragMessageType = "toolCallStart"
+	ragToolCallFinish    ragMessageType = "toolCallFinish"
+	ragMessageComplete   ragMessageType = "messageComplete"
+	ragCancelled         ragMessageType = "cancelled"
+	ragConnected         ragMessageType = "connected"
+	ragAck               ragMessageType = "ack"
+	ragResumed           ragMessageType = "resumed"
+	ragResumeFailed      ragMessageType = "resumeFailed"
+	ragBranched          ragMessageType = "branched"
+	ragBranchSwitched    ragMessageType = "branchSwitched"
+)
+
+// message types sent by the client
+const (
+	ragClientMessage       = "ragMessage"
+	ragClientChangeChatLLM = "changeChatLLM"
+	ragClientCancel        = "cancel"
+	ragClientAck           = "ack"
+	ragClientResume        = "resume"
+	ragClientEditMessage   = "editMessage"
+	ragClientRegenerate    = "regenerate"
+	ragClientSwitchBranch  = "switchBranch"
 )
 
 /*
 A RAG message is the content that is sent to the user through a websocket.
 this can be many different types of messages, such as control flow (loading),
 messages to and from the AI, tool calls, error throws, and so on.
+It is sent as the payload of a `ragEnvelope`.
 */
 type ragMessage struct {
-	// consistent for all messages
+	// sent as the type of the envelope
 
-	MessageType ragMessageType `json:"messageType"`
+	MessageType ragMessageType `json:"-"`
 
 	// dependent on the message type
 
 	Error          string         `json:"error,omitempty"`
 	ChatMessage    *gollm.Message `json:"chatMessage,omitempty"`
-	ConversationId string         `json:"conversationId"`
+	ConversationId string         `json:"conversationId,omitempty"`
 	NewTitle       string         `json:"newTitle,omitempty"`
 	ChatLLM        *llm.LLM       `json:"chatLLM,omitempty"`
-}
+	Delta          string         `json:"delta,omitempty"`
+	ToolName       string         `json:"toolName,omitempty"`
+	AckID          string         `json:"ackId,omitempty"`
+	SessionId      string         `json:"sessionId,omitempty"`
+	LastSeq        int64          `json:"lastSeq,omitempty"`
+	BranchIndex    int            `json:"branchIndex,omitempty"`
+	MessageId      *uuid.UUID     `json:"messageId,omitempty"`
 
-func newRmChatMessage(msg *gollm.Message) *ragMessage {
-	return &ragMessage{
-		MessageType: ragNewMessage,
-		ChatMessage: msg,
-	}
+	Citations []*conversation.Citation `json:"citations,omitempty"`
 }
 
 func newRmError(msg string, err error) *ragMessage {
@@ -66,37 +98,123 @@ func newRmError(msg string, err error) *ragMessage {
 	}
 }
 
-type ragUserMessage struct {
-	MessageType string `json:"messageType"`
-
-	Message   string `json:"message"`
-	ChatLLMID string `json:"chatLLMId"`
-}
-
+// Sends the message as an event on the session
 func writeRagResponse(
 	ctx context.Context,
 	logger *slog.Logger,
-	conn *websocket.Conn,
+	session *ragSession,
 	message *ragMessage,
 ) error {
-	// encode the message
-	enc, err := json.Marshal(message)
-	if err != nil {
+	if err := session.send(ctx, logger, message); err != nil {
 		return slogger.Error(ctx, logger, "failed to write the message", err)
 	}
+	return nil
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin:  middleware.CheckWebsocketOrigin,
+	Subprotocols: []string{middleware.WebsocketProtocol},
+}
+
+// Creates a ticket to open the rag2 websocket from a browser. The ticket is passed as the
+// `ticket` query parameter, or as a `ticket.<ticket>` subprotocol
+func createRag2Ticket(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	body, valid := request.Decode[createRag2TicketRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
 
-	// write the message on the connection
-	if err := conn.WriteJSON(enc); err != nil {
-		return slogger.Error(ctx, logger, "failed to write on the websocket", err)
+	// ensure the conversation belongs to the customer
+	if body.ConversationId != "" {
+		dmodel := queries.New(pool)
+		conv, err := dmodel.GetConversation(r.Context(), uuid.MustParse(body.ConversationId))
+		if err != nil || conv.CustomerID != c.ID {
+			slogger.ServerError(w, c.logger, 404, "the conversation was not found", err)
+			return
+		}
 	}
 
-	return nil
+	ticket, parsed, err := middleware.NewWebsocketTicket(c.ID, body.ConversationId)
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to create the ticket", err)
+		return
+	}
+
+	request.Encode(w, r, c.logger, http.StatusOK, &createRag2TicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: time.Unix(parsed.ExpiresAt, 0),
+	})
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+// Saves the page a web search offered to save into the websites of the customer, once the user
+// confirmed it in the chat
+func confirmRag2Save(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	body, valid := request.Decode[confirmRag2SaveRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
+	logger := c.logger.With("handler", "confirmRag2Save", "messageId", body.MessageId)
+
+	// ensure the message belongs to the customer
+	dmodel := queries.New(pool)
+	conv, err := dmodel.GetConversation(r.Context(), uuid.MustParse(body.ConversationId))
+	if err != nil || conv.CustomerID != c.ID {
+		slogger.ServerError(w, logger, 404, "the conversation was not found", err)
+		return
+	}
+	message, err := dmodel.GetConversationMessage(r.Context(), &queries.GetConversationMessageParams{
+		ID:             uuid.MustParse(body.MessageId),
+		ConversationID: conv.ID,
+	})
+	if err != nil {
+		slogger.ServerError(w, logger, 404, "the message was not found", err)
+		return
+	}
+	if message.Role != gollm.RoleToolResult.ToString() || message.ToolName != string(tool.WebSearch) {
+		slogger.ServerError(w, logger, 400, "the message is not a web search result", nil)
+		return
+	}
+	u, results, err := tool.ConfirmPendingSave(message.ToolResults, time.Now())
+	if err != nil {
+		slogger.ServerError(w, logger, 400, "the save cannot be confirmed", err)
+		return
+	}
+
+	// save the page and mark the save on the message together
+	tx, err := pool.Begin(r.Context())
+	if err != nil {
+		slogger.ServerError(w, logger, 500, "failed to start the transaction", err)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	if err := c.InsertSinglePage(r.Context(), tx, u); err != nil {
+		slogger.ServerError(w, logger, 500, "failed to save the page", err)
+		return
+	}
+	if err := queries.New(tx).UpdateConversationMessageToolResults(r.Context(), &queries.UpdateConversationMessageToolResultsParams{
+		ID:          message.ID,
+		ToolResults: results,
+	}); err != nil {
+		slogger.ServerError(w, logger, 500, "failed to mark the save on the message", err)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		slogger.ServerError(w, logger, 500, "failed to commit the transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func handleRag2(
@@ -108,48 +226,279 @@ func handleRag2(
 	logger := c.logger.With("handler", "rag2")
 
 	// upgrade the connection
-	conn, err := upgrader.Upgrade(w, r, nil)
+	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		slogger.ServerError(w, logger, 500, "failed to upgrade the connection", err)
 		return
 	}
-	defer conn.Close()
+	defer ws.Close()
+	conn := newRagConn(ws)
 
 	logger.Info("Opened ws connection")
 
-	// hold the conversation in memory for the request
-	var conv *conversation.Conversation
+	// attach to the running session of the conversation, or create a new one
+	session, err := c.rag2Session(r.Context(), logger, pool, r.URL.Query().Get("id"))
+	if err != nil {
+		conn.reply(r.Context(), logger, newRmError("failed to get the llm", err))
+		return
+	}
+	session.attach(conn)
+	defer func() {
+		session.detach(conn)
+	}()
 
-	dmodel := queries.New(pool)
+	// ping the client while the connection is open
+	done := make(chan struct{})
+	defer close(done)
+	go conn.heartbeat(done)
 
-	var chatLLM *llm.LLM
+	// send the session to the user so it can resume, along with the chatllm object
+	conn.reply(r.Context(), logger, rag2Connected(session))
+	conn.reply(r.Context(), logger, &ragMessage{
+		MessageType: ragChangeChatLLM,
+		ChatLLM:     session.chatLLM,
+	})
 
+	for {
+		// read the message that was passed from the user
+		logger.Debug("Reading message")
+		envelope, err := conn.read()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.Warn("failed to read the message", "error", err)
+			}
+			break
+		}
+
+		logger.Debug("Recieved message from user", "type", envelope.Type, "id", envelope.ID)
+
+		// parse the user message
+		if envelope.Version != ragProtocolVersion {
+			conn.reply(r.Context(), logger, newRmError("invalid message", fmt.Errorf("unsupported protocol version: %d", envelope.Version)))
+			continue
+		}
+		var payload ragClientPayload
+		if len(envelope.Payload) != 0 {
+			if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+				conn.reply(r.Context(), logger, newRmError("failed to read the user message", err))
+				continue
+			}
+		}
+
+		// acks are not acknowledged
+		if envelope.Type == ragClientAck {
+			session.ack(payload.Seq)
+			continue
+		}
+
+		// parse the user request
+		logger.Info("Handling user message", "type", envelope.Type)
+		conn.reply(r.Context(), logger, &ragMessage{
+			MessageType: ragAck,
+			AckID:       envelope.ID,
+		})
+
+		switch envelope.Type {
+		case ragClientResume:
+			session = c.rag2Resume(r.Context(), logger, conn, session, &payload)
+
+		case ragClientCancel:
+			// cancel the running generation
+			if session.cancel() {
+				logger.Info("Cancelling the running generation")
+			}
+
+		case ragClientChangeChatLLM:
+			// only a cancel is accepted while a message is being generated
+			if session.running() {
+				conn.reply(r.Context(), logger, newRmError("invalid message", fmt.Errorf("a message is already being generated")))
+				continue
+			}
+
+			// parse the id
+			chatLLMId, err := utils.GoogleUUIDFromString(payload.ChatLLMID)
+			if err != nil {
+				conn.reply(r.Context(), logger, newRmError("invalid message id", err))
+				// do not exit the connection
+				continue
+			}
+
+			// fetch the llm
+			dmodel := queries.New(pool)
+			fetchedLLM, err := dmodel.GetLLM(r.Context(), chatLLMId)
+			if err != nil {
+				conn.reply(r.Context(), logger, newRmError("failed to get the model", err))
+				continue
+			}
+			logger.Info("Setting new chat llm", "llmid", fetchedLLM.Llm.ID.String())
+			session.chatLLM = llm.FromObjects(&fetchedLLM.Llm, &fetchedLLM.AvailableModel)
+
+			// send the message to the client
+			writeRagResponse(r.Context(), logger, session, &ragMessage{
+				MessageType: ragChangeChatLLM,
+				ChatLLM:     session.chatLLM,
+			})
+
+			// save the chatllm to the conversation (THIS CAN TRANSIENTLY FAIL)
+			if session.conv != nil {
+				if err := dmodel.SetChatLLM(r.Context(), &queries.SetChatLLMParams{
+					ID:        session.conv.ID,
+					CurrLlmID: utils.GoogleUUIDToPGXUUID(session.chatLLM.Llm.ID),
+				}); err != nil {
+					logger.Error("failed to set the chatllm", "error", err)
+				}
+			}
+
+		case ragClientSwitchBranch:
+			// only a cancel is accepted while a message is being generated
+			if session.running() {
+				conn.reply(r.Context(), logger, newRmError("invalid message", fmt.Errorf("a message is already being generated")))
+				continue
+			}
+			if session.conv == nil {
+				conn.reply(r.Context(), logger, newRmError("invalid message", fmt.Errorf("there is no conversation")))
+				continue
+			}
+			messageId, err := utils.GoogleUUIDFromString(payload.MessageId)
+			if err != nil {
+				conn.reply(r.Context(), logger, newRmError("invalid message id", err))
+				continue
+			}
+
+			// the client reloads the conversation to get the messages of the branch
+			if err := session.conv.SwitchBranch(r.Context(), pool, messageId); err != nil {
+				conn.reply(r.Context(), logger, newRmError("failed to switch the branch", err))
+				continue
+			}
+			writeRagResponse(r.Context(), logger, session, &ragMessage{
+				MessageType:    ragBranchSwitched,
+				ConversationId: session.conv.ID.String(),
+			})
+
+		case ragClientMessage, ragClientEditMessage, ragClientRegenerate:
+			// only a cancel is accepted while a message is being generated
+			if session.running() {
+				conn.reply(r.Context(), logger, newRmError("invalid message", fmt.Errorf("a message is already being generated")))
+				continue
+			}
+
+			// edits and regenerations branch the conversation at the message
+			input := &ragTurnInput{Input: payload.Message}
+			if envelope.Type != ragClientMessage {
+				if session.conv == nil || payload.Index < 1 {
+					conn.reply(r.Context(), logger, newRmError("invalid message", fmt.Errorf("there is no message to branch from")))
+					continue
+				}
+				input.BranchIndex = payload.Index
+				input.Regenerate = envelope.Type == ragClientRegenerate
+			}
+
+			// check if the conversation exists in memory
+			if session.conv == nil {
+				// fetch the conversation
+				session.conv, err = conversation.AutoConversation(
+					r.Context(),
+					logger,
+					pool,
+					c.ID,
+					r.URL.Query().Get("id"),
+					prompts.TEMPLATE_RAG_COMPLETE,
+					"Information Chat",
+					"rag",
+				)
+				if err != nil {
+					conn.reply(r.Context(), logger, newRmError("failed to get the conversation", err))
+					continue
+				}
+				ragSessions.put(session.conv.ID, session)
+
+				// write the conversation id update
+				writeRagResponse(r.Context(), logger, session, &ragMessage{
+					MessageType:    ragNewConversationId,
+					ConversationId: session.conv.ID.String(),
+				})
+			}
+
+			// run the generation in the background so the loop can read a cancel. The
+			// generation is not tied to the connection so it finishes when the client drops
+			turn := newRagTurn(context.WithoutCancel(r.Context()))
+			if !session.start(turn) {
+				conn.reply(r.Context(), logger, newRmError("invalid message", fmt.Errorf("a message is already being generated")))
+				continue
+			}
+			go func(session *ragSession, logger *slog.Logger, input *ragTurnInput) {
+				defer turn.finish()
+				if err := c.rag2Turn(turn.ctx, logger, pool, session, input); err != nil {
+					writeRagResponse(turn.ctx, logger, session, newRmError("failed to send the message request", err))
+				}
+			}(session, logger.With("conversationId", session.conv.ID.String()), input)
+
+		default:
+			conn.reply(r.Context(), logger, newRmError("invalid message", fmt.Errorf("unknown message type: %s", envelope.Type)))
+		}
+	}
+
+	logger.Info("Closing ws connection")
+}
+
+func rag2Connected(session *ragSession) *ragMessage {
+	message := &ragMessage{
+		MessageType: ragConnected,
+		SessionId:   session.ID.String(),
+		LastSeq:     session.lastSeq(),
+	}
+	if session.conv != nil {
+		message.ConversationId = session.conv.ID.String()
+	}
+	return message
+}
+
+// Gets the running session of the conversation, or creates a new session with the conversation
+// and chat llm loaded from the database
+func (c *Customer) rag2Session(
+	ctx context.Context,
+	logger *slog.Logger,
+	pool *pgxpool.Pool,
+	id string,
+) (*ragSession, error) {
 	// check if a conversation id was passed, and attempt to fetch the conversation
-	convId, err := utils.GoogleUUIDFromString(r.URL.Query().Get("id"))
+	convId, err := utils.GoogleUUIDFromString(id)
+	if err == nil {
+		if session := ragSessions.get(c.ID, convId); session != nil {
+			logger.Debug("attaching to the running session", "session.ID", session.ID)
+			return session, nil
+		}
+	}
+
+	session := newRagSession(c.ID)
+	dmodel := queries.New(pool)
+
 	if err == nil {
 		// get the conversation
-		conv, err = conversation.AutoConversation(
-			r.Context(),
+		session.conv, err = conversation.AutoConversation(
+			ctx,
 			logger,
 			pool,
 			c.ID,
-			r.URL.Query().Get("id"),
-			prompts.RAG_COMPLETE_SYSTEM_PROMPT,
+			id,
+			prompts.TEMPLATE_RAG_COMPLETE,
 			"Information Chat",
 			"rag",
 		)
 		if err != nil {
 			logger.Error("failed to get the conversation", "error", err)
 		} else {
+			ragSessions.put(session.conv.ID, session)
+
 			// get the chat llm as well
-			currLLM, err := dmodel.GetChatLLM(r.Context(), &queries.GetChatLLMParams{
+			currLLM, err := dmodel.GetChatLLM(ctx, &queries.GetChatLLMParams{
 				CustomerID: c.ID,
 				ID:         convId,
 			})
 			if err == nil {
 				// set the current chat llm
 				logger.Debug("the conversation has a saved llm, using this", "llm.ID", currLLM.Llm.ID)
-				chatLLM = llm.FromObjects(&currLLM.Llm, &currLLM.AvailableModel)
+				session.chatLLM = llm.FromObjects(&currLLM.Llm, &currLLM.AvailableModel)
 			} else {
 				if strings.Contains(err.Error(), "no rows in result set") {
 					logger.Info("No saved llm exists")
@@ -159,186 +508,318 @@ func handleRag2(
 			}
 		}
 	} else {
-		logger.Debug("invalid conversation id", "conv.ID", r.URL.Query().Get("id"))
+		logger.Debug("invalid conversation id", "conv.ID", id)
 	}
 
 	// fetch a default llm to use if no conversation llm was found
-	if chatLLM == nil {
-		chatLLM, err = c.GetChatLLM(r.Context(), logger, pool)
+	if session.chatLLM == nil {
+		session.chatLLM, err = c.GetChatLLM(ctx, logger, pool)
 		if err != nil {
-			writeRagResponse(r.Context(), logger, conn, newRmError("failed to get the llm", err))
-			return
+			return nil, err
 		}
 	}
 
-	// send a message to the user populating the chatllm object
-	writeRagResponse(r.Context(), logger, conn, &ragMessage{
-		MessageType: ragChangeChatLLM,
-		ChatLLM:     chatLLM,
-	})
+	return session, nil
+}
 
-	for {
-		// read the message that was passed from the user
-		// the message will always be a string, and always be of type user.
-		logger.Debug("Reading message")
-		mt, message, err := conn.ReadMessage()
+// Handles the resume handshake. The connection moves to the session the client was connected
+// to, and every event the client missed is replayed. The session the connection ends up on is
+// returned
+func (c *Customer) rag2Resume(
+	ctx context.Context,
+	logger *slog.Logger,
+	conn *ragConn,
+	session *ragSession,
+	payload *ragClientPayload,
+) *ragSession {
+	failed := func(reason string) *ragSession {
+		logger.Info("Failed to resume the session", "reason", reason)
+		message := rag2Connected(session)
+		message.MessageType = ragResumeFailed
+		message.Error = reason
+		conn.reply(ctx, logger, message)
+		return session
+	}
+
+	// find the session the client was connected to
+	target := session
+	if payload.SessionId != session.ID.String() {
+		// the connection can only resume the conversation its ticket is for
+		ticket := middleware.GetWebsocketTicket(ctx)
+		if ticket == nil || payload.ConversationId != ticket.ConversationID {
+			return failed("the ticket is not valid for the conversation")
+		}
+		convId, err := utils.GoogleUUIDFromString(payload.ConversationId)
 		if err != nil {
-			writeRagResponse(r.Context(), logger, conn, newRmError("failed to read the message", err))
-			break
+			return failed("the session no longer exists")
+		}
+		target = ragSessions.get(c.ID, convId)
+		if target == nil || payload.SessionId != target.ID.String() {
+			return failed("the session no longer exists")
 		}
+		if session.running() {
+			return failed("a message is being generated on the current session")
+		}
+		session.detach(conn)
+		target.attach(conn)
+	}
 
-		logger.Debug("Recieved message from user", "message", string(message), "type", mt)
+	// send the missed events
+	ok, err := target.replay(conn, payload.Seq)
+	if err != nil {
+		logger.Error("failed to replay the events", "error", err)
+		return target
+	}
+	if !ok {
+		return failed("the missed events are no longer available, reload the conversation")
+	}
 
-		// parse the user message
-		var userMessage ragUserMessage
-		if err := json.Unmarshal(message, &userMessage); err != nil {
-			writeRagResponse(r.Context(), logger, conn, newRmError("failed to read the user message", err))
-			break
+	logger.Info("Resumed the session", "session.ID", target.ID, "seq", payload.Seq)
+	conn.reply(ctx, logger, &ragMessage{
+		MessageType: ragResumed,
+		SessionId:   target.ID.String(),
+		LastSeq:     target.lastSeq(),
+	})
+	return target
+}
+
+// A single generation on the websocket that can be cancelled by the user
+type ragTurn struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newRagTurn(parent context.Context) *ragTurn {
+	ctx, cancel := context.WithCancel(parent)
+	return &ragTurn{
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+func (t *ragTurn) running() bool {
+	if t == nil {
+		return false
+	}
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (t *ragTurn) finish() {
+	t.cancel()
+	close(t.done)
+}
+
+// The input of a turn. When BranchIndex is set, the conversation is branched at the message
+// with the index first, which either replaces a user message with the input, or regenerates
+// an AI response
+type ragTurnInput struct {
+	Input       string
+	BranchIndex int
+	Regenerate  bool
+}
+
+// Runs a user message through the rag chain inside of a transaction. When the user cancels
+// the generation, the partial response that was saved is committed and the session stays open
+func (c *Customer) rag2Turn(
+	ctx context.Context,
+	logger *slog.Logger,
+	pool *pgxpool.Pool,
+	session *ragSession,
+	input *ragTurnInput,
+) error {
+	conv := session.conv
+	chatLLM := session.chatLLM
+
+	// create a transaction to run this call inside of
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to start the transaction", err)
+	}
+
+	// a regeneration runs the completion on the conversation as is
+	var message *gollm.Message
+	if !input.Regenerate {
+		message = gollm.NewUserMessage(input.Input)
+	}
+
+	// start a new branch at the message
+	if input.BranchIndex != 0 {
+		branch := conv.BranchUserMessage
+		if input.Regenerate {
+			branch = conv.BranchAIMessage
+		}
+		if err := branch(ctx, tx, input.BranchIndex); err != nil {
+			tx.Rollback(context.WithoutCancel(ctx))
+			return slogger.Error(ctx, logger, "failed to branch the conversation", err)
 		}
+		if err := writeRagResponse(ctx, logger, session, &ragMessage{
+			MessageType: ragBranched,
+			BranchIndex: input.BranchIndex,
+		}); err != nil {
+			tx.Rollback(context.WithoutCancel(ctx))
+			return err
+		}
+	}
 
-		// parse the user request
-		logger.Info("Handling user message", "type", userMessage.MessageType)
+	// get the tools the customer enabled for the conversation
+	toolConfig, err := tool.GetToolConfig(ctx, tx, c.ID, conv.ConversationType)
+	if err != nil {
+		tx.Rollback(context.WithoutCancel(ctx))
+		return slogger.Error(ctx, logger, "failed to get the tool configuration", err)
+	}
+	chain := &ragChain{
+		pool:     pool,
+		tools:    toolConfig.Tools(),
+		maxDepth: toolConfig.MaxDepth,
+	}
 
-		// handle chanding chat llm
-		if userMessage.MessageType == "changeChatLLM" {
-			// parse the id
-			chatLLMId, err := utils.GoogleUUIDFromString(userMessage.ChatLLMID)
-			if err != nil {
-				writeRagResponse(r.Context(), logger, conn, newRmError("invalid message id", err))
-				// do not exit the connection
-			} else {
-				// fetch the llm
-				dmodel := queries.New(pool)
-				fetchedLLM, err := dmodel.GetLLM(r.Context(), chatLLMId)
-				if err != nil {
-					writeRagResponse(r.Context(), logger, conn, newRmError("failed to get the model", err))
-				} else {
-					logger.Info("Setting new chat llm", "llmid", fetchedLLM.Llm.ID.String())
-					chatLLM = llm.FromObjects(&fetchedLLM.Llm, &fetchedLLM.AvailableModel)
-
-					// send the message to the client
-					writeRagResponse(r.Context(), logger, conn, &ragMessage{
-						MessageType: ragChangeChatLLM,
-						ChatLLM:     chatLLM,
-					})
-
-					// save the chatllm to the conversation (THIS CAN TRANSIENTLY FAIL)
-					if err := dmodel.SetChatLLM(r.Context(), &queries.SetChatLLMParams{
-						ID:        conv.ID,
-						CurrLlmID: utils.GoogleUUIDToPGXUUID(chatLLM.Llm.ID),
-					}); err != nil {
-						logger.Error("failed to set the chatllm", "error", err)
-					}
+	// send the request
+	if err := c.rag2MessageHandler(ctx, logger, tx, session, conv, chatLLM, message, chain); err != nil {
+		if ctx.Err() == nil || !errors.Is(err, context.Canceled) {
+			tx.Rollback(context.WithoutCancel(ctx))
+
+			// the branch was rolled back, so reload the conversation
+			if input.BranchIndex != 0 {
+				if reloaded, err := conversation.GetConversation(context.WithoutCancel(ctx), logger, pool, conv.ID); err == nil {
+					session.conv = reloaded
 				}
 			}
+			return err
 		}
 
-		// handle the rag message
-		if userMessage.MessageType == "ragMessage" {
-			// check if the conversation exists in memory
-			if conv == nil {
-				// fetch the conversation
-				conv, err = conversation.AutoConversation(
-					r.Context(),
-					logger,
-					pool,
-					c.ID,
-					r.URL.Query().Get("id"),
-					prompts.RAG_COMPLETE_SYSTEM_PROMPT,
-					"Information Chat",
-					"rag",
-				)
-				if err != nil {
-					writeRagResponse(r.Context(), logger, conn, newRmError("failed to get the conversation", err))
-					break
-				}
+		// keep what was generated before the cancel
+		logger.Info("The generation was cancelled by the user")
+		ctx = context.WithoutCancel(ctx)
+		if err := tx.Commit(ctx); err != nil {
+			return slogger.Error(ctx, logger, "failed to save the cancelled generation", err)
+		}
+		return writeRagResponse(ctx, logger, session, &ragMessage{
+			MessageType:    ragCancelled,
+			ConversationId: conv.ID.String(),
+		})
+	}
 
-				// write the conversation id update
-				if err := writeRagResponse(r.Context(), logger, conn, &ragMessage{
-					MessageType:    ragNewConversationId,
-					ConversationId: conv.ID.String(),
-				}); err != nil {
-					slogger.Error(r.Context(), logger, "failed to write the rag message", err)
-					break
-				}
-				logger = logger.With("conversationId", conv.ID.String())
-			}
+	if err := tx.Commit(ctx); err != nil {
+		return slogger.Error(ctx, logger, "failed to commit the transaction", err)
+	}
 
-			// create a transaction to run this call inside of
-			tx, err := pool.Begin(r.Context())
-			if err != nil {
-				writeRagResponse(r.Context(), logger, conn, newRmError("failed to start the transaction", err))
-				break
-			}
+	// send a request to create a title if the conversation does not have one
+	if conv.Title == "Information Chat" && message != nil {
+		logger.Info("Creating a new title ... ")
+		newTitle, err := c.createRagTitle(ctx, logger, pool, conv, message)
+		if err != nil {
+			slogger.Error(ctx, logger, "failed to create the title, but not closing the ws", err)
+		}
 
-			// send the request
-			if err := c.rag2MessageHandler(r.Context(), logger, tx, conn, conv, chatLLM, gollm.NewUserMessage(userMessage.Message)); err != nil {
-				tx.Rollback(r.Context())
-				writeRagResponse(r.Context(), logger, conn, newRmError("failed to send the message request", err))
-				break
-			}
+		// update the conversation
+		dmodel := queries.New(pool)
+		_, err = dmodel.UpdateConversationTitle(ctx, &queries.UpdateConversationTitleParams{
+			ID:    conv.ID,
+			Title: newTitle,
+		})
+		if err != nil {
+			slogger.Error(ctx, logger, "failed tp update the conversation title, but not closing ws", err)
+		}
+		conv.Title = newTitle
 
-			tx.Commit(r.Context())
+		// send the new title to the user
+		writeRagResponse(ctx, logger, session, &ragMessage{
+			MessageType: ragTitleUpdate,
+			NewTitle:    newTitle,
+		})
+	}
 
-			// send a request to create a title if the conversation does not have one
-			if conv.Title == "Information Chat" {
-				logger.Info("Creating a new title ... ")
-				newTitle, err := c.createRagTitle(r.Context(), logger, pool, gollm.NewUserMessage(userMessage.Message))
-				if err != nil {
-					slogger.Error(r.Context(), logger, "failed to create the title, but not closing the ws", err)
-				}
+	return nil
+}
 
-				// update the conversation
-				dmodel := queries.New(pool)
-				_, err = dmodel.UpdateConversationTitle(r.Context(), &queries.UpdateConversationTitleParams{
-					ID:    conv.ID,
-					Title: newTitle,
-				})
-				if err != nil {
-					slogger.Error(r.Context(), logger, "failed tp update the conversation title, but not closing ws", err)
-				}
-				conv.Title = newTitle
+// The state of the tool call chain of a single turn
+type ragChain struct {
+	pool     *pgxpool.Pool
+	tools    []tool.Tool
+	maxDepth int
 
-				// send the new title to the user
-				writeRagResponse(r.Context(), logger, conn, &ragMessage{
-					MessageType: ragTitleUpdate,
-					NewTitle:    newTitle,
-				})
-			}
-		}
-	}
+	depth     int                      // number of responses with tool calls in the turn
+	citations []*conversation.Citation // citations collected from the tool calls
+}
 
-	logger.Info("Closing ws connection")
+// the highest citation number of the chain, the citations of the next tool calls are numbered
+// after it
+func (chain *ragChain) citationOffset() int {
+	offset := 0
+	for _, item := range chain.citations {
+		offset = max(offset, item.Number)
+	}
+	return offset
 }
 
 // Handles the initial message recieved from the user. This will either write the AI
 // response to the user, or it will perform the tool call chain.
+// The citations collected from the tool calls of the chain are saved on the AI response.
 func (c *Customer) rag2MessageHandler(
 	ctx context.Context,
 	logger *slog.Logger,
 	tx pgx.Tx,
-	conn *websocket.Conn,
+	session *ragSession,
 	conv *conversation.Conversation,
 	chatLLM *llm.LLM,
 	message *gollm.Message,
+	chain *ragChain,
 ) error {
-	// get the tools
-	tools := rag2Tools()
-
 	// get the customers chatllm
 	// TODO -- enable arguments to be passed over the websocket
 	logger.Debug("chatllm", "chatllm", *chatLLM)
 
-	// create a completion
-	logger.Debug("sending a completion response in the rag handler")
-	completionResponse, err := conv.Completion(ctx, tx, chatLLM, message, tool.ToolsToGollm(tools), nil)
+	// stream the completion to the user as it is generated
+	logger.Debug("sending a streamed completion in the rag handler")
+	completionResponse, err := conv.CompletionStream(ctx, tx, chatLLM, message, tool.ToolsToGollm(chain.tools), nil, func(chunk *llm.StreamChunk) error {
+		if chunk.ToolCallStart != "" {
+			return writeRagResponse(ctx, logger, session, &ragMessage{
+				MessageType: ragToolCallStart,
+				ToolName:    chunk.ToolCallStart,
+			})
+		}
+		return writeRagResponse(ctx, logger, session, &ragMessage{
+			MessageType: ragMessageDelta,
+			Delta:       chunk.Delta,
+		})
+	})
 	if err != nil {
 		return slogger.Error(ctx, logger, "failed the completion", err)
 	}
 
-	// write the message to the connection
-	if err := writeRagResponse(ctx, logger, conn, newRmChatMessage(completionResponse.Message)); err != nil {
-		return slogger.Error(ctx, logger, "failed to write the message", err)
+	// attach the sources of the tool calls to the answer
+	citations := chain.citations
+	if completionResponse.Message.Role != gollm.RoleAI {
+		citations = nil
+	}
+	if err := conv.SaveCitations(ctx, tx, citations); err != nil {
+		return slogger.Error(ctx, logger, "failed to save the citations", err)
+	}
+
+	// send the assembled messages once they have been saved, with their ids for the feedback.
+	// Every tool call of the response is saved as its own message
+	outputs := completionResponse.ToolCalls
+	if len(outputs) == 0 {
+		outputs = []*gollm.Message{completionResponse.Message}
+	}
+	messageIds := conv.GetMessageIDs()
+	messageIds = messageIds[len(messageIds)-len(outputs):]
+	for i, item := range outputs {
+		if err := writeRagResponse(ctx, logger, session, &ragMessage{
+			MessageType: ragMessageComplete,
+			ChatMessage: item,
+			Citations:   citations,
+			MessageId:   &messageIds[i],
+		}); err != nil {
+			return slogger.Error(ctx, logger, "failed to write the message", err)
+		}
 	}
 
 	// parse the message response
@@ -349,29 +830,30 @@ func (c *Customer) rag2MessageHandler(
 		return nil
 	case gollm.RoleToolCall:
 		// perform the tool call chain
-		logger.Debug("calling the rag2 tool handler")
-		return c.rag2ToolCallHandler(ctx, logger, tx, conn, conv, chatLLM, completionResponse.Message)
+		logger.Debug("calling the rag2 tool handler", "calls", len(completionResponse.ToolCalls))
+		return c.rag2ToolCallHandler(ctx, logger, tx, session, conv, chatLLM, completionResponse.ToolCalls, chain)
 	default:
 		return slogger.Error(ctx, logger, "unexpected message role from the AI", nil, "role", completionResponse.Message.Role.ToString())
 	}
 }
 
+// Runs the tool calls of a response in parallel and sends the results back to the model. Once
+// the chain reaches its max depth the calls are not run, and the turn ends with a message
+// telling the user the answer could not be completed
 func (c *Customer) rag2ToolCallHandler(
 	ctx context.Context,
 	logger *slog.Logger,
 	tx pgx.Tx,
-	conn *websocket.Conn,
+	session *ragSession,
 	conv *conversation.Conversation,
 	chatLLM *llm.LLM,
-	message *gollm.Message,
+	calls []*gollm.Message,
+	chain *ragChain,
 ) error {
-
-	// parse the tool call
-	toolType, err := tool.GetToolType(message.ToolName)
-	if err != nil {
-		return slogger.Error(ctx, logger, "failed to parse the tool name", err)
+	chain.depth++
+	if chain.depth > chain.maxDepth {
+		return c.rag2EndToolChain(ctx, logger, tx, session, conv, chatLLM, calls, chain)
 	}
-	parsedTool := tool.NewTool(toolType)
 
 	// get the summary llm
 	summaryLLM, err := c.GetSummaryLLM(ctx, logger, tx)
@@ -379,37 +861,160 @@ func (c *Customer) rag2ToolCallHandler(
 		return slogger.Error(ctx, logger, "failed to get the summary llm", err)
 	}
 
-	logger.Debug("running the rag2 parsed tool run call")
-	toolResponse, err := parsedTool.Run(ctx, logger, &tool.RunToolArgs{
-		Database:    tx,
-		Customer:    c.Customer,
-		LastMessage: message,
-		ToolLLM:     summaryLLM,
-	})
-	if err != nil {
-		return slogger.Error(ctx, logger, "failed to run the tool", err)
+	// a transaction cannot be used by several goroutines at once, so parallel calls run on the pool
+	var db queries.DBTX = tx
+	if len(calls) > 1 {
+		db = chain.pool
 	}
 
-	// write the message to the connection
-	if err := writeRagResponse(ctx, logger, conn, newRmChatMessage(toolResponse.Message)); err != nil {
-		return slogger.Error(ctx, logger, "failed to write the message", err)
+	logger.Debug("running the rag2 tool calls", "calls", len(calls), "depth", chain.depth)
+	offset := chain.citationOffset()
+	responses := make([]*tool.ToolResponse, len(calls))
+	errs := make([]error, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call *gollm.Message) {
+			defer wg.Done()
+			responses[i], errs[i] = c.rag2RunTool(ctx, logger, db, chain.pool, chain.tools, summaryLLM, call, offset+i*tool.MAX_TOOL_CITATIONS)
+		}(i, call)
+	}
+	wg.Wait()
+
+	// report the usage of the tools. Use a detached context so the tokens are recorded when cancelled
+	for i, item := range responses {
+		if item == nil {
+			continue
+		}
+		if err := utils.ReportUsage(context.WithoutCancel(ctx), logger, tx, c.ID, item.UsageRecords, conv.Conversation); err != nil {
+			return slogger.Error(ctx, logger, "failed to report the tool usage", err, "tool", calls[i].ToolName)
+		}
 	}
 
-	// recursively run the message handler
+	if ctx.Err() != nil {
+		// close the tool calls so the conversation stays valid for the next message
+		for _, call := range calls {
+			cancelled := gollm.NewToolResultMessage(call.ToolUseID, call.ToolName, "The tool call was cancelled by the user.")
+			if err := conv.SaveCancelledMessage(context.WithoutCancel(ctx), tx, chatLLM, cancelled); err != nil {
+				return slogger.Error(ctx, logger, "failed to save the cancelled tool result", err)
+			}
+		}
+		return fmt.Errorf("the tool call was cancelled: %w", ctx.Err())
+	}
+	for i, err := range errs {
+		if err != nil {
+			return slogger.Error(ctx, logger, "failed to run the tool", err, "tool", calls[i].ToolName)
+		}
+	}
+
+	// save the results in the order of the calls, and write them to the connection
+	for i, item := range responses {
+		if err := conv.SaveMessage(ctx, tx, chatLLM, item.Message); err != nil {
+			return slogger.Error(ctx, logger, "failed to save the tool result", err)
+		}
+		messageIds := conv.GetMessageIDs()
+		if err := writeRagResponse(ctx, logger, session, &ragMessage{
+			MessageType: ragToolCallFinish,
+			ChatMessage: item.Message,
+			ToolName:    calls[i].ToolName,
+			Citations:   item.Citations,
+			MessageId:   &messageIds[len(messageIds)-1], // the client confirms a pending save with it
+		}); err != nil {
+			return slogger.Error(ctx, logger, "failed to write the message", err)
+		}
+		chain.citations = append(chain.citations, item.Citations...)
+	}
+
+	// recursively run the message handler on the saved results
 	logger.Debug("recursively calling the rag2 message handler")
-	if err := c.rag2MessageHandler(ctx, logger, tx, conn, conv, chatLLM, toolResponse.Message); err != nil {
+	if err := c.rag2MessageHandler(ctx, logger, tx, session, conv, chatLLM, nil, chain); err != nil {
 		return slogger.Error(ctx, logger, "failed to recursively call the message handler", err)
 	}
 
 	return nil
 }
 
+// runs a single tool call of the model. A call to a tool that is not enabled for the
+// conversation is not run, and the model is told the tool is not enabled
+func (c *Customer) rag2RunTool(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	pool *pgxpool.Pool,
+	tools []tool.Tool,
+	summaryLLM *llm.LLM,
+	call *gollm.Message,
+	citationOffset int,
+) (*tool.ToolResponse, error) {
+	var enabledTool tool.Tool
+	for _, item := range tools {
+		if string(item.GetType()) == call.ToolName {
+			enabledTool = item
+			break
+		}
+	}
+	if enabledTool == nil {
+		logger.Warn("the model called a tool that is not enabled", "tool", call.ToolName)
+		return &tool.ToolResponse{
+			Message: gollm.NewToolResultMessage(call.ToolUseID, call.ToolName, fmt.Sprintf("The tool '%s' is not enabled for this conversation.", call.ToolName)),
+		}, nil
+	}
+	return enabledTool.Run(ctx, logger, &tool.RunToolArgs{
+		Database:       db,
+		Pool:           pool,
+		Customer:       c.Customer,
+		LastMessage:    call,
+		ToolLLM:        summaryLLM,
+		CitationOffset: citationOffset,
+	})
+}
+
+// Closes the pending tool calls without running them and ends the turn with a message to the
+// user, so the model cannot call tools without end
+func (c *Customer) rag2EndToolChain(
+	ctx context.Context,
+	logger *slog.Logger,
+	tx pgx.Tx,
+	session *ragSession,
+	conv *conversation.Conversation,
+	chatLLM *llm.LLM,
+	calls []*gollm.Message,
+	chain *ragChain,
+) error {
+	logger.Info("The tool call chain reached the max depth", "maxDepth", chain.maxDepth)
+	for _, call := range calls {
+		result := gollm.NewToolResultMessage(call.ToolUseID, call.ToolName, "The tool was not run, the limit of tool calls for this message was reached.")
+		if err := conv.SaveMessage(ctx, tx, chatLLM, result); err != nil {
+			return slogger.Error(ctx, logger, "failed to save the tool result", err)
+		}
+	}
+
+	response := &gollm.Message{
+		Role:    gollm.RoleAI,
+		Message: prompts.RAG_TOOL_DEPTH_MESSAGE,
+	}
+	if err := conv.SaveMessage(ctx, tx, chatLLM, response); err != nil {
+		return slogger.Error(ctx, logger, "failed to save the message", err)
+	}
+
+	messageIds := conv.GetMessageIDs()
+	if err := writeRagResponse(ctx, logger, session, &ragMessage{
+		MessageType: ragMessageComplete,
+		ChatMessage: response,
+		MessageId:   &messageIds[len(messageIds)-1],
+	}); err != nil {
+		return slogger.Error(ctx, logger, "failed to write the message", err)
+	}
+	return nil
+}
+
 // creates a chat title based on the passed message to this function.
 // it is recommended to use the first customer message as the input to this function
 func (c *Customer) createRagTitle(
 	ctx context.Context,
 	logger *slog.Logger,
 	db queries.DBTX,
+	conv *conversation.Conversation,
 	message *gollm.Message,
 ) (string, error) {
 	// get the title creation llm
@@ -421,11 +1026,20 @@ func (c *Customer) createRagTitle(
 
 	lm := llm.FromObjects(&response.Llm, &response.AvailableModel)
 
+	// get the title prompt the customer uses
+	system, t, err := prompts.Render(ctx, logger, db, c.ID, prompts.TEMPLATE_RAG_TITLE, nil)
+	if err != nil {
+		return "", slogger.Error(ctx, logger, "failed to get the title prompt", err)
+	}
+
 	// create a single completion
-	completion, err := lm.SingleCompletion(ctx, logger, c.ID, prompts.RAG_TITLE_GENERATION_SYSTEM_PROMPT, message.Message)
+	completion, err := lm.CachedSingleCompletion(ctx, logger, db, c.ID, system, message.Message)
 	if err != nil {
 		return "", slogger.Error(ctx, logger, "failed to send the single completion for a new title", err)
 	}
+	if err := conv.RecordPrompt(ctx, db, t); err != nil {
+		slogger.Error(ctx, logger, "failed to record the title prompt", err)
+	}
 
 	// report the usage
 	if err := utils.ReportUsage(ctx, logger, db, c.ID, []*tokens.UsageRecord{completion.UsageRecord}, nil); err != nil {
@@ -435,9 +1049,3 @@ func (c *Customer) createRagTitle(
 	return completion.Message.Message, nil
 
 }
-
-func rag2Tools() []tool.Tool {
-	tools := make([]tool.Tool, 0)
-	tools = append(tools, tool.NewTool(tool.VectorQuery))
-	return tools
-}
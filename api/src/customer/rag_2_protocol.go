@@ -0,0 +1,460 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+)
+
+/*
+Version 2 of the rag2 websocket protocol.
+
+Every frame in both directions is a JSON text frame holding an envelope:
+
+	{"version": 2, "id": "<uuid>", "seq": 12, "type": "messageDelta", "payload": {...}}
+
+  - version: always 2. Frames with another version are rejected with an error.
+  - id: unique id of the message, generated by the sender.
+  - seq: set by the server on conversation events. It increases by one for every event in
+    the session so the client can track the last event it received. Connection level
+    messages (connected, ack, resumed, resumeFailed and request errors) have no seq.
+  - type: the message type, the payload depends on it.
+  - payload: the content of the message.
+
+Client messages:
+
+  - ragMessage {message}: send a chat message.
+  - changeChatLLM {chatLLMId}: change the llm used by the chat.
+  - cancel {}: cancel the message that is being generated.
+  - editMessage {index, message}: replace the user message at index with the message. The
+    conversation is branched at the message, and the server sends a `branched` event with
+    the branchIndex before the response. The previous branch is kept.
+  - regenerate {index}: regenerate the AI response at index on a new branch.
+  - switchBranch {messageId}: switch to the latest branch holding the message. The server
+    sends a `branchSwitched` event, and the client should reload the conversation.
+  - ack {seq}: the client received every event up to seq. The server drops them from the
+    replay buffer.
+  - resume {conversationId, sessionId, seq}: sent after reconnecting. The server replays
+    every event after seq and responds with resumed, or with resumeFailed when the events
+    are no longer available. The messageDelta events of a message are not replayed one by
+    one, they are replayed as a single messageSnapshot event holding the text of the
+    message so far as its chatMessage, which replaces the text the client has. resumeFailed holds the sessionId and lastSeq of the session the
+    connection continues on, and the client should reload the conversation.
+
+Every client message except ack is acknowledged with an `ack` message holding its id as
+`ackId`.
+
+The server sends a `connected` message with the sessionId and lastSeq once the connection
+is open, and pings the client every ragPingPeriod. A connection that does not answer the
+pings within ragPongWait, or does not send a message within ragIdleTimeout while nothing is
+being generated, is closed. A generation keeps running when the connection drops, and its
+events are buffered for a resume until the session expires.
+*/
+
+const ragProtocolVersion = 2
+
+const (
+	ragWriteWait      = 10 * time.Second
+	ragPongWait       = 60 * time.Second
+	ragPingPeriod     = (ragPongWait * 9) / 10
+	ragIdleTimeout    = 15 * time.Minute
+	ragSessionTTL     = 10 * time.Minute
+	ragReplayLimit    = 2048
+	ragMaxMessageSize = 64 * 1024
+)
+
+type ragEnvelope struct {
+	Version int             `json:"version"`
+	ID      string          `json:"id"`
+	Seq     int64           `json:"seq,omitempty"`
+	Type    ragMessageType  `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	// the seq of the first delta merged into a snapshot
+	from int64
+}
+
+func newRagEnvelope(message *ragMessage) (*ragEnvelope, error) {
+	enc, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the payload: %w", err)
+	}
+	return &ragEnvelope{
+		Version: ragProtocolVersion,
+		ID:      uuid.NewString(),
+		Type:    message.MessageType,
+		Payload: enc,
+	}, nil
+}
+
+// The payload of the messages sent by the client
+type ragClientPayload struct {
+	Message        string `json:"message"`
+	ChatLLMID      string `json:"chatLLMId"`
+	Seq            int64  `json:"seq"`
+	SessionId      string `json:"sessionId"`
+	ConversationId string `json:"conversationId"`
+	Index          int    `json:"index"`
+	MessageId      string `json:"messageId"`
+}
+
+// A single websocket connection. Writes are serialized as the read loop, the heartbeat, and
+// the generation all write to the connection
+type ragConn struct {
+	conn     *websocket.Conn
+	mu       sync.Mutex
+	lastRead atomic.Int64 // unix nano of the last message from the client
+	session  atomic.Pointer[ragSession]
+}
+
+func newRagConn(conn *websocket.Conn) *ragConn {
+	rc := &ragConn{conn: conn}
+	rc.touch()
+
+	conn.SetReadLimit(ragMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(ragPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(ragPongWait))
+	})
+	return rc
+}
+
+func (rc *ragConn) touch() {
+	rc.lastRead.Store(time.Now().UnixNano())
+}
+
+func (rc *ragConn) write(envelope *ragEnvelope) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.conn.SetWriteDeadline(time.Now().Add(ragWriteWait))
+	return rc.conn.WriteJSON(envelope)
+}
+
+// reads the next envelope, the read deadline is extended with every message
+func (rc *ragConn) read() (*ragEnvelope, error) {
+	_, message, err := rc.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	rc.touch()
+	rc.conn.SetReadDeadline(time.Now().Add(ragPongWait))
+
+	var envelope ragEnvelope
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse the envelope: %w", err)
+	}
+	return &envelope, nil
+}
+
+// Writes a connection level message that is not buffered for a resume. The ackId is set
+// when the message responds to a client message
+func (rc *ragConn) reply(
+	ctx context.Context,
+	logger *slog.Logger,
+	message *ragMessage,
+) error {
+	envelope, err := newRagEnvelope(message)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to create the envelope", "error", err)
+		return err
+	}
+	if err := rc.write(envelope); err != nil {
+		logger.ErrorContext(ctx, "failed to write on the websocket", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Pings the client until done is closed. The connection is closed once the client has been
+// idle for ragIdleTimeout while nothing is being generated
+func (rc *ragConn) heartbeat(done <-chan struct{}) {
+	ticker := time.NewTicker(ragPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			lastRead := time.Unix(0, rc.lastRead.Load())
+			if time.Since(lastRead) > ragIdleTimeout && !rc.session.Load().running() {
+				rc.conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "idle timeout"),
+					time.Now().Add(ragWriteWait),
+				)
+				rc.conn.Close()
+				return
+			}
+			if err := rc.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(ragWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// The state of a conversation on the websocket. It outlives the connection so a client that
+// reconnects can resume from the last event it received
+type ragSession struct {
+	ID         uuid.UUID
+	CustomerID uuid.UUID
+
+	mu         sync.Mutex
+	seq        int64
+	events     []*ragEnvelope  // events that have not been acked by the client
+	partial    strings.Builder // text of the deltas since the last other event
+	conn       *ragConn        // nil while no client is connected
+	lastActive time.Time
+	turn       *ragTurn
+
+	// only accessed by the read loop while no turn is running, or by the running turn
+	conv    *conversation.Conversation
+	chatLLM *llm.LLM
+}
+
+func newRagSession(customerId uuid.UUID) *ragSession {
+	return &ragSession{
+		ID:         uuid.New(),
+		CustomerID: customerId,
+		events:     make([]*ragEnvelope, 0),
+		lastActive: time.Now(),
+	}
+}
+
+// Sends a conversation event. The event is buffered so it can be replayed, and written to
+// the connection when a client is connected. A failed write detaches the connection instead
+// of failing the caller, the client receives the event when it resumes
+func (s *ragSession) send(
+	ctx context.Context,
+	logger *slog.Logger,
+	message *ragMessage,
+) error {
+	envelope, err := newRagEnvelope(message)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to create the envelope", "error", err)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	envelope.Seq = s.seq
+	if message.MessageType == ragMessageDelta {
+		s.bufferDelta(message.Delta)
+	} else {
+		if err := s.finishSnapshot(); err != nil {
+			logger.ErrorContext(ctx, "failed to create the message snapshot", "error", err)
+		}
+		s.events = append(s.events, envelope)
+	}
+	if len(s.events) > ragReplayLimit {
+		s.events = s.events[len(s.events)-ragReplayLimit:]
+	}
+	s.lastActive = time.Now()
+
+	if s.conn == nil {
+		return nil
+	}
+	if err := s.conn.write(envelope); err != nil {
+		logger.WarnContext(ctx, "failed to write on the websocket, detaching the connection", "error", err)
+		s.conn = nil
+	}
+	return nil
+}
+
+// Buffers the delta into the snapshot of the message, so a long message takes a single event
+// in the buffer. The payload of the snapshot is created when it is replayed or the message ends
+func (s *ragSession) bufferDelta(delta string) {
+	s.partial.WriteString(delta)
+	if n := len(s.events); n > 0 && s.events[n-1].Type == ragMessageSnapshot && s.events[n-1].Payload == nil {
+		s.events[n-1].Seq = s.seq
+		return
+	}
+	s.events = append(s.events, &ragEnvelope{
+		Version: ragProtocolVersion,
+		Seq:     s.seq,
+		Type:    ragMessageSnapshot,
+		from:    s.seq,
+	})
+}
+
+// creates the payload of the snapshot of the message being generated, and starts a new message
+func (s *ragSession) finishSnapshot() error {
+	defer s.partial.Reset()
+	n := len(s.events)
+	if n == 0 || s.events[n-1].Type != ragMessageSnapshot || s.events[n-1].Payload != nil {
+		return nil
+	}
+	return s.fillSnapshot(s.events[n-1])
+}
+
+func (s *ragSession) fillSnapshot(envelope *ragEnvelope) error {
+	snapshot, err := newRagEnvelope(&ragMessage{
+		MessageType: ragMessageSnapshot,
+		ChatMessage: &gollm.Message{Role: gollm.RoleAI, Message: s.partial.String()},
+	})
+	if err != nil {
+		return err
+	}
+	envelope.ID = snapshot.ID
+	envelope.Payload = snapshot.Payload
+	return nil
+}
+
+// attaches the connection to the session, replacing a previous connection
+func (s *ragSession) attach(conn *ragConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil && s.conn != conn {
+		s.conn.conn.Close()
+	}
+	s.conn = conn
+	s.lastActive = time.Now()
+	conn.session.Store(s)
+}
+
+func (s *ragSession) detach(conn *ragConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.lastActive = time.Now()
+}
+
+// drops the events the client has received
+func (s *ragSession) ack(seq int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := 0
+	for i < len(s.events) && s.events[i].Seq <= seq {
+		i++
+	}
+	s.events = s.events[i:]
+}
+
+// Writes every event after seq to the connection. Returns false when some of the events are
+// no longer buffered
+func (s *ragSession) replay(conn *ragConn, seq int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if seq > s.seq {
+		return false, nil
+	}
+	first := s.seq + 1
+	if len(s.events) > 0 {
+		first = s.events[0].Seq
+		if s.events[0].Type == ragMessageSnapshot {
+			first = s.events[0].from
+		}
+	}
+	if seq+1 < first {
+		return false, nil
+	}
+
+	for _, item := range s.events {
+		if item.Seq <= seq {
+			continue
+		}
+		if item.Type == ragMessageSnapshot && item.Payload == nil {
+			// the message is still being generated, so the snapshot is sent as it is now
+			snapshot := *item
+			if err := s.fillSnapshot(&snapshot); err != nil {
+				return true, err
+			}
+			item = &snapshot
+		}
+		if err := conn.write(item); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (s *ragSession) lastSeq() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seq
+}
+
+func (s *ragSession) running() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.turn.running()
+}
+
+// starts the turn unless one is already running
+func (s *ragSession) start(turn *ragTurn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.turn.running() {
+		return false
+	}
+	s.turn = turn
+	return true
+}
+
+func (s *ragSession) cancel() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.turn.running() {
+		return false
+	}
+	s.turn.cancel()
+	return true
+}
+
+func (s *ragSession) expired() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn == nil && !s.turn.running() && time.Since(s.lastActive) > ragSessionTTL
+}
+
+// Sessions by conversation id. Sessions are held in memory, so a client has to reconnect to
+// the same instance of the api to resume
+type ragSessionStore struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*ragSession
+}
+
+var ragSessions = &ragSessionStore{sessions: make(map[uuid.UUID]*ragSession)}
+
+func (st *ragSessionStore) get(customerId uuid.UUID, conversationId uuid.UUID) *ragSession {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	// remove the expired sessions
+	for key, item := range st.sessions {
+		if item.expired() {
+			delete(st.sessions, key)
+		}
+	}
+
+	session, ok := st.sessions[conversationId]
+	if !ok || session.CustomerID != customerId {
+		return nil
+	}
+	return session
+}
+
+func (st *ragSessionStore) put(conversationId uuid.UUID, session *ragSession) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.sessions[conversationId] = session
+}
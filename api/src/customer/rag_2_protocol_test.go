@@ -0,0 +1,136 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// opens a websocket pair, returning the server side connection and the client
+func ragTestConn(t *testing.T) (*ragConn, *websocket.Conn) {
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		conns <- ws
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return newRagConn(<-conns), client
+}
+
+func ragTestRead(t *testing.T, client *websocket.Conn) (*ragEnvelope, *ragMessage) {
+	var envelope ragEnvelope
+	require.NoError(t, client.ReadJSON(&envelope))
+	require.Equal(t, ragProtocolVersion, envelope.Version)
+
+	var message ragMessage
+	require.NoError(t, json.Unmarshal(envelope.Payload, &message))
+	return &envelope, &message
+}
+
+func TestRagSessionSendsJsonFrames(t *testing.T) {
+	conn, client := ragTestConn(t)
+	session := newRagSession(uuid.New())
+	session.attach(conn)
+
+	require.NoError(t, session.send(context.TODO(), utils.DefaultLogger(), &ragMessage{
+		MessageType: ragMessageDelta,
+		Delta:       "Hello",
+	}))
+
+	envelope, message := ragTestRead(t, client)
+	require.Equal(t, ragMessageDelta, envelope.Type)
+	require.Equal(t, int64(1), envelope.Seq)
+	require.NotEmpty(t, envelope.ID)
+	require.Equal(t, "Hello", message.Delta)
+}
+
+func TestRagSessionReplay(t *testing.T) {
+	session := newRagSession(uuid.New())
+	send := func(message *ragMessage) {
+		require.NoError(t, session.send(context.TODO(), utils.DefaultLogger(), message))
+	}
+
+	// events sent while no client is connected are buffered
+	send(&ragMessage{MessageType: ragToolCallStart, ToolName: "vector_query"})
+	send(&ragMessage{MessageType: ragToolCallFinish, ToolName: "vector_query"})
+	for _, item := range []string{"a", "b", "c"} {
+		send(&ragMessage{MessageType: ragMessageDelta, Delta: item})
+	}
+
+	conn, client := ragTestConn(t)
+	session.attach(conn)
+
+	ok, err := session.replay(conn, 1)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	envelope, message := ragTestRead(t, client)
+	require.Equal(t, ragToolCallFinish, envelope.Type)
+	require.Equal(t, int64(2), envelope.Seq)
+
+	// the deltas are replayed as the message so far
+	envelope, message = ragTestRead(t, client)
+	require.Equal(t, ragMessageSnapshot, envelope.Type)
+	require.Equal(t, int64(5), envelope.Seq)
+	require.Equal(t, "abc", message.ChatMessage.Message)
+
+	// a client in the middle of the message receives the whole message
+	ok, err = session.replay(conn, 3)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, message = ragTestRead(t, client)
+	require.Equal(t, "abc", message.ChatMessage.Message)
+
+	// acked events can no longer be replayed
+	session.ack(2)
+	ok, err = session.replay(conn, 0)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a client that is up to date has nothing to replay
+	ok, err = session.replay(conn, 5)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestRagSessionReplayLongMessage(t *testing.T) {
+	session := newRagSession(uuid.New())
+	send := func(message *ragMessage) {
+		require.NoError(t, session.send(context.TODO(), utils.DefaultLogger(), message))
+	}
+
+	// a message with more deltas than the buffer holds
+	send(&ragMessage{MessageType: ragToolCallFinish, ToolName: "vector_query"})
+	for i := 0; i < ragReplayLimit+10; i++ {
+		send(&ragMessage{MessageType: ragMessageDelta, Delta: "x"})
+	}
+	send(&ragMessage{MessageType: ragMessageComplete})
+	require.Len(t, session.events, 3)
+
+	conn, client := ragTestConn(t)
+	session.attach(conn)
+	ok, err := session.replay(conn, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ragTestRead(t, client)
+	envelope, message := ragTestRead(t, client)
+	require.Equal(t, ragMessageSnapshot, envelope.Type)
+	require.Equal(t, strings.Repeat("x", ragReplayLimit+10), message.ChatMessage.Message)
+	envelope, _ = ragTestRead(t, client)
+	require.Equal(t, ragMessageComplete, envelope.Type)
+}
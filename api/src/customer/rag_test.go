@@ -1,11 +1,107 @@
 package customer
 
 import (
+	"context"
 	"testing"
 
-	_ "net/http/pprof"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/testingutils"
+	"github.com/sapphirenw/ai-content-creation-api/src/tool"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRag(t *testing.T) {
+	ctx := context.Background()
+	logger := testingutils.GetDefaultLogger()
+	pool := testingutils.GetDatabase(t, ctx)
+	row := testingutils.GetTestCustomer(t, ctx, pool)
+	c, err := NewCustomer(ctx, logger, row.ID, pool)
+	require.NoError(t, err)
 
+	model := testingutils.GetMockLLM(t, ctx, pool, row, "mock-rag")
+	llm.Mock.Reset()
+	defer llm.Mock.Reset()
+
+	// a title other than the default skips the title creation, which uses an internal model
+	conv, err := conversation.CreateConversation(ctx, logger, pool, c.ID, "You answer questions about the documents", "Test Rag", "rag")
+	require.NoError(t, err)
+	session := newRagSession(c.ID)
+	session.conv = conv
+	session.chatLLM = llm.FromObjects(&model.Llm, &model.AvailableModel)
+
+	// the model lists two folders in parallel, then answers with the results
+	llm.Mock.Queue("mock-rag",
+		&llm.MockResponse{
+			Message: "Let me look.",
+			ToolCalls: []*gollm.Message{
+				{Role: gollm.RoleToolCall, ToolUseID: "call_1", ToolName: string(tool.ListFolder), ToolArguments: map[string]any{}},
+				{Role: gollm.RoleToolCall, ToolUseID: "call_2", ToolName: string(tool.ListFolder), ToolArguments: map[string]any{"folder_id": "invalid"}},
+			},
+		},
+		&llm.MockResponse{Message: "You do not have any documents yet."},
+	)
+	require.NoError(t, c.rag2Turn(ctx, logger, pool, session, &ragTurnInput{Input: "What documents do I have?"}))
+
+	// the tool results are sent back to the model in the order of the calls
+	requests := llm.Mock.Requests()
+	require.Len(t, requests, 2)
+	require.Len(t, requests[0].Tools, len(tool.DefaultToolConfig.EnabledTools))
+	results := make([]*gollm.Message, 0)
+	for _, item := range requests[1].Messages {
+		if item.Role == gollm.RoleToolResult {
+			results = append(results, item)
+		}
+	}
+	require.Len(t, results, 2)
+	require.Equal(t, "call_1", results[0].ToolUseID)
+	require.Contains(t, results[0].Message, "[Folder: root]")
+	require.Equal(t, "call_2", results[1].ToolUseID)
+	require.Contains(t, results[1].Message, "is not a valid folder id")
+
+	// the turn was committed with the tool calls, the results and the answer
+	saved, err := conversation.GetConversation(ctx, logger, pool, conv.ID)
+	require.NoError(t, err)
+	roles := make([]gollm.Role, 0)
+	for _, item := range saved.GetMessages() {
+		roles = append(roles, item.Role)
+	}
+	require.Equal(t, []gollm.Role{
+		gollm.RoleSystem,
+		gollm.RoleUser,
+		gollm.RoleToolCall,
+		gollm.RoleToolCall,
+		gollm.RoleToolResult,
+		gollm.RoleToolResult,
+		gollm.RoleAI,
+	}, roles)
+	messages := saved.GetMessages()
+	require.Equal(t, "You do not have any documents yet.", messages[len(messages)-1].Message)
+
+	// the client was sent the tool calls and the answer
+	types := make([]ragMessageType, 0)
+	for _, item := range session.events {
+		types = append(types, item.Type)
+	}
+	require.Contains(t, types, ragToolCallStart)
+	require.Contains(t, types, ragToolCallFinish)
+	require.Equal(t, ragMessageComplete, types[len(types)-1])
+}
+
+func TestRag2RunToolNotEnabled(t *testing.T) {
+	ctx := context.Background()
+	logger := testingutils.GetDefaultLogger()
+	c := &Customer{}
+
+	// only the list folder tool is enabled, so the web search is not run
+	listFolder, err := tool.NewTool(tool.ListFolder)
+	require.NoError(t, err)
+	call := &gollm.Message{Role: gollm.RoleToolCall, ToolUseID: "call_1", ToolName: string(tool.WebSearch), ToolArguments: map[string]any{"query": "test"}}
+	response, err := c.rag2RunTool(ctx, logger, nil, nil, []tool.Tool{listFolder}, nil, call, 0)
+	require.NoError(t, err)
+	require.Empty(t, response.UsageRecords)
+	require.Empty(t, response.Citations)
+	require.Equal(t, "call_1", response.Message.ToolUseID)
+	require.Contains(t, response.Message.Message, "is not enabled")
 }
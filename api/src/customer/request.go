@@ -2,7 +2,13 @@ package customer
 
 import (
 	"context"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/tool"
 )
 
 type generatePresignedUrlRequest struct {
@@ -72,6 +78,25 @@ func (r insertSingleWebsitePageRequest) Valid(ctx context.Context) map[string]st
 	return p
 }
 
+type createFeedRequest struct {
+	Url                 string `json:"url"`
+	FetchArticle        bool   `json:"fetchArticle"`
+	PollIntervalMinutes int    `json:"pollIntervalMinutes"` // defaults to 60
+}
+
+func (r createFeedRequest) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string, 0)
+	if r.Url == "" {
+		p["url"] = "cannot be empty"
+	} else if u, err := url.Parse(r.Url); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		p["url"] = "must be a valid http(s) url"
+	}
+	if r.PollIntervalMinutes != 0 && (r.PollIntervalMinutes < 15 || r.PollIntervalMinutes > 10080) {
+		p["pollIntervalMinutes"] = "has to be between 15 and 10080"
+	}
+	return p
+}
+
 type purgeDatastoreRequest struct {
 	Timestamp *string `json:"timestamp"`
 }
@@ -102,9 +127,16 @@ func (r createCustomerRequest) Valid(ctx context.Context) map[string]string {
 }
 
 type queryVectorStoreRequest struct {
-	Query          string `json:"query"`
-	K              int    `json:"k"`
-	IncludeContent bool   `json:"includeContent"`
+	Query          string      `json:"query"`
+	K              int         `json:"k"`
+	IncludeContent bool        `json:"includeContent"`
+	FeedIDs        []uuid.UUID `json:"feedIds,omitempty"`
+	FeedItemIDs    []uuid.UUID `json:"feedItemIds,omitempty"`
+
+	// score the chunks against the query, and drop the chunks below the threshold. The default
+	// threshold is used when it is not set
+	Rerank    bool `json:"rerank,omitempty"`
+	Threshold *int `json:"threshold,omitempty"`
 }
 
 func (r queryVectorStoreRequest) Valid(ctx context.Context) map[string]string {
@@ -115,6 +147,9 @@ func (r queryVectorStoreRequest) Valid(ctx context.Context) map[string]string {
 	if r.K == 0 || r.K > 100 {
 		p["k"] = "has to be between 1 and 5"
 	}
+	if r.Threshold != nil && (*r.Threshold < 0 || *r.Threshold > 100) {
+		p["threshold"] = "has to be between 0 and 100"
+	}
 
 	return p
 }
@@ -157,3 +192,104 @@ func (r createModelRequest) Valid(ctx context.Context) map[string]string {
 
 	return p
 }
+
+type createRag2TicketRequest struct {
+	ConversationId string `json:"conversationId"`
+}
+
+func (r createRag2TicketRequest) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string, 0)
+	if r.ConversationId != "" {
+		if _, err := uuid.Parse(r.ConversationId); err != nil {
+			p["conversationId"] = "must be a valid uuid"
+		}
+	}
+	return p
+}
+
+type confirmRag2SaveRequest struct {
+	ConversationId string `json:"conversationId"`
+	MessageId      string `json:"messageId"` // the web search result that offered the save
+}
+
+func (r confirmRag2SaveRequest) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string, 0)
+	if _, err := uuid.Parse(r.ConversationId); err != nil {
+		p["conversationId"] = "must be a valid uuid"
+	}
+	if _, err := uuid.Parse(r.MessageId); err != nil {
+		p["messageId"] = "must be a valid uuid"
+	}
+	return p
+}
+
+type updateToolConfigRequest struct {
+	ConversationType string          `json:"conversationType"` // empty for every conversation type
+	EnabledTools     []tool.ToolType `json:"enabledTools"`
+	MaxDepth         int             `json:"maxDepth"`
+}
+
+func (r updateToolConfigRequest) Valid(ctx context.Context) map[string]string {
+	return r.config().Valid(ctx)
+}
+
+func (r updateToolConfigRequest) config() *tool.ToolConfig {
+	enabled := r.EnabledTools
+	if enabled == nil {
+		enabled = make([]tool.ToolType, 0)
+	}
+	return &tool.ToolConfig{
+		ConversationType: r.ConversationType,
+		EnabledTools:     enabled,
+		MaxDepth:         r.MaxDepth,
+	}
+}
+
+type updateRetryPolicyRequest struct {
+	MaxRetries       int         `json:"maxRetries"`
+	InitialBackoffMs int         `json:"initialBackoffMs"`
+	MaxBackoffMs     int         `json:"maxBackoffMs"`
+	FallbackLlmIds   []uuid.UUID `json:"fallbackLlmIds"` // tried in order
+}
+
+func (r updateRetryPolicyRequest) Valid(ctx context.Context) map[string]string {
+	return r.policy().Valid(ctx)
+}
+
+func (r updateRetryPolicyRequest) policy() *llm.RetryPolicy {
+	fallbacks := r.FallbackLlmIds
+	if fallbacks == nil {
+		fallbacks = make([]uuid.UUID, 0)
+	}
+	return &llm.RetryPolicy{
+		MaxRetries:       r.MaxRetries,
+		InitialBackoffMs: r.InitialBackoffMs,
+		MaxBackoffMs:     r.MaxBackoffMs,
+		FallbackIDs:      fallbacks,
+	}
+}
+
+type savePromptTemplateRequest struct {
+	Content     string `json:"content"`
+	Description string `json:"description"`
+}
+
+func (r savePromptTemplateRequest) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string, 0)
+	if strings.TrimSpace(r.Content) == "" {
+		p["content"] = "cannot be empty"
+	}
+	return p
+}
+
+type activatePromptTemplateRequest struct {
+	Version int `json:"version"`
+}
+
+func (r activatePromptTemplateRequest) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string, 0)
+	if r.Version < 1 {
+		p["version"] = "must be greater than 0"
+	}
+	return p
+}
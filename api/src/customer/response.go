@@ -1,8 +1,13 @@
 package customer
 
 import (
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/vectorstore"
 )
 
 type generatePresignedUrlResponse struct {
@@ -23,6 +28,29 @@ type handleWebsiteResponse struct {
 }
 
 type queryVectorStoreResponse struct {
+	// the documents and pages whose summary matched the query, closest first
+	Summaries []*vectorstore.SummaryMatch `json:"summaries"`
+
 	Documents    []*queries.Document    `json:"documents"`
 	WebsitePages []*queries.WebsitePage `json:"websitePages"`
+	FeedItems    []*queries.FeedItem    `json:"feedItems"`
+
+	// the scored chunks, most relevant first. Only set when the chunks were reranked
+	Chunks []*vectorstore.RankedVector `json:"chunks,omitempty"`
+}
+
+type summaryResponse struct {
+	Summary         string             `json:"summary"`
+	IsCurrent       bool               `json:"isCurrent"` // the summary matches the current content
+	SummaryFailedAt pgtype.Timestamptz `json:"summaryFailedAt"`
+}
+
+type createRag2TicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type retryPolicyResponse struct {
+	Policy    *llm.RetryPolicy `json:"policy"`
+	IsDefault bool             `json:"isDefault"` // the llm has no stored policy
 }
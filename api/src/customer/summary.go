@@ -0,0 +1,152 @@
+package customer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/pgvector/pgvector-go"
+	"github.com/sapphirenw/ai-content-creation-api/src/datastore"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+)
+
+// Generates the summary of the document with the summary llm of the customer when the content
+// changed since the last summary, then stores it along with the embeddings of the summary.
+// Failures are recorded on the document so the summary job does not retry it right away
+func (c *Customer) SummarizeDocument(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	item *queries.Document,
+) error {
+	logger := c.logger.With("docID", item.ID, "filename", item.Filename)
+	dmodel := queries.New(pool)
+
+	doc, err := datastore.NewDocumentFromDocument(ctx, logger, item)
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to parse the database doc", err)
+	}
+	if err := c.summarizeObject(ctx, pool, logger, doc, func(tx queries.DBTX, vector *pgvector.Vector) error {
+		txmodel := queries.New(tx)
+		if _, err := txmodel.UpdateDocumentSummary(ctx, &queries.UpdateDocumentSummaryParams{
+			ID:            doc.ID,
+			Summary:       doc.Summary,
+			SummarySha256: doc.SummarySha256,
+		}); err != nil {
+			return fmt.Errorf("failed to update the document summary: %w", err)
+		}
+		return txmodel.UpsertDocumentSummaryVector(ctx, &queries.UpsertDocumentSummaryVectorParams{
+			DocumentID: doc.ID,
+			CustomerID: c.ID,
+			Sha256:     doc.SummarySha256,
+			Embeddings: vector,
+		})
+	}); err != nil {
+		if err := dmodel.SetDocumentSummaryFailed(ctx, doc.ID); err != nil {
+			slogger.Error(ctx, logger, "failed to set the summary failure", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Same as `SummarizeDocument` for a website page. Pages are summarized once they are vectorized,
+// which is when the fingerprint of their content is known
+func (c *Customer) SummarizeWebsitePage(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	item *queries.WebsitePage,
+) error {
+	logger := c.logger.With("pageID", item.ID, "page", item.Url)
+	dmodel := queries.New(pool)
+
+	page, _ := datastore.NewWebsitePageFromWebsitePage(ctx, logger, item)
+	if err := c.summarizeObject(ctx, pool, logger, page, func(tx queries.DBTX, vector *pgvector.Vector) error {
+		txmodel := queries.New(tx)
+		if _, err := txmodel.UpdateWebsitePageSummary(ctx, &queries.UpdateWebsitePageSummaryParams{
+			ID:            page.ID,
+			Summary:       page.Summary,
+			SummarySha256: page.SummarySha256,
+		}); err != nil {
+			return fmt.Errorf("failed to update the page summary: %w", err)
+		}
+		return txmodel.UpsertWebsitePageSummaryVector(ctx, &queries.UpsertWebsitePageSummaryVectorParams{
+			WebsitePageID: page.ID,
+			CustomerID:    c.ID,
+			Sha256:        page.SummarySha256,
+			Embeddings:    vector,
+		})
+	}); err != nil {
+		if err := dmodel.SetWebsitePageSummaryFailed(ctx, page.ID); err != nil {
+			slogger.Error(ctx, logger, "failed to set the summary failure", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// summarizes and embeds the object, then saves both in a transaction with the passed function
+func (c *Customer) summarizeObject(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	logger *slog.Logger,
+	obj datastore.Object,
+	save func(tx queries.DBTX, vector *pgvector.Vector) error,
+) error {
+	model, err := c.GetSummaryLLM(ctx, logger, pool)
+	if err != nil {
+		return err
+	}
+
+	// generate the summary
+	logger.InfoContext(ctx, "Summarizing the object ...")
+	summary, err := datastore.GetSummary(obj, ctx, logger, c.ID, model, &llm.SummarizeOptions{
+		Style: llm.SUMMARY_STYLE_PARAGRAPH,
+		Cache: llm.NewCacheOptions(pool),
+		DB:    pool,
+	})
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to summarize the object", err)
+	}
+	usageRecords := summary.UsageRecords
+
+	// embed the summary for the retrieval layer
+	logger.InfoContext(ctx, "Embedding the summary ...")
+	emb := llm.GetEmbeddings(logger, c.Customer)
+	res, err := emb.Embed(ctx, logger, &gollm.EmbedArgs{
+		InputChunks: []string{summary.Summary},
+	})
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to embed the summary", err)
+	}
+	if len(res.Embeddings) == 0 {
+		return slogger.Error(ctx, logger, "there were no embeddings returned", nil)
+	}
+	if res.Usage != nil {
+		usageRecords = append(usageRecords, res.Usage)
+	}
+
+	// save the summary with its vector
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to start a transaction", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := save(tx, &res.Embeddings[0].Embedding); err != nil {
+		return slogger.Error(ctx, logger, "failed to save the summary", err)
+	}
+	if err := utils.ReportUsage(ctx, logger, tx, c.ID, usageRecords, nil); err != nil {
+		return slogger.Error(ctx, logger, "failed to report the usage", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return slogger.Error(ctx, logger, "failed to commit the transaction", err)
+	}
+
+	logger.InfoContext(ctx, "Successfully summarized the object")
+	return nil
+}
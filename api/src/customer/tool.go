@@ -0,0 +1,97 @@
+package customer
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sapphirenw/ai-content-creation-api/src/request"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+	"github.com/sapphirenw/ai-content-creation-api/src/tool"
+)
+
+type toolInfo struct {
+	Name        tool.ToolType `json:"name"`
+	Description string        `json:"description"`
+	Available   bool          `json:"available"` // whether the tool can run in this environment
+}
+
+type getToolsResponse struct {
+	Tools          []*toolInfo        `json:"tools"`
+	Defaults       *tool.ToolConfig   `json:"defaults"`
+	Configurations []*tool.ToolConfig `json:"configurations"` // the configurations saved by the customer
+}
+
+func getTools(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "getTools")
+
+	configs, err := tool.GetCustomerToolConfigs(r.Context(), pool, c.ID)
+	if err != nil {
+		slogger.ServerError(w, logger, 500, "failed to get the tool configurations", err)
+		return
+	}
+
+	names := tool.RegisteredTools()
+	tools := make([]*toolInfo, 0, len(names))
+	for _, name := range names {
+		item, err := tool.NewTool(name)
+		if err != nil {
+			slogger.ServerError(w, logger, 500, "failed to create the tool", err)
+			return
+		}
+		tools = append(tools, &toolInfo{
+			Name:        name,
+			Description: item.GetSchema().Description,
+			Available:   tool.Available(name),
+		})
+	}
+
+	request.Encode(w, r, logger, http.StatusOK, &getToolsResponse{
+		Tools:          tools,
+		Defaults:       tool.GetDefaultToolConfig(r.URL.Query().Get("conversationType")),
+		Configurations: configs,
+	})
+}
+
+func updateToolConfig(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "updateToolConfig")
+
+	body, valid := request.Decode[updateToolConfigRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
+
+	response, err := tool.SaveCustomerToolConfig(r.Context(), pool, c.ID, body.config())
+	if err != nil {
+		slogger.ServerError(w, logger, 500, "failed to save the tool configuration", err)
+		return
+	}
+
+	request.Encode(w, r, logger, http.StatusOK, response)
+}
+
+// Removes the configuration of the conversation type, so the conversations go back to the defaults
+func deleteToolConfig(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	logger := c.logger.With("handler", "deleteToolConfig")
+
+	if err := tool.DeleteCustomerToolConfig(r.Context(), pool, c.ID, r.URL.Query().Get("conversationType")); err != nil {
+		slogger.ServerError(w, logger, 500, "failed to delete the tool configuration", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -148,6 +148,9 @@ func (c *Customer) QueryVectorStore(ctx context.Context, db queries.DBTX, reques
 		Embeddings: embs,
 		Query:      request.Query,
 		K:          request.K,
+
+		FeedIDsFilter:     request.FeedIDs,
+		FeedItemIDsFilter: request.FeedItemIDs,
 	}
 
 	// run the general response
@@ -155,10 +158,49 @@ func (c *Customer) QueryVectorStore(ctx context.Context, db queries.DBTX, reques
 	if err != nil {
 		return nil, slogger.Error(ctx, logger, "failed to query the vectorstore", err)
 	}
+	if !request.Rerank {
+		return &queryVectorStoreResponse{
+			Summaries:    response.Summaries,
+			Documents:    response.Documents,
+			WebsitePages: response.WebsitePages,
+			FeedItems:    response.FeedItems,
+		}, nil
+	}
+
+	// score the chunks and summaries with the ranker, or the summary llm of the customer when there is none
+	rankerLLM, err := vectorstore.GetRankerLLM(ctx, db)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to get the ranker llm, using the summary llm", "error", err)
+		rankerLLM, err = c.GetSummaryLLM(ctx, logger, db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rerankResponse, err := vectorstore.Rerank(ctx, logger, &vectorstore.RerankInput{
+		CustomerID: c.ID,
+		DB:         db,
+		Model:      rankerLLM,
+		Query:      request.Query,
+		Vectors:    response.Vectors,
+		Summaries:  response.Summaries,
+		Threshold:  request.Threshold,
+	})
+	if rerankResponse != nil {
+		if err := utils.ReportUsage(ctx, logger, db, c.ID, rerankResponse.UsageRecords, nil); err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to report the usage", err)
+		}
+	}
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to rerank the chunks", err)
+	}
 
+	kept := response.KeepRanked(rerankResponse.Ranked, rerankResponse.Summaries)
 	return &queryVectorStoreResponse{
-		Documents:    response.Documents,
-		WebsitePages: response.WebsitePages,
+		Summaries:    kept.Summaries,
+		Documents:    kept.Documents,
+		WebsitePages: kept.WebsitePages,
+		FeedItems:    kept.FeedItems,
+		Chunks:       rerankResponse.Ranked,
 	}, nil
 }
 
@@ -232,6 +274,35 @@ func queryVectorStoreWebsitePages(
 	request.Encode(w, r, c.logger, http.StatusOK, response)
 }
 
+func queryVectorStoreFeedItems(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+) {
+	// parse the request
+	body, valid := request.Decode[queryVectorStoreRequest](w, r, c.logger)
+	if !valid {
+		return
+	}
+
+	embs := llm.GetEmbeddings(c.logger, c.Customer)
+	response, err := vectorstore.QueryFeedItems(r.Context(), c.logger, pool, &vectorstore.QueryInput{
+		CustomerID:        c.ID,
+		Embeddings:        embs,
+		Query:             body.Query,
+		K:                 body.K,
+		FeedIDsFilter:     body.FeedIDs,
+		FeedItemIDsFilter: body.FeedItemIDs,
+	})
+	if err != nil {
+		slogger.ServerError(w, c.logger, 500, "failed to query the vectorstore", err)
+		return
+	}
+
+	request.Encode(w, r, c.logger, http.StatusOK, response)
+}
+
 func queryVectorStoreRaw(
 	w http.ResponseWriter,
 	r *http.Request,
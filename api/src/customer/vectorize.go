@@ -325,6 +325,7 @@ func (c *Customer) handleWebsitePageVectorization(
 
 	// create a new page type (never returns an error)
 	page, _ := datastore.NewWebsitePageFromWebsitePage(ctx, logger, p)
+	contentType := p.ContentType
 
 	// get the raw content
 	raw, err := page.GetRaw(ctx)
@@ -332,6 +333,44 @@ func (c *Customer) handleWebsitePageVectorization(
 		return nil, fmt.Errorf("failed to get the raw content: %w", err)
 	}
 
+	// the page may have been detected as a document while fetching
+	if page.ContentType != contentType {
+		if err := dmodel.UpdateWebsitePageContentType(ctx, &queries.UpdateWebsitePageContentTypeParams{
+			ID:          page.ID,
+			ContentType: page.ContentType,
+		}); err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to update the page content type", err)
+		}
+	}
+
+	// store how the content was extracted so users can see why a page was skipped
+	metadata, err := page.GetMetadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the metadata: %w", err)
+	}
+	if err := dmodel.UpdateWebsitePageMetadata(ctx, &queries.UpdateWebsitePageMetadataParams{
+		ID:       page.ID,
+		Metadata: metadata.Bytes(),
+	}); err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to update the page metadata", err)
+	}
+	if reason := page.SkipReason(); reason != "" {
+		logger.WarnContext(ctx, "Skipping the page", "reason", reason)
+
+		// the previous content is no longer on the page, so it cannot answer queries. The
+		// signature is cleared so the page is vectorized again once it has content
+		if err := dmodel.DeleteWebsitePageVectors(ctx, page.ID); err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to delete old vectors", err)
+		}
+		if err := dmodel.UpdateWebsitePageVectorSig(ctx, &queries.UpdateWebsitePageVectorSigParams{
+			ID:           page.ID,
+			VectorSha256: "",
+		}); err != nil {
+			return nil, slogger.Error(ctx, logger, "failed to update the page signature", err)
+		}
+		return nil, nil
+	}
+
 	// get the sig
 	newSha256 := utils.GenerateFingerprint(raw.Bytes())
 	if page.VectorSha256 == newSha256 {
@@ -345,6 +384,11 @@ func (c *Customer) handleWebsitePageVectorization(
 		logger.InfoContext(ctx, "The signatures do not match", "pageSHA256", page.VectorSha256, "vectorSHA256", newSha256)
 	}
 
+	// delete the old vectors
+	if err := dmodel.DeleteWebsitePageVectors(ctx, page.ID); err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to delete old vectors", err)
+	}
+
 	logger.InfoContext(ctx, "Vecorizing the content ...")
 
 	// get the chunks
@@ -361,12 +405,6 @@ func (c *Customer) handleWebsitePageVectorization(
 		return nil, slogger.Error(ctx, logger, "failed to embed the content", err)
 	}
 
-	// get the metadata for the page
-	metadata, err := page.GetMetadata(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get the metadata: %w", err)
-	}
-
 	// lastly upload the vectors to the datastore
 	for index, vec := range res.Embeddings {
 		// create raw vector object
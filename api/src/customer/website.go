@@ -214,6 +214,42 @@ func getWebsitePageContent(
 	request.Encode(w, r, c.logger, http.StatusOK, response)
 }
 
+func getWebsitePageSummary(
+	w http.ResponseWriter,
+	r *http.Request,
+	pool *pgxpool.Pool,
+	c *Customer,
+	site *queries.Website,
+) {
+	logger := c.logger.With("handler", "getWebsitePageSummary")
+
+	// parse the page id
+	pageId, err := utils.GoogleUUIDFromString(chi.URLParam(r, "pageId"))
+	if err != nil {
+		slogger.ServerError(w, logger, 400, "failed to parse the pageId", err)
+		return
+	}
+
+	dmodel := queries.New(pool)
+	p, err := dmodel.GetWebsitePage(r.Context(), pageId)
+	if err != nil || p.CustomerID != c.ID || p.WebsiteID != site.ID {
+		slogger.ServerError(w, logger, 404, "failed to get the page", err)
+		return
+	}
+
+	page, err := datastore.NewWebsitePageFromWebsitePage(r.Context(), logger, p)
+	if err != nil {
+		slogger.ServerError(w, logger, 500, "failed to create the internal page datatype", err)
+		return
+	}
+
+	request.Encode(w, r, c.logger, http.StatusOK, &summaryResponse{
+		Summary:         page.Summary,
+		IsCurrent:       page.CurrentSummary() != "",
+		SummaryFailedAt: page.SummaryFailedAt,
+	})
+}
+
 func deleteWebsite(
 	w http.ResponseWriter,
 	r *http.Request,
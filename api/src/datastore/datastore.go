@@ -3,6 +3,13 @@ package datastore
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
 )
 
 type Object interface {
@@ -21,34 +28,40 @@ type Object interface {
 // This does NOT write the summary to the database, updates
 // will have to be handled manually for the sake of making this function
 // thread-safe.
-// func GetSummary(
-// 	obj Object,
-// 	ctx context.Context,
-// 	logger *slog.Logger,
-// 	customerId uuid.UUID,
-// 	model *llm.LLM,
-// ) (*llm.SummarizeResponse, error) {
-// 	summary := obj.getSummary()
-
-// 	if summary == "" {
-// 		logger.InfoContext(ctx, "Generating new summary for object ...")
-
-// 		// get the cleaned data
-// 		cleaned, err := obj.GetCleaned(ctx)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to get the cleaned data: %w", err)
-// 		}
-
-// 		// generate a new summary
-// 		s, err := model.Summarize(ctx, logger, customerId, cleaned.String())
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to create the summary: %w", err)
-// 		}
-// 		if err := obj.setSummary(s); err != nil {
-// 			return nil, fmt.Errorf("failed to set the summary: %w", err)
-// 		}
-// 		summary = s
-// 	}
-
-// 	return summary, nil
-// }
+func GetSummary(
+	obj Object,
+	ctx context.Context,
+	logger *slog.Logger,
+	customerId uuid.UUID,
+	model *llm.LLM,
+	opts *llm.SummarizeOptions,
+) (*llm.SummarizeResponse, error) {
+	if summary := obj.getSummary(); summary != "" {
+		return &llm.SummarizeResponse{
+			Summary:      summary,
+			UsageRecords: []*tokens.UsageRecord{},
+		}, nil
+	}
+
+	logger.InfoContext(ctx, "Generating new summary for object ...")
+
+	// get the cleaned data
+	cleaned, err := obj.GetCleaned(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the cleaned data: %w", err)
+	}
+	if strings.TrimSpace(cleaned.String()) == "" {
+		return nil, fmt.Errorf("the object has no content to summarize")
+	}
+
+	// generate a new summary
+	response, err := model.Summarize(ctx, logger, customerId, cleaned.String(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the summary: %w", err)
+	}
+	if err := obj.setSummary(response.Summary); err != nil {
+		return nil, fmt.Errorf("failed to set the summary: %w", err)
+	}
+
+	return response, nil
+}
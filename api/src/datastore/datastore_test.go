@@ -1,8 +1,14 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDatastore(t *testing.T) {
@@ -14,3 +20,31 @@ func TestDatastore(t *testing.T) {
 
 	fmt.Println(len(objects))
 }
+
+func TestWebsitePageSummary(t *testing.T) {
+	page := &WebsitePage{WebsitePage: &queries.WebsitePage{Sha256: "url", Summary: "old", SummarySha256: "url"}}
+
+	// the summary is only current once the content of the page is known
+	require.Empty(t, page.CurrentSummary())
+	require.Error(t, page.setSummary("new"))
+
+	page.VectorSha256 = "content"
+	require.Empty(t, page.CurrentSummary())
+	require.NoError(t, page.setSummary("new"))
+	require.Equal(t, "content", page.SummarySha256)
+	require.Equal(t, "new", page.CurrentSummary())
+
+	// new content makes the summary stale
+	page.VectorSha256 = "changed"
+	require.Empty(t, page.CurrentSummary())
+}
+
+func TestGetSummaryCurrent(t *testing.T) {
+	doc := &Document{Document: &queries.Document{Sha256: "content", Summary: "summary", SummarySha256: "content"}}
+
+	// a current summary is returned without a model
+	response, err := GetSummary(doc, context.TODO(), utils.DefaultLogger(), uuid.New(), nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "summary", response.Summary)
+	require.Empty(t, response.UsageRecords)
+}
@@ -216,6 +216,12 @@ func (d *Document) GetSha256() (string, error) {
 	return d.Sha256, nil
 }
 
+// The stored summary of the document. Empty when the document was never summarized, or when
+// its content changed since the summary was created
+func (d *Document) CurrentSummary() string {
+	return d.getSummary()
+}
+
 func (d *Document) getSummary() string {
 	if d.Summary == "" || d.Sha256 != d.SummarySha256 {
 		return ""
@@ -0,0 +1,105 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/textsplitter"
+)
+
+type FeedItem struct {
+	*queries.FeedItem
+
+	// cached data to reduce compute if needed
+	raw      *bytes.Buffer
+	metadata *bytes.Buffer
+	logger   *slog.Logger
+}
+
+func NewFeedItemFromFeedItem(
+	ctx context.Context,
+	logger *slog.Logger,
+	item *queries.FeedItem,
+) (*FeedItem, error) {
+	return &FeedItem{FeedItem: item, logger: logger}, nil
+}
+
+// the content of a feed item is stored in the database as markdown when the feed is polled
+func (i *FeedItem) GetRaw(ctx context.Context) (*bytes.Buffer, error) {
+	if i.raw == nil {
+		buf := new(bytes.Buffer)
+		if i.Title != "" {
+			if _, err := buf.WriteString(fmt.Sprintf("# %s\n\n", i.Title)); err != nil {
+				return nil, fmt.Errorf("failed to write to the buffer: %w", err)
+			}
+		}
+		if _, err := buf.WriteString(i.Content); err != nil {
+			return nil, fmt.Errorf("failed to write to the buffer: %w", err)
+		}
+		i.raw = buf
+	}
+
+	return i.raw, nil
+}
+
+func (i *FeedItem) GetCleaned(ctx context.Context) (*bytes.Buffer, error) {
+	return i.GetRaw(ctx)
+}
+
+func (i *FeedItem) GetChunks(ctx context.Context) ([]string, error) {
+	content, err := i.GetCleaned(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the cleaned content: %w", err)
+	}
+
+	// chunk the content as a markdown doc
+	splitter := textsplitter.NewMarkdownTextSplitter(
+		textsplitter.WithChunkSize(2000),
+		textsplitter.WithChunkOverlap(200),
+	)
+	chunks, err := splitter.SplitText(content.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to split the text: %w", err)
+	}
+
+	return chunks, nil
+}
+
+func (i *FeedItem) GetMetadata(ctx context.Context) (*bytes.Buffer, error) {
+	if i.metadata == nil {
+		data := map[string]any{
+			"title":  i.Title,
+			"link":   i.Link,
+			"author": i.Author,
+			"feedId": i.FeedID,
+		}
+		if i.PublishedAt.Valid {
+			data["published"] = i.PublishedAt.Time
+		}
+
+		enc, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the metadata: %w", err)
+		}
+		i.metadata = bytes.NewBuffer(enc)
+	}
+
+	return i.metadata, nil
+}
+
+func (i *FeedItem) GetSha256() (string, error) {
+	return i.Sha256, nil
+}
+
+// feed items are not summarized
+func (i *FeedItem) getSummary() string {
+	return ""
+}
+
+func (i *FeedItem) setSummary(s string) error {
+	return nil
+}
@@ -26,6 +26,9 @@ const (
 	FT_xml  = "application/xml"
 	FT_doc  = "application/msword"
 	FT_docx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	FT_pptx = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	FT_odt  = "application/vnd.oasis.opendocument.text"
+	FT_rtf  = "application/rtf"
 
 	FT_unknown = "unknown"
 )
@@ -74,6 +77,12 @@ func ParseFileType(filename string) (Filetype, error) {
 		ft = FT_doc
 	case "docx":
 		ft = FT_docx
+	case "pptx":
+		ft = FT_pptx
+	case "odt":
+		ft = FT_odt
+	case "rtf":
+		ft = FT_rtf
 
 	case "png":
 		ft = FT_png
@@ -0,0 +1,88 @@
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+)
+
+type FolderContents struct {
+	Self      *queries.Folder     `json:"self"` // nil for the root of the customer
+	Folders   []*queries.Folder   `json:"folders"`
+	Documents []*queries.Document `json:"documents"`
+}
+
+/*
+Lists the folders and documents directly inside of the folder, or inside of the root of the
+customer when the folder id is not valid. Folders of other customers are treated as not existing.
+*/
+func ListFolderContents(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	folderId pgtype.UUID,
+) (*FolderContents, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if folderId.Valid {
+		logger = logger.With("folderId", folderId.Bytes)
+	}
+	logger.InfoContext(ctx, "Getting all children of the folder ...")
+
+	model := queries.New(db)
+
+	var err error
+	var folder *queries.Folder
+	var folders []*queries.Folder
+	var documents []*queries.Document
+
+	if !folderId.Valid {
+		// get root file information
+		folders, err = model.GetRootFoldersByCustomer(ctx, customerId)
+		if err != nil {
+			return nil, fmt.Errorf("there was an issue getting the folders: %v", err)
+		}
+		documents, err = model.GetRootDocumentsByCustomer(ctx, customerId)
+		if err != nil {
+			return nil, fmt.Errorf("there was an issue getting the documents: %v", err)
+		}
+	} else {
+		// get self
+		uid := utils.PGXUUIDToGoogleUUID(folderId)
+		if uid == nil {
+			return nil, fmt.Errorf("failed to convert folderId to a google uuid")
+		}
+		folder, err = model.GetFolder(ctx, *uid)
+		if err != nil {
+			return nil, fmt.Errorf("this folder does not exist: %w", err)
+		}
+		if folder.CustomerID != customerId {
+			return nil, fmt.Errorf("this folder does not exist")
+		}
+
+		// get the information using the folder
+		folders, err = model.GetFoldersFromParent(ctx, folderId)
+		if err != nil {
+			return nil, fmt.Errorf("there was an issue getting the folders: %v", err)
+		}
+		documents, err = model.GetDocumentsFromParent(ctx, folderId)
+		if err != nil {
+			return nil, fmt.Errorf("there was an issue getting the documents: %v", err)
+		}
+	}
+
+	logger.InfoContext(ctx, "Successfully listed folder contents", "folders", len(folders), "documents", len(documents))
+
+	return &FolderContents{
+		Self:      folder,
+		Folders:   folders,
+		Documents: documents,
+	}, nil
+}
@@ -20,6 +20,15 @@ type WebsitePage struct {
 	metadata *bytes.Buffer // for holding the headers
 	cleaned  *bytes.Buffer // data but cleaned
 	logger   *slog.Logger
+
+	skipReason string // why no usable text could be extracted
+}
+
+// metadata stored on the page and its vectors
+type websitePageMetadata struct {
+	*webparse.ScrapeHeader
+	Strategy   string `json:"strategy,omitempty"`
+	SkipReason string `json:"skipReason,omitempty"`
 }
 
 func NewWebsitePageFromWebsitePage(
@@ -32,12 +41,26 @@ func NewWebsitePageFromWebsitePage(
 
 func (p *WebsitePage) GetRaw(ctx context.Context) (*bytes.Buffer, error) {
 	if p.raw == nil {
+		// linked documents are downloaded and parsed instead of scraped
+		contentType := p.ContentType
+		if !webparse.IsDocumentContentType(contentType) {
+			contentType = webparse.DocumentContentTypeFromURL(p.Url)
+		}
+		if webparse.IsDocumentContentType(contentType) {
+			return p.getRawDocument(ctx)
+		}
+
 		// scrape the page
 		response, err := webparse.ScrapeSingle(ctx, p.logger, p.WebsitePage)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scrape the page: %w", err)
 		}
 
+		// the server reported a document without an extension on the url
+		if webparse.IsDocumentContentType(response.ContentType) {
+			return p.getRawDocument(ctx)
+		}
+
 		// create a buffer
 		buf := new(bytes.Buffer)
 		_, err = buf.WriteString(response.Content)
@@ -46,15 +69,55 @@ func (p *WebsitePage) GetRaw(ctx context.Context) (*bytes.Buffer, error) {
 		}
 
 		met := new(bytes.Buffer)
-		enc, _ := json.Marshal(response.Header)
-		if _, err := buf.Write(enc); err != nil {
+		enc, _ := json.Marshal(&websitePageMetadata{
+			ScrapeHeader: response.Header,
+			Strategy:     response.Strategy,
+			SkipReason:   response.SkipReason,
+		})
+		if _, err := met.Write(enc); err != nil {
 			return nil, fmt.Errorf("failed to write the header: %w", err)
 		}
 
 		p.raw = buf
 		p.metadata = met
+		p.skipReason = response.SkipReason
+	}
+
+	return p.raw, nil
+}
+
+// Downloads the document the page points to and parses the text from it
+func (p *WebsitePage) getRawDocument(ctx context.Context) (*bytes.Buffer, error) {
+	doc, err := webparse.FetchDocument(ctx, p.logger, p.Url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the document: %w", err)
 	}
 
+	content, err := ParseDynamic(doc.Data, Filetype(doc.ContentType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the document: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.WriteString(content); err != nil {
+		return nil, fmt.Errorf("failed to write to the buffer: %w", err)
+	}
+
+	met := new(bytes.Buffer)
+	enc, _ := json.Marshal(map[string]any{
+		"contentType": doc.ContentType,
+		"filename":    doc.Filename,
+		"sizeBytes":   len(doc.Data),
+	})
+	if _, err := met.Write(enc); err != nil {
+		return nil, fmt.Errorf("failed to write the metadata: %w", err)
+	}
+
+	p.ContentType = doc.ContentType
+	p.raw = buf
+	p.cleaned = buf // the parsed document text does not need further cleaning
+	p.metadata = met
+
 	return p.raw, nil
 }
 
@@ -122,19 +185,35 @@ func (p *WebsitePage) GetMetadata(ctx context.Context) (*bytes.Buffer, error) {
 	return p.metadata, nil
 }
 
+// Reason the page has no usable content. Only valid after the raw content is fetched
+func (p *WebsitePage) SkipReason() string {
+	return p.skipReason
+}
+
 func (p *WebsitePage) GetSha256() (string, error) {
 	return p.Sha256, nil
 }
 
+// The stored summary of the page. Empty when the page was never summarized, or when its
+// content changed since the summary was created
+func (p *WebsitePage) CurrentSummary() string {
+	return p.getSummary()
+}
+
+// the sha of a page is created from the url, the fingerprint of the content is only known
+// once the page is vectorized
 func (p *WebsitePage) getSummary() string {
-	if p.Summary == "" || p.Sha256 != p.SummarySha256 {
+	if p.Summary == "" || p.VectorSha256 == "" || p.VectorSha256 != p.SummarySha256 {
 		return ""
 	}
 	return p.Summary
 }
 
 func (p *WebsitePage) setSummary(s string) error {
+	if p.VectorSha256 == "" {
+		return fmt.Errorf("the page has not been vectorized")
+	}
 	p.Summary = s
-	p.SummarySha256 = p.Sha256
+	p.SummarySha256 = p.VectorSha256
 	return nil
 }
@@ -24,6 +24,26 @@ func RunJobs(
 					logger.Error("Error running vectorize job", "error", err)
 				}
 			}()
+			go func() {
+				if err := jobs.PollFeedsRunner(ctx, logger); err != nil {
+					logger.Error("Error running poll feeds job", "error", err)
+				}
+			}()
+			go func() {
+				if err := jobs.SummarizeDatastoreRunner(ctx, logger); err != nil {
+					logger.Error("Error running summarize datastore job", "error", err)
+				}
+			}()
+			go func() {
+				if err := jobs.EmbedConversationsRunner(ctx, logger); err != nil {
+					logger.Error("Error running embed conversations job", "error", err)
+				}
+			}()
+			go func() {
+				if err := jobs.CleanCompletionCacheRunner(ctx, logger); err != nil {
+					logger.Error("Error running clean completion cache job", "error", err)
+				}
+			}()
 		}
 	}
 }
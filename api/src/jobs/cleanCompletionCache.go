@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+
+	db "github.com/sapphirenw/ai-content-creation-api/src/database"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+// remove the completions that expired from the completion cache
+func CleanCompletionCacheRunner(
+	ctx context.Context,
+	logger *slog.Logger,
+) error {
+	pool, err := db.GetPool()
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to get the database pool", err)
+	}
+	if err := llm.DeleteExpiredCompletions(ctx, pool); err != nil {
+		return slogger.Error(ctx, logger, "failed to clean the completion cache", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
+	db "github.com/sapphirenw/ai-content-creation-api/src/database"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+// messages embedded on every run
+const EMBED_CONVERSATIONS_BATCH_SIZE = 100
+
+// time before a message that failed to embed is retried
+const EMBED_CONVERSATIONS_RETRY_INTERVAL = time.Hour
+
+// ensures only a single embedding run at a time
+var embedConversationsRunning atomic.Bool
+
+// embed the conversation messages that have not been embedded yet, so they can be searched
+func EmbedConversationsRunner(
+	ctx context.Context,
+	logger *slog.Logger,
+) error {
+	if !embedConversationsRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer embedConversationsRunning.Store(false)
+
+	pool, err := db.GetPool()
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to get the database pool", err)
+	}
+
+	msgs, err := conversation.GetMessagesToEmbed(ctx, pool, EMBED_CONVERSATIONS_BATCH_SIZE, time.Now().Add(-EMBED_CONVERSATIONS_RETRY_INTERVAL))
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to get the messages to embed", err)
+	}
+
+	// the messages are embedded in a request per customer
+	byCustomer := make(map[uuid.UUID][]*queries.GetConversationMessagesWithoutEmbeddingsRow)
+	order := make([]uuid.UUID, 0)
+	for _, item := range msgs {
+		if _, ok := byCustomer[item.CustomerID]; !ok {
+			order = append(order, item.CustomerID)
+		}
+		byCustomer[item.CustomerID] = append(byCustomer[item.CustomerID], item)
+	}
+
+	// failures are recorded on the messages, so they do not take up the next batches
+	customers := make(map[uuid.UUID]*customer.Customer)
+	for _, id := range order {
+		c, err := summarizeCustomer(ctx, logger, pool, customers, id)
+		if err == nil {
+			err = conversation.EmbedConversationMessages(ctx, logger, pool, c.Customer, byCustomer[id])
+			if err != nil {
+				slogger.Error(ctx, logger, "failed to embed the conversation messages", err)
+			}
+		}
+		if err != nil {
+			if err := conversation.SetMessagesEmbeddingFailed(ctx, pool, byCustomer[id]); err != nil {
+				slogger.Error(ctx, logger, "failed to record the failed embeddings", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/sapphirenw/ai-content-creation-api/src/customer"
+	db "github.com/sapphirenw/ai-content-creation-api/src/database"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+// ensures only a single poll runs at a time, as polling can take longer than the job interval
+var pollFeedsRunning atomic.Bool
+
+// poll all feeds that are due and vectorize their new entries
+func PollFeedsRunner(
+	ctx context.Context,
+	logger *slog.Logger,
+) error {
+	if !pollFeedsRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer pollFeedsRunning.Store(false)
+
+	pool, err := db.GetPool()
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to get the database pool", err)
+	}
+	dmodel := queries.New(pool)
+
+	// get the feeds that are due
+	feeds, err := dmodel.GetFeedsToPoll(ctx)
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to get the feeds to poll", err)
+	}
+
+	for _, feed := range feeds {
+		logger.InfoContext(ctx, "Processing feed", "feed.ID", feed.ID, "feed.Url", feed.Url)
+
+		// get the customer
+		c, err := customer.NewCustomer(ctx, logger, feed.CustomerID, pool)
+		if err != nil {
+			slogger.Error(ctx, logger, "failed to get the customer", err)
+			continue
+		}
+
+		// errors are recorded on the feed
+		if _, err := c.PollFeed(ctx, pool, feed); err != nil {
+			slogger.Error(ctx, logger, "failed to poll the feed", err)
+			continue
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer"
+	db "github.com/sapphirenw/ai-content-creation-api/src/database"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+// objects summarized on every run of each type
+const SUMMARIZE_DATASTORE_BATCH_SIZE = 10
+
+// time before an object whose summary failed is retried
+const SUMMARIZE_DATASTORE_RETRY_INTERVAL = time.Hour
+
+// ensures only a single summary run at a time, as summarizing can take longer than the job interval
+var summarizeDatastoreRunning atomic.Bool
+
+// summarize the documents and website pages whose content changed since their last summary
+func SummarizeDatastoreRunner(
+	ctx context.Context,
+	logger *slog.Logger,
+) error {
+	if !summarizeDatastoreRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer summarizeDatastoreRunning.Store(false)
+
+	pool, err := db.GetPool()
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to get the database pool", err)
+	}
+	dmodel := queries.New(pool)
+	failedBefore := pgtype.Timestamptz{Time: time.Now().Add(-SUMMARIZE_DATASTORE_RETRY_INTERVAL), Valid: true}
+	customers := make(map[uuid.UUID]*customer.Customer)
+
+	// get the objects to summarize
+	docs, err := dmodel.GetDocumentsToSummarize(ctx, &queries.GetDocumentsToSummarizeParams{
+		SummaryFailedAt: failedBefore,
+		Limit:           SUMMARIZE_DATASTORE_BATCH_SIZE,
+	})
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to get the documents to summarize", err)
+	}
+	pages, err := dmodel.GetWebsitePagesToSummarize(ctx, &queries.GetWebsitePagesToSummarizeParams{
+		SummaryFailedAt: failedBefore,
+		Limit:           SUMMARIZE_DATASTORE_BATCH_SIZE,
+	})
+	if err != nil {
+		return slogger.Error(ctx, logger, "failed to get the pages to summarize", err)
+	}
+
+	// errors are recorded on the objects
+	for _, doc := range docs {
+		c, err := summarizeCustomer(ctx, logger, pool, customers, doc.CustomerID)
+		if err != nil {
+			continue
+		}
+		if err := c.SummarizeDocument(ctx, pool, doc); err != nil {
+			slogger.Error(ctx, logger, "failed to summarize the document", err)
+		}
+	}
+	for _, page := range pages {
+		c, err := summarizeCustomer(ctx, logger, pool, customers, page.CustomerID)
+		if err != nil {
+			continue
+		}
+		if err := c.SummarizeWebsitePage(ctx, pool, page); err != nil {
+			slogger.Error(ctx, logger, "failed to summarize the page", err)
+		}
+	}
+
+	return nil
+}
+
+// gets the customer once per run
+func summarizeCustomer(
+	ctx context.Context,
+	logger *slog.Logger,
+	pool *pgxpool.Pool,
+	customers map[uuid.UUID]*customer.Customer,
+	id uuid.UUID,
+) (*customer.Customer, error) {
+	if c, ok := customers[id]; ok {
+		return c, nil
+	}
+	c, err := customer.NewCustomer(ctx, logger, id, pool)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to get the customer", err)
+	}
+	customers[id] = c
+	return c, nil
+}
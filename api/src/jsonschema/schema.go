@@ -0,0 +1,210 @@
+package jsonschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A JSON Schema document. Only the keywords needed to describe the go types used for structured
+// output are supported
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Values      *Schema            `json:"additionalProperties,omitempty"` // the values of a map
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Generates the schema of the go type `T` from its `json` tags. Fields without `omitempty` are
+// required. The `jsonschema` tag adds keywords to a field, separated by semicolons, such as
+// `jsonschema:"description=The title of the idea;minLength=1"`
+func For[T any]() (*Schema, error) {
+	var value T
+	return FromType(reflect.TypeOf(value))
+}
+
+// Same as `For` with a reflected type
+func FromType(t reflect.Type) (*Schema, error) {
+	if t == nil {
+		return nil, fmt.Errorf("the type cannot be nil")
+	}
+	return fromType(t, map[reflect.Type]bool{})
+}
+
+func fromType(t reflect.Type, seen map[reflect.Type]bool) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// types that marshal themselves as text, such as uuids
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoded as base64 by encoding/json
+			return &Schema{Type: "string"}, nil
+		}
+		items, err := fromType(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map keys must be strings: %s", t)
+		}
+		values, err := fromType(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", Values: values}, nil
+	case reflect.Struct:
+		if seen[t] {
+			return nil, fmt.Errorf("recursive types are not supported: %s", t)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		if err := addFields(s, t, seen); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported type: %s", t)
+}
+
+// adds the fields of the struct to the properties of the schema. The fields of embedded structs
+// are promoted like encoding/json does
+func addFields(s *Schema, t reflect.Type, seen map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addFields(s, ft, seen); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := fromType(field.Type, seen)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+		if err := property.applyTag(field.Tag.Get("jsonschema")); err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+
+		s.Properties[name] = property
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+// parses the keywords of a `jsonschema` tag
+func (s *Schema) applyTag(tag string) error {
+	if tag == "" {
+		return nil
+	}
+	for _, item := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch key {
+		case "description":
+			s.Description = value
+		case "format":
+			s.Format = value
+		case "enum":
+			s.Enum = strings.Split(value, "|")
+		case "minimum", "maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %s", key, value)
+			}
+			if key == "minimum" {
+				s.Minimum = &n
+			} else {
+				s.Maximum = &n
+			}
+		case "minLength", "minItems", "maxItems":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s: %s", key, value)
+			}
+			switch key {
+			case "minLength":
+				s.MinLength = &n
+			case "minItems":
+				s.MinItems = &n
+			default:
+				s.MaxItems = &n
+			}
+		case "":
+		default:
+			return fmt.Errorf("unknown jsonschema keyword: %s", key)
+		}
+	}
+	return nil
+}
+
+// The schema as a JSON string, as it is sent to the model
+func (s *Schema) String() string {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
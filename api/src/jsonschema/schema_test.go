@@ -0,0 +1,123 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+}
+
+type testIdea struct {
+	Title string   `json:"title" jsonschema:"description=The title of the idea;minLength=1"`
+	Score int      `json:"score" jsonschema:"minimum=0;maximum=100"`
+	Kind  string   `json:"kind,omitempty" jsonschema:"enum=post|video"`
+	Tags  []string `json:"tags,omitempty" jsonschema:"maxItems=2"`
+}
+
+type testIdeas struct {
+	testBase
+	Ideas    []*testIdea        `json:"ideas" jsonschema:"minItems=1"`
+	Metadata map[string]float64 `json:"metadata,omitempty"`
+	Ignored  string             `json:"-"`
+	internal string
+}
+
+func TestSchemaFor(t *testing.T) {
+	s, err := For[testIdeas]()
+	require.NoError(t, err)
+
+	require.Equal(t, "object", s.Type)
+	require.ElementsMatch(t, []string{"id", "ideas"}, s.Required)
+	require.Len(t, s.Properties, 4)
+	require.Equal(t, "string", s.Properties["id"].Type)
+	require.Equal(t, "date-time", s.Properties["createdAt"].Format)
+	require.Equal(t, "number", s.Properties["metadata"].Values.Type)
+
+	ideas := s.Properties["ideas"]
+	require.Equal(t, "array", ideas.Type)
+	require.Equal(t, 1, *ideas.MinItems)
+	require.Equal(t, []string{"title", "score"}, ideas.Items.Required)
+	require.Equal(t, "The title of the idea", ideas.Items.Properties["title"].Description)
+	require.Equal(t, "integer", ideas.Items.Properties["score"].Type)
+	require.Equal(t, []string{"post", "video"}, ideas.Items.Properties["kind"].Enum)
+
+	// the schema is valid JSON
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte(s.String()), &decoded))
+}
+
+func TestSchemaForErrors(t *testing.T) {
+	type recursive struct {
+		Children []*recursive `json:"children"`
+	}
+	_, err := For[recursive]()
+	require.ErrorContains(t, err, "recursive types are not supported")
+
+	_, err = For[map[int]string]()
+	require.ErrorContains(t, err, "map keys must be strings")
+
+	type badTag struct {
+		Title string `json:"title" jsonschema:"pattern=.*"`
+	}
+	_, err = For[badTag]()
+	require.ErrorContains(t, err, "unknown jsonschema keyword")
+}
+
+func TestSchemaValidate(t *testing.T) {
+	s, err := For[testIdeas]()
+	require.NoError(t, err)
+
+	id := uuid.New().String()
+	require.Empty(t, s.Validate([]byte(`{"id": "`+id+`", "ideas": [{"title": "Rocks", "score": 90, "kind": "post"}]}`)))
+
+	errs := s.Validate([]byte(`{
+		"ideas": [
+			{"title": "", "score": 120.5, "kind": "podcast", "tags": ["a", "b", "c"]},
+			{"score": "high"}
+		],
+		"metadata": {"views": "many"}
+	}`))
+	messages := make(map[string]string)
+	for _, item := range errs {
+		messages[item.Path] = item.Message
+	}
+	require.Equal(t, map[string]string{
+		"$.id":             "is required",
+		"$.ideas[0].title": "expected at least 1 characters",
+		"$.ideas[0].score": "expected an integer, got 120.5",
+		"$.ideas[0].kind":  `expected one of post, video, got "podcast"`,
+		"$.ideas[0].tags":  "expected at most 2 items, got 3",
+		"$.ideas[1].title": "is required",
+		"$.ideas[1].score": "expected an integer, got a string",
+		"$.metadata.views": "expected a number, got a string",
+	}, messages)
+
+	errs = s.Validate([]byte(`{"id": "` + id + `", "ideas": []}`))
+	require.Len(t, errs, 1)
+	require.Equal(t, "$.ideas: expected at least 1 items, got 0", errs[0].Error())
+
+	errs = s.Validate([]byte(`{"id": "` + id + `", "ideas": [{"title": "Rocks", "score": -1}]}`))
+	require.Len(t, errs, 1)
+	require.Equal(t, "$.ideas[0].score", errs[0].Path)
+
+	// invalid JSON is a single error
+	errs = s.Validate([]byte(`{"ideas": [`))
+	require.Len(t, errs, 1)
+	require.Equal(t, "$", errs[0].Path)
+	require.Contains(t, errs[0].Message, "invalid JSON")
+
+	errs = s.Validate([]byte(`{} {}`))
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Message, "unexpected content")
+
+	errs = s.Validate([]byte(`[]`))
+	require.Len(t, errs, 1)
+	require.Equal(t, "expected an object, got an array", errs[0].Message)
+}
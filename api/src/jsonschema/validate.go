@@ -0,0 +1,155 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A value that does not match the schema. The path points to the value from the root, such as
+// `$.ideas[0].title`
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+/*
+Validates the raw JSON against the schema. Every value that does not match is returned, so all
+the problems can be fixed at once. Invalid JSON is reported as a single error on the root.
+*/
+func (s *Schema) Validate(raw []byte) []*ValidationError {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []*ValidationError{{Path: "$", Message: fmt.Sprintf("invalid JSON: %s", err)}}
+	}
+	if decoder.More() {
+		return []*ValidationError{{Path: "$", Message: "invalid JSON: unexpected content after the value"}}
+	}
+
+	errs := make([]*ValidationError, 0)
+	s.validate("$", value, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, value any, errs *[]*ValidationError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch s.Type {
+	case "":
+		// any value
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			fail("expected an object, got %s", typeName(value))
+			return
+		}
+		for _, name := range s.Required {
+			if _, exists := obj[name]; !exists {
+				*errs = append(*errs, &ValidationError{Path: path + "." + name, Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := s.Properties[key]; ok {
+				property.validate(path+"."+key, obj[key], errs)
+			} else if s.Values != nil {
+				s.Values.validate(path+"."+key, obj[key], errs)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			fail("expected an array, got %s", typeName(value))
+			return
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			fail("expected at least %d items, got %d", *s.MinItems, len(items))
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			fail("expected at most %d items, got %d", *s.MaxItems, len(items))
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("expected a string, got %s", typeName(value))
+			return
+		}
+		if s.MinLength != nil && utf8.RuneCountInString(str) < *s.MinLength {
+			fail("expected at least %d characters", *s.MinLength)
+		}
+		if len(s.Enum) != 0 && !slices.Contains(s.Enum, str) {
+			fail("expected one of %s, got %q", strings.Join(s.Enum, ", "), str)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("expected a boolean, got %s", typeName(value))
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			expected := "a number"
+			if s.Type == "integer" {
+				expected = "an integer"
+			}
+			fail("expected %s, got %s", expected, typeName(value))
+			return
+		}
+		if s.Type == "integer" {
+			if _, err := num.Int64(); err != nil {
+				fail("expected an integer, got %s", num)
+				return
+			}
+		}
+		f, err := num.Float64()
+		if err != nil {
+			fail("expected a number, got %s", num)
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("expected a minimum of %v, got %s", *s.Minimum, num)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("expected a maximum of %v, got %s", *s.Maximum, num)
+		}
+	}
+}
+
+// the JSON type of a decoded value
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+// how long a cached completion is served when the caller does not set a ttl
+const DEFAULT_CACHE_TTL = 7 * 24 * time.Hour
+
+/*
+Opts a completion into the completion cache. The response is stored in the database keyed by
+the model, temperature, json schema, and the messages sent to the provider, including the
+system prompt with the model instructions. A later request with the same key is answered from
+the cache and reported as usage with zero tokens.
+
+Only completions of models with a temperature of 0 are cached unless `Always` is set, as the
+responses of other temperatures are expected to vary. Completions with tools are never cached.
+*/
+type CacheOptions struct {
+	DB     queries.DBTX
+	TTL    time.Duration // `DEFAULT_CACHE_TTL` when 0
+	Always bool          // cache even when the temperature of the model is above 0
+}
+
+// Caches the completions with the default ttl when the model is deterministic
+func NewCacheOptions(db queries.DBTX) *CacheOptions {
+	return &CacheOptions{DB: db}
+}
+
+func (opts *CacheOptions) ttl() time.Duration {
+	if opts.TTL <= 0 {
+		return DEFAULT_CACHE_TTL
+	}
+	return opts.TTL
+}
+
+// whether the completion of the model can be served from the cache
+func (model *LLM) cacheable(args *CompletionArgs) bool {
+	if args.Cache == nil || args.Cache.DB == nil {
+		return false
+	}
+	if len(args.Tools) != 0 || args.RequiredTool != nil {
+		return false
+	}
+	return model.Llm.Temperature == 0 || args.Cache.Always
+}
+
+// content address of the completion of the prepared messages with the model
+func (model *LLM) cacheKey(args *CompletionArgs, msgs []*gollm.Message) (string, error) {
+	raw, err := json.Marshal(map[string]any{
+		"model":       model.Llm.Model,
+		"temperature": model.Llm.Temperature,
+		"json":        args.Json,
+		"jsonSchema":  args.JsonSchema,
+		"messages":    msgs,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the cache key: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+/*
+Runs a single completion through the cache when the args opt into it. A failing cache is logged
+and skipped so the completion itself never fails because of the cache.
+*/
+func (model *LLM) cachedCompletion(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *CompletionArgs,
+	msgs []*gollm.Message,
+) (*gollm.CompletionResponse, error) {
+	if !model.cacheable(args) {
+		return model.completion(ctx, logger, args, msgs)
+	}
+
+	customerId, err := uuid.Parse(args.CustomerID)
+	if err != nil {
+		return nil, fmt.Errorf("the CustomerID is not a valid uuid: %w", err)
+	}
+	key, err := model.cacheKey(args, msgs)
+	if err != nil {
+		return nil, err
+	}
+	l := logger.With("cacheKey", key)
+	dmodel := queries.New(args.Cache.DB)
+
+	// look for a stored response
+	hit, err := dmodel.GetCompletionCacheHit(ctx, &queries.GetCompletionCacheHitParams{
+		CustomerID: customerId,
+		Key:        key,
+	})
+	if err == nil {
+		l.InfoContext(ctx, "Serving the completion from the cache", "hits", hit.Hits)
+		return cacheHitResponse(model, hit)
+	}
+	if !strings.Contains(err.Error(), "no rows in result set") {
+		l.ErrorContext(ctx, "Failed to read the completion cache", "error", err)
+	}
+
+	response, err := model.completion(ctx, logger, args, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// store the response for the next request
+	_, err = dmodel.UpsertCompletionCache(ctx, &queries.UpsertCompletionCacheParams{
+		CustomerID:   customerId,
+		Key:          key,
+		Model:        model.Llm.Model,
+		Message:      response.Message.Message,
+		InputTokens:  int32(response.UsageRecord.InputTokens),
+		OutputTokens: int32(response.UsageRecord.OutputTokens),
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(args.Cache.ttl()), Valid: true},
+	})
+	if err != nil {
+		l.ErrorContext(ctx, "Failed to store the completion in the cache", "error", err)
+	}
+
+	return response, nil
+}
+
+// the response of a cache hit. The usage is reported against the model with zero tokens, so
+// the hit is visible in the usage without adding to the cost
+func cacheHitResponse(model *LLM, hit *queries.CompletionCache) (*gollm.CompletionResponse, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the usage record id: %w", err)
+	}
+	return &gollm.CompletionResponse{
+		Message: &gollm.Message{
+			Role:    gollm.RoleAI,
+			Message: hit.Message,
+		},
+		UsageRecord: &tokens.UsageRecord{
+			ID:    id,
+			Model: model.Llm.Model,
+		},
+	}, nil
+}
+
+// Removes the expired completions from the cache
+func DeleteExpiredCompletions(ctx context.Context, db queries.DBTX) error {
+	dmodel := queries.New(db)
+	if err := dmodel.DeleteExpiredCompletionCache(ctx); err != nil {
+		return fmt.Errorf("failed to delete the expired completions: %w", err)
+	}
+	return nil
+}
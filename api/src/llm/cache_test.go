@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/testingutils"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletionCacheKey(t *testing.T) {
+	model := mockTestModel("mock-cache")
+	args := &CompletionArgs{CustomerID: "test", Messages: streamTestMessages()}
+	msgs, err := model.prepareMessages(args)
+	require.NoError(t, err)
+
+	key, err := model.cacheKey(args, msgs)
+	require.NoError(t, err)
+	require.Len(t, key, 64)
+
+	// the same request has the same key
+	again, err := model.cacheKey(args, msgs)
+	require.NoError(t, err)
+	require.Equal(t, key, again)
+
+	// the temperature, the instructions, and the input are part of the key
+	other := mockTestModel("mock-cache")
+	other.Llm.Temperature = 0.5
+	changed, err := other.cacheKey(args, msgs)
+	require.NoError(t, err)
+	require.NotEqual(t, key, changed)
+
+	other = mockTestModel("mock-cache")
+	other.Llm.Instructions = "Be verbose"
+	otherMsgs, err := other.prepareMessages(args)
+	require.NoError(t, err)
+	changed, err = other.cacheKey(args, otherMsgs)
+	require.NoError(t, err)
+	require.NotEqual(t, key, changed)
+
+	args.Messages[1].Message = "Goodbye"
+	msgs, err = model.prepareMessages(args)
+	require.NoError(t, err)
+	changed, err = model.cacheKey(args, msgs)
+	require.NoError(t, err)
+	require.NotEqual(t, key, changed)
+}
+
+func TestCompletionCacheable(t *testing.T) {
+	model := mockTestModel("mock-cache")
+	args := &CompletionArgs{CustomerID: "test", Messages: streamTestMessages()}
+	require.False(t, model.cacheable(args))
+
+	args.Cache = &CacheOptions{}
+	require.False(t, model.cacheable(args), "the cache needs a database")
+
+	args.Cache = NewCacheOptions((*pgxpool.Pool)(nil))
+	require.True(t, model.cacheable(args))
+	require.Equal(t, DEFAULT_CACHE_TTL, args.Cache.ttl())
+
+	// only deterministic models unless the caller opts in
+	model.Llm.Temperature = 0.7
+	require.False(t, model.cacheable(args))
+	args.Cache.Always = true
+	require.True(t, model.cacheable(args))
+
+	// tool calls are never cached
+	args.RequiredTool = &gollm.Tool{Title: "vector_query"}
+	require.False(t, model.cacheable(args))
+}
+
+func TestCompletionCache(t *testing.T) {
+	ctx := context.Background()
+	pool := testingutils.GetDatabase(t, ctx)
+	c := testingutils.GetTestCustomer(t, ctx, pool)
+	logger := utils.DefaultLogger()
+
+	Mock.Reset()
+	defer Mock.Reset()
+	item := testingutils.GetMockLLM(t, ctx, pool, c, "mock-cache-db")
+	model := FromObjects(&item.Llm, &item.AvailableModel)
+
+	Mock.Queue("mock-cache-db", &MockResponse{Message: "Rock Facts", InputTokens: 40, OutputTokens: 3})
+
+	// the first request reaches the provider
+	response, err := model.CachedSingleCompletion(ctx, logger, pool, c.ID, "Create a title", "Tell me about rocks")
+	require.NoError(t, err)
+	require.Equal(t, "Rock Facts", response.Message.Message)
+	require.Equal(t, 43, response.UsageRecord.TotalTokens)
+
+	// the same request is served from the cache at no cost
+	response, err = model.CachedSingleCompletion(ctx, logger, pool, c.ID, "Create a title", "Tell me about rocks")
+	require.NoError(t, err)
+	require.Equal(t, "Rock Facts", response.Message.Message)
+	require.Equal(t, "mock-cache-db", response.UsageRecord.Model)
+	require.Zero(t, response.UsageRecord.TotalTokens)
+	require.Len(t, Mock.Requests(), 1)
+
+	// a different input is not
+	_, err = model.CachedSingleCompletion(ctx, logger, pool, c.ID, "Create a title", "Tell me about trees")
+	require.NoError(t, err)
+	require.Len(t, Mock.Requests(), 2)
+
+	// expired completions are not served
+	_, err = model.Completion(ctx, logger, &CompletionArgs{
+		CustomerID: c.ID.String(),
+		Messages: []*gollm.Message{
+			{Role: gollm.RoleSystem, Message: "Create a title"},
+			{Role: gollm.RoleUser, Message: "Tell me about stars"},
+		},
+		Cache: &CacheOptions{DB: pool, TTL: time.Millisecond},
+	})
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, DeleteExpiredCompletions(ctx, pool))
+	_, err = model.Completion(ctx, logger, &CompletionArgs{
+		CustomerID: c.ID.String(),
+		Messages: []*gollm.Message{
+			{Role: gollm.RoleSystem, Message: "Create a title"},
+			{Role: gollm.RoleUser, Message: "Tell me about stars"},
+		},
+		Cache: NewCacheOptions(pool),
+	})
+	require.NoError(t, err)
+	require.Len(t, Mock.Requests(), 4)
+}
@@ -16,6 +16,9 @@ import (
 type LLM struct {
 	*queries.Llm            `json:"llm"`
 	*queries.AvailableModel `json:"availableModel"`
+
+	// nil when the default policy is used, see `LoadRetryPolicy`
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
 }
 
 type CompletionArgs struct {
@@ -26,6 +29,10 @@ type CompletionArgs struct {
 
 	Json       bool
 	JsonSchema string
+
+	// serves deterministic completions from the database, see `CacheOptions`. Nil disables
+	// the cache
+	Cache *CacheOptions
 }
 
 func CreateLLM(
@@ -100,7 +107,7 @@ func GetLLM(ctx context.Context, db queries.DBTX, customerId uuid.UUID, id pgtyp
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch the llm with id: %w", err)
 		}
-		return &LLM{Llm: &llm.Llm, AvailableModel: &llm.AvailableModel}, nil
+		return withRetryPolicy(ctx, db, &LLM{Llm: &llm.Llm, AvailableModel: &llm.AvailableModel})
 
 	} else {
 		// get the customer's default
@@ -108,8 +115,15 @@ func GetLLM(ctx context.Context, db queries.DBTX, customerId uuid.UUID, id pgtyp
 		if err != nil {
 			return nil, fmt.Errorf("error fetching the default llm: %w", err)
 		}
-		return &LLM{Llm: &llm.Llm, AvailableModel: &llm.AvailableModel}, nil
+		return withRetryPolicy(ctx, db, &LLM{Llm: &llm.Llm, AvailableModel: &llm.AvailableModel})
+	}
+}
+
+func withRetryPolicy(ctx context.Context, db queries.DBTX, model *LLM) (*LLM, error) {
+	if err := model.LoadRetryPolicy(ctx, db); err != nil {
+		return nil, fmt.Errorf("failed to load the retry policy: %w", err)
 	}
+	return model, nil
 }
 
 func GetLLMString(ctx context.Context, db queries.DBTX, customerId uuid.UUID, id string) (*LLM, error) {
@@ -123,6 +137,10 @@ func FromObjects(llm *queries.Llm, availableModel *queries.AvailableModel) *LLM
 }
 
 func (model *LLM) GetEstimatedTokens(input string) (int32, error) {
+	// gollm does not know the models of the mock provider
+	if model.AvailableModel != nil && model.AvailableModel.Provider == PROVIDER_MOCK {
+		return int32(estimateTokens(input)), nil
+	}
 	tokens, err := gollm.TokenEstimate(model.Llm.Model, input)
 	if err != nil {
 		return 0, fmt.Errorf("failed to estimate token usage: %w", err)
@@ -130,6 +148,12 @@ func (model *LLM) GetEstimatedTokens(input string) (int32, error) {
 	return int32(tokens), nil
 }
 
+// roughly 4 characters per token, for the models the estimator of gollm
+// does not know. It does not depend on the model, so the usage is stable across runs
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
 func (model *LLM) GenerateSystemPrompt(prompt string) string {
 	if model.Llm.Instructions == "" && prompt == "" {
 		return "Follow the internal instructions you have been given."
@@ -148,6 +172,52 @@ func (model *LLM) Completion(
 	logger *slog.Logger,
 	args *CompletionArgs,
 ) (*gollm.CompletionResponse, error) {
+	response, _, err := model.CompletionWithModel(ctx, logger, args)
+	return response, err
+}
+
+// Same as `Completion`, but also returns the configuration that generated the response. This is
+// one of the fallbacks of the retry policy when the model kept failing
+func (model *LLM) CompletionWithModel(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *CompletionArgs,
+) (*gollm.CompletionResponse, *LLM, error) {
+	var response *gollm.CompletionResponse
+	used, err := model.runWithPolicy(ctx, logger, args, func(current *LLM, msgs []*gollm.Message) error {
+		var err error
+		response, err = current.cachedCompletion(ctx, logger, args, msgs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, used, nil
+}
+
+// runs a single completion of the prepared messages against the provider of the model
+func (model *LLM) completion(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *CompletionArgs,
+	msgs []*gollm.Message,
+) (*gollm.CompletionResponse, error) {
+	l := logger.With("completionType", "multi", "provider", model.AvailableModel.Provider)
+	l.InfoContext(ctx, "Sending the completion request ...")
+
+	response, err := GetProvider(model.AvailableModel.Provider).Completion(ctx, l, model, args, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	l.InfoContext(ctx, "Successfully sent the request")
+
+	return response, nil
+}
+
+// Validates the completion args and returns the messages to send with the model
+// specific instructions added to the system message
+func (model *LLM) prepareMessages(args *CompletionArgs) ([]*gollm.Message, error) {
 	if args == nil {
 		return nil, fmt.Errorf("the input cannot be empty")
 	}
@@ -161,35 +231,17 @@ func (model *LLM) Completion(
 		return nil, fmt.Errorf("the messages array must be filled")
 	}
 
-	l := logger.With("completionType", "multi")
-	l.InfoContext(ctx, "Sending the completion request ...")
-
 	// create a copy of the list
 	msgs := make([]*gollm.Message, len(args.Messages))
 	copy(msgs, args.Messages)
 
-	// add model specific instructions to the system message
-	msgs[0].Message = fmt.Sprintf("General Instructions: %s\n\nSpecific Instructions: %s", model.Llm.Instructions, msgs[0].Message)
+	// add model specific instructions to the system message. The message is copied
+	// so the instructions are not added to the caller's message on every request
+	system := *msgs[0]
+	system.Message = fmt.Sprintf("General Instructions: %s\n\nSpecific Instructions: %s", model.Llm.Instructions, system.Message)
+	msgs[0] = &system
 
-	input := &gollm.CompletionInput{
-		Model:        model.Llm.Model,
-		Temperature:  model.Llm.Temperature,
-		Json:         args.Json,
-		JsonSchema:   args.JsonSchema,
-		Conversation: msgs,
-		Tools:        args.Tools,
-		RequiredTool: args.RequiredTool,
-	}
-
-	lm := gollm.NewLanguageModel(args.CustomerID, logger, nil)
-	response, err := lm.Completion(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed the dynamic completion: %w", err)
-	}
-
-	l.InfoContext(ctx, "Successfully sent the request")
-
-	return response, nil
+	return msgs, nil
 }
 
 // Convenience function wrapper around the `Completion` function for performing one-off requests
@@ -200,12 +252,38 @@ func (model *LLM) SingleCompletion(
 	systemMessage string,
 	input string,
 ) (*gollm.CompletionResponse, error) {
-	messages := make([]*gollm.Message, 0)
-	messages = append(messages, gollm.NewSystemMessage(systemMessage))
-	messages = append(messages, gollm.NewUserMessage(input))
+	return model.singleCompletion(ctx, logger, customerId, systemMessage, input, nil)
+}
+
+// Same as `SingleCompletion`, but served from the completion cache when the model is
+// deterministic. Meant for the internal utility completions that repeat the same input
+func (model *LLM) CachedSingleCompletion(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	systemMessage string,
+	input string,
+) (*gollm.CompletionResponse, error) {
+	return model.singleCompletion(ctx, logger, customerId, systemMessage, input, NewCacheOptions(db))
+}
+
+func (model *LLM) singleCompletion(
+	ctx context.Context,
+	logger *slog.Logger,
+	customerId uuid.UUID,
+	systemMessage string,
+	input string,
+	cache *CacheOptions,
+) (*gollm.CompletionResponse, error) {
+	messages := []*gollm.Message{
+		{Role: gollm.RoleSystem, Message: systemMessage},
+		{Role: gollm.RoleUser, Message: input},
+	}
 
 	return model.Completion(ctx, logger, &CompletionArgs{
 		CustomerID: customerId.String(),
 		Messages:   messages,
+		Cache:      cache,
 	})
 }
@@ -0,0 +1,261 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+)
+
+// A scripted response of the mock provider
+type MockResponse struct {
+	Message   string           // text of the response, the raw json for json completions
+	ToolCalls []*gollm.Message // tools the model calls, in order. The text is kept on the first call
+	Err       error            // returned instead of the response
+
+	// simulated token usage, estimated from the text when 0
+	InputTokens  int
+	OutputTokens int
+}
+
+// A completion request the mock provider received
+type MockRequest struct {
+	Model        string
+	Messages     []*gollm.Message
+	Tools        []*gollm.Tool
+	RequiredTool *gollm.Tool
+	Json         bool
+	JsonSchema   string
+	Stream       bool
+}
+
+/*
+Deterministic provider for tests, selected with `PROVIDER_MOCK` as the provider of the available
+model. Responses are queued per model id, or for every model with an empty model id, and are
+returned in order. Without a queued response the provider calls the required tool, returns an
+empty json object in json mode, or repeats the last message.
+
+Every request is recorded so tests can assert on what was sent to the model.
+*/
+type MockProvider struct {
+	mu        sync.Mutex
+	responses map[string][]*MockResponse
+	requests  []*MockRequest
+	calls     int
+}
+
+// The mock provider registered under `PROVIDER_MOCK`
+var Mock = NewMockProvider()
+
+func NewMockProvider() *MockProvider {
+	return &MockProvider{
+		responses: make(map[string][]*MockResponse),
+		requests:  make([]*MockRequest, 0),
+	}
+}
+
+// Queues the responses of the model. An empty model id queues the responses for every model,
+// which are used once the responses queued for the model run out
+func (p *MockProvider) Queue(model string, responses ...*MockResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses[model] = append(p.responses[model], responses...)
+}
+
+// The requests the provider received, in order
+func (p *MockProvider) Requests() []*MockRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	response := make([]*MockRequest, len(p.requests))
+	copy(response, p.requests)
+	return response
+}
+
+// Removes the queued responses and the recorded requests
+func (p *MockProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses = make(map[string][]*MockResponse)
+	p.requests = make([]*MockRequest, 0)
+	p.calls = 0
+}
+
+func (p *MockProvider) Completion(
+	ctx context.Context,
+	logger *slog.Logger,
+	model *LLM,
+	args *CompletionArgs,
+	msgs []*gollm.Message,
+) (*gollm.CompletionResponse, error) {
+	response := p.next(model, args, msgs, false)
+	if response.Err != nil {
+		return nil, response.Err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed the mock completion: %w", err)
+	}
+
+	message := &gollm.Message{
+		Role:    gollm.RoleAI,
+		Message: response.Message,
+	}
+	if len(response.ToolCalls) != 0 {
+		message = response.ToolCalls[0]
+		message.Message = response.Message
+	}
+
+	record, err := mockUsageRecord(model, response, msgs)
+	if err != nil {
+		return nil, err
+	}
+	return &gollm.CompletionResponse{
+		Message:     message,
+		UsageRecord: record,
+	}, nil
+}
+
+// streams the text word by word followed by the tool calls, stopping when the context is
+// cancelled
+func (p *MockProvider) stream(
+	ctx context.Context,
+	logger *slog.Logger,
+	model *LLM,
+	msgs []*gollm.Message,
+	args *CompletionArgs,
+	assembler *streamAssembler,
+) error {
+	response := p.next(model, args, msgs, true)
+	if response.Err != nil {
+		return response.Err
+	}
+
+	for _, item := range strings.SplitAfter(response.Message, " ") {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := assembler.delta(item); err != nil {
+			return err
+		}
+	}
+	for i, item := range response.ToolCalls {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := assembler.toolCall(i, item.ToolUseID, item.ToolName); err != nil {
+			return err
+		}
+		arguments, err := json.Marshal(item.ToolArguments)
+		if err != nil {
+			return fmt.Errorf("failed to encode the tool arguments: %w", err)
+		}
+		assembler.toolArguments(i, string(arguments))
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	record, err := mockUsageRecord(model, response, msgs)
+	if err != nil {
+		return err
+	}
+	assembler.inputTokens = record.InputTokens
+	assembler.outputTokens = record.OutputTokens
+	return nil
+}
+
+// records the request and pops the next response of the model
+func (p *MockProvider) next(
+	model *LLM,
+	args *CompletionArgs,
+	msgs []*gollm.Message,
+	stream bool,
+) *MockResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, &MockRequest{
+		Model:        model.Llm.Model,
+		Messages:     msgs,
+		Tools:        args.Tools,
+		RequiredTool: args.RequiredTool,
+		Json:         args.Json,
+		JsonSchema:   args.JsonSchema,
+		Stream:       stream,
+	})
+
+	var response *MockResponse
+	for _, key := range []string{model.Llm.Model, ""} {
+		if queued := p.responses[key]; len(queued) != 0 {
+			response = queued[0]
+			p.responses[key] = queued[1:]
+			break
+		}
+	}
+	if response == nil {
+		response = mockDefaultResponse(args, msgs)
+	}
+
+	// copy the tool calls so the queued response is not changed, and give them ids
+	calls := make([]*gollm.Message, len(response.ToolCalls))
+	for i, item := range response.ToolCalls {
+		p.calls++
+		call := *item
+		call.Role = gollm.RoleToolCall
+		if call.ToolUseID == "" {
+			call.ToolUseID = fmt.Sprintf("mock_call_%d", p.calls)
+		}
+		if call.ToolArguments == nil {
+			call.ToolArguments = make(map[string]any)
+		}
+		calls[i] = &call
+	}
+	scripted := *response
+	scripted.ToolCalls = calls
+	return &scripted
+}
+
+// the response when nothing was queued for the model
+func mockDefaultResponse(args *CompletionArgs, msgs []*gollm.Message) *MockResponse {
+	if args.RequiredTool != nil {
+		return &MockResponse{ToolCalls: []*gollm.Message{{ToolName: args.RequiredTool.Title}}}
+	}
+	if args.Json {
+		return &MockResponse{Message: "{}"}
+	}
+	return &MockResponse{Message: fmt.Sprintf("Mock response to: %s", msgs[len(msgs)-1].Message)}
+}
+
+func mockUsageRecord(model *LLM, response *MockResponse, msgs []*gollm.Message) (*tokens.UsageRecord, error) {
+	input := response.InputTokens
+	if input == 0 {
+		for _, item := range msgs {
+			input += estimateTokens(item.Message)
+		}
+	}
+	output := response.OutputTokens
+	if output == 0 {
+		output = estimateTokens(response.Message)
+		for _, item := range response.ToolCalls {
+			arguments, _ := json.Marshal(item.ToolArguments)
+			output += estimateTokens(item.ToolName + string(arguments))
+		}
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the usage record id: %w", err)
+	}
+	return &tokens.UsageRecord{
+		ID:           id,
+		Model:        model.Llm.Model,
+		InputTokens:  input,
+		OutputTokens: output,
+		TotalTokens:  input + output,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func mockTestModel(modelId string) *LLM {
+	return &LLM{
+		Llm:            &queries.Llm{Model: modelId, Instructions: "Be brief"},
+		AvailableModel: &queries.AvailableModel{Provider: PROVIDER_MOCK},
+	}
+}
+
+func TestMockProviderCompletion(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-completion")
+
+	Mock.Queue("mock-completion", &MockResponse{Message: `{"title":"Rocks"}`, InputTokens: 20, OutputTokens: 5})
+	Mock.Queue("", &MockResponse{Err: fmt.Errorf("invalid request")})
+
+	// the response queued for the model
+	response, err := model.Completion(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+		Json:       true,
+		JsonSchema: `{"type":"object"}`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, gollm.RoleAI, response.Message.Role)
+	require.Equal(t, `{"title":"Rocks"}`, response.Message.Message)
+	require.Equal(t, 25, response.UsageRecord.TotalTokens)
+	require.Equal(t, "mock-completion", response.UsageRecord.Model)
+
+	// the response queued for every model
+	args := &CompletionArgs{CustomerID: "test", Messages: streamTestMessages()}
+	_, err = model.Completion(context.TODO(), utils.DefaultLogger(), args)
+	require.ErrorContains(t, err, "invalid request")
+
+	// nothing queued
+	response, err = model.Completion(context.TODO(), utils.DefaultLogger(), args)
+	require.NoError(t, err)
+	require.Equal(t, "Mock response to: Hello", response.Message.Message)
+	require.NotZero(t, response.UsageRecord.InputTokens)
+
+	// the requests are recorded with the instructions of the model
+	requests := Mock.Requests()
+	require.Len(t, requests, 3)
+	require.True(t, requests[0].Json)
+	require.Contains(t, requests[0].Messages[0].Message, "Be brief")
+	require.False(t, requests[0].Stream)
+}
+
+func TestMockProviderRequiredTool(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-tool")
+
+	response, err := model.Completion(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID:   "test",
+		Messages:     streamTestMessages(),
+		RequiredTool: &gollm.Tool{Title: "vector_query"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, gollm.RoleToolCall, response.Message.Role)
+	require.Equal(t, "vector_query", response.Message.ToolName)
+	require.NotEmpty(t, response.Message.ToolUseID)
+}
+
+func TestMockProviderStream(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-stream")
+
+	Mock.Queue("mock-stream", &MockResponse{
+		Message: "Let me check.",
+		ToolCalls: []*gollm.Message{
+			{ToolName: "vector_query", ToolArguments: map[string]any{"vector_query": "rocks"}},
+			{ToolName: "web_search", ToolArguments: map[string]any{"query": "rocks"}},
+		},
+	})
+
+	deltas := make([]string, 0)
+	toolCalls := make([]string, 0)
+	response, err := model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		if chunk.Delta != "" {
+			deltas = append(deltas, chunk.Delta)
+		}
+		if chunk.ToolCallStart != "" {
+			toolCalls = append(toolCalls, chunk.ToolCallStart)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Let ", "me ", "check."}, deltas)
+	require.Equal(t, []string{"vector_query", "web_search"}, toolCalls)
+	require.Len(t, response.ToolCalls, 2)
+	require.Equal(t, "Let me check.", response.Message.Message)
+	require.Equal(t, "rocks", response.ToolCalls[1].ToolArguments["query"])
+	require.NotEqual(t, response.ToolCalls[0].ToolUseID, response.ToolCalls[1].ToolUseID)
+	require.NotZero(t, response.UsageRecord.OutputTokens)
+	require.True(t, Mock.Requests()[0].Stream)
+}
+
+func TestMockProviderStreamCancelled(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-cancel")
+
+	Mock.Queue("mock-cancel", &MockResponse{Message: "one two three four"})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	response, err := model.CompletionStream(ctx, utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		cancel()
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, "one ", response.Message.Message)
+}
+
+func TestGetProvider(t *testing.T) {
+	// openai and anthropic stream against their api, the others send the whole message as a
+	// single chunk
+	for _, name := range []string{PROVIDER_OPENAI, PROVIDER_ANTHROPIC} {
+		_, ok := GetProvider(name).(streamProvider)
+		require.True(t, ok, name)
+	}
+	_, ok := GetProvider(PROVIDER_GOOGLE).(streamProvider)
+	require.False(t, ok)
+	require.Equal(t, Mock, GetProvider(PROVIDER_MOCK))
+	require.NotNil(t, GetProvider("unknown"))
+
+	require.Panics(t, func() {
+		RegisterProvider(PROVIDER_MOCK, NewMockProvider())
+	})
+}
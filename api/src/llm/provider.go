@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+)
+
+/*
+Runs the completions of the models of an `available_model.provider`. The messages are already
+prepared with the instructions of the model, and the provider has to return a usage record
+with the response.
+*/
+type Provider interface {
+	Completion(
+		ctx context.Context,
+		logger *slog.Logger,
+		model *LLM,
+		args *CompletionArgs,
+		msgs []*gollm.Message,
+	) (*gollm.CompletionResponse, error)
+}
+
+// Providers that stream the completion into the assembler. Providers that do not implement it
+// run a normal completion when a stream is requested
+type streamProvider interface {
+	Provider
+	stream(
+		ctx context.Context,
+		logger *slog.Logger,
+		model *LLM,
+		msgs []*gollm.Message,
+		args *CompletionArgs,
+		assembler *streamAssembler,
+	) error
+}
+
+var (
+	providers   = make(map[string]Provider)
+	providersMu sync.RWMutex
+)
+
+func init() {
+	RegisterProvider(PROVIDER_OPENAI, &gollmStreamProvider{streamer: (*LLM).streamOpenAI})
+	RegisterProvider(PROVIDER_ANTHROPIC, &gollmStreamProvider{streamer: (*LLM).streamAnthropic})
+	RegisterProvider(PROVIDER_GOOGLE, &gollmProvider{})
+	RegisterProvider(PROVIDER_MOCK, Mock)
+}
+
+// Registers the provider that runs the completions of the models with the provider name.
+// Registering the same name twice panics.
+func RegisterProvider(name string, provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, exists := providers[name]; exists {
+		panic(fmt.Sprintf("FATAL: the provider is registered twice: %s", name))
+	}
+	providers[name] = provider
+}
+
+// Gets the provider registered with the name. Unknown providers are sent through gollm, which
+// resolves the provider from the name of the model
+func GetProvider(name string) Provider {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	if provider, ok := providers[name]; ok {
+		return provider
+	}
+	return &gollmProvider{}
+}
+
+// sends the completions to the real providers through gollm
+type gollmProvider struct{}
+
+func (p *gollmProvider) Completion(
+	ctx context.Context,
+	logger *slog.Logger,
+	model *LLM,
+	args *CompletionArgs,
+	msgs []*gollm.Message,
+) (*gollm.CompletionResponse, error) {
+	lm := gollm.NewLanguageModel(args.CustomerID, logger, nil)
+	response, err := lm.Completion(ctx, gollmInput(model, args, msgs))
+	if err != nil {
+		return nil, fmt.Errorf("failed the dynamic completion: %w", err)
+	}
+	return response, nil
+}
+
+// Sends the completions through gollm and streams them against the api of the provider, as
+// gollm does not stream
+type gollmStreamProvider struct {
+	gollmProvider
+	streamer func(*LLM, context.Context, []*gollm.Message, *CompletionArgs, *streamAssembler) error
+}
+
+func (p *gollmStreamProvider) stream(
+	ctx context.Context,
+	logger *slog.Logger,
+	model *LLM,
+	msgs []*gollm.Message,
+	args *CompletionArgs,
+	assembler *streamAssembler,
+) error {
+	return p.streamer(model, ctx, msgs, args, assembler)
+}
+
+func gollmInput(model *LLM, args *CompletionArgs, msgs []*gollm.Message) *gollm.CompletionInput {
+	return &gollm.CompletionInput{
+		Model:        model.Llm.Model,
+		Temperature:  model.Llm.Temperature,
+		Json:         args.Json,
+		JsonSchema:   args.JsonSchema,
+		Conversation: msgs,
+		Tools:        args.Tools,
+		RequiredTool: args.RequiredTool,
+	}
+}
@@ -0,0 +1,375 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+// retry policy of the llms without a stored policy
+const (
+	DEFAULT_MAX_RETRIES        = 2
+	DEFAULT_INITIAL_BACKOFF_MS = 500
+	DEFAULT_MAX_BACKOFF_MS     = 8000
+)
+
+// upper bounds of the retry policy a customer can configure
+const (
+	RETRY_MAX_RETRIES_LIMIT    = 5
+	RETRY_MAX_BACKOFF_LIMIT_MS = 60000
+	RETRY_MAX_FALLBACKS        = 3
+)
+
+/*
+How the completions of an llm are retried. Retryable errors (rate limits, timeouts, and server
+errors) are retried with an exponential backoff up to `MaxRetries` times. When the errors
+persist, or when the input does not fit in the model, the fallbacks are tried in order with the
+same policy.
+*/
+type RetryPolicy struct {
+	MaxRetries       int         `json:"maxRetries"`
+	InitialBackoffMs int         `json:"initialBackoffMs"`
+	MaxBackoffMs     int         `json:"maxBackoffMs"`
+	FallbackIDs      []uuid.UUID `json:"fallbackLlmIds"`
+
+	Fallbacks []*LLM `json:"-"` // loaded in the order of `FallbackIDs`
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries:       DEFAULT_MAX_RETRIES,
+		InitialBackoffMs: DEFAULT_INITIAL_BACKOFF_MS,
+		MaxBackoffMs:     DEFAULT_MAX_BACKOFF_MS,
+		FallbackIDs:      []uuid.UUID{},
+		Fallbacks:        []*LLM{},
+	}
+}
+
+func retryPolicyFromDB(item *queries.LlmRetryPolicy) *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries:       int(item.MaxRetries),
+		InitialBackoffMs: int(item.InitialBackoffMs),
+		MaxBackoffMs:     int(item.MaxBackoffMs),
+		FallbackIDs:      item.FallbackLlmIds,
+		Fallbacks:        []*LLM{},
+	}
+}
+
+func (policy *RetryPolicy) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string)
+	if policy.MaxRetries < 0 || policy.MaxRetries > RETRY_MAX_RETRIES_LIMIT {
+		p["maxRetries"] = fmt.Sprintf("must be between 0 and %d", RETRY_MAX_RETRIES_LIMIT)
+	}
+	if policy.InitialBackoffMs < 1 {
+		p["initialBackoffMs"] = "must be positive"
+	}
+	if policy.MaxBackoffMs < policy.InitialBackoffMs || policy.MaxBackoffMs > RETRY_MAX_BACKOFF_LIMIT_MS {
+		p["maxBackoffMs"] = fmt.Sprintf("must be between the initial backoff and %d", RETRY_MAX_BACKOFF_LIMIT_MS)
+	}
+	if len(policy.FallbackIDs) > RETRY_MAX_FALLBACKS {
+		p["fallbackLlmIds"] = fmt.Sprintf("cannot have more than %d fallbacks", RETRY_MAX_FALLBACKS)
+	}
+	seen := make(map[uuid.UUID]bool)
+	for i, id := range policy.FallbackIDs {
+		if seen[id] {
+			p[fmt.Sprintf("fallbackLlmIds[%d]", i)] = "is a duplicate"
+		}
+		seen[id] = true
+	}
+	return p
+}
+
+// the delay before the retry with the index, doubling from the initial backoff up to the max.
+// Half of the delay is random so concurrent requests do not retry at the same time
+func (policy *RetryPolicy) backoff(retry int) time.Duration {
+	delay := time.Duration(policy.InitialBackoffMs) * time.Millisecond
+	for i := 0; i < retry && delay < time.Duration(policy.MaxBackoffMs)*time.Millisecond; i++ {
+		delay *= 2
+	}
+	delay = min(delay, time.Duration(policy.MaxBackoffMs)*time.Millisecond)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// The policy of the model, or the default when none was loaded
+func (model *LLM) retryPolicy() *RetryPolicy {
+	if model.RetryPolicy == nil {
+		return DefaultRetryPolicy()
+	}
+	return model.RetryPolicy
+}
+
+// Loads the stored retry policy of the model along with its fallbacks. The fallbacks are
+// loaded without their own policies, so a chain never loops
+func (model *LLM) LoadRetryPolicy(ctx context.Context, db queries.DBTX) error {
+	policy, err := GetRetryPolicy(ctx, db, model.Llm.ID)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		model.RetryPolicy = nil
+		return nil
+	}
+
+	dmodel := queries.New(db)
+	for _, id := range policy.FallbackIDs {
+		item, err := dmodel.GetLLM(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get the fallback llm %s: %w", id, err)
+		}
+		policy.Fallbacks = append(policy.Fallbacks, FromObjects(&item.Llm, &item.AvailableModel))
+	}
+	model.RetryPolicy = policy
+	return nil
+}
+
+// Gets the stored retry policy of the llm, nil when it uses the default
+func GetRetryPolicy(ctx context.Context, db queries.DBTX, llmId uuid.UUID) (*RetryPolicy, error) {
+	dmodel := queries.New(db)
+	response, err := dmodel.GetLLMRetryPolicy(ctx, llmId)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get the retry policy: %w", err)
+	}
+	return retryPolicyFromDB(response), nil
+}
+
+/*
+Saves the retry policy of an llm of the customer. The fallbacks have to be llms the customer
+can use, and cannot include the llm itself.
+*/
+func SaveRetryPolicy(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	llmId uuid.UUID,
+	policy *RetryPolicy,
+) (*RetryPolicy, error) {
+	if problems := policy.Valid(ctx); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid retry policy: %v", problems)
+	}
+
+	dmodel := queries.New(db)
+	item, err := dmodel.GetLLM(ctx, llmId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the llm: %w", err)
+	}
+	if !ownedBy(item.Llm.CustomerID, customerId) {
+		return nil, fmt.Errorf("the llm does not belong to the customer")
+	}
+	for _, id := range policy.FallbackIDs {
+		if id == llmId {
+			return nil, fmt.Errorf("an llm cannot fall back to itself")
+		}
+		fallback, err := dmodel.GetLLM(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the fallback llm %s: %w", id, err)
+		}
+		if fallback.Llm.CustomerID.Valid && !ownedBy(fallback.Llm.CustomerID, customerId) {
+			return nil, fmt.Errorf("the fallback llm %s does not belong to the customer", id)
+		}
+	}
+
+	fallbackIds := policy.FallbackIDs
+	if fallbackIds == nil {
+		fallbackIds = []uuid.UUID{}
+	}
+	response, err := dmodel.UpsertLLMRetryPolicy(ctx, &queries.UpsertLLMRetryPolicyParams{
+		LlmID:            llmId,
+		MaxRetries:       int32(policy.MaxRetries),
+		InitialBackoffMs: int32(policy.InitialBackoffMs),
+		MaxBackoffMs:     int32(policy.MaxBackoffMs),
+		FallbackLlmIds:   fallbackIds,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save the retry policy: %w", err)
+	}
+	return retryPolicyFromDB(response), nil
+}
+
+// Removes the stored retry policy of the llm so it uses the default
+func DeleteRetryPolicy(ctx context.Context, db queries.DBTX, llmId uuid.UUID) error {
+	dmodel := queries.New(db)
+	if err := dmodel.DeleteLLMRetryPolicy(ctx, llmId); err != nil {
+		return fmt.Errorf("failed to delete the retry policy: %w", err)
+	}
+	return nil
+}
+
+func ownedBy(owner pgtype.UUID, customerId uuid.UUID) bool {
+	return owner.Valid && uuid.UUID(owner.Bytes) == customerId
+}
+
+// an error that is returned right away, without retrying or falling back
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// status codes of the errors that are worth retrying
+var retryableStatusCodes = map[int]bool{408: true, 429: true, 500: true, 502: true, 503: true, 504: true, 529: true}
+
+// Provider errors that carry the status of the http response
+type statusCoder interface {
+	StatusCode() int
+}
+
+// the status in the text of an error without a structured status, only when it is labeled as
+// one: `HTTP 429`, `status 503`, or the `the response was not 200: 429` of gollm. Other
+// numbers, like a token count, are not read as a status
+var errorStatusPattern = regexp.MustCompile(`(?:\bhttp/?[\d.]* |\bstatus(?: code)?:? |the response was not 200: )(\d{3})\b`)
+
+// messages of the errors that are worth retrying
+var retryableMessages = []string{
+	"rate limit",
+	"rate_limit",
+	"too many requests",
+	"overloaded",
+	"timeout",
+	"timed out",
+	"temporarily unavailable",
+	"connection reset",
+	"connection refused",
+	"unexpected eof",
+}
+
+// Whether the completion error is temporary, like a rate limit, a timeout, or a server error
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if code, ok := errorStatus(err); ok {
+		return retryableStatusCodes[code]
+	}
+	msg := strings.ToLower(err.Error())
+	for _, item := range retryableMessages {
+		if strings.Contains(msg, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// gets the http status of the error, from the provider error or from a labeled status in the text
+func errorStatus(err error) (int, bool) {
+	var coder statusCoder
+	if errors.As(err, &coder) && coder.StatusCode() != 0 {
+		return coder.StatusCode(), true
+	}
+	match := errorStatusPattern.FindStringSubmatch(strings.ToLower(err.Error()))
+	if match == nil {
+		return 0, false
+	}
+	code, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// whether the prepared messages are estimated to be over the input limit of the model
+func (model *LLM) exceedsInputLimit(msgs []*gollm.Message) bool {
+	if model.AvailableModel.InputTokenLimit <= 0 {
+		return false
+	}
+	input := new(strings.Builder)
+	for _, item := range msgs {
+		input.WriteString(item.Message)
+	}
+	estimate, err := model.GetEstimatedTokens(input.String())
+	if err != nil {
+		return false
+	}
+	return estimate > model.AvailableModel.InputTokenLimit
+}
+
+/*
+Runs the attempt against the model following its retry policy, then against each fallback until
+one succeeds. Models the input does not fit in are skipped. The model of the last attempt is
+returned with the result, which is the model that generated the response on success.
+*/
+func (model *LLM) runWithPolicy(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *CompletionArgs,
+	attempt func(current *LLM, msgs []*gollm.Message) error,
+) (*LLM, error) {
+	policy := model.retryPolicy()
+	chain := append([]*LLM{model}, policy.Fallbacks...)
+
+	var lastErr error
+	var last *LLM
+	for i, current := range chain {
+		l := logger.With("model", current.Llm.Model, "llmId", current.Llm.ID)
+		if i != 0 {
+			l.WarnContext(ctx, "Falling back to the next model ...", "fallback", i, "error", lastErr)
+		}
+
+		msgs, err := current.prepareMessages(args)
+		if err != nil {
+			return current, err
+		}
+		if current.exceedsInputLimit(msgs) {
+			l.WarnContext(ctx, "The input is over the token limit of the model, skipping it", "limit", current.AvailableModel.InputTokenLimit)
+			lastErr = fmt.Errorf("the input is over the token limit of %s", current.Llm.Model)
+			continue
+		}
+
+		last = current
+		for retry := 0; ; retry++ {
+			err := attempt(current, msgs)
+			if err == nil {
+				return current, nil
+			}
+			lastErr = err
+
+			var permanent *permanentError
+			if errors.As(err, &permanent) {
+				return current, permanent.err
+			}
+			if ctx.Err() != nil {
+				return current, err
+			}
+			if !IsRetryableError(err) || retry >= policy.MaxRetries {
+				break
+			}
+
+			delay := policy.backoff(retry)
+			l.WarnContext(ctx, "The completion failed, retrying ...", "retry", retry+1, "backoff", delay, "error", err)
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return current, fmt.Errorf("cancelled while waiting to retry: %w", ctx.Err())
+			case <-timer.C:
+			}
+		}
+	}
+
+	if last == nil {
+		last = model
+	}
+	return last, lastErr
+}
@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// a mock model with a fast retry policy that falls back to the passed models
+func retryTestModel(modelId string, maxRetries int, fallbacks ...*LLM) *LLM {
+	model := mockTestModel(modelId)
+	model.Llm.ID = uuid.New()
+	model.RetryPolicy = &RetryPolicy{
+		MaxRetries:       maxRetries,
+		InitialBackoffMs: 1,
+		MaxBackoffMs:     2,
+		Fallbacks:        fallbacks,
+	}
+	return model
+}
+
+func TestCompletionRetry(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := retryTestModel("mock-retry", 2)
+
+	Mock.Queue("mock-retry",
+		&MockResponse{Err: fmt.Errorf("the response was not 200: 429 - rate limited")},
+		&MockResponse{Err: fmt.Errorf("request timed out")},
+		&MockResponse{Message: "Done"},
+	)
+
+	response, used, err := model.CompletionWithModel(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Done", response.Message.Message)
+	require.Equal(t, model, used)
+	require.Len(t, Mock.Requests(), 3)
+}
+
+func TestCompletionFallback(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	small := mockTestModel("mock-small")
+	model := retryTestModel("mock-large", 1, small)
+
+	// the retries run out, then the fallback answers
+	Mock.Queue("mock-large",
+		&MockResponse{Err: fmt.Errorf("HTTP 503: service unavailable")},
+		&MockResponse{Err: fmt.Errorf("HTTP 503: service unavailable")},
+	)
+	Mock.Queue("mock-small", &MockResponse{Message: "From the fallback"})
+
+	response, used, err := model.CompletionWithModel(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "From the fallback", response.Message.Message)
+	require.Equal(t, small, used)
+	require.Equal(t, "mock-small", response.UsageRecord.Model)
+	require.Len(t, Mock.Requests(), 3)
+
+	// errors that are not retryable go to the fallback right away
+	Mock.Reset()
+	Mock.Queue("mock-large", &MockResponse{Err: fmt.Errorf("invalid request")})
+	Mock.Queue("mock-small", &MockResponse{Err: fmt.Errorf("invalid request")})
+	_, err = model.Completion(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	})
+	require.ErrorContains(t, err, "invalid request")
+	require.Len(t, Mock.Requests(), 2)
+}
+
+func TestCompletionFallbackInputLimit(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	large := mockTestModel("mock-long-context")
+	model := retryTestModel("mock-short-context", 2, large)
+	model.AvailableModel.InputTokenLimit = 5
+
+	Mock.Queue("mock-long-context", &MockResponse{Message: "Fits"})
+
+	args := &CompletionArgs{CustomerID: "test", Messages: streamTestMessages()}
+	args.Messages[1].Message = strings.Repeat("long input ", 20)
+	response, used, err := model.CompletionWithModel(context.TODO(), utils.DefaultLogger(), args)
+	require.NoError(t, err)
+	require.Equal(t, "Fits", response.Message.Message)
+	require.Equal(t, large, used)
+
+	// the model that is over the limit is never called
+	requests := Mock.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "mock-long-context", requests[0].Model)
+}
+
+func TestCompletionStreamFallback(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	small := mockTestModel("mock-stream-small")
+	model := retryTestModel("mock-stream-large", 0, small)
+
+	Mock.Queue("mock-stream-large", &MockResponse{Err: fmt.Errorf("overloaded")})
+	Mock.Queue("mock-stream-small", &MockResponse{Message: "Hello there"})
+
+	deltas := make([]string, 0)
+	response, err := model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		deltas = append(deltas, chunk.Delta)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Hello ", "there"}, deltas)
+	require.Equal(t, small, response.Model)
+
+	// a failing handler is not retried
+	Mock.Reset()
+	calls := 0
+	_, err = model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		calls++
+		return fmt.Errorf("connection reset")
+	})
+	require.ErrorContains(t, err, "connection reset")
+	require.Equal(t, 1, calls)
+	require.Len(t, Mock.Requests(), 1)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	require.True(t, IsRetryableError(fmt.Errorf("the response was not 200: 429 - slow down")))
+	require.True(t, IsRetryableError(fmt.Errorf("failed: %w", context.DeadlineExceeded)))
+	require.True(t, IsRetryableError(fmt.Errorf("Anthropic is Overloaded")))
+	require.True(t, IsRetryableError(fmt.Errorf("status 502")))
+	require.False(t, IsRetryableError(fmt.Errorf("the response was not 200: 400 - bad request")))
+	require.False(t, IsRetryableError(fmt.Errorf("invalid api key")))
+	require.False(t, IsRetryableError(nil))
+
+	// only labeled statuses are read from the text
+	require.True(t, IsRetryableError(fmt.Errorf("HTTP 503: service unavailable")))
+	require.True(t, IsRetryableError(fmt.Errorf("failed the completion: HTTP/1.1 429 Too Many Requests")))
+	require.False(t, IsRetryableError(fmt.Errorf("the prompt is 500 tokens over the limit of the model")))
+	require.False(t, IsRetryableError(fmt.Errorf("HTTP 400: the request has 429 messages")))
+
+	// the status of the provider error takes precedence over the text
+	require.True(t, IsRetryableError(fmt.Errorf("failed: %w", &testStatusError{code: 529})))
+	require.False(t, IsRetryableError(&testStatusError{code: 401, msg: "status 503"}))
+}
+
+type testStatusError struct {
+	code int
+	msg  string
+}
+
+func (e *testStatusError) Error() string   { return e.msg }
+func (e *testStatusError) StatusCode() int { return e.code }
+
+func TestRetryPolicy(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	require.Empty(t, policy.Valid(context.TODO()))
+
+	// the backoff doubles up to the max, with half of it random
+	for retry, expected := range []time.Duration{500, 1000, 2000, 4000, 8000, 8000} {
+		delay := policy.backoff(retry)
+		require.GreaterOrEqual(t, delay, expected*time.Millisecond/2)
+		require.LessOrEqual(t, delay, expected*time.Millisecond)
+	}
+
+	id := uuid.New()
+	policy = &RetryPolicy{
+		MaxRetries:       RETRY_MAX_RETRIES_LIMIT + 1,
+		InitialBackoffMs: 100,
+		MaxBackoffMs:     50,
+		FallbackIDs:      []uuid.UUID{id, id},
+	}
+	problems := policy.Valid(context.TODO())
+	require.Contains(t, problems, "maxRetries")
+	require.Contains(t, problems, "maxBackoffMs")
+	require.Contains(t, problems, "fallbackLlmIds[1]")
+}
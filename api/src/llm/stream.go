@@ -0,0 +1,329 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+)
+
+const (
+	PROVIDER_OPENAI    = "openai"
+	PROVIDER_ANTHROPIC = "anthropic"
+	PROVIDER_GOOGLE    = "google"
+	PROVIDER_MOCK      = "mock" // scripted responses for tests, see `MockProvider`
+)
+
+// The assembled response of a streamed completion. A model can call several tools in one
+// response, `ToolCalls` holds every call in order and `Message` is the first of them
+type StreamResponse struct {
+	*gollm.CompletionResponse
+	ToolCalls []*gollm.Message
+
+	// the configuration that generated the response, a fallback of the called model when the
+	// model kept failing
+	Model *LLM
+}
+
+// A piece of a streamed completion passed to the StreamHandler as it arrives
+type StreamChunk struct {
+	Delta         string // text generated since the last chunk
+	ToolCallStart string // name of the tool the model started calling
+}
+
+// Called for every chunk of a streamed completion. Returning an error cancels the stream
+type StreamHandler func(chunk *StreamChunk) error
+
+// Streams the completion, calling the handler with every chunk as it is generated.
+// The fully assembled response is returned once the stream ends, in the same shape as
+// `Completion` along with every tool call of the response. Providers without native
+// streaming support (and json mode) run a normal completion and send the whole message as
+// a single chunk.
+//
+// Failures are retried and sent to the fallbacks of the model following its retry policy,
+// until the first chunk reaches the handler.
+//
+// When the context is cancelled mid-stream, the partial response is returned along with
+// the error so the caller can persist it and record the tokens that were consumed.
+func (model *LLM) CompletionStream(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *CompletionArgs,
+	handler StreamHandler,
+) (*StreamResponse, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("the handler cannot be nil")
+	}
+
+	// chunks that reached the handler cannot be taken back, so the request is not retried
+	started := false
+	tracked := func(chunk *StreamChunk) error {
+		started = true
+		return handler(chunk)
+	}
+
+	var response *StreamResponse
+	used, err := model.runWithPolicy(ctx, logger, args, func(current *LLM, msgs []*gollm.Message) error {
+		var err error
+		response, err = current.completionStream(ctx, logger, args, msgs, tracked)
+		if err != nil && started {
+			return &permanentError{err: err}
+		}
+		return err
+	})
+	if response != nil {
+		response.Model = used
+	}
+	return response, err
+}
+
+// streams a single completion of the prepared messages against the provider of the model
+func (model *LLM) completionStream(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *CompletionArgs,
+	msgs []*gollm.Message,
+	handler StreamHandler,
+) (*StreamResponse, error) {
+	provider, ok := GetProvider(model.AvailableModel.Provider).(streamProvider)
+	if !ok || args.Json {
+		return model.completionAsStream(ctx, logger, args, msgs, handler)
+	}
+
+	l := logger.With("completionType", "stream", "provider", model.AvailableModel.Provider)
+	l.InfoContext(ctx, "Sending the streamed completion request ...")
+
+	assembler := newStreamAssembler(handler)
+	if err := provider.stream(ctx, l, model, msgs, args, assembler); err != nil {
+		// return what was generated before the caller cancelled the stream
+		if ctx.Err() != nil {
+			l.InfoContext(ctx, "The stream was cancelled")
+			partial, perr := assembler.response(model, msgs, true)
+			if perr != nil {
+				return nil, fmt.Errorf("failed to assemble the partial response: %w", perr)
+			}
+			return partial, fmt.Errorf("the streamed completion was cancelled: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("failed the streamed completion: %w", err)
+	}
+
+	l.InfoContext(ctx, "Successfully streamed the request")
+
+	return assembler.response(model, msgs, false)
+}
+
+// runs a normal completion and reports it to the handler as a single chunk. gollm returns a
+// single message, so providers without a streamer (google) return at most one tool call
+func (model *LLM) completionAsStream(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *CompletionArgs,
+	msgs []*gollm.Message,
+	handler StreamHandler,
+) (*StreamResponse, error) {
+	response, err := model.completion(ctx, logger, args, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := &StreamChunk{}
+	streamResponse := &StreamResponse{CompletionResponse: response}
+	if response.Message.Role == gollm.RoleToolCall {
+		chunk.ToolCallStart = response.Message.ToolName
+		streamResponse.ToolCalls = []*gollm.Message{response.Message}
+	} else {
+		chunk.Delta = response.Message.Message
+	}
+	if err := handler(chunk); err != nil {
+		return nil, fmt.Errorf("the stream handler failed: %w", err)
+	}
+
+	return streamResponse, nil
+}
+
+// collects the streamed chunks into the final message
+type streamAssembler struct {
+	handler StreamHandler
+
+	text         strings.Builder
+	toolCalls    []*streamToolCall
+	toolIndexes  map[int]*streamToolCall // tool calls by the index the provider streams them with
+	inputTokens  int
+	outputTokens int
+}
+
+type streamToolCall struct {
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+func newStreamAssembler(handler StreamHandler) *streamAssembler {
+	return &streamAssembler{
+		handler:     handler,
+		toolCalls:   make([]*streamToolCall, 0),
+		toolIndexes: make(map[int]*streamToolCall),
+	}
+}
+
+func (a *streamAssembler) delta(text string) error {
+	if text == "" {
+		return nil
+	}
+	a.text.WriteString(text)
+	return a.handler(&StreamChunk{Delta: text})
+}
+
+// starts the tool call streamed with the index
+func (a *streamAssembler) toolCall(index int, id string, name string) error {
+	call := &streamToolCall{id: id, name: name}
+	a.toolCalls = append(a.toolCalls, call)
+	a.toolIndexes[index] = call
+	return a.handler(&StreamChunk{ToolCallStart: name})
+}
+
+// adds a piece of the arguments of the tool call streamed with the index
+func (a *streamAssembler) toolArguments(index int, arguments string) {
+	if call, ok := a.toolIndexes[index]; ok {
+		call.arguments.WriteString(arguments)
+	}
+}
+
+// assembles the final message. A partial response drops any unfinished tool call and
+// estimates the token usage the provider did not get the chance to report
+func (a *streamAssembler) response(
+	model *LLM,
+	msgs []*gollm.Message,
+	partial bool,
+) (*StreamResponse, error) {
+	message := &gollm.Message{
+		Role:    gollm.RoleAI,
+		Message: a.text.String(),
+	}
+	toolCalls := make([]*gollm.Message, 0, len(a.toolCalls))
+	generated := a.text.String()
+	for _, call := range a.toolCalls {
+		generated += call.arguments.String()
+	}
+	if !partial {
+		for _, call := range a.toolCalls {
+			arguments := make(map[string]any)
+			if raw := strings.TrimSpace(call.arguments.String()); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+					return nil, fmt.Errorf("failed to parse the tool arguments of %s: %w", call.name, err)
+				}
+			}
+			toolCalls = append(toolCalls, &gollm.Message{
+				Role:          gollm.RoleToolCall,
+				ToolUseID:     call.id,
+				ToolName:      call.name,
+				ToolArguments: arguments,
+			})
+		}
+	}
+
+	// the text the model generated before calling the tools is kept on the first call
+	if len(toolCalls) != 0 {
+		toolCalls[0].Message = message.Message
+		message = toolCalls[0]
+	}
+
+	if a.inputTokens == 0 {
+		input := new(strings.Builder)
+		for _, item := range msgs {
+			input.WriteString(item.Message)
+		}
+		if estimate, err := model.GetEstimatedTokens(input.String()); err == nil {
+			a.inputTokens = int(estimate)
+		}
+	}
+	if a.outputTokens == 0 {
+		if estimate, err := model.GetEstimatedTokens(generated); err == nil {
+			a.outputTokens = int(estimate)
+		}
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the usage record id: %w", err)
+	}
+
+	return &StreamResponse{
+		CompletionResponse: &gollm.CompletionResponse{
+			Message: message,
+			UsageRecord: &tokens.UsageRecord{
+				ID:           id,
+				Model:        model.Llm.Model,
+				InputTokens:  a.inputTokens,
+				OutputTokens: a.outputTokens,
+				TotalTokens:  a.inputTokens + a.outputTokens,
+			},
+		},
+		ToolCalls: toolCalls,
+	}, nil
+}
+
+// sends the request and calls onData with the payload of every server sent event
+func postEventStream(
+	ctx context.Context,
+	url string,
+	headers map[string]string,
+	body any,
+	onData func(data []byte) (bool, error),
+) error {
+	enc, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode the request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(enc))
+	if err != nil {
+		return fmt.Errorf("failed to create the request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	for key, val := range headers {
+		req.Header.Set(key, val)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("the response was not 200: %d - %s", resp.StatusCode, string(msg))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		done, err := onData([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))))
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the stream: %w", err)
+	}
+
+	return nil
+}
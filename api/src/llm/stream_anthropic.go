@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+)
+
+var anthropicBaseUrl = "https://api.anthropic.com/v1"
+
+const anthropicVersion = "2023-06-01"
+
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJson string `json:"partial_json"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (model *LLM) streamAnthropic(
+	ctx context.Context,
+	msgs []*gollm.Message,
+	args *CompletionArgs,
+	assembler *streamAssembler,
+) error {
+	system, messages := anthropicMessages(msgs)
+	body := map[string]any{
+		"model":       model.Llm.Model,
+		"temperature": model.Llm.Temperature,
+		"max_tokens":  model.AvailableModel.OutputTokenLimit,
+		"system":      system,
+		"messages":    messages,
+		"stream":      true,
+	}
+	if len(args.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(args.Tools))
+		for _, item := range args.Tools {
+			tools = append(tools, map[string]any{
+				"name":         item.Title,
+				"description":  item.Description,
+				"input_schema": item.Schema,
+			})
+		}
+		body["tools"] = tools
+	}
+	if args.RequiredTool != nil {
+		body["tool_choice"] = map[string]any{"type": "tool", "name": args.RequiredTool.Title}
+	}
+
+	headers := map[string]string{
+		"x-api-key":         os.Getenv("ANTHROPIC_API_KEY"),
+		"anthropic-version": anthropicVersion,
+	}
+	return postEventStream(ctx, fmt.Sprintf("%s/messages", anthropicBaseUrl), headers, body, func(data []byte) (bool, error) {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return false, fmt.Errorf("failed to parse the stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			assembler.inputTokens = event.Message.Usage.InputTokens
+		case "content_block_start":
+			if event.ContentBlock.Type == "tool_use" {
+				if err := assembler.toolCall(event.Index, event.ContentBlock.ID, event.ContentBlock.Name); err != nil {
+					return false, err
+				}
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				if err := assembler.delta(event.Delta.Text); err != nil {
+					return false, err
+				}
+			case "input_json_delta":
+				assembler.toolArguments(event.Index, event.Delta.PartialJson)
+			}
+		case "message_delta":
+			assembler.outputTokens = event.Usage.OutputTokens
+		case "message_stop":
+			return true, nil
+		case "error":
+			return false, fmt.Errorf("the stream returned an error: %s", event.Error.Message)
+		}
+
+		return false, nil
+	})
+}
+
+// anthropic takes the system message separately and requires the roles to alternate,
+// so consecutive messages with the same role are merged into one
+func anthropicMessages(msgs []*gollm.Message) (string, []map[string]any) {
+	system := ""
+	messages := make([]map[string]any, 0, len(msgs))
+
+	add := func(role string, block map[string]any) {
+		if len(messages) > 0 && messages[len(messages)-1]["role"] == role {
+			last := messages[len(messages)-1]
+			last["content"] = append(last["content"].([]map[string]any), block)
+			return
+		}
+		messages = append(messages, map[string]any{
+			"role":    role,
+			"content": []map[string]any{block},
+		})
+	}
+
+	for _, item := range msgs {
+		switch item.Role {
+		case gollm.RoleSystem:
+			system = item.Message
+		case gollm.RoleUser:
+			add("user", map[string]any{"type": "text", "text": item.Message})
+		case gollm.RoleAI:
+			// a cancelled completion can be saved without any text, which anthropic rejects
+			if item.Message == "" {
+				continue
+			}
+			add("assistant", map[string]any{"type": "text", "text": item.Message})
+		case gollm.RoleToolCall:
+			if item.Message != "" {
+				add("assistant", map[string]any{"type": "text", "text": item.Message})
+			}
+			input := item.ToolArguments
+			if input == nil {
+				input = make(map[string]any)
+			}
+			add("assistant", map[string]any{
+				"type":  "tool_use",
+				"id":    item.ToolUseID,
+				"name":  item.ToolName,
+				"input": input,
+			})
+		case gollm.RoleToolResult:
+			add("user", map[string]any{
+				"type":        "tool_result",
+				"tool_use_id": item.ToolUseID,
+				"content":     item.Message,
+			})
+		}
+	}
+
+	return system, messages
+}
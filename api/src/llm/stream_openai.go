@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+)
+
+var openAIBaseUrl = "https://api.openai.com/v1"
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (model *LLM) streamOpenAI(
+	ctx context.Context,
+	msgs []*gollm.Message,
+	args *CompletionArgs,
+	assembler *streamAssembler,
+) error {
+	body := map[string]any{
+		"model":          model.Llm.Model,
+		"temperature":    model.Llm.Temperature,
+		"messages":       openAIMessages(msgs),
+		"stream":         true,
+		"stream_options": map[string]any{"include_usage": true},
+	}
+	if len(args.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(args.Tools))
+		for _, item := range args.Tools {
+			tools = append(tools, map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name":        item.Title,
+					"description": item.Description,
+					"parameters":  item.Schema,
+				},
+			})
+		}
+		body["tools"] = tools
+	}
+	if args.RequiredTool != nil {
+		body["tool_choice"] = map[string]any{
+			"type":     "function",
+			"function": map[string]any{"name": args.RequiredTool.Title},
+		}
+	}
+
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", os.Getenv("OPENAI_API_KEY"))}
+	return postEventStream(ctx, fmt.Sprintf("%s/chat/completions", openAIBaseUrl), headers, body, func(data []byte) (bool, error) {
+		if string(data) == "[DONE]" {
+			return true, nil
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return false, fmt.Errorf("failed to parse the stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return false, fmt.Errorf("the stream returned an error: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			assembler.inputTokens = chunk.Usage.PromptTokens
+			assembler.outputTokens = chunk.Usage.CompletionTokens
+		}
+
+		for _, choice := range chunk.Choices {
+			if err := assembler.delta(choice.Delta.Content); err != nil {
+				return false, err
+			}
+			for _, call := range choice.Delta.ToolCalls {
+				// the first chunk of a tool call carries the id and name
+				if call.ID != "" {
+					if err := assembler.toolCall(call.Index, call.ID, call.Function.Name); err != nil {
+						return false, err
+					}
+				}
+				assembler.toolArguments(call.Index, call.Function.Arguments)
+			}
+		}
+
+		return false, nil
+	})
+}
+
+func openAIMessages(msgs []*gollm.Message) []map[string]any {
+	response := make([]map[string]any, 0, len(msgs))
+	for _, item := range msgs {
+		switch item.Role {
+		case gollm.RoleSystem:
+			response = append(response, map[string]any{"role": "system", "content": item.Message})
+		case gollm.RoleUser:
+			response = append(response, map[string]any{"role": "user", "content": item.Message})
+		case gollm.RoleAI:
+			response = append(response, map[string]any{"role": "assistant", "content": item.Message})
+		case gollm.RoleToolCall:
+			arguments, _ := json.Marshal(item.ToolArguments)
+			call := map[string]any{
+				"id":   item.ToolUseID,
+				"type": "function",
+				"function": map[string]any{
+					"name":      item.ToolName,
+					"arguments": string(arguments),
+				},
+			}
+
+			// the calls of a single response are sent as one message
+			if len(response) > 0 {
+				if calls, ok := response[len(response)-1]["tool_calls"].([]map[string]any); ok {
+					response[len(response)-1]["tool_calls"] = append(calls, call)
+					continue
+				}
+			}
+			message := map[string]any{
+				"role":       "assistant",
+				"tool_calls": []map[string]any{call},
+			}
+			if item.Message != "" {
+				message["content"] = item.Message
+			}
+			response = append(response, message)
+		case gollm.RoleToolResult:
+			response = append(response, map[string]any{
+				"role":         "tool",
+				"tool_call_id": item.ToolUseID,
+				"content":      item.Message,
+			})
+		}
+	}
+	return response
+}
@@ -0,0 +1,344 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func streamTestServer(t *testing.T, path string, events []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, path, r.URL.Path)
+
+		// ensure the request asked for a stream
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, true, body["stream"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, item := range events {
+			fmt.Fprintf(w, "data: %s\n\n", item)
+		}
+	}))
+}
+
+func streamTestMessages() []*gollm.Message {
+	return []*gollm.Message{
+		{Role: gollm.RoleSystem, Message: "You are helpful"},
+		{Role: gollm.RoleUser, Message: "Hello"},
+	}
+}
+
+func TestStreamAssemblerParallelToolCalls(t *testing.T) {
+	started := make([]string, 0)
+	assembler := newStreamAssembler(func(chunk *StreamChunk) error {
+		if chunk.ToolCallStart != "" {
+			started = append(started, chunk.ToolCallStart)
+		}
+		return nil
+	})
+
+	require.NoError(t, assembler.delta("Let me look. "))
+	require.NoError(t, assembler.toolCall(0, "call_1", "vector_query"))
+	assembler.toolArguments(0, `{"vector_query":`)
+	require.NoError(t, assembler.toolCall(1, "call_2", "web_search"))
+	assembler.toolArguments(1, `{"query":"granite"}`)
+	assembler.toolArguments(0, `"rocks"}`)
+
+	response, err := assembler.response(mockTestModel("mock-assembler"), streamTestMessages(), false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"vector_query", "web_search"}, started)
+	require.Len(t, response.ToolCalls, 2)
+	require.Equal(t, response.ToolCalls[0], response.Message)
+	require.Equal(t, "Let me look. ", response.Message.Message)
+	require.Equal(t, "rocks", response.ToolCalls[0].ToolArguments["vector_query"])
+	require.Equal(t, "call_2", response.ToolCalls[1].ToolUseID)
+	require.Equal(t, "granite", response.ToolCalls[1].ToolArguments["query"])
+}
+
+func TestCompletionStreamHandlerError(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-handler-error")
+	Mock.Queue("mock-handler-error", &MockResponse{Message: "Hello there"})
+
+	_, err := model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		return fmt.Errorf("the connection was closed")
+	})
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "the connection was closed"))
+
+	// chunks reached the handler, so the request is not retried
+	require.Len(t, Mock.Requests(), 1)
+}
+
+func TestCompletionStreamCancelledToolCall(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-cancel-tool")
+	Mock.Queue("mock-cancel-tool", &MockResponse{
+		Message: "Hello",
+		ToolCalls: []*gollm.Message{
+			{Role: gollm.RoleToolCall, ToolUseID: "call_1", ToolName: "vector_query", ToolArguments: map[string]any{"vector_query": "rocks"}},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	response, err := model.CompletionStream(ctx, utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		// cancel once the tool call starts
+		if chunk.ToolCallStart != "" {
+			cancel()
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, response)
+
+	// the unfinished tool call is dropped
+	require.Equal(t, gollm.RoleAI, response.Message.Role)
+	require.Equal(t, "Hello", response.Message.Message)
+	require.NotNil(t, response.UsageRecord)
+}
+
+// the real providers stream every chunk to the handler, instead of sending the whole message
+// as a single chunk
+func TestCompletionStreamOpenAI(t *testing.T) {
+	srv := streamTestServer(t, "/chat/completions", []string{
+		`{"choices":[{"delta":{"content":"Hel"}}]}`,
+		`{"choices":[{"delta":{"content":"lo!"}}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2}}`,
+		`[DONE]`,
+	})
+	defer srv.Close()
+	openAIBaseUrl = srv.URL
+
+	model := &LLM{
+		Llm:            &queries.Llm{Model: "gpt-4o"},
+		AvailableModel: &queries.AvailableModel{Provider: PROVIDER_OPENAI},
+	}
+
+	deltas := make([]string, 0)
+	response, err := model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		deltas = append(deltas, chunk.Delta)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Hel", "lo!"}, deltas)
+	require.Equal(t, gollm.RoleAI, response.Message.Role)
+	require.Equal(t, "Hello!", response.Message.Message)
+	require.Equal(t, 12, response.UsageRecord.TotalTokens)
+}
+
+func TestCompletionStreamOpenAIToolCall(t *testing.T) {
+	srv := streamTestServer(t, "/chat/completions", []string{
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"vector_query","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"vector_query\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"rocks\"}"}}]}}]}`,
+		`[DONE]`,
+	})
+	defer srv.Close()
+	openAIBaseUrl = srv.URL
+
+	model := &LLM{
+		Llm:            &queries.Llm{Model: "gpt-4o"},
+		AvailableModel: &queries.AvailableModel{Provider: PROVIDER_OPENAI},
+	}
+
+	started := ""
+	response, err := model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		if chunk.ToolCallStart != "" {
+			started = chunk.ToolCallStart
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "vector_query", started)
+	require.Equal(t, gollm.RoleToolCall, response.Message.Role)
+	require.Equal(t, "call_1", response.Message.ToolUseID)
+	require.Equal(t, "rocks", response.Message.ToolArguments["vector_query"])
+}
+
+func TestCompletionStreamAnthropic(t *testing.T) {
+	srv := streamTestServer(t, "/messages", []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":20}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"look."}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"web_search"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"query\": \"gra"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"nite\"}"}}`,
+		`{"type":"message_delta","usage":{"output_tokens":15}}`,
+		`{"type":"message_stop"}`,
+	})
+	defer srv.Close()
+	anthropicBaseUrl = srv.URL
+
+	model := &LLM{
+		Llm:            &queries.Llm{Model: "claude-3-5-sonnet-20240620"},
+		AvailableModel: &queries.AvailableModel{Provider: PROVIDER_ANTHROPIC, OutputTokenLimit: 4096},
+	}
+
+	chunks := make([]string, 0)
+	response, err := model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		chunks = append(chunks, chunk.Delta+chunk.ToolCallStart)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Let me ", "look.", "web_search"}, chunks)
+	require.Equal(t, gollm.RoleToolCall, response.Message.Role)
+	require.Equal(t, "Let me look.", response.Message.Message)
+	require.Equal(t, "granite", response.Message.ToolArguments["query"])
+	require.Equal(t, 35, response.UsageRecord.TotalTokens)
+}
+
+func TestCompletionStreamOpenAIParallelToolCalls(t *testing.T) {
+	// the arguments of the two calls are streamed interleaved, each at its own index
+	srv := streamTestServer(t, "/chat/completions", []string{
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"list_folder","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","function":{"name":"vector_query","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"folder_id\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"function":{"arguments":"{\"vector_query\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"root\"}"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"function":{"arguments":"\"rocks\"}"}}]}}]}`,
+		`[DONE]`,
+	})
+	defer srv.Close()
+	openAIBaseUrl = srv.URL
+
+	model := &LLM{
+		Llm:            &queries.Llm{Model: "gpt-4o"},
+		AvailableModel: &queries.AvailableModel{Provider: PROVIDER_OPENAI},
+	}
+
+	response, err := model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, response.ToolCalls, 2)
+	require.Equal(t, "call_1", response.ToolCalls[0].ToolUseID)
+	require.Equal(t, "list_folder", response.ToolCalls[0].ToolName)
+	require.Equal(t, "root", response.ToolCalls[0].ToolArguments["folder_id"])
+	require.Equal(t, "call_2", response.ToolCalls[1].ToolUseID)
+	require.Equal(t, "vector_query", response.ToolCalls[1].ToolName)
+	require.Equal(t, "rocks", response.ToolCalls[1].ToolArguments["vector_query"])
+}
+
+func TestCompletionStreamAnthropicParallelToolCalls(t *testing.T) {
+	srv := streamTestServer(t, "/messages", []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":20}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"list_folder"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"folder_id\": \"root\"}"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"web_search"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"query\": \"gra"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"nite\"}"}}`,
+		`{"type":"message_delta","usage":{"output_tokens":15}}`,
+		`{"type":"message_stop"}`,
+	})
+	defer srv.Close()
+	anthropicBaseUrl = srv.URL
+
+	model := &LLM{
+		Llm:            &queries.Llm{Model: "claude-3-5-sonnet-20240620"},
+		AvailableModel: &queries.AvailableModel{Provider: PROVIDER_ANTHROPIC, OutputTokenLimit: 4096},
+	}
+
+	response, err := model.CompletionStream(context.TODO(), utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, response.ToolCalls, 2)
+	require.Equal(t, "toolu_1", response.ToolCalls[0].ToolUseID)
+	require.Equal(t, "list_folder", response.ToolCalls[0].ToolName)
+	require.Equal(t, "root", response.ToolCalls[0].ToolArguments["folder_id"])
+	require.Equal(t, "toolu_2", response.ToolCalls[1].ToolUseID)
+	require.Equal(t, "web_search", response.ToolCalls[1].ToolName)
+	require.Equal(t, "granite", response.ToolCalls[1].ToolArguments["query"])
+}
+
+func TestCompletionStreamCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"delta":{"content":"Hello"}}]}`)
+		w.(http.Flusher).Flush()
+
+		// hold the stream open until the client goes away
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	openAIBaseUrl = srv.URL
+
+	model := &LLM{
+		Llm:            &queries.Llm{Model: "gpt-4o"},
+		AvailableModel: &queries.AvailableModel{Provider: PROVIDER_OPENAI},
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	response, err := model.CompletionStream(ctx, utils.DefaultLogger(), &CompletionArgs{
+		CustomerID: "test",
+		Messages:   streamTestMessages(),
+	}, func(chunk *StreamChunk) error {
+		cancel()
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, response)
+	require.Equal(t, "Hello", response.Message.Message)
+	require.NotNil(t, response.UsageRecord)
+}
+
+func TestOpenAIMessagesParallelToolCalls(t *testing.T) {
+	msgs := openAIMessages([]*gollm.Message{
+		{Role: gollm.RoleUser, Message: "Hello"},
+		{Role: gollm.RoleToolCall, ToolUseID: "call_1", ToolName: "vector_query", Message: "Let me look."},
+		{Role: gollm.RoleToolCall, ToolUseID: "call_2", ToolName: "web_search"},
+		{Role: gollm.RoleToolResult, ToolUseID: "call_1", ToolName: "vector_query", Message: "a"},
+		{Role: gollm.RoleToolResult, ToolUseID: "call_2", ToolName: "web_search", Message: "b"},
+	})
+
+	// the calls of the response are sent as a single assistant message
+	require.Len(t, msgs, 4)
+	require.Equal(t, "Let me look.", msgs[1]["content"])
+	require.Len(t, msgs[1]["tool_calls"], 2)
+	require.Equal(t, "call_2", msgs[3]["tool_call_id"])
+}
+
+func TestAnthropicMessagesMerged(t *testing.T) {
+	system, messages := anthropicMessages([]*gollm.Message{
+		{Role: gollm.RoleSystem, Message: "system"},
+		{Role: gollm.RoleUser, Message: "hello"},
+		{Role: gollm.RoleToolCall, ToolUseID: "1", ToolName: "vector_query", ToolArguments: map[string]any{"vector_query": "a"}},
+		{Role: gollm.RoleToolResult, ToolUseID: "1", ToolName: "vector_query", Message: "result"},
+		{Role: gollm.RoleUser, Message: "thanks"},
+	})
+	require.Equal(t, "system", system)
+	require.Len(t, messages, 3)
+	require.Len(t, messages[2]["content"], 2)
+}
@@ -1,109 +1,289 @@
 package llm
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
 	"github.com/jake-landersweb/gollm/v2/src/tokens"
 	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
 	"github.com/sapphirenw/ai-content-creation-api/src/textsplitter"
 )
 
+// defaults of the summarize options
+const (
+	SUMMARIZE_DEFAULT_TARGET_TOKENS = 1000
+	SUMMARIZE_DEFAULT_CONCURRENCY   = 4
+)
+
+// Levels of merges after the chunks are summarized. Stops the reduction when the model does
+// not shorten the summaries enough to reach the target
+const SUMMARIZE_MAX_LEVELS = 4
+
+// share of the input limit of the model used for the text of a single request, leaving room
+// for the system prompt and the response
+const summarizeChunkShare = 0.75
+
+type SummaryStyle string
+
+const (
+	SUMMARY_STYLE_PARAGRAPH SummaryStyle = "paragraph"
+	SUMMARY_STYLE_BULLETS   SummaryStyle = "bullets"
+	SUMMARY_STYLE_EXECUTIVE SummaryStyle = "executive"
+	SUMMARY_STYLE_KEY_FACTS SummaryStyle = "keyFacts"
+)
+
+var summaryStyleInstructions = map[SummaryStyle]string{
+	SUMMARY_STYLE_PARAGRAPH: prompts.SUMMARY_STYLE_PARAGRAPH,
+	SUMMARY_STYLE_BULLETS:   prompts.SUMMARY_STYLE_BULLETS,
+	SUMMARY_STYLE_EXECUTIVE: prompts.SUMMARY_STYLE_EXECUTIVE,
+	SUMMARY_STYLE_KEY_FACTS: prompts.SUMMARY_STYLE_KEY_FACTS,
+}
+
+func (s SummaryStyle) Valid() bool {
+	_, ok := summaryStyleInstructions[s]
+	return ok
+}
+
+type SummarizeOptions struct {
+	Style        SummaryStyle // `SUMMARY_STYLE_PARAGRAPH` when empty
+	TargetTokens int          // the partial summaries are merged until the summary fits
+	ChunkTokens  int          // size of the chunks of the input, based on the input limit of the model when 0
+	Concurrency  int          // max completions running at the same time
+	Cache        *CacheOptions
+	DB           queries.DBTX // resolves the summary prompts of the customer, the built-in prompts are used when nil
+}
+
+// fills the defaults of the options the caller did not set
+func (opts *SummarizeOptions) withDefaults(model *LLM) (*SummarizeOptions, error) {
+	o := SummarizeOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Style == "" {
+		o.Style = SUMMARY_STYLE_PARAGRAPH
+	}
+	if !o.Style.Valid() {
+		return nil, fmt.Errorf("invalid summary style: %s", o.Style)
+	}
+	if o.TargetTokens <= 0 {
+		o.TargetTokens = SUMMARIZE_DEFAULT_TARGET_TOKENS
+	}
+	if o.ChunkTokens <= 0 {
+		o.ChunkTokens = int(float64(model.AvailableModel.InputTokenLimit) * summarizeChunkShare)
+	}
+	if o.ChunkTokens <= 0 {
+		return nil, fmt.Errorf("the model does not have an input token limit")
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = SUMMARIZE_DEFAULT_CONCURRENCY
+	}
+	return &o, nil
+}
+
 type SummarizeResponse struct {
 	Summary      string
 	UsageRecords []*tokens.UsageRecord
+	Levels       int // merges that ran after the chunks were summarized
 }
 
-// Summarizes the input text using the provided llm. This function will chunk and run
-// the summaries in go-routines to improve performance.
+/*
+Summarizes the input text using the provided llm with a map-reduce. Input that is larger than a
+chunk is split and the chunks are summarized in parallel. The partial summaries are then merged
+in order, in batches that fit a chunk, until a single summary under the target length remains.
+Nil options use the defaults.
+*/
 func (llm *LLM) Summarize(
 	ctx context.Context,
 	logger *slog.Logger,
 	customerId uuid.UUID,
 	input string,
+	opts *SummarizeOptions,
 ) (*SummarizeResponse, error) {
-	// chunk the string if needed
-	estimatedTokens, err := llm.GetEstimatedTokens(input)
+	o, err := opts.withDefaults(llm)
 	if err != nil {
 		return nil, err
 	}
+	s := &summarizer{
+		llm:        llm,
+		logger:     logger.With("style", o.Style),
+		customerId: customerId,
+		opts:       o,
+		records:    make([]*tokens.UsageRecord, 0),
+	}
+
+	// get the prompts the customer uses
+	vars := map[string]any{
+		"Style": summaryStyleInstructions[o.Style],
+		"Words": s.targetWords(),
+	}
+	system, _, err := prompts.Render(ctx, logger, o.DB, customerId, prompts.TEMPLATE_SUMMARY, vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the summary prompt: %w", err)
+	}
+	mergeSystem, _, err := prompts.Render(ctx, logger, o.DB, customerId, prompts.TEMPLATE_SUMMARY_MERGE, vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the merge prompt: %w", err)
+	}
 
-	chunks := make([]string, 0)
-	if estimatedTokens > llm.AvailableModel.InputTokenLimit {
-		splitter := textsplitter.NewRecursiveCharacter(
-			textsplitter.WithChunkSize(int(llm.AvailableModel.InputTokenLimit)),
-			textsplitter.WithChunkOverlap(100),
-		)
+	// map the chunks of the input to summaries
+	chunks, err := s.split(input)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Summarizing chunks ...", "length", len(chunks))
+	partials, err := s.run(ctx, chunks, system)
+	if err != nil {
+		return nil, err
+	}
 
-		logger.InfoContext(ctx, "Chunking input ...", "tokens", estimatedTokens)
-		chunks, err = splitter.SplitText(input)
+	// reduce the summaries until one fits the target
+	levels := 0
+	for ; len(partials) > 1 || s.tokens(partials[0]) > o.TargetTokens; levels++ {
+		if levels == SUMMARIZE_MAX_LEVELS {
+			s.logger.WarnContext(ctx, "The summary did not reach the target length", "tokens", s.tokens(strings.Join(partials, "\n\n")))
+			break
+		}
+		batches := s.batch(partials)
+		s.logger.InfoContext(ctx, "Merging summaries ...", "level", levels+1, "summaries", len(partials), "batches", len(batches))
+		partials, err = s.run(ctx, batches, mergeSystem)
 		if err != nil {
-			return nil, fmt.Errorf("failed to split the text")
+			return nil, err
 		}
-	} else {
-		chunks = append(chunks, input)
 	}
-	logger.InfoContext(ctx, "Processing chunks ...", "length", len(chunks))
 
-	// create an internal go-routine sync state for the chunks
+	return &SummarizeResponse{
+		Summary:      strings.Join(partials, "\n\n"),
+		UsageRecords: s.records,
+		Levels:       levels,
+	}, nil
+}
+
+// the state of a single summary
+type summarizer struct {
+	llm        *LLM
+	logger     *slog.Logger
+	customerId uuid.UUID
+	opts       *SummarizeOptions
+
+	mu      sync.Mutex
+	records []*tokens.UsageRecord
+}
+
+// estimated tokens of the text. Falls back to roughly 4 characters per token when the model
+// is unknown to the estimator
+func (s *summarizer) tokens(text string) int {
+	estimate, err := s.llm.GetEstimatedTokens(text)
+	if err != nil || (estimate == 0 && text != "") {
+		return estimateTokens(text)
+	}
+	return int(estimate)
+}
+
+func (s *summarizer) split(input string) ([]string, error) {
+	if s.tokens(input) <= s.opts.ChunkTokens {
+		return []string{input}, nil
+	}
+	splitter := textsplitter.NewRecursiveCharacter(
+		textsplitter.WithChunkSize(s.opts.ChunkTokens),
+		textsplitter.WithChunkOverlap(min(100, s.opts.ChunkTokens/10)),
+		textsplitter.WithLenFunc(s.tokens),
+	)
+	chunks, err := splitter.SplitText(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split the text: %w", err)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("there is no text to summarize")
+	}
+	return chunks, nil
+}
+
+// groups the consecutive summaries into the inputs of the next level, each fitting a chunk.
+// A group always has at least 2 summaries when there are more than 1 so every level shrinks
+func (s *summarizer) batch(partials []string) []string {
+	batches := make([]string, 0)
+	current := make([]string, 0)
+	size := 0
+	for _, item := range partials {
+		n := s.tokens(item)
+		if len(current) > 1 && size+n > s.opts.ChunkTokens {
+			batches = append(batches, strings.Join(current, "\n\n"))
+			current = make([]string, 0)
+			size = 0
+		}
+		current = append(current, item)
+		size += n
+	}
+	return append(batches, strings.Join(current, "\n\n"))
+}
+
+// summarizes the inputs with the system prompt with at most `Concurrency` completions at a
+// time. The summaries are returned in the order of the inputs
+func (s *summarizer) run(ctx context.Context, inputs []string, system string) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	summaries := make([]string, len(inputs))
+	sem := make(chan struct{}, s.opts.Concurrency)
 	var wg sync.WaitGroup
-	errCh := make(chan error, len(chunks))
-	responses := make(chan string, len(chunks))
-	usageRecordsChan := make(chan *tokens.UsageRecord, len(chunks))
+	var once sync.Once
+	var firstErr error
+
+	for i, item := range inputs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
 
-	// loop through and summarize all content as neeed and chunk at the end
-	for i, item := range chunks {
 		wg.Add(1)
-		go func(index int, chunk string) {
+		go func(index int, input string) {
 			defer wg.Done()
-			l := logger.With("index", index)
+			defer func() { <-sem }()
+			l := s.logger.With("index", index)
 			l.InfoContext(ctx, "Processing chunk ...")
 
-			response, err := llm.SingleCompletion(ctx, logger, customerId, prompts.SUMMARY_SYSTEM_PROMPT, chunk)
+			response, err := s.llm.singleCompletion(ctx, l, s.customerId, system, input, s.opts.Cache)
 			if err != nil {
-				errCh <- fmt.Errorf("failed to summarize content: %w", err)
+				once.Do(func() {
+					firstErr = fmt.Errorf("failed to summarize content: %w", err)
+					cancel()
+				})
 				return
 			}
-			responses <- response.Message.Message
-			usageRecordsChan <- response.UsageRecord
+			summaries[index] = strings.TrimSpace(response.Message.Message)
+			s.record(response.UsageRecord)
 			l.InfoContext(ctx, "Successfully processed chunk")
 		}(i, item)
 	}
 
-	// collect the routines
 	wg.Wait()
-	close(errCh)
-	close(responses)
-	close(usageRecordsChan)
-
-	// parse for errors
-	for err := range errCh {
-		if err != nil {
-			return nil, err
-		}
+	if firstErr != nil {
+		return nil, firstErr
 	}
-
-	// compose the summary buffer
-	summary := new(bytes.Buffer)
-	for item := range responses {
-		summary.WriteString(item + "\n")
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("the summary was cancelled: %w", err)
 	}
+	return summaries, nil
+}
 
-	// create a list of usage records
-	usageRecords := make([]*tokens.UsageRecord, 0)
-	for item := range usageRecordsChan {
-		if item != nil {
-			usageRecords = append(usageRecords, item)
-		}
-	}
+// the target length in words, roughly 3 words for every 4 tokens
+func (s *summarizer) targetWords() int {
+	return max(50, s.opts.TargetTokens*3/4)
+}
 
-	// return the entire buffer
-	return &SummarizeResponse{
-		Summary:      summary.String(),
-		UsageRecords: usageRecords,
-	}, err
+func (s *summarizer) record(item *tokens.UsageRecord) {
+	if item == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, item)
 }
@@ -0,0 +1,159 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// numbered paragraphs of roughly 25 mock tokens each
+func summarizeTestInput(count int) string {
+	paragraphs := make([]string, count)
+	for i := range paragraphs {
+		paragraphs[i] = fmt.Sprintf("Section %02d. %s", i, strings.Repeat("rocks ", 15))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// answers with the first sentence of the input and tracks the completions running at once
+type summaryTestProvider struct {
+	active *atomic.Int32
+	peak   *atomic.Int32
+	calls  atomic.Int32
+
+	mu     sync.Mutex
+	inputs []string
+	system []string
+}
+
+func (p *summaryTestProvider) Completion(
+	ctx context.Context,
+	logger *slog.Logger,
+	model *LLM,
+	args *CompletionArgs,
+	msgs []*gollm.Message,
+) (*gollm.CompletionResponse, error) {
+	p.calls.Add(1)
+	current := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if current <= peak || p.peak.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	input := msgs[len(msgs)-1].Message
+	p.mu.Lock()
+	p.inputs = append(p.inputs, input)
+	p.system = append(p.system, msgs[0].Message)
+	p.mu.Unlock()
+
+	first, _, _ := strings.Cut(input, ".")
+	return &gollm.CompletionResponse{
+		Message:     &gollm.Message{Role: gollm.RoleAI, Message: first + "."},
+		UsageRecord: &tokens.UsageRecord{ID: uuid.New(), Model: model.Llm.Model, TotalTokens: 1},
+	}, nil
+}
+
+func (p *summaryTestProvider) systems() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.system...)
+}
+
+func (p *summaryTestProvider) lastInput() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inputs[len(p.inputs)-1]
+}
+
+func TestSummarizeSingleChunk(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-summary")
+	model.AvailableModel.InputTokenLimit = 1000
+
+	Mock.Queue("mock-summary", &MockResponse{Message: " Rocks are hard. "})
+
+	response, err := model.Summarize(context.TODO(), utils.DefaultLogger(), uuid.New(), "Rocks are very hard.", nil)
+	require.NoError(t, err)
+	require.Equal(t, "Rocks are hard.", response.Summary)
+	require.Equal(t, 0, response.Levels)
+	require.Len(t, response.UsageRecords, 1)
+
+	requests := Mock.Requests()
+	require.Len(t, requests, 1)
+	require.Contains(t, requests[0].Messages[0].Message, prompts.SUMMARY_STYLE_PARAGRAPH)
+}
+
+func TestSummarizeMapReduce(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-map-reduce")
+	model.AvailableModel.InputTokenLimit = 1000
+
+	// every summary repeats the first line of its input, so the order is visible in the result
+	var active, peak atomic.Int32
+	provider := &summaryTestProvider{active: &active, peak: &peak}
+	providersMu.Lock()
+	providers["summary-test"] = provider
+	providersMu.Unlock()
+	defer func() {
+		providersMu.Lock()
+		delete(providers, "summary-test")
+		providersMu.Unlock()
+	}()
+	model.AvailableModel.Provider = "summary-test"
+
+	response, err := model.Summarize(context.TODO(), utils.DefaultLogger(), uuid.New(), summarizeTestInput(12), &SummarizeOptions{
+		Style:        SUMMARY_STYLE_BULLETS,
+		TargetTokens: 40,
+		ChunkTokens:  60,
+		Concurrency:  2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Section 00.", response.Summary)
+	require.Equal(t, 1, response.Levels)
+	require.LessOrEqual(t, peak.Load(), int32(2))
+	require.Len(t, response.UsageRecords, int(provider.calls.Load()))
+
+	// the merge gets the summaries of the chunks in the order of the input
+	merged := strings.Split(provider.lastInput(), "\n\n")
+	require.Greater(t, len(merged), 2)
+	require.Equal(t, "Section 00.", merged[0])
+	for i := 1; i < len(merged); i++ {
+		require.Less(t, merged[i-1], merged[i])
+	}
+	require.Contains(t, provider.systems()[len(provider.systems())-1], "merge partial summaries")
+	for _, item := range provider.systems() {
+		require.Contains(t, item, prompts.SUMMARY_STYLE_BULLETS)
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	Mock.Reset()
+	defer Mock.Reset()
+	model := mockTestModel("mock-summary-error")
+	model.AvailableModel.InputTokenLimit = 1000
+
+	_, err := model.Summarize(context.TODO(), utils.DefaultLogger(), uuid.New(), "Rocks", &SummarizeOptions{Style: "haiku"})
+	require.ErrorContains(t, err, "invalid summary style")
+
+	Mock.Queue("", &MockResponse{Err: fmt.Errorf("invalid request")})
+	_, err = model.Summarize(context.TODO(), utils.DefaultLogger(), uuid.New(), "Rocks", nil)
+	require.ErrorContains(t, err, "failed to summarize content")
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	db "github.com/sapphirenw/ai-content-creation-api/src/database"
+	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
 	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
 )
 
@@ -52,6 +53,13 @@ func run(
 		}
 	}
 
+	// save the built-in prompts as the default templates
+	if pool, err := db.GetPool(); err == nil {
+		if err := prompts.SeedDefaultTemplates(ctx, logger.Logger, pool); err != nil {
+			logger.Error("Failed to seed the default prompt templates", "error", err)
+		}
+	}
+
 	httpServer := &http.Server{
 		Addr:    net.JoinHostPort(getenv("SERVER_HOST"), getenv("SERVER_PORT")),
 		Handler: srv,
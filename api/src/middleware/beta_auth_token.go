@@ -1,9 +1,8 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
 	"net/http"
-	"strings"
 
 	db "github.com/sapphirenw/ai-content-creation-api/src/database"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
@@ -14,12 +13,19 @@ import (
 // Beta middleware to handle blanket auth to Beta testers. This should NOT
 // be used long term. In the future, if the 'x-api-key' header is included,
 // the request should be rejected.
+//
+// Websocket upgrades cannot send the header from a browser, so the GET that opens the
+// rag2 websocket is authenticated with a `WebsocketTicket` instead.
 func BetaAuthToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		if strings.Contains(r.URL.Path, "rag2") {
-			fmt.Println("Ignoring this request")
-			next.ServeHTTP(w, r)
+		if acceptsWebsocketTicket(r) {
+			ticket, err := authWebsocketTicket(r)
+			if err != nil {
+				slogger.ServerError(w, nil, 403, "Not Allowed.", err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), websocketTicketKey{}, ticket)))
 			return
 		}
 
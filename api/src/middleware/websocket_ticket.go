@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// How long a websocket ticket can be used to open a connection
+const WebsocketTicketTTL = 60 * time.Second
+
+// Prefix of the `Sec-WebSocket-Protocol` entry holding the ticket, for clients that would
+// rather not put the ticket in the url
+const WebsocketTicketProtocol = "ticket."
+
+// Subprotocol the server selects when the ticket is passed as a subprotocol. Browsers reject
+// the connection when none of the offered subprotocols are selected
+const WebsocketProtocol = "rag.v2"
+
+// The only route of the customer router that accepts a ticket instead of the api key
+const websocketTicketRoute = "/rag2"
+
+type websocketTicketKey struct{}
+
+/*
+A short lived ticket that authenticates a websocket connection. Browsers cannot set the
+`x-api-key` header on a websocket, so the ticket is created through an authenticated REST call
+and passed as the `ticket` query parameter or as a `ticket.<ticket>` subprotocol.
+
+The ticket is scoped to a customer and a conversation. An empty conversation id only allows
+starting a new conversation. A ticket opens a single connection, reconnecting needs a new ticket.
+*/
+type WebsocketTicket struct {
+	ID             string    `json:"jti"`
+	CustomerID     uuid.UUID `json:"customerId"`
+	ConversationID string    `json:"conversationId"`
+	ExpiresAt      int64     `json:"exp"`
+}
+
+var (
+	ticketSecret     []byte
+	ticketSecretOnce sync.Once
+)
+
+// The secret is read from WS_TICKET_SECRET. When it is not set a random secret is used, which
+// only works when the api runs as a single instance
+func getTicketSecret() []byte {
+	ticketSecretOnce.Do(func() {
+		if secret := os.Getenv("WS_TICKET_SECRET"); secret != "" {
+			ticketSecret = []byte(secret)
+			return
+		}
+		slog.Warn("WS_TICKET_SECRET is not set, using a random secret for the websocket tickets")
+		ticketSecret = make([]byte, 32)
+		if _, err := rand.Read(ticketSecret); err != nil {
+			panic(fmt.Sprintf("failed to create the websocket ticket secret: %s", err))
+		}
+	})
+	return ticketSecret
+}
+
+// Creates a signed ticket for the customer and conversation
+func NewWebsocketTicket(customerId uuid.UUID, conversationId string) (string, *WebsocketTicket, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to create the ticket id: %w", err)
+	}
+	ticket := &WebsocketTicket{
+		ID:             base64.RawURLEncoding.EncodeToString(id),
+		CustomerID:     customerId,
+		ConversationID: conversationId,
+		ExpiresAt:      time.Now().Add(WebsocketTicketTTL).Unix(),
+	}
+	enc, err := json.Marshal(ticket)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode the ticket: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(enc)
+	return payload + "." + signTicket(payload), ticket, nil
+}
+
+// Verifies the signature and expiry of the ticket
+func ParseWebsocketTicket(raw string) (*WebsocketTicket, error) {
+	payload, signature, ok := strings.Cut(raw, ".")
+	if !ok {
+		return nil, fmt.Errorf("the ticket is malformed")
+	}
+	if !hmac.Equal([]byte(signature), []byte(signTicket(payload))) {
+		return nil, fmt.Errorf("the ticket signature is invalid")
+	}
+
+	enc, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the ticket: %w", err)
+	}
+	var ticket WebsocketTicket
+	if err := json.Unmarshal(enc, &ticket); err != nil {
+		return nil, fmt.Errorf("failed to parse the ticket: %w", err)
+	}
+	if time.Now().Unix() > ticket.ExpiresAt {
+		return nil, fmt.Errorf("the ticket is expired")
+	}
+	if ticket.ID == "" {
+		return nil, fmt.Errorf("the ticket is missing an id")
+	}
+	return &ticket, nil
+}
+
+var (
+	usedTickets   = make(map[string]int64)
+	usedTicketsMu sync.Mutex
+)
+
+// Marks the ticket as used. Returns false when the ticket was already used. The used tickets are
+// kept in memory until they expire, which like the random secret only works for a single instance
+func useWebsocketTicket(ticket *WebsocketTicket) bool {
+	usedTicketsMu.Lock()
+	defer usedTicketsMu.Unlock()
+
+	now := time.Now().Unix()
+	for id, exp := range usedTickets {
+		if now > exp {
+			delete(usedTickets, id)
+		}
+	}
+	if _, used := usedTickets[ticket.ID]; used {
+		return false
+	}
+	usedTickets[ticket.ID] = ticket.ExpiresAt
+	return true
+}
+
+// Whether the request can be authenticated with a ticket instead of the api key. Only the GET
+// that opens the rag2 websocket accepts a ticket
+func acceptsWebsocketTicket(r *http.Request) bool {
+	if r.Method != http.MethodGet || !websocket.IsWebSocketUpgrade(r) {
+		return false
+	}
+	path := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		path = rctx.RoutePath
+	}
+	return strings.TrimRight(path, "/") == websocketTicketRoute
+}
+
+func signTicket(payload string) string {
+	mac := hmac.New(sha256.New, getTicketSecret())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// reads the ticket from the query or the subprotocols
+func websocketTicketFromRequest(r *http.Request) string {
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		return ticket
+	}
+	for _, item := range websocket.Subprotocols(r) {
+		if strings.HasPrefix(item, WebsocketTicketProtocol) {
+			return strings.TrimPrefix(item, WebsocketTicketProtocol)
+		}
+	}
+	return ""
+}
+
+// Authenticates a websocket upgrade with a ticket. The ticket must be for the customer in the
+// url and for the conversation passed as the `id` query parameter, and can only be used once
+func authWebsocketTicket(r *http.Request) (*WebsocketTicket, error) {
+	ticket, err := ParseWebsocketTicket(websocketTicketFromRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	if ticket.CustomerID.String() != chi.URLParam(r, "customerId") {
+		return nil, fmt.Errorf("the ticket is for another customer")
+	}
+	if ticket.ConversationID != r.URL.Query().Get("id") {
+		return nil, fmt.Errorf("the ticket is for another conversation")
+	}
+	if !useWebsocketTicket(ticket) {
+		return nil, fmt.Errorf("the ticket was already used")
+	}
+	return ticket, nil
+}
+
+// Gets the ticket the websocket connection was authenticated with
+func GetWebsocketTicket(ctx context.Context) *WebsocketTicket {
+	ticket, _ := ctx.Value(websocketTicketKey{}).(*WebsocketTicket)
+	return ticket
+}
+
+var (
+	allowedOrigins     map[string]bool
+	allowedOriginsOnce sync.Once
+)
+
+/*
+Checks the origin of a websocket upgrade against the comma separated WS_ALLOWED_ORIGINS
+(ex: `https://app.example.com,http://localhost:3000`). A `*` entry allows any origin. When the
+allowlist is empty, only requests without an origin or from the same host are allowed.
+*/
+func CheckWebsocketOrigin(r *http.Request) bool {
+	allowedOriginsOnce.Do(func() {
+		allowedOrigins = make(map[string]bool)
+		for _, item := range strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",") {
+			if item = strings.TrimRight(strings.TrimSpace(item), "/"); item != "" {
+				allowedOrigins[strings.ToLower(item)] = true
+			}
+		}
+	})
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if allowedOrigins["*"] || allowedOrigins[strings.ToLower(origin)] {
+		return true
+	}
+	if len(allowedOrigins) == 0 {
+		return strings.EqualFold(strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://"), r.Host)
+	}
+	return false
+}
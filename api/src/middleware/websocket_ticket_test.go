@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebsocketTicket(t *testing.T) {
+	customerId := uuid.New()
+	raw, _, err := NewWebsocketTicket(customerId, "conv")
+	require.NoError(t, err)
+
+	ticket, err := ParseWebsocketTicket(raw)
+	require.NoError(t, err)
+	require.Equal(t, customerId, ticket.CustomerID)
+	require.Equal(t, "conv", ticket.ConversationID)
+
+	// a payload from another ticket fails the signature check
+	other, _, err := NewWebsocketTicket(uuid.New(), "conv")
+	require.NoError(t, err)
+	payload, _, _ := strings.Cut(other, ".")
+	_, signature, _ := strings.Cut(raw, ".")
+	_, err = ParseWebsocketTicket(payload + "." + signature)
+	require.Error(t, err)
+	_, err = ParseWebsocketTicket("invalid")
+	require.Error(t, err)
+}
+
+func TestWebsocketTicketSingleUse(t *testing.T) {
+	customerId := uuid.New()
+	raw, _, err := NewWebsocketTicket(customerId, "")
+	require.NoError(t, err)
+
+	authed := 0
+	mux := chi.NewRouter()
+	mux.Route("/v1/customers/{customerId}", func(r chi.Router) {
+		r.Use(BetaAuthToken)
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			authed++
+		}
+		r.Get("/rag2", handler)
+		r.Get("/conversations", handler)
+		r.Post("/rag2", handler)
+	})
+	open := func(method string, path string) int {
+		r := httptest.NewRequest(method, path+"?ticket="+raw, nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+	base := "/v1/customers/" + customerId.String()
+
+	// the ticket is only accepted on the rag2 websocket, and only once
+	require.Equal(t, 200, open("GET", base+"/rag2"))
+	require.Equal(t, 1, authed)
+	require.Equal(t, 403, open("GET", base+"/rag2"))
+	require.Equal(t, 1, authed)
+
+	raw, _, err = NewWebsocketTicket(customerId, "")
+	require.NoError(t, err)
+	require.NotEqual(t, 200, open("GET", base+"/conversations"))
+	require.NotEqual(t, 200, open("POST", base+"/rag2"))
+	require.Equal(t, 1, authed)
+}
+
+func TestWebsocketTicketFromSubprotocol(t *testing.T) {
+	r := httptest.NewRequest("GET", "/v1/customers/id/rag2", nil)
+	r.Header.Set("Sec-WebSocket-Protocol", WebsocketProtocol+", "+WebsocketTicketProtocol+"abc.def")
+	require.Equal(t, "abc.def", websocketTicketFromRequest(r))
+
+	r = httptest.NewRequest("GET", "/v1/customers/id/rag2?ticket=ghi.jkl", nil)
+	require.Equal(t, "ghi.jkl", websocketTicketFromRequest(r))
+}
+
+func TestCheckWebsocketOrigin(t *testing.T) {
+	t.Setenv("WS_ALLOWED_ORIGINS", "https://app.example.com, http://localhost:3000/")
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "http://localhost:3000")
+	require.True(t, CheckWebsocketOrigin(r))
+
+	r.Header.Set("Origin", "https://evil.example.com")
+	require.False(t, CheckWebsocketOrigin(r))
+}
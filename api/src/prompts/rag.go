@@ -34,6 +34,12 @@ You will be passed the following information on each request:
 - (Optional) Internet Context: A list of summaries from public website pages that were found to be relevant to the user's query
 
 If little to no context is provided to you, you MUST attempt to answer the question as best as you can while also mentioning that there is not much context for you to use to compose your answer.
+
+The tool responses list their sources with a number, such as [1] or [2].
+When you use information from a source, you MUST reference it inline with its number right after the sentence that uses it, such as "The launch is planned for March [2]."
+Multiple sources are referenced as [1][3]. Only use the numbers of sources that were passed to you, and never make up a number.
+Do not add a list of sources at the end of your response, the user is shown the sources separately.
+
 You are to respond to the user's request in a natural and informative manner, as well as following the personality instructions.
 `
 
@@ -50,3 +56,7 @@ CASES:
 - If the message is empty, then return 'Empty Chat'.
 - In all other cases, you will return the title.
 `
+
+// sent to the user in place of an answer when the model keeps calling tools past the max
+// depth of the turn
+const RAG_TOOL_DEPTH_MESSAGE = `I was not able to finish looking into this within the limit of searches for a single message. Try asking a more specific question, or ask me to continue where I left off.`
@@ -0,0 +1,335 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+// A version of a prompt. The defaults have no customer, and the built-in prompts that are not
+// in the database have no id
+type Template struct {
+	*queries.PromptTemplate
+}
+
+// the prompt in code, used when the database has no version of it
+func builtinTemplate(d *DefaultTemplate) *Template {
+	return &Template{PromptTemplate: &queries.PromptTemplate{
+		Name:        string(d.Name),
+		Content:     d.Content,
+		Description: d.Description,
+		IsActive:    true,
+	}}
+}
+
+func (t *Template) IsDefault() bool {
+	return !t.CustomerID.Valid
+}
+
+// Renders the template with the variables of the prompt
+func (t *Template) Render(vars map[string]any) (string, error) {
+	return renderTemplate(TemplateName(t.Name), t.Content, vars)
+}
+
+func customerUUID(customerId uuid.UUID) pgtype.UUID {
+	return pgtype.UUID{Bytes: customerId, Valid: customerId != uuid.Nil}
+}
+
+/*
+Gets the version of the prompt the customer uses: their active version, or the active default.
+The built-in prompt is returned when there is no database, or the database has no version of
+the prompt, so a prompt is always available. Only an unknown name is an error.
+*/
+func GetTemplate(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	name TemplateName,
+) (*Template, error) {
+	d, err := GetDefaultTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	if db == nil {
+		return builtinTemplate(d), nil
+	}
+
+	dmodel := queries.New(db)
+	item, err := dmodel.GetActivePromptTemplate(ctx, &queries.GetActivePromptTemplateParams{
+		CustomerID: customerUUID(customerId),
+		Name:       string(name),
+	})
+	if err != nil {
+		if !strings.Contains(err.Error(), "no rows in result set") {
+			logger.WarnContext(ctx, "Failed to get the prompt template, using the built-in prompt", "name", name, "error", err)
+		}
+		return builtinTemplate(d), nil
+	}
+	return &Template{PromptTemplate: item}, nil
+}
+
+// Gets and renders the prompt the customer uses. A version that fails to render falls back on
+// the built-in prompt. The template is returned so its use can be recorded
+func Render(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	name TemplateName,
+	vars map[string]any,
+) (string, *Template, error) {
+	t, err := GetTemplate(ctx, logger, db, customerId, name)
+	if err != nil {
+		return "", nil, err
+	}
+	content, err := t.Render(vars)
+	if err == nil {
+		return content, t, nil
+	}
+	if t.ID == uuid.Nil {
+		return "", nil, err
+	}
+
+	logger.WarnContext(ctx, "Failed to render the prompt template, using the built-in prompt", "name", name, "version", t.Version, "error", err)
+	d, _ := GetDefaultTemplate(name)
+	t = builtinTemplate(d)
+	content, err = t.Render(vars)
+	if err != nil {
+		return "", nil, err
+	}
+	return content, t, nil
+}
+
+// Gets the active version of every prompt for the customer, in the order of the defaults
+func GetActiveTemplates(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+) ([]*Template, error) {
+	dmodel := queries.New(db)
+	items, err := dmodel.GetActivePromptTemplates(ctx, customerUUID(customerId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the prompt templates: %w", err)
+	}
+	byName := make(map[string]*queries.PromptTemplate, len(items))
+	for _, item := range items {
+		byName[item.Name] = item
+	}
+
+	response := make([]*Template, len(defaultTemplates))
+	for i, d := range defaultTemplates {
+		if item, ok := byName[string(d.Name)]; ok {
+			response[i] = &Template{PromptTemplate: item}
+		} else {
+			response[i] = builtinTemplate(d)
+		}
+	}
+	return response, nil
+}
+
+// Gets the versions of the customer followed by the versions of the defaults, newest first
+func GetTemplateVersions(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	name TemplateName,
+) ([]*Template, error) {
+	if _, err := GetDefaultTemplate(name); err != nil {
+		return nil, err
+	}
+	dmodel := queries.New(db)
+	items, err := dmodel.GetPromptTemplateVersions(ctx, &queries.GetPromptTemplateVersionsParams{
+		CustomerID: customerUUID(customerId),
+		Name:       string(name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the prompt template versions: %w", err)
+	}
+	response := make([]*Template, len(items))
+	for i, item := range items {
+		response[i] = &Template{PromptTemplate: item}
+	}
+	return response, nil
+}
+
+// Saves a new version of the prompt for the customer and makes it the active version
+func SaveCustomerTemplate(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	customerId uuid.UUID,
+	name TemplateName,
+	content string,
+	description string,
+) (*Template, error) {
+	d, err := GetDefaultTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Validate(content); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start a transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	response, err := createVersion(ctx, tx, customerUUID(customerId), name, content, description)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit the transaction: %w", err)
+	}
+	return &Template{PromptTemplate: response}, nil
+}
+
+// Makes a saved version of the customer the active version of the prompt
+func ActivateCustomerTemplate(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	customerId uuid.UUID,
+	name TemplateName,
+	version int,
+) (*Template, error) {
+	if _, err := GetDefaultTemplate(name); err != nil {
+		return nil, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start a transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	dmodel := queries.New(tx)
+	if err := dmodel.DeactivatePromptTemplates(ctx, &queries.DeactivatePromptTemplatesParams{
+		CustomerID: customerUUID(customerId),
+		Name:       string(name),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to deactivate the prompt template: %w", err)
+	}
+	response, err := dmodel.ActivatePromptTemplate(ctx, &queries.ActivatePromptTemplateParams{
+		CustomerID: customerUUID(customerId),
+		Name:       string(name),
+		Version:    int32(version),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return nil, fmt.Errorf("there is no version %d of the template", version)
+		}
+		return nil, fmt.Errorf("failed to activate the prompt template: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit the transaction: %w", err)
+	}
+	return &Template{PromptTemplate: response}, nil
+}
+
+// Deactivates the versions of the customer so the default is used again. The versions are kept
+func ResetCustomerTemplate(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	name TemplateName,
+) error {
+	if _, err := GetDefaultTemplate(name); err != nil {
+		return err
+	}
+	dmodel := queries.New(db)
+	if err := dmodel.DeactivatePromptTemplates(ctx, &queries.DeactivatePromptTemplatesParams{
+		CustomerID: customerUUID(customerId),
+		Name:       string(name),
+	}); err != nil {
+		return fmt.Errorf("failed to deactivate the prompt template: %w", err)
+	}
+	return nil
+}
+
+/*
+Saves the built-in prompts as the default templates. A new default version is created for every
+prompt whose content changed since it was last seeded, so the defaults keep the history of the
+prompts across deploys.
+*/
+func SeedDefaultTemplates(
+	ctx context.Context,
+	logger *slog.Logger,
+	pool *pgxpool.Pool,
+) error {
+	for _, d := range defaultTemplates {
+		dmodel := queries.New(pool)
+		latest, err := dmodel.GetLatestPromptTemplate(ctx, &queries.GetLatestPromptTemplateParams{
+			Name: string(d.Name),
+		})
+		if err != nil && !strings.Contains(err.Error(), "no rows in result set") {
+			return fmt.Errorf("failed to get the default template %s: %w", d.Name, err)
+		}
+		if err == nil && latest.Content == d.Content {
+			continue
+		}
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start a transaction: %w", err)
+		}
+		response, err := createVersion(ctx, tx, pgtype.UUID{}, d.Name, d.Content, d.Description)
+		if err != nil {
+			tx.Rollback(ctx)
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit the transaction: %w", err)
+		}
+		logger.InfoContext(ctx, "Seeded the default template", "name", d.Name, "version", response.Version)
+	}
+	return nil
+}
+
+// creates the next version of the prompt as the active version
+func createVersion(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId pgtype.UUID,
+	name TemplateName,
+	content string,
+	description string,
+) (*queries.PromptTemplate, error) {
+	dmodel := queries.New(db)
+	version := int32(1)
+	latest, err := dmodel.GetLatestPromptTemplate(ctx, &queries.GetLatestPromptTemplateParams{
+		CustomerID: customerId,
+		Name:       string(name),
+	})
+	if err == nil {
+		version = latest.Version + 1
+	} else if !strings.Contains(err.Error(), "no rows in result set") {
+		return nil, fmt.Errorf("failed to get the latest version: %w", err)
+	}
+
+	if err := dmodel.DeactivatePromptTemplates(ctx, &queries.DeactivatePromptTemplatesParams{
+		CustomerID: customerId,
+		Name:       string(name),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to deactivate the prompt template: %w", err)
+	}
+	response, err := dmodel.CreatePromptTemplate(ctx, &queries.CreatePromptTemplateParams{
+		CustomerID:  customerId,
+		Name:        string(name),
+		Version:     version,
+		Content:     content,
+		Description: description,
+		IsActive:    true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the prompt template: %w", err)
+	}
+	return response, nil
+}
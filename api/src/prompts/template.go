@@ -0,0 +1,106 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// The name of a prompt that can be stored as a template. The versions of a prompt share the name
+type TemplateName string
+
+const (
+	TEMPLATE_RAG_COMPLETE         TemplateName = "rag_complete"
+	TEMPLATE_RAG_SIMPLE_QUERY     TemplateName = "rag_simple_query"
+	TEMPLATE_RAG_RANKER           TemplateName = "rag_ranker"
+	TEMPLATE_RAG_TITLE            TemplateName = "rag_title"
+	TEMPLATE_SUMMARY              TemplateName = "summary"
+	TEMPLATE_SUMMARY_MERGE        TemplateName = "summary_merge"
+	TEMPLATE_CONVERSATION_SUMMARY TemplateName = "conversation_summary"
+	TEMPLATE_PROJECT_IDEA         TemplateName = "project_idea"
+	TEMPLATE_LINKEDIN_POST        TemplateName = "linkedin_post"
+)
+
+// A variable passed to a template when it is rendered
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Example     any    `json:"example"` // used to check the templates before they are saved
+}
+
+// The built-in version of a prompt, which is seeded as the default template
+type DefaultTemplate struct {
+	Name        TemplateName        `json:"name"`
+	Description string              `json:"description"`
+	Content     string              `json:"content"`
+	Variables   []*TemplateVariable `json:"variables"`
+}
+
+var summaryVariables = []*TemplateVariable{
+	{Name: "Style", Description: "The instructions of the style of the summary", Example: SUMMARY_STYLE_PARAGRAPH},
+	{Name: "Words", Description: "The target length of the summary in words", Example: 750},
+}
+
+var defaultTemplates = []*DefaultTemplate{
+	{Name: TEMPLATE_RAG_COMPLETE, Description: "The system message of the information chat", Content: RAG_COMPLETE_SYSTEM_PROMPT},
+	{Name: TEMPLATE_RAG_SIMPLE_QUERY, Description: "Turns the query of the model into short vector store queries", Content: RAG_SIMPLE_QUERY_SYSTEM_PROMPT},
+	{Name: TEMPLATE_RAG_RANKER, Description: "Scores the relevance and quality of a chunk against a query", Content: RAG_RANKER_SYSTEM_PROMPT},
+	{Name: TEMPLATE_RAG_TITLE, Description: "Creates the title of a conversation from the first message", Content: RAG_TITLE_GENERATION_SYSTEM_PROMPT},
+	{Name: TEMPLATE_SUMMARY, Description: "Summarizes a chunk of text", Content: SUMMARY_SYSTEM_PROMPT, Variables: summaryVariables},
+	{Name: TEMPLATE_SUMMARY_MERGE, Description: "Merges the summaries of consecutive chunks", Content: SUMMARY_MERGE_SYSTEM_PROMPT, Variables: summaryVariables},
+	{Name: TEMPLATE_CONVERSATION_SUMMARY, Description: "Maintains the running summary of a long conversation", Content: CONVERSATION_SUMMARY_SYSTEM_PROMPT},
+	{Name: TEMPLATE_PROJECT_IDEA, Description: "The system message of the idea generation of a project", Content: PROJECT_IDEA_SYSTEM},
+	{Name: TEMPLATE_LINKEDIN_POST, Description: "The system message of the LinkedIn post generation", Content: LINKEDIN_POST_SYSTEM},
+}
+
+// The built-in versions of the prompts, in a stable order
+func DefaultTemplates() []*DefaultTemplate {
+	return defaultTemplates
+}
+
+// Gets the built-in version of a prompt
+func GetDefaultTemplate(name TemplateName) (*DefaultTemplate, error) {
+	for _, item := range defaultTemplates {
+		if item.Name == name {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid template name: %s", name)
+}
+
+// the example values of the variables
+func (t *DefaultTemplate) examples() map[string]any {
+	vars := make(map[string]any, len(t.Variables))
+	for _, item := range t.Variables {
+		vars[item.Name] = item.Example
+	}
+	return vars
+}
+
+// Checks that the content parses and only uses the variables of the prompt
+func (t *DefaultTemplate) Validate(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("the content cannot be empty")
+	}
+	if _, err := renderTemplate(t.Name, content, t.examples()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Renders the content with the variables with `text/template`. Using a variable that was not
+// passed is an error
+func renderTemplate(name TemplateName, content string, vars map[string]any) (string, error) {
+	tmpl, err := template.New(string(name)).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the template: %w", err)
+	}
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	buf := new(strings.Builder)
+	if err := tmpl.Execute(buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render the template: %w", err)
+	}
+	return buf.String(), nil
+}
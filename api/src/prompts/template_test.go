@@ -0,0 +1,127 @@
+package prompts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/testingutils"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTemplates(t *testing.T) {
+	names := make(map[TemplateName]bool)
+	for _, item := range DefaultTemplates() {
+		require.False(t, names[item.Name], "duplicate template %s", item.Name)
+		names[item.Name] = true
+		require.NoError(t, item.Validate(item.Content), item.Name)
+	}
+
+	_, err := GetDefaultTemplate("haiku")
+	require.ErrorContains(t, err, "invalid template name")
+}
+
+func TestTemplateValidate(t *testing.T) {
+	d, err := GetDefaultTemplate(TEMPLATE_SUMMARY)
+	require.NoError(t, err)
+
+	require.NoError(t, d.Validate("Summarize in {{.Words}} words. {{.Style}}"))
+	require.ErrorContains(t, d.Validate("  "), "cannot be empty")
+	require.ErrorContains(t, d.Validate("Summarize in {{.Words words"), "failed to parse")
+	require.ErrorContains(t, d.Validate("Summarize for {{.Audience}}"), "failed to render")
+
+	// prompts without variables cannot use any
+	d, err = GetDefaultTemplate(TEMPLATE_RAG_TITLE)
+	require.NoError(t, err)
+	require.ErrorContains(t, d.Validate("Create a title in {{.Words}} words"), "failed to render")
+}
+
+func TestRenderBuiltin(t *testing.T) {
+	ctx := context.TODO()
+	logger := utils.DefaultLogger()
+
+	// without a database the built-in prompt is used
+	content, tmpl, err := Render(ctx, logger, nil, uuid.New(), TEMPLATE_SUMMARY, map[string]any{
+		"Style": SUMMARY_STYLE_BULLETS,
+		"Words": 120,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uuid.Nil, tmpl.ID)
+	require.True(t, tmpl.IsDefault())
+	require.Contains(t, content, SUMMARY_STYLE_BULLETS)
+	require.Contains(t, content, "under 120 words")
+	require.NotContains(t, content, "{{")
+
+	content, _, err = Render(ctx, logger, nil, uuid.New(), TEMPLATE_RAG_COMPLETE, nil)
+	require.NoError(t, err)
+	require.Equal(t, RAG_COMPLETE_SYSTEM_PROMPT, content)
+
+	_, _, err = Render(ctx, logger, nil, uuid.New(), "haiku", nil)
+	require.Error(t, err)
+}
+
+func TestTemplateStore(t *testing.T) {
+	ctx := context.Background()
+	pool := testingutils.GetDatabase(t, ctx)
+	c := testingutils.GetTestCustomer(t, ctx, pool)
+	logger := utils.DefaultLogger()
+
+	// the built-in prompts are seeded once
+	require.NoError(t, SeedDefaultTemplates(ctx, logger, pool))
+	require.NoError(t, SeedDefaultTemplates(ctx, logger, pool))
+	tmpl, err := GetTemplate(ctx, logger, pool, c.ID, TEMPLATE_RAG_TITLE)
+	require.NoError(t, err)
+	require.True(t, tmpl.IsDefault())
+	require.Equal(t, int32(1), tmpl.Version)
+	require.Equal(t, RAG_TITLE_GENERATION_SYSTEM_PROMPT, tmpl.Content)
+
+	// invalid templates are not saved
+	_, err = SaveCustomerTemplate(ctx, pool, c.ID, TEMPLATE_RAG_TITLE, "Title {{.Words}}", "")
+	require.ErrorContains(t, err, "invalid template")
+
+	// the versions of the customer override the default
+	first, err := SaveCustomerTemplate(ctx, pool, c.ID, TEMPLATE_RAG_TITLE, "Create a short title", "shorter")
+	require.NoError(t, err)
+	require.Equal(t, int32(1), first.Version)
+	second, err := SaveCustomerTemplate(ctx, pool, c.ID, TEMPLATE_RAG_TITLE, "Create a title in French", "")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), second.Version)
+
+	content, tmpl, err := Render(ctx, logger, pool, c.ID, TEMPLATE_RAG_TITLE, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Create a title in French", content)
+	require.Equal(t, second.ID, tmpl.ID)
+
+	// other customers keep the default
+	_, tmpl, err = Render(ctx, logger, pool, uuid.New(), TEMPLATE_RAG_TITLE, nil)
+	require.NoError(t, err)
+	require.True(t, tmpl.IsDefault())
+
+	// a previous version can be activated again
+	_, err = ActivateCustomerTemplate(ctx, pool, c.ID, TEMPLATE_RAG_TITLE, 1)
+	require.NoError(t, err)
+	content, _, err = Render(ctx, logger, pool, c.ID, TEMPLATE_RAG_TITLE, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Create a short title", content)
+	_, err = ActivateCustomerTemplate(ctx, pool, c.ID, TEMPLATE_RAG_TITLE, 5)
+	require.ErrorContains(t, err, "there is no version 5")
+
+	versions, err := GetTemplateVersions(ctx, pool, c.ID, TEMPLATE_RAG_TITLE)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	require.False(t, versions[0].IsDefault())
+	require.True(t, versions[2].IsDefault())
+
+	// resetting goes back to the default and keeps the versions
+	require.NoError(t, ResetCustomerTemplate(ctx, pool, c.ID, TEMPLATE_RAG_TITLE))
+	active, err := GetActiveTemplates(ctx, pool, c.ID)
+	require.NoError(t, err)
+	require.Len(t, active, len(DefaultTemplates()))
+	for _, item := range active {
+		require.True(t, item.IsDefault(), item.Name)
+	}
+	versions, err = GetTemplateVersions(ctx, pool, c.ID, TEMPLATE_RAG_TITLE)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+}
@@ -239,6 +239,19 @@ type BlogPostTag struct {
 	UpdatedAt  pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
 }
 
+type CompletionCache struct {
+	CustomerID   uuid.UUID          `db:"customer_id" json:"customerId"`
+	Key          string             `db:"key" json:"key"`
+	Model        string             `db:"model" json:"model"`
+	Message      string             `db:"message" json:"message"`
+	InputTokens  int32              `db:"input_tokens" json:"inputTokens"`
+	OutputTokens int32              `db:"output_tokens" json:"outputTokens"`
+	Hits         int32              `db:"hits" json:"hits"`
+	LastHitAt    pgtype.Timestamptz `db:"last_hit_at" json:"lastHitAt"`
+	ExpiresAt    pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+}
+
 type ContentType struct {
 	Title     string             `db:"title" json:"title"`
 	Parent    string             `db:"parent" json:"parent"`
@@ -258,24 +271,58 @@ type Conversation struct {
 	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
 	UpdatedAt        pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
 	CurrLlmID        pgtype.UUID        `db:"curr_llm_id" json:"currLlmId"`
+	Summary          string             `db:"summary" json:"summary"`
+	SummaryIndex     int32              `db:"summary_index" json:"summaryIndex"`
+	ActiveMessageID  pgtype.UUID        `db:"active_message_id" json:"activeMessageId"`
 }
 
 type ConversationMessage struct {
-	ID             uuid.UUID          `db:"id" json:"id"`
-	ConversationID uuid.UUID          `db:"conversation_id" json:"conversationId"`
-	LlmID          pgtype.UUID        `db:"llm_id" json:"llmId"`
-	Model          string             `db:"model" json:"model"`
-	Temperature    float64            `db:"temperature" json:"temperature"`
-	Instructions   string             `db:"instructions" json:"instructions"`
-	Role           string             `db:"role" json:"role"`
-	Message        string             `db:"message" json:"message"`
-	Index          int32              `db:"index" json:"index"`
-	ToolUseID      string             `db:"tool_use_id" json:"toolUseId"`
-	ToolName       string             `db:"tool_name" json:"toolName"`
-	ToolArguments  []byte             `db:"tool_arguments" json:"toolArguments"`
-	ToolResults    []byte             `db:"tool_results" json:"toolResults"`
-	CreatedAt      pgtype.Timestamptz `db:"created_at" json:"createdAt"`
-	UpdatedAt      pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+	ID                uuid.UUID          `db:"id" json:"id"`
+	ConversationID    uuid.UUID          `db:"conversation_id" json:"conversationId"`
+	LlmID             pgtype.UUID        `db:"llm_id" json:"llmId"`
+	Model             string             `db:"model" json:"model"`
+	Temperature       float64            `db:"temperature" json:"temperature"`
+	Instructions      string             `db:"instructions" json:"instructions"`
+	Role              string             `db:"role" json:"role"`
+	Message           string             `db:"message" json:"message"`
+	Index             int32              `db:"index" json:"index"`
+	ToolUseID         string             `db:"tool_use_id" json:"toolUseId"`
+	ToolName          string             `db:"tool_name" json:"toolName"`
+	ToolArguments     []byte             `db:"tool_arguments" json:"toolArguments"`
+	ToolResults       []byte             `db:"tool_results" json:"toolResults"`
+	CreatedAt         pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt         pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+	IsCancelled       bool               `db:"is_cancelled" json:"isCancelled"`
+	Citations         []byte             `db:"citations" json:"citations"`
+	ParentID          pgtype.UUID        `db:"parent_id" json:"parentId"`
+	EmbeddingFailedAt pgtype.Timestamptz `db:"embedding_failed_at" json:"embeddingFailedAt"`
+}
+
+type ConversationMessageEmbedding struct {
+	ConversationMessageID uuid.UUID          `db:"conversation_message_id" json:"conversationMessageId"`
+	CustomerID            uuid.UUID          `db:"customer_id" json:"customerId"`
+	Embeddings            *pgvector.Vector   `db:"embeddings" json:"embeddings"`
+	CreatedAt             pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+}
+
+type ConversationMessageFeedback struct {
+	ID                    uuid.UUID          `db:"id" json:"id"`
+	ConversationMessageID uuid.UUID          `db:"conversation_message_id" json:"conversationMessageId"`
+	ConversationID        uuid.UUID          `db:"conversation_id" json:"conversationId"`
+	CustomerID            uuid.UUID          `db:"customer_id" json:"customerId"`
+	LlmID                 pgtype.UUID        `db:"llm_id" json:"llmId"`
+	Rating                int16              `db:"rating" json:"rating"`
+	Comment               string             `db:"comment" json:"comment"`
+	Tools                 []string           `db:"tools" json:"tools"`
+	Sources               []byte             `db:"sources" json:"sources"`
+	CreatedAt             pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt             pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
+type ConversationPromptTemplate struct {
+	ConversationID   uuid.UUID          `db:"conversation_id" json:"conversationId"`
+	PromptTemplateID uuid.UUID          `db:"prompt_template_id" json:"promptTemplateId"`
+	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
 }
 
 type Customer struct {
@@ -295,29 +342,90 @@ type CustomerLlmConfiguration struct {
 	UpdatedAt    pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
 }
 
+type CustomerToolConfiguration struct {
+	ID               uuid.UUID          `db:"id" json:"id"`
+	CustomerID       uuid.UUID          `db:"customer_id" json:"customerId"`
+	ConversationType string             `db:"conversation_type" json:"conversationType"`
+	EnabledTools     []string           `db:"enabled_tools" json:"enabledTools"`
+	MaxDepth         int32              `db:"max_depth" json:"maxDepth"`
+	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt        pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
 type Document struct {
+	ID              uuid.UUID          `db:"id" json:"id"`
+	ParentID        pgtype.UUID        `db:"parent_id" json:"parentId"`
+	CustomerID      uuid.UUID          `db:"customer_id" json:"customerId"`
+	Filename        string             `db:"filename" json:"filename"`
+	Type            string             `db:"type" json:"type"`
+	SizeBytes       int64              `db:"size_bytes" json:"sizeBytes"`
+	Sha256          string             `db:"sha_256" json:"sha256"`
+	Validated       bool               `db:"validated" json:"validated"`
+	DatastoreType   string             `db:"datastore_type" json:"datastoreType"`
+	DatastoreID     string             `db:"datastore_id" json:"datastoreId"`
+	Summary         string             `db:"summary" json:"summary"`
+	SummarySha256   string             `db:"summary_sha_256" json:"summarySha256"`
+	VectorSha256    string             `db:"vector_sha_256" json:"vectorSha256"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+	IsAsset         bool               `db:"is_asset" json:"isAsset"`
+	Vectorize       bool               `db:"vectorize" json:"vectorize"`
+	SummaryFailedAt pgtype.Timestamptz `db:"summary_failed_at" json:"summaryFailedAt"`
+}
+
+type DocumentSummaryVector struct {
+	DocumentID uuid.UUID          `db:"document_id" json:"documentId"`
+	CustomerID uuid.UUID          `db:"customer_id" json:"customerId"`
+	Sha256     string             `db:"sha_256" json:"sha256"`
+	Embeddings *pgvector.Vector   `db:"embeddings" json:"embeddings"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt  pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
+type DocumentVector struct {
 	ID            uuid.UUID          `db:"id" json:"id"`
-	ParentID      pgtype.UUID        `db:"parent_id" json:"parentId"`
+	DocumentID    uuid.UUID          `db:"document_id" json:"documentId"`
+	VectorStoreID uuid.UUID          `db:"vector_store_id" json:"vectorStoreId"`
 	CustomerID    uuid.UUID          `db:"customer_id" json:"customerId"`
-	Filename      string             `db:"filename" json:"filename"`
-	Type          string             `db:"type" json:"type"`
-	SizeBytes     int64              `db:"size_bytes" json:"sizeBytes"`
-	Sha256        string             `db:"sha_256" json:"sha256"`
-	Validated     bool               `db:"validated" json:"validated"`
-	DatastoreType string             `db:"datastore_type" json:"datastoreType"`
-	DatastoreID   string             `db:"datastore_id" json:"datastoreId"`
-	Summary       string             `db:"summary" json:"summary"`
-	SummarySha256 string             `db:"summary_sha_256" json:"summarySha256"`
-	VectorSha256  string             `db:"vector_sha_256" json:"vectorSha256"`
+	Index         int32              `db:"index" json:"index"`
+	Metadata      []byte             `db:"metadata" json:"metadata"`
 	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"createdAt"`
-	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
-	IsAsset       bool               `db:"is_asset" json:"isAsset"`
-	Vectorize     bool               `db:"vectorize" json:"vectorize"`
 }
 
-type DocumentVector struct {
+type Feed struct {
+	ID                  uuid.UUID          `db:"id" json:"id"`
+	CustomerID          uuid.UUID          `db:"customer_id" json:"customerId"`
+	Url                 string             `db:"url" json:"url"`
+	Title               string             `db:"title" json:"title"`
+	FeedType            string             `db:"feed_type" json:"feedType"`
+	FetchArticle        bool               `db:"fetch_article" json:"fetchArticle"`
+	PollIntervalMinutes int32              `db:"poll_interval_minutes" json:"pollIntervalMinutes"`
+	LastPolledAt        pgtype.Timestamptz `db:"last_polled_at" json:"lastPolledAt"`
+	LastError           *string            `db:"last_error" json:"lastError"`
+	CreatedAt           pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt           pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
+type FeedItem struct {
+	ID           uuid.UUID          `db:"id" json:"id"`
+	CustomerID   uuid.UUID          `db:"customer_id" json:"customerId"`
+	FeedID       uuid.UUID          `db:"feed_id" json:"feedId"`
+	Guid         string             `db:"guid" json:"guid"`
+	Title        string             `db:"title" json:"title"`
+	Link         string             `db:"link" json:"link"`
+	Author       string             `db:"author" json:"author"`
+	PublishedAt  pgtype.Timestamptz `db:"published_at" json:"publishedAt"`
+	Content      string             `db:"content" json:"content"`
+	Sha256       string             `db:"sha_256" json:"sha256"`
+	Metadata     []byte             `db:"metadata" json:"metadata"`
+	VectorSha256 string             `db:"vector_sha_256" json:"vectorSha256"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt    pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
+type FeedItemVector struct {
 	ID            uuid.UUID          `db:"id" json:"id"`
-	DocumentID    uuid.UUID          `db:"document_id" json:"documentId"`
+	FeedItemID    uuid.UUID          `db:"feed_item_id" json:"feedItemId"`
 	VectorStoreID uuid.UUID          `db:"vector_store_id" json:"vectorStoreId"`
 	CustomerID    uuid.UUID          `db:"customer_id" json:"customerId"`
 	Index         int32              `db:"index" json:"index"`
@@ -384,6 +492,16 @@ type Llm struct {
 	UpdatedAt    pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
 }
 
+type LlmRetryPolicy struct {
+	LlmID            uuid.UUID          `db:"llm_id" json:"llmId"`
+	MaxRetries       int32              `db:"max_retries" json:"maxRetries"`
+	InitialBackoffMs int32              `db:"initial_backoff_ms" json:"initialBackoffMs"`
+	MaxBackoffMs     int32              `db:"max_backoff_ms" json:"maxBackoffMs"`
+	FallbackLlmIds   []uuid.UUID        `db:"fallback_llm_ids" json:"fallbackLlmIds"`
+	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt        pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
 type Project struct {
 	ID                    uuid.UUID          `db:"id" json:"id"`
 	CustomerID            uuid.UUID          `db:"customer_id" json:"customerId"`
@@ -433,6 +551,18 @@ type ProjectWebsite struct {
 	UpdatedAt  pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
 }
 
+type PromptTemplate struct {
+	ID          uuid.UUID          `db:"id" json:"id"`
+	CustomerID  pgtype.UUID        `db:"customer_id" json:"customerId"`
+	Name        string             `db:"name" json:"name"`
+	Version     int32              `db:"version" json:"version"`
+	Content     string             `db:"content" json:"content"`
+	Description string             `db:"description" json:"description"`
+	IsActive    bool               `db:"is_active" json:"isActive"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt   pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
 type Resume struct {
 	ID         uuid.UUID          `db:"id" json:"id"`
 	CustomerID uuid.UUID          `db:"customer_id" json:"customerId"`
@@ -638,16 +768,27 @@ type Website struct {
 }
 
 type WebsitePage struct {
-	ID            uuid.UUID          `db:"id" json:"id"`
+	ID              uuid.UUID          `db:"id" json:"id"`
+	CustomerID      uuid.UUID          `db:"customer_id" json:"customerId"`
+	WebsiteID       uuid.UUID          `db:"website_id" json:"websiteId"`
+	Url             string             `db:"url" json:"url"`
+	Sha256          string             `db:"sha_256" json:"sha256"`
+	IsValid         bool               `db:"is_valid" json:"isValid"`
+	Metadata        []byte             `db:"metadata" json:"metadata"`
+	Summary         string             `db:"summary" json:"summary"`
+	SummarySha256   string             `db:"summary_sha_256" json:"summarySha256"`
+	VectorSha256    string             `db:"vector_sha_256" json:"vectorSha256"`
+	CreatedAt       pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt       pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+	ContentType     string             `db:"content_type" json:"contentType"`
+	SummaryFailedAt pgtype.Timestamptz `db:"summary_failed_at" json:"summaryFailedAt"`
+}
+
+type WebsitePageSummaryVector struct {
+	WebsitePageID uuid.UUID          `db:"website_page_id" json:"websitePageId"`
 	CustomerID    uuid.UUID          `db:"customer_id" json:"customerId"`
-	WebsiteID     uuid.UUID          `db:"website_id" json:"websiteId"`
-	Url           string             `db:"url" json:"url"`
 	Sha256        string             `db:"sha_256" json:"sha256"`
-	IsValid       bool               `db:"is_valid" json:"isValid"`
-	Metadata      []byte             `db:"metadata" json:"metadata"`
-	Summary       string             `db:"summary" json:"summary"`
-	SummarySha256 string             `db:"summary_sha_256" json:"summarySha256"`
-	VectorSha256  string             `db:"vector_sha_256" json:"vectorSha256"`
+	Embeddings    *pgvector.Vector   `db:"embeddings" json:"embeddings"`
 	CreatedAt     pgtype.Timestamptz `db:"created_at" json:"createdAt"`
 	UpdatedAt     pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
 }
@@ -13,6 +13,48 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+const activatePromptTemplate = `-- name: ActivatePromptTemplate :one
+UPDATE prompt_template SET
+    is_active = true,
+    updated_at = CURRENT_TIMESTAMP
+WHERE customer_id = $1
+AND name = $2
+AND version = $3
+RETURNING id, customer_id, name, version, content, description, is_active, created_at, updated_at
+`
+
+type ActivatePromptTemplateParams struct {
+	CustomerID pgtype.UUID `db:"customer_id" json:"customerId"`
+	Name       string      `db:"name" json:"name"`
+	Version    int32       `db:"version" json:"version"`
+}
+
+// ActivatePromptTemplate
+//
+//	UPDATE prompt_template SET
+//	    is_active = true,
+//	    updated_at = CURRENT_TIMESTAMP
+//	WHERE customer_id = $1
+//	AND name = $2
+//	AND version = $3
+//	RETURNING id, customer_id, name, version, content, description, is_active, created_at, updated_at
+func (q *Queries) ActivatePromptTemplate(ctx context.Context, arg *ActivatePromptTemplateParams) (*PromptTemplate, error) {
+	row := q.db.QueryRow(ctx, activatePromptTemplate, arg.CustomerID, arg.Name, arg.Version)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.Name,
+		&i.Version,
+		&i.Content,
+		&i.Description,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
 const clearConversation = `-- name: ClearConversation :exec
 DELETE FROM conversation_message
 WHERE conversation_id = $1
@@ -61,7 +103,7 @@ const createConversation = `-- name: CreateConversation :one
 INSERT INTO conversation (
     customer_id, title, conversation_type, system_message, metadata
 ) VALUES ( $1, $2, $3, $4, $5 )
-RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id
+RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id
 `
 
 type CreateConversationParams struct {
@@ -77,7 +119,7 @@ type CreateConversationParams struct {
 //	INSERT INTO conversation (
 //	    customer_id, title, conversation_type, system_message, metadata
 //	) VALUES ( $1, $2, $3, $4, $5 )
-//	RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id
+//	RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id
 func (q *Queries) CreateConversation(ctx context.Context, arg *CreateConversationParams) (*Conversation, error) {
 	row := q.db.QueryRow(ctx, createConversation,
 		arg.CustomerID,
@@ -99,6 +141,9 @@ func (q *Queries) CreateConversation(ctx context.Context, arg *CreateConversatio
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.CurrLlmID,
+		&i.Summary,
+		&i.SummaryIndex,
+		&i.ActiveMessageID,
 	)
 	return &i, err
 }
@@ -116,12 +161,11 @@ INSERT INTO conversation_message (
     tool_use_id,
     tool_name,
     tool_arguments,
-    tool_results
-) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12 )
-ON CONFLICT (conversation_id, index)
-DO UPDATE SET
-    updated_at = CURRENT_TIMESTAMP
-RETURNING id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at
+    tool_results,
+    is_cancelled,
+    parent_id
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14 )
+RETURNING id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at, is_cancelled, citations, parent_id, embedding_failed_at
 `
 
 type CreateConversationMessageParams struct {
@@ -137,6 +181,8 @@ type CreateConversationMessageParams struct {
 	ToolName       string      `db:"tool_name" json:"toolName"`
 	ToolArguments  []byte      `db:"tool_arguments" json:"toolArguments"`
 	ToolResults    []byte      `db:"tool_results" json:"toolResults"`
+	IsCancelled    bool        `db:"is_cancelled" json:"isCancelled"`
+	ParentID       pgtype.UUID `db:"parent_id" json:"parentId"`
 }
 
 // CreateConversationMessage
@@ -153,12 +199,11 @@ type CreateConversationMessageParams struct {
 //	    tool_use_id,
 //	    tool_name,
 //	    tool_arguments,
-//	    tool_results
-//	) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12 )
-//	ON CONFLICT (conversation_id, index)
-//	DO UPDATE SET
-//	    updated_at = CURRENT_TIMESTAMP
-//	RETURNING id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at
+//	    tool_results,
+//	    is_cancelled,
+//	    parent_id
+//	) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14 )
+//	RETURNING id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at, is_cancelled, citations, parent_id, embedding_failed_at
 func (q *Queries) CreateConversationMessage(ctx context.Context, arg *CreateConversationMessageParams) (*ConversationMessage, error) {
 	row := q.db.QueryRow(ctx, createConversationMessage,
 		arg.ConversationID,
@@ -173,6 +218,8 @@ func (q *Queries) CreateConversationMessage(ctx context.Context, arg *CreateConv
 		arg.ToolName,
 		arg.ToolArguments,
 		arg.ToolResults,
+		arg.IsCancelled,
+		arg.ParentID,
 	)
 	var i ConversationMessage
 	err := row.Scan(
@@ -191,10 +238,69 @@ func (q *Queries) CreateConversationMessage(ctx context.Context, arg *CreateConv
 		&i.ToolResults,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsCancelled,
+		&i.Citations,
+		&i.ParentID,
+		&i.EmbeddingFailedAt,
 	)
 	return &i, err
 }
 
+const createConversationMessageEmbedding = `-- name: CreateConversationMessageEmbedding :exec
+INSERT INTO conversation_message_embedding (
+    conversation_message_id, customer_id, embeddings
+) VALUES ( $1, $2, $3 )
+ON CONFLICT (conversation_message_id)
+DO UPDATE SET
+    embeddings = EXCLUDED.embeddings
+`
+
+type CreateConversationMessageEmbeddingParams struct {
+	ConversationMessageID uuid.UUID        `db:"conversation_message_id" json:"conversationMessageId"`
+	CustomerID            uuid.UUID        `db:"customer_id" json:"customerId"`
+	Embeddings            *pgvector.Vector `db:"embeddings" json:"embeddings"`
+}
+
+// CreateConversationMessageEmbedding
+//
+//	INSERT INTO conversation_message_embedding (
+//	    conversation_message_id, customer_id, embeddings
+//	) VALUES ( $1, $2, $3 )
+//	ON CONFLICT (conversation_message_id)
+//	DO UPDATE SET
+//	    embeddings = EXCLUDED.embeddings
+func (q *Queries) CreateConversationMessageEmbedding(ctx context.Context, arg *CreateConversationMessageEmbeddingParams) error {
+	_, err := q.db.Exec(ctx, createConversationMessageEmbedding, arg.ConversationMessageID, arg.CustomerID, arg.Embeddings)
+	return err
+}
+
+const createConversationPromptTemplate = `-- name: CreateConversationPromptTemplate :exec
+INSERT INTO conversation_prompt_template (
+    conversation_id, prompt_template_id
+) VALUES (
+    $1, $2
+)
+ON CONFLICT DO NOTHING
+`
+
+type CreateConversationPromptTemplateParams struct {
+	ConversationID   uuid.UUID `db:"conversation_id" json:"conversationId"`
+	PromptTemplateID uuid.UUID `db:"prompt_template_id" json:"promptTemplateId"`
+}
+
+// CreateConversationPromptTemplate
+//
+//	INSERT INTO conversation_prompt_template (
+//	    conversation_id, prompt_template_id
+//	) VALUES (
+//	    $1, $2
+//	)
+//	ON CONFLICT DO NOTHING
+func (q *Queries) CreateConversationPromptTemplate(ctx context.Context, arg *CreateConversationPromptTemplateParams) error {
+	_, err := q.db.Exec(ctx, createConversationPromptTemplate, arg.ConversationID, arg.PromptTemplateID)
+	return err
+}
+
 const createCustomer = `-- name: CreateCustomer :one
 INSERT INTO customer (
     name, is_admin
@@ -271,7 +377,7 @@ INSERT INTO document (
 )
 ON CONFLICT (customer_id, parent_id, filename) DO UPDATE
 SET updated_at = CURRENT_TIMESTAMP
-RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize
+RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at
 `
 
 type CreateDocumentParams struct {
@@ -294,7 +400,7 @@ type CreateDocumentParams struct {
 //	)
 //	ON CONFLICT (customer_id, parent_id, filename) DO UPDATE
 //	SET updated_at = CURRENT_TIMESTAMP
-//	RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize
+//	RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at
 func (q *Queries) CreateDocument(ctx context.Context, arg *CreateDocumentParams) (*Document, error) {
 	row := q.db.QueryRow(ctx, createDocument,
 		arg.ParentID,
@@ -325,6 +431,7 @@ func (q *Queries) CreateDocument(ctx context.Context, arg *CreateDocumentParams)
 		&i.UpdatedAt,
 		&i.IsAsset,
 		&i.Vectorize,
+		&i.SummaryFailedAt,
 	)
 	return &i, err
 }
@@ -375,6 +482,197 @@ func (q *Queries) CreateDocumentVector(ctx context.Context, arg *CreateDocumentV
 	return &i, err
 }
 
+const createFeed = `-- name: CreateFeed :one
+INSERT INTO feed (
+    customer_id, url, title, feed_type, fetch_article, poll_interval_minutes
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT ON CONSTRAINT cnst_unique_feed
+DO UPDATE SET
+    updated_at = CURRENT_TIMESTAMP,
+    fetch_article = EXCLUDED.fetch_article,
+    poll_interval_minutes = EXCLUDED.poll_interval_minutes
+RETURNING id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at
+`
+
+type CreateFeedParams struct {
+	CustomerID          uuid.UUID `db:"customer_id" json:"customerId"`
+	Url                 string    `db:"url" json:"url"`
+	Title               string    `db:"title" json:"title"`
+	FeedType            string    `db:"feed_type" json:"feedType"`
+	FetchArticle        bool      `db:"fetch_article" json:"fetchArticle"`
+	PollIntervalMinutes int32     `db:"poll_interval_minutes" json:"pollIntervalMinutes"`
+}
+
+// CreateFeed
+//
+//	INSERT INTO feed (
+//	    customer_id, url, title, feed_type, fetch_article, poll_interval_minutes
+//	) VALUES (
+//	    $1, $2, $3, $4, $5, $6
+//	)
+//	ON CONFLICT ON CONSTRAINT cnst_unique_feed
+//	DO UPDATE SET
+//	    updated_at = CURRENT_TIMESTAMP,
+//	    fetch_article = EXCLUDED.fetch_article,
+//	    poll_interval_minutes = EXCLUDED.poll_interval_minutes
+//	RETURNING id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at
+func (q *Queries) CreateFeed(ctx context.Context, arg *CreateFeedParams) (*Feed, error) {
+	row := q.db.QueryRow(ctx, createFeed,
+		arg.CustomerID,
+		arg.Url,
+		arg.Title,
+		arg.FeedType,
+		arg.FetchArticle,
+		arg.PollIntervalMinutes,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.Url,
+		&i.Title,
+		&i.FeedType,
+		&i.FetchArticle,
+		&i.PollIntervalMinutes,
+		&i.LastPolledAt,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const createFeedItem = `-- name: CreateFeedItem :one
+INSERT INTO feed_item (
+    customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT ON CONSTRAINT cnst_unique_feed_item
+DO UPDATE SET
+    updated_at = CURRENT_TIMESTAMP,
+    title = EXCLUDED.title,
+    link = EXCLUDED.link,
+    author = EXCLUDED.author,
+    published_at = EXCLUDED.published_at,
+    content = EXCLUDED.content,
+    sha_256 = EXCLUDED.sha_256,
+    metadata = EXCLUDED.metadata
+RETURNING id, customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata, vector_sha_256, created_at, updated_at
+`
+
+type CreateFeedItemParams struct {
+	CustomerID  uuid.UUID          `db:"customer_id" json:"customerId"`
+	FeedID      uuid.UUID          `db:"feed_id" json:"feedId"`
+	Guid        string             `db:"guid" json:"guid"`
+	Title       string             `db:"title" json:"title"`
+	Link        string             `db:"link" json:"link"`
+	Author      string             `db:"author" json:"author"`
+	PublishedAt pgtype.Timestamptz `db:"published_at" json:"publishedAt"`
+	Content     string             `db:"content" json:"content"`
+	Sha256      string             `db:"sha_256" json:"sha256"`
+	Metadata    []byte             `db:"metadata" json:"metadata"`
+}
+
+// CreateFeedItem
+//
+//	INSERT INTO feed_item (
+//	    customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata
+//	) VALUES (
+//	    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+//	)
+//	ON CONFLICT ON CONSTRAINT cnst_unique_feed_item
+//	DO UPDATE SET
+//	    updated_at = CURRENT_TIMESTAMP,
+//	    title = EXCLUDED.title,
+//	    link = EXCLUDED.link,
+//	    author = EXCLUDED.author,
+//	    published_at = EXCLUDED.published_at,
+//	    content = EXCLUDED.content,
+//	    sha_256 = EXCLUDED.sha_256,
+//	    metadata = EXCLUDED.metadata
+//	RETURNING id, customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata, vector_sha_256, created_at, updated_at
+func (q *Queries) CreateFeedItem(ctx context.Context, arg *CreateFeedItemParams) (*FeedItem, error) {
+	row := q.db.QueryRow(ctx, createFeedItem,
+		arg.CustomerID,
+		arg.FeedID,
+		arg.Guid,
+		arg.Title,
+		arg.Link,
+		arg.Author,
+		arg.PublishedAt,
+		arg.Content,
+		arg.Sha256,
+		arg.Metadata,
+	)
+	var i FeedItem
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.FeedID,
+		&i.Guid,
+		&i.Title,
+		&i.Link,
+		&i.Author,
+		&i.PublishedAt,
+		&i.Content,
+		&i.Sha256,
+		&i.Metadata,
+		&i.VectorSha256,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const createFeedItemVector = `-- name: CreateFeedItemVector :one
+INSERT INTO feed_item_vector (
+    feed_item_id, vector_store_id, customer_id, index, metadata
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING id, feed_item_id, vector_store_id, customer_id, index, metadata, created_at
+`
+
+type CreateFeedItemVectorParams struct {
+	FeedItemID    uuid.UUID `db:"feed_item_id" json:"feedItemId"`
+	VectorStoreID uuid.UUID `db:"vector_store_id" json:"vectorStoreId"`
+	CustomerID    uuid.UUID `db:"customer_id" json:"customerId"`
+	Index         int32     `db:"index" json:"index"`
+	Metadata      []byte    `db:"metadata" json:"metadata"`
+}
+
+// CreateFeedItemVector
+//
+//	INSERT INTO feed_item_vector (
+//	    feed_item_id, vector_store_id, customer_id, index, metadata
+//	) VALUES (
+//	    $1, $2, $3, $4, $5
+//	)
+//	RETURNING id, feed_item_id, vector_store_id, customer_id, index, metadata, created_at
+func (q *Queries) CreateFeedItemVector(ctx context.Context, arg *CreateFeedItemVectorParams) (*FeedItemVector, error) {
+	row := q.db.QueryRow(ctx, createFeedItemVector,
+		arg.FeedItemID,
+		arg.VectorStoreID,
+		arg.CustomerID,
+		arg.Index,
+		arg.Metadata,
+	)
+	var i FeedItemVector
+	err := row.Scan(
+		&i.ID,
+		&i.FeedItemID,
+		&i.VectorStoreID,
+		&i.CustomerID,
+		&i.Index,
+		&i.Metadata,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
 const createFolder = `-- name: CreateFolder :one
 INSERT INTO folder (
     parent_id, customer_id, title
@@ -756,6 +1054,56 @@ func (q *Queries) CreateProjectLibraryRecord(ctx context.Context, arg *CreatePro
 	return &i, err
 }
 
+const createPromptTemplate = `-- name: CreatePromptTemplate :one
+INSERT INTO prompt_template (
+    customer_id, name, version, content, description, is_active
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING id, customer_id, name, version, content, description, is_active, created_at, updated_at
+`
+
+type CreatePromptTemplateParams struct {
+	CustomerID  pgtype.UUID `db:"customer_id" json:"customerId"`
+	Name        string      `db:"name" json:"name"`
+	Version     int32       `db:"version" json:"version"`
+	Content     string      `db:"content" json:"content"`
+	Description string      `db:"description" json:"description"`
+	IsActive    bool        `db:"is_active" json:"isActive"`
+}
+
+// CreatePromptTemplate
+//
+//	INSERT INTO prompt_template (
+//	    customer_id, name, version, content, description, is_active
+//	) VALUES (
+//	    $1, $2, $3, $4, $5, $6
+//	)
+//	RETURNING id, customer_id, name, version, content, description, is_active, created_at, updated_at
+func (q *Queries) CreatePromptTemplate(ctx context.Context, arg *CreatePromptTemplateParams) (*PromptTemplate, error) {
+	row := q.db.QueryRow(ctx, createPromptTemplate,
+		arg.CustomerID,
+		arg.Name,
+		arg.Version,
+		arg.Content,
+		arg.Description,
+		arg.IsActive,
+	)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.Name,
+		&i.Version,
+		&i.Content,
+		&i.Description,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
 const createResume = `-- name: CreateResume :one
 INSERT INTO resume (
     customer_id, title
@@ -1486,37 +1834,38 @@ func (q *Queries) CreateWebsite(ctx context.Context, arg *CreateWebsiteParams) (
 
 const createWebsitePage = `-- name: CreateWebsitePage :one
 INSERT INTO website_page (
-    customer_id, website_id, url, sha_256, metadata
+    customer_id, website_id, url, sha_256, metadata, content_type
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6
 )
 ON CONFLICT ON CONSTRAINT cnst_unique_website_page
 DO UPDATE SET
     updated_at = CURRENT_TIMESTAMP,
     is_valid = TRUE
-RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at
+RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at
 `
 
 type CreateWebsitePageParams struct {
-	CustomerID uuid.UUID `db:"customer_id" json:"customerId"`
-	WebsiteID  uuid.UUID `db:"website_id" json:"websiteId"`
-	Url        string    `db:"url" json:"url"`
-	Sha256     string    `db:"sha_256" json:"sha256"`
-	Metadata   []byte    `db:"metadata" json:"metadata"`
+	CustomerID  uuid.UUID `db:"customer_id" json:"customerId"`
+	WebsiteID   uuid.UUID `db:"website_id" json:"websiteId"`
+	Url         string    `db:"url" json:"url"`
+	Sha256      string    `db:"sha_256" json:"sha256"`
+	Metadata    []byte    `db:"metadata" json:"metadata"`
+	ContentType string    `db:"content_type" json:"contentType"`
 }
 
 // CreateWebsitePage
 //
 //	INSERT INTO website_page (
-//	    customer_id, website_id, url, sha_256, metadata
+//	    customer_id, website_id, url, sha_256, metadata, content_type
 //	) VALUES (
-//	    $1, $2, $3, $4, $5
+//	    $1, $2, $3, $4, $5, $6
 //	)
 //	ON CONFLICT ON CONSTRAINT cnst_unique_website_page
 //	DO UPDATE SET
 //	    updated_at = CURRENT_TIMESTAMP,
 //	    is_valid = TRUE
-//	RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at
+//	RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at
 func (q *Queries) CreateWebsitePage(ctx context.Context, arg *CreateWebsitePageParams) (*WebsitePage, error) {
 	row := q.db.QueryRow(ctx, createWebsitePage,
 		arg.CustomerID,
@@ -1524,6 +1873,7 @@ func (q *Queries) CreateWebsitePage(ctx context.Context, arg *CreateWebsitePageP
 		arg.Url,
 		arg.Sha256,
 		arg.Metadata,
+		arg.ContentType,
 	)
 	var i WebsitePage
 	err := row.Scan(
@@ -1539,6 +1889,8 @@ func (q *Queries) CreateWebsitePage(ctx context.Context, arg *CreateWebsitePageP
 		&i.VectorSha256,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ContentType,
+		&i.SummaryFailedAt,
 	)
 	return &i, err
 }
@@ -1589,6 +1941,54 @@ func (q *Queries) CreateWebsitePageVector(ctx context.Context, arg *CreateWebsit
 	return &i, err
 }
 
+const deactivatePromptTemplates = `-- name: DeactivatePromptTemplates :exec
+UPDATE prompt_template SET
+    is_active = false,
+    updated_at = CURRENT_TIMESTAMP
+WHERE customer_id IS NOT DISTINCT FROM $1
+AND name = $2
+AND is_active = true
+`
+
+type DeactivatePromptTemplatesParams struct {
+	CustomerID pgtype.UUID `db:"customer_id" json:"customerId"`
+	Name       string      `db:"name" json:"name"`
+}
+
+// DeactivatePromptTemplates
+//
+//	UPDATE prompt_template SET
+//	    is_active = false,
+//	    updated_at = CURRENT_TIMESTAMP
+//	WHERE customer_id IS NOT DISTINCT FROM $1
+//	AND name = $2
+//	AND is_active = true
+func (q *Queries) DeactivatePromptTemplates(ctx context.Context, arg *DeactivatePromptTemplatesParams) error {
+	_, err := q.db.Exec(ctx, deactivatePromptTemplates, arg.CustomerID, arg.Name)
+	return err
+}
+
+const deleteConversationMessageFeedback = `-- name: DeleteConversationMessageFeedback :exec
+DELETE FROM conversation_message_feedback
+WHERE conversation_message_id = $1
+AND conversation_id = $2
+`
+
+type DeleteConversationMessageFeedbackParams struct {
+	ConversationMessageID uuid.UUID `db:"conversation_message_id" json:"conversationMessageId"`
+	ConversationID        uuid.UUID `db:"conversation_id" json:"conversationId"`
+}
+
+// DeleteConversationMessageFeedback
+//
+//	DELETE FROM conversation_message_feedback
+//	WHERE conversation_message_id = $1
+//	AND conversation_id = $2
+func (q *Queries) DeleteConversationMessageFeedback(ctx context.Context, arg *DeleteConversationMessageFeedbackParams) error {
+	_, err := q.db.Exec(ctx, deleteConversationMessageFeedback, arg.ConversationMessageID, arg.ConversationID)
+	return err
+}
+
 const deleteCustomer = `-- name: DeleteCustomer :exec
 DELETE FROM customer
 WHERE id = $1
@@ -1603,6 +2003,25 @@ func (q *Queries) DeleteCustomer(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteCustomerToolConfiguration = `-- name: DeleteCustomerToolConfiguration :exec
+DELETE FROM customer_tool_configuration
+WHERE customer_id = $1 AND conversation_type = $2
+`
+
+type DeleteCustomerToolConfigurationParams struct {
+	CustomerID       uuid.UUID `db:"customer_id" json:"customerId"`
+	ConversationType string    `db:"conversation_type" json:"conversationType"`
+}
+
+// DeleteCustomerToolConfiguration
+//
+//	DELETE FROM customer_tool_configuration
+//	WHERE customer_id = $1 AND conversation_type = $2
+func (q *Queries) DeleteCustomerToolConfiguration(ctx context.Context, arg *DeleteCustomerToolConfigurationParams) error {
+	_, err := q.db.Exec(ctx, deleteCustomerToolConfiguration, arg.CustomerID, arg.ConversationType)
+	return err
+}
+
 const deleteDocumentVectors = `-- name: DeleteDocumentVectors :exec
 DELETE FROM document_vector
 WHERE document_id = $1
@@ -1638,6 +2057,46 @@ func (q *Queries) DeleteDocumentsOlderThan(ctx context.Context, arg *DeleteDocum
 	return err
 }
 
+const deleteExpiredCompletionCache = `-- name: DeleteExpiredCompletionCache :exec
+DELETE FROM completion_cache
+WHERE expires_at <= CURRENT_TIMESTAMP
+`
+
+// DeleteExpiredCompletionCache
+//
+//	DELETE FROM completion_cache
+//	WHERE expires_at <= CURRENT_TIMESTAMP
+func (q *Queries) DeleteExpiredCompletionCache(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredCompletionCache)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feed WHERE id = $1
+`
+
+// DeleteFeed
+//
+//	DELETE FROM feed WHERE id = $1
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteFeed, id)
+	return err
+}
+
+const deleteFeedItemVectors = `-- name: DeleteFeedItemVectors :exec
+DELETE FROM feed_item_vector
+WHERE feed_item_id = $1
+`
+
+// DeleteFeedItemVectors
+//
+//	DELETE FROM feed_item_vector
+//	WHERE feed_item_id = $1
+func (q *Queries) DeleteFeedItemVectors(ctx context.Context, feedItemID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteFeedItemVectors, feedItemID)
+	return err
+}
+
 const deleteFoldersOlderThan = `-- name: DeleteFoldersOlderThan :exec
 DELETE FROM folder
 WHERE customer_id = $1
@@ -1659,6 +2118,20 @@ func (q *Queries) DeleteFoldersOlderThan(ctx context.Context, arg *DeleteFolders
 	return err
 }
 
+const deleteLLMRetryPolicy = `-- name: DeleteLLMRetryPolicy :exec
+DELETE FROM llm_retry_policy
+WHERE llm_id = $1
+`
+
+// DeleteLLMRetryPolicy
+//
+//	DELETE FROM llm_retry_policy
+//	WHERE llm_id = $1
+func (q *Queries) DeleteLLMRetryPolicy(ctx context.Context, llmID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteLLMRetryPolicy, llmID)
+	return err
+}
+
 const deleteWebsite = `-- name: DeleteWebsite :exec
 DELETE FROM website WHERE id = $1
 `
@@ -1753,29 +2226,115 @@ func (q *Queries) DeleteWebsitePagesOlderThan(ctx context.Context, arg *DeleteWe
 	return err
 }
 
-const getAvailableModel = `-- name: GetAvailableModel :one
-SELECT id, provider, display_name, description, input_token_limit, output_token_limit, currency, input_cost_per_million_tokens, output_cost_per_million_tokens, depreciated_warning, is_depreciated, created_at, updated_at, is_visible FROM available_model
-WHERE id = $1
+const getActivePromptTemplate = `-- name: GetActivePromptTemplate :one
+-- the active version of the customer, or the active default
+SELECT id, customer_id, name, version, content, description, is_active, created_at, updated_at FROM prompt_template
+WHERE (customer_id = $1 OR customer_id IS NULL)
+AND name = $2
+AND is_active = true
+ORDER BY customer_id NULLS LAST
+LIMIT 1
 `
 
-// GetAvailableModel
+type GetActivePromptTemplateParams struct {
+	CustomerID pgtype.UUID `db:"customer_id" json:"customerId"`
+	Name       string      `db:"name" json:"name"`
+}
+
+// GetActivePromptTemplate
 //
-//	SELECT id, provider, display_name, description, input_token_limit, output_token_limit, currency, input_cost_per_million_tokens, output_cost_per_million_tokens, depreciated_warning, is_depreciated, created_at, updated_at, is_visible FROM available_model
-//	WHERE id = $1
-func (q *Queries) GetAvailableModel(ctx context.Context, id string) (*AvailableModel, error) {
-	row := q.db.QueryRow(ctx, getAvailableModel, id)
-	var i AvailableModel
+//	-- the active version of the customer, or the active default
+//	SELECT id, customer_id, name, version, content, description, is_active, created_at, updated_at FROM prompt_template
+//	WHERE (customer_id = $1 OR customer_id IS NULL)
+//	AND name = $2
+//	AND is_active = true
+//	ORDER BY customer_id NULLS LAST
+//	LIMIT 1
+func (q *Queries) GetActivePromptTemplate(ctx context.Context, arg *GetActivePromptTemplateParams) (*PromptTemplate, error) {
+	row := q.db.QueryRow(ctx, getActivePromptTemplate, arg.CustomerID, arg.Name)
+	var i PromptTemplate
 	err := row.Scan(
 		&i.ID,
-		&i.Provider,
-		&i.DisplayName,
+		&i.CustomerID,
+		&i.Name,
+		&i.Version,
+		&i.Content,
 		&i.Description,
-		&i.InputTokenLimit,
-		&i.OutputTokenLimit,
-		&i.Currency,
-		&i.InputCostPerMillionTokens,
-		&i.OutputCostPerMillionTokens,
-		&i.DepreciatedWarning,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const getActivePromptTemplates = `-- name: GetActivePromptTemplates :many
+-- the active version of every prompt the customer uses
+SELECT DISTINCT ON (name) id, customer_id, name, version, content, description, is_active, created_at, updated_at FROM prompt_template
+WHERE (customer_id = $1 OR customer_id IS NULL)
+AND is_active = true
+ORDER BY name, customer_id NULLS LAST
+`
+
+// GetActivePromptTemplates
+//
+//	-- the active version of every prompt the customer uses
+//	SELECT DISTINCT ON (name) id, customer_id, name, version, content, description, is_active, created_at, updated_at FROM prompt_template
+//	WHERE (customer_id = $1 OR customer_id IS NULL)
+//	AND is_active = true
+//	ORDER BY name, customer_id NULLS LAST
+func (q *Queries) GetActivePromptTemplates(ctx context.Context, customerID pgtype.UUID) ([]*PromptTemplate, error) {
+	rows, err := q.db.Query(ctx, getActivePromptTemplates, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*PromptTemplate{}
+	for rows.Next() {
+		var i PromptTemplate
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.Name,
+			&i.Version,
+			&i.Content,
+			&i.Description,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAvailableModel = `-- name: GetAvailableModel :one
+SELECT id, provider, display_name, description, input_token_limit, output_token_limit, currency, input_cost_per_million_tokens, output_cost_per_million_tokens, depreciated_warning, is_depreciated, created_at, updated_at, is_visible FROM available_model
+WHERE id = $1
+`
+
+// GetAvailableModel
+//
+//	SELECT id, provider, display_name, description, input_token_limit, output_token_limit, currency, input_cost_per_million_tokens, output_cost_per_million_tokens, depreciated_warning, is_depreciated, created_at, updated_at, is_visible FROM available_model
+//	WHERE id = $1
+func (q *Queries) GetAvailableModel(ctx context.Context, id string) (*AvailableModel, error) {
+	row := q.db.QueryRow(ctx, getAvailableModel, id)
+	var i AvailableModel
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.DisplayName,
+		&i.Description,
+		&i.InputTokenLimit,
+		&i.OutputTokenLimit,
+		&i.Currency,
+		&i.InputCostPerMillionTokens,
+		&i.OutputCostPerMillionTokens,
+		&i.DepreciatedWarning,
 		&i.IsDepreciated,
 		&i.CreatedAt,
 		&i.UpdatedAt,
@@ -1957,14 +2516,58 @@ func (q *Queries) GetChatLLM(ctx context.Context, arg *GetChatLLMParams) (*GetCh
 	return &i, err
 }
 
+const getCompletionCacheHit = `-- name: GetCompletionCacheHit :one
+UPDATE completion_cache
+SET
+    hits = hits + 1,
+    last_hit_at = CURRENT_TIMESTAMP
+WHERE customer_id = $1
+  AND key = $2
+  AND expires_at > CURRENT_TIMESTAMP
+RETURNING customer_id, key, model, message, input_tokens, output_tokens, hits, last_hit_at, expires_at, created_at
+`
+
+type GetCompletionCacheHitParams struct {
+	CustomerID uuid.UUID `db:"customer_id" json:"customerId"`
+	Key        string    `db:"key" json:"key"`
+}
+
+// GetCompletionCacheHit
+//
+//	UPDATE completion_cache
+//	SET
+//	    hits = hits + 1,
+//	    last_hit_at = CURRENT_TIMESTAMP
+//	WHERE customer_id = $1
+//	  AND key = $2
+//	  AND expires_at > CURRENT_TIMESTAMP
+//	RETURNING customer_id, key, model, message, input_tokens, output_tokens, hits, last_hit_at, expires_at, created_at
+func (q *Queries) GetCompletionCacheHit(ctx context.Context, arg *GetCompletionCacheHitParams) (*CompletionCache, error) {
+	row := q.db.QueryRow(ctx, getCompletionCacheHit, arg.CustomerID, arg.Key)
+	var i CompletionCache
+	err := row.Scan(
+		&i.CustomerID,
+		&i.Key,
+		&i.Model,
+		&i.Message,
+		&i.InputTokens,
+		&i.OutputTokens,
+		&i.Hits,
+		&i.LastHitAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
 const getConversation = `-- name: GetConversation :one
-SELECT id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id FROM conversation
+SELECT id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id FROM conversation
 WHERE id = $1
 `
 
 // GetConversation
 //
-//	SELECT id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id FROM conversation
+//	SELECT id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id FROM conversation
 //	WHERE id = $1
 func (q *Queries) GetConversation(ctx context.Context, id uuid.UUID) (*Conversation, error) {
 	row := q.db.QueryRow(ctx, getConversation, id)
@@ -1981,23 +2584,40 @@ func (q *Queries) GetConversation(ctx context.Context, id uuid.UUID) (*Conversat
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.CurrLlmID,
+		&i.Summary,
+		&i.SummaryIndex,
+		&i.ActiveMessageID,
 	)
 	return &i, err
 }
 
-const getConversationMessages = `-- name: GetConversationMessages :many
-SELECT id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at FROM conversation_message
-WHERE conversation_id = $1
-ORDER BY index ASC
+const getConversationBranch = `-- name: GetConversationBranch :many
+WITH RECURSIVE branch AS (
+    SELECT m.id, m.parent_id FROM conversation_message m
+    WHERE m.id = $1
+    UNION ALL
+    SELECT m.id, m.parent_id FROM conversation_message m
+    JOIN branch b ON m.id = b.parent_id
+)
+SELECT cm.id, cm.conversation_id, cm.llm_id, cm.model, cm.temperature, cm.instructions, cm.role, cm.message, cm.index, cm.tool_use_id, cm.tool_name, cm.tool_arguments, cm.tool_results, cm.created_at, cm.updated_at, cm.is_cancelled, cm.citations, cm.parent_id, cm.embedding_failed_at FROM conversation_message cm
+JOIN branch b ON cm.id = b.id
+ORDER BY cm.index ASC
 `
 
-// GetConversationMessages
+// GetConversationBranch
 //
-//	SELECT id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at FROM conversation_message
-//	WHERE conversation_id = $1
-//	ORDER BY index ASC
-func (q *Queries) GetConversationMessages(ctx context.Context, conversationID uuid.UUID) ([]*ConversationMessage, error) {
-	rows, err := q.db.Query(ctx, getConversationMessages, conversationID)
+//	WITH RECURSIVE branch AS (
+//	    SELECT m.id, m.parent_id FROM conversation_message m
+//	    WHERE m.id = $1
+//	    UNION ALL
+//	    SELECT m.id, m.parent_id FROM conversation_message m
+//	    JOIN branch b ON m.id = b.parent_id
+//	)
+//	SELECT cm.id, cm.conversation_id, cm.llm_id, cm.model, cm.temperature, cm.instructions, cm.role, cm.message, cm.index, cm.tool_use_id, cm.tool_name, cm.tool_arguments, cm.tool_results, cm.created_at, cm.updated_at, cm.is_cancelled, cm.citations, cm.parent_id, cm.embedding_failed_at FROM conversation_message cm
+//	JOIN branch b ON cm.id = b.id
+//	ORDER BY cm.index ASC
+func (q *Queries) GetConversationBranch(ctx context.Context, id uuid.UUID) ([]*ConversationMessage, error) {
+	rows, err := q.db.Query(ctx, getConversationBranch, id)
 	if err != nil {
 		return nil, err
 	}
@@ -2021,6 +2641,10 @@ func (q *Queries) GetConversationMessages(ctx context.Context, conversationID uu
 			&i.ToolResults,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsCancelled,
+			&i.Citations,
+			&i.ParentID,
+			&i.EmbeddingFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -2032,38 +2656,36 @@ func (q *Queries) GetConversationMessages(ctx context.Context, conversationID uu
 	return items, nil
 }
 
-const getConversations = `-- name: GetConversations :many
-SELECT id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id FROM conversation
-WHERE customer_id = $1
-ORDER BY updated_at DESC
+const getConversationFeedback = `-- name: GetConversationFeedback :many
+SELECT id, conversation_message_id, conversation_id, customer_id, llm_id, rating, comment, tools, sources, created_at, updated_at FROM conversation_message_feedback
+WHERE conversation_id = $1
 `
 
-// GetConversations
+// GetConversationFeedback
 //
-//	SELECT id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id FROM conversation
-//	WHERE customer_id = $1
-//	ORDER BY updated_at DESC
-func (q *Queries) GetConversations(ctx context.Context, customerID uuid.UUID) ([]*Conversation, error) {
-	rows, err := q.db.Query(ctx, getConversations, customerID)
+//	SELECT id, conversation_message_id, conversation_id, customer_id, llm_id, rating, comment, tools, sources, created_at, updated_at FROM conversation_message_feedback
+//	WHERE conversation_id = $1
+func (q *Queries) GetConversationFeedback(ctx context.Context, conversationID uuid.UUID) ([]*ConversationMessageFeedback, error) {
+	rows, err := q.db.Query(ctx, getConversationFeedback, conversationID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Conversation{}
+	items := []*ConversationMessageFeedback{}
 	for rows.Next() {
-		var i Conversation
+		var i ConversationMessageFeedback
 		if err := rows.Scan(
 			&i.ID,
+			&i.ConversationMessageID,
+			&i.ConversationID,
 			&i.CustomerID,
-			&i.Title,
-			&i.ConversationType,
-			&i.SystemMessage,
-			&i.Metadata,
-			&i.HasError,
-			&i.ErrorMessage,
+			&i.LlmID,
+			&i.Rating,
+			&i.Comment,
+			&i.Tools,
+			&i.Sources,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.CurrLlmID,
 		); err != nil {
 			return nil, err
 		}
@@ -2075,62 +2697,54 @@ func (q *Queries) GetConversations(ctx context.Context, customerID uuid.UUID) ([
 	return items, nil
 }
 
-const getConversationsWithCount = `-- name: GetConversationsWithCount :many
-SELECT c.id, c.customer_id, c.title, c.conversation_type, c.system_message, c.metadata, c.has_error, c.error_message, c.created_at, c.updated_at, c.curr_llm_id, COUNT(cm.id) AS message_count
-FROM conversation c
-JOIN conversation_message cm
-ON c.id = cm.conversation_id
-WHERE c.customer_id = $1
-GROUP BY c.id
-ORDER BY c.updated_at DESC
+const getConversationLeaves = `-- name: GetConversationLeaves :many
+SELECT cm.id, cm.conversation_id, cm.llm_id, cm.model, cm.temperature, cm.instructions, cm.role, cm.message, cm.index, cm.tool_use_id, cm.tool_name, cm.tool_arguments, cm.tool_results, cm.created_at, cm.updated_at, cm.is_cancelled, cm.citations, cm.parent_id, cm.embedding_failed_at FROM conversation_message cm
+WHERE cm.conversation_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM conversation_message child
+    WHERE child.parent_id = cm.id
+)
+ORDER BY cm.id DESC
 `
 
-type GetConversationsWithCountRow struct {
-	ID               uuid.UUID          `db:"id" json:"id"`
-	CustomerID       uuid.UUID          `db:"customer_id" json:"customerId"`
-	Title            string             `db:"title" json:"title"`
-	ConversationType string             `db:"conversation_type" json:"conversationType"`
-	SystemMessage    string             `db:"system_message" json:"systemMessage"`
-	Metadata         []byte             `db:"metadata" json:"metadata"`
-	HasError         bool               `db:"has_error" json:"hasError"`
-	ErrorMessage     *string            `db:"error_message" json:"errorMessage"`
-	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
-	UpdatedAt        pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
-	CurrLlmID        pgtype.UUID        `db:"curr_llm_id" json:"currLlmId"`
-	MessageCount     int64              `db:"message_count" json:"messageCount"`
-}
-
-// GetConversationsWithCount
+// GetConversationLeaves
 //
-//	SELECT c.id, c.customer_id, c.title, c.conversation_type, c.system_message, c.metadata, c.has_error, c.error_message, c.created_at, c.updated_at, c.curr_llm_id, COUNT(cm.id) AS message_count
-//	FROM conversation c
-//	JOIN conversation_message cm
-//	ON c.id = cm.conversation_id
-//	WHERE c.customer_id = $1
-//	GROUP BY c.id
-//	ORDER BY c.updated_at DESC
-func (q *Queries) GetConversationsWithCount(ctx context.Context, customerID uuid.UUID) ([]*GetConversationsWithCountRow, error) {
-	rows, err := q.db.Query(ctx, getConversationsWithCount, customerID)
+//	SELECT cm.id, cm.conversation_id, cm.llm_id, cm.model, cm.temperature, cm.instructions, cm.role, cm.message, cm.index, cm.tool_use_id, cm.tool_name, cm.tool_arguments, cm.tool_results, cm.created_at, cm.updated_at, cm.is_cancelled, cm.citations, cm.parent_id, cm.embedding_failed_at FROM conversation_message cm
+//	WHERE cm.conversation_id = $1
+//	AND NOT EXISTS (
+//	    SELECT 1 FROM conversation_message child
+//	    WHERE child.parent_id = cm.id
+//	)
+//	ORDER BY cm.id DESC
+func (q *Queries) GetConversationLeaves(ctx context.Context, conversationID uuid.UUID) ([]*ConversationMessage, error) {
+	rows, err := q.db.Query(ctx, getConversationLeaves, conversationID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*GetConversationsWithCountRow{}
+	items := []*ConversationMessage{}
 	for rows.Next() {
-		var i GetConversationsWithCountRow
+		var i ConversationMessage
 		if err := rows.Scan(
 			&i.ID,
-			&i.CustomerID,
-			&i.Title,
-			&i.ConversationType,
-			&i.SystemMessage,
-			&i.Metadata,
-			&i.HasError,
-			&i.ErrorMessage,
+			&i.ConversationID,
+			&i.LlmID,
+			&i.Model,
+			&i.Temperature,
+			&i.Instructions,
+			&i.Role,
+			&i.Message,
+			&i.Index,
+			&i.ToolUseID,
+			&i.ToolName,
+			&i.ToolArguments,
+			&i.ToolResults,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.CurrLlmID,
-			&i.MessageCount,
+			&i.IsCancelled,
+			&i.Citations,
+			&i.ParentID,
+			&i.EmbeddingFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -2142,109 +2756,1021 @@ func (q *Queries) GetConversationsWithCount(ctx context.Context, customerID uuid
 	return items, nil
 }
 
-const getCustomer = `-- name: GetCustomer :one
-SELECT id, name, datastore, created_at, updated_at, is_admin FROM customer
-WHERE id = $1 LIMIT 1
+const getConversationMessage = `-- name: GetConversationMessage :one
+SELECT id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at, is_cancelled, citations, parent_id, embedding_failed_at FROM conversation_message
+WHERE id = $1
+AND conversation_id = $2
 `
 
-// GetCustomer
-//
-//	SELECT id, name, datastore, created_at, updated_at, is_admin FROM customer
-//	WHERE id = $1 LIMIT 1
-func (q *Queries) GetCustomer(ctx context.Context, id uuid.UUID) (*Customer, error) {
-	row := q.db.QueryRow(ctx, getCustomer, id)
-	var i Customer
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Datastore,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.IsAdmin,
-	)
-	return &i, err
+type GetConversationMessageParams struct {
+	ID             uuid.UUID `db:"id" json:"id"`
+	ConversationID uuid.UUID `db:"conversation_id" json:"conversationId"`
 }
 
-const getCustomerByName = `-- name: GetCustomerByName :one
-SELECT id, name, datastore, created_at, updated_at, is_admin FROM customer
-WHERE name = $1 LIMIT 1
-`
-
-// GetCustomerByName
+// GetConversationMessage
 //
-//	SELECT id, name, datastore, created_at, updated_at, is_admin FROM customer
-//	WHERE name = $1 LIMIT 1
-func (q *Queries) GetCustomerByName(ctx context.Context, name string) (*Customer, error) {
-	row := q.db.QueryRow(ctx, getCustomerByName, name)
-	var i Customer
+//	SELECT id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at, is_cancelled, citations, parent_id, embedding_failed_at FROM conversation_message
+//	WHERE id = $1
+//	AND conversation_id = $2
+func (q *Queries) GetConversationMessage(ctx context.Context, arg *GetConversationMessageParams) (*ConversationMessage, error) {
+	row := q.db.QueryRow(ctx, getConversationMessage, arg.ID, arg.ConversationID)
+	var i ConversationMessage
 	err := row.Scan(
 		&i.ID,
-		&i.Name,
-		&i.Datastore,
+		&i.ConversationID,
+		&i.LlmID,
+		&i.Model,
+		&i.Temperature,
+		&i.Instructions,
+		&i.Role,
+		&i.Message,
+		&i.Index,
+		&i.ToolUseID,
+		&i.ToolName,
+		&i.ToolArguments,
+		&i.ToolResults,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.IsAdmin,
+		&i.IsCancelled,
+		&i.Citations,
+		&i.ParentID,
+		&i.EmbeddingFailedAt,
 	)
 	return &i, err
 }
 
-const getCustomerChatLLM = `-- name: GetCustomerChatLLM :one
-WITH RequiredLLM AS (
-    -- First, try to find a customer-specific llm from the configurations
-    SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at FROM customer_llm_configurations clc
-    JOIN llm ON llm.id = clc.chat_llm_id
-    WHERE clc.customer_id = $1
-
-    UNION ALL
-
-    -- Fallback to a global default if no customer-specific default is found
-    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
-    WHERE llm.customer_id IS NULL AND llm.is_default = true
-    AND NOT EXISTS (
-        SELECT 1
-        FROM customer_llm_configurations clc
-        JOIN llm ON llm.id = clc.chat_llm_id
-        WHERE clc.customer_id = $1
-    )
-)
-SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
-FROM RequiredLLM llm
-INNER JOIN available_model am ON am.id = llm.model
-LIMIT 1
+const getConversationMessageTree = `-- name: GetConversationMessageTree :many
+SELECT id, parent_id, index FROM conversation_message
+WHERE conversation_id = $1
+ORDER BY index ASC, id ASC
 `
 
-type GetCustomerChatLLMRow struct {
-	Llm            Llm            `db:"llm" json:"llm"`
-	AvailableModel AvailableModel `db:"available_model" json:"availableModel"`
+type GetConversationMessageTreeRow struct {
+	ID       uuid.UUID   `db:"id" json:"id"`
+	ParentID pgtype.UUID `db:"parent_id" json:"parentId"`
+	Index    int32       `db:"index" json:"index"`
 }
 
-// GetCustomerChatLLM
-//
-//	WITH RequiredLLM AS (
-//	    -- First, try to find a customer-specific llm from the configurations
-//	    SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at FROM customer_llm_configurations clc
-//	    JOIN llm ON llm.id = clc.chat_llm_id
-//	    WHERE clc.customer_id = $1
-//
-//	    UNION ALL
+// GetConversationMessageTree
 //
-//	    -- Fallback to a global default if no customer-specific default is found
+//	SELECT id, parent_id, index FROM conversation_message
+//	WHERE conversation_id = $1
+//	ORDER BY index ASC, id ASC
+func (q *Queries) GetConversationMessageTree(ctx context.Context, conversationID uuid.UUID) ([]*GetConversationMessageTreeRow, error) {
+	rows, err := q.db.Query(ctx, getConversationMessageTree, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetConversationMessageTreeRow{}
+	for rows.Next() {
+		var i GetConversationMessageTreeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.Index,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationMessages = `-- name: GetConversationMessages :many
+SELECT id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at, is_cancelled, citations, parent_id, embedding_failed_at FROM conversation_message
+WHERE conversation_id = $1
+ORDER BY index ASC
+`
+
+// GetConversationMessages
+//
+//	SELECT id, conversation_id, llm_id, model, temperature, instructions, role, message, index, tool_use_id, tool_name, tool_arguments, tool_results, created_at, updated_at, is_cancelled, citations, parent_id, embedding_failed_at FROM conversation_message
+//	WHERE conversation_id = $1
+//	ORDER BY index ASC
+func (q *Queries) GetConversationMessages(ctx context.Context, conversationID uuid.UUID) ([]*ConversationMessage, error) {
+	rows, err := q.db.Query(ctx, getConversationMessages, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*ConversationMessage{}
+	for rows.Next() {
+		var i ConversationMessage
+		if err := rows.Scan(
+			&i.ID,
+			&i.ConversationID,
+			&i.LlmID,
+			&i.Model,
+			&i.Temperature,
+			&i.Instructions,
+			&i.Role,
+			&i.Message,
+			&i.Index,
+			&i.ToolUseID,
+			&i.ToolName,
+			&i.ToolArguments,
+			&i.ToolResults,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsCancelled,
+			&i.Citations,
+			&i.ParentID,
+			&i.EmbeddingFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationMessagesWithoutEmbeddings = `-- name: GetConversationMessagesWithoutEmbeddings :many
+SELECT cm.id, cm.message, c.customer_id
+FROM conversation_message cm
+JOIN conversation c ON c.id = cm.conversation_id
+LEFT JOIN conversation_message_embedding cme ON cme.conversation_message_id = cm.id
+WHERE cme.conversation_message_id IS NULL
+AND cm.role = ANY($1::text[])
+AND cm.message != ''
+AND (cm.embedding_failed_at IS NULL OR cm.embedding_failed_at < $3)
+ORDER BY cm.id DESC
+LIMIT $2
+`
+
+type GetConversationMessagesWithoutEmbeddingsParams struct {
+	Column1           []string           `db:"column_1" json:"column1"`
+	Limit             int32              `db:"limit" json:"limit"`
+	EmbeddingFailedAt pgtype.Timestamptz `db:"embedding_failed_at" json:"embeddingFailedAt"`
+}
+
+type GetConversationMessagesWithoutEmbeddingsRow struct {
+	ID         uuid.UUID `db:"id" json:"id"`
+	Message    string    `db:"message" json:"message"`
+	CustomerID uuid.UUID `db:"customer_id" json:"customerId"`
+}
+
+// GetConversationMessagesWithoutEmbeddings
+//
+//	SELECT cm.id, cm.message, c.customer_id
+//	FROM conversation_message cm
+//	JOIN conversation c ON c.id = cm.conversation_id
+//	LEFT JOIN conversation_message_embedding cme ON cme.conversation_message_id = cm.id
+//	WHERE cme.conversation_message_id IS NULL
+//	AND cm.role = ANY($1::text[])
+//	AND cm.message != ''
+//	AND (cm.embedding_failed_at IS NULL OR cm.embedding_failed_at < $3)
+//	ORDER BY cm.id DESC
+//	LIMIT $2
+func (q *Queries) GetConversationMessagesWithoutEmbeddings(ctx context.Context, arg *GetConversationMessagesWithoutEmbeddingsParams) ([]*GetConversationMessagesWithoutEmbeddingsRow, error) {
+	rows, err := q.db.Query(ctx, getConversationMessagesWithoutEmbeddings, arg.Column1, arg.Limit, arg.EmbeddingFailedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetConversationMessagesWithoutEmbeddingsRow{}
+	for rows.Next() {
+		var i GetConversationMessagesWithoutEmbeddingsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Message,
+			&i.CustomerID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationPromptTemplates = `-- name: GetConversationPromptTemplates :many
+SELECT prompt_template.id, prompt_template.customer_id, prompt_template.name, prompt_template.version, prompt_template.content, prompt_template.description, prompt_template.is_active, prompt_template.created_at, prompt_template.updated_at, conversation_prompt_template.created_at AS used_at
+FROM conversation_prompt_template
+JOIN prompt_template ON prompt_template.id = conversation_prompt_template.prompt_template_id
+WHERE conversation_prompt_template.conversation_id = $1
+ORDER BY conversation_prompt_template.created_at ASC
+`
+
+type GetConversationPromptTemplatesRow struct {
+	PromptTemplate PromptTemplate     `db:"prompt_template" json:"promptTemplate"`
+	UsedAt         pgtype.Timestamptz `db:"used_at" json:"usedAt"`
+}
+
+// GetConversationPromptTemplates
+//
+//	SELECT prompt_template.id, prompt_template.customer_id, prompt_template.name, prompt_template.version, prompt_template.content, prompt_template.description, prompt_template.is_active, prompt_template.created_at, prompt_template.updated_at, conversation_prompt_template.created_at AS used_at
+//	FROM conversation_prompt_template
+//	JOIN prompt_template ON prompt_template.id = conversation_prompt_template.prompt_template_id
+//	WHERE conversation_prompt_template.conversation_id = $1
+//	ORDER BY conversation_prompt_template.created_at ASC
+func (q *Queries) GetConversationPromptTemplates(ctx context.Context, conversationID uuid.UUID) ([]*GetConversationPromptTemplatesRow, error) {
+	rows, err := q.db.Query(ctx, getConversationPromptTemplates, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetConversationPromptTemplatesRow{}
+	for rows.Next() {
+		var i GetConversationPromptTemplatesRow
+		if err := rows.Scan(
+			&i.PromptTemplate.ID,
+			&i.PromptTemplate.CustomerID,
+			&i.PromptTemplate.Name,
+			&i.PromptTemplate.Version,
+			&i.PromptTemplate.Content,
+			&i.PromptTemplate.Description,
+			&i.PromptTemplate.IsActive,
+			&i.PromptTemplate.CreatedAt,
+			&i.PromptTemplate.UpdatedAt,
+			&i.UsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationTokenUsage = `-- name: GetConversationTokenUsage :many
+SELECT id, customer_id, conversation_id, model, input_tokens, output_tokens, total_tokens, created_at FROM token_usage
+WHERE conversation_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+// GetConversationTokenUsage
+//
+//	SELECT id, customer_id, conversation_id, model, input_tokens, output_tokens, total_tokens, created_at FROM token_usage
+//	WHERE conversation_id = $1
+//	ORDER BY created_at ASC, id ASC
+func (q *Queries) GetConversationTokenUsage(ctx context.Context, conversationID pgtype.UUID) ([]*TokenUsage, error) {
+	rows, err := q.db.Query(ctx, getConversationTokenUsage, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*TokenUsage{}
+	for rows.Next() {
+		var i TokenUsage
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.ConversationID,
+			&i.Model,
+			&i.InputTokens,
+			&i.OutputTokens,
+			&i.TotalTokens,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversations = `-- name: GetConversations :many
+SELECT id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id FROM conversation
+WHERE customer_id = $1
+ORDER BY updated_at DESC
+`
+
+// GetConversations
+//
+//	SELECT id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id FROM conversation
+//	WHERE customer_id = $1
+//	ORDER BY updated_at DESC
+func (q *Queries) GetConversations(ctx context.Context, customerID uuid.UUID) ([]*Conversation, error) {
+	rows, err := q.db.Query(ctx, getConversations, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Conversation{}
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.Title,
+			&i.ConversationType,
+			&i.SystemMessage,
+			&i.Metadata,
+			&i.HasError,
+			&i.ErrorMessage,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CurrLlmID,
+			&i.Summary,
+			&i.SummaryIndex,
+			&i.ActiveMessageID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationsWithCount = `-- name: GetConversationsWithCount :many
+SELECT c.id, c.customer_id, c.title, c.conversation_type, c.system_message, c.metadata, c.has_error, c.error_message, c.created_at, c.updated_at, c.curr_llm_id, c.summary, c.summary_index, c.active_message_id, COUNT(cm.id) AS message_count
+FROM conversation c
+JOIN conversation_message cm
+ON c.id = cm.conversation_id
+WHERE c.customer_id = $1
+GROUP BY c.id
+ORDER BY c.updated_at DESC
+`
+
+type GetConversationsWithCountRow struct {
+	ID               uuid.UUID          `db:"id" json:"id"`
+	CustomerID       uuid.UUID          `db:"customer_id" json:"customerId"`
+	Title            string             `db:"title" json:"title"`
+	ConversationType string             `db:"conversation_type" json:"conversationType"`
+	SystemMessage    string             `db:"system_message" json:"systemMessage"`
+	Metadata         []byte             `db:"metadata" json:"metadata"`
+	HasError         bool               `db:"has_error" json:"hasError"`
+	ErrorMessage     *string            `db:"error_message" json:"errorMessage"`
+	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	UpdatedAt        pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+	CurrLlmID        pgtype.UUID        `db:"curr_llm_id" json:"currLlmId"`
+	Summary          string             `db:"summary" json:"summary"`
+	SummaryIndex     int32              `db:"summary_index" json:"summaryIndex"`
+	ActiveMessageID  pgtype.UUID        `db:"active_message_id" json:"activeMessageId"`
+	MessageCount     int64              `db:"message_count" json:"messageCount"`
+}
+
+// GetConversationsWithCount
+//
+//	SELECT c.id, c.customer_id, c.title, c.conversation_type, c.system_message, c.metadata, c.has_error, c.error_message, c.created_at, c.updated_at, c.curr_llm_id, c.summary, c.summary_index, c.active_message_id, COUNT(cm.id) AS message_count
+//	FROM conversation c
+//	JOIN conversation_message cm
+//	ON c.id = cm.conversation_id
+//	WHERE c.customer_id = $1
+//	GROUP BY c.id
+//	ORDER BY c.updated_at DESC
+func (q *Queries) GetConversationsWithCount(ctx context.Context, customerID uuid.UUID) ([]*GetConversationsWithCountRow, error) {
+	rows, err := q.db.Query(ctx, getConversationsWithCount, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetConversationsWithCountRow{}
+	for rows.Next() {
+		var i GetConversationsWithCountRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.Title,
+			&i.ConversationType,
+			&i.SystemMessage,
+			&i.Metadata,
+			&i.HasError,
+			&i.ErrorMessage,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CurrLlmID,
+			&i.Summary,
+			&i.SummaryIndex,
+			&i.ActiveMessageID,
+			&i.MessageCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCustomer = `-- name: GetCustomer :one
+SELECT id, name, datastore, created_at, updated_at, is_admin FROM customer
+WHERE id = $1 LIMIT 1
+`
+
+// GetCustomer
+//
+//	SELECT id, name, datastore, created_at, updated_at, is_admin FROM customer
+//	WHERE id = $1 LIMIT 1
+func (q *Queries) GetCustomer(ctx context.Context, id uuid.UUID) (*Customer, error) {
+	row := q.db.QueryRow(ctx, getCustomer, id)
+	var i Customer
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Datastore,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsAdmin,
+	)
+	return &i, err
+}
+
+const getCustomerByName = `-- name: GetCustomerByName :one
+SELECT id, name, datastore, created_at, updated_at, is_admin FROM customer
+WHERE name = $1 LIMIT 1
+`
+
+// GetCustomerByName
+//
+//	SELECT id, name, datastore, created_at, updated_at, is_admin FROM customer
+//	WHERE name = $1 LIMIT 1
+func (q *Queries) GetCustomerByName(ctx context.Context, name string) (*Customer, error) {
+	row := q.db.QueryRow(ctx, getCustomerByName, name)
+	var i Customer
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Datastore,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsAdmin,
+	)
+	return &i, err
+}
+
+const getCustomerChatLLM = `-- name: GetCustomerChatLLM :one
+WITH RequiredLLM AS (
+    -- First, try to find a customer-specific llm from the configurations
+    SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at FROM customer_llm_configurations clc
+    JOIN llm ON llm.id = clc.chat_llm_id
+    WHERE clc.customer_id = $1
+
+    UNION ALL
+
+    -- Fallback to a global default if no customer-specific default is found
+    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
+    WHERE llm.customer_id IS NULL AND llm.is_default = true
+    AND NOT EXISTS (
+        SELECT 1
+        FROM customer_llm_configurations clc
+        JOIN llm ON llm.id = clc.chat_llm_id
+        WHERE clc.customer_id = $1
+    )
+)
+SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
+FROM RequiredLLM llm
+INNER JOIN available_model am ON am.id = llm.model
+LIMIT 1
+`
+
+type GetCustomerChatLLMRow struct {
+	Llm            Llm            `db:"llm" json:"llm"`
+	AvailableModel AvailableModel `db:"available_model" json:"availableModel"`
+}
+
+// GetCustomerChatLLM
+//
+//	WITH RequiredLLM AS (
+//	    -- First, try to find a customer-specific llm from the configurations
+//	    SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at FROM customer_llm_configurations clc
+//	    JOIN llm ON llm.id = clc.chat_llm_id
+//	    WHERE clc.customer_id = $1
+//
+//	    UNION ALL
+//
+//	    -- Fallback to a global default if no customer-specific default is found
+//	    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
+//	    WHERE llm.customer_id IS NULL AND llm.is_default = true
+//	    AND NOT EXISTS (
+//	        SELECT 1
+//	        FROM customer_llm_configurations clc
+//	        JOIN llm ON llm.id = clc.chat_llm_id
+//	        WHERE clc.customer_id = $1
+//	    )
+//	)
+//	SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
+//	FROM RequiredLLM llm
+//	INNER JOIN available_model am ON am.id = llm.model
+//	LIMIT 1
+func (q *Queries) GetCustomerChatLLM(ctx context.Context, customerID uuid.UUID) (*GetCustomerChatLLMRow, error) {
+	row := q.db.QueryRow(ctx, getCustomerChatLLM, customerID)
+	var i GetCustomerChatLLMRow
+	err := row.Scan(
+		&i.Llm.ID,
+		&i.Llm.CustomerID,
+		&i.Llm.Title,
+		&i.Llm.Color,
+		&i.Llm.Model,
+		&i.Llm.Temperature,
+		&i.Llm.Instructions,
+		&i.Llm.IsDefault,
+		&i.Llm.Public,
+		&i.Llm.CreatedAt,
+		&i.Llm.UpdatedAt,
+		&i.AvailableModel.ID,
+		&i.AvailableModel.Provider,
+		&i.AvailableModel.DisplayName,
+		&i.AvailableModel.Description,
+		&i.AvailableModel.InputTokenLimit,
+		&i.AvailableModel.OutputTokenLimit,
+		&i.AvailableModel.Currency,
+		&i.AvailableModel.InputCostPerMillionTokens,
+		&i.AvailableModel.OutputCostPerMillionTokens,
+		&i.AvailableModel.DepreciatedWarning,
+		&i.AvailableModel.IsDepreciated,
+		&i.AvailableModel.CreatedAt,
+		&i.AvailableModel.UpdatedAt,
+		&i.AvailableModel.IsVisible,
+	)
+	return &i, err
+}
+
+const getCustomerLLMConfigurations = `-- name: GetCustomerLLMConfigurations :one
+SELECT customer_id, summary_llm_id, chat_llm_id, created_at, updated_at FROM customer_llm_configurations
+WHERE customer_id = $1
+`
+
+// GetCustomerLLMConfigurations
+//
+//	SELECT customer_id, summary_llm_id, chat_llm_id, created_at, updated_at FROM customer_llm_configurations
+//	WHERE customer_id = $1
+func (q *Queries) GetCustomerLLMConfigurations(ctx context.Context, customerID uuid.UUID) (*CustomerLlmConfiguration, error) {
+	row := q.db.QueryRow(ctx, getCustomerLLMConfigurations, customerID)
+	var i CustomerLlmConfiguration
+	err := row.Scan(
+		&i.CustomerID,
+		&i.SummaryLlmID,
+		&i.ChatLlmID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const getCustomerSummaryLLM = `-- name: GetCustomerSummaryLLM :one
+WITH RequiredLLM AS (
+    -- First, try to find a customer-specific llm from the configurations
+    SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at FROM customer_llm_configurations clc
+    JOIN llm ON llm.id = clc.summary_llm_id
+    WHERE clc.customer_id = $1
+
+    UNION ALL
+
+    -- Fallback to a global default if no customer-specific default is found
+    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
+    WHERE llm.customer_id IS NULL AND llm.is_default = true
+    AND NOT EXISTS (
+        SELECT 1
+        FROM customer_llm_configurations clc
+        JOIN llm ON llm.id = clc.summary_llm_id
+        WHERE clc.customer_id = $1
+    )
+)
+SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
+FROM RequiredLLM llm
+INNER JOIN available_model am ON am.id = llm.model
+LIMIT 1
+`
+
+type GetCustomerSummaryLLMRow struct {
+	Llm            Llm            `db:"llm" json:"llm"`
+	AvailableModel AvailableModel `db:"available_model" json:"availableModel"`
+}
+
+// GetCustomerSummaryLLM
+//
+//	WITH RequiredLLM AS (
+//	    -- First, try to find a customer-specific llm from the configurations
+//	    SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at FROM customer_llm_configurations clc
+//	    JOIN llm ON llm.id = clc.summary_llm_id
+//	    WHERE clc.customer_id = $1
+//
+//	    UNION ALL
+//
+//	    -- Fallback to a global default if no customer-specific default is found
+//	    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
+//	    WHERE llm.customer_id IS NULL AND llm.is_default = true
+//	    AND NOT EXISTS (
+//	        SELECT 1
+//	        FROM customer_llm_configurations clc
+//	        JOIN llm ON llm.id = clc.summary_llm_id
+//	        WHERE clc.customer_id = $1
+//	    )
+//	)
+//	SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
+//	FROM RequiredLLM llm
+//	INNER JOIN available_model am ON am.id = llm.model
+//	LIMIT 1
+func (q *Queries) GetCustomerSummaryLLM(ctx context.Context, customerID uuid.UUID) (*GetCustomerSummaryLLMRow, error) {
+	row := q.db.QueryRow(ctx, getCustomerSummaryLLM, customerID)
+	var i GetCustomerSummaryLLMRow
+	err := row.Scan(
+		&i.Llm.ID,
+		&i.Llm.CustomerID,
+		&i.Llm.Title,
+		&i.Llm.Color,
+		&i.Llm.Model,
+		&i.Llm.Temperature,
+		&i.Llm.Instructions,
+		&i.Llm.IsDefault,
+		&i.Llm.Public,
+		&i.Llm.CreatedAt,
+		&i.Llm.UpdatedAt,
+		&i.AvailableModel.ID,
+		&i.AvailableModel.Provider,
+		&i.AvailableModel.DisplayName,
+		&i.AvailableModel.Description,
+		&i.AvailableModel.InputTokenLimit,
+		&i.AvailableModel.OutputTokenLimit,
+		&i.AvailableModel.Currency,
+		&i.AvailableModel.InputCostPerMillionTokens,
+		&i.AvailableModel.OutputCostPerMillionTokens,
+		&i.AvailableModel.DepreciatedWarning,
+		&i.AvailableModel.IsDepreciated,
+		&i.AvailableModel.CreatedAt,
+		&i.AvailableModel.UpdatedAt,
+		&i.AvailableModel.IsVisible,
+	)
+	return &i, err
+}
+
+const getCustomerTokenUsage = `-- name: GetCustomerTokenUsage :many
+SELECT id, customer_id, conversation_id, model, input_tokens, output_tokens, total_tokens, created_at FROM token_usage
+WHERE customer_id = $1
+  AND (model = $2 OR $2 = '')
+ORDER BY created_at DESC, id DESC
+LIMIT $3 OFFSET ($4::int - 1) * $3
+`
+
+type GetCustomerTokenUsageParams struct {
+	CustomerID uuid.UUID `db:"customer_id" json:"customerId"`
+	Model      string    `db:"model" json:"model"`
+	Limit      int32     `db:"limit" json:"limit"`
+	Column4    int32     `db:"column_4" json:"column4"`
+}
+
+// GetCustomerTokenUsage
+//
+//	SELECT id, customer_id, conversation_id, model, input_tokens, output_tokens, total_tokens, created_at FROM token_usage
+//	WHERE customer_id = $1
+//	  AND (model = $2 OR $2 = '')
+//	ORDER BY created_at DESC, id DESC
+//	LIMIT $3 OFFSET ($4::int - 1) * $3
+func (q *Queries) GetCustomerTokenUsage(ctx context.Context, arg *GetCustomerTokenUsageParams) ([]*TokenUsage, error) {
+	rows, err := q.db.Query(ctx, getCustomerTokenUsage,
+		arg.CustomerID,
+		arg.Model,
+		arg.Limit,
+		arg.Column4,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*TokenUsage{}
+	for rows.Next() {
+		var i TokenUsage
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.ConversationID,
+			&i.Model,
+			&i.InputTokens,
+			&i.OutputTokens,
+			&i.TotalTokens,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCustomerTokensPageCount = `-- name: GetCustomerTokensPageCount :one
+SELECT
+    CEIL(COUNT(*)::FLOAT / $3) as max_pages
+FROM token_usage
+WHERE customer_id = $1
+AND (model = $2 OR $2 = '')
+`
+
+type GetCustomerTokensPageCountParams struct {
+	CustomerID uuid.UUID   `db:"customer_id" json:"customerId"`
+	Model      string      `db:"model" json:"model"`
+	Column3    interface{} `db:"column_3" json:"column3"`
+}
+
+// GetCustomerTokensPageCount
+//
+//	SELECT
+//	    CEIL(COUNT(*)::FLOAT / $3) as max_pages
+//	FROM token_usage
+//	WHERE customer_id = $1
+//	AND (model = $2 OR $2 = '')
+func (q *Queries) GetCustomerTokensPageCount(ctx context.Context, arg *GetCustomerTokensPageCountParams) (float64, error) {
+	row := q.db.QueryRow(ctx, getCustomerTokensPageCount, arg.CustomerID, arg.Model, arg.Column3)
+	var max_pages float64
+	err := row.Scan(&max_pages)
+	return max_pages, err
+}
+
+const getCustomerToolConfiguration = `-- name: GetCustomerToolConfiguration :one
+-- the configuration of the conversation type, or the configuration of every type
+SELECT id, customer_id, conversation_type, enabled_tools, max_depth, created_at, updated_at FROM customer_tool_configuration
+WHERE customer_id = $1
+AND (conversation_type = $2 OR conversation_type = '')
+ORDER BY conversation_type DESC
+LIMIT 1
+`
+
+type GetCustomerToolConfigurationParams struct {
+	CustomerID       uuid.UUID `db:"customer_id" json:"customerId"`
+	ConversationType string    `db:"conversation_type" json:"conversationType"`
+}
+
+// GetCustomerToolConfiguration
+//
+//	-- the configuration of the conversation type, or the configuration of every type
+//	SELECT id, customer_id, conversation_type, enabled_tools, max_depth, created_at, updated_at FROM customer_tool_configuration
+//	WHERE customer_id = $1
+//	AND (conversation_type = $2 OR conversation_type = '')
+//	ORDER BY conversation_type DESC
+//	LIMIT 1
+func (q *Queries) GetCustomerToolConfiguration(ctx context.Context, arg *GetCustomerToolConfigurationParams) (*CustomerToolConfiguration, error) {
+	row := q.db.QueryRow(ctx, getCustomerToolConfiguration, arg.CustomerID, arg.ConversationType)
+	var i CustomerToolConfiguration
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.ConversationType,
+		&i.EnabledTools,
+		&i.MaxDepth,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const getCustomerToolConfigurations = `-- name: GetCustomerToolConfigurations :many
+SELECT id, customer_id, conversation_type, enabled_tools, max_depth, created_at, updated_at FROM customer_tool_configuration
+WHERE customer_id = $1
+ORDER BY conversation_type
+`
+
+// GetCustomerToolConfigurations
+//
+//	SELECT id, customer_id, conversation_type, enabled_tools, max_depth, created_at, updated_at FROM customer_tool_configuration
+//	WHERE customer_id = $1
+//	ORDER BY conversation_type
+func (q *Queries) GetCustomerToolConfigurations(ctx context.Context, customerID uuid.UUID) ([]*CustomerToolConfiguration, error) {
+	rows, err := q.db.Query(ctx, getCustomerToolConfigurations, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*CustomerToolConfiguration{}
+	for rows.Next() {
+		var i CustomerToolConfiguration
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.ConversationType,
+			&i.EnabledTools,
+			&i.MaxDepth,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCustomerUsageGrouped = `-- name: GetCustomerUsageGrouped :many
+SELECT
+    tu.model AS model,
+    SUM(tu.input_tokens) AS input_tokens_sum,
+    SUM(tu.output_tokens) AS output_tokens_sum,
+    SUM(tu.total_tokens) AS total_tokens_sum,
+    am.input_cost_per_million_tokens,
+    am.output_cost_per_million_tokens
+FROM token_usage tu
+JOIN available_model am ON tu.model = am.id
+WHERE tu.customer_id = $1
+GROUP BY tu.model, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens
+`
+
+type GetCustomerUsageGroupedRow struct {
+	Model                      string         `db:"model" json:"model"`
+	InputTokensSum             int64          `db:"input_tokens_sum" json:"inputTokensSum"`
+	OutputTokensSum            int64          `db:"output_tokens_sum" json:"outputTokensSum"`
+	TotalTokensSum             int64          `db:"total_tokens_sum" json:"totalTokensSum"`
+	InputCostPerMillionTokens  pgtype.Numeric `db:"input_cost_per_million_tokens" json:"inputCostPerMillionTokens"`
+	OutputCostPerMillionTokens pgtype.Numeric `db:"output_cost_per_million_tokens" json:"outputCostPerMillionTokens"`
+}
+
+// GetCustomerUsageGrouped
+//
+//	SELECT
+//	    tu.model AS model,
+//	    SUM(tu.input_tokens) AS input_tokens_sum,
+//	    SUM(tu.output_tokens) AS output_tokens_sum,
+//	    SUM(tu.total_tokens) AS total_tokens_sum,
+//	    am.input_cost_per_million_tokens,
+//	    am.output_cost_per_million_tokens
+//	FROM token_usage tu
+//	JOIN available_model am ON tu.model = am.id
+//	WHERE tu.customer_id = $1
+//	GROUP BY tu.model, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens
+func (q *Queries) GetCustomerUsageGrouped(ctx context.Context, customerID uuid.UUID) ([]*GetCustomerUsageGroupedRow, error) {
+	rows, err := q.db.Query(ctx, getCustomerUsageGrouped, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetCustomerUsageGroupedRow{}
+	for rows.Next() {
+		var i GetCustomerUsageGroupedRow
+		if err := rows.Scan(
+			&i.Model,
+			&i.InputTokensSum,
+			&i.OutputTokensSum,
+			&i.TotalTokensSum,
+			&i.InputCostPerMillionTokens,
+			&i.OutputCostPerMillionTokens,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCustomerVectorizeJobs = `-- name: GetCustomerVectorizeJobs :many
+WITH latest_vji AS (
+    SELECT 
+        vji.id, vji.job_id, vji.status, vji.message, vji.error, vji.created_at, vji.updated_at, 
+        ROW_NUMBER() OVER (PARTITION BY vji.job_id ORDER BY vji.created_at DESC) AS rn
+    FROM 
+        vectorize_job_item vji
+)
+SELECT 
+    vj.id, vj.customer_id, vj.documents, vj.websites, vj.created_at, vj.updated_at, 
+    vji.status, 
+    vji.message, 
+    vji.error
+FROM 
+    vectorize_job vj
+LEFT JOIN 
+    latest_vji vji 
+    ON vj.id = vji.job_id 
+    AND vji.rn = 1
+WHERE 
+    vj.customer_id = $1
+ORDER BY 
+    vj.created_at DESC
+`
+
+type GetCustomerVectorizeJobsRow struct {
+	ID         uuid.UUID              `db:"id" json:"id"`
+	CustomerID uuid.UUID              `db:"customer_id" json:"customerId"`
+	Documents  bool                   `db:"documents" json:"documents"`
+	Websites   bool                   `db:"websites" json:"websites"`
+	CreatedAt  pgtype.Timestamptz     `db:"created_at" json:"createdAt"`
+	UpdatedAt  pgtype.Timestamptz     `db:"updated_at" json:"updatedAt"`
+	Status     NullVectorizeJobStatus `db:"status" json:"status"`
+	Message    *string                `db:"message" json:"message"`
+	Error      *string                `db:"error" json:"error"`
+}
+
+// GetCustomerVectorizeJobs
+//
+//	WITH latest_vji AS (
+//	    SELECT
+//	        vji.id, vji.job_id, vji.status, vji.message, vji.error, vji.created_at, vji.updated_at,
+//	        ROW_NUMBER() OVER (PARTITION BY vji.job_id ORDER BY vji.created_at DESC) AS rn
+//	    FROM
+//	        vectorize_job_item vji
+//	)
+//	SELECT
+//	    vj.id, vj.customer_id, vj.documents, vj.websites, vj.created_at, vj.updated_at,
+//	    vji.status,
+//	    vji.message,
+//	    vji.error
+//	FROM
+//	    vectorize_job vj
+//	LEFT JOIN
+//	    latest_vji vji
+//	    ON vj.id = vji.job_id
+//	    AND vji.rn = 1
+//	WHERE
+//	    vj.customer_id = $1
+//	ORDER BY
+//	    vj.created_at DESC
+func (q *Queries) GetCustomerVectorizeJobs(ctx context.Context, customerID uuid.UUID) ([]*GetCustomerVectorizeJobsRow, error) {
+	rows, err := q.db.Query(ctx, getCustomerVectorizeJobs, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetCustomerVectorizeJobsRow{}
+	for rows.Next() {
+		var i GetCustomerVectorizeJobsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.Documents,
+			&i.Websites,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Status,
+			&i.Message,
+			&i.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDefaultLLM = `-- name: GetDefaultLLM :one
+WITH RequiredLLM AS (
+    -- First, try to find a customer-specific default if customer_id is provided
+    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
+    WHERE llm.customer_id = $1 AND llm.is_default = true
+
+    UNION ALL
+
+    -- Fallback to a global default if no customer-specific default is found
+    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
+    WHERE llm.customer_id IS NULL AND llm.is_default = true
+    AND NOT EXISTS (
+        SELECT 1
+        FROM llm
+        WHERE llm.customer_id = $1 AND llm.is_default = true
+    )
+)
+SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
+FROM RequiredLLM llm
+INNER JOIN available_model am ON am.id = llm.model
+LIMIT 1
+`
+
+type GetDefaultLLMRow struct {
+	Llm            Llm            `db:"llm" json:"llm"`
+	AvailableModel AvailableModel `db:"available_model" json:"availableModel"`
+}
+
+// GetDefaultLLM
+//
+//	WITH RequiredLLM AS (
+//	    -- First, try to find a customer-specific default if customer_id is provided
+//	    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
+//	    WHERE llm.customer_id = $1 AND llm.is_default = true
+//
+//	    UNION ALL
+//
+//	    -- Fallback to a global default if no customer-specific default is found
 //	    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
 //	    WHERE llm.customer_id IS NULL AND llm.is_default = true
 //	    AND NOT EXISTS (
 //	        SELECT 1
-//	        FROM customer_llm_configurations clc
-//	        JOIN llm ON llm.id = clc.chat_llm_id
-//	        WHERE clc.customer_id = $1
+//	        FROM llm
+//	        WHERE llm.customer_id = $1 AND llm.is_default = true
 //	    )
 //	)
 //	SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
 //	FROM RequiredLLM llm
 //	INNER JOIN available_model am ON am.id = llm.model
 //	LIMIT 1
-func (q *Queries) GetCustomerChatLLM(ctx context.Context, customerID uuid.UUID) (*GetCustomerChatLLMRow, error) {
-	row := q.db.QueryRow(ctx, getCustomerChatLLM, customerID)
-	var i GetCustomerChatLLMRow
+func (q *Queries) GetDefaultLLM(ctx context.Context, customerID pgtype.UUID) (*GetDefaultLLMRow, error) {
+	row := q.db.QueryRow(ctx, getDefaultLLM, customerID)
+	var i GetDefaultLLMRow
 	err := row.Scan(
 		&i.Llm.ID,
 		&i.Llm.CustomerID,
@@ -2275,160 +3801,415 @@ func (q *Queries) GetCustomerChatLLM(ctx context.Context, customerID uuid.UUID)
 	return &i, err
 }
 
-const getCustomerLLMConfigurations = `-- name: GetCustomerLLMConfigurations :one
-SELECT customer_id, summary_llm_id, chat_llm_id, created_at, updated_at FROM customer_llm_configurations
+const getDocument = `-- name: GetDocument :one
+SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
+WHERE id = $1 LIMIT 1
+`
+
+// GetDocument
+//
+//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
+//	WHERE id = $1 LIMIT 1
+func (q *Queries) GetDocument(ctx context.Context, id uuid.UUID) (*Document, error) {
+	row := q.db.QueryRow(ctx, getDocument, id)
+	var i Document
+	err := row.Scan(
+		&i.ID,
+		&i.ParentID,
+		&i.CustomerID,
+		&i.Filename,
+		&i.Type,
+		&i.SizeBytes,
+		&i.Sha256,
+		&i.Validated,
+		&i.DatastoreType,
+		&i.DatastoreID,
+		&i.Summary,
+		&i.SummarySha256,
+		&i.VectorSha256,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsAsset,
+		&i.Vectorize,
+		&i.SummaryFailedAt,
+	)
+	return &i, err
+}
+
+const getDocumentsByCustomer = `-- name: GetDocumentsByCustomer :many
+SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
+WHERE customer_id = $1 AND validated = true
+`
+
+// GetDocumentsByCustomer
+//
+//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
+//	WHERE customer_id = $1 AND validated = true
+func (q *Queries) GetDocumentsByCustomer(ctx context.Context, customerID uuid.UUID) ([]*Document, error) {
+	rows, err := q.db.Query(ctx, getDocumentsByCustomer, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Document{}
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.CustomerID,
+			&i.Filename,
+			&i.Type,
+			&i.SizeBytes,
+			&i.Sha256,
+			&i.Validated,
+			&i.DatastoreType,
+			&i.DatastoreID,
+			&i.Summary,
+			&i.SummarySha256,
+			&i.VectorSha256,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAsset,
+			&i.Vectorize,
+			&i.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDocumentsFromListIDs = `-- name: GetDocumentsFromListIDs :many
+SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at from document
+WHERE customer_id = $1
+AND id = ANY($2::uuid[])
+AND ($3::uuid[] IS NULL OR parent_id = ANY($3::uuid[]))
+`
+
+type GetDocumentsFromListIDsParams struct {
+	CustomerID uuid.UUID   `db:"customer_id" json:"customerId"`
+	Column2    []uuid.UUID `db:"column_2" json:"column2"`
+	Column3    []uuid.UUID `db:"column_3" json:"column3"`
+}
+
+// GetDocumentsFromListIDs
+//
+//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at from document
+//	WHERE customer_id = $1
+//	AND id = ANY($2::uuid[])
+//	AND ($3::uuid[] IS NULL OR parent_id = ANY($3::uuid[]))
+func (q *Queries) GetDocumentsFromListIDs(ctx context.Context, arg *GetDocumentsFromListIDsParams) ([]*Document, error) {
+	rows, err := q.db.Query(ctx, getDocumentsFromListIDs, arg.CustomerID, arg.Column2, arg.Column3)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Document{}
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.CustomerID,
+			&i.Filename,
+			&i.Type,
+			&i.SizeBytes,
+			&i.Sha256,
+			&i.Validated,
+			&i.DatastoreType,
+			&i.DatastoreID,
+			&i.Summary,
+			&i.SummarySha256,
+			&i.VectorSha256,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAsset,
+			&i.Vectorize,
+			&i.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDocumentsFromParent = `-- name: GetDocumentsFromParent :many
+SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
+WHERE parent_id = $1 AND validated = true
+`
+
+// GetDocumentsFromParent
+//
+//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
+//	WHERE parent_id = $1 AND validated = true
+func (q *Queries) GetDocumentsFromParent(ctx context.Context, parentID pgtype.UUID) ([]*Document, error) {
+	rows, err := q.db.Query(ctx, getDocumentsFromParent, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Document{}
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.CustomerID,
+			&i.Filename,
+			&i.Type,
+			&i.SizeBytes,
+			&i.Sha256,
+			&i.Validated,
+			&i.DatastoreType,
+			&i.DatastoreID,
+			&i.Summary,
+			&i.SummarySha256,
+			&i.VectorSha256,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAsset,
+			&i.Vectorize,
+			&i.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDocumentsOlderThan = `-- name: GetDocumentsOlderThan :many
+SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
 WHERE customer_id = $1
+AND updated_at < $2
 `
 
-// GetCustomerLLMConfigurations
+type GetDocumentsOlderThanParams struct {
+	CustomerID uuid.UUID          `db:"customer_id" json:"customerId"`
+	UpdatedAt  pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
+// GetDocumentsOlderThan
 //
-//	SELECT customer_id, summary_llm_id, chat_llm_id, created_at, updated_at FROM customer_llm_configurations
+//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
 //	WHERE customer_id = $1
-func (q *Queries) GetCustomerLLMConfigurations(ctx context.Context, customerID uuid.UUID) (*CustomerLlmConfiguration, error) {
-	row := q.db.QueryRow(ctx, getCustomerLLMConfigurations, customerID)
-	var i CustomerLlmConfiguration
+//	AND updated_at < $2
+func (q *Queries) GetDocumentsOlderThan(ctx context.Context, arg *GetDocumentsOlderThanParams) ([]*Document, error) {
+	rows, err := q.db.Query(ctx, getDocumentsOlderThan, arg.CustomerID, arg.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Document{}
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.CustomerID,
+			&i.Filename,
+			&i.Type,
+			&i.SizeBytes,
+			&i.Sha256,
+			&i.Validated,
+			&i.DatastoreType,
+			&i.DatastoreID,
+			&i.Summary,
+			&i.SummarySha256,
+			&i.VectorSha256,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAsset,
+			&i.Vectorize,
+			&i.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDocumentsToSummarize = `-- name: GetDocumentsToSummarize :many
+SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
+WHERE validated = true
+  AND is_asset = false
+  AND (
+    summary_sha_256 != sha_256
+    -- summaries created before the retrieval layer only need to be embedded
+    OR NOT EXISTS (SELECT 1 FROM document_summary_vector v WHERE v.document_id = document.id)
+  )
+  AND (summary_failed_at IS NULL OR summary_failed_at < $1)
+ORDER BY updated_at ASC
+LIMIT $2
+`
+
+type GetDocumentsToSummarizeParams struct {
+	SummaryFailedAt pgtype.Timestamptz `db:"summary_failed_at" json:"summaryFailedAt"`
+	Limit           int32              `db:"limit" json:"limit"`
+}
+
+// GetDocumentsToSummarize
+//
+//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
+//	WHERE validated = true
+//	  AND is_asset = false
+//	  AND (
+//	    summary_sha_256 != sha_256
+//	    -- summaries created before the retrieval layer only need to be embedded
+//	    OR NOT EXISTS (SELECT 1 FROM document_summary_vector v WHERE v.document_id = document.id)
+//	  )
+//	  AND (summary_failed_at IS NULL OR summary_failed_at < $1)
+//	ORDER BY updated_at ASC
+//	LIMIT $2
+func (q *Queries) GetDocumentsToSummarize(ctx context.Context, arg *GetDocumentsToSummarizeParams) ([]*Document, error) {
+	rows, err := q.db.Query(ctx, getDocumentsToSummarize, arg.SummaryFailedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Document{}
+	for rows.Next() {
+		var i Document
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.CustomerID,
+			&i.Filename,
+			&i.Type,
+			&i.SizeBytes,
+			&i.Sha256,
+			&i.Validated,
+			&i.DatastoreType,
+			&i.DatastoreID,
+			&i.Summary,
+			&i.SummarySha256,
+			&i.VectorSha256,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAsset,
+			&i.Vectorize,
+			&i.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeed = `-- name: GetFeed :one
+SELECT id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at FROM feed
+WHERE id = $1
+`
+
+// GetFeed
+//
+//	SELECT id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at FROM feed
+//	WHERE id = $1
+func (q *Queries) GetFeed(ctx context.Context, id uuid.UUID) (*Feed, error) {
+	row := q.db.QueryRow(ctx, getFeed, id)
+	var i Feed
 	err := row.Scan(
+		&i.ID,
 		&i.CustomerID,
-		&i.SummaryLlmID,
-		&i.ChatLlmID,
+		&i.Url,
+		&i.Title,
+		&i.FeedType,
+		&i.FetchArticle,
+		&i.PollIntervalMinutes,
+		&i.LastPolledAt,
+		&i.LastError,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return &i, err
 }
 
-const getCustomerSummaryLLM = `-- name: GetCustomerSummaryLLM :one
-WITH RequiredLLM AS (
-    -- First, try to find a customer-specific llm from the configurations
-    SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at FROM customer_llm_configurations clc
-    JOIN llm ON llm.id = clc.summary_llm_id
-    WHERE clc.customer_id = $1
-
-    UNION ALL
-
-    -- Fallback to a global default if no customer-specific default is found
-    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
-    WHERE llm.customer_id IS NULL AND llm.is_default = true
-    AND NOT EXISTS (
-        SELECT 1
-        FROM customer_llm_configurations clc
-        JOIN llm ON llm.id = clc.summary_llm_id
-        WHERE clc.customer_id = $1
-    )
-)
-SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
-FROM RequiredLLM llm
-INNER JOIN available_model am ON am.id = llm.model
-LIMIT 1
+const getFeedItem = `-- name: GetFeedItem :one
+SELECT id, customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata, vector_sha_256, created_at, updated_at FROM feed_item
+WHERE id = $1
 `
 
-type GetCustomerSummaryLLMRow struct {
-	Llm            Llm            `db:"llm" json:"llm"`
-	AvailableModel AvailableModel `db:"available_model" json:"availableModel"`
-}
-
-// GetCustomerSummaryLLM
-//
-//	WITH RequiredLLM AS (
-//	    -- First, try to find a customer-specific llm from the configurations
-//	    SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at FROM customer_llm_configurations clc
-//	    JOIN llm ON llm.id = clc.summary_llm_id
-//	    WHERE clc.customer_id = $1
+// GetFeedItem
 //
-//	    UNION ALL
-//
-//	    -- Fallback to a global default if no customer-specific default is found
-//	    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
-//	    WHERE llm.customer_id IS NULL AND llm.is_default = true
-//	    AND NOT EXISTS (
-//	        SELECT 1
-//	        FROM customer_llm_configurations clc
-//	        JOIN llm ON llm.id = clc.summary_llm_id
-//	        WHERE clc.customer_id = $1
-//	    )
-//	)
-//	SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
-//	FROM RequiredLLM llm
-//	INNER JOIN available_model am ON am.id = llm.model
-//	LIMIT 1
-func (q *Queries) GetCustomerSummaryLLM(ctx context.Context, customerID uuid.UUID) (*GetCustomerSummaryLLMRow, error) {
-	row := q.db.QueryRow(ctx, getCustomerSummaryLLM, customerID)
-	var i GetCustomerSummaryLLMRow
+//	SELECT id, customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata, vector_sha_256, created_at, updated_at FROM feed_item
+//	WHERE id = $1
+func (q *Queries) GetFeedItem(ctx context.Context, id uuid.UUID) (*FeedItem, error) {
+	row := q.db.QueryRow(ctx, getFeedItem, id)
+	var i FeedItem
 	err := row.Scan(
-		&i.Llm.ID,
-		&i.Llm.CustomerID,
-		&i.Llm.Title,
-		&i.Llm.Color,
-		&i.Llm.Model,
-		&i.Llm.Temperature,
-		&i.Llm.Instructions,
-		&i.Llm.IsDefault,
-		&i.Llm.Public,
-		&i.Llm.CreatedAt,
-		&i.Llm.UpdatedAt,
-		&i.AvailableModel.ID,
-		&i.AvailableModel.Provider,
-		&i.AvailableModel.DisplayName,
-		&i.AvailableModel.Description,
-		&i.AvailableModel.InputTokenLimit,
-		&i.AvailableModel.OutputTokenLimit,
-		&i.AvailableModel.Currency,
-		&i.AvailableModel.InputCostPerMillionTokens,
-		&i.AvailableModel.OutputCostPerMillionTokens,
-		&i.AvailableModel.DepreciatedWarning,
-		&i.AvailableModel.IsDepreciated,
-		&i.AvailableModel.CreatedAt,
-		&i.AvailableModel.UpdatedAt,
-		&i.AvailableModel.IsVisible,
+		&i.ID,
+		&i.CustomerID,
+		&i.FeedID,
+		&i.Guid,
+		&i.Title,
+		&i.Link,
+		&i.Author,
+		&i.PublishedAt,
+		&i.Content,
+		&i.Sha256,
+		&i.Metadata,
+		&i.VectorSha256,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return &i, err
 }
 
-const getCustomerTokenUsage = `-- name: GetCustomerTokenUsage :many
-SELECT id, customer_id, conversation_id, model, input_tokens, output_tokens, total_tokens, created_at FROM token_usage
-WHERE customer_id = $1
-  AND (model = $2 OR $2 = '')
-ORDER BY created_at DESC, id DESC
-LIMIT $3 OFFSET ($4::int - 1) * $3
+const getFeedItemsByFeed = `-- name: GetFeedItemsByFeed :many
+SELECT id, customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata, vector_sha_256, created_at, updated_at FROM feed_item
+WHERE feed_id = $1
+ORDER BY published_at DESC NULLS LAST
 `
 
-type GetCustomerTokenUsageParams struct {
-	CustomerID uuid.UUID `db:"customer_id" json:"customerId"`
-	Model      string    `db:"model" json:"model"`
-	Limit      int32     `db:"limit" json:"limit"`
-	Column4    int32     `db:"column_4" json:"column4"`
-}
-
-// GetCustomerTokenUsage
+// GetFeedItemsByFeed
 //
-//	SELECT id, customer_id, conversation_id, model, input_tokens, output_tokens, total_tokens, created_at FROM token_usage
-//	WHERE customer_id = $1
-//	  AND (model = $2 OR $2 = '')
-//	ORDER BY created_at DESC, id DESC
-//	LIMIT $3 OFFSET ($4::int - 1) * $3
-func (q *Queries) GetCustomerTokenUsage(ctx context.Context, arg *GetCustomerTokenUsageParams) ([]*TokenUsage, error) {
-	rows, err := q.db.Query(ctx, getCustomerTokenUsage,
-		arg.CustomerID,
-		arg.Model,
-		arg.Limit,
-		arg.Column4,
-	)
+//	SELECT id, customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata, vector_sha_256, created_at, updated_at FROM feed_item
+//	WHERE feed_id = $1
+//	ORDER BY published_at DESC NULLS LAST
+func (q *Queries) GetFeedItemsByFeed(ctx context.Context, feedID uuid.UUID) ([]*FeedItem, error) {
+	rows, err := q.db.Query(ctx, getFeedItemsByFeed, feedID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*TokenUsage{}
+	items := []*FeedItem{}
 	for rows.Next() {
-		var i TokenUsage
+		var i FeedItem
 		if err := rows.Scan(
 			&i.ID,
 			&i.CustomerID,
-			&i.ConversationID,
-			&i.Model,
-			&i.InputTokens,
-			&i.OutputTokens,
-			&i.TotalTokens,
+			&i.FeedID,
+			&i.Guid,
+			&i.Title,
+			&i.Link,
+			&i.Author,
+			&i.PublishedAt,
+			&i.Content,
+			&i.Sha256,
+			&i.Metadata,
+			&i.VectorSha256,
 			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -2440,86 +4221,41 @@ func (q *Queries) GetCustomerTokenUsage(ctx context.Context, arg *GetCustomerTok
 	return items, nil
 }
 
-const getCustomerTokensPageCount = `-- name: GetCustomerTokensPageCount :one
-SELECT
-    CEIL(COUNT(*)::FLOAT / $3) as max_pages
-FROM token_usage
-WHERE customer_id = $1
-AND (model = $2 OR $2 = '')
-`
-
-type GetCustomerTokensPageCountParams struct {
-	CustomerID uuid.UUID   `db:"customer_id" json:"customerId"`
-	Model      string      `db:"model" json:"model"`
-	Column3    interface{} `db:"column_3" json:"column3"`
-}
-
-// GetCustomerTokensPageCount
-//
-//	SELECT
-//	    CEIL(COUNT(*)::FLOAT / $3) as max_pages
-//	FROM token_usage
-//	WHERE customer_id = $1
-//	AND (model = $2 OR $2 = '')
-func (q *Queries) GetCustomerTokensPageCount(ctx context.Context, arg *GetCustomerTokensPageCountParams) (float64, error) {
-	row := q.db.QueryRow(ctx, getCustomerTokensPageCount, arg.CustomerID, arg.Model, arg.Column3)
-	var max_pages float64
-	err := row.Scan(&max_pages)
-	return max_pages, err
-}
-
-const getCustomerUsageGrouped = `-- name: GetCustomerUsageGrouped :many
-SELECT
-    tu.model AS model,
-    SUM(tu.input_tokens) AS input_tokens_sum,
-    SUM(tu.output_tokens) AS output_tokens_sum,
-    SUM(tu.total_tokens) AS total_tokens_sum,
-    am.input_cost_per_million_tokens,
-    am.output_cost_per_million_tokens
-FROM token_usage tu
-JOIN available_model am ON tu.model = am.id
-WHERE tu.customer_id = $1
-GROUP BY tu.model, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens
+const getFeedItemsNotVectorized = `-- name: GetFeedItemsNotVectorized :many
+SELECT id, customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata, vector_sha_256, created_at, updated_at FROM feed_item
+WHERE feed_id = $1
+AND sha_256 != vector_sha_256
 `
 
-type GetCustomerUsageGroupedRow struct {
-	Model                      string         `db:"model" json:"model"`
-	InputTokensSum             int64          `db:"input_tokens_sum" json:"inputTokensSum"`
-	OutputTokensSum            int64          `db:"output_tokens_sum" json:"outputTokensSum"`
-	TotalTokensSum             int64          `db:"total_tokens_sum" json:"totalTokensSum"`
-	InputCostPerMillionTokens  pgtype.Numeric `db:"input_cost_per_million_tokens" json:"inputCostPerMillionTokens"`
-	OutputCostPerMillionTokens pgtype.Numeric `db:"output_cost_per_million_tokens" json:"outputCostPerMillionTokens"`
-}
-
-// GetCustomerUsageGrouped
+// GetFeedItemsNotVectorized
 //
-//	SELECT
-//	    tu.model AS model,
-//	    SUM(tu.input_tokens) AS input_tokens_sum,
-//	    SUM(tu.output_tokens) AS output_tokens_sum,
-//	    SUM(tu.total_tokens) AS total_tokens_sum,
-//	    am.input_cost_per_million_tokens,
-//	    am.output_cost_per_million_tokens
-//	FROM token_usage tu
-//	JOIN available_model am ON tu.model = am.id
-//	WHERE tu.customer_id = $1
-//	GROUP BY tu.model, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens
-func (q *Queries) GetCustomerUsageGrouped(ctx context.Context, customerID uuid.UUID) ([]*GetCustomerUsageGroupedRow, error) {
-	rows, err := q.db.Query(ctx, getCustomerUsageGrouped, customerID)
+//	SELECT id, customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata, vector_sha_256, created_at, updated_at FROM feed_item
+//	WHERE feed_id = $1
+//	AND sha_256 != vector_sha_256
+func (q *Queries) GetFeedItemsNotVectorized(ctx context.Context, feedID uuid.UUID) ([]*FeedItem, error) {
+	rows, err := q.db.Query(ctx, getFeedItemsNotVectorized, feedID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*GetCustomerUsageGroupedRow{}
+	items := []*FeedItem{}
 	for rows.Next() {
-		var i GetCustomerUsageGroupedRow
+		var i FeedItem
 		if err := rows.Scan(
-			&i.Model,
-			&i.InputTokensSum,
-			&i.OutputTokensSum,
-			&i.TotalTokensSum,
-			&i.InputCostPerMillionTokens,
-			&i.OutputCostPerMillionTokens,
+			&i.ID,
+			&i.CustomerID,
+			&i.FeedID,
+			&i.Guid,
+			&i.Title,
+			&i.Link,
+			&i.Author,
+			&i.PublishedAt,
+			&i.Content,
+			&i.Sha256,
+			&i.Metadata,
+			&i.VectorSha256,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -2531,86 +4267,66 @@ func (q *Queries) GetCustomerUsageGrouped(ctx context.Context, customerID uuid.U
 	return items, nil
 }
 
-const getCustomerVectorizeJobs = `-- name: GetCustomerVectorizeJobs :many
-WITH latest_vji AS (
-    SELECT 
-        vji.id, vji.job_id, vji.status, vji.message, vji.error, vji.created_at, vji.updated_at, 
-        ROW_NUMBER() OVER (PARTITION BY vji.job_id ORDER BY vji.created_at DESC) AS rn
-    FROM 
-        vectorize_job_item vji
-)
-SELECT 
-    vj.id, vj.customer_id, vj.documents, vj.websites, vj.created_at, vj.updated_at, 
-    vji.status, 
-    vji.message, 
-    vji.error
-FROM 
-    vectorize_job vj
-LEFT JOIN 
-    latest_vji vji 
-    ON vj.id = vji.job_id 
-    AND vji.rn = 1
-WHERE 
-    vj.customer_id = $1
-ORDER BY 
-    vj.created_at DESC
-`
+const getFeedbackByConversationType = `-- name: GetFeedbackByConversationType :many
+SELECT
+    c.conversation_type,
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE f.rating = 1) AS positive,
+    COUNT(*) FILTER (WHERE f.rating = -1) AS negative,
+    COUNT(*) FILTER (WHERE f.comment != '') AS comments
+FROM conversation_message_feedback f
+JOIN conversation c ON c.id = f.conversation_id
+WHERE f.customer_id = $1
+AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+GROUP BY c.conversation_type
+ORDER BY total DESC
+`
+
+type GetFeedbackByConversationTypeParams struct {
+	CustomerID uuid.UUID          `db:"customer_id" json:"customerId"`
+	Column2    pgtype.Timestamptz `db:"column_2" json:"column2"`
+	Column3    pgtype.Timestamptz `db:"column_3" json:"column3"`
+}
 
-type GetCustomerVectorizeJobsRow struct {
-	ID         uuid.UUID              `db:"id" json:"id"`
-	CustomerID uuid.UUID              `db:"customer_id" json:"customerId"`
-	Documents  bool                   `db:"documents" json:"documents"`
-	Websites   bool                   `db:"websites" json:"websites"`
-	CreatedAt  pgtype.Timestamptz     `db:"created_at" json:"createdAt"`
-	UpdatedAt  pgtype.Timestamptz     `db:"updated_at" json:"updatedAt"`
-	Status     NullVectorizeJobStatus `db:"status" json:"status"`
-	Message    *string                `db:"message" json:"message"`
-	Error      *string                `db:"error" json:"error"`
+type GetFeedbackByConversationTypeRow struct {
+	ConversationType string `db:"conversation_type" json:"conversationType"`
+	Total            int64  `db:"total" json:"total"`
+	Positive         int64  `db:"positive" json:"positive"`
+	Negative         int64  `db:"negative" json:"negative"`
+	Comments         int64  `db:"comments" json:"comments"`
 }
 
-// GetCustomerVectorizeJobs
+// GetFeedbackByConversationType
 //
-//	WITH latest_vji AS (
-//	    SELECT
-//	        vji.id, vji.job_id, vji.status, vji.message, vji.error, vji.created_at, vji.updated_at,
-//	        ROW_NUMBER() OVER (PARTITION BY vji.job_id ORDER BY vji.created_at DESC) AS rn
-//	    FROM
-//	        vectorize_job_item vji
-//	)
 //	SELECT
-//	    vj.id, vj.customer_id, vj.documents, vj.websites, vj.created_at, vj.updated_at,
-//	    vji.status,
-//	    vji.message,
-//	    vji.error
-//	FROM
-//	    vectorize_job vj
-//	LEFT JOIN
-//	    latest_vji vji
-//	    ON vj.id = vji.job_id
-//	    AND vji.rn = 1
-//	WHERE
-//	    vj.customer_id = $1
-//	ORDER BY
-//	    vj.created_at DESC
-func (q *Queries) GetCustomerVectorizeJobs(ctx context.Context, customerID uuid.UUID) ([]*GetCustomerVectorizeJobsRow, error) {
-	rows, err := q.db.Query(ctx, getCustomerVectorizeJobs, customerID)
+//	    c.conversation_type,
+//	    COUNT(*) AS total,
+//	    COUNT(*) FILTER (WHERE f.rating = 1) AS positive,
+//	    COUNT(*) FILTER (WHERE f.rating = -1) AS negative,
+//	    COUNT(*) FILTER (WHERE f.comment != '') AS comments
+//	FROM conversation_message_feedback f
+//	JOIN conversation c ON c.id = f.conversation_id
+//	WHERE f.customer_id = $1
+//	AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+//	AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+//	GROUP BY c.conversation_type
+//	ORDER BY total DESC
+func (q *Queries) GetFeedbackByConversationType(ctx context.Context, arg *GetFeedbackByConversationTypeParams) ([]*GetFeedbackByConversationTypeRow, error) {
+	rows, err := q.db.Query(ctx, getFeedbackByConversationType, arg.CustomerID, arg.Column2, arg.Column3)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*GetCustomerVectorizeJobsRow{}
+	items := []*GetFeedbackByConversationTypeRow{}
 	for rows.Next() {
-		var i GetCustomerVectorizeJobsRow
+		var i GetFeedbackByConversationTypeRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.CustomerID,
-			&i.Documents,
-			&i.Websites,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Status,
-			&i.Message,
-			&i.Error,
+			&i.ConversationType,
+			&i.Total,
+			&i.Positive,
+			&i.Negative,
+			&i.Comments,
 		); err != nil {
 			return nil, err
 		}
@@ -2618,163 +4334,78 @@ func (q *Queries) GetCustomerVectorizeJobs(ctx context.Context, customerID uuid.
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
-	}
-	return items, nil
-}
-
-const getDefaultLLM = `-- name: GetDefaultLLM :one
-WITH RequiredLLM AS (
-    -- First, try to find a customer-specific default if customer_id is provided
-    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
-    WHERE llm.customer_id = $1 AND llm.is_default = true
-
-    UNION ALL
-
-    -- Fallback to a global default if no customer-specific default is found
-    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
-    WHERE llm.customer_id IS NULL AND llm.is_default = true
-    AND NOT EXISTS (
-        SELECT 1
-        FROM llm
-        WHERE llm.customer_id = $1 AND llm.is_default = true
-    )
-)
-SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
-FROM RequiredLLM llm
-INNER JOIN available_model am ON am.id = llm.model
-LIMIT 1
-`
-
-type GetDefaultLLMRow struct {
-	Llm            Llm            `db:"llm" json:"llm"`
-	AvailableModel AvailableModel `db:"available_model" json:"availableModel"`
-}
-
-// GetDefaultLLM
-//
-//	WITH RequiredLLM AS (
-//	    -- First, try to find a customer-specific default if customer_id is provided
-//	    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
-//	    WHERE llm.customer_id = $1 AND llm.is_default = true
-//
-//	    UNION ALL
-//
-//	    -- Fallback to a global default if no customer-specific default is found
-//	    SELECT id, customer_id, title, color, model, temperature, instructions, is_default, public, created_at, updated_at FROM llm
-//	    WHERE llm.customer_id IS NULL AND llm.is_default = true
-//	    AND NOT EXISTS (
-//	        SELECT 1
-//	        FROM llm
-//	        WHERE llm.customer_id = $1 AND llm.is_default = true
-//	    )
-//	)
-//	SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
-//	FROM RequiredLLM llm
-//	INNER JOIN available_model am ON am.id = llm.model
-//	LIMIT 1
-func (q *Queries) GetDefaultLLM(ctx context.Context, customerID pgtype.UUID) (*GetDefaultLLMRow, error) {
-	row := q.db.QueryRow(ctx, getDefaultLLM, customerID)
-	var i GetDefaultLLMRow
-	err := row.Scan(
-		&i.Llm.ID,
-		&i.Llm.CustomerID,
-		&i.Llm.Title,
-		&i.Llm.Color,
-		&i.Llm.Model,
-		&i.Llm.Temperature,
-		&i.Llm.Instructions,
-		&i.Llm.IsDefault,
-		&i.Llm.Public,
-		&i.Llm.CreatedAt,
-		&i.Llm.UpdatedAt,
-		&i.AvailableModel.ID,
-		&i.AvailableModel.Provider,
-		&i.AvailableModel.DisplayName,
-		&i.AvailableModel.Description,
-		&i.AvailableModel.InputTokenLimit,
-		&i.AvailableModel.OutputTokenLimit,
-		&i.AvailableModel.Currency,
-		&i.AvailableModel.InputCostPerMillionTokens,
-		&i.AvailableModel.OutputCostPerMillionTokens,
-		&i.AvailableModel.DepreciatedWarning,
-		&i.AvailableModel.IsDepreciated,
-		&i.AvailableModel.CreatedAt,
-		&i.AvailableModel.UpdatedAt,
-		&i.AvailableModel.IsVisible,
-	)
-	return &i, err
-}
-
-const getDocument = `-- name: GetDocument :one
-SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
-WHERE id = $1 LIMIT 1
-`
+	}
+	return items, nil
+}
 
-// GetDocument
-//
-//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
-//	WHERE id = $1 LIMIT 1
-func (q *Queries) GetDocument(ctx context.Context, id uuid.UUID) (*Document, error) {
-	row := q.db.QueryRow(ctx, getDocument, id)
-	var i Document
-	err := row.Scan(
-		&i.ID,
-		&i.ParentID,
-		&i.CustomerID,
-		&i.Filename,
-		&i.Type,
-		&i.SizeBytes,
-		&i.Sha256,
-		&i.Validated,
-		&i.DatastoreType,
-		&i.DatastoreID,
-		&i.Summary,
-		&i.SummarySha256,
-		&i.VectorSha256,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.IsAsset,
-		&i.Vectorize,
-	)
-	return &i, err
+const getFeedbackByLLM = `-- name: GetFeedbackByLLM :many
+SELECT
+    f.llm_id,
+    COALESCE(l.title, '')::text AS title,
+    COALESCE(l.model, '')::text AS model,
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE f.rating = 1) AS positive,
+    COUNT(*) FILTER (WHERE f.rating = -1) AS negative,
+    COUNT(*) FILTER (WHERE f.comment != '') AS comments
+FROM conversation_message_feedback f
+LEFT JOIN llm l ON l.id = f.llm_id
+WHERE f.customer_id = $1
+AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+GROUP BY f.llm_id, l.title, l.model
+ORDER BY total DESC
+`
+
+type GetFeedbackByLLMParams struct {
+	CustomerID uuid.UUID          `db:"customer_id" json:"customerId"`
+	Column2    pgtype.Timestamptz `db:"column_2" json:"column2"`
+	Column3    pgtype.Timestamptz `db:"column_3" json:"column3"`
 }
 
-const getDocumentsByCustomer = `-- name: GetDocumentsByCustomer :many
-SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
-WHERE customer_id = $1 AND validated = true
-`
+type GetFeedbackByLLMRow struct {
+	LlmID    pgtype.UUID `db:"llm_id" json:"llmId"`
+	Title    string      `db:"title" json:"title"`
+	Model    string      `db:"model" json:"model"`
+	Total    int64       `db:"total" json:"total"`
+	Positive int64       `db:"positive" json:"positive"`
+	Negative int64       `db:"negative" json:"negative"`
+	Comments int64       `db:"comments" json:"comments"`
+}
 
-// GetDocumentsByCustomer
+// GetFeedbackByLLM
 //
-//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
-//	WHERE customer_id = $1 AND validated = true
-func (q *Queries) GetDocumentsByCustomer(ctx context.Context, customerID uuid.UUID) ([]*Document, error) {
-	rows, err := q.db.Query(ctx, getDocumentsByCustomer, customerID)
+//	SELECT
+//	    f.llm_id,
+//	    COALESCE(l.title, '')::text AS title,
+//	    COALESCE(l.model, '')::text AS model,
+//	    COUNT(*) AS total,
+//	    COUNT(*) FILTER (WHERE f.rating = 1) AS positive,
+//	    COUNT(*) FILTER (WHERE f.rating = -1) AS negative,
+//	    COUNT(*) FILTER (WHERE f.comment != '') AS comments
+//	FROM conversation_message_feedback f
+//	LEFT JOIN llm l ON l.id = f.llm_id
+//	WHERE f.customer_id = $1
+//	AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+//	AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+//	GROUP BY f.llm_id, l.title, l.model
+//	ORDER BY total DESC
+func (q *Queries) GetFeedbackByLLM(ctx context.Context, arg *GetFeedbackByLLMParams) ([]*GetFeedbackByLLMRow, error) {
+	rows, err := q.db.Query(ctx, getFeedbackByLLM, arg.CustomerID, arg.Column2, arg.Column3)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Document{}
+	items := []*GetFeedbackByLLMRow{}
 	for rows.Next() {
-		var i Document
+		var i GetFeedbackByLLMRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.ParentID,
-			&i.CustomerID,
-			&i.Filename,
-			&i.Type,
-			&i.SizeBytes,
-			&i.Sha256,
-			&i.Validated,
-			&i.DatastoreType,
-			&i.DatastoreID,
-			&i.Summary,
-			&i.SummarySha256,
-			&i.VectorSha256,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.IsAsset,
-			&i.Vectorize,
+			&i.LlmID,
+			&i.Title,
+			&i.Model,
+			&i.Total,
+			&i.Positive,
+			&i.Negative,
+			&i.Comments,
 		); err != nil {
 			return nil, err
 		}
@@ -2786,52 +4417,84 @@ func (q *Queries) GetDocumentsByCustomer(ctx context.Context, customerID uuid.UU
 	return items, nil
 }
 
-const getDocumentsFromListIDs = `-- name: GetDocumentsFromListIDs :many
-SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize from document
-WHERE customer_id = $1
-AND id = ANY($2::uuid[])
-AND ($3::uuid[] IS NULL OR parent_id = ANY($3::uuid[]))
-`
+const getFeedbackBySource = `-- name: GetFeedbackBySource :many
+SELECT
+    (s->>'sourceType')::text AS source_type,
+    (s->>'sourceId')::uuid AS source_id,
+    MAX(s->>'title')::text AS title,
+    COUNT(DISTINCT f.id) AS total,
+    COUNT(DISTINCT f.id) FILTER (WHERE f.rating = 1) AS positive,
+    COUNT(DISTINCT f.id) FILTER (WHERE f.rating = -1) AS negative,
+    COUNT(DISTINCT f.id) FILTER (WHERE f.comment != '') AS comments
+FROM conversation_message_feedback f
+CROSS JOIN LATERAL jsonb_array_elements(f.sources) s
+WHERE f.customer_id = $1
+AND s ? 'sourceId'
+AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+GROUP BY source_type, source_id
+ORDER BY negative DESC, total DESC
+LIMIT $4
+`
+
+type GetFeedbackBySourceParams struct {
+	CustomerID uuid.UUID          `db:"customer_id" json:"customerId"`
+	Column2    pgtype.Timestamptz `db:"column_2" json:"column2"`
+	Column3    pgtype.Timestamptz `db:"column_3" json:"column3"`
+	Limit      int32              `db:"limit" json:"limit"`
+}
 
-type GetDocumentsFromListIDsParams struct {
-	CustomerID uuid.UUID   `db:"customer_id" json:"customerId"`
-	Column2    []uuid.UUID `db:"column_2" json:"column2"`
-	Column3    []uuid.UUID `db:"column_3" json:"column3"`
+type GetFeedbackBySourceRow struct {
+	SourceType string    `db:"source_type" json:"sourceType"`
+	SourceID   uuid.UUID `db:"source_id" json:"sourceId"`
+	Title      string    `db:"title" json:"title"`
+	Total      int64     `db:"total" json:"total"`
+	Positive   int64     `db:"positive" json:"positive"`
+	Negative   int64     `db:"negative" json:"negative"`
+	Comments   int64     `db:"comments" json:"comments"`
 }
 
-// GetDocumentsFromListIDs
+// GetFeedbackBySource
 //
-//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize from document
-//	WHERE customer_id = $1
-//	AND id = ANY($2::uuid[])
-//	AND ($3::uuid[] IS NULL OR parent_id = ANY($3::uuid[]))
-func (q *Queries) GetDocumentsFromListIDs(ctx context.Context, arg *GetDocumentsFromListIDsParams) ([]*Document, error) {
-	rows, err := q.db.Query(ctx, getDocumentsFromListIDs, arg.CustomerID, arg.Column2, arg.Column3)
+//	SELECT
+//	    (s->>'sourceType')::text AS source_type,
+//	    (s->>'sourceId')::uuid AS source_id,
+//	    MAX(s->>'title')::text AS title,
+//	    COUNT(DISTINCT f.id) AS total,
+//	    COUNT(DISTINCT f.id) FILTER (WHERE f.rating = 1) AS positive,
+//	    COUNT(DISTINCT f.id) FILTER (WHERE f.rating = -1) AS negative,
+//	    COUNT(DISTINCT f.id) FILTER (WHERE f.comment != '') AS comments
+//	FROM conversation_message_feedback f
+//	CROSS JOIN LATERAL jsonb_array_elements(f.sources) s
+//	WHERE f.customer_id = $1
+//	AND s ? 'sourceId'
+//	AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+//	AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+//	GROUP BY source_type, source_id
+//	ORDER BY negative DESC, total DESC
+//	LIMIT $4
+func (q *Queries) GetFeedbackBySource(ctx context.Context, arg *GetFeedbackBySourceParams) ([]*GetFeedbackBySourceRow, error) {
+	rows, err := q.db.Query(ctx, getFeedbackBySource,
+		arg.CustomerID,
+		arg.Column2,
+		arg.Column3,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Document{}
+	items := []*GetFeedbackBySourceRow{}
 	for rows.Next() {
-		var i Document
+		var i GetFeedbackBySourceRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.ParentID,
-			&i.CustomerID,
-			&i.Filename,
-			&i.Type,
-			&i.SizeBytes,
-			&i.Sha256,
-			&i.Validated,
-			&i.DatastoreType,
-			&i.DatastoreID,
-			&i.Summary,
-			&i.SummarySha256,
-			&i.VectorSha256,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.IsAsset,
-			&i.Vectorize,
+			&i.SourceType,
+			&i.SourceID,
+			&i.Title,
+			&i.Total,
+			&i.Positive,
+			&i.Negative,
+			&i.Comments,
 		); err != nil {
 			return nil, err
 		}
@@ -2843,42 +4506,38 @@ func (q *Queries) GetDocumentsFromListIDs(ctx context.Context, arg *GetDocuments
 	return items, nil
 }
 
-const getDocumentsFromParent = `-- name: GetDocumentsFromParent :many
-SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
-WHERE parent_id = $1 AND validated = true
+const getFeedsByCustomer = `-- name: GetFeedsByCustomer :many
+SELECT id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at FROM feed
+WHERE customer_id = $1
+ORDER BY created_at DESC
 `
 
-// GetDocumentsFromParent
+// GetFeedsByCustomer
 //
-//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
-//	WHERE parent_id = $1 AND validated = true
-func (q *Queries) GetDocumentsFromParent(ctx context.Context, parentID pgtype.UUID) ([]*Document, error) {
-	rows, err := q.db.Query(ctx, getDocumentsFromParent, parentID)
+//	SELECT id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at FROM feed
+//	WHERE customer_id = $1
+//	ORDER BY created_at DESC
+func (q *Queries) GetFeedsByCustomer(ctx context.Context, customerID uuid.UUID) ([]*Feed, error) {
+	rows, err := q.db.Query(ctx, getFeedsByCustomer, customerID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Document{}
+	items := []*Feed{}
 	for rows.Next() {
-		var i Document
+		var i Feed
 		if err := rows.Scan(
 			&i.ID,
-			&i.ParentID,
 			&i.CustomerID,
-			&i.Filename,
-			&i.Type,
-			&i.SizeBytes,
-			&i.Sha256,
-			&i.Validated,
-			&i.DatastoreType,
-			&i.DatastoreID,
-			&i.Summary,
-			&i.SummarySha256,
-			&i.VectorSha256,
+			&i.Url,
+			&i.Title,
+			&i.FeedType,
+			&i.FetchArticle,
+			&i.PollIntervalMinutes,
+			&i.LastPolledAt,
+			&i.LastError,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.IsAsset,
-			&i.Vectorize,
 		); err != nil {
 			return nil, err
 		}
@@ -2890,49 +4549,40 @@ func (q *Queries) GetDocumentsFromParent(ctx context.Context, parentID pgtype.UU
 	return items, nil
 }
 
-const getDocumentsOlderThan = `-- name: GetDocumentsOlderThan :many
-SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
-WHERE customer_id = $1
-AND updated_at < $2
+const getFeedsToPoll = `-- name: GetFeedsToPoll :many
+SELECT id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at FROM feed
+WHERE last_polled_at IS NULL
+OR last_polled_at < CURRENT_TIMESTAMP - make_interval(mins => poll_interval_minutes)
+ORDER BY last_polled_at ASC NULLS FIRST
 `
 
-type GetDocumentsOlderThanParams struct {
-	CustomerID uuid.UUID          `db:"customer_id" json:"customerId"`
-	UpdatedAt  pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
-}
-
-// GetDocumentsOlderThan
+// GetFeedsToPoll
 //
-//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
-//	WHERE customer_id = $1
-//	AND updated_at < $2
-func (q *Queries) GetDocumentsOlderThan(ctx context.Context, arg *GetDocumentsOlderThanParams) ([]*Document, error) {
-	rows, err := q.db.Query(ctx, getDocumentsOlderThan, arg.CustomerID, arg.UpdatedAt)
+//	SELECT id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at FROM feed
+//	WHERE last_polled_at IS NULL
+//	OR last_polled_at < CURRENT_TIMESTAMP - make_interval(mins => poll_interval_minutes)
+//	ORDER BY last_polled_at ASC NULLS FIRST
+func (q *Queries) GetFeedsToPoll(ctx context.Context) ([]*Feed, error) {
+	rows, err := q.db.Query(ctx, getFeedsToPoll)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Document{}
+	items := []*Feed{}
 	for rows.Next() {
-		var i Document
+		var i Feed
 		if err := rows.Scan(
 			&i.ID,
-			&i.ParentID,
 			&i.CustomerID,
-			&i.Filename,
-			&i.Type,
-			&i.SizeBytes,
-			&i.Sha256,
-			&i.Validated,
-			&i.DatastoreType,
-			&i.DatastoreID,
-			&i.Summary,
-			&i.SummarySha256,
-			&i.VectorSha256,
+			&i.Url,
+			&i.Title,
+			&i.FeedType,
+			&i.FetchArticle,
+			&i.PollIntervalMinutes,
+			&i.LastPolledAt,
+			&i.LastError,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.IsAsset,
-			&i.Vectorize,
 		); err != nil {
 			return nil, err
 		}
@@ -3212,6 +4862,30 @@ func (q *Queries) GetLLM(ctx context.Context, id uuid.UUID) (*GetLLMRow, error)
 	return &i, err
 }
 
+const getLLMRetryPolicy = `-- name: GetLLMRetryPolicy :one
+SELECT llm_id, max_retries, initial_backoff_ms, max_backoff_ms, fallback_llm_ids, created_at, updated_at FROM llm_retry_policy
+WHERE llm_id = $1
+`
+
+// GetLLMRetryPolicy
+//
+//	SELECT llm_id, max_retries, initial_backoff_ms, max_backoff_ms, fallback_llm_ids, created_at, updated_at FROM llm_retry_policy
+//	WHERE llm_id = $1
+func (q *Queries) GetLLMRetryPolicy(ctx context.Context, llmID uuid.UUID) (*LlmRetryPolicy, error) {
+	row := q.db.QueryRow(ctx, getLLMRetryPolicy, llmID)
+	var i LlmRetryPolicy
+	err := row.Scan(
+		&i.LlmID,
+		&i.MaxRetries,
+		&i.InitialBackoffMs,
+		&i.MaxBackoffMs,
+		&i.FallbackLlmIds,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
 const getLLMsByCustomer = `-- name: GetLLMsByCustomer :many
 SELECT llm.id, llm.customer_id, llm.title, llm.color, llm.model, llm.temperature, llm.instructions, llm.is_default, llm.public, llm.created_at, llm.updated_at, am.id, am.provider, am.display_name, am.description, am.input_token_limit, am.output_token_limit, am.currency, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens, am.depreciated_warning, am.is_depreciated, am.created_at, am.updated_at, am.is_visible
 FROM llm
@@ -3342,6 +5016,107 @@ func (q *Queries) GetLLMsByCustomerAvailable(ctx context.Context, customerID pgt
 	return items, nil
 }
 
+const getLatestConversationLeaf = `-- name: GetLatestConversationLeaf :one
+WITH RECURSIVE descendant AS (
+    SELECT m.id FROM conversation_message m
+    WHERE m.id = $1
+    UNION ALL
+    SELECT m.id FROM conversation_message m
+    JOIN descendant d ON m.parent_id = d.id
+)
+SELECT cm.id, cm.conversation_id, cm.llm_id, cm.model, cm.temperature, cm.instructions, cm.role, cm.message, cm.index, cm.tool_use_id, cm.tool_name, cm.tool_arguments, cm.tool_results, cm.created_at, cm.updated_at, cm.is_cancelled, cm.citations, cm.parent_id, cm.embedding_failed_at FROM conversation_message cm
+JOIN descendant d ON cm.id = d.id
+WHERE NOT EXISTS (
+    SELECT 1 FROM conversation_message child
+    WHERE child.parent_id = cm.id
+)
+ORDER BY cm.index DESC, cm.id DESC
+LIMIT 1
+`
+
+// GetLatestConversationLeaf
+//
+//	WITH RECURSIVE descendant AS (
+//	    SELECT m.id FROM conversation_message m
+//	    WHERE m.id = $1
+//	    UNION ALL
+//	    SELECT m.id FROM conversation_message m
+//	    JOIN descendant d ON m.parent_id = d.id
+//	)
+//	SELECT cm.id, cm.conversation_id, cm.llm_id, cm.model, cm.temperature, cm.instructions, cm.role, cm.message, cm.index, cm.tool_use_id, cm.tool_name, cm.tool_arguments, cm.tool_results, cm.created_at, cm.updated_at, cm.is_cancelled, cm.citations, cm.parent_id, cm.embedding_failed_at FROM conversation_message cm
+//	JOIN descendant d ON cm.id = d.id
+//	WHERE NOT EXISTS (
+//	    SELECT 1 FROM conversation_message child
+//	    WHERE child.parent_id = cm.id
+//	)
+//	ORDER BY cm.index DESC, cm.id DESC
+//	LIMIT 1
+func (q *Queries) GetLatestConversationLeaf(ctx context.Context, id uuid.UUID) (*ConversationMessage, error) {
+	row := q.db.QueryRow(ctx, getLatestConversationLeaf, id)
+	var i ConversationMessage
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.LlmID,
+		&i.Model,
+		&i.Temperature,
+		&i.Instructions,
+		&i.Role,
+		&i.Message,
+		&i.Index,
+		&i.ToolUseID,
+		&i.ToolName,
+		&i.ToolArguments,
+		&i.ToolResults,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsCancelled,
+		&i.Citations,
+		&i.ParentID,
+		&i.EmbeddingFailedAt,
+	)
+	return &i, err
+}
+
+const getLatestPromptTemplate = `-- name: GetLatestPromptTemplate :one
+-- the newest version of the customer, or of the defaults when the customer is null
+SELECT id, customer_id, name, version, content, description, is_active, created_at, updated_at FROM prompt_template
+WHERE customer_id IS NOT DISTINCT FROM $1
+AND name = $2
+ORDER BY version DESC
+LIMIT 1
+`
+
+type GetLatestPromptTemplateParams struct {
+	CustomerID pgtype.UUID `db:"customer_id" json:"customerId"`
+	Name       string      `db:"name" json:"name"`
+}
+
+// GetLatestPromptTemplate
+//
+//	-- the newest version of the customer, or of the defaults when the customer is null
+//	SELECT id, customer_id, name, version, content, description, is_active, created_at, updated_at FROM prompt_template
+//	WHERE customer_id IS NOT DISTINCT FROM $1
+//	AND name = $2
+//	ORDER BY version DESC
+//	LIMIT 1
+func (q *Queries) GetLatestPromptTemplate(ctx context.Context, arg *GetLatestPromptTemplateParams) (*PromptTemplate, error) {
+	row := q.db.QueryRow(ctx, getLatestPromptTemplate, arg.CustomerID, arg.Name)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.Name,
+		&i.Version,
+		&i.Content,
+		&i.Description,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
 const getLinkedInPost = `-- name: GetLinkedInPost :one
 SELECT id, project_id, project_library_id, project_idea_id, title, asset_id, metadata, created_at, updated_at FROM linkedin_post
 WHERE id = $1
@@ -3695,15 +5470,65 @@ func (q *Queries) GetProjects(ctx context.Context, customerID uuid.UUID) ([]*Pro
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Project{}
+	items := []*Project{}
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.Title,
+			&i.Topic,
+			&i.IdeaGenerationModelID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPromptTemplateVersions = `-- name: GetPromptTemplateVersions :many
+-- the versions of the customer followed by the versions of the defaults, newest first
+SELECT id, customer_id, name, version, content, description, is_active, created_at, updated_at FROM prompt_template
+WHERE (customer_id = $1 OR customer_id IS NULL)
+AND name = $2
+ORDER BY customer_id NULLS LAST, version DESC
+`
+
+type GetPromptTemplateVersionsParams struct {
+	CustomerID pgtype.UUID `db:"customer_id" json:"customerId"`
+	Name       string      `db:"name" json:"name"`
+}
+
+// GetPromptTemplateVersions
+//
+//	-- the versions of the customer followed by the versions of the defaults, newest first
+//	SELECT id, customer_id, name, version, content, description, is_active, created_at, updated_at FROM prompt_template
+//	WHERE (customer_id = $1 OR customer_id IS NULL)
+//	AND name = $2
+//	ORDER BY customer_id NULLS LAST, version DESC
+func (q *Queries) GetPromptTemplateVersions(ctx context.Context, arg *GetPromptTemplateVersionsParams) ([]*PromptTemplate, error) {
+	rows, err := q.db.Query(ctx, getPromptTemplateVersions, arg.CustomerID, arg.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*PromptTemplate{}
 	for rows.Next() {
-		var i Project
+		var i PromptTemplate
 		if err := rows.Scan(
 			&i.ID,
 			&i.CustomerID,
-			&i.Title,
-			&i.Topic,
-			&i.IdeaGenerationModelID,
+			&i.Name,
+			&i.Version,
+			&i.Content,
+			&i.Description,
+			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -3870,14 +5695,14 @@ func (q *Queries) GetResumeApplications(ctx context.Context, resumeID uuid.UUID)
 }
 
 const getResumeDocuments = `-- name: GetResumeDocuments :many
-SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize FROM resume_document rd
+SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at FROM resume_document rd
 JOIN document d ON d.id = rd.document_id
 WHERE rd.resume_id = $1
 `
 
 // GetResumeDocuments
 //
-//	SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize FROM resume_document rd
+//	SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at FROM resume_document rd
 //	JOIN document d ON d.id = rd.document_id
 //	WHERE rd.resume_id = $1
 func (q *Queries) GetResumeDocuments(ctx context.Context, resumeID uuid.UUID) ([]*Document, error) {
@@ -3907,6 +5732,7 @@ func (q *Queries) GetResumeDocuments(ctx context.Context, resumeID uuid.UUID) ([
 			&i.UpdatedAt,
 			&i.IsAsset,
 			&i.Vectorize,
+			&i.SummaryFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -3919,7 +5745,7 @@ func (q *Queries) GetResumeDocuments(ctx context.Context, resumeID uuid.UUID) ([
 }
 
 const getResumeResume = `-- name: GetResumeResume :one
-SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize FROM resume_document rd
+SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at FROM resume_document rd
 JOIN document d ON d.id = rd.document_id
 WHERE rd.resume_id = $1
 AND rd.is_resume
@@ -3928,7 +5754,7 @@ LIMIT 1
 
 // GetResumeResume
 //
-//	SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize FROM resume_document rd
+//	SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at FROM resume_document rd
 //	JOIN document d ON d.id = rd.document_id
 //	WHERE rd.resume_id = $1
 //	AND rd.is_resume
@@ -3954,19 +5780,20 @@ func (q *Queries) GetResumeResume(ctx context.Context, resumeID uuid.UUID) (*Doc
 		&i.UpdatedAt,
 		&i.IsAsset,
 		&i.Vectorize,
+		&i.SummaryFailedAt,
 	)
 	return &i, err
 }
 
 const getResumeWebsitePages = `-- name: GetResumeWebsitePages :many
-SELECT wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at FROM resume_website_page rwp
+SELECT wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at, wp.content_type, wp.summary_failed_at FROM resume_website_page rwp
 JOIN website_page wp ON wp.id = rwp.website_page_id
 WHERE rwp.resume_id = $1
 `
 
 // GetResumeWebsitePages
 //
-//	SELECT wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at FROM resume_website_page rwp
+//	SELECT wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at, wp.content_type, wp.summary_failed_at FROM resume_website_page rwp
 //	JOIN website_page wp ON wp.id = rwp.website_page_id
 //	WHERE rwp.resume_id = $1
 func (q *Queries) GetResumeWebsitePages(ctx context.Context, resumeID uuid.UUID) ([]*WebsitePage, error) {
@@ -3991,6 +5818,8 @@ func (q *Queries) GetResumeWebsitePages(ctx context.Context, resumeID uuid.UUID)
 			&i.VectorSha256,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ContentType,
+			&i.SummaryFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -4120,13 +5949,13 @@ func (q *Queries) GetResumesCustomer(ctx context.Context, customerID uuid.UUID)
 }
 
 const getRootDocumentsByCustomer = `-- name: GetRootDocumentsByCustomer :many
-SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
+SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
 WHERE customer_id = $1 AND parent_id is NULL
 `
 
 // GetRootDocumentsByCustomer
 //
-//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
+//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
 //	WHERE customer_id = $1 AND parent_id is NULL
 func (q *Queries) GetRootDocumentsByCustomer(ctx context.Context, customerID uuid.UUID) ([]*Document, error) {
 	rows, err := q.db.Query(ctx, getRootDocumentsByCustomer, customerID)
@@ -4155,6 +5984,7 @@ func (q *Queries) GetRootDocumentsByCustomer(ctx context.Context, customerID uui
 			&i.UpdatedAt,
 			&i.IsAsset,
 			&i.Vectorize,
+			&i.SummaryFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -4203,13 +6033,13 @@ func (q *Queries) GetRootFoldersByCustomer(ctx context.Context, customerID uuid.
 }
 
 const getUnvalidatedDocumentsByCustomer = `-- name: GetUnvalidatedDocumentsByCustomer :many
-SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
+SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
 WHERE customer_id = $1 AND validated = false
 `
 
 // GetUnvalidatedDocumentsByCustomer
 //
-//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize FROM document
+//	SELECT id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at FROM document
 //	WHERE customer_id = $1 AND validated = false
 func (q *Queries) GetUnvalidatedDocumentsByCustomer(ctx context.Context, customerID uuid.UUID) ([]*Document, error) {
 	rows, err := q.db.Query(ctx, getUnvalidatedDocumentsByCustomer, customerID)
@@ -4238,6 +6068,56 @@ func (q *Queries) GetUnvalidatedDocumentsByCustomer(ctx context.Context, custome
 			&i.UpdatedAt,
 			&i.IsAsset,
 			&i.Vectorize,
+			&i.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVectorChunkIndexes = `-- name: GetVectorChunkIndexes :many
+SELECT vector_store_id, index FROM document_vector
+WHERE document_vector.vector_store_id = ANY($1::uuid[])
+UNION ALL
+SELECT vector_store_id, index FROM website_page_vector
+WHERE website_page_vector.vector_store_id = ANY($1::uuid[])
+UNION ALL
+SELECT vector_store_id, index FROM feed_item_vector
+WHERE feed_item_vector.vector_store_id = ANY($1::uuid[])
+`
+
+type GetVectorChunkIndexesRow struct {
+	VectorStoreID uuid.UUID `db:"vector_store_id" json:"vectorStoreId"`
+	Index         int32     `db:"index" json:"index"`
+}
+
+// GetVectorChunkIndexes
+//
+//	SELECT vector_store_id, index FROM document_vector
+//	WHERE document_vector.vector_store_id = ANY($1::uuid[])
+//	UNION ALL
+//	SELECT vector_store_id, index FROM website_page_vector
+//	WHERE website_page_vector.vector_store_id = ANY($1::uuid[])
+//	UNION ALL
+//	SELECT vector_store_id, index FROM feed_item_vector
+//	WHERE feed_item_vector.vector_store_id = ANY($1::uuid[])
+func (q *Queries) GetVectorChunkIndexes(ctx context.Context, column1 []uuid.UUID) ([]*GetVectorChunkIndexesRow, error) {
+	rows, err := q.db.Query(ctx, getVectorChunkIndexes, column1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*GetVectorChunkIndexesRow{}
+	for rows.Next() {
+		var i GetVectorChunkIndexesRow
+		if err := rows.Scan(
+			&i.VectorStoreID,
+			&i.Index,
 		); err != nil {
 			return nil, err
 		}
@@ -4448,14 +6328,50 @@ func (q *Queries) GetWebsite(ctx context.Context, id uuid.UUID) (*Website, error
 	return &i, err
 }
 
+const getWebsiteByPath = `-- name: GetWebsiteByPath :one
+SELECT id, customer_id, protocol, domain, path, blacklist, whitelist, created_at, updated_at FROM website
+WHERE customer_id = $1
+AND domain = $2
+AND path = $3
+`
+
+type GetWebsiteByPathParams struct {
+	CustomerID uuid.UUID `db:"customer_id" json:"customerId"`
+	Domain     string    `db:"domain" json:"domain"`
+	Path       string    `db:"path" json:"path"`
+}
+
+// GetWebsiteByPath
+//
+//	SELECT id, customer_id, protocol, domain, path, blacklist, whitelist, created_at, updated_at FROM website
+//	WHERE customer_id = $1
+//	AND domain = $2
+//	AND path = $3
+func (q *Queries) GetWebsiteByPath(ctx context.Context, arg *GetWebsiteByPathParams) (*Website, error) {
+	row := q.db.QueryRow(ctx, getWebsiteByPath, arg.CustomerID, arg.Domain, arg.Path)
+	var i Website
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.Protocol,
+		&i.Domain,
+		&i.Path,
+		&i.Blacklist,
+		&i.Whitelist,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
 const getWebsitePage = `-- name: GetWebsitePage :one
-SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at FROM website_page
+SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at FROM website_page
 WHERE id = $1
 `
 
 // GetWebsitePage
 //
-//	SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at FROM website_page
+//	SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at FROM website_page
 //	WHERE id = $1
 func (q *Queries) GetWebsitePage(ctx context.Context, id uuid.UUID) (*WebsitePage, error) {
 	row := q.db.QueryRow(ctx, getWebsitePage, id)
@@ -4473,18 +6389,60 @@ func (q *Queries) GetWebsitePage(ctx context.Context, id uuid.UUID) (*WebsitePag
 		&i.VectorSha256,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ContentType,
+		&i.SummaryFailedAt,
+	)
+	return &i, err
+}
+
+const getWebsitePageByUrl = `-- name: GetWebsitePageByUrl :one
+SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at FROM website_page
+WHERE customer_id = $1 AND url = $2 AND is_valid = true
+ORDER BY updated_at DESC
+LIMIT 1
+`
+
+type GetWebsitePageByUrlParams struct {
+	CustomerID uuid.UUID `db:"customer_id" json:"customerId"`
+	Url        string    `db:"url" json:"url"`
+}
+
+// GetWebsitePageByUrl
+//
+//	SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at FROM website_page
+//	WHERE customer_id = $1 AND url = $2 AND is_valid = true
+//	ORDER BY updated_at DESC
+//	LIMIT 1
+func (q *Queries) GetWebsitePageByUrl(ctx context.Context, arg *GetWebsitePageByUrlParams) (*WebsitePage, error) {
+	row := q.db.QueryRow(ctx, getWebsitePageByUrl, arg.CustomerID, arg.Url)
+	var i WebsitePage
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.WebsiteID,
+		&i.Url,
+		&i.Sha256,
+		&i.IsValid,
+		&i.Metadata,
+		&i.Summary,
+		&i.SummarySha256,
+		&i.VectorSha256,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentType,
+		&i.SummaryFailedAt,
 	)
 	return &i, err
 }
 
 const getWebsitePagesBySite = `-- name: GetWebsitePagesBySite :many
-SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at FROM website_page
+SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at FROM website_page
 WHERE website_id = $1
 `
 
 // GetWebsitePagesBySite
 //
-//	SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at FROM website_page
+//	SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at FROM website_page
 //	WHERE website_id = $1
 func (q *Queries) GetWebsitePagesBySite(ctx context.Context, websiteID uuid.UUID) ([]*WebsitePage, error) {
 	rows, err := q.db.Query(ctx, getWebsitePagesBySite, websiteID)
@@ -4508,6 +6466,75 @@ func (q *Queries) GetWebsitePagesBySite(ctx context.Context, websiteID uuid.UUID
 			&i.VectorSha256,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ContentType,
+			&i.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebsitePagesToSummarize = `-- name: GetWebsitePagesToSummarize :many
+SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at FROM website_page
+WHERE is_valid = true
+  AND vector_sha_256 != ''
+  AND (
+    summary_sha_256 != vector_sha_256
+    -- summaries created before the retrieval layer only need to be embedded
+    OR NOT EXISTS (SELECT 1 FROM website_page_summary_vector v WHERE v.website_page_id = website_page.id)
+  )
+  AND (summary_failed_at IS NULL OR summary_failed_at < $1)
+ORDER BY updated_at ASC
+LIMIT $2
+`
+
+type GetWebsitePagesToSummarizeParams struct {
+	SummaryFailedAt pgtype.Timestamptz `db:"summary_failed_at" json:"summaryFailedAt"`
+	Limit           int32              `db:"limit" json:"limit"`
+}
+
+// GetWebsitePagesToSummarize
+//
+//	SELECT id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at FROM website_page
+//	WHERE is_valid = true
+//	  AND vector_sha_256 != ''
+//	  AND (
+//	    summary_sha_256 != vector_sha_256
+//	    -- summaries created before the retrieval layer only need to be embedded
+//	    OR NOT EXISTS (SELECT 1 FROM website_page_summary_vector v WHERE v.website_page_id = website_page.id)
+//	  )
+//	  AND (summary_failed_at IS NULL OR summary_failed_at < $1)
+//	ORDER BY updated_at ASC
+//	LIMIT $2
+func (q *Queries) GetWebsitePagesToSummarize(ctx context.Context, arg *GetWebsitePagesToSummarizeParams) ([]*WebsitePage, error) {
+	rows, err := q.db.Query(ctx, getWebsitePagesToSummarize, arg.SummaryFailedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*WebsitePage{}
+	for rows.Next() {
+		var i WebsitePage
+		if err := rows.Scan(
+			&i.ID,
+			&i.CustomerID,
+			&i.WebsiteID,
+			&i.Url,
+			&i.Sha256,
+			&i.IsValid,
+			&i.Metadata,
+			&i.Summary,
+			&i.SummarySha256,
+			&i.VectorSha256,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentType,
+			&i.SummaryFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -4729,7 +6756,7 @@ const markDocumentAsUploaded = `-- name: MarkDocumentAsUploaded :one
 UPDATE document
 SET validated = true
 WHERE id = $1
-RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize
+RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at
 `
 
 // MarkDocumentAsUploaded
@@ -4737,7 +6764,7 @@ RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, valid
 //	UPDATE document
 //	SET validated = true
 //	WHERE id = $1
-//	RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize
+//	RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at
 func (q *Queries) MarkDocumentAsUploaded(ctx context.Context, id uuid.UUID) (*Document, error) {
 	row := q.db.QueryRow(ctx, markDocumentAsUploaded, id)
 	var i Document
@@ -4759,12 +6786,89 @@ func (q *Queries) MarkDocumentAsUploaded(ctx context.Context, id uuid.UUID) (*Do
 		&i.UpdatedAt,
 		&i.IsAsset,
 		&i.Vectorize,
+		&i.SummaryFailedAt,
 	)
 	return &i, err
 }
 
+const queryDocumentSummaries = `-- name: QueryDocumentSummaries :many
+SELECT
+    d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at,
+    (dsv.embeddings <#> $3)::float AS distance
+FROM document_summary_vector dsv
+JOIN document d ON d.id = dsv.document_id
+WHERE dsv.customer_id = $1
+  AND dsv.sha_256 = d.summary_sha_256
+  AND d.summary_sha_256 = d.sha_256
+ORDER BY dsv.embeddings <#> $3
+LIMIT $2
+`
+
+type QueryDocumentSummariesParams struct {
+	CustomerID uuid.UUID        `db:"customer_id" json:"customerId"`
+	Limit      int32            `db:"limit" json:"limit"`
+	Embeddings *pgvector.Vector `db:"embeddings" json:"embeddings"`
+}
+
+type QueryDocumentSummariesRow struct {
+	Document Document `db:"document" json:"document"`
+	Distance float64  `db:"distance" json:"distance"`
+}
+
+// QueryDocumentSummaries
+//
+//	SELECT
+//	    d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at,
+//	    (dsv.embeddings <#> $3)::float AS distance
+//	FROM document_summary_vector dsv
+//	JOIN document d ON d.id = dsv.document_id
+//	WHERE dsv.customer_id = $1
+//	  AND dsv.sha_256 = d.summary_sha_256
+//	  AND d.summary_sha_256 = d.sha_256
+//	ORDER BY dsv.embeddings <#> $3
+//	LIMIT $2
+func (q *Queries) QueryDocumentSummaries(ctx context.Context, arg *QueryDocumentSummariesParams) ([]*QueryDocumentSummariesRow, error) {
+	rows, err := q.db.Query(ctx, queryDocumentSummaries, arg.CustomerID, arg.Limit, arg.Embeddings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*QueryDocumentSummariesRow{}
+	for rows.Next() {
+		var i QueryDocumentSummariesRow
+		if err := rows.Scan(
+			&i.Document.ID,
+			&i.Document.ParentID,
+			&i.Document.CustomerID,
+			&i.Document.Filename,
+			&i.Document.Type,
+			&i.Document.SizeBytes,
+			&i.Document.Sha256,
+			&i.Document.Validated,
+			&i.Document.DatastoreType,
+			&i.Document.DatastoreID,
+			&i.Document.Summary,
+			&i.Document.SummarySha256,
+			&i.Document.VectorSha256,
+			&i.Document.CreatedAt,
+			&i.Document.UpdatedAt,
+			&i.Document.IsAsset,
+			&i.Document.Vectorize,
+			&i.Document.SummaryFailedAt,
+			&i.Distance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const queryVectorStoreDocuments = `-- name: QueryVectorStoreDocuments :many
-SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize
+SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at
 FROM vector_store vs
 JOIN document_vector dv ON vs.id = dv.vector_store_id
 JOIN document d ON d.id = dv.document_id
@@ -4781,7 +6885,7 @@ type QueryVectorStoreDocumentsParams struct {
 
 // QueryVectorStoreDocuments
 //
-//	SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize
+//	SELECT d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at
 //	FROM vector_store vs
 //	JOIN document_vector dv ON vs.id = dv.vector_store_id
 //	JOIN document d ON d.id = dv.document_id
@@ -4815,6 +6919,7 @@ func (q *Queries) QueryVectorStoreDocuments(ctx context.Context, arg *QueryVecto
 			&i.UpdatedAt,
 			&i.IsAsset,
 			&i.Vectorize,
+			&i.SummaryFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -4829,7 +6934,7 @@ func (q *Queries) QueryVectorStoreDocuments(ctx context.Context, arg *QueryVecto
 const queryVectorStoreDocumentsScoped = `-- name: QueryVectorStoreDocumentsScoped :many
 SELECT
     vs.id, vs.customer_id, vs.raw, vs.embeddings, vs.content_type, vs.object_id, vs.object_parent_id, vs.metadata, vs.created_at,
-    d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize
+    d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at
 FROM vector_store vs
 JOIN document_vector dv ON vs.id = dv.vector_store_id
 JOIN document d ON d.id = dv.document_id
@@ -4863,7 +6968,7 @@ type QueryVectorStoreDocumentsScopedRow struct {
 //
 //	SELECT
 //	    vs.id, vs.customer_id, vs.raw, vs.embeddings, vs.content_type, vs.object_id, vs.object_parent_id, vs.metadata, vs.created_at,
-//	    d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize
+//	    d.id, d.parent_id, d.customer_id, d.filename, d.type, d.size_bytes, d.sha_256, d.validated, d.datastore_type, d.datastore_id, d.summary, d.summary_sha_256, d.vector_sha_256, d.created_at, d.updated_at, d.is_asset, d.vectorize, d.summary_failed_at
 //	FROM vector_store vs
 //	JOIN document_vector dv ON vs.id = dv.vector_store_id
 //	JOIN document d ON d.id = dv.document_id
@@ -4892,7 +6997,101 @@ func (q *Queries) QueryVectorStoreDocumentsScoped(ctx context.Context, arg *Quer
 	defer rows.Close()
 	items := []*QueryVectorStoreDocumentsScopedRow{}
 	for rows.Next() {
-		var i QueryVectorStoreDocumentsScopedRow
+		var i QueryVectorStoreDocumentsScopedRow
+		if err := rows.Scan(
+			&i.VectorStore.ID,
+			&i.VectorStore.CustomerID,
+			&i.VectorStore.Raw,
+			&i.VectorStore.Embeddings,
+			&i.VectorStore.ContentType,
+			&i.VectorStore.ObjectID,
+			&i.VectorStore.ObjectParentID,
+			&i.VectorStore.Metadata,
+			&i.VectorStore.CreatedAt,
+			&i.Document.ID,
+			&i.Document.ParentID,
+			&i.Document.CustomerID,
+			&i.Document.Filename,
+			&i.Document.Type,
+			&i.Document.SizeBytes,
+			&i.Document.Sha256,
+			&i.Document.Validated,
+			&i.Document.DatastoreType,
+			&i.Document.DatastoreID,
+			&i.Document.Summary,
+			&i.Document.SummarySha256,
+			&i.Document.VectorSha256,
+			&i.Document.CreatedAt,
+			&i.Document.UpdatedAt,
+			&i.Document.IsAsset,
+			&i.Document.Vectorize,
+			&i.Document.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const queryVectorStoreFeedItemsScoped = `-- name: QueryVectorStoreFeedItemsScoped :many
+SELECT
+    vs.id, vs.customer_id, vs.raw, vs.embeddings, vs.content_type, vs.object_id, vs.object_parent_id, vs.metadata, vs.created_at,
+    fi.id, fi.customer_id, fi.feed_id, fi.guid, fi.title, fi.link, fi.author, fi.published_at, fi.content, fi.sha_256, fi.metadata, fi.vector_sha_256, fi.created_at, fi.updated_at
+FROM vector_store vs
+JOIN feed_item_vector fiv ON vs.id = fiv.vector_store_id
+JOIN feed_item fi ON fi.id = fiv.feed_item_id
+WHERE vs.customer_id = $1
+AND ($4::uuid[] IS NULL OR fi.id = ANY($4::uuid[]))
+AND ($5::uuid[] IS NULL OR fi.feed_id = ANY($5::uuid[]))
+ORDER BY vs.embeddings <#> $3
+LIMIT $2
+`
+
+type QueryVectorStoreFeedItemsScopedParams struct {
+	CustomerID uuid.UUID        `db:"customer_id" json:"customerId"`
+	Limit      int32            `db:"limit" json:"limit"`
+	Embeddings *pgvector.Vector `db:"embeddings" json:"embeddings"`
+	Column4    []uuid.UUID      `db:"column_4" json:"column4"`
+	Column5    []uuid.UUID      `db:"column_5" json:"column5"`
+}
+
+type QueryVectorStoreFeedItemsScopedRow struct {
+	VectorStore VectorStore `db:"vector_store" json:"vectorStore"`
+	FeedItem    FeedItem    `db:"feed_item" json:"feedItem"`
+}
+
+// QueryVectorStoreFeedItemsScoped
+//
+//	SELECT
+//	    vs.id, vs.customer_id, vs.raw, vs.embeddings, vs.content_type, vs.object_id, vs.object_parent_id, vs.metadata, vs.created_at,
+//	    fi.id, fi.customer_id, fi.feed_id, fi.guid, fi.title, fi.link, fi.author, fi.published_at, fi.content, fi.sha_256, fi.metadata, fi.vector_sha_256, fi.created_at, fi.updated_at
+//	FROM vector_store vs
+//	JOIN feed_item_vector fiv ON vs.id = fiv.vector_store_id
+//	JOIN feed_item fi ON fi.id = fiv.feed_item_id
+//	WHERE vs.customer_id = $1
+//	AND ($4::uuid[] IS NULL OR fi.id = ANY($4::uuid[]))
+//	AND ($5::uuid[] IS NULL OR fi.feed_id = ANY($5::uuid[]))
+//	ORDER BY vs.embeddings <#> $3
+//	LIMIT $2
+func (q *Queries) QueryVectorStoreFeedItemsScoped(ctx context.Context, arg *QueryVectorStoreFeedItemsScopedParams) ([]*QueryVectorStoreFeedItemsScopedRow, error) {
+	rows, err := q.db.Query(ctx, queryVectorStoreFeedItemsScoped,
+		arg.CustomerID,
+		arg.Limit,
+		arg.Embeddings,
+		arg.Column4,
+		arg.Column5,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*QueryVectorStoreFeedItemsScopedRow{}
+	for rows.Next() {
+		var i QueryVectorStoreFeedItemsScopedRow
 		if err := rows.Scan(
 			&i.VectorStore.ID,
 			&i.VectorStore.CustomerID,
@@ -4903,23 +7102,20 @@ func (q *Queries) QueryVectorStoreDocumentsScoped(ctx context.Context, arg *Quer
 			&i.VectorStore.ObjectParentID,
 			&i.VectorStore.Metadata,
 			&i.VectorStore.CreatedAt,
-			&i.Document.ID,
-			&i.Document.ParentID,
-			&i.Document.CustomerID,
-			&i.Document.Filename,
-			&i.Document.Type,
-			&i.Document.SizeBytes,
-			&i.Document.Sha256,
-			&i.Document.Validated,
-			&i.Document.DatastoreType,
-			&i.Document.DatastoreID,
-			&i.Document.Summary,
-			&i.Document.SummarySha256,
-			&i.Document.VectorSha256,
-			&i.Document.CreatedAt,
-			&i.Document.UpdatedAt,
-			&i.Document.IsAsset,
-			&i.Document.Vectorize,
+			&i.FeedItem.ID,
+			&i.FeedItem.CustomerID,
+			&i.FeedItem.FeedID,
+			&i.FeedItem.Guid,
+			&i.FeedItem.Title,
+			&i.FeedItem.Link,
+			&i.FeedItem.Author,
+			&i.FeedItem.PublishedAt,
+			&i.FeedItem.Content,
+			&i.FeedItem.Sha256,
+			&i.FeedItem.Metadata,
+			&i.FeedItem.VectorSha256,
+			&i.FeedItem.CreatedAt,
+			&i.FeedItem.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -4983,7 +7179,7 @@ func (q *Queries) QueryVectorStoreRaw(ctx context.Context, arg *QueryVectorStore
 const queryVectorStoreWebsitePages = `-- name: QueryVectorStoreWebsitePages :many
 SELECT
     vs.id, vs.customer_id, vs.raw, vs.embeddings, vs.content_type, vs.object_id, vs.object_parent_id, vs.metadata, vs.created_at,
-    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at
+    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at, wp.content_type, wp.summary_failed_at
 FROM vector_store vs
 JOIN website_page_vector wpv ON vs.id = wpv.vector_store_id
 JOIN website_page wp ON wp.id = wpv.website_page_id
@@ -5007,7 +7203,7 @@ type QueryVectorStoreWebsitePagesRow struct {
 //
 //	SELECT
 //	    vs.id, vs.customer_id, vs.raw, vs.embeddings, vs.content_type, vs.object_id, vs.object_parent_id, vs.metadata, vs.created_at,
-//	    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at
+//	    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at, wp.content_type, wp.summary_failed_at
 //	FROM vector_store vs
 //	JOIN website_page_vector wpv ON vs.id = wpv.vector_store_id
 //	JOIN website_page wp ON wp.id = wpv.website_page_id
@@ -5045,6 +7241,8 @@ func (q *Queries) QueryVectorStoreWebsitePages(ctx context.Context, arg *QueryVe
 			&i.WebsitePage.VectorSha256,
 			&i.WebsitePage.CreatedAt,
 			&i.WebsitePage.UpdatedAt,
+			&i.WebsitePage.ContentType,
+			&i.WebsitePage.SummaryFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -5059,7 +7257,7 @@ func (q *Queries) QueryVectorStoreWebsitePages(ctx context.Context, arg *QueryVe
 const queryVectorStoreWebsitePagesScoped = `-- name: QueryVectorStoreWebsitePagesScoped :many
 SELECT
     vs.id, vs.customer_id, vs.raw, vs.embeddings, vs.content_type, vs.object_id, vs.object_parent_id, vs.metadata, vs.created_at,
-    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at
+    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at, wp.content_type, wp.summary_failed_at
 FROM vector_store vs
 JOIN website_page_vector wpv ON vs.id = wpv.vector_store_id
 JOIN website_page wp ON wp.id = wpv.website_page_id
@@ -5091,7 +7289,7 @@ type QueryVectorStoreWebsitePagesScopedRow struct {
 //
 //	SELECT
 //	    vs.id, vs.customer_id, vs.raw, vs.embeddings, vs.content_type, vs.object_id, vs.object_parent_id, vs.metadata, vs.created_at,
-//	    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at
+//	    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at, wp.content_type, wp.summary_failed_at
 //	FROM vector_store vs
 //	JOIN website_page_vector wpv ON vs.id = wpv.vector_store_id
 //	JOIN website_page wp ON wp.id = wpv.website_page_id
@@ -5141,6 +7339,312 @@ func (q *Queries) QueryVectorStoreWebsitePagesScoped(ctx context.Context, arg *Q
 			&i.WebsitePage.VectorSha256,
 			&i.WebsitePage.CreatedAt,
 			&i.WebsitePage.UpdatedAt,
+			&i.WebsitePage.ContentType,
+			&i.WebsitePage.SummaryFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const queryWebsitePageSummaries = `-- name: QueryWebsitePageSummaries :many
+SELECT
+    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at, wp.content_type, wp.summary_failed_at,
+    (wpsv.embeddings <#> $3)::float AS distance
+FROM website_page_summary_vector wpsv
+JOIN website_page wp ON wp.id = wpsv.website_page_id
+WHERE wpsv.customer_id = $1
+  AND wpsv.sha_256 = wp.summary_sha_256
+  AND wp.summary_sha_256 = wp.vector_sha_256
+ORDER BY wpsv.embeddings <#> $3
+LIMIT $2
+`
+
+type QueryWebsitePageSummariesParams struct {
+	CustomerID uuid.UUID        `db:"customer_id" json:"customerId"`
+	Limit      int32            `db:"limit" json:"limit"`
+	Embeddings *pgvector.Vector `db:"embeddings" json:"embeddings"`
+}
+
+type QueryWebsitePageSummariesRow struct {
+	WebsitePage WebsitePage `db:"website_page" json:"websitePage"`
+	Distance    float64     `db:"distance" json:"distance"`
+}
+
+// QueryWebsitePageSummaries
+//
+//	SELECT
+//	    wp.id, wp.customer_id, wp.website_id, wp.url, wp.sha_256, wp.is_valid, wp.metadata, wp.summary, wp.summary_sha_256, wp.vector_sha_256, wp.created_at, wp.updated_at, wp.content_type, wp.summary_failed_at,
+//	    (wpsv.embeddings <#> $3)::float AS distance
+//	FROM website_page_summary_vector wpsv
+//	JOIN website_page wp ON wp.id = wpsv.website_page_id
+//	WHERE wpsv.customer_id = $1
+//	  AND wpsv.sha_256 = wp.summary_sha_256
+//	  AND wp.summary_sha_256 = wp.vector_sha_256
+//	ORDER BY wpsv.embeddings <#> $3
+//	LIMIT $2
+func (q *Queries) QueryWebsitePageSummaries(ctx context.Context, arg *QueryWebsitePageSummariesParams) ([]*QueryWebsitePageSummariesRow, error) {
+	rows, err := q.db.Query(ctx, queryWebsitePageSummaries, arg.CustomerID, arg.Limit, arg.Embeddings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*QueryWebsitePageSummariesRow{}
+	for rows.Next() {
+		var i QueryWebsitePageSummariesRow
+		if err := rows.Scan(
+			&i.WebsitePage.ID,
+			&i.WebsitePage.CustomerID,
+			&i.WebsitePage.WebsiteID,
+			&i.WebsitePage.Url,
+			&i.WebsitePage.Sha256,
+			&i.WebsitePage.IsValid,
+			&i.WebsitePage.Metadata,
+			&i.WebsitePage.Summary,
+			&i.WebsitePage.SummarySha256,
+			&i.WebsitePage.VectorSha256,
+			&i.WebsitePage.CreatedAt,
+			&i.WebsitePage.UpdatedAt,
+			&i.WebsitePage.ContentType,
+			&i.WebsitePage.SummaryFailedAt,
+			&i.Distance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchConversationMessages = `-- name: SearchConversationMessages :many
+SELECT
+    cm.id,
+    cm.conversation_id,
+    cm.role,
+    cm.index,
+    cm.created_at,
+    c.title,
+    c.conversation_type,
+    ts_headline(
+        'english',
+        cm.message,
+        websearch_to_tsquery('english', $2::text),
+        'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=**, StopSel=**'
+    )::text AS snippet,
+    ts_rank(to_tsvector('english', cm.message), websearch_to_tsquery('english', $2::text))::float8 AS rank
+FROM conversation_message cm
+JOIN conversation c ON c.id = cm.conversation_id
+WHERE c.customer_id = $1
+AND to_tsvector('english', cm.message) @@ websearch_to_tsquery('english', $2::text)
+AND cm.role = ANY($3::text[])
+AND (c.conversation_type = $4 OR $4 = '')
+AND ($5::timestamptz IS NULL OR cm.created_at >= $5::timestamptz)
+AND ($6::timestamptz IS NULL OR cm.created_at < $6::timestamptz)
+AND ($7::uuid[] IS NULL OR cm.llm_id = ANY($7::uuid[]))
+ORDER BY rank DESC, cm.created_at DESC
+LIMIT $8
+`
+
+type SearchConversationMessagesParams struct {
+	CustomerID       uuid.UUID          `db:"customer_id" json:"customerId"`
+	Column2          string             `db:"column_2" json:"column2"`
+	Column3          []string           `db:"column_3" json:"column3"`
+	ConversationType string             `db:"conversation_type" json:"conversationType"`
+	Column5          pgtype.Timestamptz `db:"column_5" json:"column5"`
+	Column6          pgtype.Timestamptz `db:"column_6" json:"column6"`
+	Column7          []uuid.UUID        `db:"column_7" json:"column7"`
+	Limit            int32              `db:"limit" json:"limit"`
+}
+
+type SearchConversationMessagesRow struct {
+	ID               uuid.UUID          `db:"id" json:"id"`
+	ConversationID   uuid.UUID          `db:"conversation_id" json:"conversationId"`
+	Role             string             `db:"role" json:"role"`
+	Index            int32              `db:"index" json:"index"`
+	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	Title            string             `db:"title" json:"title"`
+	ConversationType string             `db:"conversation_type" json:"conversationType"`
+	Snippet          string             `db:"snippet" json:"snippet"`
+	Rank             float64            `db:"rank" json:"rank"`
+}
+
+// SearchConversationMessages
+//
+//	SELECT
+//	    cm.id,
+//	    cm.conversation_id,
+//	    cm.role,
+//	    cm.index,
+//	    cm.created_at,
+//	    c.title,
+//	    c.conversation_type,
+//	    ts_headline(
+//	        'english',
+//	        cm.message,
+//	        websearch_to_tsquery('english', $2::text),
+//	        'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=**, StopSel=**'
+//	    )::text AS snippet,
+//	    ts_rank(to_tsvector('english', cm.message), websearch_to_tsquery('english', $2::text))::float8 AS rank
+//	FROM conversation_message cm
+//	JOIN conversation c ON c.id = cm.conversation_id
+//	WHERE c.customer_id = $1
+//	AND to_tsvector('english', cm.message) @@ websearch_to_tsquery('english', $2::text)
+//	AND cm.role = ANY($3::text[])
+//	AND (c.conversation_type = $4 OR $4 = '')
+//	AND ($5::timestamptz IS NULL OR cm.created_at >= $5::timestamptz)
+//	AND ($6::timestamptz IS NULL OR cm.created_at < $6::timestamptz)
+//	AND ($7::uuid[] IS NULL OR cm.llm_id = ANY($7::uuid[]))
+//	ORDER BY rank DESC, cm.created_at DESC
+//	LIMIT $8
+func (q *Queries) SearchConversationMessages(ctx context.Context, arg *SearchConversationMessagesParams) ([]*SearchConversationMessagesRow, error) {
+	rows, err := q.db.Query(ctx, searchConversationMessages,
+		arg.CustomerID,
+		arg.Column2,
+		arg.Column3,
+		arg.ConversationType,
+		arg.Column5,
+		arg.Column6,
+		arg.Column7,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*SearchConversationMessagesRow{}
+	for rows.Next() {
+		var i SearchConversationMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ConversationID,
+			&i.Role,
+			&i.Index,
+			&i.CreatedAt,
+			&i.Title,
+			&i.ConversationType,
+			&i.Snippet,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchConversationMessagesSemantic = `-- name: SearchConversationMessagesSemantic :many
+SELECT
+    cm.id,
+    cm.conversation_id,
+    cm.role,
+    cm.message,
+    cm.index,
+    cm.created_at,
+    c.title,
+    c.conversation_type,
+    (cme.embeddings <#> $2)::float8 AS distance
+FROM conversation_message_embedding cme
+JOIN conversation_message cm ON cm.id = cme.conversation_message_id
+JOIN conversation c ON c.id = cm.conversation_id
+WHERE cme.customer_id = $1
+AND cm.role = ANY($3::text[])
+AND (c.conversation_type = $4 OR $4 = '')
+AND ($5::timestamptz IS NULL OR cm.created_at >= $5::timestamptz)
+AND ($6::timestamptz IS NULL OR cm.created_at < $6::timestamptz)
+AND ($7::uuid[] IS NULL OR cm.llm_id = ANY($7::uuid[]))
+AND (cme.embeddings <#> $2) <= $9::float8
+ORDER BY cme.embeddings <#> $2
+LIMIT $8
+`
+
+type SearchConversationMessagesSemanticParams struct {
+	CustomerID       uuid.UUID          `db:"customer_id" json:"customerId"`
+	Embeddings       *pgvector.Vector   `db:"embeddings" json:"embeddings"`
+	Column3          []string           `db:"column_3" json:"column3"`
+	ConversationType string             `db:"conversation_type" json:"conversationType"`
+	Column5          pgtype.Timestamptz `db:"column_5" json:"column5"`
+	Column6          pgtype.Timestamptz `db:"column_6" json:"column6"`
+	Column7          []uuid.UUID        `db:"column_7" json:"column7"`
+	Limit            int32              `db:"limit" json:"limit"`
+	Column9          float64            `db:"column_9" json:"column9"`
+}
+
+type SearchConversationMessagesSemanticRow struct {
+	ID               uuid.UUID          `db:"id" json:"id"`
+	ConversationID   uuid.UUID          `db:"conversation_id" json:"conversationId"`
+	Role             string             `db:"role" json:"role"`
+	Message          string             `db:"message" json:"message"`
+	Index            int32              `db:"index" json:"index"`
+	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	Title            string             `db:"title" json:"title"`
+	ConversationType string             `db:"conversation_type" json:"conversationType"`
+	Distance         float64            `db:"distance" json:"distance"`
+}
+
+// SearchConversationMessagesSemantic
+//
+//	SELECT
+//	    cm.id,
+//	    cm.conversation_id,
+//	    cm.role,
+//	    cm.message,
+//	    cm.index,
+//	    cm.created_at,
+//	    c.title,
+//	    c.conversation_type,
+//	    (cme.embeddings <#> $2)::float8 AS distance
+//	FROM conversation_message_embedding cme
+//	JOIN conversation_message cm ON cm.id = cme.conversation_message_id
+//	JOIN conversation c ON c.id = cm.conversation_id
+//	WHERE cme.customer_id = $1
+//	AND cm.role = ANY($3::text[])
+//	AND (c.conversation_type = $4 OR $4 = '')
+//	AND ($5::timestamptz IS NULL OR cm.created_at >= $5::timestamptz)
+//	AND ($6::timestamptz IS NULL OR cm.created_at < $6::timestamptz)
+//	AND ($7::uuid[] IS NULL OR cm.llm_id = ANY($7::uuid[]))
+//	AND (cme.embeddings <#> $2) <= $9::float8
+//	ORDER BY cme.embeddings <#> $2
+//	LIMIT $8
+func (q *Queries) SearchConversationMessagesSemantic(ctx context.Context, arg *SearchConversationMessagesSemanticParams) ([]*SearchConversationMessagesSemanticRow, error) {
+	rows, err := q.db.Query(ctx, searchConversationMessagesSemantic,
+		arg.CustomerID,
+		arg.Embeddings,
+		arg.Column3,
+		arg.ConversationType,
+		arg.Column5,
+		arg.Column6,
+		arg.Column7,
+		arg.Limit,
+		arg.Column9,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*SearchConversationMessagesSemanticRow{}
+	for rows.Next() {
+		var i SearchConversationMessagesSemanticRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ConversationID,
+			&i.Role,
+			&i.Message,
+			&i.Index,
+			&i.CreatedAt,
+			&i.Title,
+			&i.ConversationType,
+			&i.Distance,
 		); err != nil {
 			return nil, err
 		}
@@ -5173,12 +7677,35 @@ func (q *Queries) SetChatLLM(ctx context.Context, arg *SetChatLLMParams) error {
 	return err
 }
 
+const setConversationActiveMessage = `-- name: SetConversationActiveMessage :exec
+UPDATE conversation SET
+    active_message_id = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type SetConversationActiveMessageParams struct {
+	ID              uuid.UUID   `db:"id" json:"id"`
+	ActiveMessageID pgtype.UUID `db:"active_message_id" json:"activeMessageId"`
+}
+
+// SetConversationActiveMessage
+//
+//	UPDATE conversation SET
+//	    active_message_id = $2,
+//	    updated_at = CURRENT_TIMESTAMP
+//	WHERE id = $1
+func (q *Queries) SetConversationActiveMessage(ctx context.Context, arg *SetConversationActiveMessageParams) error {
+	_, err := q.db.Exec(ctx, setConversationActiveMessage, arg.ID, arg.ActiveMessageID)
+	return err
+}
+
 const setConversationError = `-- name: SetConversationError :one
 UPDATE conversation SET
     has_error = true,
     error_message = $2
 WHERE id = $1
-RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id
+RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id
 `
 
 type SetConversationErrorParams struct {
@@ -5192,7 +7719,7 @@ type SetConversationErrorParams struct {
 //	    has_error = true,
 //	    error_message = $2
 //	WHERE id = $1
-//	RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id
+//	RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id
 func (q *Queries) SetConversationError(ctx context.Context, arg *SetConversationErrorParams) (*Conversation, error) {
 	row := q.db.QueryRow(ctx, setConversationError, arg.ID, arg.ErrorMessage)
 	var i Conversation
@@ -5208,10 +7735,45 @@ func (q *Queries) SetConversationError(ctx context.Context, arg *SetConversation
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.CurrLlmID,
+		&i.Summary,
+		&i.SummaryIndex,
+		&i.ActiveMessageID,
 	)
 	return &i, err
 }
 
+const setConversationMessagesEmbeddingFailed = `-- name: SetConversationMessagesEmbeddingFailed :exec
+UPDATE conversation_message SET
+    embedding_failed_at = CURRENT_TIMESTAMP
+WHERE id = ANY($1::uuid[])
+`
+
+// SetConversationMessagesEmbeddingFailed
+//
+//	UPDATE conversation_message SET
+//	    embedding_failed_at = CURRENT_TIMESTAMP
+//	WHERE id = ANY($1::uuid[])
+func (q *Queries) SetConversationMessagesEmbeddingFailed(ctx context.Context, column1 []uuid.UUID) error {
+	_, err := q.db.Exec(ctx, setConversationMessagesEmbeddingFailed, column1)
+	return err
+}
+
+const setDocumentSummaryFailed = `-- name: SetDocumentSummaryFailed :exec
+UPDATE document SET
+    summary_failed_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+// SetDocumentSummaryFailed
+//
+//	UPDATE document SET
+//	    summary_failed_at = CURRENT_TIMESTAMP
+//	WHERE id = $1
+func (q *Queries) SetDocumentSummaryFailed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, setDocumentSummaryFailed, id)
+	return err
+}
+
 const setProjectIdeaUsed = `-- name: SetProjectIdeaUsed :one
 UPDATE project_idea
     SET used = true
@@ -5271,6 +7833,22 @@ func (q *Queries) SetResumeTitle(ctx context.Context, arg *SetResumeTitleParams)
 	return &i, err
 }
 
+const setWebsitePageSummaryFailed = `-- name: SetWebsitePageSummaryFailed :exec
+UPDATE website_page SET
+    summary_failed_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+// SetWebsitePageSummaryFailed
+//
+//	UPDATE website_page SET
+//	    summary_failed_at = CURRENT_TIMESTAMP
+//	WHERE id = $1
+func (q *Queries) SetWebsitePageSummaryFailed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, setWebsitePageSummaryFailed, id)
+	return err
+}
+
 const setWebsitePagesNotValid = `-- name: SetWebsitePagesNotValid :exec
 UPDATE website_page SET
     updated_at = CURRENT_TIMESTAMP,
@@ -5328,11 +7906,83 @@ func (q *Queries) TouchWebsitePage(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const updateConversationMessageCitations = `-- name: UpdateConversationMessageCitations :exec
+UPDATE conversation_message SET
+    citations = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdateConversationMessageCitationsParams struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Citations []byte    `db:"citations" json:"citations"`
+}
+
+// UpdateConversationMessageCitations
+//
+//	UPDATE conversation_message SET
+//	    citations = $2,
+//	    updated_at = CURRENT_TIMESTAMP
+//	WHERE id = $1
+func (q *Queries) UpdateConversationMessageCitations(ctx context.Context, arg *UpdateConversationMessageCitationsParams) error {
+	_, err := q.db.Exec(ctx, updateConversationMessageCitations, arg.ID, arg.Citations)
+	return err
+}
+
+const updateConversationMessageToolResults = `-- name: UpdateConversationMessageToolResults :exec
+UPDATE conversation_message SET
+    tool_results = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdateConversationMessageToolResultsParams struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	ToolResults []byte    `db:"tool_results" json:"toolResults"`
+}
+
+// UpdateConversationMessageToolResults
+//
+//	UPDATE conversation_message SET
+//	    tool_results = $2,
+//	    updated_at = CURRENT_TIMESTAMP
+//	WHERE id = $1
+func (q *Queries) UpdateConversationMessageToolResults(ctx context.Context, arg *UpdateConversationMessageToolResultsParams) error {
+	_, err := q.db.Exec(ctx, updateConversationMessageToolResults, arg.ID, arg.ToolResults)
+	return err
+}
+
+const updateConversationSummary = `-- name: UpdateConversationSummary :exec
+UPDATE conversation SET
+    summary = $2,
+    summary_index = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdateConversationSummaryParams struct {
+	ID           uuid.UUID `db:"id" json:"id"`
+	Summary      string    `db:"summary" json:"summary"`
+	SummaryIndex int32     `db:"summary_index" json:"summaryIndex"`
+}
+
+// UpdateConversationSummary
+//
+//	UPDATE conversation SET
+//	    summary = $2,
+//	    summary_index = $3,
+//	    updated_at = CURRENT_TIMESTAMP
+//	WHERE id = $1
+func (q *Queries) UpdateConversationSummary(ctx context.Context, arg *UpdateConversationSummaryParams) error {
+	_, err := q.db.Exec(ctx, updateConversationSummary, arg.ID, arg.Summary, arg.SummaryIndex)
+	return err
+}
+
 const updateConversationTitle = `-- name: UpdateConversationTitle :one
 UPDATE conversation SET
     title = $2
 WHERE id = $1
-RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id
+RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id
 `
 
 type UpdateConversationTitleParams struct {
@@ -5345,7 +7995,7 @@ type UpdateConversationTitleParams struct {
 //	UPDATE conversation SET
 //	    title = $2
 //	WHERE id = $1
-//	RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id
+//	RETURNING id, customer_id, title, conversation_type, system_message, metadata, has_error, error_message, created_at, updated_at, curr_llm_id, summary, summary_index, active_message_id
 func (q *Queries) UpdateConversationTitle(ctx context.Context, arg *UpdateConversationTitleParams) (*Conversation, error) {
 	row := q.db.QueryRow(ctx, updateConversationTitle, arg.ID, arg.Title)
 	var i Conversation
@@ -5361,6 +8011,9 @@ func (q *Queries) UpdateConversationTitle(ctx context.Context, arg *UpdateConver
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.CurrLlmID,
+		&i.Summary,
+		&i.SummaryIndex,
+		&i.ActiveMessageID,
 	)
 	return &i, err
 }
@@ -5454,9 +8107,10 @@ const updateDocumentSummary = `-- name: UpdateDocumentSummary :one
 UPDATE document SET
     updated_at = CURRENT_TIMESTAMP,
     summary = $2,
-    summary_sha_256 = $3
+    summary_sha_256 = $3,
+    summary_failed_at = NULL
 WHERE id = $1
-RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize
+RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at
 `
 
 type UpdateDocumentSummaryParams struct {
@@ -5470,9 +8124,10 @@ type UpdateDocumentSummaryParams struct {
 //	UPDATE document SET
 //	    updated_at = CURRENT_TIMESTAMP,
 //	    summary = $2,
-//	    summary_sha_256 = $3
+//	    summary_sha_256 = $3,
+//	    summary_failed_at = NULL
 //	WHERE id = $1
-//	RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize
+//	RETURNING id, parent_id, customer_id, filename, type, size_bytes, sha_256, validated, datastore_type, datastore_id, summary, summary_sha_256, vector_sha_256, created_at, updated_at, is_asset, vectorize, summary_failed_at
 func (q *Queries) UpdateDocumentSummary(ctx context.Context, arg *UpdateDocumentSummaryParams) (*Document, error) {
 	row := q.db.QueryRow(ctx, updateDocumentSummary, arg.ID, arg.Summary, arg.SummarySha256)
 	var i Document
@@ -5494,6 +8149,7 @@ func (q *Queries) UpdateDocumentSummary(ctx context.Context, arg *UpdateDocument
 		&i.UpdatedAt,
 		&i.IsAsset,
 		&i.Vectorize,
+		&i.SummaryFailedAt,
 	)
 	return &i, err
 }
@@ -5505,22 +8161,97 @@ UPDATE document SET
 WHERE id = $1
 `
 
-type UpdateDocumentVectorSigParams struct {
+type UpdateDocumentVectorSigParams struct {
+	ID           uuid.UUID `db:"id" json:"id"`
+	VectorSha256 string    `db:"vector_sha_256" json:"vectorSha256"`
+}
+
+// UpdateDocumentVectorSig
+//
+//	UPDATE document SET
+//	    updated_at = CURRENT_TIMESTAMP,
+//	    vector_sha_256 = $2
+//	WHERE id = $1
+func (q *Queries) UpdateDocumentVectorSig(ctx context.Context, arg *UpdateDocumentVectorSigParams) error {
+	_, err := q.db.Exec(ctx, updateDocumentVectorSig, arg.ID, arg.VectorSha256)
+	return err
+}
+
+const updateFeedItemVectorSig = `-- name: UpdateFeedItemVectorSig :exec
+UPDATE feed_item SET
+    updated_at = CURRENT_TIMESTAMP,
+    vector_sha_256 = $2
+WHERE id = $1
+`
+
+type UpdateFeedItemVectorSigParams struct {
 	ID           uuid.UUID `db:"id" json:"id"`
 	VectorSha256 string    `db:"vector_sha_256" json:"vectorSha256"`
 }
 
-// UpdateDocumentVectorSig
+// UpdateFeedItemVectorSig
 //
-//	UPDATE document SET
+//	UPDATE feed_item SET
 //	    updated_at = CURRENT_TIMESTAMP,
 //	    vector_sha_256 = $2
 //	WHERE id = $1
-func (q *Queries) UpdateDocumentVectorSig(ctx context.Context, arg *UpdateDocumentVectorSigParams) error {
-	_, err := q.db.Exec(ctx, updateDocumentVectorSig, arg.ID, arg.VectorSha256)
+func (q *Queries) UpdateFeedItemVectorSig(ctx context.Context, arg *UpdateFeedItemVectorSigParams) error {
+	_, err := q.db.Exec(ctx, updateFeedItemVectorSig, arg.ID, arg.VectorSha256)
 	return err
 }
 
+const updateFeedPolled = `-- name: UpdateFeedPolled :one
+UPDATE feed SET
+    updated_at = CURRENT_TIMESTAMP,
+    last_polled_at = CURRENT_TIMESTAMP,
+    title = $2,
+    feed_type = $3,
+    last_error = $4
+WHERE id = $1
+RETURNING id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at
+`
+
+type UpdateFeedPolledParams struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Title     string    `db:"title" json:"title"`
+	FeedType  string    `db:"feed_type" json:"feedType"`
+	LastError *string   `db:"last_error" json:"lastError"`
+}
+
+// UpdateFeedPolled
+//
+//	UPDATE feed SET
+//	    updated_at = CURRENT_TIMESTAMP,
+//	    last_polled_at = CURRENT_TIMESTAMP,
+//	    title = $2,
+//	    feed_type = $3,
+//	    last_error = $4
+//	WHERE id = $1
+//	RETURNING id, customer_id, url, title, feed_type, fetch_article, poll_interval_minutes, last_polled_at, last_error, created_at, updated_at
+func (q *Queries) UpdateFeedPolled(ctx context.Context, arg *UpdateFeedPolledParams) (*Feed, error) {
+	row := q.db.QueryRow(ctx, updateFeedPolled,
+		arg.ID,
+		arg.Title,
+		arg.FeedType,
+		arg.LastError,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.Url,
+		&i.Title,
+		&i.FeedType,
+		&i.FetchArticle,
+		&i.PollIntervalMinutes,
+		&i.LastPolledAt,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
 const updateLLM = `-- name: UpdateLLM :one
 UPDATE llm SET
     title = $2,
@@ -5573,12 +8304,58 @@ func (q *Queries) UpdateLLM(ctx context.Context, arg *UpdateLLMParams) (*Llm, er
 	return &i, err
 }
 
+const updateWebsitePageContentType = `-- name: UpdateWebsitePageContentType :exec
+UPDATE website_page SET
+    updated_at = CURRENT_TIMESTAMP,
+    content_type = $2
+WHERE id = $1
+`
+
+type UpdateWebsitePageContentTypeParams struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	ContentType string    `db:"content_type" json:"contentType"`
+}
+
+// UpdateWebsitePageContentType
+//
+//	UPDATE website_page SET
+//	    updated_at = CURRENT_TIMESTAMP,
+//	    content_type = $2
+//	WHERE id = $1
+func (q *Queries) UpdateWebsitePageContentType(ctx context.Context, arg *UpdateWebsitePageContentTypeParams) error {
+	_, err := q.db.Exec(ctx, updateWebsitePageContentType, arg.ID, arg.ContentType)
+	return err
+}
+
+const updateWebsitePageMetadata = `-- name: UpdateWebsitePageMetadata :exec
+UPDATE website_page SET
+    updated_at = CURRENT_TIMESTAMP,
+    metadata = $2
+WHERE id = $1
+`
+
+type UpdateWebsitePageMetadataParams struct {
+	ID       uuid.UUID `db:"id" json:"id"`
+	Metadata []byte    `db:"metadata" json:"metadata"`
+}
+
+// UpdateWebsitePageMetadata
+//
+//	UPDATE website_page SET
+//	    updated_at = CURRENT_TIMESTAMP,
+//	    metadata = $2
+//	WHERE id = $1
+func (q *Queries) UpdateWebsitePageMetadata(ctx context.Context, arg *UpdateWebsitePageMetadataParams) error {
+	_, err := q.db.Exec(ctx, updateWebsitePageMetadata, arg.ID, arg.Metadata)
+	return err
+}
+
 const updateWebsitePageSignature = `-- name: UpdateWebsitePageSignature :one
 UPDATE website_page SET
     updated_at = CURRENT_TIMESTAMP,
     sha_256 = $2
 WHERE id = $1
-RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at
+RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at
 `
 
 type UpdateWebsitePageSignatureParams struct {
@@ -5592,7 +8369,7 @@ type UpdateWebsitePageSignatureParams struct {
 //	    updated_at = CURRENT_TIMESTAMP,
 //	    sha_256 = $2
 //	WHERE id = $1
-//	RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at
+//	RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at
 func (q *Queries) UpdateWebsitePageSignature(ctx context.Context, arg *UpdateWebsitePageSignatureParams) (*WebsitePage, error) {
 	row := q.db.QueryRow(ctx, updateWebsitePageSignature, arg.ID, arg.Sha256)
 	var i WebsitePage
@@ -5609,6 +8386,8 @@ func (q *Queries) UpdateWebsitePageSignature(ctx context.Context, arg *UpdateWeb
 		&i.VectorSha256,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ContentType,
+		&i.SummaryFailedAt,
 	)
 	return &i, err
 }
@@ -5617,9 +8396,10 @@ const updateWebsitePageSummary = `-- name: UpdateWebsitePageSummary :one
 UPDATE website_page SET
     updated_at = CURRENT_TIMESTAMP,
     summary = $2,
-    summary_sha_256 = $3
+    summary_sha_256 = $3,
+    summary_failed_at = NULL
 WHERE id = $1
-RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at
+RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at
 `
 
 type UpdateWebsitePageSummaryParams struct {
@@ -5633,9 +8413,10 @@ type UpdateWebsitePageSummaryParams struct {
 //	UPDATE website_page SET
 //	    updated_at = CURRENT_TIMESTAMP,
 //	    summary = $2,
-//	    summary_sha_256 = $3
+//	    summary_sha_256 = $3,
+//	    summary_failed_at = NULL
 //	WHERE id = $1
-//	RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at
+//	RETURNING id, customer_id, website_id, url, sha_256, is_valid, metadata, summary, summary_sha_256, vector_sha_256, created_at, updated_at, content_type, summary_failed_at
 func (q *Queries) UpdateWebsitePageSummary(ctx context.Context, arg *UpdateWebsitePageSummaryParams) (*WebsitePage, error) {
 	row := q.db.QueryRow(ctx, updateWebsitePageSummary, arg.ID, arg.Summary, arg.SummarySha256)
 	var i WebsitePage
@@ -5652,6 +8433,8 @@ func (q *Queries) UpdateWebsitePageSummary(ctx context.Context, arg *UpdateWebsi
 		&i.VectorSha256,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ContentType,
+		&i.SummaryFailedAt,
 	)
 	return &i, err
 }
@@ -5678,3 +8461,429 @@ func (q *Queries) UpdateWebsitePageVectorSig(ctx context.Context, arg *UpdateWeb
 	_, err := q.db.Exec(ctx, updateWebsitePageVectorSig, arg.ID, arg.VectorSha256)
 	return err
 }
+
+const upsertAvailableModel = `-- name: UpsertAvailableModel :one
+INSERT INTO available_model (
+    id, provider, display_name, description, input_token_limit, output_token_limit,
+    input_cost_per_million_tokens, output_cost_per_million_tokens, is_visible
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (id) DO UPDATE SET
+    provider = EXCLUDED.provider,
+    display_name = EXCLUDED.display_name,
+    description = EXCLUDED.description,
+    input_token_limit = EXCLUDED.input_token_limit,
+    output_token_limit = EXCLUDED.output_token_limit,
+    input_cost_per_million_tokens = EXCLUDED.input_cost_per_million_tokens,
+    output_cost_per_million_tokens = EXCLUDED.output_cost_per_million_tokens,
+    is_visible = EXCLUDED.is_visible,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING id, provider, display_name, description, input_token_limit, output_token_limit, currency, input_cost_per_million_tokens, output_cost_per_million_tokens, depreciated_warning, is_depreciated, created_at, updated_at, is_visible
+`
+
+type UpsertAvailableModelParams struct {
+	ID                         string         `db:"id" json:"id"`
+	Provider                   string         `db:"provider" json:"provider"`
+	DisplayName                string         `db:"display_name" json:"displayName"`
+	Description                string         `db:"description" json:"description"`
+	InputTokenLimit            int32          `db:"input_token_limit" json:"inputTokenLimit"`
+	OutputTokenLimit           int32          `db:"output_token_limit" json:"outputTokenLimit"`
+	InputCostPerMillionTokens  pgtype.Numeric `db:"input_cost_per_million_tokens" json:"inputCostPerMillionTokens"`
+	OutputCostPerMillionTokens pgtype.Numeric `db:"output_cost_per_million_tokens" json:"outputCostPerMillionTokens"`
+	IsVisible                  bool           `db:"is_visible" json:"isVisible"`
+}
+
+// UpsertAvailableModel
+//
+//	INSERT INTO available_model (
+//	    id, provider, display_name, description, input_token_limit, output_token_limit,
+//	    input_cost_per_million_tokens, output_cost_per_million_tokens, is_visible
+//	) VALUES (
+//	    $1, $2, $3, $4, $5, $6, $7, $8, $9
+//	)
+//	ON CONFLICT (id) DO UPDATE SET
+//	    provider = EXCLUDED.provider,
+//	    display_name = EXCLUDED.display_name,
+//	    description = EXCLUDED.description,
+//	    input_token_limit = EXCLUDED.input_token_limit,
+//	    output_token_limit = EXCLUDED.output_token_limit,
+//	    input_cost_per_million_tokens = EXCLUDED.input_cost_per_million_tokens,
+//	    output_cost_per_million_tokens = EXCLUDED.output_cost_per_million_tokens,
+//	    is_visible = EXCLUDED.is_visible,
+//	    updated_at = CURRENT_TIMESTAMP
+//	RETURNING id, provider, display_name, description, input_token_limit, output_token_limit, currency, input_cost_per_million_tokens, output_cost_per_million_tokens, depreciated_warning, is_depreciated, created_at, updated_at, is_visible
+func (q *Queries) UpsertAvailableModel(ctx context.Context, arg *UpsertAvailableModelParams) (*AvailableModel, error) {
+	row := q.db.QueryRow(ctx, upsertAvailableModel,
+		arg.ID,
+		arg.Provider,
+		arg.DisplayName,
+		arg.Description,
+		arg.InputTokenLimit,
+		arg.OutputTokenLimit,
+		arg.InputCostPerMillionTokens,
+		arg.OutputCostPerMillionTokens,
+		arg.IsVisible,
+	)
+	var i AvailableModel
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.DisplayName,
+		&i.Description,
+		&i.InputTokenLimit,
+		&i.OutputTokenLimit,
+		&i.Currency,
+		&i.InputCostPerMillionTokens,
+		&i.OutputCostPerMillionTokens,
+		&i.DepreciatedWarning,
+		&i.IsDepreciated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsVisible,
+	)
+	return &i, err
+}
+
+const upsertCompletionCache = `-- name: UpsertCompletionCache :one
+INSERT INTO completion_cache (
+    customer_id, key, model, message, input_tokens, output_tokens, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (customer_id, key) DO UPDATE SET
+    model = EXCLUDED.model,
+    message = EXCLUDED.message,
+    input_tokens = EXCLUDED.input_tokens,
+    output_tokens = EXCLUDED.output_tokens,
+    expires_at = EXCLUDED.expires_at,
+    hits = 0,
+    last_hit_at = NULL,
+    created_at = CURRENT_TIMESTAMP
+RETURNING customer_id, key, model, message, input_tokens, output_tokens, hits, last_hit_at, expires_at, created_at
+`
+
+type UpsertCompletionCacheParams struct {
+	CustomerID   uuid.UUID          `db:"customer_id" json:"customerId"`
+	Key          string             `db:"key" json:"key"`
+	Model        string             `db:"model" json:"model"`
+	Message      string             `db:"message" json:"message"`
+	InputTokens  int32              `db:"input_tokens" json:"inputTokens"`
+	OutputTokens int32              `db:"output_tokens" json:"outputTokens"`
+	ExpiresAt    pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+}
+
+// UpsertCompletionCache
+//
+//	INSERT INTO completion_cache (
+//	    customer_id, key, model, message, input_tokens, output_tokens, expires_at
+//	) VALUES (
+//	    $1, $2, $3, $4, $5, $6, $7
+//	)
+//	ON CONFLICT (customer_id, key) DO UPDATE SET
+//	    model = EXCLUDED.model,
+//	    message = EXCLUDED.message,
+//	    input_tokens = EXCLUDED.input_tokens,
+//	    output_tokens = EXCLUDED.output_tokens,
+//	    expires_at = EXCLUDED.expires_at,
+//	    hits = 0,
+//	    last_hit_at = NULL,
+//	    created_at = CURRENT_TIMESTAMP
+//	RETURNING customer_id, key, model, message, input_tokens, output_tokens, hits, last_hit_at, expires_at, created_at
+func (q *Queries) UpsertCompletionCache(ctx context.Context, arg *UpsertCompletionCacheParams) (*CompletionCache, error) {
+	row := q.db.QueryRow(ctx, upsertCompletionCache,
+		arg.CustomerID,
+		arg.Key,
+		arg.Model,
+		arg.Message,
+		arg.InputTokens,
+		arg.OutputTokens,
+		arg.ExpiresAt,
+	)
+	var i CompletionCache
+	err := row.Scan(
+		&i.CustomerID,
+		&i.Key,
+		&i.Model,
+		&i.Message,
+		&i.InputTokens,
+		&i.OutputTokens,
+		&i.Hits,
+		&i.LastHitAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return &i, err
+}
+
+const upsertConversationMessageFeedback = `-- name: UpsertConversationMessageFeedback :one
+INSERT INTO conversation_message_feedback (
+    conversation_message_id,
+    conversation_id,
+    customer_id,
+    llm_id,
+    rating,
+    comment,
+    tools,
+    sources
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8 )
+ON CONFLICT (conversation_message_id)
+DO UPDATE SET
+    llm_id = EXCLUDED.llm_id,
+    rating = EXCLUDED.rating,
+    comment = EXCLUDED.comment,
+    tools = EXCLUDED.tools,
+    sources = EXCLUDED.sources,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING id, conversation_message_id, conversation_id, customer_id, llm_id, rating, comment, tools, sources, created_at, updated_at
+`
+
+type UpsertConversationMessageFeedbackParams struct {
+	ConversationMessageID uuid.UUID   `db:"conversation_message_id" json:"conversationMessageId"`
+	ConversationID        uuid.UUID   `db:"conversation_id" json:"conversationId"`
+	CustomerID            uuid.UUID   `db:"customer_id" json:"customerId"`
+	LlmID                 pgtype.UUID `db:"llm_id" json:"llmId"`
+	Rating                int16       `db:"rating" json:"rating"`
+	Comment               string      `db:"comment" json:"comment"`
+	Tools                 []string    `db:"tools" json:"tools"`
+	Sources               []byte      `db:"sources" json:"sources"`
+}
+
+// UpsertConversationMessageFeedback
+//
+//	INSERT INTO conversation_message_feedback (
+//	    conversation_message_id,
+//	    conversation_id,
+//	    customer_id,
+//	    llm_id,
+//	    rating,
+//	    comment,
+//	    tools,
+//	    sources
+//	) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8 )
+//	ON CONFLICT (conversation_message_id)
+//	DO UPDATE SET
+//	    llm_id = EXCLUDED.llm_id,
+//	    rating = EXCLUDED.rating,
+//	    comment = EXCLUDED.comment,
+//	    tools = EXCLUDED.tools,
+//	    sources = EXCLUDED.sources,
+//	    updated_at = CURRENT_TIMESTAMP
+//	RETURNING id, conversation_message_id, conversation_id, customer_id, llm_id, rating, comment, tools, sources, created_at, updated_at
+func (q *Queries) UpsertConversationMessageFeedback(ctx context.Context, arg *UpsertConversationMessageFeedbackParams) (*ConversationMessageFeedback, error) {
+	row := q.db.QueryRow(ctx, upsertConversationMessageFeedback,
+		arg.ConversationMessageID,
+		arg.ConversationID,
+		arg.CustomerID,
+		arg.LlmID,
+		arg.Rating,
+		arg.Comment,
+		arg.Tools,
+		arg.Sources,
+	)
+	var i ConversationMessageFeedback
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationMessageID,
+		&i.ConversationID,
+		&i.CustomerID,
+		&i.LlmID,
+		&i.Rating,
+		&i.Comment,
+		&i.Tools,
+		&i.Sources,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const upsertCustomerToolConfiguration = `-- name: UpsertCustomerToolConfiguration :one
+INSERT INTO customer_tool_configuration (
+    customer_id, conversation_type, enabled_tools, max_depth
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (customer_id, conversation_type) DO UPDATE SET
+    enabled_tools = EXCLUDED.enabled_tools,
+    max_depth = EXCLUDED.max_depth,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING id, customer_id, conversation_type, enabled_tools, max_depth, created_at, updated_at
+`
+
+type UpsertCustomerToolConfigurationParams struct {
+	CustomerID       uuid.UUID `db:"customer_id" json:"customerId"`
+	ConversationType string    `db:"conversation_type" json:"conversationType"`
+	EnabledTools     []string  `db:"enabled_tools" json:"enabledTools"`
+	MaxDepth         int32     `db:"max_depth" json:"maxDepth"`
+}
+
+// UpsertCustomerToolConfiguration
+//
+//	INSERT INTO customer_tool_configuration (
+//	    customer_id, conversation_type, enabled_tools, max_depth
+//	) VALUES (
+//	    $1, $2, $3, $4
+//	)
+//	ON CONFLICT (customer_id, conversation_type) DO UPDATE SET
+//	    enabled_tools = EXCLUDED.enabled_tools,
+//	    max_depth = EXCLUDED.max_depth,
+//	    updated_at = CURRENT_TIMESTAMP
+//	RETURNING id, customer_id, conversation_type, enabled_tools, max_depth, created_at, updated_at
+func (q *Queries) UpsertCustomerToolConfiguration(ctx context.Context, arg *UpsertCustomerToolConfigurationParams) (*CustomerToolConfiguration, error) {
+	row := q.db.QueryRow(ctx, upsertCustomerToolConfiguration,
+		arg.CustomerID,
+		arg.ConversationType,
+		arg.EnabledTools,
+		arg.MaxDepth,
+	)
+	var i CustomerToolConfiguration
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.ConversationType,
+		&i.EnabledTools,
+		&i.MaxDepth,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const upsertDocumentSummaryVector = `-- name: UpsertDocumentSummaryVector :exec
+INSERT INTO document_summary_vector (
+    document_id, customer_id, sha_256, embeddings
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (document_id) DO UPDATE SET
+    sha_256 = EXCLUDED.sha_256,
+    embeddings = EXCLUDED.embeddings,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertDocumentSummaryVectorParams struct {
+	DocumentID uuid.UUID        `db:"document_id" json:"documentId"`
+	CustomerID uuid.UUID        `db:"customer_id" json:"customerId"`
+	Sha256     string           `db:"sha_256" json:"sha256"`
+	Embeddings *pgvector.Vector `db:"embeddings" json:"embeddings"`
+}
+
+// UpsertDocumentSummaryVector
+//
+//	INSERT INTO document_summary_vector (
+//	    document_id, customer_id, sha_256, embeddings
+//	) VALUES (
+//	    $1, $2, $3, $4
+//	)
+//	ON CONFLICT (document_id) DO UPDATE SET
+//	    sha_256 = EXCLUDED.sha_256,
+//	    embeddings = EXCLUDED.embeddings,
+//	    updated_at = CURRENT_TIMESTAMP
+func (q *Queries) UpsertDocumentSummaryVector(ctx context.Context, arg *UpsertDocumentSummaryVectorParams) error {
+	_, err := q.db.Exec(ctx, upsertDocumentSummaryVector,
+		arg.DocumentID,
+		arg.CustomerID,
+		arg.Sha256,
+		arg.Embeddings,
+	)
+	return err
+}
+
+const upsertLLMRetryPolicy = `-- name: UpsertLLMRetryPolicy :one
+INSERT INTO llm_retry_policy (
+    llm_id, max_retries, initial_backoff_ms, max_backoff_ms, fallback_llm_ids
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (llm_id) DO UPDATE SET
+    max_retries = EXCLUDED.max_retries,
+    initial_backoff_ms = EXCLUDED.initial_backoff_ms,
+    max_backoff_ms = EXCLUDED.max_backoff_ms,
+    fallback_llm_ids = EXCLUDED.fallback_llm_ids,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING llm_id, max_retries, initial_backoff_ms, max_backoff_ms, fallback_llm_ids, created_at, updated_at
+`
+
+type UpsertLLMRetryPolicyParams struct {
+	LlmID            uuid.UUID   `db:"llm_id" json:"llmId"`
+	MaxRetries       int32       `db:"max_retries" json:"maxRetries"`
+	InitialBackoffMs int32       `db:"initial_backoff_ms" json:"initialBackoffMs"`
+	MaxBackoffMs     int32       `db:"max_backoff_ms" json:"maxBackoffMs"`
+	FallbackLlmIds   []uuid.UUID `db:"fallback_llm_ids" json:"fallbackLlmIds"`
+}
+
+// UpsertLLMRetryPolicy
+//
+//	INSERT INTO llm_retry_policy (
+//	    llm_id, max_retries, initial_backoff_ms, max_backoff_ms, fallback_llm_ids
+//	) VALUES (
+//	    $1, $2, $3, $4, $5
+//	)
+//	ON CONFLICT (llm_id) DO UPDATE SET
+//	    max_retries = EXCLUDED.max_retries,
+//	    initial_backoff_ms = EXCLUDED.initial_backoff_ms,
+//	    max_backoff_ms = EXCLUDED.max_backoff_ms,
+//	    fallback_llm_ids = EXCLUDED.fallback_llm_ids,
+//	    updated_at = CURRENT_TIMESTAMP
+//	RETURNING llm_id, max_retries, initial_backoff_ms, max_backoff_ms, fallback_llm_ids, created_at, updated_at
+func (q *Queries) UpsertLLMRetryPolicy(ctx context.Context, arg *UpsertLLMRetryPolicyParams) (*LlmRetryPolicy, error) {
+	row := q.db.QueryRow(ctx, upsertLLMRetryPolicy,
+		arg.LlmID,
+		arg.MaxRetries,
+		arg.InitialBackoffMs,
+		arg.MaxBackoffMs,
+		arg.FallbackLlmIds,
+	)
+	var i LlmRetryPolicy
+	err := row.Scan(
+		&i.LlmID,
+		&i.MaxRetries,
+		&i.InitialBackoffMs,
+		&i.MaxBackoffMs,
+		&i.FallbackLlmIds,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
+const upsertWebsitePageSummaryVector = `-- name: UpsertWebsitePageSummaryVector :exec
+INSERT INTO website_page_summary_vector (
+    website_page_id, customer_id, sha_256, embeddings
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (website_page_id) DO UPDATE SET
+    sha_256 = EXCLUDED.sha_256,
+    embeddings = EXCLUDED.embeddings,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertWebsitePageSummaryVectorParams struct {
+	WebsitePageID uuid.UUID        `db:"website_page_id" json:"websitePageId"`
+	CustomerID    uuid.UUID        `db:"customer_id" json:"customerId"`
+	Sha256        string           `db:"sha_256" json:"sha256"`
+	Embeddings    *pgvector.Vector `db:"embeddings" json:"embeddings"`
+}
+
+// UpsertWebsitePageSummaryVector
+//
+//	INSERT INTO website_page_summary_vector (
+//	    website_page_id, customer_id, sha_256, embeddings
+//	) VALUES (
+//	    $1, $2, $3, $4
+//	)
+//	ON CONFLICT (website_page_id) DO UPDATE SET
+//	    sha_256 = EXCLUDED.sha_256,
+//	    embeddings = EXCLUDED.embeddings,
+//	    updated_at = CURRENT_TIMESTAMP
+func (q *Queries) UpsertWebsitePageSummaryVector(ctx context.Context, arg *UpsertWebsitePageSummaryVectorParams) error {
+	_, err := q.db.Exec(ctx, upsertWebsitePageSummaryVector,
+		arg.WebsitePageID,
+		arg.CustomerID,
+		arg.Sha256,
+		arg.Embeddings,
+	)
+	return err
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	db "github.com/sapphirenw/ai-content-creation-api/src/database"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
@@ -63,6 +64,41 @@ func GetTestCustomer(t *testing.T, ctx context.Context, db queries.DBTX) *querie
 	return customer
 }
 
+// Creates an llm of the customer backed by a hidden model of the mock provider, so completions
+// run offline against the responses queued on `llm.Mock` for the model id
+func GetMockLLM(t *testing.T, ctx context.Context, db queries.DBTX, customer *queries.Customer, modelId string) *queries.GetLLMRow {
+	model := queries.New(db)
+
+	var cost pgtype.Numeric
+	require.NoError(t, cost.Scan("0"))
+	_, err := model.UpsertAvailableModel(ctx, &queries.UpsertAvailableModelParams{
+		ID:                         modelId,
+		Provider:                   "mock", // llm.PROVIDER_MOCK, llm imports this package in its tests
+		DisplayName:                modelId,
+		Description:                "Scripted responses for tests",
+		InputTokenLimit:            128000,
+		OutputTokenLimit:           4096,
+		InputCostPerMillionTokens:  cost,
+		OutputCostPerMillionTokens: cost,
+		IsVisible:                  false,
+	})
+	require.NoError(t, err)
+
+	created, err := model.CreateLLM(ctx, &queries.CreateLLMParams{
+		CustomerID:   pgtype.UUID{Bytes: customer.ID, Valid: true},
+		Title:        modelId,
+		Model:        modelId,
+		Temperature:  0,
+		Instructions: "You are a helpful assistant used for testing",
+		IsDefault:    false,
+	})
+	require.NoError(t, err)
+
+	response, err := model.GetLLM(ctx, created.ID)
+	require.NoError(t, err)
+	return response
+}
+
 func GetDefaultLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 }
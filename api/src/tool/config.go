@@ -0,0 +1,185 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+// upper bound of the max depth a customer can configure
+const TOOL_MAX_DEPTH_LIMIT = 20
+
+// max number of citations a single tool call creates. Tool calls that run in parallel number
+// their citations in blocks of this size so the numbers do not collide
+const MAX_TOOL_CITATIONS = 10
+
+/*
+The tools a conversation can call, and the max number of model responses with tool calls in a
+single turn. Once the depth is reached, the turn ends without running the pending tool calls.
+
+A customer can save a configuration for a conversation type, or for every conversation type
+with an empty `ConversationType`, which take precedence over the defaults in code.
+*/
+type ToolConfig struct {
+	ConversationType string     `json:"conversationType"`
+	EnabledTools     []ToolType `json:"enabledTools"`
+	MaxDepth         int        `json:"maxDepth"`
+}
+
+var DefaultToolConfig = &ToolConfig{
+	EnabledTools: []ToolType{VectorQuery, WebSearch, ListFolder, ReadDocument, ReadWebsitePage, DocumentSummary},
+	MaxDepth:     5,
+}
+
+var (
+	toolConfigs   = map[string]*ToolConfig{}
+	toolConfigsMu sync.RWMutex
+)
+
+// Sets the default tool configuration of a conversation type
+func SetToolConfig(conversationType string, config *ToolConfig) {
+	toolConfigsMu.Lock()
+	defer toolConfigsMu.Unlock()
+	toolConfigs[conversationType] = config
+}
+
+// Gets the default tool configuration of a conversation type, or the default when it has none
+func GetDefaultToolConfig(conversationType string) *ToolConfig {
+	toolConfigsMu.RLock()
+	defer toolConfigsMu.RUnlock()
+	if config, ok := toolConfigs[conversationType]; ok {
+		return config
+	}
+	return DefaultToolConfig
+}
+
+func toolConfigFromDB(item *queries.CustomerToolConfiguration) *ToolConfig {
+	config := &ToolConfig{
+		ConversationType: item.ConversationType,
+		EnabledTools:     make([]ToolType, len(item.EnabledTools)),
+		MaxDepth:         int(item.MaxDepth),
+	}
+	for i, name := range item.EnabledTools {
+		config.EnabledTools[i] = ToolType(name)
+	}
+	return config
+}
+
+// Gets the tool configuration the customer uses for the conversation type
+func GetToolConfig(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	conversationType string,
+) (*ToolConfig, error) {
+	dmodel := queries.New(db)
+	response, err := dmodel.GetCustomerToolConfiguration(ctx, &queries.GetCustomerToolConfigurationParams{
+		CustomerID:       customerId,
+		ConversationType: conversationType,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return GetDefaultToolConfig(conversationType), nil
+		}
+		return nil, fmt.Errorf("failed to get the tool configuration: %w", err)
+	}
+	return toolConfigFromDB(response), nil
+}
+
+// Gets the tool configurations the customer saved
+func GetCustomerToolConfigs(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+) ([]*ToolConfig, error) {
+	dmodel := queries.New(db)
+	items, err := dmodel.GetCustomerToolConfigurations(ctx, customerId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the tool configurations: %w", err)
+	}
+	response := make([]*ToolConfig, len(items))
+	for i, item := range items {
+		response[i] = toolConfigFromDB(item)
+	}
+	return response, nil
+}
+
+// Saves the configuration for the customer, replacing the configuration of the same
+// conversation type
+func SaveCustomerToolConfig(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	config *ToolConfig,
+) (*ToolConfig, error) {
+	if problems := config.Valid(ctx); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid tool configuration: %v", problems)
+	}
+	enabled := make([]string, len(config.EnabledTools))
+	for i, name := range config.EnabledTools {
+		enabled[i] = string(name)
+	}
+
+	dmodel := queries.New(db)
+	response, err := dmodel.UpsertCustomerToolConfiguration(ctx, &queries.UpsertCustomerToolConfigurationParams{
+		CustomerID:       customerId,
+		ConversationType: config.ConversationType,
+		EnabledTools:     enabled,
+		MaxDepth:         int32(config.MaxDepth),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save the tool configuration: %w", err)
+	}
+	return toolConfigFromDB(response), nil
+}
+
+// Removes the configuration the customer saved for the conversation type
+func DeleteCustomerToolConfig(
+	ctx context.Context,
+	db queries.DBTX,
+	customerId uuid.UUID,
+	conversationType string,
+) error {
+	dmodel := queries.New(db)
+	if err := dmodel.DeleteCustomerToolConfiguration(ctx, &queries.DeleteCustomerToolConfigurationParams{
+		CustomerID:       customerId,
+		ConversationType: conversationType,
+	}); err != nil {
+		return fmt.Errorf("failed to delete the tool configuration: %w", err)
+	}
+	return nil
+}
+
+func (config *ToolConfig) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string)
+	if config.MaxDepth < 1 || config.MaxDepth > TOOL_MAX_DEPTH_LIMIT {
+		p["maxDepth"] = fmt.Sprintf("must be between 1 and %d", TOOL_MAX_DEPTH_LIMIT)
+	}
+	for i, name := range config.EnabledTools {
+		if _, err := GetToolType(string(name)); err != nil {
+			p[fmt.Sprintf("enabledTools[%d]", i)] = err.Error()
+		}
+	}
+	return p
+}
+
+// Creates the enabled tools that can run in this environment. Tools that are no longer
+// registered are skipped
+func (config *ToolConfig) Tools() []Tool {
+	tools := make([]Tool, 0, len(config.EnabledTools))
+	for _, name := range config.EnabledTools {
+		if !Available(name) {
+			continue
+		}
+		item, err := NewTool(name)
+		if err != nil {
+			continue
+		}
+		tools = append(tools, item)
+	}
+	return tools
+}
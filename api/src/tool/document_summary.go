@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/ltypes"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+type ToolDocumentSummary struct{}
+
+func init() {
+	Register(DocumentSummary, func() Tool { return newToolDocumentSummary() }, nil)
+}
+
+func newToolDocumentSummary() *ToolDocumentSummary {
+	return &ToolDocumentSummary{}
+}
+
+func (t *ToolDocumentSummary) GetType() ToolType {
+	return DocumentSummary
+}
+
+func (t *ToolDocumentSummary) GetSchema() *gollm.Tool {
+	return &gollm.Tool{
+		Title:       string(t.GetType()),
+		Description: "Get the stored summary of one of the user's documents by its id, which you can find with the list_folder tool. This is faster than reading the whole document, so prefer it when the user asks for an overview of a document. When no summary is stored, read the document with the read_document tool instead.",
+		Schema: &ltypes.ToolSchema{
+			Type: "object",
+			Properties: map[string]*ltypes.ToolSchema{
+				"document_id": {
+					Type:        "string",
+					Description: "The id of the document to get the summary of.",
+				},
+			},
+		},
+	}
+}
+
+func (t *ToolDocumentSummary) Run(
+	ctx context.Context,
+	l *slog.Logger,
+	args *RunToolArgs,
+) (*ToolResponse, error) {
+	logger := l.With("tool", t.GetType())
+	if err := args.Validate(); err != nil {
+		return nil, slogger.Error(ctx, logger, "ARGUMENT ERROR", err)
+	}
+
+	// ensure the arguments are present
+	rawId, ok := args.LastMessage.ToolArguments["document_id"].(string)
+	if !ok || strings.TrimSpace(rawId) == "" {
+		return nil, slogger.Error(ctx, logger, "the argument 'document_id' does not exist", nil)
+	}
+
+	doc, problem := getCustomerDocument(ctx, logger, args, rawId)
+	if problem != "" {
+		return textResponse(args, problem, nil), nil
+	}
+
+	// summaries of outdated content are not returned
+	summary := strings.TrimSpace(doc.CurrentSummary())
+	if summary == "" {
+		return textResponse(args, fmt.Sprintf("No summary is stored for %s. Read it with the read_document tool instead.", doc.Filename), nil), nil
+	}
+
+	citation := documentCitation(args, doc, summary, nil)
+	return citedResponse(args, fmt.Sprintf("[Document Summary]:\n[%d] %s\n%s", citation.Number, doc.Filename, summary), citation, nil), nil
+}
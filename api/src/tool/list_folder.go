@@ -0,0 +1,123 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/ltypes"
+	"github.com/sapphirenw/ai-content-creation-api/src/datastore"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+)
+
+// max number of folders and of documents passed back to the model
+const listFolderMaxItems = 100
+
+type ToolListFolder struct{}
+
+func init() {
+	Register(ListFolder, func() Tool { return newToolListFolder() }, nil)
+}
+
+func newToolListFolder() *ToolListFolder {
+	return &ToolListFolder{}
+}
+
+func (t *ToolListFolder) GetType() ToolType {
+	return ListFolder
+}
+
+func (t *ToolListFolder) GetSchema() *gollm.Tool {
+	return &gollm.Tool{
+		Title:       string(t.GetType()),
+		Description: "List the folders and documents the user has stored in a folder, or in their root folder. Use this to find a document the user refers to by its name or its location, then read it with its id. Browse into a folder by calling this tool again with the id of the folder.",
+		Schema: &ltypes.ToolSchema{
+			Type: "object",
+			Properties: map[string]*ltypes.ToolSchema{
+				"folder_id": {
+					Type:        "string",
+					Description: "Optional id of the folder to list. Leave it empty to list the root folder of the user.",
+				},
+			},
+		},
+	}
+}
+
+func (t *ToolListFolder) Run(
+	ctx context.Context,
+	l *slog.Logger,
+	args *RunToolArgs,
+) (*ToolResponse, error) {
+	logger := l.With("tool", t.GetType())
+	if err := args.Validate(); err != nil {
+		return nil, slogger.Error(ctx, logger, "ARGUMENT ERROR", err)
+	}
+
+	var folderId pgtype.UUID
+	if raw, _ := args.LastMessage.ToolArguments["folder_id"].(string); strings.TrimSpace(raw) != "" {
+		id, err := utils.GoogleUUIDFromString(strings.TrimSpace(raw))
+		if err != nil {
+			return textResponse(args, fmt.Sprintf("'%s' is not a valid folder id.", raw), nil), nil
+		}
+		folderId = utils.GoogleUUIDToPGXUUID(id)
+	}
+
+	// a folder that cannot be found is reported to the model so it can list another one
+	contents, err := datastore.ListFolderContents(ctx, logger, args.Database, args.Customer.ID, folderId)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to list the folder", "error", err)
+		return textResponse(args, "The folder does not exist. List the root folder to find the available folders.", nil), nil
+	}
+
+	return textResponse(args, formatFolderContents(contents), map[string]any{"contents": contents}), nil
+}
+
+// lists the folders and the documents with their ids so the model can call the other tools
+func formatFolderContents(contents *datastore.FolderContents) string {
+	buf := new(strings.Builder)
+	if contents.Self == nil {
+		buf.WriteString("[Folder: root]\n")
+	} else {
+		buf.WriteString(fmt.Sprintf("[Folder: %s]\n", contents.Self.Title))
+	}
+
+	buf.WriteString("Folders:\n")
+	if len(contents.Folders) == 0 {
+		buf.WriteString("(none)\n")
+	}
+	for i, item := range contents.Folders {
+		if i == listFolderMaxItems {
+			buf.WriteString(fmt.Sprintf("... and %d more folders\n", len(contents.Folders)-i))
+			break
+		}
+		buf.WriteString(fmt.Sprintf("- %s (id: %s)\n", item.Title, item.ID))
+	}
+
+	buf.WriteString("Documents:\n")
+	if len(contents.Documents) == 0 {
+		buf.WriteString("(none)\n")
+	}
+	for i, item := range contents.Documents {
+		if i == listFolderMaxItems {
+			buf.WriteString(fmt.Sprintf("... and %d more documents\n", len(contents.Documents)-i))
+			break
+		}
+		buf.WriteString(fmt.Sprintf("- %s (id: %s, type: %s, size: %d bytes", item.Filename, item.ID, item.Type, item.SizeBytes))
+		if (&datastore.Document{Document: item}).CurrentSummary() != "" {
+			buf.WriteString(", summary available")
+		}
+		buf.WriteString(")\n")
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// creates the tool result from the content without any citations
+func textResponse(args *RunToolArgs, content string, arguments map[string]any) *ToolResponse {
+	message := gollm.NewToolResultMessage(args.LastMessage.ToolUseID, args.LastMessage.ToolName, content)
+	message.ToolArguments = arguments
+	return &ToolResponse{Message: message}
+}
@@ -0,0 +1,228 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/ltypes"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
+	"github.com/sapphirenw/ai-content-creation-api/src/datastore"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+// max number of characters of content passed back to the model in a single call
+const readContentMaxLength = 16000
+
+// max number of chunks passed back to the model in a single call
+const readDocumentMaxChunks = 8
+
+type ToolReadDocument struct{}
+
+func init() {
+	Register(ReadDocument, func() Tool { return newToolReadDocument() }, nil)
+}
+
+func newToolReadDocument() *ToolReadDocument {
+	return &ToolReadDocument{}
+}
+
+func (t *ToolReadDocument) GetType() ToolType {
+	return ReadDocument
+}
+
+func (t *ToolReadDocument) GetSchema() *gollm.Tool {
+	return &gollm.Tool{
+		Title:       string(t.GetType()),
+		Description: "Read the text of one of the user's documents by its id, which you can find with the list_folder tool. Short documents are returned whole. Long documents are returned in numbered chunks, read the rest of the document by calling this tool again with the next chunk range. Cite the document by its number when using its content in your answer.",
+		Schema: &ltypes.ToolSchema{
+			Type: "object",
+			Properties: map[string]*ltypes.ToolSchema{
+				"document_id": {
+					Type:        "string",
+					Description: "The id of the document to read.",
+				},
+				"start_chunk": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Optional index of the first chunk to read, starting at 0. Up to %d chunks are returned at a time.", readDocumentMaxChunks),
+				},
+				"end_chunk": {
+					Type:        "integer",
+					Description: "Optional index of the last chunk to read, inclusive.",
+				},
+			},
+		},
+	}
+}
+
+func (t *ToolReadDocument) Run(
+	ctx context.Context,
+	l *slog.Logger,
+	args *RunToolArgs,
+) (*ToolResponse, error) {
+	logger := l.With("tool", t.GetType())
+	if err := args.Validate(); err != nil {
+		return nil, slogger.Error(ctx, logger, "ARGUMENT ERROR", err)
+	}
+
+	// ensure the arguments are present
+	rawId, ok := args.LastMessage.ToolArguments["document_id"].(string)
+	if !ok || strings.TrimSpace(rawId) == "" {
+		return nil, slogger.Error(ctx, logger, "the argument 'document_id' does not exist", nil)
+	}
+	start, hasStart := intArgument(args.LastMessage.ToolArguments["start_chunk"])
+	end, hasEnd := intArgument(args.LastMessage.ToolArguments["end_chunk"])
+
+	doc, problem := getCustomerDocument(ctx, logger, args, rawId)
+	if problem != "" {
+		return textResponse(args, problem, nil), nil
+	}
+	logger = logger.With("documentId", doc.ID)
+
+	// return short documents whole when no range was asked for
+	if !hasStart && !hasEnd {
+		logger.InfoContext(ctx, "Reading the document ...")
+		cleaned, err := doc.GetCleaned(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to read the document", "error", err)
+			return textResponse(args, fmt.Sprintf("Failed to read %s.", doc.Filename), nil), nil
+		}
+		content := strings.TrimSpace(cleaned.String())
+		if utf8.RuneCountInString(content) <= readContentMaxLength {
+			citation := documentCitation(args, doc, content, nil)
+			return citedResponse(args, fmt.Sprintf("[Document]:\n[%d] %s\n%s", citation.Number, doc.Filename, content), citation, nil), nil
+		}
+	}
+
+	logger.InfoContext(ctx, "Reading the chunks of the document ...", "start", start, "end", end)
+	chunks, err := doc.GetChunks(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to chunk the document", "error", err)
+		return textResponse(args, fmt.Sprintf("Failed to read %s.", doc.Filename), nil), nil
+	}
+	if len(chunks) == 0 {
+		return textResponse(args, fmt.Sprintf("%s has no readable text.", doc.Filename), nil), nil
+	}
+
+	start, end = chunkRange(start, end, hasEnd, len(chunks))
+	if start >= len(chunks) {
+		return textResponse(args, fmt.Sprintf("%s only has %d chunks, numbered 0 to %d.", doc.Filename, len(chunks), len(chunks)-1), nil), nil
+	}
+
+	// keep whole chunks until the content is too long, always keeping the first one
+	buf := new(strings.Builder)
+	last := start
+	for i := start; i <= end; i++ {
+		chunk := strings.TrimSpace(chunks[i])
+		if i != start && utf8.RuneCountInString(buf.String())+utf8.RuneCountInString(chunk) > readContentMaxLength {
+			break
+		}
+		buf.WriteString(fmt.Sprintf("(chunk %d)\n%s\n\n", i, chunk))
+		last = i
+	}
+	content := strings.TrimSpace(buf.String())
+
+	index := int32(start)
+	citation := documentCitation(args, doc, content, &index)
+	header := fmt.Sprintf("[Document]:\n[%d] %s, chunks %d to %d of %d chunks numbered 0 to %d", citation.Number, doc.Filename, start, last, len(chunks), len(chunks)-1)
+	if last < len(chunks)-1 {
+		header += fmt.Sprintf(". Read on from chunk %d to continue", last+1)
+	}
+	return citedResponse(args, header+"\n"+content, citation, map[string]any{"start": start, "end": last, "chunks": len(chunks)}), nil
+}
+
+/*
+Bounds the requested chunk range to the document and to `readDocumentMaxChunks`. The range
+starts at the first chunk when no start was given, and a start past the last chunk is returned
+unchanged so it can be reported.
+*/
+func chunkRange(start int, end int, hasEnd bool, length int) (int, int) {
+	start = max(0, start)
+	if !hasEnd || end < start {
+		end = start + readDocumentMaxChunks - 1
+	}
+	end = min(end, start+readDocumentMaxChunks-1, length-1)
+	return start, end
+}
+
+/*
+Gets a document of the customer from the id the model passed. When the document cannot be used,
+the reason is returned as a message for the model instead, so it can correct the call.
+*/
+func getCustomerDocument(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *RunToolArgs,
+	rawId string,
+) (*datastore.Document, string) {
+	id, err := uuid.Parse(strings.TrimSpace(rawId))
+	if err != nil {
+		return nil, fmt.Sprintf("'%s' is not a valid document id. Use the list_folder tool to find the id of the document.", rawId)
+	}
+	doc, err := datastore.GetDocument(ctx, logger, args.Database, id)
+	if err != nil {
+		if !strings.Contains(err.Error(), "no rows in result set") {
+			logger.ErrorContext(ctx, "failed to get the document", "documentId", id, "error", err)
+		}
+		return nil, "The document does not exist. Use the list_folder tool to find the id of the document."
+	}
+
+	// documents of other customers are treated as not existing
+	if doc.CustomerID != args.Customer.ID {
+		return nil, "The document does not exist. Use the list_folder tool to find the id of the document."
+	}
+	return doc, ""
+}
+
+func documentCitation(
+	args *RunToolArgs,
+	doc *datastore.Document,
+	content string,
+	chunkIndex *int32,
+) *conversation.Citation {
+	id := doc.ID
+	return &conversation.Citation{
+		Number:     args.CitationOffset + 1,
+		SourceType: conversation.CITATION_SOURCE_DOCUMENT,
+		SourceID:   &id,
+		Title:      doc.Filename,
+		ChunkIndex: chunkIndex,
+		Snippet:    conversation.CitationSnippet(content),
+	}
+}
+
+// creates the tool result from the content of a single source
+func citedResponse(
+	args *RunToolArgs,
+	content string,
+	citation *conversation.Citation,
+	arguments map[string]any,
+) *ToolResponse {
+	response := textResponse(args, content, arguments)
+	response.Citations = []*conversation.Citation{citation}
+	return response
+}
+
+// models send integers as numbers or as strings
+func intArgument(val any) (int, bool) {
+	switch v := val.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, false
+		}
+		return int(v), true
+	case int:
+		return v, true
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,93 @@
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/sapphirenw/ai-content-creation-api/src/datastore"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentToolsRegistered(t *testing.T) {
+	for _, name := range []ToolType{ListFolder, ReadDocument, ReadWebsitePage, DocumentSummary} {
+		require.True(t, Available(name))
+		require.Contains(t, DefaultToolConfig.EnabledTools, name)
+		item, err := NewTool(name)
+		require.NoError(t, err)
+		require.Equal(t, string(name), item.GetSchema().Title)
+	}
+}
+
+func TestChunkRange(t *testing.T) {
+	// no range reads from the first chunk
+	start, end := chunkRange(0, 0, false, 20)
+	require.Equal(t, 0, start)
+	require.Equal(t, readDocumentMaxChunks-1, end)
+
+	// the range is bounded by the document
+	start, end = chunkRange(3, 40, true, 5)
+	require.Equal(t, 3, start)
+	require.Equal(t, 4, end)
+
+	// the range is bounded by the max number of chunks
+	start, end = chunkRange(-2, 30, true, 40)
+	require.Equal(t, 0, start)
+	require.Equal(t, readDocumentMaxChunks-1, end)
+
+	// a start past the end is kept so it can be reported
+	start, _ = chunkRange(9, 0, false, 5)
+	require.Equal(t, 9, start)
+}
+
+func TestIntArgument(t *testing.T) {
+	val, ok := intArgument(float64(3))
+	require.True(t, ok)
+	require.Equal(t, 3, val)
+
+	val, ok = intArgument(" 7 ")
+	require.True(t, ok)
+	require.Equal(t, 7, val)
+
+	_, ok = intArgument("seven")
+	require.False(t, ok)
+	_, ok = intArgument(nil)
+	require.False(t, ok)
+}
+
+func TestReadDocumentInvalidId(t *testing.T) {
+	args := &RunToolArgs{
+		Customer: &queries.Customer{ID: uuid.New()},
+		LastMessage: &gollm.Message{
+			Role:          gollm.RoleToolCall,
+			ToolUseID:     "call_1",
+			ToolName:      string(ReadDocument),
+			ToolArguments: map[string]any{"document_id": "not-an-id"},
+		},
+	}
+	doc, problem := getCustomerDocument(context.TODO(), nil, args, "not-an-id")
+	require.Nil(t, doc)
+	require.Contains(t, problem, "not a valid document id")
+}
+
+func TestFormatFolderContents(t *testing.T) {
+	folder := &queries.Folder{ID: uuid.New(), Title: "Strategy"}
+	summarized := &queries.Document{ID: uuid.New(), Filename: "q3-planning.md", Type: "md", Sha256: "abc", Summary: "The plan", SummarySha256: "abc"}
+	outdated := &queries.Document{ID: uuid.New(), Filename: "notes.txt", Type: "txt", Sha256: "def", Summary: "Old", SummarySha256: "abc"}
+
+	content := formatFolderContents(&datastore.FolderContents{
+		Self:      folder,
+		Folders:   []*queries.Folder{},
+		Documents: []*queries.Document{summarized, outdated},
+	})
+	require.Contains(t, content, "[Folder: Strategy]")
+	require.Contains(t, content, "Folders:\n(none)")
+	require.Contains(t, content, "q3-planning.md (id: "+summarized.ID.String()+", type: md, size: 0 bytes, summary available)")
+	require.Contains(t, content, "notes.txt (id: "+outdated.ID.String()+", type: txt, size: 0 bytes)")
+
+	root := formatFolderContents(&datastore.FolderContents{Folders: []*queries.Folder{folder}})
+	require.Contains(t, root, "[Folder: root]")
+	require.Contains(t, root, "- Strategy (id: "+folder.ID.String()+")")
+}
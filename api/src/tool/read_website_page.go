@@ -0,0 +1,144 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/ltypes"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
+	"github.com/sapphirenw/ai-content-creation-api/src/datastore"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+)
+
+type ToolReadWebsitePage struct{}
+
+func init() {
+	Register(ReadWebsitePage, func() Tool { return newToolReadWebsitePage() }, nil)
+}
+
+func newToolReadWebsitePage() *ToolReadWebsitePage {
+	return &ToolReadWebsitePage{}
+}
+
+func (t *ToolReadWebsitePage) GetType() ToolType {
+	return ReadWebsitePage
+}
+
+func (t *ToolReadWebsitePage) GetSchema() *gollm.Tool {
+	return &gollm.Tool{
+		Title:       string(t.GetType()),
+		Description: "Read the current content of a page of one of the websites the user has stored, by its url or its id. Use this when a search result from the user's information comes from a website page and you need more of the page than the result contains. Cite the page by its number when using its content in your answer.",
+		Schema: &ltypes.ToolSchema{
+			Type: "object",
+			Properties: map[string]*ltypes.ToolSchema{
+				"url": {
+					Type:        "string",
+					Description: "The url of the page to read, exactly as it was returned to you.",
+				},
+				"page_id": {
+					Type:        "string",
+					Description: "Optional id of the page to read instead of the url.",
+				},
+			},
+		},
+	}
+}
+
+func (t *ToolReadWebsitePage) Run(
+	ctx context.Context,
+	l *slog.Logger,
+	args *RunToolArgs,
+) (*ToolResponse, error) {
+	logger := l.With("tool", t.GetType())
+	if err := args.Validate(); err != nil {
+		return nil, slogger.Error(ctx, logger, "ARGUMENT ERROR", err)
+	}
+
+	// ensure the arguments are present
+	rawUrl, _ := args.LastMessage.ToolArguments["url"].(string)
+	rawId, _ := args.LastMessage.ToolArguments["page_id"].(string)
+	if strings.TrimSpace(rawUrl) == "" && strings.TrimSpace(rawId) == "" {
+		return nil, slogger.Error(ctx, logger, "the arguments 'url' and 'page_id' do not exist", nil)
+	}
+
+	page, problem := getCustomerWebsitePage(ctx, logger, args, strings.TrimSpace(rawUrl), strings.TrimSpace(rawId))
+	if problem != "" {
+		return textResponse(args, problem, nil), nil
+	}
+	logger = logger.With("pageId", page.ID)
+
+	// the page is fetched again so the content is current
+	logger.InfoContext(ctx, "Reading the website page ...", "url", page.Url)
+	obj, err := datastore.NewWebsitePageFromWebsitePage(ctx, logger, page)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to create the website page", err)
+	}
+	cleaned, err := obj.GetCleaned(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to read the website page", "error", err)
+		return textResponse(args, fmt.Sprintf("Failed to read %s.", page.Url), nil), nil
+	}
+	content := strings.TrimSpace(cleaned.String())
+	if obj.SkipReason() != "" || content == "" {
+		return textResponse(args, fmt.Sprintf("%s has no readable content.", page.Url), nil), nil
+	}
+	if utf8.RuneCountInString(content) > readContentMaxLength {
+		content = string([]rune(content)[:readContentMaxLength]) + "\n(the rest of the page was cut off)"
+	}
+
+	id := page.ID
+	citation := &conversation.Citation{
+		Number:     args.CitationOffset + 1,
+		SourceType: conversation.CITATION_SOURCE_WEBSITE_PAGE,
+		SourceID:   &id,
+		Title:      page.Url,
+		Url:        page.Url,
+		Snippet:    conversation.CitationSnippet(content),
+	}
+	return citedResponse(args, fmt.Sprintf("[Website Page]:\n[%d] %s\n%s", citation.Number, page.Url, content), citation, nil), nil
+}
+
+// Gets a website page of the customer by its id, or by its url when no id was passed. When the
+// page cannot be used, the reason is returned as a message for the model instead
+func getCustomerWebsitePage(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *RunToolArgs,
+	rawUrl string,
+	rawId string,
+) (*queries.WebsitePage, string) {
+	dmodel := queries.New(args.Database)
+
+	var page *queries.WebsitePage
+	var err error
+	if rawId != "" {
+		id, parseErr := uuid.Parse(rawId)
+		if parseErr != nil {
+			return nil, fmt.Sprintf("'%s' is not a valid page id.", rawId)
+		}
+		page, err = dmodel.GetWebsitePage(ctx, id)
+	} else {
+		page, err = dmodel.GetWebsitePageByUrl(ctx, &queries.GetWebsitePageByUrlParams{
+			CustomerID: args.Customer.ID,
+			Url:        rawUrl,
+		})
+	}
+	if err != nil {
+		if !strings.Contains(err.Error(), "no rows in result set") {
+			logger.ErrorContext(ctx, "failed to get the website page", "error", err)
+		}
+		return nil, "The page is not one of the user's stored website pages."
+	}
+
+	// pages of other customers are treated as not existing
+	if page.CustomerID != args.Customer.ID {
+		return nil, "The page is not one of the user's stored website pages."
+	}
+	return page, ""
+}
@@ -0,0 +1,71 @@
+package tool
+
+import (
+	"fmt"
+	"sync"
+)
+
+type registeredTool struct {
+	create    func() Tool
+	available func() bool // nil when the tool is always available
+}
+
+var (
+	registry      = make(map[ToolType]*registeredTool)
+	registryOrder = make([]ToolType, 0) // registration order, so the tools are sent to the model in a stable order
+	registryMu    sync.RWMutex
+)
+
+// Registers a tool under its name, tools register themselves in an `init` function of their
+// file. `available` reports whether the tool can run in this environment (for example when it
+// needs an api key), and can be nil. Registering the same name twice panics.
+func Register(name ToolType, create func() Tool, available func() bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("FATAL: the tool is registered twice: %s", name))
+	}
+	registry[name] = &registeredTool{create: create, available: available}
+	registryOrder = append(registryOrder, name)
+}
+
+// Gets the tool type of a registered tool from its name
+func GetToolType(input string) (ToolType, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if _, exists := registry[ToolType(input)]; !exists {
+		return "", fmt.Errorf("invalid tool type: %s", input)
+	}
+	return ToolType(input), nil
+}
+
+// Creates a registered tool from its name
+func NewTool(name ToolType) (Tool, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	item, exists := registry[name]
+	if !exists {
+		return nil, fmt.Errorf("invalid tool type: %s", name)
+	}
+	return item.create(), nil
+}
+
+// Whether the tool is registered and can run in this environment
+func Available(name ToolType) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	item, exists := registry[name]
+	if !exists {
+		return false
+	}
+	return item.available == nil || item.available()
+}
+
+// The names of all registered tools in registration order
+func RegisteredTools() []ToolType {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	response := make([]ToolType, len(registryOrder))
+	copy(response, registryOrder)
+	return response
+}
@@ -0,0 +1,52 @@
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	require.Contains(t, RegisteredTools(), VectorQuery)
+	require.Contains(t, RegisteredTools(), WebSearch)
+
+	toolType, err := GetToolType("vector_query")
+	require.NoError(t, err)
+	item, err := NewTool(toolType)
+	require.NoError(t, err)
+	require.Equal(t, VectorQuery, item.GetType())
+
+	_, err = GetToolType("unknown")
+	require.Error(t, err)
+	_, err = NewTool("unknown")
+	require.Error(t, err)
+	require.False(t, Available("unknown"))
+
+	require.Panics(t, func() {
+		Register(VectorQuery, func() Tool { return newToolVectorQuery() }, nil)
+	})
+}
+
+func TestToolConfig(t *testing.T) {
+	config := &ToolConfig{
+		EnabledTools: []ToolType{VectorQuery},
+		MaxDepth:     3,
+	}
+	require.Empty(t, config.Valid(context.TODO()))
+
+	tools := config.Tools()
+	require.Len(t, tools, 1)
+	require.Equal(t, VectorQuery, tools[0].GetType())
+
+	config.EnabledTools = append(config.EnabledTools, "unknown")
+	config.MaxDepth = 0
+	problems := config.Valid(context.TODO())
+	require.Contains(t, problems, "maxDepth")
+	require.Contains(t, problems, "enabledTools[1]")
+
+	// the defaults of a conversation type
+	require.Equal(t, DefaultToolConfig, GetDefaultToolConfig("test"))
+	SetToolConfig("test", config)
+	require.Equal(t, config, GetDefaultToolConfig("test"))
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jake-landersweb/gollm/v2/src/gollm"
 	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
 	"github.com/sapphirenw/ai-content-creation-api/src/llm"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
 )
@@ -14,23 +16,33 @@ import (
 type ToolType string
 
 const (
-	VectorQuery ToolType = "vector_query"
+	VectorQuery     ToolType = "vector_query"
+	WebSearch       ToolType = "web_search"
+	ListFolder      ToolType = "list_folder"
+	ReadDocument    ToolType = "read_document"
+	ReadWebsitePage ToolType = "read_website_page"
+	DocumentSummary ToolType = "get_document_summary"
 )
 
-func GetToolType(input string) (ToolType, error) {
-	switch input {
-	case string(VectorQuery):
-		return VectorQuery, nil
-	default:
-		return "", fmt.Errorf("invalid tool type: %s", input)
-	}
-}
-
 type RunToolArgs struct {
 	Database    queries.DBTX
+	Pool        *pgxpool.Pool // for the work of the tool that runs on several goroutines, the database is used when nil
 	Customer    *queries.Customer
 	LastMessage *gollm.Message
 	ToolLLM     *llm.LLM
+
+	// number of citations already created in the turn, the citations of the tool are
+	// numbered after them
+	CitationOffset int
+}
+
+// The database to use from several goroutines at once. A transaction cannot be shared between
+// goroutines, so the pool is used when the tool runs inside of one
+func (args *RunToolArgs) concurrentDB() queries.DBTX {
+	if args.Pool != nil {
+		return args.Pool
+	}
+	return args.Database
 }
 
 func (args *RunToolArgs) Validate() error {
@@ -52,6 +64,13 @@ func (args *RunToolArgs) Validate() error {
 type ToolResponse struct {
 	Message      *gollm.Message
 	UsageRecords []*tokens.UsageRecord
+	Citations    []*conversation.Citation
+}
+
+// The response of a tool that failed after it used tokens. It is returned with the error so
+// the caller still records the usage
+func usedTokens(records []*tokens.UsageRecord) *ToolResponse {
+	return &ToolResponse{UsageRecords: records}
 }
 
 type Tool interface {
@@ -64,14 +83,6 @@ type Tool interface {
 	GetType() ToolType
 }
 
-func NewTool(name ToolType) Tool {
-	switch name {
-	case VectorQuery:
-		return newToolVectorQuery()
-	}
-	panic(fmt.Sprintf("FATAL: invalid place of program reached. name is invalid: %s", name))
-}
-
 func ToolsToGollm(tools []Tool) []*gollm.Tool {
 	response := make([]*gollm.Tool, len(tools))
 	for i, item := range tools {
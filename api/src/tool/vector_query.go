@@ -1,15 +1,17 @@
 package tool
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/jake-landersweb/gollm/v2/src/gollm"
 	"github.com/jake-landersweb/gollm/v2/src/ltypes"
 	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
 	"github.com/sapphirenw/ai-content-creation-api/src/llm"
 	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
@@ -18,8 +20,16 @@ import (
 	"github.com/sapphirenw/ai-content-creation-api/src/vectorstore"
 )
 
+// max number of summaries a single call cites. They take the place of chunks, as a summary
+// covers a whole document or page
+const MAX_TOOL_SUMMARIES = 2
+
 type ToolVectorQuery struct{}
 
+func init() {
+	Register(VectorQuery, func() Tool { return newToolVectorQuery() }, nil)
+}
+
 func newToolVectorQuery() *ToolVectorQuery {
 	return &ToolVectorQuery{}
 }
@@ -75,9 +85,15 @@ func (t *ToolVectorQuery) Run(
 	}
 	simpleQueryLLM := llm.FromObjects(&tmp.Llm, &tmp.AvailableModel)
 
+	// get the query prompt the customer uses
+	simpleQueryPrompt, _, err := prompts.Render(ctx, logger, args.Database, args.Customer.ID, prompts.TEMPLATE_RAG_SIMPLE_QUERY, nil)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to get the simple query prompt", err)
+	}
+
 	// run a single completion
-	simpleQueryResponse, err := simpleQueryLLM.SingleCompletion(
-		ctx, logger, args.Customer.ID, prompts.RAG_SIMPLE_QUERY_SYSTEM_PROMPT,
+	simpleQueryResponse, err := simpleQueryLLM.CachedSingleCompletion(
+		ctx, logger, args.Database, args.Customer.ID, simpleQueryPrompt,
 		vectorQuery.(string),
 	)
 	if err != nil {
@@ -103,23 +119,30 @@ func (t *ToolVectorQuery) Run(
 			K:          4,
 		})
 		if err != nil {
-			return nil, slogger.Error(ctx, logger, "failed to query the vectorstore", err)
+			return usedTokens(append(usageRecords, embs.GetUsageRecords()...)), slogger.Error(ctx, logger, "failed to query the vectorstore", err)
 		}
 		vectorResponses = append(vectorResponses, vectorResponse)
 	}
 
 	// create separate lists
+	summaries := make([]*vectorstore.SummaryMatch, 0)
 	vectors := make([]*queries.VectorStore, 0)
 	docs := make([]*queries.Document, 0)
 	pages := make([]*queries.WebsitePage, 0)
+	feedItems := make([]*queries.FeedItem, 0)
 
 	for _, item := range vectorResponses {
+		summaries = append(summaries, item.Summaries...)
 		vectors = append(vectors, item.Vectors...)
 		docs = append(docs, item.Documents...)
 		pages = append(pages, item.WebsitePages...)
+		feedItems = append(feedItems, item.FeedItems...)
 	}
 
 	// remove the duplicates
+	summaries = utils.RemoveDuplicates(summaries, func(val *vectorstore.SummaryMatch) any {
+		return val.ObjectID()
+	})
 	vectors = utils.RemoveDuplicates(vectors, func(val *queries.VectorStore) any {
 		return val.ID
 	})
@@ -129,41 +152,103 @@ func (t *ToolVectorQuery) Run(
 	pages = utils.RemoveDuplicates(pages, func(val *queries.WebsitePage) any {
 		return val.ID
 	})
+	feedItems = utils.RemoveDuplicates(feedItems, func(val *queries.FeedItem) any {
+		return val.ID
+	})
 
-	// craft a response for the caller
-	var toolResponse string
+	// the closest summaries are cited first, the chunks fill the rest of the citations
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Distance < summaries[j].Distance
+	})
 
-	if len(vectors) != 0 {
-		// summarize the vectors
-		buf := new(bytes.Buffer)
-		for _, item := range vectors {
-			if _, err := buf.WriteString(item.Raw); err != nil {
-				logger.Warn("failed to write to the buffer", "error", err)
-			}
+	// score the summaries and chunks against the query of the model and drop the irrelevant
+	// ones. Every chunk and the closest summaries are kept when none of them could be ranked
+	scores := make(map[uuid.UUID]*vectorstore.RankedVector)
+	ranked := false
+	if len(vectors)+len(summaries) != 0 {
+		rankerLLM, err := vectorstore.GetRankerLLM(ctx, args.Database)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get the ranker llm, using the tool llm", "error", err)
+			rankerLLM = args.ToolLLM
+		}
+		rerankResponse, err := vectorstore.Rerank(ctx, logger, &vectorstore.RerankInput{
+			CustomerID: args.Customer.ID,
+			DB:         args.Database,
+			Model:      rankerLLM,
+			Query:      vectorQuery.(string),
+			Vectors:    vectors,
+			Summaries:  summaries,
+		})
+		if rerankResponse != nil {
+			usageRecords = append(usageRecords, rerankResponse.UsageRecords...)
 		}
-
-		// send the summary
-		bufStr := buf.String()
-		response, err := args.ToolLLM.Summarize(ctx, logger, args.Customer.ID, bufStr)
 		if err != nil {
-			logger.Error("failed to summarize the content", "err", err)
-			// still pass on information to response even with an error
-			toolResponse = fmt.Sprintf("[Query Response]: %s", bufStr)
+			logger.WarnContext(ctx, "failed to rerank the chunks, keeping every chunk", "error", err)
 		} else {
-			// write the summary
-			toolResponse = fmt.Sprintf("[Query Response]: %s", response.Summary)
-			if response.UsageRecords != nil {
-				usageRecords = append(usageRecords, response.UsageRecords...)
+			ranked = true
+			rankedSummaries := rerankResponse.Summaries
+			if len(rankedSummaries) > MAX_TOOL_SUMMARIES {
+				rankedSummaries = rankedSummaries[:MAX_TOOL_SUMMARIES]
+			}
+			rankedVectors := rerankResponse.Ranked
+			if maxChunks := MAX_TOOL_CITATIONS - len(rankedSummaries); len(rankedVectors) > maxChunks {
+				rankedVectors = rankedVectors[:maxChunks]
+			}
+			kept := (&vectorstore.QueryResponse{
+				Documents:    docs,
+				WebsitePages: pages,
+				FeedItems:    feedItems,
+			}).KeepRanked(rankedVectors, rankedSummaries)
+			summaries, vectors, docs, pages, feedItems = kept.Summaries, kept.Vectors, kept.Documents, kept.WebsitePages, kept.FeedItems
+			for _, item := range rankedVectors {
+				scores[item.VectorID] = item
+			}
+		}
+	}
+	if !ranked && len(summaries) > MAX_TOOL_SUMMARIES {
+		summaries = summaries[:MAX_TOOL_SUMMARIES]
+	}
+	maxChunks := MAX_TOOL_CITATIONS - len(summaries)
+
+	// the citations of a call are limited so the calls that run in parallel do not share numbers
+	if len(vectors) > maxChunks {
+		vectors = vectors[:maxChunks]
+	}
+
+	// number every summary and chunk as a source the model can cite
+	citations := summaryCitations(args.CitationOffset, summaries)
+	chunkCitations, err := vectorCitations(ctx, dmodel, args.CitationOffset+len(summaries), vectors, docs, pages, feedItems)
+	if err != nil {
+		return usedTokens(append(usageRecords, embs.GetUsageRecords()...)), slogger.Error(ctx, logger, "failed to create the citations", err)
+	}
+	citations = append(citations, chunkCitations...)
+
+	// craft a response for the caller
+	buf := new(strings.Builder)
+	buf.WriteString("[Query Response]:\n")
+	for i, item := range summaries {
+		buf.WriteString(fmt.Sprintf("[%d] %s (summary)\n%s\n\n", citations[i].Number, citations[i].Title, strings.TrimSpace(item.Summary())))
+	}
+	if len(vectors) != 0 {
+		for i, item := range vectors {
+			citation := chunkCitations[i]
+			buf.WriteString(fmt.Sprintf("[%d] %s", citation.Number, citation.Title))
+			if score, ok := scores[item.ID]; ok {
+				buf.WriteString(fmt.Sprintf(" (relevance: %d/100)", score.Relevance))
 			}
+			buf.WriteString(fmt.Sprintf("\n%s\n\n", strings.TrimSpace(item.Raw)))
 		}
-	} else {
-		toolResponse = "[Query Response]: No valid information found"
+	} else if len(summaries) == 0 {
+		buf.WriteString("No valid information found")
 	}
 
-	message := gollm.NewToolResultMessage(args.LastMessage.ToolUseID, args.LastMessage.ToolName, toolResponse)
+	message := gollm.NewToolResultMessage(args.LastMessage.ToolUseID, args.LastMessage.ToolName, strings.TrimSpace(buf.String()))
 	arguments := make(map[string]any)
+	arguments["summaries"] = summaries
 	arguments["docs"] = docs
 	arguments["pages"] = pages
+	arguments["feedItems"] = feedItems
+	arguments["scores"] = vectorScores(vectors, scores)
 	message.ToolArguments = arguments
 
 	// add the usage records
@@ -173,5 +258,127 @@ func (t *ToolVectorQuery) Run(
 	return &ToolResponse{
 		Message:      message,
 		UsageRecords: usageRecords,
+		Citations:    citations,
 	}, nil
 }
+
+// Creates a citation for every vector, in the same order, from the object the vector was
+// created from
+func vectorCitations(
+	ctx context.Context,
+	dmodel *queries.Queries,
+	offset int,
+	vectors []*queries.VectorStore,
+	docs []*queries.Document,
+	pages []*queries.WebsitePage,
+	feedItems []*queries.FeedItem,
+) ([]*conversation.Citation, error) {
+	citations := make([]*conversation.Citation, len(vectors))
+	if len(vectors) == 0 {
+		return citations, nil
+	}
+
+	// get the chunk index of the vectors
+	ids := make([]uuid.UUID, len(vectors))
+	for i, item := range vectors {
+		ids[i] = item.ID
+	}
+	rows, err := dmodel.GetVectorChunkIndexes(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the chunk indexes: %w", err)
+	}
+	chunkIndexes := make(map[uuid.UUID]int32)
+	for _, item := range rows {
+		chunkIndexes[item.VectorStoreID] = item.Index
+	}
+
+	// index the objects the vectors were created from
+	docsById := make(map[uuid.UUID]*queries.Document)
+	for _, item := range docs {
+		docsById[item.ID] = item
+	}
+	pagesById := make(map[uuid.UUID]*queries.WebsitePage)
+	for _, item := range pages {
+		pagesById[item.ID] = item
+	}
+	feedItemsById := make(map[uuid.UUID]*queries.FeedItem)
+	for _, item := range feedItems {
+		feedItemsById[item.ID] = item
+	}
+
+	for i, item := range vectors {
+		objectId := item.ObjectID
+		citation := &conversation.Citation{
+			Number:   offset + i + 1,
+			SourceID: &objectId,
+			Snippet:  conversation.CitationSnippet(strings.TrimSpace(item.Raw)),
+		}
+		if index, ok := chunkIndexes[item.ID]; ok {
+			citation.ChunkIndex = &index
+		}
+
+		if doc, ok := docsById[objectId]; ok {
+			citation.SourceType = conversation.CITATION_SOURCE_DOCUMENT
+			citation.Title = doc.Filename
+		} else if page, ok := pagesById[objectId]; ok {
+			citation.SourceType = conversation.CITATION_SOURCE_WEBSITE_PAGE
+			citation.Title = page.Url
+			citation.Url = page.Url
+		} else if feedItem, ok := feedItemsById[objectId]; ok {
+			citation.SourceType = conversation.CITATION_SOURCE_FEED_ITEM
+			citation.Title = feedItem.Title
+			citation.Url = feedItem.Link
+		} else {
+			// the object was not returned with the vector, fall back on the content type
+			citation.SourceType = item.ContentType
+		}
+		citations[i] = citation
+	}
+	return citations, nil
+}
+
+// Creates a citation for every summary, in the same order. A summary covers the whole object so
+// it has no chunk index
+func summaryCitations(offset int, summaries []*vectorstore.SummaryMatch) []*conversation.Citation {
+	citations := make([]*conversation.Citation, len(summaries))
+	for i, item := range summaries {
+		objectId := item.ObjectID()
+		citation := &conversation.Citation{
+			Number:   offset + i + 1,
+			SourceID: &objectId,
+			Snippet:  conversation.CitationSnippet(strings.TrimSpace(item.Summary())),
+		}
+		if item.Document != nil {
+			citation.SourceType = conversation.CITATION_SOURCE_DOCUMENT
+			citation.Title = item.Document.Filename
+		} else {
+			citation.SourceType = conversation.CITATION_SOURCE_WEBSITE_PAGE
+			citation.Title = item.WebsitePage.Url
+			citation.Url = item.WebsitePage.Url
+		}
+		citations[i] = citation
+	}
+	return citations
+}
+
+// the score of a chunk in the tool result
+type vectorScore struct {
+	VectorID  uuid.UUID `json:"vectorId"`
+	Relevance int       `json:"relevance"`
+	Quality   int       `json:"quality"`
+}
+
+// lists the scores of the vectors that were ranked, in the order of the vectors
+func vectorScores(vectors []*queries.VectorStore, scores map[uuid.UUID]*vectorstore.RankedVector) []*vectorScore {
+	response := make([]*vectorScore, 0, len(scores))
+	for _, item := range vectors {
+		if score, ok := scores[item.ID]; ok {
+			response = append(response, &vectorScore{
+				VectorID:  item.ID,
+				Relevance: score.Relevance,
+				Quality:   score.Quality,
+			})
+		}
+	}
+	return response
+}
@@ -0,0 +1,32 @@
+package tool
+
+import (
+	"testing"
+
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/vectorstore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryCitations(t *testing.T) {
+	doc := &queries.Document{Filename: "rocks.pdf", Summary: " Rocks are hard. "}
+	page := &queries.WebsitePage{Url: "https://rocks.com", Summary: "All about rocks."}
+
+	citations := summaryCitations(3, []*vectorstore.SummaryMatch{
+		{Document: doc, Distance: -0.9},
+		{WebsitePage: page, Distance: -0.8},
+	})
+	require.Len(t, citations, 2)
+
+	require.Equal(t, 4, citations[0].Number)
+	require.Equal(t, conversation.CITATION_SOURCE_DOCUMENT, citations[0].SourceType)
+	require.Equal(t, "rocks.pdf", citations[0].Title)
+	require.Equal(t, "Rocks are hard.", citations[0].Snippet)
+	require.Equal(t, doc.ID, *citations[0].SourceID)
+	require.Nil(t, citations[0].ChunkIndex)
+
+	require.Equal(t, 5, citations[1].Number)
+	require.Equal(t, conversation.CITATION_SOURCE_WEBSITE_PAGE, citations[1].SourceType)
+	require.Equal(t, "https://rocks.com", citations[1].Url)
+}
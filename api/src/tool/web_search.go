@@ -0,0 +1,279 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/ltypes"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/customer/conversation"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/sapphirenw/ai-content-creation-api/src/webparse"
+)
+
+// number of search results passed back to the model
+const webSearchMaxResults = 5
+
+// number of the top results that are scraped and summarized
+const webSearchMaxSummaries = 3
+
+// A result the model offered to save into the websites of the customer. It is kept in the
+// arguments of the tool result until the user confirms it, see `ConfirmPendingSave`
+type PendingSave struct {
+	Url     string     `json:"url"`
+	SavedAt *time.Time `json:"savedAt,omitempty"`
+}
+
+type ToolWebSearch struct{}
+
+func init() {
+	Register(WebSearch, func() Tool { return newToolWebSearch() }, WebSearchAvailable)
+}
+
+func newToolWebSearch() *ToolWebSearch {
+	return &ToolWebSearch{}
+}
+
+// Whether a search provider is configured, the tool should only be offered when it is
+func WebSearchAvailable() bool {
+	_, err := webparse.GetSearchProvider()
+	return err == nil
+}
+
+func (t *ToolWebSearch) GetType() ToolType {
+	return WebSearch
+}
+
+func (t *ToolWebSearch) GetSchema() *gollm.Tool {
+	return &gollm.Tool{
+		Title:       string(t.GetType()),
+		Description: "Search the public internet for up to date information that is not available in the user's private stored information. The response contains the numbered top results with their urls, which you should cite by their number when using the information in your answer.",
+		Schema: &ltypes.ToolSchema{
+			Type: "object",
+			Properties: map[string]*ltypes.ToolSchema{
+				"query": {
+					Type:        "string",
+					Description: "The query to send to the search engine. Write it as you would type it into a search engine.",
+				},
+				"summarize": {
+					Type:        "boolean",
+					Description: "Whether to read and summarize the top results instead of only returning the search snippets. This is slower, so only use it when the snippets are not enough to answer.",
+				},
+				"save_url": {
+					Type:        "string",
+					Description: "Optional url of a result to offer to save into the user's websites so it is stored with their private information. The user has to confirm the save, so tell them it is waiting for their confirmation. Only use this when the user asks to save or remember a source.",
+				},
+			},
+		},
+	}
+}
+
+func (t *ToolWebSearch) Run(
+	ctx context.Context,
+	l *slog.Logger,
+	args *RunToolArgs,
+) (*ToolResponse, error) {
+	logger := l.With("tool", t.GetType())
+	if err := args.Validate(); err != nil {
+		return nil, slogger.Error(ctx, logger, "ARGUMENT ERROR", err)
+	}
+
+	usageRecords := make([]*tokens.UsageRecord, 0)
+
+	// ensure the arguments are present
+	query, ok := args.LastMessage.ToolArguments["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return nil, slogger.Error(ctx, logger, "the argument 'query' does not exist", nil)
+	}
+	summarize := boolArgument(args.LastMessage.ToolArguments["summarize"])
+	saveUrl, _ := args.LastMessage.ToolArguments["save_url"].(string)
+
+	arguments := make(map[string]any)
+	buf := new(strings.Builder)
+	buf.WriteString("[Web Search Response]:\n")
+
+	// the model cannot save into the datastore on its own. The page is offered to the user, who
+	// confirms it from the chat with the `rag2/confirmSave` endpoint
+	if saveUrl != "" {
+		if u, err := pendingSaveUrl(saveUrl); err != nil {
+			logger.WarnContext(ctx, "invalid url to save", "url", saveUrl, "error", err)
+			buf.WriteString(fmt.Sprintf("Cannot save %s, it is not a valid url.\n\n", saveUrl))
+		} else {
+			arguments["pendingSave"] = &PendingSave{Url: u}
+			buf.WriteString(fmt.Sprintf("Asked the user to confirm saving %s to their websites. It is only saved once they confirm.\n\n", u))
+		}
+	}
+
+	// run the search. Failures are reported to the model so the chat can continue
+	logger.InfoContext(ctx, "Running web search ...", "query", query)
+	results, err := runWebSearch(ctx, query)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to run the web search", "error", err)
+		buf.WriteString("The web search failed, answer without it.")
+		return t.response(args, buf.String(), arguments, usageRecords), nil
+	}
+	if len(results) == 0 {
+		buf.WriteString("No results found.")
+		return t.response(args, buf.String(), arguments, usageRecords), nil
+	}
+
+	citations := make([]*conversation.Citation, len(results))
+	for i, item := range results {
+		content := item.Content
+
+		// replace the snippet with a summary of the page
+		if summarize && i < webSearchMaxSummaries {
+			summary, records, err := summarizeResult(ctx, logger, args, item)
+			if err != nil {
+				logger.WarnContext(ctx, "failed to summarize the result, using the snippet", "url", item.Url, "error", err)
+			} else {
+				content = summary
+				usageRecords = append(usageRecords, records...)
+			}
+		}
+
+		citations[i] = &conversation.Citation{
+			Number:     args.CitationOffset + i + 1,
+			SourceType: conversation.CITATION_SOURCE_WEB,
+			Title:      item.Title,
+			Url:        item.Url,
+			Snippet:    conversation.CitationSnippet(strings.TrimSpace(content)),
+		}
+		buf.WriteString(fmt.Sprintf("[%d] %s\nURL: %s\n%s\n\n", citations[i].Number, item.Title, item.Url, strings.TrimSpace(content)))
+	}
+	arguments["results"] = results
+
+	response := t.response(args, strings.TrimSpace(buf.String()), arguments, usageRecords)
+	response.Citations = citations
+	return response, nil
+}
+
+func (t *ToolWebSearch) response(
+	args *RunToolArgs,
+	content string,
+	arguments map[string]any,
+	usageRecords []*tokens.UsageRecord,
+) *ToolResponse {
+	message := gollm.NewToolResultMessage(args.LastMessage.ToolUseID, args.LastMessage.ToolName, content)
+	message.ToolArguments = arguments
+	return &ToolResponse{
+		Message:      message,
+		UsageRecords: usageRecords,
+	}
+}
+
+func runWebSearch(ctx context.Context, query string) ([]*webparse.Result, error) {
+	provider, err := webparse.GetSearchProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the search provider: %w", err)
+	}
+	response, err := provider.Search(ctx, &webparse.SearchArgs{
+		Query:      query,
+		SafeSearch: webparse.SAFE_SEARCH_MODERATE,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	// only keep results that can be cited
+	results := make([]*webparse.Result, 0, webSearchMaxResults)
+	for _, item := range utils.RemoveDuplicates(response.Results, func(val *webparse.Result) any { return val.Url }) {
+		if item.Url == "" {
+			continue
+		}
+		results = append(results, item)
+		if len(results) == webSearchMaxResults {
+			break
+		}
+	}
+	return results, nil
+}
+
+// scrapes the result and summarizes it with the tool llm
+func summarizeResult(
+	ctx context.Context,
+	logger *slog.Logger,
+	args *RunToolArgs,
+	result *webparse.Result,
+) (string, []*tokens.UsageRecord, error) {
+	scraped, err := webparse.ScrapeSingle(ctx, logger, &queries.WebsitePage{Url: result.Url})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to scrape the page: %w", err)
+	}
+	if scraped.SkipReason != "" {
+		return "", nil, fmt.Errorf("no usable content: %s", scraped.SkipReason)
+	}
+
+	// the summary runs its completions in parallel, so the cache and the prompts are read
+	// outside of the transaction of the turn
+	response, err := args.ToolLLM.Summarize(ctx, logger, args.Customer.ID, scraped.Content, &llm.SummarizeOptions{
+		Cache: llm.NewCacheOptions(args.concurrentDB()),
+		DB:    args.concurrentDB(),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to summarize the page: %w", err)
+	}
+	return response.Summary, response.UsageRecords, nil
+}
+
+// validates the url the model asked to save, without the fragment
+func pendingSaveUrl(raw string) (string, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("invalid url: %s", raw)
+	}
+	parsed.Fragment = ""
+	return parsed.String(), nil
+}
+
+/*
+Confirms the pending save in the saved results of a web search message. Returns the url to save
+and the results with the save marked, which replace the results of the message so the save is
+only confirmed once
+*/
+func ConfirmPendingSave(results []byte, savedAt time.Time) (string, []byte, error) {
+	var parsed map[string]any
+	if err := json.Unmarshal(results, &parsed); err != nil {
+		return "", nil, fmt.Errorf("failed to parse the results: %w", err)
+	}
+	enc, err := json.Marshal(parsed["pendingSave"])
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode the pending save: %w", err)
+	}
+	var pending *PendingSave
+	if err := json.Unmarshal(enc, &pending); err != nil || pending == nil || pending.Url == "" {
+		return "", nil, fmt.Errorf("the message does not have a pending save")
+	}
+	if pending.SavedAt != nil {
+		return "", nil, fmt.Errorf("the page was already saved")
+	}
+
+	pending.SavedAt = &savedAt
+	parsed["pendingSave"] = pending
+	updated, err := json.Marshal(parsed)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode the results: %w", err)
+	}
+	return pending.Url, updated, nil
+}
+
+// models are not consistent in how they send booleans
+func boolArgument(val any) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
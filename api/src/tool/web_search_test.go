@@ -0,0 +1,35 @@
+package tool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingSaveUrl(t *testing.T) {
+	u, err := pendingSaveUrl("https://example.com/articles?id=42#comments")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/articles?id=42", u)
+
+	_, err = pendingSaveUrl("ftp://example.com/file")
+	require.Error(t, err)
+	_, err = pendingSaveUrl("/articles")
+	require.Error(t, err)
+}
+
+func TestConfirmPendingSave(t *testing.T) {
+	results := []byte(`{"pendingSave": {"url": "https://example.com/articles"}, "results": []}`)
+	now := time.Now()
+	u, updated, err := ConfirmPendingSave(results, now)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/articles", u)
+	require.Contains(t, string(updated), `"results":[]`)
+
+	// a save is only confirmed once
+	_, _, err = ConfirmPendingSave(updated, now)
+	require.ErrorContains(t, err, "already saved")
+
+	_, _, err = ConfirmPendingSave([]byte(`{"results": []}`), now)
+	require.ErrorContains(t, err, "does not have a pending save")
+}
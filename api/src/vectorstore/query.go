@@ -6,11 +6,15 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
 	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
 )
 
-// Query all objects in the datastore. Respects all passed filters
+// Query all objects in the datastore. Respects all passed filters.
+//
+// K applies to each source, so the response holds up to K chunks of documents, K of website
+// pages and K of feed items, along with the matched summaries
 func Query(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -32,6 +36,12 @@ func Query(
 		return nil, slogger.Error(ctx, logger, "failed to get vectors", err)
 	}
 
+	// get the summaries first so broad queries match whole objects
+	summaries, err := QuerySummaries(ctx, logger, db, input)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to get the summaries", err)
+	}
+
 	// get documents
 	docResponse, err := QueryDocuments(ctx, logger, db, input)
 	if err != nil {
@@ -42,16 +52,24 @@ func Query(
 	if err != nil {
 		return nil, slogger.Error(ctx, logger, "failed to get the website pages", err)
 	}
+	// get feed items
+	feedResponse, err := QueryFeedItems(ctx, logger, db, input)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to get the feed items", err)
+	}
 
 	// combine vectors
 	vectors := make([]*queries.VectorStore, 0)
 	vectors = append(vectors, docResponse.Vectors...)
 	vectors = append(vectors, pageResponse.Vectors...)
+	vectors = append(vectors, feedResponse.Vectors...)
 
 	return &QueryResponse{
+		Summaries:    summaries,
 		Vectors:      vectors,
 		Documents:    docResponse.Documents,
 		WebsitePages: pageResponse.WebsitePages,
+		FeedItems:    feedResponse.FeedItems,
 	}, nil
 }
 
@@ -126,8 +144,8 @@ func QueryDocuments(
 		CustomerID: input.CustomerID,
 		Limit:      int32(input.K),
 		Embeddings: &vector.Embedding,
-		Column4:    input.DocumentIDsFilter,
-		Column5:    input.FolderIDsFilter,
+		Column4:    nullableIDs(input.DocumentIDsFilter),
+		Column5:    nullableIDs(input.FolderIDsFilter),
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), "db cannot be empty") {
@@ -214,3 +232,68 @@ func QueryWebsitePages(
 		WebsitePages: pages,
 	}, nil
 }
+
+// query feed items in the datastore.
+// Respects the feed and feed item filters
+func QueryFeedItems(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	input *QueryInput,
+) (*QueryFeedItemsResponse, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "Querying vector store for related feed items ...")
+
+	// send the request
+	vector, err := input.GetVectors(ctx, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vectors: %w", err)
+	}
+
+	// send the request to the database
+	model := queries.New(db)
+	response, err := model.QueryVectorStoreFeedItemsScoped(ctx, &queries.QueryVectorStoreFeedItemsScopedParams{
+		CustomerID: input.CustomerID,
+		Limit:      int32(input.K),
+		Embeddings: &vector.Embedding,
+		Column4:    nullableIDs(input.FeedItemIDsFilter),
+		Column5:    nullableIDs(input.FeedIDsFilter),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "db cannot be empty") {
+			logger.InfoContext(ctx, "The result was empty")
+			return &QueryFeedItemsResponse{}, nil
+		}
+		return nil, fmt.Errorf("error querying the vector store: %w", err)
+	}
+
+	logger.InfoContext(ctx, "Successfully found feed items", "length", len(response))
+
+	// convert to format
+	vectors := make([]*queries.VectorStore, 0)
+	items := make([]*queries.FeedItem, 0)
+
+	for _, item := range response {
+		vectors = append(vectors, &item.VectorStore)
+		items = append(items, &item.FeedItem)
+	}
+
+	return &QueryFeedItemsResponse{
+		Vectors:   vectors,
+		FeedItems: items,
+	}, nil
+}
+
+// An empty filter is sent as NULL, which the queries treat as no filter. An empty array would
+// match nothing
+func nullableIDs(ids []uuid.UUID) []uuid.UUID {
+	if len(ids) == 0 {
+		return nil
+	}
+	return ids
+}
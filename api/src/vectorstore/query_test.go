@@ -2,8 +2,19 @@ package vectorstore
 
 import (
 	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVectorQuery(t *testing.T) {
 
 }
+
+func TestNullableIDs(t *testing.T) {
+	require.Nil(t, nullableIDs(nil))
+	require.Nil(t, nullableIDs([]uuid.UUID{}))
+
+	ids := []uuid.UUID{uuid.New()}
+	require.Equal(t, ids, nullableIDs(ids))
+}
@@ -0,0 +1,301 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jake-landersweb/gollm/v2/src/gollm"
+	"github.com/jake-landersweb/gollm/v2/src/tokens"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/prompts"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+// min relevance out of 100 a chunk needs to be kept by the reranker
+const RERANK_DEFAULT_THRESHOLD = 40
+
+// title of the internal model that ranks the chunks
+const RANKER_LLM_TITLE = "Content Ranker"
+
+// max number of chunks that are ranked at the same time
+const rerankConcurrency = 8
+
+type RerankInput struct {
+	CustomerID uuid.UUID
+	DB         queries.DBTX // resolves the ranker prompt of the customer, the built-in prompt is used when nil
+	Model      *llm.LLM
+	Query      string
+	Vectors    []*queries.VectorStore
+	Summaries  []*SummaryMatch // ranked like the chunks, so only relevant summaries are kept
+
+	// chunks with a relevance below the threshold are dropped. Defaults to
+	// `RERANK_DEFAULT_THRESHOLD` when nil, a threshold of 0 keeps every chunk
+	Threshold *int
+}
+
+func (input *RerankInput) Validate() error {
+	if input == nil {
+		return fmt.Errorf("input cannot be nil")
+	}
+	if input.Model == nil {
+		return fmt.Errorf("the model cannot be nil")
+	}
+	if input.Query == "" {
+		return fmt.Errorf("no query provided")
+	}
+	if input.Threshold != nil && (*input.Threshold < 0 || *input.Threshold > 100) {
+		return fmt.Errorf("the threshold must be between 0 and 100")
+	}
+	return nil
+}
+
+// A chunk scored against the query by the reranker
+type RankedVector struct {
+	Vector *queries.VectorStore `json:"-"`
+
+	VectorID    uuid.UUID `json:"vectorId"`
+	ObjectID    uuid.UUID `json:"objectId"`
+	ContentType string    `json:"contentType"`
+	Raw         string    `json:"raw"`
+	Relevance   int       `json:"relevance"`
+	Quality     int       `json:"quality"`
+}
+
+// A summary scored against the query by the reranker
+type RankedSummary struct {
+	Match *SummaryMatch `json:"-"`
+
+	ObjectID  uuid.UUID `json:"objectId"`
+	Relevance int       `json:"relevance"`
+	Quality   int       `json:"quality"`
+}
+
+type RerankResponse struct {
+	Ranked       []*RankedVector        // the chunks that were kept, most relevant first
+	Dropped      []*RankedVector        // the chunks below the threshold
+	Failed       []*queries.VectorStore // the chunks that could not be ranked, they are dropped
+	Summaries    []*RankedSummary       // the summaries that were kept, most relevant first
+	UsageRecords []*tokens.UsageRecord
+}
+
+/*
+Scores every chunk against the query with the model, and drops the chunks with a relevance
+below the threshold. The chunks are ranked concurrently, up to `rerankConcurrency` at a time.
+The kept chunks and summaries are sorted by relevance, then by quality.
+
+A chunk or summary that fails to rank is dropped, and an error is only returned when every one
+of them failed.
+*/
+func Rerank(
+	ctx context.Context,
+	logger *slog.Logger,
+	input *RerankInput,
+) (*RerankResponse, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	threshold := RERANK_DEFAULT_THRESHOLD
+	if input.Threshold != nil {
+		threshold = *input.Threshold
+	}
+
+	logger.InfoContext(ctx, "Reranking the chunks ...", "length", len(input.Vectors), "summaries", len(input.Summaries), "threshold", threshold)
+
+	system, _, err := prompts.Render(ctx, logger, input.DB, input.CustomerID, prompts.TEMPLATE_RAG_RANKER, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the ranker prompt: %w", err)
+	}
+
+	// the summaries are ranked after the chunks
+	total := len(input.Vectors) + len(input.Summaries)
+	scores := make([]*prompts.RagRankerSchema, total)
+	usageRecords := make([]*tokens.UsageRecord, total)
+	errs := make([]error, total)
+	sem := make(chan struct{}, rerankConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var id uuid.UUID
+			var content string
+			if i < len(input.Vectors) {
+				id, content = input.Vectors[i].ID, input.Vectors[i].Raw
+			} else {
+				match := input.Summaries[i-len(input.Vectors)]
+				id, content = match.ObjectID(), match.Summary()
+			}
+			score, usage, err := rankChunk(ctx, logger, input, system, content)
+			usageRecords[i] = usage
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to rank %s: %w", id, err)
+				return
+			}
+			scores[i] = score
+		}(i)
+	}
+	wg.Wait()
+
+	response := &RerankResponse{
+		Ranked:       make([]*RankedVector, 0, len(input.Vectors)),
+		Dropped:      make([]*RankedVector, 0),
+		Failed:       make([]*queries.VectorStore, 0),
+		Summaries:    make([]*RankedSummary, 0, len(input.Summaries)),
+		UsageRecords: make([]*tokens.UsageRecord, 0, total),
+	}
+	for _, item := range usageRecords {
+		if item != nil {
+			response.UsageRecords = append(response.UsageRecords, item)
+		}
+	}
+	failed := 0
+	var lastErr error
+	for _, err := range errs {
+		if err != nil {
+			logger.WarnContext(ctx, "failed to rank the content, dropping it", "error", err)
+			failed++
+			lastErr = err
+		}
+	}
+	if total != 0 && failed == total {
+		// the usage is returned so the caller can record the tokens of the chunks that were ranked
+		return response, fmt.Errorf("failed to rank every chunk: %w", lastErr)
+	}
+
+	for i, item := range input.Vectors {
+		if scores[i] == nil {
+			response.Failed = append(response.Failed, item)
+			continue
+		}
+		ranked := &RankedVector{
+			Vector:      item,
+			VectorID:    item.ID,
+			ObjectID:    item.ObjectID,
+			ContentType: item.ContentType,
+			Raw:         item.Raw,
+			Relevance:   clampScore(scores[i].Relevance),
+			Quality:     clampScore(scores[i].Quality),
+		}
+		if ranked.Relevance < threshold {
+			response.Dropped = append(response.Dropped, ranked)
+			continue
+		}
+		response.Ranked = append(response.Ranked, ranked)
+	}
+	for i, item := range input.Summaries {
+		score := scores[len(input.Vectors)+i]
+		if score == nil || clampScore(score.Relevance) < threshold {
+			continue
+		}
+		response.Summaries = append(response.Summaries, &RankedSummary{
+			Match:     item,
+			ObjectID:  item.ObjectID(),
+			Relevance: clampScore(score.Relevance),
+			Quality:   clampScore(score.Quality),
+		})
+	}
+	sort.SliceStable(response.Summaries, func(i, j int) bool {
+		if response.Summaries[i].Relevance != response.Summaries[j].Relevance {
+			return response.Summaries[i].Relevance > response.Summaries[j].Relevance
+		}
+		return response.Summaries[i].Quality > response.Summaries[j].Quality
+	})
+	sort.SliceStable(response.Ranked, func(i, j int) bool {
+		if response.Ranked[i].Relevance != response.Ranked[j].Relevance {
+			return response.Ranked[i].Relevance > response.Ranked[j].Relevance
+		}
+		return response.Ranked[i].Quality > response.Ranked[j].Quality
+	})
+
+	logger.InfoContext(ctx, "Successfully reranked the chunks", "kept", len(response.Ranked), "dropped", len(response.Dropped), "failed", len(response.Failed), "summaries", len(response.Summaries))
+	return response, nil
+}
+
+// Gets the internal model that ranks the chunks
+func GetRankerLLM(ctx context.Context, db queries.DBTX) (*llm.LLM, error) {
+	dmodel := queries.New(db)
+	response, err := dmodel.GetInteralLLM(ctx, RANKER_LLM_TITLE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the ranker llm: %w", err)
+	}
+	return llm.FromObjects(&response.Llm, &response.AvailableModel), nil
+}
+
+// scores a single chunk against the query
+func rankChunk(
+	ctx context.Context,
+	logger *slog.Logger,
+	input *RerankInput,
+	system string,
+	content string,
+) (*prompts.RagRankerSchema, *tokens.UsageRecord, error) {
+	response, err := input.Model.Completion(ctx, logger, &llm.CompletionArgs{
+		CustomerID: input.CustomerID.String(),
+		Messages: []*gollm.Message{
+			{Role: gollm.RoleSystem, Message: system},
+			{Role: gollm.RoleUser, Message: fmt.Sprintf("Query: %s\n\nContent:\n%s", input.Query, content)},
+		},
+		Json:       true,
+		JsonSchema: prompts.RAG_RANKER_SCHEMA,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed the completion: %w", err)
+	}
+
+	var score prompts.RagRankerSchema
+	if err := json.Unmarshal([]byte(response.Message.Message), &score); err != nil {
+		return nil, response.UsageRecord, fmt.Errorf("failed to parse the score: %w", err)
+	}
+	return &score, response.UsageRecord, nil
+}
+
+func clampScore(score int) int {
+	return max(0, min(100, score))
+}
+
+// Keeps the vectors of the ranked chunks and the ranked summaries in ranked order, along with
+// the objects the chunks were created from
+func (response *QueryResponse) KeepRanked(ranked []*RankedVector, summaries []*RankedSummary) *QueryResponse {
+	objectIds := make(map[uuid.UUID]bool, len(ranked))
+	kept := &QueryResponse{
+		Summaries:    make([]*SummaryMatch, len(summaries)),
+		Vectors:      make([]*queries.VectorStore, len(ranked)),
+		Documents:    make([]*queries.Document, 0),
+		WebsitePages: make([]*queries.WebsitePage, 0),
+		FeedItems:    make([]*queries.FeedItem, 0),
+	}
+	for i, item := range ranked {
+		kept.Vectors[i] = item.Vector
+		objectIds[item.ObjectID] = true
+	}
+	for i, item := range summaries {
+		kept.Summaries[i] = item.Match
+	}
+	for _, item := range response.Documents {
+		if objectIds[item.ID] {
+			kept.Documents = append(kept.Documents, item)
+		}
+	}
+	for _, item := range response.WebsitePages {
+		if objectIds[item.ID] {
+			kept.WebsitePages = append(kept.WebsitePages, item)
+		}
+	}
+	for _, item := range response.FeedItems {
+		if objectIds[item.ID] {
+			kept.FeedItems = append(kept.FeedItems, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,113 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/llm"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeepRanked(t *testing.T) {
+	doc := &queries.Document{ID: uuid.New()}
+	page := &queries.WebsitePage{ID: uuid.New()}
+	docVector := &queries.VectorStore{ID: uuid.New(), ObjectID: doc.ID}
+	pageVector := &queries.VectorStore{ID: uuid.New(), ObjectID: page.ID}
+
+	response := &QueryResponse{
+		Vectors:      []*queries.VectorStore{docVector, pageVector},
+		Documents:    []*queries.Document{doc},
+		WebsitePages: []*queries.WebsitePage{page},
+	}
+
+	response.Summaries = []*SummaryMatch{{Document: doc}, {WebsitePage: page}}
+
+	// the page chunk and summary were dropped by the reranker
+	kept := response.KeepRanked([]*RankedVector{
+		{Vector: docVector, VectorID: docVector.ID, ObjectID: doc.ID, Relevance: 90},
+	}, []*RankedSummary{
+		{Match: response.Summaries[0], ObjectID: doc.ID, Relevance: 80},
+	})
+	require.Equal(t, []*queries.VectorStore{docVector}, kept.Vectors)
+	require.Equal(t, []*SummaryMatch{response.Summaries[0]}, kept.Summaries)
+	require.Equal(t, []*queries.Document{doc}, kept.Documents)
+	require.Empty(t, kept.WebsitePages)
+	require.Empty(t, kept.FeedItems)
+}
+
+func TestRerankInputValidate(t *testing.T) {
+	input := &RerankInput{Query: "rocks"}
+	require.Error(t, input.Validate())
+
+	require.Equal(t, 100, clampScore(120))
+	require.Equal(t, 0, clampScore(-5))
+	require.Equal(t, 42, clampScore(42))
+}
+
+func TestRerank(t *testing.T) {
+	llm.Mock.Reset()
+	defer llm.Mock.Reset()
+	model := &llm.LLM{
+		Llm:            &queries.Llm{Model: "mock-ranker"},
+		AvailableModel: &queries.AvailableModel{Provider: llm.PROVIDER_MOCK},
+	}
+	vectors := []*queries.VectorStore{
+		{ID: uuid.New(), Raw: "granite"},
+		{ID: uuid.New(), Raw: "basalt"},
+		{ID: uuid.New(), Raw: "marble"},
+	}
+
+	// a threshold of 0 keeps every chunk, and a chunk that fails to rank is only dropped itself
+	threshold := 0
+	llm.Mock.Queue("mock-ranker",
+		&llm.MockResponse{Message: `{"relevance": 0, "quality": 10}`},
+		&llm.MockResponse{Message: `not json`},
+		&llm.MockResponse{Message: `{"relevance": 0, "quality": 20}`},
+	)
+	response, err := Rerank(context.TODO(), nil, &RerankInput{
+		Model:     model,
+		Query:     "rocks",
+		Vectors:   vectors,
+		Threshold: &threshold,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Ranked, 2)
+	require.Empty(t, response.Dropped)
+	require.Len(t, response.Failed, 1)
+
+	// the summaries are ranked after the chunks with the same threshold
+	llm.Mock.Reset()
+	summaries := []*SummaryMatch{
+		{Document: &queries.Document{ID: uuid.New()}},
+		{Document: &queries.Document{ID: uuid.New()}},
+	}
+	llm.Mock.Queue("mock-ranker",
+		&llm.MockResponse{Message: `{"relevance": 10, "quality": 10}`},
+		&llm.MockResponse{Message: `{"relevance": 70, "quality": 50}`},
+	)
+	response, err = Rerank(context.TODO(), nil, &RerankInput{
+		Model:     model,
+		Query:     "rocks",
+		Summaries: summaries,
+	})
+	require.NoError(t, err)
+	require.Empty(t, response.Ranked)
+	require.Len(t, response.Summaries, 1)
+	require.Equal(t, 70, response.Summaries[0].Relevance)
+
+	// every chunk failing is an error
+	llm.Mock.Reset()
+	llm.Mock.Queue("mock-ranker",
+		&llm.MockResponse{Message: `not json`},
+		&llm.MockResponse{Message: `not json`},
+		&llm.MockResponse{Message: `not json`},
+	)
+	_, err = Rerank(context.TODO(), nil, &RerankInput{
+		Model:   model,
+		Query:   "rocks",
+		Vectors: vectors,
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+)
+
+// A document or website page whose summary matched the query. Exactly one of the objects is set
+type SummaryMatch struct {
+	Document    *queries.Document    `json:"document,omitempty"`
+	WebsitePage *queries.WebsitePage `json:"websitePage,omitempty"`
+	Distance    float64              `json:"distance"` // negative inner product, lower is closer
+}
+
+func (m *SummaryMatch) ObjectID() uuid.UUID {
+	if m.Document != nil {
+		return m.Document.ID
+	}
+	return m.WebsitePage.ID
+}
+
+func (m *SummaryMatch) Summary() string {
+	if m.Document != nil {
+		return m.Document.Summary
+	}
+	return m.WebsitePage.Summary
+}
+
+/*
+Query the summaries of the documents and website pages. This is the retrieval layer that is
+searched before the chunks, so questions about a whole object match its summary instead of a
+handful of its chunks. Only summaries that are current with the content are returned, closest
+first.
+*/
+func QuerySummaries(
+	ctx context.Context,
+	logger *slog.Logger,
+	db queries.DBTX,
+	input *QueryInput,
+) ([]*SummaryMatch, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	logger.InfoContext(ctx, "Querying vector store for related summaries ...")
+
+	// send the request
+	vector, err := input.GetVectors(ctx, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vectors: %w", err)
+	}
+
+	model := queries.New(db)
+	matches := make([]*SummaryMatch, 0)
+
+	docs, err := model.QueryDocumentSummaries(ctx, &queries.QueryDocumentSummariesParams{
+		CustomerID: input.CustomerID,
+		Limit:      int32(input.K),
+		Embeddings: &vector.Embedding,
+	})
+	if err != nil && !strings.Contains(err.Error(), "db cannot be empty") {
+		return nil, fmt.Errorf("error querying the document summaries: %w", err)
+	}
+	for _, item := range docs {
+		matches = append(matches, &SummaryMatch{Document: &item.Document, Distance: item.Distance})
+	}
+
+	pages, err := model.QueryWebsitePageSummaries(ctx, &queries.QueryWebsitePageSummariesParams{
+		CustomerID: input.CustomerID,
+		Limit:      int32(input.K),
+		Embeddings: &vector.Embedding,
+	})
+	if err != nil && !strings.Contains(err.Error(), "db cannot be empty") {
+		return nil, fmt.Errorf("error querying the website page summaries: %w", err)
+	}
+	for _, item := range pages {
+		matches = append(matches, &SummaryMatch{WebsitePage: &item.WebsitePage, Distance: item.Distance})
+	}
+
+	// merge both lists into the closest k
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+	if len(matches) > input.K {
+		matches = matches[:input.K]
+	}
+
+	logger.InfoContext(ctx, "Successfully found summaries", "length", len(matches))
+	return matches, nil
+}
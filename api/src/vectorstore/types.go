@@ -16,9 +16,9 @@ type QueryInput struct {
 	CustomerID uuid.UUID
 	Embeddings gollm.Embeddings
 	Query      string
-	K          int
+	K          int // max number of chunks returned from each source
 
-	// filters
+	// filters, an empty list does not filter
 
 	FolderIDsFilter   []uuid.UUID
 	DocumentIDsFilter []uuid.UUID
@@ -26,6 +26,9 @@ type QueryInput struct {
 	WebsiteIDsFilter     []uuid.UUID
 	WebsitePageIDsFilter []uuid.UUID
 
+	FeedIDsFilter     []uuid.UUID
+	FeedItemIDsFilter []uuid.UUID
+
 	// can inbed the vector incase the input is re-used, or user already embedded content
 	Vector *ltypes.EmbeddingsData
 }
@@ -81,9 +84,11 @@ func (input *QueryInput) GetVectors(ctx context.Context, logger *slog.Logger) (*
 }
 
 type QueryResponse struct {
+	Summaries    []*SummaryMatch // matched before the chunks, see `QuerySummaries`
 	Vectors      []*queries.VectorStore
 	Documents    []*queries.Document
 	WebsitePages []*queries.WebsitePage
+	FeedItems    []*queries.FeedItem
 }
 
 type QueryDocumentsResponse struct {
@@ -95,3 +100,8 @@ type QueryWebsitePagesResponse struct {
 	Vectors      []*queries.VectorStore
 	WebsitePages []*queries.WebsitePage
 }
+
+type QueryFeedItemsResponse struct {
+	Vectors   []*queries.VectorStore
+	FeedItems []*queries.FeedItem
+}
@@ -0,0 +1,119 @@
+package webparse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// max size of a linked document that will be downloaded
+var DocumentMaxBytes int64 = 20 * 1024 * 1024
+
+// binary documents that can be parsed by the datastore, keyed by extension
+var documentExtensions = map[string]string{
+	".pdf":  "application/pdf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".rtf":  "application/rtf",
+}
+
+type DocumentResponse struct {
+	Url         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Filename    string `json:"filename"`
+	Data        []byte `json:"-"`
+}
+
+// Returns the document content type based on the extension of the url,
+// or an empty string when the url does not point to a supported document
+func DocumentContentTypeFromURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return documentExtensions[strings.ToLower(path.Ext(parsed.Path))]
+}
+
+// Whether the content type is a supported binary document
+func IsDocumentContentType(contentType string) bool {
+	ct := normalizeContentType(contentType)
+	for _, item := range documentExtensions {
+		if item == ct {
+			return true
+		}
+	}
+	return false
+}
+
+// Downloads a binary document from the url. Documents larger than
+// DocumentMaxBytes are rejected
+func FetchDocument(
+	ctx context.Context,
+	logger *slog.Logger,
+	u string,
+) (*DocumentResponse, error) {
+	logger.InfoContext(ctx, "Fetching document ...", "url", u)
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the document returned a bad status code: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > DocumentMaxBytes {
+		return nil, fmt.Errorf("the document is too large: %d bytes", resp.ContentLength)
+	}
+
+	// read one extra byte to detect documents without a content length that are too large
+	data, err := io.ReadAll(io.LimitReader(resp.Body, DocumentMaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the document: %w", err)
+	}
+	if int64(len(data)) > DocumentMaxBytes {
+		return nil, fmt.Errorf("the document is larger than the limit of %d bytes", DocumentMaxBytes)
+	}
+
+	// prefer the content type from the server, falling back on the extension
+	contentType := normalizeContentType(resp.Header.Get("Content-Type"))
+	if !IsDocumentContentType(contentType) {
+		contentType = DocumentContentTypeFromURL(u)
+	}
+	if contentType == "" {
+		return nil, fmt.Errorf("the url is not a supported document: %s", resp.Header.Get("Content-Type"))
+	}
+
+	return &DocumentResponse{
+		Url:         u,
+		ContentType: contentType,
+		Filename:    path.Base(resp.Request.URL.Path),
+		Data:        data,
+	}, nil
+}
+
+func normalizeContentType(contentType string) string {
+	mediatype, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediatype
+}
@@ -0,0 +1,28 @@
+package webparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentContentTypeFromURL(t *testing.T) {
+	require.Equal(t, "application/pdf", DocumentContentTypeFromURL("https://example.com/files/Report.PDF?v=2"))
+	require.Equal(t, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", DocumentContentTypeFromURL("https://example.com/a.docx"))
+	require.Equal(t, "", DocumentContentTypeFromURL("https://example.com/about"))
+	require.Equal(t, "", DocumentContentTypeFromURL("https://example.com/index.html"))
+}
+
+func TestIsDocumentContentType(t *testing.T) {
+	require.True(t, IsDocumentContentType("application/pdf"))
+	require.True(t, IsDocumentContentType("Application/PDF; charset=binary"))
+	require.False(t, IsDocumentContentType("text/html; charset=utf-8"))
+	require.False(t, IsDocumentContentType(""))
+}
+
+func TestIsSameDomain(t *testing.T) {
+	require.True(t, isSameDomain("https://example.com/files/report.pdf", "example.com"))
+	require.True(t, isSameDomain("http://localhost:8080/a.pdf", "localhost:8080"))
+	require.False(t, isSameDomain("https://cdn.other.com/example.com/report.pdf", "example.com"))
+	require.False(t, isSameDomain("https://sub.example.com/report.pdf", "example.com"))
+}
@@ -0,0 +1,222 @@
+package webparse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+)
+
+const (
+	SCRAPE_STRATEGY_MAIN       = "main"
+	SCRAPE_STRATEGY_BODY       = "body"
+	SCRAPE_STRATEGY_AMP        = "amp"
+	SCRAPE_STRATEGY_NOSCRIPT   = "noscript"
+	SCRAPE_STRATEGY_JSON_LD    = "jsonLd"
+	SCRAPE_STRATEGY_NEXT_DATA  = "nextData"
+	SCRAPE_STRATEGY_OPEN_GRAPH = "openGraph"
+)
+
+// minimum number of letters and digits for an extraction to be considered usable
+const thinContentMinChars = 200
+
+// minimum length of a string inside of a __NEXT_DATA__ payload to be treated as content
+const nextDataMinChars = 80
+
+// json-ld keys that hold readable text, in the order they are written
+var jsonLDTextKeys = []string{"headline", "description", "abstract", "articleBody", "text"}
+
+func isThinContent(content string) bool {
+	count := 0
+	for _, r := range content {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			count++
+			if count >= thinContentMinChars {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Attempts the javascript-free fallbacks in order of how much of the page they usually
+// represent. Returns the content and the strategy used, or the reason nothing was found
+func (p *scrapedPage) fallback(
+	ctx context.Context,
+	logger *slog.Logger,
+	converter *md.Converter,
+) (string, string, string) {
+	// the page could not be fetched at all
+	if p.err != nil && p.main == "" && p.body == "" {
+		if p.statusCode != 0 {
+			return "", "", fmt.Sprintf("the page returned status code %d", p.statusCode)
+		}
+		return "", "", fmt.Sprintf("failed to fetch the page: %s", p.err)
+	}
+
+	// server rendered amp variant
+	if p.ampUrl != "" && p.ampUrl != p.url {
+		logger.InfoContext(ctx, "Attempting the amp variant", "url", p.ampUrl)
+		amp := scrapePage(ctx, logger, p.ampUrl)
+		if content, _, err := amp.markdown(logger, converter); err == nil && !isThinContent(content) {
+			return content, SCRAPE_STRATEGY_AMP, ""
+		}
+	}
+
+	if content := p.noscriptMarkdown(converter); !isThinContent(content) {
+		return content, SCRAPE_STRATEGY_NOSCRIPT, ""
+	}
+
+	if content := p.jsonLDMarkdown(); !isThinContent(content) {
+		return content, SCRAPE_STRATEGY_JSON_LD, ""
+	}
+
+	if content := p.nextDataMarkdown(converter); !isThinContent(content) {
+		return content, SCRAPE_STRATEGY_NEXT_DATA, ""
+	}
+
+	// the open graph description is short, so accept anything as a last resort
+	if content := p.openGraphMarkdown(); content != "" {
+		return content, SCRAPE_STRATEGY_OPEN_GRAPH, ""
+	}
+
+	return "", "", "no usable text was found on the page, it likely requires javascript to render"
+}
+
+func (p *scrapedPage) noscriptMarkdown(converter *md.Converter) string {
+	parts := make([]string, 0, len(p.noscript))
+	for _, item := range p.noscript {
+		markdown, err := converter.ConvertString(item)
+		if err != nil {
+			continue
+		}
+		if markdown = strings.TrimSpace(markdown); markdown != "" {
+			parts = append(parts, markdown)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (p *scrapedPage) jsonLDMarkdown() string {
+	parts := make([]string, 0)
+	for _, item := range p.jsonLD {
+		var data any
+		if err := json.Unmarshal([]byte(item), &data); err != nil {
+			continue
+		}
+		parts = append(parts, jsonLDText(data)...)
+	}
+	return strings.Join(utils.RemoveDuplicates(parts, func(val string) any { return val }), "\n\n")
+}
+
+// walks the json-ld graph collecting the readable text fields
+func jsonLDText(data any) []string {
+	parts := make([]string, 0)
+	switch val := data.(type) {
+	case []any:
+		for _, item := range val {
+			parts = append(parts, jsonLDText(item)...)
+		}
+	case map[string]any:
+		for _, key := range jsonLDTextKeys {
+			text, ok := val[key].(string)
+			if !ok || strings.TrimSpace(text) == "" {
+				continue
+			}
+			if key == "headline" {
+				parts = append(parts, fmt.Sprintf("# %s", strings.TrimSpace(text)))
+			} else {
+				parts = append(parts, strings.TrimSpace(text))
+			}
+		}
+		for _, key := range sortedKeys(val) {
+			switch val[key].(type) {
+			case []any, map[string]any:
+				parts = append(parts, jsonLDText(val[key])...)
+			}
+		}
+	}
+	return parts
+}
+
+func (p *scrapedPage) nextDataMarkdown(converter *md.Converter) string {
+	if p.nextData == "" {
+		return ""
+	}
+	var data any
+	if err := json.Unmarshal([]byte(p.nextData), &data); err != nil {
+		return ""
+	}
+
+	parts := make([]string, 0)
+	for _, text := range nextDataText(data) {
+		// rich text fields are commonly stored as html
+		if strings.Contains(text, "<") && strings.Contains(text, ">") {
+			markdown, err := converter.ConvertString(text)
+			if err != nil {
+				continue
+			}
+			text = strings.TrimSpace(markdown)
+		}
+		if text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(utils.RemoveDuplicates(parts, func(val string) any { return val }), "\n\n")
+}
+
+// walks the next.js payload collecting long strings that read like prose
+func nextDataText(data any) []string {
+	parts := make([]string, 0)
+	switch val := data.(type) {
+	case []any:
+		for _, item := range val {
+			parts = append(parts, nextDataText(item)...)
+		}
+	case map[string]any:
+		for _, key := range sortedKeys(val) {
+			parts = append(parts, nextDataText(val[key])...)
+		}
+	case string:
+		text := strings.TrimSpace(val)
+		if len(text) >= nextDataMinChars && strings.Count(text, " ") >= 5 && !strings.HasPrefix(text, "http") {
+			parts = append(parts, text)
+		}
+	}
+	return parts
+}
+
+func (p *scrapedPage) openGraphMarkdown() string {
+	title := strings.TrimSpace(p.ogTitle)
+	if title == "" {
+		title = strings.TrimSpace(p.header.Title)
+	}
+	description := strings.TrimSpace(p.ogDescription)
+	if description == "" {
+		description = strings.TrimSpace(p.header.Description)
+	}
+
+	// a title alone is not usable content
+	if description == "" {
+		return ""
+	}
+	if title == "" {
+		return description
+	}
+	return fmt.Sprintf("# %s\n\n%s", title, description)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
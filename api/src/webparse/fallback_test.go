@@ -0,0 +1,98 @@
+package webparse
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sapphirenw/ai-content-creation-api/src/queries"
+	"github.com/sapphirenw/ai-content-creation-api/src/utils"
+	"github.com/stretchr/testify/require"
+)
+
+var testArticle = strings.Repeat("Server rendered article text that is long enough to be useful. ", 6)
+
+const testShell = `<html><head><title>App</title>%s</head><body><div id="root"></div>%s</body></html>`
+
+func scrapeTestPage(t *testing.T, pages map[string]string) *ScrapeResponse {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	response, err := ScrapeSingle(context.TODO(), utils.DefaultLogger(), &queries.WebsitePage{Url: srv.URL + "/"})
+	require.NoError(t, err)
+	return response
+}
+
+func TestScrapeFallbackAMP(t *testing.T) {
+	response := scrapeTestPage(t, map[string]string{
+		"/":    fmt.Sprintf(testShell, `<link rel="amphtml" href="/amp">`, ""),
+		"/amp": fmt.Sprintf(`<html><body><main><p>%s</p></main></body></html>`, testArticle),
+	})
+	require.Equal(t, SCRAPE_STRATEGY_AMP, response.Strategy)
+	require.Contains(t, response.Content, "Server rendered article")
+	require.Empty(t, response.SkipReason)
+}
+
+func TestScrapeFallbackNoscript(t *testing.T) {
+	response := scrapeTestPage(t, map[string]string{
+		"/": fmt.Sprintf(testShell, "", fmt.Sprintf(`<noscript><p>%s</p></noscript>`, testArticle)),
+	})
+	require.Equal(t, SCRAPE_STRATEGY_NOSCRIPT, response.Strategy)
+	require.Contains(t, response.Content, "Server rendered article")
+}
+
+func TestScrapeFallbackJSONLD(t *testing.T) {
+	ld := fmt.Sprintf(`<script type="application/ld+json">{"@graph": [{"@type": "Article", "headline": "Hello", "articleBody": %q}]}</script>`, testArticle)
+	response := scrapeTestPage(t, map[string]string{
+		"/": fmt.Sprintf(testShell, ld, ""),
+	})
+	require.Equal(t, SCRAPE_STRATEGY_JSON_LD, response.Strategy)
+	require.True(t, strings.HasPrefix(response.Content, "# Hello"))
+}
+
+func TestScrapeFallbackNextData(t *testing.T) {
+	next := fmt.Sprintf(`<script id="__NEXT_DATA__" type="application/json">{"props": {"pageProps": {"slug": "hello", "body": "<p>%s</p>"}}}</script>`, testArticle)
+	response := scrapeTestPage(t, map[string]string{
+		"/": fmt.Sprintf(testShell, "", next),
+	})
+	require.Equal(t, SCRAPE_STRATEGY_NEXT_DATA, response.Strategy)
+	require.Contains(t, response.Content, "Server rendered article")
+	require.NotContains(t, response.Content, "<p>")
+}
+
+func TestScrapeFallbackOpenGraph(t *testing.T) {
+	og := `<meta property="og:title" content="My App"><meta property="og:description" content="A short description">`
+	response := scrapeTestPage(t, map[string]string{
+		"/": fmt.Sprintf(testShell, og, ""),
+	})
+	require.Equal(t, SCRAPE_STRATEGY_OPEN_GRAPH, response.Strategy)
+	require.Equal(t, "# My App\n\nA short description", response.Content)
+}
+
+func TestScrapeFallbackSkipReason(t *testing.T) {
+	response := scrapeTestPage(t, map[string]string{
+		"/": fmt.Sprintf(testShell, "", "<noscript>Please enable javascript</noscript>"),
+	})
+	require.Empty(t, response.Strategy)
+	require.NotEmpty(t, response.SkipReason)
+}
+
+func TestScrapeShortPageIsKept(t *testing.T) {
+	response := scrapeTestPage(t, map[string]string{
+		"/": `<html><body><main><p>Open Monday to Friday.</p></main></body></html>`,
+	})
+	require.Equal(t, SCRAPE_STRATEGY_MAIN, response.Strategy)
+	require.Equal(t, "Open Monday to Friday.", response.Content)
+	require.Empty(t, response.SkipReason)
+}
@@ -0,0 +1,370 @@
+package webparse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+)
+
+const (
+	FEED_TYPE_RSS  = "rss"
+	FEED_TYPE_ATOM = "atom"
+	FEED_TYPE_JSON = "json"
+)
+
+// max size of a feed document that will be read
+const feedMaxBytes = 10 * 1024 * 1024
+
+type Feed struct {
+	Title string      `json:"title"`
+	Type  string      `json:"type"`
+	Items []*FeedItem `json:"items"`
+}
+
+type FeedItem struct {
+	GUID      string     `json:"guid"`
+	Title     string     `json:"title"`
+	Link      string     `json:"link"`
+	Author    string     `json:"author"`
+	Published *time.Time `json:"published"`
+	Content   string     `json:"content"` // markdown representation of the entry content
+}
+
+// Fetches and parses an rss, atom, or json feed from the passed url
+func FetchFeed(
+	ctx context.Context,
+	logger *slog.Logger,
+	url string,
+) (*Feed, error) {
+	logger.InfoContext(ctx, "Fetching feed ...", "url", url)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the request: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the feed returned a bad status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, feedMaxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the feed body: %w", err)
+	}
+
+	return ParseFeed(data)
+}
+
+// Parses the raw feed data. The format is detected from the content
+func ParseFeed(data []byte) (*Feed, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("the feed is empty")
+	}
+
+	// json feed
+	if trimmed[0] == '{' {
+		return parseJSONFeed(trimmed)
+	}
+
+	// find the root element to decide between rss and atom
+	decoder := xml.NewDecoder(bytes.NewReader(trimmed))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the root element of the feed: %w", err)
+		}
+		if el, ok := token.(xml.StartElement); ok {
+			switch strings.ToLower(el.Name.Local) {
+			case "rss", "rdf":
+				return parseRSSFeed(trimmed)
+			case "feed":
+				return parseAtomFeed(trimmed)
+			default:
+				return nil, fmt.Errorf("unsupported feed root element: %s", el.Name.Local)
+			}
+		}
+	}
+}
+
+// rss 2.0 (and rss 1.0 rdf) representation
+type rssFeed struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"` // rss 1.0 places items next to the channel
+}
+
+type rssItem struct {
+	GUID           string `xml:"guid"`
+	Title          string `xml:"title"`
+	Link           string `xml:"link"`
+	Author         string `xml:"author"`
+	Creator        string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate        string `xml:"pubDate"`
+	Date           string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description    string `xml:"description"`
+	ContentEncoded string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+func parseRSSFeed(data []byte) (*Feed, error) {
+	var raw rssFeed
+	if err := unmarshalXML(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the rss feed: %w", err)
+	}
+
+	items := append(raw.Channel.Items, raw.Items...)
+	feed := &Feed{
+		Title: strings.TrimSpace(raw.Channel.Title),
+		Type:  FEED_TYPE_RSS,
+		Items: make([]*FeedItem, 0, len(items)),
+	}
+	for _, item := range items {
+		content := item.ContentEncoded
+		if content == "" {
+			content = item.Description
+		}
+		author := item.Author
+		if author == "" {
+			author = item.Creator
+		}
+		published := parseFeedTime(item.PubDate)
+		if published == nil {
+			published = parseFeedTime(item.Date)
+		}
+
+		feed.Items = append(feed.Items, newFeedItem(item.GUID, item.Title, item.Link, author, published, content))
+	}
+
+	return feed, nil
+}
+
+// atom representation
+type atomFeed struct {
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string `xml:"id"`
+	Title     string `xml:"title"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Content string `xml:"content"`
+	Summary string `xml:"summary"`
+}
+
+func parseAtomFeed(data []byte) (*Feed, error) {
+	var raw atomFeed
+	if err := unmarshalXML(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the atom feed: %w", err)
+	}
+
+	feed := &Feed{
+		Title: strings.TrimSpace(raw.Title),
+		Type:  FEED_TYPE_ATOM,
+		Items: make([]*FeedItem, 0, len(raw.Entries)),
+	}
+	for _, entry := range raw.Entries {
+		// prefer the alternate link
+		link := ""
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+		if link == "" && len(entry.Links) > 0 {
+			link = entry.Links[0].Href
+		}
+
+		authors := make([]string, 0, len(entry.Authors))
+		for _, a := range entry.Authors {
+			if name := strings.TrimSpace(a.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+
+		content := entry.Content
+		if content == "" {
+			content = entry.Summary
+		}
+		published := parseFeedTime(entry.Published)
+		if published == nil {
+			published = parseFeedTime(entry.Updated)
+		}
+
+		feed.Items = append(feed.Items, newFeedItem(entry.ID, entry.Title, link, strings.Join(authors, ", "), published, content))
+	}
+
+	return feed, nil
+}
+
+// json feed (https://jsonfeed.org) representation
+type jsonFeed struct {
+	Version string         `json:"version"`
+	Title   string         `json:"title"`
+	Items   []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            any              `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Author        *jsonFeedAuthor  `json:"author"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+}
+
+func parseJSONFeed(data []byte) (*Feed, error) {
+	var raw jsonFeed
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the json feed: %w", err)
+	}
+	if !strings.Contains(raw.Version, "jsonfeed.org") {
+		return nil, fmt.Errorf("the json document is not a json feed")
+	}
+
+	feed := &Feed{
+		Title: strings.TrimSpace(raw.Title),
+		Type:  FEED_TYPE_JSON,
+		Items: make([]*FeedItem, 0, len(raw.Items)),
+	}
+	for _, item := range raw.Items {
+		guid := ""
+		if item.ID != nil {
+			guid = fmt.Sprint(item.ID)
+		}
+
+		authors := make([]string, 0)
+		if item.Author != nil && item.Author.Name != "" {
+			authors = append(authors, item.Author.Name)
+		}
+		for _, a := range item.Authors {
+			if a.Name != "" {
+				authors = append(authors, a.Name)
+			}
+		}
+
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+		if content == "" {
+			content = item.Summary
+		}
+		published := parseFeedTime(item.DatePublished)
+		if published == nil {
+			published = parseFeedTime(item.DateModified)
+		}
+
+		feed.Items = append(feed.Items, newFeedItem(guid, item.Title, item.URL, strings.Join(authors, ", "), published, content))
+	}
+
+	return feed, nil
+}
+
+func newFeedItem(guid, title, link, author string, published *time.Time, content string) *FeedItem {
+	guid = strings.TrimSpace(guid)
+	link = strings.TrimSpace(link)
+	if guid == "" {
+		guid = link
+	}
+	return &FeedItem{
+		GUID:      guid,
+		Title:     strings.TrimSpace(title),
+		Link:      link,
+		Author:    strings.TrimSpace(author),
+		Published: published,
+		Content:   feedContentToMarkdown(content),
+	}
+}
+
+func unmarshalXML(data []byte, v any) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	// feeds are commonly served in non utf-8 charsets, pass the bytes through
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder.Decode(v)
+}
+
+// feed content is usually html, so convert it to markdown
+func feedContentToMarkdown(content string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+	converter := md.NewConverter("", true, nil)
+	markdown, err := converter.ConvertString(content)
+	if err != nil {
+		return content
+	}
+	return strings.TrimSpace(markdown)
+}
+
+var feedTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseFeedTime(raw string) *time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
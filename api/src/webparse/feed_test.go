@@ -0,0 +1,112 @@
+package webparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+	<title>Example Blog</title>
+	<item>
+		<title>First Post</title>
+		<link>https://example.com/first</link>
+		<guid>first-post</guid>
+		<dc:creator>Jane Doe</dc:creator>
+		<pubDate>Mon, 22 Jul 2024 10:00:00 +0000</pubDate>
+		<description>Short description</description>
+		<content:encoded><![CDATA[<p>The <strong>full</strong> content</p>]]></content:encoded>
+	</item>
+	<item>
+		<title>Second Post</title>
+		<link>https://example.com/second</link>
+		<description>&lt;p&gt;Only a description&lt;/p&gt;</description>
+	</item>
+</channel>
+</rss>`
+
+const testAtomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Example Atom</title>
+	<entry>
+		<id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+		<title>Atom Entry</title>
+		<link rel="self" href="https://example.com/self"/>
+		<link rel="alternate" href="https://example.com/atom-entry"/>
+		<updated>2024-07-20T18:30:02Z</updated>
+		<author><name>John Smith</name></author>
+		<summary>Some summary text</summary>
+	</entry>
+</feed>`
+
+const testJSONFeed = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Example JSON Feed",
+	"items": [
+		{
+			"id": 2,
+			"url": "https://example.com/json-entry",
+			"title": "JSON Entry",
+			"content_html": "<p>Hello world</p>",
+			"date_published": "2024-07-21T08:00:00-07:00",
+			"authors": [{"name": "Alex"}]
+		}
+	]
+}`
+
+func TestParseFeedRSS(t *testing.T) {
+	feed, err := ParseFeed([]byte(testRSSFeed))
+	require.NoError(t, err)
+	require.Equal(t, FEED_TYPE_RSS, feed.Type)
+	require.Equal(t, "Example Blog", feed.Title)
+	require.Len(t, feed.Items, 2)
+
+	first := feed.Items[0]
+	require.Equal(t, "first-post", first.GUID)
+	require.Equal(t, "Jane Doe", first.Author)
+	require.Equal(t, "The **full** content", first.Content)
+	require.NotNil(t, first.Published)
+	require.Equal(t, 22, first.Published.Day())
+
+	// the guid falls back to the link
+	second := feed.Items[1]
+	require.Equal(t, "https://example.com/second", second.GUID)
+	require.Equal(t, "Only a description", second.Content)
+	require.Nil(t, second.Published)
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	feed, err := ParseFeed([]byte(testAtomFeed))
+	require.NoError(t, err)
+	require.Equal(t, FEED_TYPE_ATOM, feed.Type)
+	require.Len(t, feed.Items, 1)
+
+	entry := feed.Items[0]
+	require.Equal(t, "https://example.com/atom-entry", entry.Link)
+	require.Equal(t, "John Smith", entry.Author)
+	require.Equal(t, "Some summary text", entry.Content)
+	require.NotNil(t, entry.Published)
+}
+
+func TestParseFeedJSON(t *testing.T) {
+	feed, err := ParseFeed([]byte(testJSONFeed))
+	require.NoError(t, err)
+	require.Equal(t, FEED_TYPE_JSON, feed.Type)
+	require.Len(t, feed.Items, 1)
+
+	item := feed.Items[0]
+	require.Equal(t, "2", item.GUID)
+	require.Equal(t, "Alex", item.Author)
+	require.Equal(t, "Hello world", item.Content)
+	require.Equal(t, 15, item.Published.Hour())
+}
+
+func TestParseFeedInvalid(t *testing.T) {
+	_, err := ParseFeed([]byte(`<html><body>not a feed</body></html>`))
+	require.Error(t, err)
+
+	_, err = ParseFeed([]byte(`{"hello": "world"}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package webparse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// number of results returned per page by the fixture provider
+const fixturePageSize = 10
+
+// Search provider that serves canned results, used for tests and local development.
+// Results are keyed by the lowercased query
+type FixtureSearchProvider struct {
+	fixtures map[string][]*Result
+}
+
+func NewFixtureSearchProvider(fixtures map[string][]*Result) *FixtureSearchProvider {
+	normalized := make(map[string][]*Result, len(fixtures))
+	for query, results := range fixtures {
+		normalized[fixtureKey(query)] = results
+	}
+	return &FixtureSearchProvider{fixtures: normalized}
+}
+
+// Loads the fixtures from a json file that maps queries to a list of results
+func NewFixtureSearchProviderFromFile(path string) (*FixtureSearchProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the fixtures: %w", err)
+	}
+	var fixtures map[string][]*Result
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse the fixtures: %w", err)
+	}
+	return NewFixtureSearchProvider(fixtures), nil
+}
+
+func (p *FixtureSearchProvider) Name() string {
+	return SEARCH_PROVIDER_FIXTURE
+}
+
+func (p *FixtureSearchProvider) Search(ctx context.Context, args *SearchArgs) (*SearchResponse, error) {
+	if err := args.normalize(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// filter to the requested category, results without one are general
+	matches := make([]*Result, 0)
+	for _, item := range p.fixtures[fixtureKey(args.Query)] {
+		category := item.Category
+		if category == "" {
+			category = SEARCH_CATEGORY_GENERAL
+		}
+		if category == args.Category {
+			matches = append(matches, item)
+		}
+	}
+
+	// paginate
+	start := (args.Page - 1) * fixturePageSize
+	end := start + fixturePageSize
+	if start > len(matches) {
+		start = len(matches)
+	}
+	if end > len(matches) {
+		end = len(matches)
+	}
+	results := matches[start:end]
+
+	return &SearchResponse{
+		Provider:        p.Name(),
+		Query:           args.Query,
+		Page:            args.Page,
+		NumberOfResults: len(results),
+		Results:         results,
+		InfoBoxes:       []*InfoBox{},
+		Suggestions:     []string{},
+	}, nil
+}
+
+func fixtureKey(query string) string {
+	return strings.ToLower(strings.TrimSpace(query))
+}
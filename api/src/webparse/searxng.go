@@ -0,0 +1,154 @@
+package webparse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// max size of a search response that will be read
+const searchMaxBytes = 5 * 1024 * 1024
+
+// Search provider backed by a SearxNG instance using the json api
+type SearxNGProvider struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewSearxNGProvider(endpoint string, timeout time.Duration) *SearxNGProvider {
+	if timeout == 0 {
+		timeout = searchDefaultTimeout
+	}
+	return &SearxNGProvider{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *SearxNGProvider) Name() string {
+	return SEARCH_PROVIDER_SEARXNG
+}
+
+func (p *SearxNGProvider) Search(ctx context.Context, args *SearchArgs) (*SearchResponse, error) {
+	if err := args.normalize(); err != nil {
+		return nil, err
+	}
+
+	// build the query
+	params := url.Values{}
+	params.Set("q", args.Query)
+	params.Set("format", "json")
+	params.Set("categories", args.Category)
+	params.Set("pageno", strconv.Itoa(args.Page))
+	params.Set("safesearch", strconv.Itoa(int(args.SafeSearch)))
+	if lang := searxngLanguage(args.Language, args.Region); lang != "" {
+		params.Set("language", lang)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/search?%s", p.endpoint, params.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the search request: %w", err)
+	}
+
+	// send the request
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send the search request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// parse the body
+	body, err := io.ReadAll(io.LimitReader(resp.Body, searchMaxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the body: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("the response was not 200: %d - %s", resp.StatusCode, string(body))
+	}
+
+	// parse the json
+	var raw searxngResponse
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the json: %w", err)
+	}
+
+	return raw.normalize(p.Name(), args), nil
+}
+
+// searxng takes the region as part of the language code (en-US)
+func searxngLanguage(language string, region string) string {
+	if language == "" || region == "" || strings.Contains(language, "-") {
+		return language
+	}
+	return fmt.Sprintf("%s-%s", language, strings.ToUpper(region))
+}
+
+// raw json representation returned by searxng
+type searxngResponse struct {
+	Query           string           `json:"query"`
+	NumberOfResults int              `json:"number_of_results"`
+	Results         []*searxngResult `json:"results"`
+	InfoBoxes       []*InfoBox       `json:"infoboxes"`
+	Suggestions     []string         `json:"suggestions"`
+}
+
+type searxngResult struct {
+	Title         string   `json:"title"`
+	Content       string   `json:"content"`
+	Url           string   `json:"url"`
+	Engine        string   `json:"engine"`
+	Engines       []string `json:"engines"`
+	Score         float32  `json:"score"`
+	Category      string   `json:"category"`
+	PublishedDate *string  `json:"publishedDate"`
+
+	ImgSrc       string `json:"img_src"`
+	ThumbnailSrc string `json:"thumbnail_src"`
+	Resolution   string `json:"resolution"`
+	ImgFormat    string `json:"img_format"`
+}
+
+func (r *searxngResponse) normalize(provider string, args *SearchArgs) *SearchResponse {
+	results := make([]*Result, 0, len(r.Results))
+	for _, item := range r.Results {
+		result := &Result{
+			Title:        item.Title,
+			Content:      item.Content,
+			Url:          item.Url,
+			Engine:       item.Engine,
+			Engines:      item.Engines,
+			Score:        item.Score,
+			Category:     item.Category,
+			ImgSrc:       item.ImgSrc,
+			ThumbnailSrc: item.ThumbnailSrc,
+			Resolution:   item.Resolution,
+			ImgFormat:    item.ImgFormat,
+		}
+		if item.PublishedDate != nil {
+			result.Published = parseFeedTime(*item.PublishedDate)
+		}
+		results = append(results, result)
+	}
+
+	query := r.Query
+	if query == "" {
+		query = args.Query
+	}
+
+	return &SearchResponse{
+		Provider:        provider,
+		Query:           query,
+		Page:            args.Page,
+		NumberOfResults: len(results),
+		Results:         results,
+		InfoBoxes:       r.InfoBoxes,
+		Suggestions:     r.Suggestions,
+	}
+}
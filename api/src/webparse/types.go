@@ -1,8 +1,13 @@
 package webparse
 
+import "time"
+
 type ScrapeResponse struct {
-	Header  *ScrapeHeader `json:"header"`
-	Content string        `json:"content"`
+	Header      *ScrapeHeader `json:"header"`
+	Content     string        `json:"content"`
+	ContentType string        `json:"contentType"` // content type reported by the server
+	Strategy    string        `json:"strategy"`    // how the content was extracted from the page
+	SkipReason  string        `json:"skipReason"`  // set when no usable text could be extracted
 }
 
 type ScrapeHeader struct {
@@ -11,29 +16,31 @@ type ScrapeHeader struct {
 	Tags        []string `json:"tags"`
 }
 
+// normalized response returned by every search provider
 type SearchResponse struct {
-	Query           string     `json:"query"`             // The query passed to the search engine
-	NumberOfResults int        `json:"number_of_results"` // number of results parsed
-	Results         []*Result  `json:"results"`           // list of result objects
-	InfoBoxes       []*InfoBox `json:"infoboxes"`         // list of info boxes, usually from Wikipedia. Usually empty
-	Suggestions     []string   `json:"suggestions"`       // suggested future search terms
+	Provider        string     `json:"provider"`        // name of the provider that ran the search
+	Query           string     `json:"query"`           // The query passed to the search engine
+	Page            int        `json:"page"`            // 1 indexed page of the results
+	NumberOfResults int        `json:"numberOfResults"` // number of results parsed
+	Results         []*Result  `json:"results"`         // list of result objects
+	InfoBoxes       []*InfoBox `json:"infoboxes"`       // list of info boxes, usually from Wikipedia. Usually empty
+	Suggestions     []string   `json:"suggestions"`     // suggested future search terms
 }
 
 type Result struct {
-	Title     string   `json:"title"`      // title of the result
-	Content   string   `json:"content"`    // string content description of the result | only available in web search
-	Url       string   `json:"url"`        // url of the search result
-	Engine    string   `json:"engine"`     // which engine this result comes from
-	ParsedUrl []string `json:"parsed_url"` // list of url components
-	Engines   []string `json:"engines"`    // list of engines this result appears with
-	Positions []int    `json:"positions"`  // list of the search position for this result
-	Score     float32  `json:"score"`      // extrapolation of search position across providers
-	Category  string   `json:"category"`   // category of the result
-
-	ImgSrc       string `json:"img_src"`              // endpoint of the image | only available in image search
-	ThumbnailSrc string `json:"thumbnail_src"`        // endpoint for the thumbnail | only available in image search
+	Title     string     `json:"title"`     // title of the result
+	Content   string     `json:"content"`   // string content description of the result | only available in web search
+	Url       string     `json:"url"`       // url of the search result
+	Engine    string     `json:"engine"`    // which engine this result comes from
+	Engines   []string   `json:"engines"`   // list of engines this result appears with
+	Score     float32    `json:"score"`     // extrapolation of search position across providers
+	Category  string     `json:"category"`  // category of the result
+	Published *time.Time `json:"published"` // publish date of the result when known
+
+	ImgSrc       string `json:"imgSrc"`               // endpoint of the image | only available in image search
+	ThumbnailSrc string `json:"thumbnailSrc"`         // endpoint for the thumbnail | only available in image search
 	Resolution   string `json:"resolution,omitempty"` // resolution of the image. Not always available | only available in image search
-	ImgFormat    string `json:"img_format,omitempty"` // image format. Not always available | only available in image search
+	ImgFormat    string `json:"imgFormat,omitempty"`  // image format. Not always available | only available in image search
 }
 
 type InfoBox struct {
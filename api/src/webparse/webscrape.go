@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"strings"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
@@ -11,7 +12,6 @@ import (
 	"github.com/gocolly/colly/v2/extensions"
 	"github.com/sapphirenw/ai-content-creation-api/src/queries"
 	"github.com/sapphirenw/ai-content-creation-api/src/slogger"
-	"github.com/sapphirenw/ai-content-creation-api/src/utils"
 )
 
 type ScrapeHrefsArgs struct {
@@ -45,8 +45,16 @@ func ScrapeHrefs(
 		return nil, fmt.Errorf("REGEX: there was an issue parsing the regex: %v", err)
 	}
 
-	// create the results
+	// create the results. Urls are only added once so repeated links do not use up the limit
 	result := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(u string) {
+		if seen[u] || len(result) >= args.Limit {
+			return
+		}
+		seen[u] = true
+		result = append(result, u)
+	}
 
 	// converter and scraper
 	c := colly.NewCollector(
@@ -94,15 +102,32 @@ func ScrapeHrefs(
 		}
 
 		// check white/black list
-		if isURLAllowed(u, whitelist, blacklist) {
-			e.Request.Visit(u)
+		if !isURLAllowed(u, whitelist, blacklist) {
+			return
+		}
+
+		// linked documents are collected without being downloaded, so the domain filter of the
+		// collector is checked here
+		if DocumentContentTypeFromURL(u) != "" {
+			if args.AllowOtherDomains || isSameDomain(u, site.Domain) {
+				add(u)
+			}
+			return
+		}
+
+		e.Request.Visit(u)
+	})
+
+	// documents served without an extension are detected from the headers
+	c.OnResponseHeaders(func(r *colly.Response) {
+		if IsDocumentContentType(r.Headers.Get("Content-Type")) {
+			add(r.Request.URL.String())
+			r.Request.Abort()
 		}
 	})
 
 	c.OnScraped(func(r *colly.Response) {
-		if len(result) < args.Limit {
-			result = append(result, r.Request.URL.String())
-		}
+		add(r.Request.URL.String())
 	})
 
 	// error handler
@@ -114,14 +139,18 @@ func ScrapeHrefs(
 	c.Visit(fmt.Sprintf("%s://%s%s", site.Protocol, site.Domain, site.Path))
 	c.Wait()
 
-	// remove duplicates
-	result = utils.RemoveDuplicates(result, func(val string) any {
-		return val
-	})
-
 	return result, nil
 }
 
+// whether the url is on the domain, as the allowed domains of the collector are checked
+func isSameDomain(u string, domain string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return parsed.Hostname() == domain || parsed.Host == domain
+}
+
 func ScrapeSingleOLD(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -189,15 +218,80 @@ func ScrapeSingle(
 	logger *slog.Logger,
 	page *queries.WebsitePage,
 ) (*ScrapeResponse, error) {
-	var res string
-	header := ScrapeHeader{}
-
-	// converter and scraper
 	converter := md.NewConverter("", true, nil)
+
+	scraped := scrapePage(ctx, logger, page.Url)
+	if IsDocumentContentType(scraped.contentType) {
+		logger.InfoContext(ctx, "The page is a document", "url", page.Url, "contentType", scraped.contentType)
+		return &ScrapeResponse{
+			Header:      &scraped.header,
+			ContentType: scraped.contentType,
+		}, nil
+	}
+
+	res, strategy, err := scraped.markdown(logger, converter)
+	if err != nil {
+		return nil, slogger.Error(ctx, logger, "failed to parse the markdown", err)
+	}
+
+	response := &ScrapeResponse{
+		Header:      &scraped.header,
+		Content:     res,
+		ContentType: scraped.contentType,
+		Strategy:    strategy,
+	}
+
+	// client side rendered pages return little to no content, so try the fallbacks
+	if isThinContent(res) {
+		logger.InfoContext(ctx, "Thin content found, attempting fallbacks", "url", page.Url)
+		content, strategy, reason := scraped.fallback(ctx, logger, converter)
+		if content != "" {
+			response.Content = content
+			response.Strategy = strategy
+		} else if strings.TrimSpace(res) == "" {
+			response.Strategy = ""
+			response.SkipReason = reason
+		}
+		// otherwise the page is short, but the content is real so it is still indexed
+	}
+
+	return response, nil
+}
+
+// raw extraction of a single html page
+type scrapedPage struct {
+	url         string
+	header      ScrapeHeader
+	contentType string
+	statusCode  int
+	err         error
+
+	main     string
+	body     string
+	noscript []string
+	jsonLD   []string
+	nextData string
+	ampUrl   string
+
+	ogTitle       string
+	ogDescription string
+}
+
+func scrapePage(
+	ctx context.Context,
+	logger *slog.Logger,
+	url string,
+) *scrapedPage {
+	page := &scrapedPage{url: url}
 	scraper := colly.NewCollector()
 
-	var main string
-	var body string
+	// do not download binary documents, they are fetched separately
+	scraper.OnResponseHeaders(func(r *colly.Response) {
+		page.contentType = normalizeContentType(r.Headers.Get("Content-Type"))
+		if IsDocumentContentType(page.contentType) {
+			r.Request.Abort()
+		}
+	})
 
 	// parse both main and body in-case website does not use main
 	scraper.OnHTML("main", func(e *colly.HTMLElement) {
@@ -207,7 +301,7 @@ func ScrapeSingle(
 			logger.ErrorContext(ctx, "Error parsing the html", "error", err)
 			return
 		}
-		main = raw
+		page.main = raw
 	})
 	scraper.OnHTML("body", func(e *colly.HTMLElement) {
 		// parse the response
@@ -216,23 +310,43 @@ func ScrapeSingle(
 			logger.ErrorContext(ctx, "Error parsing the html", "error", err)
 			return
 		}
-		body = raw
+		page.body = raw
 	})
 
 	// parse the header
 	scraper.OnHTML("head", func(e *colly.HTMLElement) {
-		header.Title = e.ChildText("title")
-		header.Description = e.ChildAttr(`meta[name="description"]`, "content")
+		page.header.Title = e.ChildText("title")
+		page.header.Description = e.ChildAttr(`meta[name="description"]`, "content")
 
 		// get the keywords
 		keywordsRaw := e.ChildAttr(`meta[name="keywords"]`, "content")
 		keywords := make([]string, 0)
 		keywords = append(keywords, strings.Split(keywordsRaw, ",")...)
-		header.Tags = keywords
+		page.header.Tags = keywords
+
+		// values used by the fallbacks
+		page.ogTitle = e.ChildAttr(`meta[property="og:title"]`, "content")
+		page.ogDescription = e.ChildAttr(`meta[property="og:description"]`, "content")
+		if amp := e.ChildAttr(`link[rel="amphtml"]`, "href"); amp != "" {
+			page.ampUrl = e.Request.AbsoluteURL(amp)
+		}
+	})
+
+	// server rendered payloads for pages that render on the client
+	scraper.OnHTML("noscript", func(e *colly.HTMLElement) {
+		page.noscript = append(page.noscript, e.Text)
+	})
+	scraper.OnHTML(`script[type="application/ld+json"]`, func(e *colly.HTMLElement) {
+		page.jsonLD = append(page.jsonLD, e.Text)
+	})
+	scraper.OnHTML(`script#__NEXT_DATA__`, func(e *colly.HTMLElement) {
+		page.nextData = e.Text
 	})
 
 	// error handler
 	scraper.OnError(func(r *colly.Response, err error) {
+		page.statusCode = r.StatusCode
+		page.err = err
 		logger.ErrorContext(ctx, "There was an issue scraping the url", "url", r.Request.URL, "statusCode", r.StatusCode)
 	})
 
@@ -240,30 +354,27 @@ func ScrapeSingle(
 		logger.DebugContext(ctx, "Visiting url", "url", r.URL)
 	})
 
-	scraper.Visit(page.Url)
+	scraper.Visit(url)
 
+	return page
+}
+
+// Converts the main element of the page to markdown, falling back on the body
+func (p *scrapedPage) markdown(logger *slog.Logger, converter *md.Converter) (string, string, error) {
 	// parse from the main element
-	markdown, err := converter.ConvertString(main)
+	markdown, err := converter.ConvertString(p.main)
 	if err != nil {
-		return nil, slogger.Error(ctx, logger, "failed to parse the markdown from main", err)
-	} else {
-		res = markdown
+		return "", "", fmt.Errorf("failed to parse the markdown from main: %w", err)
+	}
+	if markdown != "" {
+		return markdown, SCRAPE_STRATEGY_MAIN, nil
 	}
 
 	// check if any content was found and if not use the body
-	if res == "" {
-		logger.Info("No content found in main, using body")
-		markdown, err := converter.ConvertString(body)
-		if err != nil {
-			return nil, slogger.Error(ctx, logger, "failed to parse the markdown from body", err)
-		} else {
-			// add to response
-			res = markdown
-		}
+	logger.Info("No content found in main, using body")
+	markdown, err = converter.ConvertString(p.body)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse the markdown from body: %w", err)
 	}
-
-	return &ScrapeResponse{
-		Header:  &header,
-		Content: res,
-	}, nil
+	return markdown, SCRAPE_STRATEGY_BODY, nil
 }
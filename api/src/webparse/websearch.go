@@ -1,47 +1,123 @@
 package webparse
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
+	"strings"
+	"time"
 )
 
-func WebSearch(query string) (*SearchResponse, error) {
-	endpoint, exists := os.LookupEnv("WEBSEARCH_ENDPOINT")
-	if !exists {
-		return nil, fmt.Errorf("the env variable `WEBSEARCH_ENDPOINT` is required")
-	}
+const (
+	SEARCH_PROVIDER_SEARXNG = "searxng"
+	SEARCH_PROVIDER_FIXTURE = "fixture"
 
-	// send the request
-	u := fmt.Sprintf("%s/search?q=%s&format=json", endpoint, url.QueryEscape(query))
-	resp, err := http.Get(u)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send the search request: %w", err)
+	SEARCH_CATEGORY_GENERAL = "general"
+	SEARCH_CATEGORY_IMAGES  = "images"
+)
+
+type SafeSearch int
+
+const (
+	SAFE_SEARCH_OFF SafeSearch = iota
+	SAFE_SEARCH_MODERATE
+	SAFE_SEARCH_STRICT
+)
+
+// default timeout for a single search request
+const searchDefaultTimeout = 15 * time.Second
+
+// A backend that can run web searches. Implementations normalize their
+// results into a SearchResponse so they can be swapped by configuration
+type SearchProvider interface {
+	// name of the provider, reported on the response
+	Name() string
+
+	// runs the search described by the args
+	Search(ctx context.Context, args *SearchArgs) (*SearchResponse, error)
+}
+
+type SearchArgs struct {
+	Query      string     `json:"query"`
+	Category   string     `json:"category"`   // SEARCH_CATEGORY_*, defaults to general
+	Page       int        `json:"page"`       // 1 indexed page of results
+	SafeSearch SafeSearch `json:"safeSearch"` // SAFE_SEARCH_*
+	Language   string     `json:"language"`   // language code such as `en`
+	Region     string     `json:"region"`     // region code such as `US`
+}
+
+func (a *SearchArgs) Valid(ctx context.Context) map[string]string {
+	p := make(map[string]string)
+	if strings.TrimSpace(a.Query) == "" {
+		p["query"] = "cannot be empty"
 	}
-	defer resp.Body.Close()
+	if a.Page < 0 {
+		p["page"] = "cannot be negative"
+	}
+	if a.Category != "" && a.Category != SEARCH_CATEGORY_GENERAL && a.Category != SEARCH_CATEGORY_IMAGES {
+		p["category"] = fmt.Sprintf("must be one of: %s, %s", SEARCH_CATEGORY_GENERAL, SEARCH_CATEGORY_IMAGES)
+	}
+	if a.SafeSearch < SAFE_SEARCH_OFF || a.SafeSearch > SAFE_SEARCH_STRICT {
+		p["safeSearch"] = "must be 0 (off), 1 (moderate), or 2 (strict)"
+	}
+	return p
+}
 
-	// parse the body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read the body: %w", err)
+// fills in the defaults and validates the args
+func (a *SearchArgs) normalize() error {
+	if a.Category == "" {
+		a.Category = SEARCH_CATEGORY_GENERAL
+	}
+	if a.Page == 0 {
+		a.Page = 1
+	}
+	if problems := a.Valid(context.Background()); len(problems) > 0 {
+		return fmt.Errorf("invalid search args: %v", problems)
 	}
+	return nil
+}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("the response was not 200: %d - %s", resp.StatusCode, string(body))
+// Creates the search provider configured by the environment.
+// `WEBSEARCH_PROVIDER` selects the backend (defaults to searxng),
+// searxng reads `WEBSEARCH_ENDPOINT` and fixture reads `WEBSEARCH_FIXTURES`
+func GetSearchProvider() (SearchProvider, error) {
+	provider := os.Getenv("WEBSEARCH_PROVIDER")
+	if provider == "" {
+		provider = SEARCH_PROVIDER_SEARXNG
 	}
 
-	// parse the json
-	var response SearchResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse the json: %w", err)
+	switch provider {
+	case SEARCH_PROVIDER_SEARXNG:
+		endpoint, exists := os.LookupEnv("WEBSEARCH_ENDPOINT")
+		if !exists {
+			return nil, fmt.Errorf("the env variable `WEBSEARCH_ENDPOINT` is required")
+		}
+		return NewSearxNGProvider(endpoint, searchDefaultTimeout), nil
+	case SEARCH_PROVIDER_FIXTURE:
+		path, exists := os.LookupEnv("WEBSEARCH_FIXTURES")
+		if !exists {
+			return nil, fmt.Errorf("the env variable `WEBSEARCH_FIXTURES` is required")
+		}
+		return NewFixtureSearchProviderFromFile(path)
+	default:
+		return nil, fmt.Errorf("unknown search provider: %s", provider)
 	}
+}
 
-	return &response, err
+// Runs a general web search with the configured provider
+func WebSearch(ctx context.Context, query string) (*SearchResponse, error) {
+	provider, err := GetSearchProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the search provider: %w", err)
+	}
+	return provider.Search(ctx, &SearchArgs{Query: query})
 }
 
-func ImageSearch(query string) (*SearchResponse, error) {
-	return WebSearch(fmt.Sprintf("!images %s", query))
+// Runs an image search with the configured provider
+func ImageSearch(ctx context.Context, query string) (*SearchResponse, error) {
+	provider, err := GetSearchProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the search provider: %w", err)
+	}
+	return provider.Search(ctx, &SearchArgs{Query: query, Category: SEARCH_CATEGORY_IMAGES})
 }
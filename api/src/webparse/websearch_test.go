@@ -1,8 +1,11 @@
 package webparse
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -11,7 +14,7 @@ import (
 func TestWeb(t *testing.T) {
 	query := "what is the most common rock in the world"
 
-	response, err := WebSearch(query)
+	response, err := WebSearch(context.TODO(), query)
 	require.NoError(t, err)
 	require.NotEmpty(t, response.Results)
 
@@ -20,9 +23,9 @@ func TestWeb(t *testing.T) {
 }
 
 func TestImage(t *testing.T) {
-	query := "!images what is the most common rock in the world"
+	query := "what is the most common rock in the world"
 
-	response, err := WebSearch(query)
+	response, err := ImageSearch(context.TODO(), query)
 	require.NoError(t, err)
 	require.NotEmpty(t, response.Results)
 	require.NotEmpty(t, response.Results[0].ImgSrc)
@@ -30,3 +33,98 @@ func TestImage(t *testing.T) {
 	enc, _ := json.MarshalIndent(response.Results[0], "", "    ")
 	fmt.Println(string(enc))
 }
+
+func TestSearxNGProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		require.Equal(t, "/search", r.URL.Path)
+		require.Equal(t, "granite", q.Get("q"))
+		require.Equal(t, "json", q.Get("format"))
+		require.Equal(t, "2", q.Get("pageno"))
+		require.Equal(t, "2", q.Get("safesearch"))
+		require.Equal(t, "en-US", q.Get("language"))
+		require.Equal(t, SEARCH_CATEGORY_GENERAL, q.Get("categories"))
+
+		w.Write([]byte(`{
+			"query": "granite",
+			"number_of_results": 120,
+			"results": [{
+				"title": "Granite",
+				"content": "Granite is a coarse-grained igneous rock",
+				"url": "https://example.com/granite",
+				"engine": "duckduckgo",
+				"engines": ["duckduckgo", "bing"],
+				"parsed_url": ["https", "example.com", "/granite"],
+				"score": 2.5,
+				"category": "general",
+				"publishedDate": "2024-07-01T00:00:00"
+			}],
+			"infoboxes": [],
+			"suggestions": ["basalt"]
+		}`))
+	}))
+	defer srv.Close()
+
+	provider := NewSearxNGProvider(srv.URL+"/", 0)
+	response, err := provider.Search(context.TODO(), &SearchArgs{
+		Query:      "granite",
+		Page:       2,
+		SafeSearch: SAFE_SEARCH_STRICT,
+		Language:   "en",
+		Region:     "us",
+	})
+	require.NoError(t, err)
+	require.Equal(t, SEARCH_PROVIDER_SEARXNG, response.Provider)
+	require.Equal(t, 2, response.Page)
+	require.Equal(t, 1, response.NumberOfResults)
+	require.Equal(t, []string{"basalt"}, response.Suggestions)
+	require.Equal(t, "https://example.com/granite", response.Results[0].Url)
+	require.NotNil(t, response.Results[0].Published)
+}
+
+func TestSearxNGProviderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	provider := NewSearxNGProvider(srv.URL, 0)
+	_, err := provider.Search(context.TODO(), &SearchArgs{Query: "granite"})
+	require.Error(t, err)
+
+	// invalid args are rejected before sending the request
+	_, err = provider.Search(context.TODO(), &SearchArgs{Query: " "})
+	require.Error(t, err)
+}
+
+func TestFixtureSearchProvider(t *testing.T) {
+	results := make([]*Result, 0)
+	for i := 0; i < 15; i++ {
+		results = append(results, &Result{Title: fmt.Sprintf("Result %d", i), Url: fmt.Sprintf("https://example.com/%d", i)})
+	}
+	results = append(results, &Result{Title: "Image", Category: SEARCH_CATEGORY_IMAGES, ImgSrc: "https://example.com/img.png"})
+
+	provider := NewFixtureSearchProvider(map[string][]*Result{"Granite": results})
+
+	// first page
+	response, err := provider.Search(context.TODO(), &SearchArgs{Query: "granite"})
+	require.NoError(t, err)
+	require.Equal(t, SEARCH_PROVIDER_FIXTURE, response.Provider)
+	require.Len(t, response.Results, fixturePageSize)
+
+	// second page
+	response, err = provider.Search(context.TODO(), &SearchArgs{Query: "granite", Page: 2})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 5)
+	require.Equal(t, "Result 10", response.Results[0].Title)
+
+	// images
+	response, err = provider.Search(context.TODO(), &SearchArgs{Query: "granite", Category: SEARCH_CATEGORY_IMAGES})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+
+	// unknown queries have no results
+	response, err = provider.Search(context.TODO(), &SearchArgs{Query: "basalt"})
+	require.NoError(t, err)
+	require.Empty(t, response.Results)
+}
@@ -0,0 +1,110 @@
+-- +goose Up
+-- +goose StatementBegin
+
+--
+-- Begin feeds
+--
+
+-- rss, atom, or json feeds that are polled for new entries
+CREATE TABLE feed(
+    id uuid NOT NULL DEFAULT uuid7(),
+    customer_id uuid NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    feed_type TEXT NOT NULL DEFAULT '', -- rss, atom, json. Set on the first successful poll
+    fetch_article BOOLEAN NOT NULL DEFAULT false, -- scrape the linked article of every entry in place of the feed content, once per entry
+    poll_interval_minutes INT NOT NULL DEFAULT 60,
+    last_polled_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    last_error TEXT DEFAULT NULL,
+
+    PRIMARY KEY (id),
+    CONSTRAINT cnst_unique_feed UNIQUE (customer_id, url),
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- a single entry inside of a feed
+CREATE TABLE feed_item(
+    id uuid NOT NULL DEFAULT uuid7(),
+    customer_id uuid NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    feed_id uuid NOT NULL REFERENCES feed(id) ON DELETE CASCADE,
+    guid TEXT NOT NULL, -- id of the entry inside the feed. Falls back to the link
+    title TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    content TEXT NOT NULL DEFAULT '', -- markdown content of the entry or the fetched article
+    sha_256 CHAR(64) NOT NULL,
+    metadata JSONB DEFAULT '{}',
+
+    vector_sha_256 CHAR(64) NOT NULL DEFAULT '', -- fingerprint when last vectorized
+
+    PRIMARY KEY (id),
+    CONSTRAINT cnst_unique_feed_item UNIQUE (feed_id, guid), -- entries are only allowed once
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- vectors associated with a feed item's contents
+CREATE TABLE feed_item_vector(
+    id uuid NOT NULL DEFAULT uuid7(),
+    feed_item_id uuid NOT NULL REFERENCES feed_item(id) ON DELETE CASCADE,
+    vector_store_id uuid NOT NULL,
+    customer_id uuid NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    index INT NOT NULL, -- data is chunked, so an index is required to sort the data
+    metadata JSONB DEFAULT '{}',
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_vector_store FOREIGN KEY (vector_store_id, customer_id) REFERENCES vector_store(id, customer_id) ON DELETE CASCADE,
+    CONSTRAINT fk_feed_item_id_vector_store_id UNIQUE (feed_item_id, vector_store_id, customer_id),
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- include feed items when checking for unreferenced vectors
+CREATE OR REPLACE FUNCTION delete_vector_if_unreferenced()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT COUNT(*) FROM document_vector WHERE vector_store_id = OLD.vector_store_id) = 0
+    AND (SELECT COUNT(*) FROM website_page_vector WHERE vector_store_id = OLD.vector_store_id) = 0
+    AND (SELECT COUNT(*) FROM feed_item_vector WHERE vector_store_id = OLD.vector_store_id) = 0 THEN
+        DELETE FROM vector_store WHERE id = OLD.vector_store_id;
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_after_delete_feed_item_vector
+AFTER DELETE ON feed_item_vector
+FOR EACH ROW
+EXECUTE FUNCTION delete_vector_if_unreferenced();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER trg_after_delete_feed_item_vector ON feed_item_vector;
+
+CREATE OR REPLACE FUNCTION delete_vector_if_unreferenced()
+RETURNS TRIGGER AS $$
+BEGIN
+    -- Check if there are no more references in document_vector
+    IF (SELECT COUNT(*) FROM document_vector WHERE vector_store_id = OLD.vector_store_id) = 0 THEN
+        -- Check if there are no more references in website_page_vector
+        IF (SELECT COUNT(*) FROM website_page_vector WHERE vector_store_id = OLD.vector_store_id) = 0 THEN
+            -- Delete from vector_store if there are no references
+            DELETE FROM vector_store WHERE id = OLD.vector_store_id;
+        END IF;
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE feed_item_vector;
+DROP TABLE feed_item;
+DROP TABLE feed;
+
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- mime type of the page. Binary documents linked from a site (pdf, docx, etc.)
+-- are stored as pages with their original content type
+ALTER TABLE website_page ADD COLUMN content_type TEXT NOT NULL DEFAULT 'text/html';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE website_page DROP COLUMN content_type;
+-- +goose StatementEnd
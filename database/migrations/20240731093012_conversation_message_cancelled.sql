@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- set when the user cancelled the generation of this message. The message
+-- contains whatever was generated before the cancellation
+ALTER TABLE conversation_message ADD COLUMN is_cancelled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE conversation_message DROP COLUMN is_cancelled;
+-- +goose StatementEnd
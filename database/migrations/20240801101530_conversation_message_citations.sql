@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- the sources an AI message was composed from, referenced in the message
+-- as numbered markers. ex: [{"number": 1, "sourceType": "document", ...}]
+ALTER TABLE conversation_message ADD COLUMN citations JSONB NOT NULL DEFAULT '[]';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE conversation_message DROP COLUMN citations;
+-- +goose StatementEnd
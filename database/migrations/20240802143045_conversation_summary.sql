@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- running summary of the earlier messages of the conversation, sent in place of the
+-- messages once the conversation no longer fits in the context window of the model.
+-- summary_index is the index of the first message that is not covered by the summary
+ALTER TABLE conversation ADD COLUMN summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE conversation ADD COLUMN summary_index INT NOT NULL DEFAULT 0;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE conversation DROP COLUMN summary_index;
+ALTER TABLE conversation DROP COLUMN summary;
+-- +goose StatementEnd
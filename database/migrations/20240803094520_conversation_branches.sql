@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- messages form a tree, where editing or regenerating a message creates a sibling of it
+-- under the same parent. The index is the depth of the message in its branch
+ALTER TABLE conversation_message ADD COLUMN parent_id uuid NULL REFERENCES conversation_message(id) ON DELETE CASCADE;
+ALTER TABLE conversation_message DROP CONSTRAINT cnst_conversation_message_unique;
+CREATE INDEX idx_conversation_message_parent ON conversation_message(conversation_id, parent_id);
+
+-- link the existing messages into a single branch
+UPDATE conversation_message cm SET
+    parent_id = prev.id
+FROM conversation_message prev
+WHERE prev.conversation_id = cm.conversation_id
+AND prev.index = cm.index - 1;
+
+-- the last message of the branch that is shown and continued
+ALTER TABLE conversation ADD COLUMN active_message_id uuid NULL REFERENCES conversation_message(id) ON DELETE SET NULL;
+UPDATE conversation c SET
+    active_message_id = (
+        SELECT cm.id FROM conversation_message cm
+        WHERE cm.conversation_id = c.id
+        ORDER BY cm.index DESC
+        LIMIT 1
+    );
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- only keep the active branch of every conversation
+WITH RECURSIVE active AS (
+    SELECT cm.id, cm.parent_id FROM conversation_message cm
+    JOIN conversation c ON c.active_message_id = cm.id
+    UNION ALL
+    SELECT cm.id, cm.parent_id FROM conversation_message cm
+    JOIN active a ON cm.id = a.parent_id
+)
+DELETE FROM conversation_message
+WHERE id NOT IN (SELECT id FROM active);
+
+ALTER TABLE conversation DROP COLUMN active_message_id;
+DROP INDEX idx_conversation_message_parent;
+ALTER TABLE conversation_message DROP COLUMN parent_id;
+ALTER TABLE conversation_message ADD CONSTRAINT cnst_conversation_message_unique UNIQUE (conversation_id, index);
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- full text search over the messages of the conversations. The expression has to match the
+-- search queries for the index to be used
+CREATE INDEX idx_conversation_message_search ON conversation_message
+USING gin (to_tsvector('english', message));
+
+-- embeddings of the messages for semantic search. The messages are embedded by a background
+-- job after they are saved, so they are kept out of the message table
+CREATE TABLE conversation_message_embedding(
+    conversation_message_id uuid NOT NULL PRIMARY KEY,
+    customer_id uuid NOT NULL,
+    embeddings VECTOR(512) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_conversation_message FOREIGN KEY (conversation_message_id) REFERENCES conversation_message(id) ON DELETE CASCADE,
+    CONSTRAINT fk_customer FOREIGN KEY (customer_id) REFERENCES customer(id) ON DELETE CASCADE
+);
+CREATE INDEX ON conversation_message_embedding USING hnsw (embeddings vector_ip_ops);
+CREATE INDEX idx_conversation_message_embedding_customer ON conversation_message_embedding(customer_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE conversation_message_embedding;
+DROP INDEX idx_conversation_message_search;
+-- +goose StatementEnd
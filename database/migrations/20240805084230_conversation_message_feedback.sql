@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- feedback of the users on the AI messages. The model, the tools, and the sources that
+-- produced the message are copied onto the feedback when it is created, so the feedback can
+-- be aggregated without walking the conversations
+CREATE TABLE conversation_message_feedback(
+    id uuid NOT NULL DEFAULT uuid7(),
+    conversation_message_id uuid NOT NULL REFERENCES conversation_message(id) ON DELETE CASCADE,
+    conversation_id uuid NOT NULL REFERENCES conversation(id) ON DELETE CASCADE,
+    customer_id uuid NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    llm_id uuid REFERENCES llm(id) ON DELETE SET NULL,
+
+    rating SMALLINT NOT NULL, -- 1 for a thumbs up, -1 for a thumbs down
+    comment TEXT NOT NULL DEFAULT '',
+    tools TEXT[] NOT NULL DEFAULT '{}', -- names of the tools called for the message
+    sources JSONB NOT NULL DEFAULT '[]', -- the citations of the message
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (id),
+    CONSTRAINT cnst_conversation_message_feedback_unique UNIQUE (conversation_message_id),
+    CONSTRAINT cnst_conversation_message_feedback_rating CHECK (rating IN (-1, 1))
+);
+CREATE INDEX idx_conversation_message_feedback_customer ON conversation_message_feedback(customer_id, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE conversation_message_feedback;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- the tools a customer enables for their conversations, and how many tool calls can be
+-- chained in a single turn. A configuration without a conversation type applies to every
+-- conversation type that does not have its own
+CREATE TABLE customer_tool_configuration(
+    id uuid NOT NULL DEFAULT uuid7(),
+    customer_id uuid NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    conversation_type TEXT NOT NULL DEFAULT '',
+
+    enabled_tools TEXT[] NOT NULL DEFAULT '{}',
+    max_depth INT NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (id),
+    CONSTRAINT cnst_customer_tool_configuration_unique UNIQUE (customer_id, conversation_type),
+    CONSTRAINT cnst_customer_tool_configuration_max_depth CHECK (max_depth > 0)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE customer_tool_configuration;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- how completions of an llm are retried when the provider fails, and the ordered llms that
+-- are tried next when the errors persist or the input does not fit in the model. Llms
+-- without a policy use the default policy in code without any fallbacks
+CREATE TABLE llm_retry_policy(
+    llm_id uuid NOT NULL REFERENCES llm(id) ON DELETE CASCADE,
+
+    max_retries INT NOT NULL,
+    initial_backoff_ms INT NOT NULL,
+    max_backoff_ms INT NOT NULL,
+    fallback_llm_ids uuid[] NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (llm_id),
+    CONSTRAINT cnst_llm_retry_policy_retries CHECK (max_retries >= 0),
+    CONSTRAINT cnst_llm_retry_policy_backoff CHECK (initial_backoff_ms > 0 AND max_backoff_ms >= initial_backoff_ms)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE llm_retry_policy;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- responses of deterministic completions, keyed by a sha256 of the model, temperature, json
+-- schema, and the messages sent to the provider. Hits are served without calling the provider
+-- until the entry expires
+CREATE TABLE completion_cache(
+    customer_id uuid NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    key CHAR(64) NOT NULL,
+
+    model VARCHAR(256) NOT NULL,
+    message TEXT NOT NULL,
+
+    -- usage of the completion that was cached, which every hit saves
+    input_tokens INT NOT NULL,
+    output_tokens INT NOT NULL,
+
+    hits INT NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMP WITH TIME ZONE NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (customer_id, key)
+);
+CREATE INDEX idx_completion_cache_expires_at ON completion_cache(expires_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE completion_cache;
+-- +goose StatementEnd
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- when the last summary of the object failed, so the summary job does not retry it on every run
+ALTER TABLE document ADD COLUMN summary_failed_at TIMESTAMP WITH TIME ZONE NULL;
+ALTER TABLE website_page ADD COLUMN summary_failed_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- embeddings of the summaries of documents and website pages. Searched before the chunks so
+-- broad questions match whole objects. `sha_256` is the fingerprint of the summary that was
+-- embedded, so stale vectors are ignored until the summary is regenerated
+CREATE TABLE document_summary_vector(
+    document_id uuid NOT NULL REFERENCES document(id) ON DELETE CASCADE,
+    customer_id uuid NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    sha_256 CHAR(64) NOT NULL,
+    embeddings VECTOR(512) NOT NULL,
+
+    PRIMARY KEY (document_id),
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX ON document_summary_vector USING hnsw (embeddings vector_ip_ops);
+CREATE INDEX idx_document_summary_vector_customer_id ON document_summary_vector(customer_id);
+
+CREATE TABLE website_page_summary_vector(
+    website_page_id uuid NOT NULL REFERENCES website_page(id) ON DELETE CASCADE,
+    customer_id uuid NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    sha_256 CHAR(64) NOT NULL,
+    embeddings VECTOR(512) NOT NULL,
+
+    PRIMARY KEY (website_page_id),
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX ON website_page_summary_vector USING hnsw (embeddings vector_ip_ops);
+CREATE INDEX idx_website_page_summary_vector_customer_id ON website_page_summary_vector(customer_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE website_page_summary_vector;
+DROP TABLE document_summary_vector;
+ALTER TABLE website_page DROP COLUMN summary_failed_at;
+ALTER TABLE document DROP COLUMN summary_failed_at;
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- versions of the system prompts. The built-in defaults have no customer and are seeded from
+-- the prompts in code on startup, a customer can save their own versions to override them.
+-- Only a single version of a prompt is active for the customer, and for the defaults
+CREATE TABLE prompt_template(
+    id uuid NOT NULL DEFAULT uuid7(),
+    customer_id uuid NULL REFERENCES customer(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    version INT NOT NULL,
+
+    content TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT false,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (id),
+    CONSTRAINT cnst_prompt_template_version CHECK (version > 0)
+);
+CREATE UNIQUE INDEX idx_prompt_template_version ON prompt_template(
+    COALESCE(customer_id, '00000000-0000-0000-0000-000000000000'), name, version
+);
+CREATE UNIQUE INDEX idx_prompt_template_active ON prompt_template(
+    COALESCE(customer_id, '00000000-0000-0000-0000-000000000000'), name
+) WHERE is_active = true;
+
+-- the prompt versions that were used in a conversation
+CREATE TABLE conversation_prompt_template(
+    conversation_id uuid NOT NULL REFERENCES conversation(id) ON DELETE CASCADE,
+    prompt_template_id uuid NOT NULL REFERENCES prompt_template(id) ON DELETE CASCADE,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (conversation_id, prompt_template_id)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE conversation_prompt_template;
+DROP TABLE prompt_template;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- when the last embedding of the message failed, so the embedding job does not retry it on
+-- every run
+ALTER TABLE conversation_message ADD COLUMN embedding_failed_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE conversation_message DROP COLUMN embedding_failed_at;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- name: GetCompletionCacheHit :one
+UPDATE completion_cache
+SET
+    hits = hits + 1,
+    last_hit_at = CURRENT_TIMESTAMP
+WHERE customer_id = $1
+  AND key = $2
+  AND expires_at > CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: UpsertCompletionCache :one
+INSERT INTO completion_cache (
+    customer_id, key, model, message, input_tokens, output_tokens, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (customer_id, key) DO UPDATE SET
+    model = EXCLUDED.model,
+    message = EXCLUDED.message,
+    input_tokens = EXCLUDED.input_tokens,
+    output_tokens = EXCLUDED.output_tokens,
+    expires_at = EXCLUDED.expires_at,
+    hits = 0,
+    last_hit_at = NULL,
+    created_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteExpiredCompletionCache :exec
+DELETE FROM completion_cache
+WHERE expires_at <= CURRENT_TIMESTAMP;
@@ -42,18 +42,77 @@ INSERT INTO conversation_message (
     tool_use_id,
     tool_name,
     tool_arguments,
-    tool_results
-) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12 )
-ON CONFLICT (conversation_id, index)
-DO UPDATE SET
-    updated_at = CURRENT_TIMESTAMP
+    tool_results,
+    is_cancelled,
+    parent_id
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14 )
 RETURNING *;
 
+-- name: UpdateConversationMessageCitations :exec
+UPDATE conversation_message SET
+    citations = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: UpdateConversationMessageToolResults :exec
+UPDATE conversation_message SET
+    tool_results = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
 -- name: GetConversationMessages :many
 SELECT * FROM conversation_message
 WHERE conversation_id = $1
 ORDER BY index ASC;
 
+-- name: GetConversationMessage :one
+SELECT * FROM conversation_message
+WHERE id = $1
+AND conversation_id = $2;
+
+-- name: GetConversationBranch :many
+WITH RECURSIVE branch AS (
+    SELECT m.id, m.parent_id FROM conversation_message m
+    WHERE m.id = $1
+    UNION ALL
+    SELECT m.id, m.parent_id FROM conversation_message m
+    JOIN branch b ON m.id = b.parent_id
+)
+SELECT cm.* FROM conversation_message cm
+JOIN branch b ON cm.id = b.id
+ORDER BY cm.index ASC;
+
+-- name: GetLatestConversationLeaf :one
+WITH RECURSIVE descendant AS (
+    SELECT m.id FROM conversation_message m
+    WHERE m.id = $1
+    UNION ALL
+    SELECT m.id FROM conversation_message m
+    JOIN descendant d ON m.parent_id = d.id
+)
+SELECT cm.* FROM conversation_message cm
+JOIN descendant d ON cm.id = d.id
+WHERE NOT EXISTS (
+    SELECT 1 FROM conversation_message child
+    WHERE child.parent_id = cm.id
+)
+ORDER BY cm.index DESC, cm.id DESC
+LIMIT 1;
+
+-- name: GetConversationLeaves :many
+SELECT cm.* FROM conversation_message cm
+WHERE cm.conversation_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM conversation_message child
+    WHERE child.parent_id = cm.id
+)
+ORDER BY cm.id DESC;
+
+-- name: GetConversationMessageTree :many
+SELECT id, parent_id, index FROM conversation_message
+WHERE conversation_id = $1
+ORDER BY index ASC, id ASC;
+
 -- name: ClearConversation :exec
 DELETE FROM conversation_message
 WHERE conversation_id = $1;
@@ -64,6 +123,19 @@ UPDATE conversation SET
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateConversationSummary :exec
+UPDATE conversation SET
+    summary = $2,
+    summary_index = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: SetConversationActiveMessage :exec
+UPDATE conversation SET
+    active_message_id = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
 -- name: SetChatLLM :exec
 UPDATE conversation SET
     curr_llm_id = $2
@@ -77,4 +149,80 @@ FROM conversation c
 JOIN llm ON llm.id = c.curr_llm_id
 JOIN available_model am ON llm.model = am.id
 WHERE c.customer_id = $1
-AND c.id = $2;
\ No newline at end of file
+AND c.id = $2;
+-- name: SearchConversationMessages :many
+SELECT
+    cm.id,
+    cm.conversation_id,
+    cm.role,
+    cm.index,
+    cm.created_at,
+    c.title,
+    c.conversation_type,
+    ts_headline(
+        'english',
+        cm.message,
+        websearch_to_tsquery('english', $2::text),
+        'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=**, StopSel=**'
+    )::text AS snippet,
+    ts_rank(to_tsvector('english', cm.message), websearch_to_tsquery('english', $2::text))::float8 AS rank
+FROM conversation_message cm
+JOIN conversation c ON c.id = cm.conversation_id
+WHERE c.customer_id = $1
+AND to_tsvector('english', cm.message) @@ websearch_to_tsquery('english', $2::text)
+AND cm.role = ANY($3::text[])
+AND (c.conversation_type = $4 OR $4 = '')
+AND ($5::timestamptz IS NULL OR cm.created_at >= $5::timestamptz)
+AND ($6::timestamptz IS NULL OR cm.created_at < $6::timestamptz)
+AND ($7::uuid[] IS NULL OR cm.llm_id = ANY($7::uuid[]))
+ORDER BY rank DESC, cm.created_at DESC
+LIMIT $8;
+
+-- name: SearchConversationMessagesSemantic :many
+SELECT
+    cm.id,
+    cm.conversation_id,
+    cm.role,
+    cm.message,
+    cm.index,
+    cm.created_at,
+    c.title,
+    c.conversation_type,
+    (cme.embeddings <#> $2)::float8 AS distance
+FROM conversation_message_embedding cme
+JOIN conversation_message cm ON cm.id = cme.conversation_message_id
+JOIN conversation c ON c.id = cm.conversation_id
+WHERE cme.customer_id = $1
+AND cm.role = ANY($3::text[])
+AND (c.conversation_type = $4 OR $4 = '')
+AND ($5::timestamptz IS NULL OR cm.created_at >= $5::timestamptz)
+AND ($6::timestamptz IS NULL OR cm.created_at < $6::timestamptz)
+AND ($7::uuid[] IS NULL OR cm.llm_id = ANY($7::uuid[]))
+AND (cme.embeddings <#> $2) <= $9::float8
+ORDER BY cme.embeddings <#> $2
+LIMIT $8;
+
+-- name: GetConversationMessagesWithoutEmbeddings :many
+SELECT cm.id, cm.message, c.customer_id
+FROM conversation_message cm
+JOIN conversation c ON c.id = cm.conversation_id
+LEFT JOIN conversation_message_embedding cme ON cme.conversation_message_id = cm.id
+WHERE cme.conversation_message_id IS NULL
+AND cm.role = ANY($1::text[])
+AND cm.message != ''
+AND (cm.embedding_failed_at IS NULL OR cm.embedding_failed_at < $3)
+ORDER BY cm.id DESC
+LIMIT $2;
+
+-- name: SetConversationMessagesEmbeddingFailed :exec
+UPDATE conversation_message SET
+    embedding_failed_at = CURRENT_TIMESTAMP
+WHERE id = ANY($1::uuid[]);
+
+-- name: CreateConversationMessageEmbedding :exec
+INSERT INTO conversation_message_embedding (
+    conversation_message_id, customer_id, embeddings
+) VALUES ( $1, $2, $3 )
+ON CONFLICT (conversation_message_id)
+DO UPDATE SET
+    embeddings = EXCLUDED.embeddings;
@@ -43,10 +43,40 @@ WHERE id = $1;
 UPDATE document SET
     updated_at = CURRENT_TIMESTAMP,
     summary = $2,
-    summary_sha_256 = $3
+    summary_sha_256 = $3,
+    summary_failed_at = NULL
 WHERE id = $1
 RETURNING *;
 
+-- name: SetDocumentSummaryFailed :exec
+UPDATE document SET
+    summary_failed_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetDocumentsToSummarize :many
+SELECT * FROM document
+WHERE validated = true
+  AND is_asset = false
+  AND (
+    summary_sha_256 != sha_256
+    -- summaries created before the retrieval layer only need to be embedded
+    OR NOT EXISTS (SELECT 1 FROM document_summary_vector v WHERE v.document_id = document.id)
+  )
+  AND (summary_failed_at IS NULL OR summary_failed_at < $1)
+ORDER BY updated_at ASC
+LIMIT $2;
+
+-- name: UpsertDocumentSummaryVector :exec
+INSERT INTO document_summary_vector (
+    document_id, customer_id, sha_256, embeddings
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (document_id) DO UPDATE SET
+    sha_256 = EXCLUDED.sha_256,
+    embeddings = EXCLUDED.embeddings,
+    updated_at = CURRENT_TIMESTAMP;
+
 -- name: MarkDocumentAsUploaded :one
 UPDATE document
 SET validated = true
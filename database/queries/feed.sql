@@ -0,0 +1,103 @@
+-- name: CreateFeed :one
+INSERT INTO feed (
+    customer_id, url, title, feed_type, fetch_article, poll_interval_minutes
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT ON CONSTRAINT cnst_unique_feed
+DO UPDATE SET
+    updated_at = CURRENT_TIMESTAMP,
+    fetch_article = EXCLUDED.fetch_article,
+    poll_interval_minutes = EXCLUDED.poll_interval_minutes
+RETURNING *;
+
+-- name: GetFeed :one
+SELECT * FROM feed
+WHERE id = $1;
+
+-- name: GetFeedsByCustomer :many
+SELECT * FROM feed
+WHERE customer_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetFeedsToPoll :many
+SELECT * FROM feed
+WHERE last_polled_at IS NULL
+OR last_polled_at < CURRENT_TIMESTAMP - make_interval(mins => poll_interval_minutes)
+ORDER BY last_polled_at ASC NULLS FIRST;
+
+-- name: UpdateFeedPolled :one
+UPDATE feed SET
+    updated_at = CURRENT_TIMESTAMP,
+    last_polled_at = CURRENT_TIMESTAMP,
+    title = $2,
+    feed_type = $3,
+    last_error = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFeed :exec
+DELETE FROM feed WHERE id = $1;
+
+-- name: CreateFeedItem :one
+INSERT INTO feed_item (
+    customer_id, feed_id, guid, title, link, author, published_at, content, sha_256, metadata
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT ON CONSTRAINT cnst_unique_feed_item
+DO UPDATE SET
+    updated_at = CURRENT_TIMESTAMP,
+    title = EXCLUDED.title,
+    link = EXCLUDED.link,
+    author = EXCLUDED.author,
+    published_at = EXCLUDED.published_at,
+    content = EXCLUDED.content,
+    sha_256 = EXCLUDED.sha_256,
+    metadata = EXCLUDED.metadata
+RETURNING *;
+
+-- name: GetFeedItem :one
+SELECT * FROM feed_item
+WHERE id = $1;
+
+-- name: GetFeedItemsByFeed :many
+SELECT * FROM feed_item
+WHERE feed_id = $1
+ORDER BY published_at DESC NULLS LAST;
+
+-- name: GetFeedItemsNotVectorized :many
+SELECT * FROM feed_item
+WHERE feed_id = $1
+AND sha_256 != vector_sha_256;
+
+-- name: UpdateFeedItemVectorSig :exec
+UPDATE feed_item SET
+    updated_at = CURRENT_TIMESTAMP,
+    vector_sha_256 = $2
+WHERE id = $1;
+
+-- name: CreateFeedItemVector :one
+INSERT INTO feed_item_vector (
+    feed_item_id, vector_store_id, customer_id, index, metadata
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: DeleteFeedItemVectors :exec
+DELETE FROM feed_item_vector
+WHERE feed_item_id = $1;
+
+-- name: QueryVectorStoreFeedItemsScoped :many
+SELECT
+    sqlc.embed(vs),
+    sqlc.embed(fi)
+FROM vector_store vs
+JOIN feed_item_vector fiv ON vs.id = fiv.vector_store_id
+JOIN feed_item fi ON fi.id = fiv.feed_item_id
+WHERE vs.customer_id = $1
+AND ($4::uuid[] IS NULL OR fi.id = ANY($4::uuid[]))
+AND ($5::uuid[] IS NULL OR fi.feed_id = ANY($5::uuid[]))
+ORDER BY vs.embeddings <#> $3
+LIMIT $2;
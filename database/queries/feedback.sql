@@ -0,0 +1,80 @@
+-- name: UpsertConversationMessageFeedback :one
+INSERT INTO conversation_message_feedback (
+    conversation_message_id,
+    conversation_id,
+    customer_id,
+    llm_id,
+    rating,
+    comment,
+    tools,
+    sources
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8 )
+ON CONFLICT (conversation_message_id)
+DO UPDATE SET
+    llm_id = EXCLUDED.llm_id,
+    rating = EXCLUDED.rating,
+    comment = EXCLUDED.comment,
+    tools = EXCLUDED.tools,
+    sources = EXCLUDED.sources,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetConversationFeedback :many
+SELECT * FROM conversation_message_feedback
+WHERE conversation_id = $1;
+
+-- name: DeleteConversationMessageFeedback :exec
+DELETE FROM conversation_message_feedback
+WHERE conversation_message_id = $1
+AND conversation_id = $2;
+
+-- name: GetFeedbackByLLM :many
+SELECT
+    f.llm_id,
+    COALESCE(l.title, '')::text AS title,
+    COALESCE(l.model, '')::text AS model,
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE f.rating = 1) AS positive,
+    COUNT(*) FILTER (WHERE f.rating = -1) AS negative,
+    COUNT(*) FILTER (WHERE f.comment != '') AS comments
+FROM conversation_message_feedback f
+LEFT JOIN llm l ON l.id = f.llm_id
+WHERE f.customer_id = $1
+AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+GROUP BY f.llm_id, l.title, l.model
+ORDER BY total DESC;
+
+-- name: GetFeedbackByConversationType :many
+SELECT
+    c.conversation_type,
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE f.rating = 1) AS positive,
+    COUNT(*) FILTER (WHERE f.rating = -1) AS negative,
+    COUNT(*) FILTER (WHERE f.comment != '') AS comments
+FROM conversation_message_feedback f
+JOIN conversation c ON c.id = f.conversation_id
+WHERE f.customer_id = $1
+AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+GROUP BY c.conversation_type
+ORDER BY total DESC;
+
+-- name: GetFeedbackBySource :many
+SELECT
+    (s->>'sourceType')::text AS source_type,
+    (s->>'sourceId')::uuid AS source_id,
+    MAX(s->>'title')::text AS title,
+    COUNT(DISTINCT f.id) AS total,
+    COUNT(DISTINCT f.id) FILTER (WHERE f.rating = 1) AS positive,
+    COUNT(DISTINCT f.id) FILTER (WHERE f.rating = -1) AS negative,
+    COUNT(DISTINCT f.id) FILTER (WHERE f.comment != '') AS comments
+FROM conversation_message_feedback f
+CROSS JOIN LATERAL jsonb_array_elements(f.sources) s
+WHERE f.customer_id = $1
+AND s ? 'sourceId'
+AND ($2::timestamptz IS NULL OR f.created_at >= $2::timestamptz)
+AND ($3::timestamptz IS NULL OR f.created_at < $3::timestamptz)
+GROUP BY source_type, source_id
+ORDER BY negative DESC, total DESC
+LIMIT $4;
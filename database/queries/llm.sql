@@ -128,4 +128,25 @@ WITH RequiredLLM AS (
 SELECT sqlc.embed(llm), sqlc.embed(am)
 FROM RequiredLLM llm
 INNER JOIN available_model am ON am.id = llm.model
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+-- name: GetLLMRetryPolicy :one
+SELECT * FROM llm_retry_policy
+WHERE llm_id = $1;
+
+-- name: UpsertLLMRetryPolicy :one
+INSERT INTO llm_retry_policy (
+    llm_id, max_retries, initial_backoff_ms, max_backoff_ms, fallback_llm_ids
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (llm_id) DO UPDATE SET
+    max_retries = EXCLUDED.max_retries,
+    initial_backoff_ms = EXCLUDED.initial_backoff_ms,
+    max_backoff_ms = EXCLUDED.max_backoff_ms,
+    fallback_llm_ids = EXCLUDED.fallback_llm_ids,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteLLMRetryPolicy :exec
+DELETE FROM llm_retry_policy
+WHERE llm_id = $1;
@@ -7,4 +7,22 @@ WHERE provider = $1;
 
 -- name: GetAvailableModel :one
 SELECT * FROM available_model
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+-- name: UpsertAvailableModel :one
+INSERT INTO available_model (
+    id, provider, display_name, description, input_token_limit, output_token_limit,
+    input_cost_per_million_tokens, output_cost_per_million_tokens, is_visible
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (id) DO UPDATE SET
+    provider = EXCLUDED.provider,
+    display_name = EXCLUDED.display_name,
+    description = EXCLUDED.description,
+    input_token_limit = EXCLUDED.input_token_limit,
+    output_token_limit = EXCLUDED.output_token_limit,
+    input_cost_per_million_tokens = EXCLUDED.input_cost_per_million_tokens,
+    output_cost_per_million_tokens = EXCLUDED.output_cost_per_million_tokens,
+    is_visible = EXCLUDED.is_visible,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
@@ -0,0 +1,70 @@
+-- name: GetActivePromptTemplate :one
+-- the active version of the customer, or the active default
+SELECT * FROM prompt_template
+WHERE (customer_id = $1 OR customer_id IS NULL)
+AND name = $2
+AND is_active = true
+ORDER BY customer_id NULLS LAST
+LIMIT 1;
+
+-- name: GetActivePromptTemplates :many
+-- the active version of every prompt the customer uses
+SELECT DISTINCT ON (name) * FROM prompt_template
+WHERE (customer_id = $1 OR customer_id IS NULL)
+AND is_active = true
+ORDER BY name, customer_id NULLS LAST;
+
+-- name: GetPromptTemplateVersions :many
+-- the versions of the customer followed by the versions of the defaults, newest first
+SELECT * FROM prompt_template
+WHERE (customer_id = $1 OR customer_id IS NULL)
+AND name = $2
+ORDER BY customer_id NULLS LAST, version DESC;
+
+-- name: GetLatestPromptTemplate :one
+-- the newest version of the customer, or of the defaults when the customer is null
+SELECT * FROM prompt_template
+WHERE customer_id IS NOT DISTINCT FROM $1
+AND name = $2
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: CreatePromptTemplate :one
+INSERT INTO prompt_template (
+    customer_id, name, version, content, description, is_active
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: DeactivatePromptTemplates :exec
+UPDATE prompt_template SET
+    is_active = false,
+    updated_at = CURRENT_TIMESTAMP
+WHERE customer_id IS NOT DISTINCT FROM $1
+AND name = $2
+AND is_active = true;
+
+-- name: ActivatePromptTemplate :one
+UPDATE prompt_template SET
+    is_active = true,
+    updated_at = CURRENT_TIMESTAMP
+WHERE customer_id = $1
+AND name = $2
+AND version = $3
+RETURNING *;
+
+-- name: CreateConversationPromptTemplate :exec
+INSERT INTO conversation_prompt_template (
+    conversation_id, prompt_template_id
+) VALUES (
+    $1, $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetConversationPromptTemplates :many
+SELECT sqlc.embed(prompt_template), conversation_prompt_template.created_at AS used_at
+FROM conversation_prompt_template
+JOIN prompt_template ON prompt_template.id = conversation_prompt_template.prompt_template_id
+WHERE conversation_prompt_template.conversation_id = $1
+ORDER BY conversation_prompt_template.created_at ASC;
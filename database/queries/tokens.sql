@@ -40,3 +40,8 @@ FROM token_usage tu
 JOIN available_model am ON tu.model = am.id
 WHERE tu.customer_id = $1
 GROUP BY tu.model, am.input_cost_per_million_tokens, am.output_cost_per_million_tokens;
+
+-- name: GetConversationTokenUsage :many
+SELECT * FROM token_usage
+WHERE conversation_id = $1
+ORDER BY created_at ASC, id ASC;
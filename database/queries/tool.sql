@@ -0,0 +1,28 @@
+-- name: GetCustomerToolConfigurations :many
+SELECT * FROM customer_tool_configuration
+WHERE customer_id = $1
+ORDER BY conversation_type;
+
+-- name: GetCustomerToolConfiguration :one
+-- the configuration of the conversation type, or the configuration of every type
+SELECT * FROM customer_tool_configuration
+WHERE customer_id = $1
+AND (conversation_type = $2 OR conversation_type = '')
+ORDER BY conversation_type DESC
+LIMIT 1;
+
+-- name: UpsertCustomerToolConfiguration :one
+INSERT INTO customer_tool_configuration (
+    customer_id, conversation_type, enabled_tools, max_depth
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (customer_id, conversation_type) DO UPDATE SET
+    enabled_tools = EXCLUDED.enabled_tools,
+    max_depth = EXCLUDED.max_depth,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteCustomerToolConfiguration :exec
+DELETE FROM customer_tool_configuration
+WHERE customer_id = $1 AND conversation_type = $2;
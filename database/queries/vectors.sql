@@ -100,6 +100,30 @@ AND (
 ORDER BY vs.embeddings <#> $3
 LIMIT $2;
 
+-- name: QueryDocumentSummaries :many
+SELECT
+    sqlc.embed(d),
+    (dsv.embeddings <#> $3)::float AS distance
+FROM document_summary_vector dsv
+JOIN document d ON d.id = dsv.document_id
+WHERE dsv.customer_id = $1
+  AND dsv.sha_256 = d.summary_sha_256
+  AND d.summary_sha_256 = d.sha_256
+ORDER BY dsv.embeddings <#> $3
+LIMIT $2;
+
+-- name: QueryWebsitePageSummaries :many
+SELECT
+    sqlc.embed(wp),
+    (wpsv.embeddings <#> $3)::float AS distance
+FROM website_page_summary_vector wpsv
+JOIN website_page wp ON wp.id = wpsv.website_page_id
+WHERE wpsv.customer_id = $1
+  AND wpsv.sha_256 = wp.summary_sha_256
+  AND wp.summary_sha_256 = wp.vector_sha_256
+ORDER BY wpsv.embeddings <#> $3
+LIMIT $2;
+
 -- -- name: QueryVectorStore :many
 -- SELECT
 --     sqlc.embed(vs),
@@ -139,4 +163,13 @@ LIMIT $2;
 --     ($7::uuid[] IS NULL OR w.id = ANY($7::uuid[]))
 -- )
 -- ORDER BY vs.embeddings <#> $3
--- LIMIT $2;
\ No newline at end of file
+-- LIMIT $2;
+-- name: GetVectorChunkIndexes :many
+SELECT vector_store_id, index FROM document_vector
+WHERE document_vector.vector_store_id = ANY($1::uuid[])
+UNION ALL
+SELECT vector_store_id, index FROM website_page_vector
+WHERE website_page_vector.vector_store_id = ANY($1::uuid[])
+UNION ALL
+SELECT vector_store_id, index FROM feed_item_vector
+WHERE feed_item_vector.vector_store_id = ANY($1::uuid[]);
@@ -2,6 +2,12 @@
 SELECT * FROM website
 WHERE id = $1;
 
+-- name: GetWebsiteByPath :one
+SELECT * FROM website
+WHERE customer_id = $1
+AND domain = $2
+AND path = $3;
+
 -- name: GetWebsitesByCustomer :many
 SELECT * FROM website
 WHERE customer_id = $1;
@@ -39,9 +45,9 @@ DELETE FROM website WHERE id = $1;
 
 -- name: CreateWebsitePage :one
 INSERT INTO website_page (
-    customer_id, website_id, url, sha_256, metadata
+    customer_id, website_id, url, sha_256, metadata, content_type
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6
 )
 ON CONFLICT ON CONSTRAINT cnst_unique_website_page
 DO UPDATE SET
@@ -53,6 +59,12 @@ RETURNING *;
 SELECT * FROM website_page
 WHERE id = $1;
 
+-- name: GetWebsitePageByUrl :one
+SELECT * FROM website_page
+WHERE customer_id = $1 AND url = $2 AND is_valid = true
+ORDER BY updated_at DESC
+LIMIT 1;
+
 -- name: UpdateWebsitePageSignature :one
 UPDATE website_page SET
     updated_at = CURRENT_TIMESTAMP,
@@ -64,10 +76,52 @@ RETURNING *;
 UPDATE website_page SET
     updated_at = CURRENT_TIMESTAMP,
     summary = $2,
-    summary_sha_256 = $3
+    summary_sha_256 = $3,
+    summary_failed_at = NULL
 WHERE id = $1
 RETURNING *;
 
+-- name: SetWebsitePageSummaryFailed :exec
+UPDATE website_page SET
+    summary_failed_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetWebsitePagesToSummarize :many
+SELECT * FROM website_page
+WHERE is_valid = true
+  AND vector_sha_256 != ''
+  AND (
+    summary_sha_256 != vector_sha_256
+    -- summaries created before the retrieval layer only need to be embedded
+    OR NOT EXISTS (SELECT 1 FROM website_page_summary_vector v WHERE v.website_page_id = website_page.id)
+  )
+  AND (summary_failed_at IS NULL OR summary_failed_at < $1)
+ORDER BY updated_at ASC
+LIMIT $2;
+
+-- name: UpsertWebsitePageSummaryVector :exec
+INSERT INTO website_page_summary_vector (
+    website_page_id, customer_id, sha_256, embeddings
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (website_page_id) DO UPDATE SET
+    sha_256 = EXCLUDED.sha_256,
+    embeddings = EXCLUDED.embeddings,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: UpdateWebsitePageContentType :exec
+UPDATE website_page SET
+    updated_at = CURRENT_TIMESTAMP,
+    content_type = $2
+WHERE id = $1;
+
+-- name: UpdateWebsitePageMetadata :exec
+UPDATE website_page SET
+    updated_at = CURRENT_TIMESTAMP,
+    metadata = $2
+WHERE id = $1;
+
 -- name: UpdateWebsitePageVectorSig :exec
 UPDATE website_page SET
     updated_at = CURRENT_TIMESTAMP,
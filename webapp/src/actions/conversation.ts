@@ -1,6 +1,6 @@
 "use server"
 
-import { Conversation, ConversationResponse } from "@/types/conversation"
+import { Conversation, ConversationResponse, MessageFeedback } from "@/types/conversation"
 import { getCID } from "./customer"
 import { cookies } from "next/headers"
 import { sendRequestV1 } from "./api"
@@ -13,6 +13,20 @@ export async function getAllConversations(): Promise<Conversation[]> {
     return response
 }
 
+export async function sendMessageFeedback(
+    conversationId: string,
+    messageId: string,
+    rating: 1 | -1,
+    comment: string,
+): Promise<MessageFeedback> {
+    const cid = await getCID()
+    return await sendRequestV1<MessageFeedback>({
+        route: `customers/${cid}/conversations/${conversationId}/messages/${messageId}/feedback`,
+        method: "PUT",
+        body: JSON.stringify({ rating: rating, comment: comment }),
+    })
+}
+
 export async function getConversation(): Promise<ConversationResponse> {
     // read the cookie
     const convId = cookies().get("conversationId")?.value
@@ -1,6 +1,6 @@
 "use server"
 
-import { RAG2Request, RAGRequest, RAGResponse } from "@/types/rag"
+import { RAG2Request, RAG2Ticket, RAGRequest, RAGResponse } from "@/types/rag"
 import { cookies } from "next/headers"
 import { getCID } from "./customer"
 import { sendRequestV1 } from "./api"
@@ -14,6 +14,27 @@ export async function createRagRequest(): Promise<RAG2Request> {
     })
 }
 
+// creates a short lived ticket to open the rag websocket for the conversation
+export async function createRagTicket(conversationId: string): Promise<RAG2Ticket> {
+    const cid = await getCID()
+    return await sendRequestV1<RAG2Ticket>({
+        route: `customers/${cid}/rag2/ticket`,
+        method: "POST",
+        body: JSON.stringify({ conversationId: conversationId }),
+    })
+}
+
+// saves the page a web search offered to save, once the user confirmed it
+export async function confirmRagSave(conversationId: string, messageId: string): Promise<boolean> {
+    const cid = await getCID()
+    await sendRequestV1({
+        route: `customers/${cid}/rag2/confirmSave`,
+        method: "POST",
+        body: JSON.stringify({ conversationId: conversationId, messageId: messageId }),
+    })
+    return true
+}
+
 export async function handleRAG(req: RAGRequest): Promise<RAGResponse> {
     const cid = await getCID()
     // get the conversationId
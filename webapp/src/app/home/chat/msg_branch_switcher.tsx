@@ -0,0 +1,34 @@
+import { MessageBranches } from "@/types/conversation"
+import { ChevronLeft, ChevronRight } from "lucide-react"
+
+// switches between the versions of an edited or regenerated message
+export default function MessageBranchSwitcher({
+    branches,
+    disabled,
+    onSwitch,
+}: {
+    branches: MessageBranches
+    disabled?: boolean
+    onSwitch: (messageId: string) => void
+}) {
+    const count = branches.messageIds.length
+    const current = branches.current
+
+    return <div className="flex items-center space-x-1 text-xs text-muted-foreground">
+        <button
+            className="disabled:opacity-30"
+            disabled={disabled || current == 0}
+            onClick={() => onSwitch(branches.messageIds[current - 1])}
+        >
+            <ChevronLeft size={14} />
+        </button>
+        <p>{current + 1}/{count}</p>
+        <button
+            className="disabled:opacity-30"
+            disabled={disabled || current == count - 1}
+            onClick={() => onSwitch(branches.messageIds[current + 1])}
+        >
+            <ChevronRight size={14} />
+        </button>
+    </div>
+}
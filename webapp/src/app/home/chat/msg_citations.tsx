@@ -0,0 +1,28 @@
+import { Citation } from "@/types/conversation"
+import Link from "next/link"
+
+// the sources that are referenced in an ai message as [number]
+export default function MessageCitations({
+    citations
+}: {
+    citations: Citation[]
+}) {
+    return <div className="flex flex-wrap gap-2 pt-2">
+        {citations.map((item) => <CitationItem key={item.number} citation={item} />)}
+    </div>
+}
+
+function CitationItem({ citation }: { citation: Citation }) {
+    const content = <div className="flex items-center space-x-2 bg-secondary hover:opacity-75 transition-opacity rounded-lg px-2 py-1 max-w-xs" title={citation.snippet}>
+        <span className="font-bold">[{citation.number}]</span>
+        <p className="truncate">{citation.title || citation.url || citation.sourceType}</p>
+    </div>
+
+    if (citation.sourceType === "document" && citation.sourceId !== undefined) {
+        return <Link href={`/settings/documents/${citation.sourceId}`}>{content}</Link>
+    }
+    if (citation.url) {
+        return <a href={citation.url} target="_blank" rel="noreferrer">{content}</a>
+    }
+    return content
+}
@@ -0,0 +1,44 @@
+import { MessageFeedback } from "@/types/conversation"
+import { ThumbsDown, ThumbsUp } from "lucide-react"
+import { useState } from "react"
+import { Button } from "@/components/ui/button"
+import { Textarea } from "@/components/ui/textarea"
+
+// thumbs up or down on an ai message, with an optional comment on a thumbs down
+export default function MessageFeedbackButtons({
+    feedback,
+    onFeedback,
+}: {
+    feedback?: MessageFeedback
+    onFeedback: (rating: 1 | -1, comment: string) => void
+}) {
+    const [showComment, setShowComment] = useState(false)
+    const [comment, setComment] = useState(feedback?.comment ?? "")
+
+    const submitComment = () => {
+        setShowComment(false)
+        onFeedback(-1, comment)
+    }
+
+    return <div className="flex flex-col space-y-2">
+        <div className="flex items-center space-x-2">
+            <button onClick={() => onFeedback(1, "")}>
+                <ThumbsUp size={14} className={feedback?.rating === 1 ? "text-primary" : ""} />
+            </button>
+            <button onClick={() => setShowComment(!showComment)}>
+                <ThumbsDown size={14} className={feedback?.rating === -1 ? "text-primary" : ""} />
+            </button>
+        </div>
+        {showComment && <div className="max-w-lg space-y-2">
+            <Textarea
+                placeholder="What was wrong with this answer? (optional)"
+                value={comment}
+                onChange={(e) => setComment(e.target.value)}
+            />
+            <div className="flex justify-end space-x-2">
+                <Button variant="ghost" onClick={() => setShowComment(false)}>Cancel</Button>
+                <Button onClick={submitComment}>Send</Button>
+            </div>
+        </div>}
+    </div>
+}
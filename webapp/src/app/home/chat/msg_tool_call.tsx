@@ -13,6 +13,15 @@ export default function MessageToolCall({
         switch (message.name) {
             case "vector_query":
                 return "Searching local information ..."
+            case "list_folder":
+                return "Browsing documents ..."
+            case "read_document":
+            case "get_document_summary":
+                return "Reading document ..."
+            case "read_website_page":
+                return "Reading website page ..."
+            case "web_search":
+                return "Searching the web ..."
             default:
                 return ""
         }
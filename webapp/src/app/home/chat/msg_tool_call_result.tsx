@@ -2,16 +2,19 @@ import { Button } from "@/components/ui/button";
 import { ConversationMessage } from "@/types/conversation"
 import { Document } from "@/types/document"
 import { WebsitePage } from "@/types/websites";
-import { File, FileText } from "lucide-react";
+import { Check, File, FileText } from "lucide-react";
 import Image from "next/image";
 import Link from "next/link";
+import { useState } from "react";
 
 export default function MessageToolCallResult({
     message,
-    offset
+    offset,
+    onConfirmSave,
 }: {
     message: ConversationMessage
     offset: number
+    onConfirmSave?: () => Promise<void>
 }) {
     const getItems = () => {
         const items: JSX.Element[] = []
@@ -27,6 +30,11 @@ export default function MessageToolCallResult({
                 for (let i = 0; i < message.arguments.pages.length; i++) {
                     items.push(<WebsitePageItem key={`page-${i}`} page={message.arguments.pages[i]} />)
                 }
+                break
+            case "web_search":
+                if (message.arguments.pendingSave && onConfirmSave) {
+                    items.push(<PendingSaveItem key="pending-save" pendingSave={message.arguments.pendingSave} onConfirm={onConfirmSave} />)
+                }
         }
 
         return items
@@ -63,4 +71,28 @@ function WebsitePageItem({ page }: { page: WebsitePage }) {
             </div>
         </div>
     </a>
+}
+
+// a page the model offered to save into the websites, only saved once the user confirms it
+function PendingSaveItem({
+    pendingSave,
+    onConfirm,
+}: {
+    pendingSave: { url: string, savedAt?: string }
+    onConfirm: () => Promise<void>
+}) {
+    const [isSaving, setIsSaving] = useState(false)
+
+    const confirm = async () => {
+        setIsSaving(true)
+        await onConfirm()
+        setIsSaving(false)
+    }
+
+    return <div className="bg-secondary rounded-lg p-2 col-span-2 flex items-center justify-between space-x-2">
+        <p className="text-wrap text-left px-2">Save {pendingSave.url} to your websites?</p>
+        {pendingSave.savedAt
+            ? <div className="flex items-center space-x-1 opacity-60 text-sm px-2"><Check size={14} /><p>Saved</p></div>
+            : <Button disabled={isSaving} onClick={confirm}>Save</Button>}
+    </div>
 }
\ No newline at end of file
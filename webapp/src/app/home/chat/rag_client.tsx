@@ -1,13 +1,14 @@
 "use client"
 
 import { ConversationMessage } from '@/types/conversation';
-import { RagMessagePayload } from '@/types/rag';
-import React, { KeyboardEvent, useEffect, useRef, useState } from 'react';
+import { RagClientPayload, RagEnvelope, RagMessagePayload } from '@/types/rag';
+import React, { KeyboardEvent, useCallback, useEffect, useRef, useState } from 'react';
 import { useQuery, useQueryClient } from '@tanstack/react-query';
 import DefaultLoader from '@/components/default_loader';
 import RagMessage from './rag_message';
 import Cookies from "js-cookie"
-import { getConversation } from '@/actions/conversation';
+import { getConversation, sendMessageFeedback } from '@/actions/conversation';
+import { confirmRagSave, createRagTicket } from '@/actions/rag';
 import RagEmpty from './rag_empty';
 import { toast } from '@/components/ui/use-toast';
 import useWebSocket from 'react-use-websocket';
@@ -16,11 +17,17 @@ import { ModelRow } from '@/types/llm';
 import { House, Settings } from 'lucide-react';
 import Link from 'next/link';
 
+// placeholder id for the message that is being streamed
+const streamingId = "streaming"
+
+// version of the websocket protocol
+const protocolVersion = 2
+
 export default function RagClient({ wsBaseUrl }: { wsBaseUrl: string }) {
     const queryClient = useQueryClient()
 
-    // for controlling the socketUrl
-    const [socketUrl, setSocketUrl] = useState("");
+    // the websocket connects once the conversation is loaded
+    const [socketEnabled, setSocketEnabled] = useState(false);
 
     const [isLoading, setIsLoading] = useState(true)
     const [input, setInput] = useState("")
@@ -30,8 +37,34 @@ export default function RagClient({ wsBaseUrl }: { wsBaseUrl: string }) {
 
     const textareaRef = useRef<HTMLTextAreaElement>(null);
 
-    // websocket
-    const { sendMessage, lastMessage, readyState } = useWebSocket(socketUrl);
+    // set when the current turn started a new branch, so the branches are reloaded once it completes
+    const branched = useRef(false)
+
+    // websocket. The session is kept across reconnects so missed events can be resumed
+    const session = useRef<{ sessionId?: string, conversationId?: string, lastSeq: number }>({ lastSeq: 0 })
+
+    // every connection needs a new ticket, as they are short lived and scoped to the conversation
+    const getSocketUrl = useCallback(async () => {
+        const conversationId = session.current.conversationId ?? ""
+        const ticket = await createRagTicket(conversationId)
+        return `${wsBaseUrl}?id=${conversationId}&ticket=${encodeURIComponent(ticket.ticket)}`
+    }, [wsBaseUrl])
+
+    const { sendMessage, lastMessage, readyState } = useWebSocket(socketEnabled ? getSocketUrl : null, {
+        shouldReconnect: () => true,
+        reconnectAttempts: 10,
+        reconnectInterval: 3000,
+    });
+
+    const send = (type: string, payload?: RagClientPayload) => {
+        const envelope: RagEnvelope<RagClientPayload> = {
+            version: protocolVersion,
+            id: crypto.randomUUID(),
+            type: type,
+            payload: payload,
+        }
+        sendMessage(JSON.stringify(envelope))
+    }
 
     const scrollableDivRef = useRef<HTMLDivElement | null>(null);
 
@@ -47,10 +80,18 @@ export default function RagClient({ wsBaseUrl }: { wsBaseUrl: string }) {
     useEffect(() => {
         if (conv.status === "success") {
             // set the websocket state
-            setSocketUrl(`${wsBaseUrl}?id=${conv.data.conversationId}`)
+            session.current.conversationId = conv.data.conversationId
+            setSocketEnabled(true)
 
             // set the message state
-            setMessages(conv.data!.messages)
+            setMessages(conv.data!.messages.map((item, i) => ({
+                ...item,
+                index: i,
+                citations: conv.data!.citations?.[i],
+                branches: conv.data!.branches?.[i],
+                messageId: conv.data!.messageIds?.[i],
+                feedback: conv.data!.feedback?.[i],
+            })))
             setIsFirstMessage(conv.data!.messages.length === 0)
             setTimeout(() => scrollToBottom(), 200)
         }
@@ -69,29 +110,101 @@ export default function RagClient({ wsBaseUrl }: { wsBaseUrl: string }) {
     // handle when new messages are recieved in the websocket
     useEffect(() => {
         if (lastMessage !== null) {
-            // parse the base64 payload into json
-            const base64String = lastMessage.data.trim().replace(/^"|"$/g, '');
-            const message = atob(base64String);
-            const data = JSON.parse(message) as RagMessagePayload
-            console.log(data)
+            const envelope = JSON.parse(lastMessage.data) as RagEnvelope<RagMessagePayload>
+            const data = { ...envelope.payload, messageType: envelope.type } as RagMessagePayload
+            console.log(envelope)
+
+            // track and ack the conversation events, ignoring events that were already handled
+            if (envelope.seq !== undefined) {
+                if (envelope.seq <= session.current.lastSeq) {
+                    return
+                }
+                session.current.lastSeq = envelope.seq
+                send("ack", { seq: envelope.seq })
+            }
 
             // process the message based on the type
             switch (data.messageType) {
+                case "connected":
+                    // resume the previous session to receive the missed events
+                    if (session.current.sessionId !== undefined) {
+                        send("resume", {
+                            sessionId: session.current.sessionId,
+                            conversationId: session.current.conversationId,
+                            seq: session.current.lastSeq,
+                        })
+                    } else {
+                        session.current = { sessionId: data.sessionId, conversationId: data.conversationId, lastSeq: data.lastSeq ?? 0 }
+                    }
+                    break
+                case "resumed":
+                    session.current.sessionId = data.sessionId
+                    break
+                case "resumeFailed":
+                    // the missed events are gone, continue on the current session and reload the conversation
+                    session.current = { sessionId: data.sessionId, conversationId: data.conversationId, lastSeq: data.lastSeq ?? 0 }
+                    queryClient.invalidateQueries({ queryKey: ['conversation'] })
+                    setIsLoading(false)
+                    break
+                case "ack":
+                    break
                 case "loading":
                     setIsLoading(true)
                     break
-                case "newMessage":
-                    // recieved a new chat message from the connection
-                    setMessages((prev) => prev.concat(data.chatMessage!))
+                case "messageDelta":
+                    // append the streamed text to the message being generated
+                    setMessages((prev) => {
+                        const last = prev[prev.length - 1]
+                        if (last !== undefined && last.role === 2 && last.id === streamingId) {
+                            return prev.slice(0, -1).concat({ ...last, message: last.message + data.delta! })
+                        }
+                        return prev.concat({ role: 2, message: data.delta!, index: prev.length, id: streamingId })
+                    })
+                    setTimeout(() => scrollToBottom(), 200)
+                    break
+                case "toolCallStart":
+                    setIsLoading(true)
+                    break
+                case "messageComplete":
+                    // replace the streamed message with the assembled message
+                    setMessages((prev) => {
+                        const last = prev[prev.length - 1]
+                        const message = { ...data.chatMessage!, citations: data.citations, messageId: data.messageId }
+                        if (last !== undefined && last.id === streamingId) {
+                            return prev.slice(0, -1).concat(message)
+                        }
+                        return prev.concat(message)
+                    })
                     setTimeout(() => scrollToBottom(), 200)
 
                     // handle when to stop loading
                     if (data.chatMessage!.role === 2) {
                         setIsLoading(false)
+                        if (branched.current) {
+                            branched.current = false
+                            queryClient.invalidateQueries({ queryKey: ['conversation'] })
+                        }
                     }
                     break
+                case "branched":
+                    // the messages from the branch index are replaced by the new branch
+                    setMessages((prev) => prev.slice(0, data.branchIndex!))
+                    branched.current = true
+                    break
+                case "branchSwitched":
+                    queryClient.invalidateQueries({ queryKey: ['conversation'] })
+                    break
+                case "cancelled":
+                    // the partial message was saved by the server
+                    setIsLoading(false)
+                    break
+                case "toolCallFinish":
+                    setMessages((prev) => prev.concat({ ...data.chatMessage!, messageId: data.messageId }))
+                    setTimeout(() => scrollToBottom(), 200)
+                    break
                 case "newConversationId":
                     // set the conversation id as a cookie
+                    session.current.conversationId = data.conversationId
                     Cookies.set("conversationId", data.conversationId!, { secure: true, sameSite: "strict" })
                     queryClient.invalidateQueries({ queryKey: ['allConversations'] })
                     break
@@ -147,13 +260,65 @@ export default function RagClient({ wsBaseUrl }: { wsBaseUrl: string }) {
 
         // send the request on the websocket
         setIsLoading(true)
-        sendMessage(JSON.stringify({ "messageType": "ragMessage", "message": input }))
+        send("ragMessage", { message: input })
 
         // sendRequest(input)
     }
 
+    const handleCancel = () => {
+        send("cancel")
+    }
+
+    const handleEdit = (index: number, message: string) => {
+        setMessages((prev) => prev.slice(0, index).concat({ role: 1, message: message, index: index }))
+        setIsLoading(true)
+        send("editMessage", { index: index, message: message })
+    }
+
+    const handleRegenerate = (index: number) => {
+        setIsLoading(true)
+        send("regenerate", { index: index })
+    }
+
+    const handleFeedback = async (index: number, rating: 1 | -1, comment: string) => {
+        const message = messages[index]
+        if (message.messageId === undefined || session.current.conversationId === undefined) {
+            return
+        }
+        try {
+            const feedback = await sendMessageFeedback(session.current.conversationId, message.messageId, rating, comment)
+            setMessages((prev) => prev.map((item, i) => i === index ? { ...item, feedback: feedback } : item))
+        } catch (e) {
+            console.error(e)
+            toast({ variant: "destructive", title: "Failed to send the feedback" })
+        }
+    }
+
+    const handleConfirmSave = async (index: number) => {
+        const message = messages[index]
+        if (message.messageId === undefined || session.current.conversationId === undefined) {
+            return
+        }
+        try {
+            await confirmRagSave(session.current.conversationId, message.messageId)
+            const savedAt = new Date().toISOString()
+            setMessages((prev) => prev.map((item, i) => i === index
+                ? { ...item, arguments: { ...item.arguments, pendingSave: { ...item.arguments.pendingSave, savedAt: savedAt } } }
+                : item))
+            toast({ title: "Saved to your websites" })
+        } catch (e) {
+            console.error(e)
+            toast({ variant: "destructive", title: "Failed to save the page" })
+        }
+    }
+
+    const handleSwitchBranch = (messageId: string) => {
+        setIsLoading(true)
+        send("switchBranch", { messageId: messageId })
+    }
+
     const changeChatLLM = (model: ModelRow) => {
-        sendMessage(JSON.stringify({ "messageType": "changeChatLLM", "chatLLMId": model.llm.id }))
+        send("changeChatLLM", { chatLLMId: model.llm.id })
     }
 
     const getMessages = () => {
@@ -171,7 +336,16 @@ export default function RagClient({ wsBaseUrl }: { wsBaseUrl: string }) {
             // ignore system messages
             if (messages[i].role != 0) {
                 items.push(<div key={`rag_message-${i}`}>
-                    <RagMessage message={messages[i]} offset={messages.length - i - 1} />
+                    <RagMessage
+                        message={messages[i]}
+                        offset={messages.length - i - 1}
+                        disabled={isLoading}
+                        onEdit={(message) => handleEdit(i, message)}
+                        onRegenerate={() => handleRegenerate(i)}
+                        onSwitchBranch={handleSwitchBranch}
+                        onFeedback={(rating, comment) => handleFeedback(i, rating, comment)}
+                        onConfirmSave={() => handleConfirmSave(i)}
+                    />
                 </div>)
             }
         }
@@ -230,7 +404,7 @@ export default function RagClient({ wsBaseUrl }: { wsBaseUrl: string }) {
                     </div>
                     <button
                         className="bg-primary hover:opacity-70 text-primary-foreground w-10 h-10 rounded-full font-bold flex-shrink-0"
-                        onClick={handleSubmit}
+                        onClick={isLoading ? handleCancel : handleSubmit}
                     >
                         <div className='grid place-items-center'>
                             {isLoading || conv.isLoading ? <DefaultLoader /> : <p>&uarr;</p>}
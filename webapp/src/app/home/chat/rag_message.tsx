@@ -1,30 +1,82 @@
 import { ConversationMessage } from "@/types/conversation"
 import MessageToolCallResult from "./msg_tool_call_result"
 import MessageToolCall from "./msg_tool_call"
+import MessageCitations from "./msg_citations"
+import MessageBranchSwitcher from "./msg_branch_switcher"
+import MessageFeedbackButtons from "./msg_feedback"
+import { useState } from "react"
+import { Button } from "@/components/ui/button"
+import { Textarea } from "@/components/ui/textarea"
+import { Pencil, RefreshCw } from "lucide-react"
 
 
 export default function RagMessage({
     message,
-    offset
+    offset,
+    disabled,
+    onEdit,
+    onRegenerate,
+    onSwitchBranch,
+    onFeedback,
+    onConfirmSave,
 }: {
     message: ConversationMessage
     offset: number
+    disabled?: boolean
+    onEdit?: (message: string) => void
+    onRegenerate?: () => void
+    onSwitchBranch?: (messageId: string) => void
+    onFeedback?: (rating: 1 | -1, comment: string) => void
+    onConfirmSave?: () => Promise<void>
 }) {
+    const [isEditing, setIsEditing] = useState(false)
+    const [editInput, setEditInput] = useState(message.message)
+
     // ignore system messages
     if (message.role == 0) {
         return <></>
     }
 
+    const submitEdit = () => {
+        if (editInput.trim() === "" || editInput === message.message) {
+            setIsEditing(false)
+            return
+        }
+        setIsEditing(false)
+        onEdit?.(editInput)
+    }
+
+    const branchSwitcher = message.branches && onSwitchBranch
+        ? <MessageBranchSwitcher branches={message.branches} disabled={disabled} onSwitch={onSwitchBranch} />
+        : <></>
+
     const proseClass = "prose prose-slate prose-invert whitespace-pre-line max-w-none"
 
     const getMessage = () => {
         switch (message.role) {
             case 1:
                 // user
-                return <div className="w-full flex justify-end">
+                if (isEditing) {
+                    return <div className="w-full flex justify-end">
+                        <div className="bg-secondary p-4 rounded-2xl w-full max-w-lg space-y-2">
+                            <Textarea value={editInput} onChange={(e) => setEditInput(e.target.value)} />
+                            <div className="flex justify-end space-x-2">
+                                <Button variant="ghost" onClick={() => { setEditInput(message.message); setIsEditing(false) }}>Cancel</Button>
+                                <Button onClick={submitEdit}>Send</Button>
+                            </div>
+                        </div>
+                    </div>
+                }
+                return <div className="w-full flex flex-col items-end group">
                     <div className="bg-secondary p-4 rounded-2xl w-fit max-w-lg">
                         <p className={proseClass}>{message.message}</p>
                     </div>
+                    <div className="flex items-center space-x-2 pt-1">
+                        {branchSwitcher}
+                        {onEdit && <button className="opacity-0 group-hover:opacity-100 disabled:opacity-30" disabled={disabled} onClick={() => setIsEditing(true)}>
+                            <Pencil size={14} />
+                        </button>}
+                    </div>
                 </div>
             case 2:
                 // ai
@@ -32,13 +84,23 @@ export default function RagMessage({
                     <div className="w-12 h-12 bg-primary rounded-full flex-shrink-0 font-bold text-white grid place-items-center">
                         <p>AI</p>
                     </div>
-                    <div className={`${proseClass} prose-lg`}>{message.message}</div>
+                    <div>
+                        <div className={`${proseClass} prose-lg`}>{message.message}</div>
+                        {message.citations && message.citations.length > 0 && <MessageCitations citations={message.citations} />}
+                        <div className="flex items-center space-x-2 pt-1">
+                            {branchSwitcher}
+                            {onRegenerate && <button className="disabled:opacity-30" disabled={disabled} onClick={onRegenerate}>
+                                <RefreshCw size={14} />
+                            </button>}
+                            {onFeedback && message.messageId && <MessageFeedbackButtons feedback={message.feedback} onFeedback={onFeedback} />}
+                        </div>
+                    </div>
                 </div>
             case 3:
                 // tool call
                 return <MessageToolCall message={message} offset={offset} />
             case 4:
-                return <MessageToolCallResult message={message} offset={offset} />
+                return <MessageToolCallResult message={message} offset={offset} onConfirmSave={onConfirmSave} />
             default:
                 return <div className="">{message.message}</div>
         }
@@ -14,9 +14,41 @@ export interface ConversationMessage {
     id?: string
     name?: string
     arguments?: any
+    citations?: Citation[]
+    branches?: MessageBranches
+    messageId?: string // id of the saved message
+    feedback?: MessageFeedback
+}
+
+// the thumbs up (1) or down (-1) of the user on an ai message
+export interface MessageFeedback {
+    messageId: string
+    rating: 1 | -1
+    comment: string
+}
+
+// the messages that share a parent with a message, when it was edited or regenerated
+export interface MessageBranches {
+    messageIds: string[]
+    current: number
+}
+
+// a source that was used to compose an ai message, referenced as [number] in the message
+export interface Citation {
+    number: number
+    sourceType: "document" | "websitePage" | "feedItem" | "web"
+    sourceId?: string
+    title?: string
+    url?: string
+    chunkIndex?: number
+    snippet: string
 }
 
 export interface ConversationResponse {
     conversationId: string
     messages: ConversationMessage[]
+    messageIds?: string[]
+    citations?: Record<number, Citation[]> // by message index
+    branches?: Record<number, MessageBranches> // by message index
+    feedback?: Record<number, MessageFeedback> // by message index
 }
\ No newline at end of file
@@ -1,4 +1,4 @@
-import { ConversationMessage } from "./conversation";
+import { Citation, ConversationMessage } from "./conversation";
 import { ModelRow } from "./llm";
 
 export type RAG2Request = {
@@ -6,6 +6,11 @@ export type RAG2Request = {
     path: string
 }
 
+export type RAG2Ticket = {
+    ticket: string
+    expiresAt: string
+}
+
 export interface RAGRequest {
     input: string;
     checkQuality?: boolean;
@@ -33,6 +38,25 @@ export interface RAGResponse {
 // NewTitle       string         `json:"newTitle,omitempty"`
 // Error          string         `json:"error,omitempty"`
 
+// the v2 websocket envelope, sent as a json frame in both directions
+export type RagEnvelope<T> = {
+    version: number
+    id: string
+    seq?: number
+    type: string
+    payload?: T
+}
+
+export type RagClientPayload = {
+    message?: string
+    chatLLMId?: string
+    seq?: number
+    sessionId?: string
+    conversationId?: string
+    index?: number
+    messageId?: string
+}
+
 export type RagMessagePayload = {
     messageType: string
 
@@ -41,4 +65,12 @@ export type RagMessagePayload = {
     conversationId?: string
     newTitle?: string
     chatLLM?: ModelRow
+    delta?: string
+    toolName?: string
+    ackId?: string
+    sessionId?: string
+    lastSeq?: number
+    citations?: Citation[]
+    branchIndex?: number
+    messageId?: string
 }
\ No newline at end of file